@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/internal/registry"
+)
+
+// CleanCmd removes received projects and, optionally, the registry cache.
+type CleanCmd struct {
+	All bool `help:"Also remove the local registry cache for the configured registry"`
+}
+
+// Run executes the clean command.
+func (c *CleanCmd) Run(globals *GlobalOptions, ctx context.Context) error {
+	wctx, err := OpenWorkspaceContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := wctx.WS.CleanVendor(); err != nil {
+		return fmt.Errorf("clean vendor directory: %w", err)
+	}
+	logger.Log(ctx).Info().Msg("Removed received projects")
+
+	if !c.All {
+		return nil
+	}
+
+	if globals.RegistryURL == "" {
+		return fmt.Errorf("registry URL not configured")
+	}
+	if err := registry.RemoveCache(ctx, globals.CacheDir, globals.RegistryURL); err != nil {
+		return err
+	}
+	logger.Log(ctx).Info().Str("registry", globals.RegistryURL).Msg("Removed registry cache")
+
+	return nil
+}
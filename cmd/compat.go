@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/local"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/internal/protoc"
+	"github.com/rahulagarwal0605/protato/internal/utils"
+)
+
+// CompatCmd checks owned protos for wire compatibility with a baseline ref.
+type CompatCmd struct {
+	Projects []string `arg:"" optional:"" help:"Projects to check (default: all owned projects)"`
+	Baseline string   `help:"Git ref of this repository to compare against (e.g., origin/main, v1.2.0)" required:""`
+}
+
+// Run executes the compat command.
+func (c *CompatCmd) Run(globals *GlobalOptions, ctx context.Context) error {
+	wctx, err := OpenWorkspaceContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	projects, err := resolveOwnedProjects(wctx.WS, c.Projects)
+	if err != nil {
+		return err
+	}
+
+	files, owned, err := collectOwnedProtoFiles(ctx, wctx.WS, projects)
+	if err != nil {
+		return err
+	}
+
+	vendor, err := wctx.WS.VendorRoots(ctx)
+	if err != nil {
+		vendor = nil // No vendor dir configured, that's OK
+	}
+
+	baseline, err := c.loadBaseline(ctx, wctx.Repo, owned, vendor, projects)
+	if err != nil {
+		return err
+	}
+	// Protos that are new since the baseline are compatible; protos deleted since
+	// then are compared, so their removed types are reported
+	if len(baseline.Files) == 0 {
+		logger.Log(ctx).Info().Str("baseline", c.Baseline).Msg("No proto files to compare")
+		return nil
+	}
+
+	current := protoc.CompatSource{
+		Resolver: protoc.NewWorkspaceResolver(ctx, owned, vendor),
+		Files:    files,
+	}
+	issues, err := protoc.CheckCompatibility(ctx, baseline, current)
+	if err != nil {
+		return fmt.Errorf("check compatibility: %w", err)
+	}
+
+	rep := globals.reporter(ctx)
+	for _, issue := range issues {
+		rep.Diagnostic(Diagnostic{Level: zerolog.NoLevel, Rule: issue.Rule, Message: issue.String()})
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("found %d incompatibilities with %s", len(issues), c.Baseline)
+	}
+
+	logger.Log(ctx).Info().Str("baseline", c.Baseline).Int("files", len(files)).Msg("Compatible with baseline")
+	return nil
+}
+
+// loadBaseline reads the owned and vendor protos at the baseline ref into memory.
+// Only owned files of the given projects are compiled; the rest resolve imports.
+func (c *CompatCmd) loadBaseline(
+	ctx context.Context,
+	repo git.RepositoryInterface,
+	owned []protoc.OwnedRoot,
+	vendor []protoc.VendorRoot,
+	projects []local.ProjectPath,
+) (protoc.CompatSource, error) {
+	var paths []string
+	ownedRel := make([]protoc.OwnedRoot, len(owned))
+	for i, root := range owned {
+		dir, err := repoRelPath(repo.Root(), root.Dir)
+		if err != nil {
+			return protoc.CompatSource{}, err
+		}
+		ownedRel[i] = protoc.OwnedRoot{Dir: dir, ImportPrefix: root.ImportPrefix, Projects: root.Projects}
+		paths = append(paths, dir)
+	}
+	vendorRel := make([]protoc.VendorRoot, len(vendor))
+	for i, root := range vendor {
+		dir, err := repoRelPath(repo.Root(), root.Dir)
+		if err != nil {
+			return protoc.CompatSource{}, err
+		}
+		vendorRel[i] = protoc.VendorRoot{Dir: dir, ImportPrefix: root.ImportPrefix}
+		paths = append(paths, dir)
+	}
+
+	entries, err := repo.ReadTree(ctx, git.Treeish(c.Baseline), git.ReadTreeOptions{Recurse: true, Paths: paths})
+	if err != nil {
+		return protoc.CompatSource{}, fmt.Errorf("read baseline %s: %w", c.Baseline, err)
+	}
+
+	contents := make(map[string][]byte)
+	var files []string
+	for _, entry := range entries {
+		if entry.Type != git.BlobType || !utils.IsProjectFile(entry.Path) {
+			continue
+		}
+		importPath, isOwned := baselineImportPath(entry.Path, ownedRel, vendorRel)
+		if importPath == "" {
+			continue
+		}
+		if _, exists := contents[importPath]; exists {
+			continue // Owned files take precedence over vendor files
+		}
+
+		var buf bytes.Buffer
+		if err := repo.ReadObject(ctx, git.BlobType, entry.Hash, &buf); err != nil {
+			return protoc.CompatSource{}, fmt.Errorf("read baseline %s: %w", entry.Path, err)
+		}
+		contents[importPath] = buf.Bytes()
+		if isOwned && inProjects(importPath, ownedRel, projects) {
+			files = append(files, importPath)
+		}
+	}
+	sort.Strings(files)
+
+	return protoc.CompatSource{
+		Resolver: protoc.NewMemoryResolver(ctx, contents),
+		Files:    files,
+	}, nil
+}
+
+// repoRelPath returns dir relative to the repository root, slash-separated.
+func repoRelPath(root, dir string) (string, error) {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", dir, err)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// baselineImportPath maps a repository path to its import path and reports whether it is owned.
+// Returns "" for paths outside the owned and vendor directories.
+func baselineImportPath(repoPath string, ownedRel []protoc.OwnedRoot, vendorRel []protoc.VendorRoot) (string, bool) {
+	for _, root := range ownedRel {
+		if rel, ok := underDir(repoPath, root.Dir); ok {
+			return path.Join(root.ImportPrefix, rel), true
+		}
+	}
+	for _, root := range vendorRel {
+		if rel, ok := underDir(repoPath, root.Dir); ok {
+			return path.Join(root.ImportPrefix, rel), false
+		}
+	}
+	return "", false
+}
+
+// underDir returns p relative to dir if p is inside it.
+func underDir(p, dir string) (string, bool) {
+	if dir == "." {
+		return p, true
+	}
+	if strings.HasPrefix(p, dir+"/") {
+		return strings.TrimPrefix(p, dir+"/"), true
+	}
+	return "", false
+}
+
+// inProjects reports whether an owned import path belongs to one of the projects, under the
+// owned directory the project is in.
+func inProjects(importPath string, ownedRel []protoc.OwnedRoot, projects []local.ProjectPath) bool {
+	for _, root := range ownedRel {
+		for _, project := range root.Projects {
+			if !slices.Contains(projects, local.ProjectPath(project)) {
+				continue
+			}
+			if strings.HasPrefix(importPath, path.Join(root.ImportPrefix, project)+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
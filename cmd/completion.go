@@ -0,0 +1,354 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+// CompletionCmd prints a shell completion script.
+type CompletionCmd struct {
+	Shell string `arg:"" enum:"bash,zsh,fish" help:"Shell to generate the completion script for (bash, zsh or fish)"`
+}
+
+// projectPredictor is the predictor tag value of positional arguments that take registry project paths.
+// The completion scripts suggest these by running "list --offline --format json".
+const projectPredictor = "project"
+
+// nonIdentChars matches the bytes of a program name that can't appear in a shell function name.
+var nonIdentChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// completionNode is one command as the completion scripts see it.
+type completionNode struct {
+	path     string            // Space-separated command path; "" for the application itself
+	help     string            // Short help of the command
+	commands []*completionNode // Visible subcommands
+	flags    []completionFlag  // Visible flags of the command and its parents
+	values   []string          // Allowed values of the positional arguments
+	projects bool              // A positional argument completes registry project paths
+}
+
+// completionFlag is a flag as the completion scripts see it.
+type completionFlag struct {
+	long       string   // Name without the leading dashes
+	aliases    []string // Alternative long names
+	short      string   // Short name, or "" if the flag has none
+	help       string
+	takesValue bool
+	enum       []string // Allowed values; nil completes file names
+}
+
+// names returns every form of the flag as typed on the command line.
+func (f completionFlag) names() []string {
+	names := []string{"--" + f.long}
+	for _, alias := range f.aliases {
+		names = append(names, "--"+alias)
+	}
+	if f.short != "" {
+		names = append(names, "-"+f.short)
+	}
+	return names
+}
+
+// flagNames returns every form of every flag of the node.
+func (n *completionNode) flagNames() []string {
+	var names []string
+	for _, flag := range n.flags {
+		names = append(names, flag.names()...)
+	}
+	return names
+}
+
+// Run executes the completion command.
+func (c *CompletionCmd) Run(globals *GlobalOptions, ctx context.Context, kctx *kong.Context) error {
+	return writeCompletion(kctx.Stdout, c.Shell, kctx.Model)
+}
+
+// writeCompletion writes the completion script of app for shell.
+func writeCompletion(w io.Writer, shell string, app *kong.Application) error {
+	name := app.Name
+	nodes := completionNodes(app.Node, "", nil)
+
+	var script strings.Builder
+	switch shell {
+	case "bash":
+		writeBashCompletion(&script, name, nodes)
+	case "zsh":
+		writeZshCompletion(&script, name, nodes)
+	case "fish":
+		writeFishCompletion(&script, name, nodes)
+	default:
+		return fmt.Errorf("unsupported shell %q", shell)
+	}
+	if _, err := io.WriteString(w, script.String()); err != nil {
+		return fmt.Errorf("write completion script: %w", err)
+	}
+	return nil
+}
+
+// completionNodes returns node and its visible descendants in depth-first order.
+// inherited holds the flags of the node's parents, which stay valid after a subcommand.
+func completionNodes(node *kong.Node, path string, inherited []*kong.Flag) []*completionNode {
+	flags := append(append([]*kong.Flag(nil), inherited...), node.Flags...)
+	current := &completionNode{path: path, help: node.Help}
+	for _, flag := range flags {
+		if !flag.Hidden {
+			current.flags = append(current.flags, newCompletionFlag(flag))
+		}
+	}
+	for _, arg := range node.Positional {
+		if arg.Tag.Get("predictor") == projectPredictor {
+			current.projects = true
+		}
+		current.values = append(current.values, enumValues(arg)...)
+	}
+
+	nodes := []*completionNode{current}
+	for _, child := range node.Children {
+		if child.Hidden || child.Type != kong.CommandNode {
+			continue
+		}
+		descendants := completionNodes(child, strings.TrimSpace(path+" "+child.Name), flags)
+		current.commands = append(current.commands, descendants[0])
+		nodes = append(nodes, descendants...)
+	}
+	return nodes
+}
+
+// newCompletionFlag describes a kong flag for the completion scripts.
+func newCompletionFlag(flag *kong.Flag) completionFlag {
+	f := completionFlag{
+		long:       flag.Name,
+		aliases:    flag.Aliases,
+		help:       flag.Help,
+		takesValue: !flag.IsBool() && !flag.IsCounter(),
+	}
+	if flag.Short != 0 {
+		f.short = string(flag.Short)
+	}
+	if f.takesValue {
+		f.enum = enumValues(flag.Value)
+	}
+	return f
+}
+
+// enumValues returns the non-empty allowed values of a flag or argument, or nil if any value is allowed.
+func enumValues(value *kong.Value) []string {
+	if value.Enum == "" {
+		return nil
+	}
+	var values []string
+	for _, v := range value.EnumSlice() {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// commandNames returns the last path segment of each command.
+func commandNames(commands []*completionNode) []string {
+	names := make([]string, len(commands))
+	for i, command := range commands {
+		names[i] = command.path[strings.LastIndex(command.path, " ")+1:]
+	}
+	return names
+}
+
+// commandPaths returns the quoted case pattern matching the path of any command below the application.
+func commandPaths(nodes []*completionNode) string {
+	var paths []string
+	for _, node := range nodes[1:] {
+		paths = append(paths, "'"+node.path+"'")
+	}
+	return strings.Join(paths, "|")
+}
+
+// projectListCommand is the pipeline the bash and zsh scripts use to print registry project paths, one per line.
+func projectListCommand(name string) string {
+	return name + ` list --offline --format json 2>/dev/null | sed -n 's/^ *"project": *"\([^"]*\)".*/\1/p'`
+}
+
+// writeBashCompletion writes a bash completion script.
+// Words are matched against the command paths to find the command being completed;
+// an empty reply falls back to file names, which covers flags that take a path.
+func writeBashCompletion(b *strings.Builder, name string, nodes []*completionNode) {
+	fn := "_" + nonIdentChars.ReplaceAllString(name, "_")
+
+	fmt.Fprintf(b, "# bash completion for %s\n", name)
+	fmt.Fprintf(b, "# Load with: source <(%s completion bash)\n\n", name)
+	fmt.Fprintf(b, "%s_projects() {\n    %s\n}\n\n", fn, projectListCommand(name))
+	fmt.Fprintf(b, "%s() {\n", fn)
+	b.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	b.WriteString("    local cmdpath=\"\" next candidates=\"\" projects=0 i\n")
+	b.WriteString("    for ((i = 1; i < COMP_CWORD; i++)); do\n")
+	b.WriteString("        next=\"${cmdpath:+$cmdpath }${COMP_WORDS[i]}\"\n")
+	b.WriteString("        case \"$next\" in\n")
+	fmt.Fprintf(b, "        %s) cmdpath=\"$next\" ;;\n", commandPaths(nodes))
+	b.WriteString("        esac\n")
+	b.WriteString("    done\n\n")
+	b.WriteString("    case \"$cmdpath\" in\n")
+	for _, node := range nodes {
+		fmt.Fprintf(b, "    '%s')\n", node.path)
+		if enums, files := valueFlags(node); len(enums)+len(files) > 0 {
+			b.WriteString("        case \"$prev\" in\n")
+			for _, flag := range enums {
+				fmt.Fprintf(b, "        %s) COMPREPLY=($(compgen -W '%s' -- \"$cur\")); return ;;\n",
+					strings.Join(flag.names(), "|"), strings.Join(flag.enum, " "))
+			}
+			if len(files) > 0 {
+				fmt.Fprintf(b, "        %s) return ;;\n", strings.Join(files, "|"))
+			}
+			b.WriteString("        esac\n")
+		}
+		fmt.Fprintf(b, "        if [[ $cur == -* ]]; then\n            candidates='%s'\n", strings.Join(node.flagNames(), " "))
+		if len(node.commands) > 0 {
+			fmt.Fprintf(b, "        else\n            candidates='%s'\n", strings.Join(commandNames(node.commands), " "))
+		} else if len(node.values) > 0 {
+			fmt.Fprintf(b, "        else\n            candidates='%s'\n", strings.Join(node.values, " "))
+		} else if node.projects {
+			b.WriteString("        else\n            projects=1\n")
+		}
+		b.WriteString("        fi\n        ;;\n")
+	}
+	b.WriteString("    esac\n\n")
+	fmt.Fprintf(b, "    if ((projects)); then\n        candidates=\"$(%s_projects)\"\n    fi\n", fn)
+	b.WriteString("    COMPREPLY=($(compgen -W \"$candidates\" -- \"$cur\"))\n")
+	b.WriteString("}\n\n")
+	fmt.Fprintf(b, "complete -o default -F %s %s\n", fn, name)
+}
+
+// writeZshCompletion writes a zsh completion script that works both sourced and autoloaded from $fpath.
+func writeZshCompletion(b *strings.Builder, name string, nodes []*completionNode) {
+	fn := "_" + nonIdentChars.ReplaceAllString(name, "_")
+
+	fmt.Fprintf(b, "#compdef %s\n", name)
+	fmt.Fprintf(b, "# zsh completion for %s\n", name)
+	fmt.Fprintf(b, "# Load with: source <(%s completion zsh), or save as %s in a directory on $fpath\n\n", name, fn)
+	fmt.Fprintf(b, "%s_projects() {\n    %s\n}\n\n", fn, projectListCommand(name))
+	fmt.Fprintf(b, "%s() {\n", fn)
+	b.WriteString("    local cur=\"${words[CURRENT]}\" prev=\"${words[CURRENT-1]}\"\n")
+	b.WriteString("    local cmdpath=\"\" next projects=0 i\n")
+	b.WriteString("    local -a candidates\n")
+	b.WriteString("    for ((i = 2; i < CURRENT; i++)); do\n")
+	b.WriteString("        next=\"${cmdpath:+$cmdpath }${words[i]}\"\n")
+	b.WriteString("        case \"$next\" in\n")
+	fmt.Fprintf(b, "        %s) cmdpath=\"$next\" ;;\n", commandPaths(nodes))
+	b.WriteString("        esac\n")
+	b.WriteString("    done\n\n")
+	b.WriteString("    case \"$cmdpath\" in\n")
+	for _, node := range nodes {
+		fmt.Fprintf(b, "    '%s')\n", node.path)
+		if enums, files := valueFlags(node); len(enums)+len(files) > 0 {
+			b.WriteString("        case \"$prev\" in\n")
+			for _, flag := range enums {
+				fmt.Fprintf(b, "        %s) compadd -- %s; return ;;\n",
+					strings.Join(flag.names(), "|"), strings.Join(flag.enum, " "))
+			}
+			if len(files) > 0 {
+				fmt.Fprintf(b, "        %s) _files; return ;;\n", strings.Join(files, "|"))
+			}
+			b.WriteString("        esac\n")
+		}
+		fmt.Fprintf(b, "        if [[ $cur == -* ]]; then\n            candidates=(%s)\n", strings.Join(node.flagNames(), " "))
+		if len(node.commands) > 0 {
+			fmt.Fprintf(b, "        else\n            candidates=(%s)\n", strings.Join(commandNames(node.commands), " "))
+		} else if len(node.values) > 0 {
+			fmt.Fprintf(b, "        else\n            candidates=(%s)\n", strings.Join(node.values, " "))
+		} else if node.projects {
+			b.WriteString("        else\n            projects=1\n")
+		}
+		b.WriteString("        fi\n        ;;\n")
+	}
+	b.WriteString("    esac\n\n")
+	fmt.Fprintf(b, "    if ((projects)); then\n        candidates=(${(f)\"$(%s_projects)\"})\n    fi\n", fn)
+	b.WriteString("    if ((${#candidates})); then\n        compadd -- \"${candidates[@]}\"\n    else\n        _files\n    fi\n")
+	b.WriteString("}\n\n")
+	fmt.Fprintf(b, "if [[ \"${funcstack[1]}\" == \"%s\" ]]; then\n    %s \"$@\"\nelse\n    compdef %s %s\nfi\n", fn, fn, fn, name)
+}
+
+// writeFishCompletion writes a fish completion script.
+// Each completion is guarded by the command path it applies to; file names are only
+// offered for flags that take a path.
+func writeFishCompletion(b *strings.Builder, name string, nodes []*completionNode) {
+	fn := "__" + nonIdentChars.ReplaceAllString(name, "_")
+
+	var paths []string
+	for _, node := range nodes[1:] {
+		paths = append(paths, fishQuote(node.path))
+	}
+
+	fmt.Fprintf(b, "# fish completion for %s\n", name)
+	fmt.Fprintf(b, "# Load with: %s completion fish | source\n\n", name)
+	fmt.Fprintf(b, "function %s_command\n", fn)
+	b.WriteString("    set -l cmdpath ''\n")
+	b.WriteString("    for word in (commandline -opc)[2..-1]\n")
+	b.WriteString("        set -l next (string trim -- \"$cmdpath $word\")\n")
+	fmt.Fprintf(b, "        if contains -- $next %s\n", strings.Join(paths, " "))
+	b.WriteString("            set cmdpath $next\n")
+	b.WriteString("        end\n")
+	b.WriteString("    end\n")
+	b.WriteString("    echo $cmdpath\n")
+	b.WriteString("end\n\n")
+	fmt.Fprintf(b, "function %s_using\n", fn)
+	fmt.Fprintf(b, "    set -l cmdpath (%s_command)\n", fn)
+	b.WriteString("    test \"$cmdpath\" = \"$argv\"\n")
+	b.WriteString("end\n\n")
+	fmt.Fprintf(b, "function %s_projects\n", fn)
+	fmt.Fprintf(b, "    %s list --offline --format json 2>/dev/null | string match -rg '^ *\"project\": *\"([^\"]*)\"'\n", name)
+	b.WriteString("end\n\n")
+	fmt.Fprintf(b, "complete -c %s -f\n", name)
+
+	for _, node := range nodes {
+		cond := fmt.Sprintf("-n %s", fishQuote(fn+"_using "+node.path))
+		for _, command := range node.commands {
+			fmt.Fprintf(b, "complete -c %s %s -a %s -d %s\n",
+				name, cond, commandNames([]*completionNode{command})[0], fishQuote(command.help))
+		}
+		for _, flag := range node.flags {
+			line := fmt.Sprintf("complete -c %s %s -l %s", name, cond, flag.long)
+			for _, alias := range flag.aliases {
+				line += " -l " + alias
+			}
+			if flag.short != "" {
+				line += " -s " + flag.short
+			}
+			switch {
+			case flag.enum != nil:
+				line += " -x -a " + fishQuote(strings.Join(flag.enum, " "))
+			case flag.takesValue:
+				line += " -r -F"
+			}
+			fmt.Fprintf(b, "%s -d %s\n", line, fishQuote(flag.help))
+		}
+		if len(node.values) > 0 {
+			fmt.Fprintf(b, "complete -c %s %s -a %s\n", name, cond, fishQuote(strings.Join(node.values, " ")))
+		}
+		if node.projects {
+			fmt.Fprintf(b, "complete -c %s %s -a '(%s_projects)'\n", name, cond, fn)
+		}
+	}
+}
+
+// valueFlags splits the flags of the node that take a value into those with allowed values
+// and the names of those whose value is completed as a file name.
+func valueFlags(node *completionNode) (enums []completionFlag, files []string) {
+	for _, flag := range node.flags {
+		switch {
+		case flag.enum != nil:
+			enums = append(enums, flag)
+		case flag.takesValue:
+			files = append(files, flag.names()...)
+		}
+	}
+	return enums, files
+}
+
+// fishQuote quotes s as a single-quoted fish string.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
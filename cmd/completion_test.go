@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+// completionTestCLI is a small grammar with the shapes the completion scripts handle:
+// inherited flags, enum flags, nested commands and project arguments.
+type completionTestCLI struct {
+	GlobalOptions
+
+	Verbosity int    `short:"v" type:"counter" help:"Increase verbosity"`
+	Profile   string `hidden:"" enum:",cpu,mem" default:"" help:"Write a pprof profile of the command"`
+
+	Pull       PullCmd       `cmd:"" help:"Download projects from registry"`
+	List       ListCmd       `cmd:"" help:"List available projects"`
+	Debug      DebugCmd      `cmd:"" help:"Diagnose the protato environment"`
+	Completion CompletionCmd `cmd:"" help:"Print a shell completion script"`
+}
+
+// newCompletionTestParser builds a parser for the test grammar that prints to stdout.
+func newCompletionTestParser(t *testing.T, stdout io.Writer) *kong.Kong {
+	t.Helper()
+	parser, err := kong.New(&completionTestCLI{},
+		kong.Name("protato"),
+		kong.Writers(stdout, io.Discard),
+		kong.Vars{"defaultCacheDir": t.TempDir()},
+	)
+	if err != nil {
+		t.Fatalf("kong.New() error = %v", err)
+	}
+	return parser
+}
+
+// completionScript parses "completion <shell>" against the test grammar and returns the script it prints.
+func completionScript(t *testing.T, shell string) string {
+	t.Helper()
+	var out bytes.Buffer
+	kctx, err := newCompletionTestParser(t, &out).Parse([]string{"completion", shell})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	c := kctx.Selected().Target.Addr().Interface().(*CompletionCmd)
+	if err := c.Run(&GlobalOptions{}, testContext(), kctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	return out.String()
+}
+
+func TestCompletionCmd_Run(t *testing.T) {
+	tests := []struct {
+		shell    string
+		wantStrs []string
+	}{
+		{shell: "bash", wantStrs: []string{"complete -o default -F _protato protato", "'debug git-config'", "--no-deps", "list --offline --format json"}},
+		{shell: "zsh", wantStrs: []string{"#compdef protato", "compdef _protato protato", "'debug git-config'", "--no-deps"}},
+		{shell: "fish", wantStrs: []string{"complete -c protato -f", "-l format -x -a 'text json'", "-a '(__protato_projects)'"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			script := completionScript(t, tt.shell)
+			for _, want := range tt.wantStrs {
+				if !strings.Contains(script, want) {
+					t.Errorf("%s script missing %q", tt.shell, want)
+				}
+			}
+			// Hidden flags stay out of completion
+			if strings.Contains(script, "--profile") {
+				t.Errorf("%s script completes the hidden --profile flag", tt.shell)
+			}
+			if shell, err := exec.LookPath(tt.shell); err == nil {
+				check := exec.Command(shell, "-n")
+				check.Stdin = strings.NewReader(script)
+				if out, err := check.CombinedOutput(); err != nil {
+					t.Errorf("%s -n: %v\n%s", tt.shell, err, out)
+				}
+			}
+		})
+	}
+}
+
+func TestCompletionCmd_UnsupportedShell(t *testing.T) {
+	if _, err := newCompletionTestParser(t, io.Discard).Parse([]string{"completion", "powershell"}); err == nil {
+		t.Error("Parse() of an unsupported shell expected error")
+	}
+}
+
+func TestBashCompletion_Candidates(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+	script := completionScript(t, "bash")
+
+	var listing bytes.Buffer
+	if err := writeJSON(&listing, []registryProjectJSON{{Project: "team/a"}, {Project: "team/b"}, {Project: "other/x"}}); err != nil {
+		t.Fatal(err)
+	}
+	// Stands in for the binary so project completion sees a fixed registry
+	stub := "protato() {\ncat <<'EOF'\n" + listing.String() + "EOF\n}\n"
+
+	tests := []struct {
+		line string // Words before the cursor; the last one is being completed
+		want string
+	}{
+		{line: "protato pu", want: "pull"},
+		{line: "protato debug ", want: "git-config"},
+		{line: "protato list --format ", want: "text json"},
+		{line: "protato -v pull --no-d", want: "--no-deps"},
+		{line: "protato pull team/", want: "team/a team/b"},
+		{line: "protato pull team/a ", want: "team/a team/b other/x"},
+		{line: "protato completion ", want: "bash zsh fish"},
+		{line: "protato pull --output-dir ", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			words := strings.Split(tt.line, " ")
+			quoted := make([]string, len(words))
+			for i, w := range words {
+				quoted[i] = "'" + w + "'"
+			}
+			run := script + stub +
+				"COMP_WORDS=(" + strings.Join(quoted, " ") + ")\n" +
+				"COMP_CWORD=" + strconv.Itoa(len(words)-1) + "\n" +
+				"_protato\necho \"${COMPREPLY[*]}\"\n"
+			out, err := exec.Command(bash, "-c", run).CombinedOutput()
+			if err != nil {
+				t.Fatalf("bash: %v\n%s", err, out)
+			}
+			if got := strings.TrimSpace(string(out)); got != tt.want {
+				t.Errorf("completions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+
+	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/internal/registry"
+)
+
+// DoctorCmd checks the health of the workspace and, optionally, the registry.
+type DoctorCmd struct {
+	Registry bool `help:"Also check the registry: reachability, default branch, protos/ layout and registry config"`
+}
+
+// Workspace health checks run by doctor.
+const (
+	checkWorkspaceConfig = "workspace config"
+	checkOwnedProjects   = "owned projects"
+)
+
+// Run executes the doctor command.
+func (c *DoctorCmd) Run(globals *GlobalOptions, ctx context.Context) error {
+	findings := c.workspaceFindings(ctx)
+	if c.Registry {
+		findings = append(findings, c.registryFindings(ctx, globals)...)
+	}
+
+	rep := globals.reporter(ctx)
+	failed := 0
+	for _, f := range findings {
+		if f.Err != nil {
+			failed++
+		}
+		rep.Diagnostic(Diagnostic{Level: zerolog.NoLevel, Message: formatFinding(f)})
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(findings))
+	}
+	return nil
+}
+
+// workspaceFindings checks that the workspace config loads and its owned projects can be listed.
+func (c *DoctorCmd) workspaceFindings(ctx context.Context) []registry.Finding {
+	wctx, err := OpenWorkspaceContext(ctx)
+	if err != nil {
+		return []registry.Finding{{Check: checkWorkspaceConfig, Err: err}}
+	}
+	findings := []registry.Finding{{Check: checkWorkspaceConfig, Detail: wctx.WS.Root()}}
+
+	projects, err := wctx.WS.OwnedProjects()
+	if err != nil {
+		return append(findings, registry.Finding{Check: checkOwnedProjects, Err: err})
+	}
+	return append(findings, registry.Finding{Check: checkOwnedProjects, Detail: fmt.Sprintf("%d found", len(projects))})
+}
+
+// registryFindings opens the registry, verifying the cache's objects, and runs its diagnostics.
+// A registry that cannot be opened (usually a failed clone) is reported as unreachable.
+func (c *DoctorCmd) registryFindings(ctx context.Context, globals *GlobalOptions) []registry.Finding {
+	reg, err := openVerifiedRegistry(ctx, globals)
+	if err != nil {
+		return []registry.Finding{{Check: registry.CheckReachable, Err: err}}
+	}
+	defer reg.Close()
+
+	if err := reg.Refresh(ctx); err != nil {
+		logger.Log(ctx).Warn().Err(err).Msg("Failed to refresh registry")
+	}
+
+	return reg.Diagnose(ctx)
+}
+
+// formatFinding renders a finding as one pass/fail line.
+func formatFinding(f registry.Finding) string {
+	if f.Err != nil {
+		return fmt.Sprintf("FAIL  %s: %v", f.Check, f.Err)
+	}
+	return fmt.Sprintf("ok    %s: %s", f.Check, f.Detail)
+}
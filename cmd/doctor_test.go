@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/internal/registry"
+)
+
+func TestFormatFinding(t *testing.T) {
+	tests := []struct {
+		name    string
+		finding registry.Finding
+		want    string
+	}{
+		{
+			name:    "pass",
+			finding: registry.Finding{Check: registry.CheckDefaultBranch, Detail: "main"},
+			want:    "ok    default branch: main",
+		},
+		{
+			name:    "fail",
+			finding: registry.Finding{Check: registry.CheckProtosDir, Err: errors.New("protos/ not found at abc1234")},
+			want:    "FAIL  protos dir: protos/ not found at abc1234",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatFinding(tt.finding); got != tt.want {
+				t.Errorf("formatFinding() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,31 @@
 // Package cmd provides CLI command implementations.
 package cmd
 
+import "time"
+
 // GlobalOptions contains global CLI options (flags and environment variables).
 type GlobalOptions struct {
-	CacheDir    string `help:"Registry cache directory" env:"PROTATO_REGISTRY_CACHE" default:"${defaultCacheDir}"`
-	RegistryURL string `help:"Registry Git URL" env:"PROTATO_REGISTRY_URL"`
+	CacheDir       string `help:"Registry cache directory" env:"PROTATO_REGISTRY_CACHE" default:"${defaultCacheDir}"`
+	RegistryURL    string `help:"Registry Git URL" env:"PROTATO_REGISTRY_URL"`
+	RegistryBranch string `help:"Registry branch (default: the remote's default branch)" env:"PROTATO_REGISTRY_BRANCH"`
+	JSONLEvents    bool   `name:"jsonl-events" help:"Write command events to stdout as JSON lines; logs stay on stderr"`
+
+	// CacheLockTimeout bounds how long a command waits for other protato processes using the cache.
+	CacheLockTimeout time.Duration `help:"How long to wait for other protato processes using the registry cache" env:"PROTATO_CACHE_LOCK_TIMEOUT" default:"30s"`
+
+	// Credentials for a private registry; ambient git credentials are used when none are set.
+	RegistryToken    string `help:"Bearer token for an HTTPS registry" env:"PROTATO_REGISTRY_TOKEN"`
+	RegistryUsername string `help:"Basic auth username for an HTTPS registry" env:"PROTATO_REGISTRY_USERNAME"`
+	RegistryPassword string `help:"Basic auth password for an HTTPS registry" env:"PROTATO_REGISTRY_PASSWORD"`
+	RegistrySSHKey   string `name:"registry-ssh-key" help:"SSH private key for an SSH registry" env:"PROTATO_REGISTRY_SSH_KEY" type:"path"`
+
+	// Reporter receives command results; nil renders them to the console.
+	Reporter Reporter `kong:"-"`
+}
+
+// BuildInfo contains version metadata embedded at build time.
+type BuildInfo struct {
+	Version string
+	Commit  string
+	Date    string
 }
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/rahulagarwal0605/protato/internal/protoc"
+	"github.com/rahulagarwal0605/protato/internal/registry"
+)
+
+// GraphCmd prints the dependency graph of registry projects.
+type GraphCmd struct {
+	Projects []string `arg:"" optional:"" predictor:"project" help:"Projects to start from (default: owned projects)"`
+	Format   string   `help:"Output format: dot (Graphviz) or mermaid" enum:"dot,mermaid" default:"dot"`
+	Offline  bool     `help:"Don't refresh registry"`
+}
+
+// Graph output formats.
+const (
+	graphFormatDOT     = "dot"
+	graphFormatMermaid = "mermaid"
+)
+
+// graphEdge is an import from one project to another.
+type graphEdge struct {
+	from, to registry.ProjectPath
+}
+
+// Run executes the graph command.
+func (c *GraphCmd) Run(globals *GlobalOptions, ctx context.Context) error {
+	reg, err := OpenRegistryWithRefresh(ctx, globals, c.Offline)
+	if err != nil {
+		return err
+	}
+	defer reg.Close()
+
+	projects, err := c.startProjects(ctx)
+	if err != nil {
+		return err
+	}
+	snapshot, err := reg.GetSnapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("get snapshot: %w", err)
+	}
+	graph, err := protoc.DiscoverDependencyGraph(ctx, reg, snapshot, projects)
+	if err != nil {
+		return fmt.Errorf("discover dependencies: %w", err)
+	}
+
+	cycles := graph.Cycles()
+	rep := globals.reporter(ctx)
+	for _, cycle := range cycles {
+		rep.Diagnostic(Diagnostic{Level: zerolog.WarnLevel, Message: "Import cycle: " + joinProjects(cycle, " -> ")})
+	}
+
+	if c.Format == graphFormatMermaid {
+		renderMermaidGraph(os.Stdout, graph, cycles)
+	} else {
+		renderDOTGraph(os.Stdout, graph, cycles)
+	}
+	return nil
+}
+
+// startProjects returns the registry paths of the projects given as arguments, or of the
+// workspace's owned projects when there are none.
+func (c *GraphCmd) startProjects(ctx context.Context) ([]registry.ProjectPath, error) {
+	if len(c.Projects) > 0 {
+		projects := make([]registry.ProjectPath, len(c.Projects))
+		for i, p := range c.Projects {
+			projects[i] = registry.ProjectPath(strings.Trim(p, "/"))
+		}
+		return projects, nil
+	}
+
+	wctx, err := OpenWorkspaceContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	owned, err := wctx.WS.OwnedProjects()
+	if err != nil {
+		return nil, fmt.Errorf("get owned projects: %w", err)
+	}
+	if len(owned) == 0 {
+		return nil, fmt.Errorf("no owned projects; pass the projects to graph")
+	}
+	projects := make([]registry.ProjectPath, 0, len(owned))
+	for _, p := range owned {
+		registryPath, err := wctx.WS.RegistryProjectPath(p)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, registry.ProjectPath(registryPath))
+	}
+	return projects, nil
+}
+
+// cycleEdges returns the edges that lie on one of the cycles.
+func cycleEdges(cycles [][]registry.ProjectPath) map[graphEdge]bool {
+	edges := make(map[graphEdge]bool)
+	for _, cycle := range cycles {
+		for i := 1; i < len(cycle); i++ {
+			edges[graphEdge{from: cycle[i-1], to: cycle[i]}] = true
+		}
+	}
+	return edges
+}
+
+// joinProjects joins project paths with sep.
+func joinProjects(projects []registry.ProjectPath, sep string) string {
+	parts := make([]string, len(projects))
+	for i, p := range projects {
+		parts[i] = string(p)
+	}
+	return strings.Join(parts, sep)
+}
+
+// renderDOTGraph writes the graph in Graphviz DOT. Every project is declared as a node, so
+// projects without imports still appear; edges on a cycle are drawn red and labelled.
+func renderDOTGraph(w io.Writer, graph protoc.ProjectGraph, cycles [][]registry.ProjectPath) {
+	inCycle := cycleEdges(cycles)
+	fmt.Fprintln(w, "digraph protato {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	for _, p := range graph.Projects() {
+		fmt.Fprintf(w, "  %q;\n", p)
+	}
+	for _, p := range graph.Projects() {
+		for _, dep := range graph[p] {
+			if inCycle[graphEdge{from: p, to: dep}] {
+				fmt.Fprintf(w, "  %q -> %q [color=red, label=\"cycle\"];\n", p, dep)
+			} else {
+				fmt.Fprintf(w, "  %q -> %q;\n", p, dep)
+			}
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// renderMermaidGraph writes the graph as a Mermaid flowchart. Project paths are not valid
+// Mermaid node IDs, so nodes are numbered in path order and labelled with their path;
+// edges on a cycle are labelled.
+func renderMermaidGraph(w io.Writer, graph protoc.ProjectGraph, cycles [][]registry.ProjectPath) {
+	inCycle := cycleEdges(cycles)
+	projects := graph.Projects()
+	ids := make(map[registry.ProjectPath]string, len(projects))
+
+	fmt.Fprintln(w, "graph LR")
+	for i, p := range projects {
+		ids[p] = fmt.Sprintf("p%d", i)
+		fmt.Fprintf(w, "  %s[\"%s\"]\n", ids[p], p)
+	}
+	for _, p := range projects {
+		for _, dep := range graph[p] {
+			if inCycle[graphEdge{from: p, to: dep}] {
+				fmt.Fprintf(w, "  %s -->|cycle| %s\n", ids[p], ids[dep])
+			} else {
+				fmt.Fprintf(w, "  %s --> %s\n", ids[p], ids[dep])
+			}
+		}
+	}
+}
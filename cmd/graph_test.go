@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/internal/protoc"
+)
+
+func TestRenderGraph(t *testing.T) {
+	graph := protoc.ProjectGraph{
+		"svc/orders":  {"svc/billing", "svc/common"},
+		"svc/billing": {"svc/orders"},
+		"svc/common":  {},
+	}
+	cycles := graph.Cycles()
+
+	tests := []struct {
+		name   string
+		render func(*bytes.Buffer)
+		want   string
+	}{
+		{
+			name:   "dot",
+			render: func(b *bytes.Buffer) { renderDOTGraph(b, graph, cycles) },
+			want: "digraph protato {\n" +
+				"  rankdir=LR;\n" +
+				"  \"svc/billing\";\n" +
+				"  \"svc/common\";\n" +
+				"  \"svc/orders\";\n" +
+				"  \"svc/billing\" -> \"svc/orders\" [color=red, label=\"cycle\"];\n" +
+				"  \"svc/orders\" -> \"svc/billing\" [color=red, label=\"cycle\"];\n" +
+				"  \"svc/orders\" -> \"svc/common\";\n" +
+				"}\n",
+		},
+		{
+			name:   "mermaid",
+			render: func(b *bytes.Buffer) { renderMermaidGraph(b, graph, cycles) },
+			want: "graph LR\n" +
+				"  p0[\"svc/billing\"]\n" +
+				"  p1[\"svc/common\"]\n" +
+				"  p2[\"svc/orders\"]\n" +
+				"  p0 -->|cycle| p2\n" +
+				"  p2 -->|cycle| p0\n" +
+				"  p2 --> p1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.render(&buf)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("render() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,12 +2,20 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path"
+	"strings"
+
+	"github.com/rs/zerolog"
 
 	"github.com/rahulagarwal0605/protato/internal/git"
 	"github.com/rahulagarwal0605/protato/internal/local"
 	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/internal/protoc"
 	"github.com/rahulagarwal0605/protato/internal/registry"
 )
 
@@ -56,7 +64,7 @@ func OpenRegistry(ctx context.Context, globals *GlobalOptions) (registry.CacheIn
 		return nil, fmt.Errorf("registry URL not configured")
 	}
 
-	reg, err := registry.Open(ctx, globals.CacheDir, globals.RegistryURL)
+	reg, err := registry.Open(ctx, globals.CacheDir, globals.RegistryURL, globals.registryOpenOptions())
 	if err != nil {
 		return nil, fmt.Errorf("open registry: %w", err)
 	}
@@ -64,7 +72,23 @@ func OpenRegistry(ctx context.Context, globals *GlobalOptions) (registry.CacheIn
 	return reg, nil
 }
 
-// OpenAndRefreshRegistry opens and refreshes the registry.
+// registryOpenOptions returns the options for opening the registry, including any explicit
+// credentials, branch and cache lock timeout.
+func (g *GlobalOptions) registryOpenOptions() registry.OpenOptions {
+	return registry.OpenOptions{
+		Auth: git.AuthOptions{
+			Token:      g.RegistryToken,
+			Username:   g.RegistryUsername,
+			Password:   g.RegistryPassword,
+			SSHKeyPath: g.RegistrySSHKey,
+		},
+		Branch:      g.RegistryBranch,
+		LockTimeout: g.CacheLockTimeout,
+	}
+}
+
+// OpenAndRefreshRegistry opens and refreshes the registry. A failed refresh is retried once
+// after verifying the cache's objects, so a corrupt cache is re-cloned instead of failing every command.
 func OpenAndRefreshRegistry(ctx context.Context, globals *GlobalOptions) (registry.CacheInterface, error) {
 	reg, err := OpenRegistry(ctx, globals)
 	if err != nil {
@@ -72,13 +96,62 @@ func OpenAndRefreshRegistry(ctx context.Context, globals *GlobalOptions) (regist
 	}
 
 	logger.Log(ctx).Info().Msg("Refreshing registry")
+	err = reg.Refresh(ctx)
+	if err == nil {
+		return reg, nil
+	}
+	logger.Log(ctx).Warn().Err(err).Msg("Failed to refresh registry, verifying the cache")
+	reg.Close()
+
+	reg, err = openVerifiedRegistry(ctx, globals)
+	if err != nil {
+		return nil, err
+	}
 	if err := reg.Refresh(ctx); err != nil {
+		reg.Close()
 		return nil, fmt.Errorf("refresh registry: %w", err)
 	}
+	return reg, nil
+}
 
+// openVerifiedRegistry opens the registry cache after checking it has every object its refs
+// reach, re-cloning it if not. The check walks the whole cache, so it is not run on every open.
+func openVerifiedRegistry(ctx context.Context, globals *GlobalOptions) (registry.CacheInterface, error) {
+	if globals.RegistryURL == "" {
+		return nil, fmt.Errorf("registry URL not configured")
+	}
+
+	opts := globals.registryOpenOptions()
+	opts.VerifyObjects = true
+	reg, err := registry.Open(ctx, globals.CacheDir, globals.RegistryURL, opts)
+	if err != nil {
+		return nil, fmt.Errorf("open registry: %w", err)
+	}
 	return reg, nil
 }
 
+// writePorcelainRecord writes one porcelain record: tab-separated fields ending in a newline.
+// Porcelain column orders are part of the CLI contract and must not change.
+func writePorcelainRecord(w io.Writer, fields ...string) {
+	fmt.Fprintln(w, strings.Join(fields, "\t"))
+}
+
+// Output formats accepted by --format.
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// writeJSON writes v to w as indented JSON followed by a newline.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("encode JSON: %w", err)
+	}
+	return nil
+}
+
 // logProjectError logs an error with project context.
 func logProjectError(ctx context.Context, err error, project registry.ProjectPath, operation string) {
 	logger.Log(ctx).Warn().Err(err).Str("project", string(project)).Msg(operation)
@@ -107,3 +180,73 @@ func OpenRegistryWithRefresh(ctx context.Context, globals *GlobalOptions, offlin
 
 	return reg, nil
 }
+
+// resolveOwnedProjects returns the named projects, or all owned projects if none are named.
+// Named projects must be owned by this repository.
+func resolveOwnedProjects(ws local.WorkspaceInterface, names []string) ([]local.ProjectPath, error) {
+	if len(names) == 0 {
+		projects, err := ws.OwnedProjects()
+		if err != nil {
+			return nil, fmt.Errorf("get owned projects: %w", err)
+		}
+		return projects, nil
+	}
+
+	projects := make([]local.ProjectPath, len(names))
+	for i, p := range names {
+		project := local.ProjectPath(p)
+		if !ws.IsProjectOwned(project) {
+			return nil, fmt.Errorf("project %s is not owned by this repository", p)
+		}
+		projects[i] = project
+	}
+	return projects, nil
+}
+
+// collectOwnedProtoFiles lists the import paths of all proto files in the given owned projects,
+// each under the owned directory its project is in, along with the owned directories.
+func collectOwnedProtoFiles(ctx context.Context, ws local.WorkspaceInterface, projects []local.ProjectPath) ([]string, []protoc.OwnedRoot, error) {
+	owned, err := ws.OwnedRoots()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var files []string
+	for _, project := range projects {
+		importPrefix, err := ws.OwnedDirFor(project)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get owned directory: %w", err)
+		}
+		projectFiles, err := ws.ListOwnedProjectFiles(project)
+		if err != nil {
+			logger.Log(ctx).Warn().Err(err).Str("project", string(project)).Msg("Failed to list files")
+			continue
+		}
+		for _, f := range projectFiles {
+			files = append(files, path.Join(importPrefix, string(project), f.Path))
+		}
+	}
+	return files, owned, nil
+}
+
+// ownedDirNames returns the name of each owned directory (e.g., "proto"), in configured order.
+func ownedDirNames(ws local.WorkspaceInterface) ([]string, error) {
+	owned, err := ws.OwnedRoots()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(owned))
+	for i, root := range owned {
+		names[i] = root.ImportPrefix
+	}
+	return names, nil
+}
+
+// explainOwnership reports who owns the path behind a failed claim, when the registry knows.
+func explainOwnership(rep Reporter, project string, err error) {
+	var claimErr *registry.ClaimError
+	if !errors.As(err, &claimErr) || claimErr.Explanation() == "" {
+		return
+	}
+	rep.Diagnostic(Diagnostic{Level: zerolog.NoLevel, Project: project, Message: fmt.Sprintf("%s: %s", project, claimErr.Explanation())})
+}
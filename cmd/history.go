@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/rahulagarwal0605/protato/internal/errors"
+	"github.com/rahulagarwal0605/protato/internal/registry"
+)
+
+// HistoryCmd lists the registry commits that changed a project.
+type HistoryCmd struct {
+	Project        string `arg:"" predictor:"project" help:"Registry project path"`
+	File           string `help:"Only list the commits that changed this file of the project, following renames"`
+	IncludeDeleted bool   `help:"Trace a --file deleted from the project, from the commit that deleted it"`
+	Limit          int    `help:"List at most this many commits (0 for all)" default:"0"`
+	Offline        bool   `help:"Don't refresh registry"`
+}
+
+// Run executes the history command.
+func (c *HistoryCmd) Run(globals *GlobalOptions, ctx context.Context) error {
+	if c.IncludeDeleted && c.File == "" {
+		return fmt.Errorf("--include-deleted needs --file")
+	}
+
+	reg, err := OpenRegistryWithRefresh(ctx, globals, c.Offline)
+	if err != nil {
+		return err
+	}
+	defer reg.Close()
+
+	project := registry.ProjectPath(strings.Trim(c.Project, "/"))
+	revisions, err := reg.GetProjectHistory(ctx, &registry.ProjectHistoryRequest{
+		Project:        project,
+		File:           strings.Trim(c.File, "/"),
+		IncludeDeleted: c.IncludeDeleted,
+		Limit:          c.Limit,
+	})
+	if stderrors.Is(err, errors.ErrNotFound) && c.File != "" && !c.IncludeDeleted {
+		return fmt.Errorf("%s is not in %s; pass --include-deleted to trace a deleted file", c.File, project)
+	}
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		globals.reporter(ctx).Diagnostic(Diagnostic{Level: zerolog.NoLevel, Message: "No history for " + string(project)})
+		return nil
+	}
+
+	printHistory(os.Stdout, revisions)
+	return nil
+}
+
+// printHistory prints one line per revision: short snapshot, date, author and the
+// first line of the commit message.
+func printHistory(w io.Writer, revisions []registry.ProjectRevision) {
+	for _, rev := range revisions {
+		subject, _, _ := strings.Cut(rev.Message, "\n")
+		fmt.Fprintf(w, "%s %s %s %s\n", rev.Snapshot.Short(), rev.Date.Format("2006-01-02"), rev.Author.Name, subject)
+	}
+}
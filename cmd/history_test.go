@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/registry"
+)
+
+func TestPrintHistory(t *testing.T) {
+	revisions := []registry.ProjectRevision{
+		{
+			Snapshot: "4f2a9c1e0000000000000000000000000000beef",
+			Author:   git.Author{Name: "Alice", Email: "alice@example.com"},
+			Date:     time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC),
+			Message:  "Update payments/api\n\nApproved-by: Bob",
+		},
+		{
+			Snapshot: "9be07d3",
+			Author:   git.Author{Name: "Bob"},
+			Date:     time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+			Message:  "Create payments/api",
+		},
+	}
+
+	var buf bytes.Buffer
+	printHistory(&buf, revisions)
+	want := "4f2a9c1 2024-03-02 Alice Update payments/api\n" +
+		"9be07d3 2024-03-01 Bob Create payments/api\n"
+	if got := buf.String(); got != want {
+		t.Errorf("printHistory() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHistoryCmd_IncludeDeletedNeedsFile(t *testing.T) {
+	c := &HistoryCmd{Project: "payments/api", IncludeDeleted: true}
+	if err := c.Run(&GlobalOptions{}, testContext()); err == nil {
+		t.Error("Run() with --include-deleted and no --file error = nil, want error")
+	}
+}
@@ -17,18 +17,24 @@ import (
 
 // InitCmd initializes protato in a repository.
 type InitCmd struct {
-	Force          bool     `help:"Force overwrite existing configuration"`
-	Projects       []string `help:"Project patterns (glob) to find projects when auto-discover is disabled. Examples: payments/**, orders/v*. Only used when auto_discover=false." short:"p"`
-	Ignores        []string `help:"Ignore patterns (glob) to exclude projects or files. Examples: **/test/** (exclude test projects/files), deprecated/* (exclude deprecated projects). Works with both auto_discover=true (filter projects) and auto_discover=false (filter files)." short:"i"`
-	Service        string   `help:"Service name for registry namespacing" short:"s"`
-	OwnedDir       string   `help:"Directory for owned protos"`
-	VendorDir      string   `help:"Directory for consumed protos"`
-	SkipPrompts    bool     `help:"Skip interactive prompts and use defaults" short:"y"`
-	NoAutoDiscover bool     `help:"Disable auto-discovery of projects"`
+	Force           bool     `help:"Force overwrite existing configuration"`
+	Projects        []string `help:"Project patterns (glob) to find projects when auto-discover is disabled. Examples: payments/**, orders/v*. Only used when auto_discover=false." short:"p"`
+	Ignores         []string `help:"Ignore patterns (glob) to exclude projects or files. Examples: **/test/** (exclude test projects/files), deprecated/* (exclude deprecated projects). Works with both auto_discover=true (filter projects) and auto_discover=false (filter files)." short:"i"`
+	Service         string   `help:"Service name for registry namespacing" short:"s"`
+	OwnedDir        string   `help:"Directory for owned protos"`
+	VendorDir       string   `help:"Directory for consumed protos"`
+	SkipPrompts     bool     `help:"Skip interactive prompts and use defaults" short:"y"`
+	NoAutoDiscover  bool     `help:"Disable auto-discovery of projects"`
+	BareRegistry    string   `help:"Create a new empty registry at the given path or remote URL instead of a workspace" placeholder:"PATH|URL"`
+	VendorGitignore string   `enum:",ignore,track" default:"" help:"Keep the vendor dir in .gitignore (ignore) or out of it (track) on init and pull"`
 }
 
 // Run executes the init command.
 func (c *InitCmd) Run(globals *GlobalOptions, ctx context.Context) error {
+	if c.BareRegistry != "" {
+		return c.initBareRegistry(ctx)
+	}
+
 	// Get current Git repository
 	repo, err := GetCurrentRepo(ctx)
 	if err != nil {
@@ -69,6 +75,10 @@ func (c *InitCmd) Run(globals *GlobalOptions, ctx context.Context) error {
 		return err
 	}
 
+	if _, err := ws.UpdateGitignore(); err != nil {
+		return fmt.Errorf("update .gitignore: %w", err)
+	}
+
 	// Initialize registry cache if URL is provided
 	c.initRegistryCache(ctx, globals)
 
@@ -81,6 +91,35 @@ func (c *InitCmd) Run(globals *GlobalOptions, ctx context.Context) error {
 	return nil
 }
 
+// initBareRegistry creates a new registry repository with an initial commit.
+// For remote URLs the repository is built in a temporary directory and pushed.
+func (c *InitCmd) initBareRegistry(ctx context.Context) error {
+	req := &registry.InitRequest{Path: c.BareRegistry}
+	if utils.IsRemoteURL(c.BareRegistry) {
+		tmpDir, err := os.MkdirTemp("", "protato-registry-*")
+		if err != nil {
+			return fmt.Errorf("create temp dir: %w", err)
+		}
+		defer os.RemoveAll(tmpDir)
+
+		req.Path = tmpDir
+		req.Remote = c.BareRegistry
+	} else if _, err := os.Stat(c.BareRegistry); err == nil {
+		return fmt.Errorf("registry path already exists: %s", c.BareRegistry)
+	}
+
+	commit, err := registry.Init(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	logger.Log(ctx).Info().
+		Str("registry", c.BareRegistry).
+		Str("commit", commit.Short()).
+		Msg("Registry initialized successfully")
+	return nil
+}
+
 // gatherConfig collects configuration from flags or interactive prompts.
 func (c *InitCmd) gatherConfig(ctx context.Context, root string) (*local.Config, error) {
 	cfg := &local.Config{
@@ -90,9 +129,10 @@ func (c *InitCmd) gatherConfig(ctx context.Context, root string) (*local.Config,
 			Vendor: c.VendorDir,
 		},
 		// Auto-discover is enabled by default unless explicitly disabled
-		AutoDiscover: !c.NoAutoDiscover,
-		Projects:     c.Projects,
-		Ignores:      c.Ignores,
+		AutoDiscover:    !c.NoAutoDiscover,
+		Projects:        c.Projects,
+		Ignores:         c.Ignores,
+		VendorGitignore: c.VendorGitignore,
 	}
 
 	// Use interactive mode if not skipped
@@ -321,7 +361,7 @@ func (c *InitCmd) initRegistryCache(ctx context.Context, globals *GlobalOptions)
 
 	logger.Log(ctx).Info().Msg("Initializing registry cache")
 
-	_, err := registry.Open(ctx, globals.CacheDir, globals.RegistryURL)
+	_, err := registry.Open(ctx, globals.CacheDir, globals.RegistryURL, globals.registryOpenOptions())
 	if err != nil {
 		logger.Log(ctx).Warn().Err(err).Msg("Failed to initialize registry cache")
 	}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+
+	"github.com/rahulagarwal0605/protato/internal/local"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/internal/protoc"
+)
+
+// LintCmd checks owned protos against style rules.
+type LintCmd struct {
+	Projects []string `arg:"" optional:"" help:"Projects to lint (default: all owned projects)"`
+	Rules    []string `help:"Only run these rules (comma-separated, default: lint.rules from protato.yaml or all rules)" sep:","`
+	Disable  []string `help:"Rules to skip, besides lint.disable from protato.yaml (comma-separated)" sep:","`
+}
+
+// Run executes the lint command.
+func (c *LintCmd) Run(globals *GlobalOptions, ctx context.Context) error {
+	wctx, err := OpenWorkspaceContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	rules, err := lintRules(wctx.WS, c.Rules, c.Disable)
+	if err != nil {
+		return err
+	}
+
+	projects, err := resolveOwnedProjects(wctx.WS, c.Projects)
+	if err != nil {
+		return err
+	}
+
+	issues, files, err := lintOwnedProtos(ctx, wctx.WS, projects, rules)
+	if err != nil {
+		return err
+	}
+	if files == 0 {
+		logger.Log(ctx).Info().Msg("No proto files to lint")
+		return nil
+	}
+
+	reportLintIssues(globals.reporter(ctx), issues)
+	if len(issues) > 0 {
+		return fmt.Errorf("found %d lint issues", len(issues))
+	}
+
+	logger.Log(ctx).Info().Int("files", files).Msg("Lint passed")
+	return nil
+}
+
+// lintRules returns the enabled rules: the lint section of protato.yaml, with rules replaced
+// by the given ones when set and the given disabled rules skipped as well.
+func lintRules(ws local.WorkspaceInterface, rules, disable []string) (protoc.LintRules, error) {
+	cfg := ws.LintConfig()
+	if len(rules) == 0 {
+		rules = cfg.Rules
+	}
+	return protoc.ParseLintRules(rules, append(append([]string{}, cfg.Disable...), disable...))
+}
+
+// lintOwnedProtos lints the proto files of owned projects and returns the issues found and
+// the number of files linted. Files are compiled against the owned and vendor directories.
+func lintOwnedProtos(ctx context.Context, ws local.WorkspaceInterface, projects []local.ProjectPath, rules protoc.LintRules) ([]protoc.LintIssue, int, error) {
+	files, owned, err := collectOwnedProtoFiles(ctx, ws, projects)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(files) == 0 {
+		return nil, 0, nil
+	}
+
+	vendor, err := ws.VendorRoots(ctx)
+	if err != nil {
+		vendor = nil // No vendor dir configured, that's OK
+	}
+
+	resolver := protoc.NewWorkspaceResolver(ctx, owned, vendor)
+	issues, err := protoc.LintProtos(ctx, resolver, files, rules)
+	if err != nil {
+		return nil, 0, fmt.Errorf("lint: %w", err)
+	}
+	return issues, len(files), nil
+}
+
+// reportLintIssues reports each issue as a diagnostic with its position and rule.
+func reportLintIssues(rep Reporter, issues []protoc.LintIssue) {
+	for _, issue := range issues {
+		rep.Diagnostic(Diagnostic{
+			Level:   zerolog.NoLevel,
+			File:    issue.File,
+			Line:    issue.Line,
+			Column:  issue.Column,
+			Rule:    issue.Rule,
+			Message: issue.String(),
+		})
+	}
+}
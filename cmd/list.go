@@ -3,28 +3,52 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"sort"
 
+	"github.com/rs/zerolog"
+
+	"github.com/rahulagarwal0605/protato/internal/git"
 	"github.com/rahulagarwal0605/protato/internal/local"
+	"github.com/rahulagarwal0605/protato/internal/logger"
 	"github.com/rahulagarwal0605/protato/internal/registry"
 )
 
 // ListCmd lists available projects.
 type ListCmd struct {
-	Local   bool `help:"List local projects instead of registry" short:"l"`
-	Offline bool `help:"Don't refresh registry"`
+	Local     bool   `help:"List local projects instead of registry" short:"l"`
+	Offline   bool   `help:"Don't refresh registry"`
+	Branch    string `help:"List projects on a registry branch instead of the default snapshot"`
+	Porcelain bool   `help:"Print stable tab-separated output for scripts"`
+	Owner     string `help:"Only list registry projects owned by this repository URL"`
+	Format    string `help:"Output format: text, or json for an array of project objects" enum:"text,json" default:"text"`
+}
+
+// localProjectJSON is one local project in --format json output.
+type localProjectJSON struct {
+	Kind     string `json:"kind"` // "owned" or "pulled"
+	Project  string `json:"project"`
+	Snapshot string `json:"snapshot,omitempty"` // Registry snapshot a pulled project was received from
+}
+
+// registryProjectJSON is one registry project in --format json output.
+type registryProjectJSON struct {
+	Project       string `json:"project"`
+	RepositoryURL string `json:"repository_url"` // Repository that owns the project
+	Commit        string `json:"commit"`         // Commit of the owning repository that was pushed
 }
 
 // Run executes the list command.
 func (c *ListCmd) Run(globals *GlobalOptions, ctx context.Context) error {
 	if c.Local {
-		return c.listLocal(ctx)
+		return c.listLocal(ctx, globals)
 	}
 	return c.listRegistry(ctx, globals)
 }
 
 // listLocal lists projects in the local workspace.
-func (c *ListCmd) listLocal(ctx context.Context) error {
+func (c *ListCmd) listLocal(ctx context.Context, globals *GlobalOptions) error {
 	wctx, err := OpenWorkspaceContext(ctx)
 	if err != nil {
 		return err
@@ -40,44 +64,92 @@ func (c *ListCmd) listLocal(ctx context.Context) error {
 		return fmt.Errorf("get received projects: %w", err)
 	}
 
-	c.printLocalProjects(owned, received)
+	if c.Format == formatJSON {
+		return writeJSON(os.Stdout, localProjectRecords(owned, received))
+	}
+	if c.Porcelain {
+		writeLocalPorcelain(os.Stdout, owned, received)
+		return nil
+	}
+	c.printLocalProjects(globals.reporter(ctx), owned, received)
 	return nil
 }
 
-// printLocalProjects prints owned and received projects.
-func (c *ListCmd) printLocalProjects(owned []local.ProjectPath, received []*local.ReceivedProject) {
-	if len(owned) > 0 {
-		fmt.Println("Owned projects:")
-		for _, p := range owned {
-			fmt.Printf("  %s\n", p)
-		}
+// writeLocalPorcelain writes one record per local project.
+// Columns: kind ("owned" or "pulled"), project, snapshot (empty for owned projects).
+func writeLocalPorcelain(w io.Writer, owned []local.ProjectPath, received []*local.ReceivedProject) {
+	for _, p := range owned {
+		writePorcelainRecord(w, "owned", string(p), "")
+	}
+	for _, r := range received {
+		writePorcelainRecord(w, "pulled", string(r.Project), r.ProviderSnapshot)
 	}
+}
 
-	if len(received) > 0 {
-		fmt.Println("Pulled projects:")
-		for _, r := range received {
-			fmt.Printf("  %s (snapshot: %s)\n", r.Project, r.ProviderSnapshot[:7])
-		}
+// localProjectRecords describes owned and received projects for JSON output.
+func localProjectRecords(owned []local.ProjectPath, received []*local.ReceivedProject) []localProjectJSON {
+	records := make([]localProjectJSON, 0, len(owned)+len(received))
+	for _, p := range owned {
+		records = append(records, localProjectJSON{Kind: ProjectKindOwned, Project: string(p)})
+	}
+	for _, r := range received {
+		records = append(records, localProjectJSON{Kind: ProjectKindPulled, Project: string(r.Project), Snapshot: r.ProviderSnapshot})
+	}
+	return records
+}
+
+// printLocalProjects reports owned and received projects.
+func (c *ListCmd) printLocalProjects(rep Reporter, owned []local.ProjectPath, received []*local.ReceivedProject) {
+	for _, p := range owned {
+		rep.ProjectListed(ListedProject{Kind: ProjectKindOwned, Project: string(p)})
+	}
+
+	for _, r := range received {
+		rep.ProjectListed(ListedProject{Kind: ProjectKindPulled, Project: string(r.Project), Snapshot: git.Hash(r.ProviderSnapshot)})
 	}
 
 	if len(owned) == 0 && len(received) == 0 {
-		fmt.Println("No projects found")
+		rep.Diagnostic(Diagnostic{Level: zerolog.NoLevel, Message: "No projects found"})
 	}
 }
 
 // listRegistry lists projects from the remote registry.
 func (c *ListCmd) listRegistry(ctx context.Context, globals *GlobalOptions) error {
-	reg, err := OpenRegistryWithRefresh(ctx, globals, c.Offline)
+	reg, err := OpenRegistryWithRefresh(ctx, globals, c.Offline || c.Branch != "")
 	if err != nil {
 		return err
 	}
 
-	return c.printRegistryProjects(ctx, reg)
+	snapshot, err := c.resolveSnapshot(ctx, reg)
+	if err != nil {
+		return err
+	}
+
+	return c.printRegistryProjects(ctx, globals.reporter(ctx), reg, snapshot)
 }
 
-// printRegistryProjects lists and prints all projects from the registry.
-func (c *ListCmd) printRegistryProjects(ctx context.Context, reg registry.CacheInterface) error {
-	projects, err := reg.ListProjects(ctx, nil)
+// resolveSnapshot returns the snapshot of --branch, or "" for the default snapshot.
+func (c *ListCmd) resolveSnapshot(ctx context.Context, reg registry.CacheInterface) (git.Hash, error) {
+	if c.Branch == "" {
+		return "", nil
+	}
+
+	if !c.Offline {
+		if err := reg.RefreshBranch(ctx, c.Branch); err != nil {
+			logger.Log(ctx).Warn().Err(err).Str("branch", c.Branch).Msg("Failed to refresh registry branch")
+		}
+	}
+
+	snapshot, err := reg.BranchSnapshot(ctx, c.Branch)
+	if err != nil {
+		return "", fmt.Errorf("resolve branch: %w", err)
+	}
+	return snapshot, nil
+}
+
+// printRegistryProjects lists and reports all projects from the registry at a snapshot.
+func (c *ListCmd) printRegistryProjects(ctx context.Context, rep Reporter, reg registry.CacheInterface, snapshot git.Hash) error {
+	projects, err := reg.ListProjects(ctx, &registry.ListProjectsOptions{Snapshot: snapshot, Owner: c.Owner})
 	if err != nil {
 		return fmt.Errorf("list projects: %w", err)
 	}
@@ -88,13 +160,56 @@ func (c *ListCmd) printRegistryProjects(ctx context.Context, reg registry.CacheI
 	}
 	sort.Strings(projectStrings)
 
+	if c.Format == formatJSON {
+		records, err := registryProjectRecords(ctx, reg, projectStrings, snapshot)
+		if err != nil {
+			return err
+		}
+		return writeJSON(os.Stdout, records)
+	}
+
+	if c.Porcelain {
+		if snapshot == "" {
+			if snapshot, err = reg.Snapshot(ctx); err != nil {
+				return fmt.Errorf("get snapshot: %w", err)
+			}
+		}
+		writeRegistryPorcelain(os.Stdout, projectStrings, snapshot)
+		return nil
+	}
+
 	for _, p := range projectStrings {
-		fmt.Println(p)
+		rep.ProjectListed(ListedProject{Kind: ProjectKindRegistry, Project: p})
 	}
 
 	if len(projects) == 0 {
-		fmt.Println("No projects in registry")
+		rep.Diagnostic(Diagnostic{Level: zerolog.NoLevel, Message: "No projects in registry"})
 	}
 
 	return nil
 }
+
+// writeRegistryPorcelain writes one record per registry project.
+// Columns: project, snapshot the listing was read from.
+func writeRegistryPorcelain(w io.Writer, projects []string, snapshot git.Hash) {
+	for _, p := range projects {
+		writePorcelainRecord(w, p, string(snapshot))
+	}
+}
+
+// registryProjectRecords looks up the owner of each registry project at a snapshot for JSON output.
+func registryProjectRecords(ctx context.Context, reg registry.CacheInterface, projects []string, snapshot git.Hash) ([]registryProjectJSON, error) {
+	records := make([]registryProjectJSON, 0, len(projects))
+	for _, p := range projects {
+		res, err := reg.LookupProject(ctx, &registry.LookupProjectRequest{Path: p, Snapshot: snapshot})
+		if err != nil {
+			return nil, fmt.Errorf("look up %s: %w", p, err)
+		}
+		records = append(records, registryProjectJSON{
+			Project:       p,
+			RepositoryURL: res.Project.RepositoryURL,
+			Commit:        string(res.Project.Commit),
+		})
+	}
+	return records, nil
+}
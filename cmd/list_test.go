@@ -2,8 +2,10 @@ package cmd
 
 import (
 "bytes"
+"encoding/json"
 "io"
 "os"
+"reflect"
 "testing"
 
 "github.com/rahulagarwal0605/protato/internal/local"
@@ -53,7 +55,7 @@ r, w, _ := os.Pipe()
 			os.Stdout = w
 
 			cmd := &ListCmd{}
-			cmd.printLocalProjects(tt.owned, tt.received)
+			cmd.printLocalProjects(newConsoleReporter(testContext(), os.Stdout), tt.owned, tt.received)
 
 			w.Close()
 			os.Stdout = oldStdout
@@ -70,3 +72,78 @@ r, w, _ := os.Pipe()
 		})
 	}
 }
+
+func TestWriteLocalPorcelain(t *testing.T) {
+	owned := []local.ProjectPath{"team/service1", "team/service2"}
+	received := []*local.ReceivedProject{
+		{Project: "other/service", ProviderSnapshot: "abc123def456"},
+	}
+
+	var buf bytes.Buffer
+	writeLocalPorcelain(&buf, owned, received)
+
+	want := "owned\tteam/service1\t\n" +
+		"owned\tteam/service2\t\n" +
+		"pulled\tother/service\tabc123def456\n"
+	if buf.String() != want {
+		t.Errorf("writeLocalPorcelain() = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestLocalProjectRecordsJSON(t *testing.T) {
+	owned := []local.ProjectPath{"team/service1"}
+	received := []*local.ReceivedProject{
+		{Project: "other/service", ProviderSnapshot: "abc123def456"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, localProjectRecords(owned, received)); err != nil {
+		t.Fatalf("writeJSON() error = %v", err)
+	}
+
+	var got []map[string]string
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal %q: %v", buf.String(), err)
+	}
+	want := []map[string]string{
+		{"kind": "owned", "project": "team/service1"},
+		{"kind": "pulled", "project": "other/service", "snapshot": "abc123def456"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("JSON = %v, want %v", got, want)
+	}
+
+	buf.Reset()
+	writeJSON(&buf, localProjectRecords(nil, nil))
+	if buf.String() != "[]\n" {
+		t.Errorf("JSON for no projects = %q, want an empty array", buf.String())
+	}
+}
+
+func TestWriteRegistryPorcelain(t *testing.T) {
+	tests := []struct {
+		name     string
+		projects []string
+		want     string
+	}{
+		{
+			name:     "projects",
+			projects: []string{"team/a", "team/b"},
+			want:     "team/a\tabc123\nteam/b\tabc123\n",
+		},
+		{
+			name: "empty registry prints nothing",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writeRegistryPorcelain(&buf, tt.projects, "abc123")
+			if buf.String() != tt.want {
+				t.Errorf("writeRegistryPorcelain() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
 	"sort"
 
@@ -12,8 +14,17 @@ import (
 
 // MineCmd lists files owned by this repository.
 type MineCmd struct {
-	Projects bool `help:"List project paths only" short:"p"`
-	Absolute bool `help:"Print absolute paths" short:"a"`
+	Projects  bool   `help:"List project paths only" short:"p"`
+	Absolute  bool   `help:"Print absolute paths" short:"a"`
+	Porcelain bool   `help:"Print stable tab-separated output for scripts"`
+	Local     bool   `help:"Show project paths without the service prefix" aliases:"strip-prefix"`
+	Format    string `help:"Output format: text, or json for an array of projects with their files" enum:"text,json" default:"text"`
+}
+
+// ownedProjectJSON is one owned project in --format json output.
+type ownedProjectJSON struct {
+	Project string   `json:"project"`
+	Files   []string `json:"files,omitempty"` // Omitted with --projects
 }
 
 // Run executes the mine command.
@@ -28,16 +39,29 @@ func (c *MineCmd) Run(globals *GlobalOptions, ctx context.Context) error {
 		return fmt.Errorf("get owned projects: %w", err)
 	}
 
+	if c.Format == formatJSON {
+		return writeJSON(os.Stdout, c.projectRecords(ctx, wctx.WS, wctx.Repo.Root(), projects))
+	}
+
 	if c.Projects {
-		for _, p := range projects {
-			fmt.Println(p)
-		}
+		c.writeProjects(os.Stdout, wctx.WS, projects)
 		return nil
 	}
 
+	if c.Porcelain {
+		c.writeFilesPorcelain(ctx, os.Stdout, wctx.WS, wctx.Repo.Root(), projects)
+		return nil
+	}
 	return c.printFiles(ctx, wctx, projects)
 }
 
+// writeProjects writes one project path per line.
+func (c *MineCmd) writeProjects(w io.Writer, ws local.WorkspaceInterface, projects []local.ProjectPath) {
+	for _, p := range projects {
+		fmt.Fprintln(w, c.formatProject(ws, p))
+	}
+}
+
 // printFiles lists and prints all files from owned projects.
 func (c *MineCmd) printFiles(ctx context.Context, wctx *WorkspaceContext, projects []local.ProjectPath) error {
 	var allFiles []string
@@ -63,6 +87,62 @@ func (c *MineCmd) printFiles(ctx context.Context, wctx *WorkspaceContext, projec
 	return nil
 }
 
+// writeFilesPorcelain writes one record per owned file, sorted by project then path.
+// Columns: project, file path (relative to the repository root unless --absolute).
+func (c *MineCmd) writeFilesPorcelain(ctx context.Context, w io.Writer, ws local.WorkspaceInterface, repoRoot string, projects []local.ProjectPath) {
+	sorted := append([]local.ProjectPath(nil), projects...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	for _, project := range sorted {
+		files, err := ws.ListOwnedProjectFiles(project)
+		if err != nil {
+			logger.Log(ctx).Warn().Err(err).Str("project", string(project)).Msg("Failed to list files")
+			continue
+		}
+
+		paths := make([]string, len(files))
+		for i, f := range files {
+			paths[i] = c.formatPath(f.AbsolutePath, repoRoot)
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			writePorcelainRecord(w, c.formatProject(ws, project), p)
+		}
+	}
+}
+
+// projectRecords describes owned projects and their files, sorted, for JSON output.
+// Files are left out with --projects.
+func (c *MineCmd) projectRecords(ctx context.Context, ws local.WorkspaceInterface, repoRoot string, projects []local.ProjectPath) []ownedProjectJSON {
+	sorted := append([]local.ProjectPath(nil), projects...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	records := make([]ownedProjectJSON, 0, len(sorted))
+	for _, project := range sorted {
+		record := ownedProjectJSON{Project: c.formatProject(ws, project)}
+		if !c.Projects {
+			files, err := ws.ListOwnedProjectFiles(project)
+			if err != nil {
+				logger.Log(ctx).Warn().Err(err).Str("project", string(project)).Msg("Failed to list files")
+			}
+			for _, f := range files {
+				record.Files = append(record.Files, c.formatPath(f.AbsolutePath, repoRoot))
+			}
+			sort.Strings(record.Files)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// formatProject formats a project path, dropping the service segment when --local is set.
+func (c *MineCmd) formatProject(ws local.WorkspaceInterface, project local.ProjectPath) string {
+	if c.Local {
+		return string(ws.LocalProjectPath(project))
+	}
+	return string(project)
+}
+
 // formatPath formats the file path based on the Absolute flag.
 func (c *MineCmd) formatPath(absPath, repoRoot string) string {
 	if c.Absolute {
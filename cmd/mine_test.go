@@ -1,7 +1,14 @@
 package cmd
 
 import (
+"bytes"
+"encoding/json"
+"os"
+"path/filepath"
+"reflect"
 "testing"
+
+"github.com/rahulagarwal0605/protato/internal/local"
 )
 
 func TestMineCmdFormatPath(t *testing.T) {
@@ -45,3 +52,145 @@ cmd := &MineCmd{Absolute: tt.absolute}
 		})
 	}
 }
+
+func TestMineCmdWriteFilesPorcelain(t *testing.T) {
+	root := t.TempDir()
+	ws, err := local.Init(testContext(), root, &local.Config{
+		Service:     "test-service",
+		Directories: local.DefaultDirectoryConfig(),
+	}, false)
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	for _, f := range []string{"proto/team/b/api.proto", "proto/team/a/z.proto", "proto/team/a/a.proto"} {
+		path := filepath.Join(root, f)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("syntax = \"proto3\";"), 0644)
+	}
+
+	tests := []struct {
+		name     string
+		absolute bool
+		want     string
+	}{
+		{
+			name: "relative paths",
+			want: "team/a\tproto/team/a/a.proto\n" +
+				"team/a\tproto/team/a/z.proto\n" +
+				"team/b\tproto/team/b/api.proto\n",
+		},
+		{
+			name:     "absolute paths",
+			absolute: true,
+			want: "team/a\t" + filepath.Join(root, "proto/team/a/a.proto") + "\n" +
+				"team/a\t" + filepath.Join(root, "proto/team/a/z.proto") + "\n" +
+				"team/b\t" + filepath.Join(root, "proto/team/b/api.proto") + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cmd := &MineCmd{Absolute: tt.absolute}
+			cmd.writeFilesPorcelain(testContext(), &buf, ws, root, []local.ProjectPath{"team/b", "team/a"})
+			if buf.String() != tt.want {
+				t.Errorf("writeFilesPorcelain() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestMineCmdWriteProjects(t *testing.T) {
+	ws, err := local.Init(testContext(), t.TempDir(), &local.Config{
+		Service:     "payments",
+		Directories: local.DefaultDirectoryConfig(),
+	}, false)
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	projects := []local.ProjectPath{"payments/api/v1", "team/service"}
+
+	tests := []struct {
+		name  string
+		local bool
+		want  string
+	}{
+		{
+			name: "prefix kept by default",
+			want: "payments/api/v1\nteam/service\n",
+		},
+		{
+			name:  "prefix stripped with --local",
+			local: true,
+			want:  "api/v1\nteam/service\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cmd := &MineCmd{Local: tt.local}
+			cmd.writeProjects(&buf, ws, projects)
+			if buf.String() != tt.want {
+				t.Errorf("writeProjects() = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestMineCmdProjectRecordsJSON(t *testing.T) {
+	root := t.TempDir()
+	ws, err := local.Init(testContext(), root, &local.Config{
+		Service:     "test-service",
+		Directories: local.DefaultDirectoryConfig(),
+	}, false)
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	for _, f := range []string{"proto/team/b/api.proto", "proto/team/a/z.proto", "proto/team/a/a.proto"} {
+		path := filepath.Join(root, f)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("syntax = \"proto3\";"), 0644)
+	}
+
+	type record struct {
+		Project string   `json:"project"`
+		Files   []string `json:"files"`
+	}
+	tests := []struct {
+		name     string
+		projects bool
+		want     []record
+	}{
+		{
+			name: "projects with files",
+			want: []record{
+				{Project: "team/a", Files: []string{"proto/team/a/a.proto", "proto/team/a/z.proto"}},
+				{Project: "team/b", Files: []string{"proto/team/b/api.proto"}},
+			},
+		},
+		{
+			name:     "files left out with --projects",
+			projects: true,
+			want:     []record{{Project: "team/a"}, {Project: "team/b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cmd := &MineCmd{Projects: tt.projects, Format: formatJSON}
+			if err := writeJSON(&buf, cmd.projectRecords(testContext(), ws, root, []local.ProjectPath{"team/b", "team/a"})); err != nil {
+				t.Fatalf("writeJSON() error = %v", err)
+			}
+
+			var got []record
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("unmarshal %q: %v", buf.String(), err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("JSON = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,15 +2,34 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/rs/zerolog"
+
+	protatoerrors "github.com/rahulagarwal0605/protato/internal/errors"
+	"github.com/rahulagarwal0605/protato/internal/local"
 	"github.com/rahulagarwal0605/protato/internal/logger"
 	"github.com/rahulagarwal0605/protato/internal/utils"
 )
 
 // NewCmd creates a new project (claim ownership).
 type NewCmd struct {
-	Paths []string `arg:"" required:"" help:"Project paths to create (e.g., team/service)"`
+	Paths            []string `arg:"" required:"" help:"Project paths to create (e.g., team/service)"`
+	DryRun           bool     `help:"Report whether each claim would succeed without changing anything"`
+	ExplainOwnership bool     `help:"On a claim conflict, print who owns the conflicting path and at which commit"`
+	From             string   `help:"Scaffold each project from a template: service, message or a directory of .proto templates"`
+}
+
+// claimReasons maps claim errors to the explanation shown by --dry-run.
+var claimReasons = []struct {
+	err         error
+	explanation string
+}{
+	{protatoerrors.ErrOwnershipConflict, "ownership conflict"},
+	{protatoerrors.ErrSubprojectConflict, "subproject conflict"},
+	{protatoerrors.ErrParentProjectExists, "parent project exists"},
+	{protatoerrors.ErrCaseConflict, "case conflict"},
 }
 
 // Run executes the new command.
@@ -29,6 +48,10 @@ func (c *NewCmd) Run(globals *GlobalOptions, ctx context.Context) error {
 		return err
 	}
 
+	if c.DryRun {
+		return c.planClaims(ctx, globals, wctx, repoURL)
+	}
+
 	if err := c.checkRegistryConflicts(ctx, globals, wctx, repoURL); err != nil {
 		return err
 	}
@@ -39,6 +62,23 @@ func (c *NewCmd) Run(globals *GlobalOptions, ctx context.Context) error {
 
 	logProjectCreationSuccess(ctx, wctx, c.Paths)
 
+	if c.From != "" {
+		return c.scaffoldProjects(ctx, wctx)
+	}
+	return nil
+}
+
+// scaffoldProjects creates the starter files of the --from template in each new project.
+func (c *NewCmd) scaffoldProjects(ctx context.Context, wctx *WorkspaceContext) error {
+	for _, p := range c.Paths {
+		files, err := wctx.WS.ScaffoldProject(local.ProjectPath(p), c.From)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			logger.Log(ctx).Info().Str("project", p).Str("file", f).Msg("Created file from template")
+		}
+	}
 	return nil
 }
 
@@ -82,9 +122,62 @@ func (c *NewCmd) checkRegistryConflicts(ctx context.Context, globals *GlobalOpti
 			return fmt.Errorf("get registry path for %s: %w", p, err)
 		}
 		if err := reg.CheckProjectClaim(ctx, snapshot, repoURL, string(registryPath)); err != nil {
+			if c.ExplainOwnership {
+				explainOwnership(globals.reporter(ctx), p, err)
+			}
 			return err
 		}
 	}
 
 	return nil
 }
+
+// planClaims checks every path against the registry and reports whether it could be claimed.
+// Nothing is written locally or to the registry.
+func (c *NewCmd) planClaims(ctx context.Context, globals *GlobalOptions, wctx *WorkspaceContext, repoURL string) error {
+	reg, err := OpenAndRefreshRegistry(ctx, globals)
+	if err != nil {
+		return err
+	}
+	defer reg.Close()
+
+	snapshot, err := reg.GetSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	rep := globals.reporter(ctx)
+	var failed int
+	for _, p := range c.Paths {
+		registryPath, err := wctx.WS.GetRegistryPath(p)
+		if err != nil {
+			return fmt.Errorf("get registry path for %s: %w", p, err)
+		}
+		claimErr := reg.CheckProjectClaim(ctx, snapshot, repoURL, string(registryPath))
+		if claimErr != nil {
+			failed++
+		}
+		rep.Diagnostic(Diagnostic{Level: zerolog.NoLevel, Project: p, Message: describeClaim(p, claimErr)})
+		if c.ExplainOwnership {
+			explainOwnership(rep, p, claimErr)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d claims would fail", failed, len(c.Paths))
+	}
+	return nil
+}
+
+// describeClaim returns the --dry-run line for a path given the result of its claim check.
+func describeClaim(path string, claimErr error) string {
+	if claimErr == nil {
+		return fmt.Sprintf("%s: claim would succeed", path)
+	}
+	for _, r := range claimReasons {
+		if errors.Is(claimErr, r.err) {
+			return fmt.Sprintf("%s: claim would fail (%s): %v", path, r.explanation, claimErr)
+		}
+	}
+	return fmt.Sprintf("%s: claim would fail: %v", path, claimErr)
+}
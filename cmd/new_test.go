@@ -1,8 +1,14 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"testing"
 
+	protatoerrors "github.com/rahulagarwal0605/protato/internal/errors"
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/registry"
 	"github.com/rahulagarwal0605/protato/internal/utils"
 )
 
@@ -92,3 +98,89 @@ func TestNewCmdValidatePathsLogic(t *testing.T) {
 		})
 	}
 }
+
+func TestDescribeClaim(t *testing.T) {
+	tests := []struct {
+		name     string
+		claimErr error
+		want     string
+	}{
+		{
+			name: "claim succeeds",
+			want: "team/service: claim would succeed",
+		},
+		{
+			name:     "ownership conflict",
+			claimErr: &registry.ClaimError{Reason: protatoerrors.ErrOwnershipConflict, Message: "project \"team/service\" is owned by other"},
+			want:     "team/service: claim would fail (ownership conflict): project \"team/service\" is owned by other",
+		},
+		{
+			name:     "subproject conflict",
+			claimErr: &registry.ClaimError{Reason: protatoerrors.ErrSubprojectConflict, Message: "overlaps with existing projects"},
+			want:     "team/service: claim would fail (subproject conflict): overlaps with existing projects",
+		},
+		{
+			name:     "parent exists",
+			claimErr: fmt.Errorf("check: %w", &registry.ClaimError{Reason: protatoerrors.ErrParentProjectExists, Message: "parent project \"team\" already exists"}),
+			want:     "team/service: claim would fail (parent project exists): check: parent project \"team\" already exists",
+		},
+		{
+			name:     "case conflict",
+			claimErr: &registry.ClaimError{Reason: protatoerrors.ErrCaseConflict, Message: "conflicts with existing project \"Team/Service\" by case"},
+			want:     "team/service: claim would fail (case conflict): conflicts with existing project \"Team/Service\" by case",
+		},
+		{
+			name:     "other error",
+			claimErr: errors.New("lookup project: boom"),
+			want:     "team/service: claim would fail: lookup project: boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeClaim("team/service", tt.claimErr); got != tt.want {
+				t.Errorf("describeClaim() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExplainOwnership(t *testing.T) {
+	owner := &registry.Project{
+		RepositoryURL: "github.com/org/other",
+		Commit:        git.Hash("0123456789abcdef0123456789abcdef01234567"),
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "owner known",
+			err: fmt.Errorf("check: %w", &registry.ClaimError{
+				Reason: protatoerrors.ErrOwnershipConflict,
+				Owner:  owner,
+			}),
+			want: "team/service: owned by github.com/org/other at 0123456; contact them or choose another path\n",
+		},
+		{
+			name: "owner unknown",
+			err:  &registry.ClaimError{Reason: protatoerrors.ErrCaseConflict},
+		},
+		{
+			name: "not a claim error",
+			err:  errors.New("boom"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			explainOwnership(newConsoleReporter(testContext(), &buf), "team/service", tt.err)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// Profile kinds accepted by --profile.
+const (
+	ProfileCPU    = "cpu"
+	ProfileMemory = "mem"
+)
+
+// StartProfile starts profiling protato itself and returns a function that
+// stops it and writes the profile to path. An empty kind disables profiling.
+// The stop function must be called on every exit path, including errors,
+// or the profile is left empty.
+func StartProfile(kind, path string) (func() error, error) {
+	if kind == "" {
+		return func() error { return nil }, nil
+	}
+	if kind != ProfileCPU && kind != ProfileMemory {
+		return nil, fmt.Errorf("unknown profile kind %q (want %s or %s)", kind, ProfileCPU, ProfileMemory)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create profile: %w", err)
+	}
+
+	if kind == ProfileCPU {
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("start cpu profile: %w", err)
+		}
+		return func() error {
+			pprof.StopCPUProfile()
+			return f.Close()
+		}, nil
+	}
+
+	return func() error {
+		runtime.GC() // Get up-to-date allocation statistics
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			f.Close()
+			return fmt.Errorf("write heap profile: %w", err)
+		}
+		return f.Close()
+	}, nil
+}
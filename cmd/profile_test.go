@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStartProfile(t *testing.T) {
+	for _, kind := range []string{ProfileCPU, ProfileMemory} {
+		t.Run(kind, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "protato.pprof")
+
+			stop, err := StartProfile(kind, path)
+			if err != nil {
+				t.Fatalf("StartProfile() error = %v", err)
+			}
+
+			// A trivial command that fails still produces a profile once stopped
+			oldWd, _ := os.Getwd()
+			defer os.Chdir(oldWd)
+			os.Chdir(dir)
+			list := &ListCmd{Local: true}
+			if err := list.Run(&GlobalOptions{}, testContext()); err == nil {
+				t.Fatal("ListCmd.Run() outside a workspace succeeded")
+			}
+
+			if err := stop(); err != nil {
+				t.Fatalf("stop() error = %v", err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("profile not written: %v", err)
+			}
+			if info.Size() == 0 {
+				t.Error("profile is empty")
+			}
+		})
+	}
+}
+
+func TestStartProfile_Disabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "protato.pprof")
+
+	stop, err := StartProfile("", path)
+	if err != nil {
+		t.Fatalf("StartProfile() error = %v", err)
+	}
+	if err := stop(); err != nil {
+		t.Fatalf("stop() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("profile written with profiling disabled: %v", err)
+	}
+}
+
+func TestStartProfile_UnknownKind(t *testing.T) {
+	if _, err := StartProfile("block", filepath.Join(t.TempDir(), "protato.pprof")); err == nil {
+		t.Error("StartProfile() expected error for unknown kind")
+	}
+}
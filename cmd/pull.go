@@ -2,8 +2,15 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
 
+	"github.com/rs/zerolog"
+
+	protatoerrors "github.com/rahulagarwal0605/protato/internal/errors"
 	"github.com/rahulagarwal0605/protato/internal/git"
 	"github.com/rahulagarwal0605/protato/internal/local"
 	"github.com/rahulagarwal0605/protato/internal/logger"
@@ -14,9 +21,18 @@ import (
 
 // PullCmd downloads projects from registry.
 type PullCmd struct {
-	Projects []string `arg:"" optional:"" help:"Projects to pull"`
-	Force    bool     `help:"Force pull even if files would be deleted" short:"f"`
-	NoDeps   bool     `help:"Don't pull dependencies"`
+	Projects        []string `arg:"" optional:"" predictor:"project" help:"Projects to pull, each optionally pinned to a registry tag or commit (e.g., team/service@v1.2.0)"`
+	Force           bool     `help:"Force pull even if files would be deleted" short:"f"`
+	NoDeps          bool     `help:"Don't pull dependencies"`
+	UpdateAll       bool     `help:"Update every received project to the latest registry snapshot"`
+	Prune           bool     `help:"With --update-all, delete received projects and files the registry has removed"`
+	NoGitattributes bool     `help:"Don't write .gitattributes into received projects"`
+	RefAsBranch     string   `help:"Pull from a registry branch and track it in the lock file for later updates" placeholder:"BRANCH"`
+	OutputDir       string   `help:"Export projects into this directory instead of the vendor dir, without updating lock files" type:"path"`
+	SinceSnapshot   string   `help:"Only re-pull received projects that changed since this registry snapshot" placeholder:"HASH"`
+	BufYAML         bool     `name:"buf-yaml" help:"Add the vendor dir to buf.yaml so buf build and lint include received projects"`
+	LockOnly        bool     `help:"Only advance protato.lock to the latest snapshot; fails if any received file differs from it"`
+	CheckOnly       bool     `help:"List received projects the registry has changed or removed since they were pulled, without pulling"`
 }
 
 // pullCtx represents the context for pulling a project.
@@ -26,8 +42,40 @@ type pullCtx struct {
 	toDelete []string
 }
 
+// pullSource is where a group of projects is pulled from. The zero value is the default snapshot.
+type pullSource struct {
+	branch string // Tracked registry branch
+	ref    string // Pinned registry tag or commit
+}
+
+// pullBatch holds the projects pulled from one registry snapshot.
+type pullBatch struct {
+	branch   string // Tracked registry branch; "" for the default snapshot
+	ref      string // Pinned registry tag or commit the snapshot was resolved from
+	snapshot git.Hash
+	contexts []pullCtx
+}
+
 // Run executes the pull command.
 func (c *PullCmd) Run(globals *GlobalOptions, ctx context.Context) error {
+	if c.UpdateAll && len(c.Projects) > 0 {
+		return fmt.Errorf("--update-all cannot be combined with project arguments")
+	}
+	if c.Prune && !c.UpdateAll {
+		return fmt.Errorf("--prune needs --update-all")
+	}
+	if c.LockOnly && c.OutputDir != "" {
+		return fmt.Errorf("--lock-only cannot be combined with --output-dir")
+	}
+	if c.CheckOnly && (c.LockOnly || c.OutputDir != "" || c.UpdateAll) {
+		return fmt.Errorf("--check-only cannot be combined with --lock-only, --output-dir or --update-all")
+	}
+	for _, arg := range c.Projects {
+		if _, ref := parseProjectRef(arg); ref != "" && (c.RefAsBranch != "" || c.CheckOnly) {
+			return fmt.Errorf("%s: a pinned project cannot be combined with --ref-as-branch or --check-only", arg)
+		}
+	}
+
 	wctx, err := OpenWorkspaceContext(ctx)
 	if err != nil {
 		return err
@@ -37,50 +85,308 @@ func (c *PullCmd) Run(globals *GlobalOptions, ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	defer reg.Close()
+
+	rep := globals.reporter(ctx)
+	if c.CheckOnly {
+		return c.reportStale(ctx, rep, wctx.WS, reg)
+	}
 
-	snapshot, err := reg.GetSnapshot(ctx)
+	var removed map[registry.ProjectPath]bool
+	if c.Prune {
+		if removed, err = c.findRemovedProjects(ctx, wctx.WS, reg); err != nil {
+			return err
+		}
+	}
+
+	batches, err := c.planPull(ctx, wctx.WS, reg, removed)
 	if err != nil {
 		return err
 	}
-	logger.Log(ctx).Debug().Str("snapshot", snapshot.Short()).Msg("Using registry snapshot")
 
-	projectsToPull, err := c.resolveProjects(ctx, wctx.WS, reg, snapshot)
+	if len(batches) == 0 && len(removed) == 0 {
+		rep.Diagnostic(Diagnostic{Level: zerolog.InfoLevel, Message: "No projects to pull"})
+		return c.updateWorkspaceFiles(rep, wctx.WS)
+	}
+
+	progress := &progressCounter{rep: rep, command: "pull"}
+	for _, batch := range batches {
+		progress.total += len(batch.contexts)
+	}
+	for _, batch := range batches {
+		if err := c.executePull(ctx, rep, progress, wctx.WS, reg, batch); err != nil {
+			return err
+		}
+	}
+	if err := c.pruneProjects(rep, wctx.WS, removed); err != nil {
+		return err
+	}
+	return c.updateWorkspaceFiles(rep, wctx.WS)
+}
+
+// findRemovedProjects returns the received projects the registry has removed, at the current
+// snapshot or at the tip of the branch they track. Local and pinned projects are never removed.
+func (c *PullCmd) findRemovedProjects(ctx context.Context, ws local.WorkspaceInterface, reg registry.CacheInterface) (map[registry.ProjectPath]bool, error) {
+	received, err := ws.ReceivedProjects(ctx)
 	if err != nil {
+		return nil, fmt.Errorf("list received projects: %w", err)
+	}
+
+	removed := make(map[registry.ProjectPath]bool)
+	refreshed := make(map[string]bool)
+	for _, p := range received {
+		if p.IsLocal() || p.Ref != "" {
+			continue
+		}
+		if p.Branch != "" && !refreshed[p.Branch] {
+			if err := reg.RefreshBranch(ctx, p.Branch); err != nil {
+				return nil, fmt.Errorf("refresh branch %s: %w", p.Branch, err)
+			}
+			refreshed[p.Branch] = true
+		}
+
+		_, _, err := ws.IsReceivedProjectStale(ctx, p.Project, reg)
+		switch {
+		case errors.Is(err, protatoerrors.ErrNotFound):
+			removed[registry.ProjectPath(p.Project)] = true
+		case err != nil:
+			return nil, err
+		}
+	}
+	return removed, nil
+}
+
+// pruneProjects deletes the received projects the registry has removed.
+func (c *PullCmd) pruneProjects(rep Reporter, ws local.WorkspaceInterface, removed map[registry.ProjectPath]bool) error {
+	projects := make([]registry.ProjectPath, 0, len(removed))
+	for p := range removed {
+		projects = append(projects, p)
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i] < projects[j] })
+
+	for _, p := range projects {
+		if err := ws.RemoveReceivedProject(local.ProjectPath(p)); err != nil {
+			return fmt.Errorf("prune %s: %w", p, err)
+		}
+		rep.Diagnostic(Diagnostic{Level: zerolog.InfoLevel, Project: string(p), Message: fmt.Sprintf("Pruned %s, removed from the registry", p)})
+	}
+	return nil
+}
+
+// reportStale lists the received projects the registry has changed or removed since their lock,
+// or only the given projects when there are any.
+func (c *PullCmd) reportStale(ctx context.Context, rep Reporter, ws local.WorkspaceInterface, reg registry.CacheInterface) error {
+	received, err := ws.ReceivedProjects(ctx)
+	if err != nil {
+		return fmt.Errorf("list received projects: %w", err)
+	}
+	wanted := utils.SliceToMap(c.Projects, func(p string) string { return p })
+
+	refreshed := make(map[string]bool)
+	found := 0
+	for _, p := range received {
+		if p.IsLocal() || (len(wanted) > 0 && !wanted[string(p.Project)]) {
+			continue
+		}
+		if p.Branch != "" && !refreshed[p.Branch] {
+			if err := reg.RefreshBranch(ctx, p.Branch); err != nil {
+				return fmt.Errorf("refresh branch %s: %w", p.Branch, err)
+			}
+			refreshed[p.Branch] = true
+		}
+
+		stale, snapshot, err := ws.IsReceivedProjectStale(ctx, p.Project, reg)
+		switch {
+		case errors.Is(err, protatoerrors.ErrNotFound):
+			rep.Diagnostic(Diagnostic{Level: zerolog.NoLevel, Project: string(p.Project), Message: fmt.Sprintf("%s: removed from the registry", p.Project)})
+			found++
+		case err != nil:
+			return err
+		case stale:
+			rep.Diagnostic(Diagnostic{Level: zerolog.NoLevel, Project: string(p.Project), Message: fmt.Sprintf("%s: stale, locked at %s, registry has %s", p.Project, git.Hash(p.ProviderSnapshot).Short(), snapshot.Short())})
+			found++
+		}
+	}
+
+	if found == 0 {
+		rep.Diagnostic(Diagnostic{Level: zerolog.InfoLevel, Message: "Received projects are up to date"})
+	}
+	return nil
+}
+
+// updateWorkspaceFiles updates the root buf.yaml and .gitignore for the vendor dir.
+// Exports to --output-dir leave both alone, since the vendor dir was not written.
+func (c *PullCmd) updateWorkspaceFiles(rep Reporter, ws local.WorkspaceInterface) error {
+	if c.OutputDir != "" {
+		return nil
+	}
+	if err := c.updateBufConfig(rep, ws); err != nil {
 		return err
 	}
+	return c.updateGitignore(rep, ws)
+}
 
-	if len(projectsToPull) == 0 {
-		logger.Log(ctx).Info().Msg("No projects to pull")
+// updateBufConfig adds the vendor dir to buf.yaml when --buf-yaml is set.
+func (c *PullCmd) updateBufConfig(rep Reporter, ws local.WorkspaceInterface) error {
+	if !c.BufYAML {
 		return nil
 	}
+	changed, err := ws.UpdateBufConfig()
+	if err != nil {
+		return fmt.Errorf("update buf.yaml: %w", err)
+	}
+	if changed {
+		rep.Diagnostic(Diagnostic{Level: zerolog.InfoLevel, Message: "Added vendor dir to buf.yaml"})
+	}
+	return nil
+}
 
-	contexts, err := c.createPullContexts(ctx, wctx.WS, reg, snapshot, projectsToPull)
+// updateGitignore adds or removes the vendor dir in .gitignore as vendor_gitignore asks.
+func (c *PullCmd) updateGitignore(rep Reporter, ws local.WorkspaceInterface) error {
+	changed, err := ws.UpdateGitignore()
 	if err != nil {
-		return err
+		return fmt.Errorf("update .gitignore: %w", err)
+	}
+	if changed {
+		rep.Diagnostic(Diagnostic{Level: zerolog.InfoLevel, Message: "Updated vendor dir entry in .gitignore"})
+	}
+	return nil
+}
+
+// planPull resolves the projects to pull, grouped by the registry branch or ref they follow.
+// All pull contexts are created before anything is written, so a refused deletion aborts the whole pull.
+// Received projects in pruned are left out, as the registry has removed them.
+func (c *PullCmd) planPull(ctx context.Context, ws local.WorkspaceInterface, reg registry.CacheInterface, pruned map[registry.ProjectPath]bool) ([]pullBatch, error) {
+	initial := c.getInitialProjects(ctx, ws, pruned)
+
+	assigned := make(map[registry.ProjectPath]pullSource)
+	for source, projects := range initial {
+		for _, p := range projects {
+			assigned[p] = source
+		}
+	}
+
+	var batches []pullBatch
+	planned := make(map[registry.ProjectPath]bool)
+	for _, source := range sortedPullSources(initial) {
+		snapshot, err := c.resolveSnapshot(ctx, reg, source)
+		if err != nil {
+			return nil, err
+		}
+		logger.Log(ctx).Debug().Str("branch", source.branch).Str("ref", source.ref).Str("snapshot", snapshot.Short()).Msg("Using registry snapshot")
+
+		var projects []registry.ProjectPath
+		for _, p := range c.resolveProjects(ctx, ws, reg, snapshot, initial[source]) {
+			// Dependencies that follow another branch or ref are pulled with it
+			if s, ok := assigned[p]; (ok && s != source) || planned[p] {
+				continue
+			}
+			planned[p] = true
+			projects = append(projects, p)
+		}
+		if c.SinceSnapshot != "" {
+			projects, err = c.filterUnchangedProjects(ctx, ws, reg, snapshot, projects)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(projects) == 0 {
+			continue
+		}
+
+		contexts, err := c.createPullContexts(ctx, ws, reg, snapshot, projects)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, pullBatch{branch: source.branch, ref: source.ref, snapshot: snapshot, contexts: contexts})
 	}
 
-	return c.executePull(ctx, wctx.WS, reg, snapshot, contexts)
+	return batches, nil
+}
+
+// sortedPullSources returns the sources of initial ordered by branch, then ref, so pulls are deterministic.
+func sortedPullSources(initial map[pullSource][]registry.ProjectPath) []pullSource {
+	sources := make([]pullSource, 0, len(initial))
+	for source := range initial {
+		sources = append(sources, source)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if sources[i].branch != sources[j].branch {
+			return sources[i].branch < sources[j].branch
+		}
+		return sources[i].ref < sources[j].ref
+	})
+	return sources
 }
 
-// resolveProjects determines which projects need to be pulled.
-func (c *PullCmd) resolveProjects(ctx context.Context, ws local.WorkspaceInterface, reg registry.CacheInterface, snapshot git.Hash) ([]registry.ProjectPath, error) {
-	projectsToPull := c.getInitialProjects(ctx, ws)
+// filterUnchangedProjects drops received projects whose registry tree is the same at
+// --since-snapshot and snapshot. Projects not received yet are always kept.
+func (c *PullCmd) filterUnchangedProjects(ctx context.Context, ws local.WorkspaceInterface, reg registry.CacheInterface, snapshot git.Hash, projects []registry.ProjectPath) ([]registry.ProjectPath, error) {
+	changed, err := reg.SnapshotDiff(ctx, git.Hash(c.SinceSnapshot), snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("diff since snapshot %s: %w", c.SinceSnapshot, err)
+	}
+	changedSet := utils.SliceToMap(changed, func(p registry.ProjectPath) string { return string(p) })
+
+	var filtered []registry.ProjectPath
+	for _, p := range projects {
+		if !changedSet[string(p)] && c.previousSnapshot(ws, p) != "" {
+			logger.Log(ctx).Debug().Str("project", string(p)).Msg("Project unchanged since snapshot, skipping")
+			continue
+		}
+		filtered = append(filtered, p)
+	}
+	return filtered, nil
+}
+
+// resolveSnapshot returns the commit a pinned ref points to, the tip of a tracked registry
+// branch, or the default snapshot for the zero source.
+func (c *PullCmd) resolveSnapshot(ctx context.Context, reg registry.CacheInterface, source pullSource) (git.Hash, error) {
+	if source.ref != "" {
+		snapshot, err := reg.ResolveRef(ctx, source.ref)
+		if err != nil {
+			return "", fmt.Errorf("resolve ref %s: %w", source.ref, err)
+		}
+		return snapshot, nil
+	}
+	branch := source.branch
+	if branch == "" {
+		return reg.GetSnapshot(ctx)
+	}
+
+	if err := reg.RefreshBranch(ctx, branch); err != nil {
+		return "", fmt.Errorf("refresh branch %s: %w", branch, err)
+	}
+	snapshot, err := reg.BranchSnapshot(ctx, branch)
+	if err != nil {
+		return "", fmt.Errorf("resolve branch %s: %w", branch, err)
+	}
+	return snapshot, nil
+}
+
+// resolveProjects adds dependencies to the given projects and drops owned ones.
+func (c *PullCmd) resolveProjects(ctx context.Context, ws local.WorkspaceInterface, reg registry.CacheInterface, snapshot git.Hash, projectsToPull []registry.ProjectPath) []registry.ProjectPath {
 	ownedPaths := c.buildOwnedPathsSet(ws)
 
 	if !c.NoDeps && len(projectsToPull) > 0 {
 		projectsToPull = c.discoverDependencies(ctx, reg, snapshot, projectsToPull)
 	}
 
-	return c.filterOwnedProjects(projectsToPull, ownedPaths), nil
+	return c.filterOwnedProjects(projectsToPull, ownedPaths)
 }
 
-
-// getInitialProjects returns the initial list of projects to pull.
-func (c *PullCmd) getInitialProjects(ctx context.Context, ws local.WorkspaceInterface) []registry.ProjectPath {
+// getInitialProjects returns the initial projects to pull, keyed by the registry branch or ref
+// they follow. --ref-as-branch applies to every project being pulled; received projects pinned
+// to a ref stay on it.
+func (c *PullCmd) getInitialProjects(ctx context.Context, ws local.WorkspaceInterface, pruned map[registry.ProjectPath]bool) map[pullSource][]registry.ProjectPath {
 	if len(c.Projects) > 0 {
-		return utils.ConvertSlice(c.Projects, func(p string) registry.ProjectPath {
-			return registry.ProjectPath(p)
-		})
+		projects := make(map[pullSource][]registry.ProjectPath)
+		for _, arg := range c.Projects {
+			project, ref := parseProjectRef(arg)
+			source := pullSource{branch: c.RefAsBranch, ref: ref}
+			projects[source] = append(projects[source], registry.ProjectPath(project))
+		}
+		return projects
 	}
 
 	received, err := ws.ReceivedProjects(ctx)
@@ -89,9 +395,30 @@ func (c *PullCmd) getInitialProjects(ctx context.Context, ws local.WorkspaceInte
 		return nil
 	}
 
-	return utils.ConvertSlice(received, func(r *local.ReceivedProject) registry.ProjectPath {
-		return registry.ProjectPath(r.Project)
-	})
+	projects := make(map[pullSource][]registry.ProjectPath)
+	for _, r := range received {
+		// Projects vendored from a local directory have no registry counterpart
+		if r.IsLocal() {
+			logger.Log(ctx).Debug().Str("project", string(r.Project)).Msg("Skipping locally received project")
+			continue
+		}
+		if pruned[registry.ProjectPath(r.Project)] {
+			continue
+		}
+		source := pullSource{branch: r.Branch, ref: r.Ref}
+		if c.RefAsBranch != "" {
+			source = pullSource{branch: c.RefAsBranch}
+		}
+		projects[source] = append(projects[source], registry.ProjectPath(r.Project))
+	}
+	return projects
+}
+
+// parseProjectRef splits a project argument of the form project@ref into the project
+// and the registry tag or commit it is pinned to ("" when unpinned).
+func parseProjectRef(arg string) (project, ref string) {
+	project, ref, _ = strings.Cut(arg, "@")
+	return project, ref
 }
 
 // buildOwnedPathsSet builds a set of owned project paths.
@@ -118,6 +445,12 @@ func (c *PullCmd) discoverDependencies(ctx context.Context, reg registry.CacheIn
 	logger.Log(ctx).Info().Msg("Discovering dependencies")
 
 	allProjects, err := protoc.DiscoverDependencies(ctx, reg, snapshot, projects)
+	var cycleErr *protoc.CycleError
+	if errors.As(err, &cycleErr) {
+		// Every project was still discovered; the cycle only matters to their owners
+		logger.Log(ctx).Warn().Err(err).Msg("Dependencies import each other in a cycle")
+		return allProjects
+	}
 	if err != nil {
 		logger.Log(ctx).Warn().Err(err).Msg("Failed to discover dependencies")
 		return projects
@@ -142,12 +475,24 @@ func (c *PullCmd) createPullContexts(ctx context.Context, ws local.WorkspaceInte
 	var contexts []pullCtx
 
 	for _, project := range projects {
+		// --lock-only never writes files, so dependencies that were not received stay that way
+		if c.LockOnly && !isReceived(ws, project) {
+			logger.Log(ctx).Warn().
+				Str("project", string(project)).
+				Msg("Project is not received, skipping; pull without --lock-only to receive it")
+			continue
+		}
+
 		pc, err := c.createProjectContext(ctx, ws, reg, snapshot, project)
 		if err != nil {
 			return nil, err
 		}
 
-		if err := c.validateDeletions(ctx, pc); err != nil {
+		if c.LockOnly {
+			if err := c.verifyLockOnly(ws, pc); err != nil {
+				return nil, err
+			}
+		} else if err := c.validateDeletions(ctx, pc); err != nil {
 			return nil, err
 		}
 
@@ -167,18 +512,23 @@ func (c *PullCmd) createProjectContext(ctx context.Context, ws local.WorkspaceIn
 		return pullCtx{}, fmt.Errorf("list project files %s: %w", project, err)
 	}
 
+	pc := pullCtx{
+		project: project,
+		files:   filesRes.Files,
+	}
+
+	// Exports are not tracked, so there is nothing to reconcile against
+	if c.OutputDir != "" {
+		return pc, nil
+	}
+
 	localFiles, err := ws.ListVendorProjectFiles(local.ProjectPath(project))
 	if err != nil {
 		return pullCtx{}, fmt.Errorf("list local files %s: %w", project, err)
 	}
 
-	toDelete := c.findFilesToDelete(filesRes.Files, localFiles)
-
-	return pullCtx{
-		project:  project,
-		files:    filesRes.Files,
-		toDelete: toDelete,
-	}, nil
+	pc.toDelete = c.findFilesToDelete(filesRes.Files, localFiles)
+	return pc, nil
 }
 
 // findFilesToDelete finds local files not in the registry.
@@ -197,71 +547,152 @@ func (c *PullCmd) findFilesToDelete(regFiles []registry.ProjectFile, localFiles
 
 // validateDeletions checks if deletions are allowed.
 func (c *PullCmd) validateDeletions(ctx context.Context, pc pullCtx) error {
-	if len(pc.toDelete) > 0 && !c.Force {
+	if len(pc.toDelete) > 0 && !c.Force && !c.Prune {
 		logger.Log(ctx).Error().
 			Str("project", string(pc.project)).
 			Int("count", len(pc.toDelete)).
-			Msg("Would delete files. Use --force to proceed")
+			Msg("Would delete files. Use --force or --update-all --prune to proceed")
 		return fmt.Errorf("would delete %d files in %s", len(pc.toDelete), pc.project)
 	}
 	return nil
 }
 
-// executePull executes all pull contexts.
-func (c *PullCmd) executePull(ctx context.Context, ws local.WorkspaceInterface, reg registry.CacheInterface, snapshot git.Hash, contexts []pullCtx) error {
+// isReceived reports whether project has been received into the workspace.
+func isReceived(ws local.WorkspaceInterface, project registry.ProjectPath) bool {
+	_, err := ws.GetProjectLock(local.ProjectPath(project))
+	return err == nil
+}
+
+// verifyLockOnly checks that a project's received files are identical to the registry's,
+// so --lock-only can move its lock without masking a change.
+func (c *PullCmd) verifyLockOnly(ws local.WorkspaceInterface, pc pullCtx) error {
+	want := make([]local.ManifestEntry, 0, len(pc.files))
+	for _, f := range pc.files {
+		want = append(want, local.NewManifestEntry(f.Path, f.Hash, f.Mode))
+	}
+	differing, err := ws.CompareVendorFiles(local.ProjectPath(pc.project), want)
+	if err != nil {
+		return fmt.Errorf("compare files %s: %w", pc.project, err)
+	}
+	if len(differing) > 0 {
+		return fmt.Errorf("--lock-only: %d files in %s differ from the registry (%s); pull without --lock-only to update them",
+			len(differing), pc.project, strings.Join(differing, ", "))
+	}
+	return nil
+}
+
+// executePull executes the pull contexts of a batch.
+func (c *PullCmd) executePull(ctx context.Context, rep Reporter, progress *progressCounter, ws local.WorkspaceInterface, reg registry.CacheInterface, batch pullBatch) error {
 	var totalChanged, totalDeleted int
+	snapshot, contexts := batch.snapshot, batch.contexts
 
 	for _, pc := range contexts {
-		stats, err := c.executeProjectPull(ctx, ws, reg, snapshot, pc)
+		previous := c.previousSnapshot(ws, pc.project)
+		stats, err := c.executeProjectPull(ctx, rep, ws, reg, batch, pc)
 		if err != nil {
 			return err
 		}
+		if c.UpdateAll {
+			c.reportProjectUpdate(ctx, pc.project, previous, snapshot, stats)
+		}
 		totalChanged += stats.FilesChanged
 		totalDeleted += stats.FilesDeleted
+		progress.projectDone(string(pc.project))
 	}
 
-	logger.Log(ctx).Info().
-		Int("projects", len(contexts)).
-		Int("changed", totalChanged).
-		Int("deleted", totalDeleted).
-		Msg("Pull complete")
+	rep.Stats(Stats{
+		Command:  "pull",
+		Projects: len(contexts),
+		Changed:  totalChanged,
+		Deleted:  totalDeleted,
+		Snapshot: snapshot,
+	})
 
 	return nil
 }
 
+// previousSnapshot returns the snapshot a project was last received at, or "" if it is new.
+func (c *PullCmd) previousSnapshot(ws local.WorkspaceInterface, project registry.ProjectPath) git.Hash {
+	lock, err := ws.GetProjectLock(local.ProjectPath(project))
+	if err != nil {
+		return ""
+	}
+	return git.Hash(lock.Snapshot)
+}
+
+// reportProjectUpdate logs the per-project result of --update-all.
+func (c *PullCmd) reportProjectUpdate(ctx context.Context, project registry.ProjectPath, previous, snapshot git.Hash, stats *local.ReceiveStats) {
+	event := logger.Log(ctx).Info().
+		Str("project", string(project)).
+		Str("from", previous.Short()).
+		Str("to", snapshot.Short()).
+		Int("changed", stats.FilesChanged).
+		Int("deleted", stats.FilesDeleted)
+
+	if stats.FilesChanged == 0 && stats.FilesDeleted == 0 {
+		event.Msg("Project already up to date")
+		return
+	}
+	event.Msg("Updated project")
+}
+
 // executeProjectPull pulls a single project.
-func (c *PullCmd) executeProjectPull(ctx context.Context, ws local.WorkspaceInterface, reg registry.CacheInterface, snapshot git.Hash, pc pullCtx) (*local.ReceiveStats, error) {
+func (c *PullCmd) executeProjectPull(ctx context.Context, rep Reporter, ws local.WorkspaceInterface, reg registry.CacheInterface, batch pullBatch, pc pullCtx) (*local.ReceiveStats, error) {
+	snapshot := batch.snapshot
+	if c.LockOnly {
+		logger.Log(ctx).Info().
+			Str("project", string(pc.project)).
+			Str("snapshot", snapshot.Short()).
+			Msg("Updating lock file")
+		lock := &local.LockFile{Snapshot: string(snapshot), Branch: batch.branch}
+		if batch.ref != "" {
+			lock.Ref, lock.ResolvedCommit = batch.ref, string(snapshot)
+		}
+		// The vendored files are untouched, so their checksums still hold
+		if prev, err := ws.GetProjectLock(local.ProjectPath(pc.project)); err == nil {
+			lock.Files = prev.Files
+		}
+		if err := ws.SetProjectLock(local.ProjectPath(pc.project), lock); err != nil {
+			return nil, fmt.Errorf("update lock file: %w", err)
+		}
+		return &local.ReceiveStats{}, nil
+	}
+
 	logger.Log(ctx).Info().
 		Str("project", string(pc.project)).
 		Int("files", len(pc.files)).
 		Msg("Pulling project")
 
 	recv, err := ws.ReceiveProject(&local.ReceiveProjectRequest{
-		Project:  local.ProjectPath(pc.project),
-		Snapshot: snapshot,
+		Project:         local.ProjectPath(pc.project),
+		Snapshot:        snapshot,
+		Branch:          batch.branch,
+		Ref:             batch.ref,
+		NoGitattributes: c.NoGitattributes,
+		OutputDir:       c.OutputDir,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("receive project: %w", err)
 	}
 
-	if err := c.pullFiles(ctx, reg, recv, pc.files); err != nil {
+	if err := c.pullFiles(ctx, rep, reg, recv, pc.project, pc.files); err != nil {
 		return nil, err
 	}
 
-	c.deleteFiles(ctx, recv, pc.toDelete)
+	c.deleteFiles(rep, recv, pc.project, pc.toDelete)
 
 	return recv.Finish()
 }
 
 // pullFiles downloads files from the registry.
-func (c *PullCmd) pullFiles(ctx context.Context, reg registry.CacheInterface, recv *local.ProjectReceiver, files []registry.ProjectFile) error {
-	for _, file := range files {
-		w, err := recv.CreateFile(file.Path)
+func (c *PullCmd) pullFiles(ctx context.Context, rep Reporter, reg registry.CacheInterface, recv *local.ProjectReceiver, project registry.ProjectPath, files []registry.ProjectFile) error {
+	return reg.ReadProjectFiles(ctx, files, func(file registry.ProjectFile, content io.Reader) error {
+		w, err := recv.CreateFileWithMode(file.Path, file.Mode)
 		if err != nil {
 			return fmt.Errorf("create file %s: %w", file.Path, err)
 		}
 
-		if err := reg.ReadProjectFile(ctx, file, w); err != nil {
+		if _, err := io.Copy(w, content); err != nil {
 			w.Close()
 			return fmt.Errorf("read file %s: %w", file.Path, err)
 		}
@@ -269,15 +700,20 @@ func (c *PullCmd) pullFiles(ctx context.Context, reg registry.CacheInterface, re
 		if err := w.Close(); err != nil {
 			return fmt.Errorf("close file %s: %w", file.Path, err)
 		}
-	}
-	return nil
+		rep.FilePulled(PulledFile{Project: string(project), Path: file.Path})
+		return nil
+	})
 }
 
 // deleteFiles removes files that no longer exist in the registry.
-func (c *PullCmd) deleteFiles(ctx context.Context, recv *local.ProjectReceiver, toDelete []string) {
+func (c *PullCmd) deleteFiles(rep Reporter, recv *local.ProjectReceiver, project registry.ProjectPath, toDelete []string) {
 	for _, path := range toDelete {
 		if err := recv.DeleteFile(path); err != nil {
-			logger.Log(ctx).Warn().Err(err).Str("path", path).Msg("Failed to delete file")
+			rep.Diagnostic(Diagnostic{
+				Level:   zerolog.WarnLevel,
+				Project: string(project),
+				Message: fmt.Sprintf("Failed to delete file %s: %v", path, err),
+			})
 		}
 	}
 }
@@ -24,6 +24,27 @@ func TestPullCmd_Struct(t *testing.T) {
 	}
 }
 
+func TestParseProjectRef(t *testing.T) {
+	tests := []struct {
+		arg         string
+		wantProject string
+		wantRef     string
+	}{
+		{arg: "team/service", wantProject: "team/service"},
+		{arg: "team/service@v1.2.0", wantProject: "team/service", wantRef: "v1.2.0"},
+		{arg: "team/service@abc1234", wantProject: "team/service", wantRef: "abc1234"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			project, ref := parseProjectRef(tt.arg)
+			if project != tt.wantProject || ref != tt.wantRef {
+				t.Errorf("parseProjectRef() = (%q, %q), want (%q, %q)", project, ref, tt.wantProject, tt.wantRef)
+			}
+		})
+	}
+}
+
 func TestPullCtx_Struct(t *testing.T) {
 	pctx := &pullCtx{
 		project:  registry.ProjectPath("team/service"),
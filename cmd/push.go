@@ -5,10 +5,14 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path"
 	"strings"
 	"time"
 
+	"github.com/rs/zerolog"
+
 	"github.com/rahulagarwal0605/protato/internal/constants"
+	protatoerrors "github.com/rahulagarwal0605/protato/internal/errors"
 	"github.com/rahulagarwal0605/protato/internal/git"
 	"github.com/rahulagarwal0605/protato/internal/local"
 	"github.com/rahulagarwal0605/protato/internal/logger"
@@ -19,9 +23,19 @@ import (
 
 // PushCmd publishes owned projects to registry.
 type PushCmd struct {
-	Retries    int           `help:"Number of retries on conflict" default:"5" env:"PROTATO_PUSH_RETRIES"`
-	RetryDelay time.Duration `help:"Delay between retries" default:"200ms" env:"PROTATO_PUSH_RETRY_DELAY"`
-	NoValidate bool          `help:"Skip proto validation"`
+	Retries             int           `help:"Number of retries on conflict" default:"5" env:"PROTATO_PUSH_RETRIES"`
+	RetryDelay          time.Duration `help:"Delay between retries" default:"200ms" env:"PROTATO_PUSH_RETRY_DELAY"`
+	NoValidate          bool          `help:"Skip proto validation"`
+	Strict              bool          `help:"Fail validation if any project could not be loaded"`
+	StrictSyntax        bool          `help:"Fail validation when a file imports a file of a different syntax or edition"`
+	AllowLarge          bool          `help:"Allow pushing files larger than max_file_size"`
+	Amend               bool          `help:"Replace the last registry commit instead of adding one; it must be your own push of these projects"`
+	ExplainOwnership    bool          `help:"On an ownership conflict, print who owns the conflicting path and at which commit"`
+	Approval            string        `help:"Approval (e.g. the co-signer, never the pusher) recorded on pushes of projects that require one" env:"PROTATO_PUSH_APPROVAL"`
+	DereferenceSymlinks bool          `help:"Publish the target content of symlinked files of every type; symlinked .proto files are always published"`
+	CheckBreaking       bool          `help:"Compare the pushed projects with the registry and fail on changes that break consumers"`
+	Force               bool          `help:"Push even when --check-breaking finds breaking changes"`
+	DryRun              bool          `help:"Show the files the push would add, modify or delete in the registry without pushing"`
 }
 
 // pushCtx holds the context for a push operation.
@@ -32,6 +46,7 @@ type pushCtx struct {
 	currentCommit git.Hash
 	ownedProjects []local.ProjectPath
 	author        *git.Author // Current Git user for commits
+	rep           Reporter
 }
 
 // Run executes the push command.
@@ -40,12 +55,16 @@ func (c *PushCmd) Run(globals *GlobalOptions, ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	defer pctx.reg.Close()
 
 	if len(pctx.ownedProjects) == 0 {
-		logger.Log(ctx).Info().Msg("No owned projects to push")
+		pctx.rep.Diagnostic(Diagnostic{Level: zerolog.InfoLevel, Message: "No owned projects to push"})
 		return nil
 	}
 
+	if c.DryRun {
+		return c.planPush(ctx, pctx)
+	}
 	return c.executePush(ctx, pctx)
 }
 
@@ -91,6 +110,7 @@ func (c *PushCmd) createPushContext(ctx context.Context, globals *GlobalOptions)
 		currentCommit: currentCommit,
 		ownedProjects: ownedProjects,
 		author:        author,
+		rep:           globals.reporter(ctx),
 	}, nil
 }
 
@@ -121,7 +141,6 @@ func (c *PushCmd) executePush(ctx context.Context, pctx *pushCtx) error {
 	return fmt.Errorf("push failed after %d retries", c.Retries)
 }
 
-
 // isRetryableError determines if an error should be retried.
 // Returns false for validation errors, ownership errors, and other non-transient errors.
 // Returns true for push conflicts and network errors that might succeed on retry.
@@ -138,6 +157,12 @@ func (c *PushCmd) isRetryableError(err error) bool {
 		constants.ErrMsgCompilationFailed,
 		constants.ErrMsgProjectClaim,
 		constants.ErrMsgOwnership,
+		constants.ErrMsgFileTooLarge,
+		constants.ErrMsgAmend,
+		constants.ErrMsgSymlink,
+		constants.ErrMsgBreakingChanges,
+		protatoerrors.ErrDisallowedFile.Error(),
+		protatoerrors.ErrApprovalRequired.Error(),
 	}
 
 	if utils.ContainsAny(errStr, nonRetryablePatterns...) {
@@ -149,7 +174,6 @@ func (c *PushCmd) isRetryableError(err error) bool {
 	return true
 }
 
-
 // attemptPush performs a single push attempt.
 func (c *PushCmd) attemptPush(ctx context.Context, pctx *pushCtx) error {
 	snapshot, err := pctx.reg.RefreshAndGetSnapshot(ctx)
@@ -161,7 +185,12 @@ func (c *PushCmd) attemptPush(ctx context.Context, pctx *pushCtx) error {
 		return err
 	}
 
-	finalSnapshot, registryProjects, err := c.updateProjects(ctx, pctx, snapshot)
+	base, err := c.resolveBase(ctx, pctx, snapshot)
+	if err != nil {
+		return err
+	}
+
+	finalSnapshot, registryProjects, err := c.updateProjects(ctx, pctx, base)
 	if err != nil {
 		return err
 	}
@@ -174,9 +203,91 @@ func (c *PushCmd) attemptPush(ctx context.Context, pctx *pushCtx) error {
 		return err
 	}
 
+	if err := c.checkBreakingIfEnabled(ctx, pctx, snapshot, finalSnapshot, registryProjects); err != nil {
+		return err
+	}
+
+	if c.Amend {
+		return c.amendRemote(ctx, pctx, finalSnapshot, snapshot)
+	}
 	return c.pushToRemote(ctx, pctx, finalSnapshot)
 }
 
+// planPush reports the file changes a push would make to the registry, running the same
+// checks as a push but stopping before a commit is created.
+func (c *PushCmd) planPush(ctx context.Context, pctx *pushCtx) error {
+	snapshot, err := pctx.reg.RefreshAndGetSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := c.checkOwnershipClaims(ctx, pctx, snapshot); err != nil {
+		return err
+	}
+
+	base, err := c.resolveBase(ctx, pctx, snapshot)
+	if err != nil {
+		return err
+	}
+
+	reqs, _, err := c.projectRequests(ctx, pctx, base)
+	if err != nil {
+		return err
+	}
+
+	changes, err := pctx.reg.PlanProjects(ctx, reqs)
+	if err != nil {
+		return fmt.Errorf("plan projects: %w", err)
+	}
+	reportPushPlan(pctx.rep, changes, base)
+	return nil
+}
+
+// reportPushPlan prints one line per changed file, its git status letter (A, M or D) followed
+// by its registry path, and a summary.
+func reportPushPlan(rep Reporter, changes []registry.FileChange, base git.Hash) {
+	counts := make(map[git.DiffStatus]int)
+	for _, change := range changes {
+		counts[change.Status]++
+		rep.Diagnostic(Diagnostic{
+			Level:   zerolog.NoLevel,
+			Project: string(change.Project),
+			File:    change.Path,
+			Message: fmt.Sprintf("%s %s", change.Status, path.Join(string(change.Project), change.Path)),
+		})
+	}
+
+	if len(changes) == 0 {
+		rep.Diagnostic(Diagnostic{Level: zerolog.NoLevel, Message: fmt.Sprintf("Nothing to push; the registry is up to date at %s", base.Short())})
+		return
+	}
+	rep.Diagnostic(Diagnostic{Level: zerolog.NoLevel, Message: fmt.Sprintf("Would push %d added, %d modified and %d deleted file(s) onto %s",
+		counts[git.DiffAdded], counts[git.DiffModified], counts[git.DiffDeleted], base.Short())})
+}
+
+// resolveBase returns the snapshot new commits are built on:
+// the registry snapshot, or with --amend the parent of the commit being replaced.
+func (c *PushCmd) resolveBase(ctx context.Context, pctx *pushCtx, snapshot git.Hash) (git.Hash, error) {
+	if !c.Amend {
+		return snapshot, nil
+	}
+
+	var projects []registry.ProjectPath
+	for _, project := range pctx.ownedProjects {
+		registryPath, err := pctx.wctx.WS.GetRegistryPathForProject(project)
+		if err != nil {
+			return "", err
+		}
+		projects = append(projects, registry.ProjectPath(registryPath))
+	}
+
+	base, err := pctx.reg.AmendBase(ctx, snapshot, *pctx.author, pctx.repoURL, projects)
+	if err != nil {
+		return "", err
+	}
+	logger.Log(ctx).Info().Str("replacing", snapshot.Short()).Str("base", base.Short()).Msg("Amending last registry commit")
+	return base, nil
+}
 
 // checkOwnershipClaims verifies all projects can be pushed.
 func (c *PushCmd) checkOwnershipClaims(ctx context.Context, pctx *pushCtx, snapshot git.Hash) error {
@@ -186,21 +297,45 @@ func (c *PushCmd) checkOwnershipClaims(ctx context.Context, pctx *pushCtx, snaps
 			return err
 		}
 		if err := pctx.reg.CheckProjectClaim(ctx, snapshot, pctx.repoURL, string(registryPath)); err != nil {
+			if c.ExplainOwnership {
+				explainOwnership(pctx.rep, string(registryPath), err)
+			}
 			return err
 		}
 	}
 	return nil
 }
 
-// updateProjects updates all owned projects in the registry.
+// updateProjects updates all owned projects in the registry with a single commit on snapshot.
 func (c *PushCmd) updateProjects(ctx context.Context, pctx *pushCtx, snapshot git.Hash) (git.Hash, []registry.ProjectPath, error) {
-	var finalSnapshot git.Hash
-	var registryProjects []registry.ProjectPath
+	reqs, registryProjects, err := c.projectRequests(ctx, pctx, snapshot)
+	if err != nil {
+		return "", nil, err
+	}
 
+	res, err := pctx.reg.SetProjects(ctx, reqs)
+	if err != nil {
+		return "", nil, fmt.Errorf("set projects: %w", err)
+	}
+
+	return res.Snapshot, registryProjects, nil
+}
+
+// projectRequests builds the registry updates of all owned projects on snapshot,
+// along with the registry paths of the projects.
+func (c *PushCmd) projectRequests(ctx context.Context, pctx *pushCtx, snapshot git.Hash) ([]*registry.SetProjectRequest, []registry.ProjectPath, error) {
+	config, err := pctx.reg.Config(ctx, snapshot)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read registry config: %w", err)
+	}
+
+	var reqs []*registry.SetProjectRequest
+	var registryProjects []registry.ProjectPath
+	progress := &progressCounter{rep: pctx.rep, command: "push", total: len(pctx.ownedProjects)}
 	for _, project := range pctx.ownedProjects {
 		registryPath, err := pctx.wctx.WS.GetRegistryPathForProject(project)
 		if err != nil {
-			return "", nil, err
+			return nil, nil, err
 		}
 		registryProjects = append(registryProjects, registry.ProjectPath(registryPath))
 
@@ -209,45 +344,68 @@ func (c *PushCmd) updateProjects(ctx context.Context, pctx *pushCtx, snapshot gi
 			Str("registry", string(registryPath)).
 			Msg("Preparing project")
 
-		newSnapshot, err := c.updateSingleProject(ctx, pctx, project, registryPath, snapshot)
+		req, err := c.projectRequest(ctx, pctx, config, project, registryPath, snapshot)
 		if err != nil {
-			return "", nil, err
+			return nil, nil, err
 		}
-
-		finalSnapshot = newSnapshot
-		snapshot = finalSnapshot
+		reqs = append(reqs, req)
+		progress.projectDone(string(registryPath))
 	}
-
-	return finalSnapshot, registryProjects, nil
+	return reqs, registryProjects, nil
 }
 
-// updateSingleProject updates a single project in the registry.
-func (c *PushCmd) updateSingleProject(ctx context.Context, pctx *pushCtx, localProject local.ProjectPath, registryPath local.ProjectPath, snapshot git.Hash) (git.Hash, error) {
-	files, err := pctx.wctx.WS.ListOwnedProjectFiles(localProject)
+// projectRequest builds the registry update of a single owned project.
+func (c *PushCmd) projectRequest(ctx context.Context, pctx *pushCtx, config *registry.Config, localProject local.ProjectPath, registryPath local.ProjectPath, snapshot git.Hash) (*registry.SetProjectRequest, error) {
+	// Push every file type the registry accepts, not just protos
+	files, err := pctx.wctx.WS.ListOwnedProjectFilesWithExtensions(localProject, config.Extensions())
 	if err != nil {
-		return "", fmt.Errorf("list files %s: %w", localProject, err)
+		return nil, fmt.Errorf("list files %s: %w", localProject, err)
 	}
 
-	ownedDir, _ := pctx.wctx.WS.OwnedDirName()
+	ownedDirs, err := ownedDirNames(pctx.wctx.WS)
+	if err != nil {
+		return nil, err
+	}
 	serviceName := pctx.wctx.WS.ServiceName()
 	pulledPrefixes := c.getPulledPrefixes(ctx, pctx)
-	regFiles := c.prepareRegistryFiles(ctx, files, ownedDir, serviceName, pulledPrefixes)
+	regFiles := c.prepareRegistryFiles(ctx, files, ownedDirs, serviceName, pulledPrefixes)
 
-	res, err := pctx.reg.SetProject(ctx, &registry.SetProjectRequest{
+	return &registry.SetProjectRequest{
 		Project: &registry.Project{
-			Path:          registry.ProjectPath(registryPath),
-			Commit:        pctx.currentCommit,
-			RepositoryURL: pctx.repoURL,
+			Path:            registry.ProjectPath(registryPath),
+			Commit:          pctx.currentCommit,
+			RepositoryURL:   pctx.repoURL,
+			RequireApproval: pctx.wctx.WS.RequiresApproval(localProject),
 		},
-		Files:    regFiles,
-		Snapshot: snapshot,
-		Author:   pctx.author,
-	})
-	if err != nil {
-		return "", fmt.Errorf("set project %s: %w", registryPath, err)
+		Files:       regFiles,
+		Snapshot:    snapshot,
+		Author:      pctx.author,
+		MaxFileSize: c.maxFileSize(pctx),
+		Validate:    protoc.ValidateRegistryProject,
+		Approval:    c.approvalSource(),
+		Symlinks: registry.SymlinkPolicy{
+			Dereference: c.DereferenceSymlinks,
+			Root:        pctx.wctx.WS.Root(),
+		},
+	}, nil
+}
+
+// approvalSource supplies --approval to projects that require one, or nil when it is unset.
+func (c *PushCmd) approvalSource() registry.ApprovalSource {
+	if c.Approval == "" {
+		return nil
+	}
+	return func(context.Context, registry.ProjectPath) (string, error) {
+		return c.Approval, nil
 	}
+}
 
-	return res.Snapshot, nil
+// maxFileSize returns the push size limit, or 0 when --allow-large disables it.
+func (c *PushCmd) maxFileSize(pctx *pushCtx) int64 {
+	if c.AllowLarge {
+		return 0
+	}
+	return pctx.wctx.WS.MaxFileSize()
 }
 
 // getPulledPrefixes extracts service name prefixes from pulled projects.
@@ -273,7 +431,7 @@ func (c *PushCmd) getPulledPrefixes(ctx context.Context, pctx *pushCtx) []string
 }
 
 // prepareRegistryFiles prepares registry files with transformed imports.
-func (c *PushCmd) prepareRegistryFiles(ctx context.Context, files []local.ProjectFile, ownedDir, serviceName string, pulledPrefixes []string) []registry.LocalProjectFile {
+func (c *PushCmd) prepareRegistryFiles(ctx context.Context, files []local.ProjectFile, ownedDirs []string, serviceName string, pulledPrefixes []string) []registry.LocalProjectFile {
 	regFiles := make([]registry.LocalProjectFile, len(files))
 	for i, f := range files {
 		regFile := registry.LocalProjectFile{
@@ -282,7 +440,7 @@ func (c *PushCmd) prepareRegistryFiles(ctx context.Context, files []local.Projec
 		}
 
 		if strings.HasSuffix(f.Path, constants.ProtoFileExt) && serviceName != "" {
-			transformed := c.transformProtoFile(ctx, f.AbsolutePath, f.Path, ownedDir, serviceName, pulledPrefixes)
+			transformed := c.transformProtoFile(ctx, f.AbsolutePath, f.Path, ownedDirs, serviceName, pulledPrefixes)
 			if transformed != nil {
 				regFile.Content = transformed
 			}
@@ -294,19 +452,20 @@ func (c *PushCmd) prepareRegistryFiles(ctx context.Context, files []local.Projec
 }
 
 // transformProtoFile transforms imports in a proto file and returns the transformed content if changed.
-func (c *PushCmd) transformProtoFile(ctx context.Context, filePath, fileName, ownedDir, serviceName string, pulledPrefixes []string) []byte {
+// Imports of owned files are transformed with the owned directory they start with.
+func (c *PushCmd) transformProtoFile(ctx context.Context, filePath, fileName string, ownedDirs []string, serviceName string, pulledPrefixes []string) []byte {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil
 	}
 
-	transformed := protoc.TransformImportsWithPulled(content, ownedDir, serviceName, pulledPrefixes)
+	transformed := protoc.TransformOwnedImportsWithPulled(content, ownedDirs, serviceName, pulledPrefixes)
 	if !bytes.Equal(content, transformed) {
-		logger.Log(ctx).Debug().Str("file", fileName).Str("ownedDir", ownedDir).Str("serviceName", serviceName).Msg("Transformed imports")
+		logger.Log(ctx).Debug().Str("file", fileName).Strs("ownedDirs", ownedDirs).Str("serviceName", serviceName).Msg("Transformed imports")
 		return transformed
 	}
 
-	logger.Log(ctx).Debug().Str("file", fileName).Str("ownedDir", ownedDir).Str("serviceName", serviceName).Msg("No imports transformed")
+	logger.Log(ctx).Debug().Str("file", fileName).Strs("ownedDirs", ownedDirs).Str("serviceName", serviceName).Msg("No imports transformed")
 	return nil
 }
 
@@ -322,25 +481,33 @@ func (c *PushCmd) validateIfEnabled(ctx context.Context, pctx *pushCtx, snapshot
 		logger.Log(ctx).Warn().Err(err).Msg("Could not get owned directory, using default")
 		ownedDir = "proto"
 	}
+	owned, err := pctx.wctx.WS.OwnedRoots()
+	if err != nil {
+		return fmt.Errorf("get owned directories: %w", err)
+	}
 
 	workspaceRoot := pctx.wctx.WS.Root()
 	serviceName := pctx.wctx.WS.ServiceName()
 
-	// Get vendor directory for pulled dependencies
-	vendorDir, err := pctx.wctx.WS.VendorDir()
+	// Get vendor directories for pulled dependencies
+	vendor, err := pctx.wctx.WS.VendorRoots(ctx)
 	if err != nil {
-		vendorDir = "" // No vendor dir configured, that's OK
+		vendor = nil // No vendor dir configured, that's OK
 	}
 
 	logger.Log(ctx).Info().Msg("Validating proto files")
 	if err := protoc.ValidateProtos(ctx, protoc.ValidateProtosConfig{
-		Cache:         pctx.reg,
-		Snapshot:      snapshot,
-		Projects:      projects,
-		OwnedDir:      ownedDir,
-		VendorDir:     vendorDir,
-		WorkspaceRoot: workspaceRoot,
-		ServiceName:   serviceName,
+		Cache:            pctx.reg,
+		Snapshot:         snapshot,
+		Projects:         projects,
+		OwnedDir:         ownedDir,
+		Owned:            owned,
+		Vendor:           vendor,
+		WorkspaceRoot:    workspaceRoot,
+		ServiceName:      serviceName,
+		GoogleImports:    pctx.wctx.WS.GoogleImports(),
+		FailOnLoadErrors: c.Strict,
+		StrictSyntax:     c.StrictSyntax,
 	}); err != nil {
 		return fmt.Errorf("%s: %w", constants.ErrMsgValidationFailed, err)
 	}
@@ -348,14 +515,57 @@ func (c *PushCmd) validateIfEnabled(ctx context.Context, pctx *pushCtx, snapshot
 	return nil
 }
 
+// checkBreakingIfEnabled reports changes from the registry snapshot to the snapshot being pushed
+// that break consumers. They fail the push unless --force is set.
+func (c *PushCmd) checkBreakingIfEnabled(ctx context.Context, pctx *pushCtx, snapshot, finalSnapshot git.Hash, projects []registry.ProjectPath) error {
+	if !c.CheckBreaking {
+		return nil
+	}
+
+	logger.Log(ctx).Info().Str("base", snapshot.Short()).Msg("Checking for breaking changes")
+	changes, err := protoc.DetectBreaking(ctx, pctx.reg, snapshot, finalSnapshot, projects)
+	if err != nil {
+		return fmt.Errorf("check breaking changes: %w", err)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	level := zerolog.ErrorLevel
+	if c.Force {
+		level = zerolog.WarnLevel
+	}
+	for _, change := range changes {
+		pctx.rep.Diagnostic(Diagnostic{Level: level, File: change.File, Rule: change.Rule, Message: change.String()})
+	}
+	if c.Force {
+		logger.Log(ctx).Warn().Int("changes", len(changes)).Msg("Pushing breaking changes (--force)")
+		return nil
+	}
+	return fmt.Errorf("%s: %d change(s) break consumers of %s; use --force to push anyway", constants.ErrMsgBreakingChanges, len(changes), snapshot.Short())
+}
+
+// amendRemote replaces the registry commit at replaced with snapshot.
+func (c *PushCmd) amendRemote(ctx context.Context, pctx *pushCtx, snapshot, replaced git.Hash) error {
+	logger.Log(ctx).Info().Str("snapshot", snapshot.Short()).Msg("Force-pushing amended commit to registry")
+
+	if err := pctx.reg.PushAmend(ctx, snapshot, replaced); err != nil {
+		return err
+	}
+
+	pctx.rep.Stats(Stats{Command: "push", Projects: len(pctx.ownedProjects), Snapshot: snapshot})
+	return nil
+}
+
 // pushToRemote pushes the final snapshot to the remote registry.
 func (c *PushCmd) pushToRemote(ctx context.Context, pctx *pushCtx, snapshot git.Hash) error {
 	logger.Log(ctx).Info().Str("snapshot", snapshot.Short()).Msg("Pushing to registry")
 
-	if err := pctx.reg.Push(ctx, snapshot); err != nil {
+	pushed, err := pctx.reg.Push(ctx, snapshot)
+	if err != nil {
 		return err
 	}
 
-	logger.Log(ctx).Info().Msg("Push complete")
+	pctx.rep.Stats(Stats{Command: "push", Projects: len(pctx.ownedProjects), Snapshot: pushed})
 	return nil
 }
@@ -1,10 +1,15 @@
 package cmd
 
 import (
+"bytes"
 "errors"
+"fmt"
 "testing"
 
 "github.com/rahulagarwal0605/protato/internal/constants"
+protatoerrors "github.com/rahulagarwal0605/protato/internal/errors"
+"github.com/rahulagarwal0605/protato/internal/git"
+"github.com/rahulagarwal0605/protato/internal/registry"
 )
 
 func TestPushCmdIsRetryableError(t *testing.T) {
@@ -40,6 +45,31 @@ func TestPushCmdIsRetryableError(t *testing.T) {
 			err:  errors.New(constants.ErrMsgOwnership + ": some details"),
 			want: false,
 		},
+		{
+			name: "file too large error",
+			err:  errors.New(constants.ErrMsgFileTooLarge + ": big.bin (2000000 bytes, limit 131072)"),
+			want: false,
+		},
+		{
+			name: "disallowed file error",
+			err:  fmt.Errorf("set project team/service: %w: notes.md", protatoerrors.ErrDisallowedFile),
+			want: false,
+		},
+		{
+			name: "symlink error",
+			err:  errors.New(constants.ErrMsgSymlink + " v1/api.proto: target /etc/api.proto is outside /repo"),
+			want: false,
+		},
+		{
+			name: "breaking changes error",
+			err:  errors.New(constants.ErrMsgBreakingChanges + ": 1 change(s) break consumers of abc1234; use --force to push anyway"),
+			want: false,
+		},
+		{
+			name: "approval required error",
+			err:  fmt.Errorf("set project team/service: %w: team/service", protatoerrors.ErrApprovalRequired),
+			want: false,
+		},
 		{
 			name: "network error - retryable",
 			err:  errors.New("network connection reset"),
@@ -61,3 +91,39 @@ t.Errorf("isRetryableError() = %v, want %v", got, tt.want)
 })
 	}
 }
+
+func TestReportPushPlan(t *testing.T) {
+	base := git.Hash("0123456789abcdef0123456789abcdef01234567")
+	tests := []struct {
+		name    string
+		changes []registry.FileChange
+		want    string
+	}{
+		{
+			name: "changes",
+			changes: []registry.FileChange{
+				{Project: "team/service", Path: "v1/api.proto", Status: git.DiffModified},
+				{Project: "team/service", Path: "v1/gone.proto", Status: git.DiffDeleted},
+				{Project: "team/service", Path: "v1/new.proto", Status: git.DiffAdded},
+			},
+			want: "M team/service/v1/api.proto\n" +
+				"D team/service/v1/gone.proto\n" +
+				"A team/service/v1/new.proto\n" +
+				"Would push 1 added, 1 modified and 1 deleted file(s) onto 0123456\n",
+		},
+		{
+			name: "up to date",
+			want: "Nothing to push; the registry is up to date at 0123456\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			reportPushPlan(newConsoleReporter(testContext(), &buf), tt.changes, base)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+)
+
+func TestLocalSnapshot(t *testing.T) {
+	files := []localFile{
+		{path: "v1/api.proto", content: []byte("syntax = \"proto3\";")},
+		{path: "v1/types.proto", content: []byte("syntax = \"proto3\";")},
+	}
+
+	got := localSnapshot(files)
+	if !strings.HasPrefix(string(got), constants.LocalSnapshotPrefix) {
+		t.Errorf("localSnapshot() = %v, want prefix %q", got, constants.LocalSnapshotPrefix)
+	}
+	if len(got) < 7 {
+		t.Errorf("localSnapshot() = %v, want at least 7 characters", got)
+	}
+	if again := localSnapshot(files); again != got {
+		t.Errorf("localSnapshot() not stable: %v != %v", again, got)
+	}
+
+	changed := []localFile{
+		{path: "v1/api.proto", content: []byte("syntax = \"proto2\";")},
+		{path: "v1/types.proto", content: []byte("syntax = \"proto3\";")},
+	}
+	if localSnapshot(changed) == got {
+		t.Error("localSnapshot() should change when file content changes")
+	}
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+
+	protatoerrors "github.com/rahulagarwal0605/protato/internal/errors"
+	"github.com/rahulagarwal0605/protato/internal/local"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/internal/registry"
+)
+
+// RemoveCmd deletes an owned project from the registry and un-claims it.
+type RemoveCmd struct {
+	Project string `arg:"" help:"Owned project to remove (e.g., team/service)"`
+	Local   bool   `help:"Also delete the project's files from the owned directory"`
+	DryRun  bool   `help:"Report what would be removed without changing anything"`
+}
+
+// Run executes the remove command.
+func (c *RemoveCmd) Run(globals *GlobalOptions, ctx context.Context) error {
+	wctx, err := OpenWorkspaceContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	project := local.ProjectPath(c.Project)
+	if !wctx.WS.IsProjectOwned(project) {
+		return fmt.Errorf("project %s is not owned by this repository", project)
+	}
+	registryPath, err := wctx.WS.GetRegistryPathForProject(project)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.deleteFromRegistry(ctx, globals, wctx, registry.ProjectPath(registryPath))
+	if err != nil {
+		return err
+	}
+
+	rep := globals.reporter(ctx)
+	if c.DryRun {
+		c.reportPlan(rep, registryPath, res)
+		return nil
+	}
+
+	if err := wctx.WS.RemoveOwnedProject(project, c.Local); err != nil {
+		return fmt.Errorf("remove %s locally: %w", project, err)
+	}
+	if wctx.WS.IsProjectOwned(project) {
+		rep.Diagnostic(Diagnostic{
+			Level:   zerolog.WarnLevel,
+			Project: string(project),
+			Message: "Project is still matched by the workspace config and will be pushed again; remove its files with --local or add it to ignores",
+		})
+	}
+
+	if res != nil {
+		rep.Diagnostic(Diagnostic{Level: zerolog.NoLevel, Message: fmt.Sprintf("Removed %s from the registry at snapshot %s", registryPath, res.Snapshot)})
+		rep.Stats(Stats{Command: "remove", Projects: 1, Deleted: res.FilesDeleted, Snapshot: res.Snapshot})
+	}
+	return nil
+}
+
+// deleteFromRegistry deletes the project from the registry and, unless this is a dry run, pushes the result.
+// Returns nil without error when the project was never pushed.
+func (c *RemoveCmd) deleteFromRegistry(ctx context.Context, globals *GlobalOptions, wctx *WorkspaceContext, registryPath registry.ProjectPath) (*registry.DeleteProjectResponse, error) {
+	repoURL, err := wctx.Repo.GetRepoURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := wctx.Repo.GetUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get Git user: %w", err)
+	}
+
+	reg, err := OpenAndRefreshRegistry(ctx, globals)
+	if err != nil {
+		return nil, err
+	}
+	defer reg.Close()
+
+	snapshot, err := reg.GetSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := reg.DeleteProject(ctx, &registry.DeleteProjectRequest{
+		Project:       registryPath,
+		RepositoryURL: repoURL,
+		Snapshot:      snapshot,
+		Author:        &user,
+	})
+	if errors.Is(err, protatoerrors.ErrNotFound) {
+		logger.Log(ctx).Warn().Str("project", string(registryPath)).Msg("Project is not in the registry; it was never pushed")
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("delete %s from registry: %w", registryPath, err)
+	}
+
+	if c.DryRun {
+		return res, nil
+	}
+	logger.Log(ctx).Info().Str("project", string(registryPath)).Str("snapshot", res.Snapshot.Short()).Msg("Pushing to registry")
+	pushed, err := reg.Push(ctx, res.Snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("push: %w", err)
+	}
+	res.Snapshot = pushed
+	return res, nil
+}
+
+// reportPlan prints what a dry run would remove.
+func (c *RemoveCmd) reportPlan(rep Reporter, registryPath local.ProjectPath, res *registry.DeleteProjectResponse) {
+	if res != nil {
+		rep.Diagnostic(Diagnostic{Level: zerolog.NoLevel, Message: fmt.Sprintf("Would remove %s from the registry (%d files)", registryPath, res.FilesDeleted)})
+	}
+	rep.Diagnostic(Diagnostic{Level: zerolog.NoLevel, Message: fmt.Sprintf("Would un-claim %s locally", c.Project)})
+	if c.Local {
+		rep.Diagnostic(Diagnostic{Level: zerolog.NoLevel, Message: fmt.Sprintf("Would delete the files of %s", c.Project)})
+	}
+}
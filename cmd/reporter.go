@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/rs/zerolog"
+
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+)
+
+// Kinds of listed projects.
+const (
+	ProjectKindOwned    = "owned"
+	ProjectKindPulled   = "pulled"
+	ProjectKindRegistry = "registry"
+)
+
+// Reporter receives the results of a command as structured events.
+// The CLI renders them to the console; library consumers can supply their own
+// through GlobalOptions.Reporter to collect them instead.
+type Reporter interface {
+	ProjectListed(ListedProject)
+	FilePulled(PulledFile)
+	Diagnostic(Diagnostic)
+	Progress(Progress)
+	Stats(Stats)
+}
+
+// ListedProject is a project reported by list.
+type ListedProject struct {
+	Kind     string   // ProjectKindOwned, ProjectKindPulled or ProjectKindRegistry
+	Project  string   // Project path
+	Snapshot git.Hash // Snapshot the project was received at (pulled projects only)
+}
+
+// PulledFile is a file written into a received project by pull.
+type PulledFile struct {
+	Project string // Registry project path
+	Path    string // File path relative to the project
+}
+
+// Diagnostic is a message about the progress of a command.
+// Messages with zerolog.NoLevel are plain command output rather than log entries.
+type Diagnostic struct {
+	Level   zerolog.Level
+	Project string // Project the message is about, if any
+	File    string // File the message is about, if any
+	Line    int    // 1-based position in File (0 if unknown)
+	Column  int
+	Rule    string // Check that produced the message, if any
+	Message string
+}
+
+// Progress reports that a command finished one of the projects it works through.
+type Progress struct {
+	Command string // Command making progress ("pull" or "push")
+	Project string // Project just finished
+	Done    int    // Projects finished so far, including Project
+	Total   int    // Projects the command works through
+}
+
+// Stats summarizes the outcome of a command.
+type Stats struct {
+	Command  string   // Command that produced the stats ("pull", "push" or "remove")
+	Projects int      // Number of projects processed
+	Changed  int      // Files written
+	Deleted  int      // Files deleted
+	Snapshot git.Hash // Registry snapshot the command ended at
+}
+
+// consoleReporter renders events the way the CLI always has:
+// listings and plain output to w, everything else through the logger.
+type consoleReporter struct {
+	ctx      context.Context
+	w        io.Writer
+	lastKind string
+}
+
+// newConsoleReporter creates a reporter that writes listings to w.
+func newConsoleReporter(ctx context.Context, w io.Writer) *consoleReporter {
+	return &consoleReporter{ctx: ctx, w: w}
+}
+
+// ProjectListed prints a project, with a heading before the first project of each local kind.
+func (r *consoleReporter) ProjectListed(p ListedProject) {
+	if p.Kind != r.lastKind {
+		r.lastKind = p.Kind
+		switch p.Kind {
+		case ProjectKindOwned:
+			fmt.Fprintln(r.w, "Owned projects:")
+		case ProjectKindPulled:
+			fmt.Fprintln(r.w, "Pulled projects:")
+		}
+	}
+
+	switch p.Kind {
+	case ProjectKindOwned:
+		fmt.Fprintf(r.w, "  %s\n", p.Project)
+	case ProjectKindPulled:
+		fmt.Fprintf(r.w, "  %s (snapshot: %s)\n", p.Project, p.Snapshot.Short())
+	default:
+		fmt.Fprintln(r.w, p.Project)
+	}
+}
+
+// FilePulled logs a received file at debug level.
+func (r *consoleReporter) FilePulled(f PulledFile) {
+	logger.Log(r.ctx).Debug().Str("project", f.Project).Str("path", f.Path).Msg("Pulled file")
+}
+
+// Diagnostic prints plain output to w and logs everything else at its level.
+func (r *consoleReporter) Diagnostic(d Diagnostic) {
+	if d.Level == zerolog.NoLevel {
+		fmt.Fprintln(r.w, d.Message)
+		return
+	}
+
+	event := logger.Log(r.ctx).WithLevel(d.Level)
+	if d.Project != "" {
+		event = event.Str("project", d.Project)
+	}
+	event.Msg(d.Message)
+}
+
+// Progress logs a finished project at debug level; the command logs its own progress.
+func (r *consoleReporter) Progress(p Progress) {
+	logger.Log(r.ctx).Debug().
+		Str("command", p.Command).
+		Str("project", p.Project).
+		Int("done", p.Done).
+		Int("total", p.Total).
+		Msg("Progress")
+}
+
+// Stats logs the completion summary of a command.
+func (r *consoleReporter) Stats(s Stats) {
+	switch s.Command {
+	case "pull":
+		logger.Log(r.ctx).Info().
+			Int("projects", s.Projects).
+			Int("changed", s.Changed).
+			Int("deleted", s.Deleted).
+			Msg("Pull complete")
+	case "push":
+		logger.Log(r.ctx).Info().
+			Int("projects", s.Projects).
+			Str("snapshot", s.Snapshot.Short()).
+			Msg("Push complete")
+	case "remove":
+		logger.Log(r.ctx).Info().
+			Int("deleted", s.Deleted).
+			Str("snapshot", s.Snapshot.Short()).
+			Msg("Remove complete")
+	}
+}
+
+// Types of --jsonl-events events.
+const (
+	EventProjectListed = "project_listed"
+	EventFilePulled    = "file_pulled"
+	EventDiagnostic    = "diagnostic"
+	EventProgress      = "progress"
+	EventStats         = "stats"
+)
+
+// jsonlReporter writes each event as one JSON object per line, for editors and other tools.
+// Every object has a "type" field naming the event; the other fields depend on it.
+type jsonlReporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONLReporter creates a reporter that writes events to w as JSON lines.
+func NewJSONLReporter(w io.Writer) Reporter {
+	return &jsonlReporter{enc: json.NewEncoder(w)}
+}
+
+// projectListedEvent is the JSON form of a ListedProject.
+type projectListedEvent struct {
+	Type     string `json:"type"`
+	Kind     string `json:"kind"`
+	Project  string `json:"project"`
+	Snapshot string `json:"snapshot,omitempty"`
+}
+
+// filePulledEvent is the JSON form of a PulledFile.
+type filePulledEvent struct {
+	Type    string `json:"type"`
+	Project string `json:"project"`
+	Path    string `json:"path"`
+}
+
+// diagnosticEvent is the JSON form of a Diagnostic. Level is omitted for plain command output.
+type diagnosticEvent struct {
+	Type    string `json:"type"`
+	Level   string `json:"level,omitempty"`
+	Project string `json:"project,omitempty"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+}
+
+// progressEvent is the JSON form of Progress.
+type progressEvent struct {
+	Type    string `json:"type"`
+	Command string `json:"command"`
+	Project string `json:"project"`
+	Done    int    `json:"done"`
+	Total   int    `json:"total"`
+}
+
+// statsEvent is the JSON form of Stats.
+type statsEvent struct {
+	Type     string `json:"type"`
+	Command  string `json:"command"`
+	Projects int    `json:"projects"`
+	Changed  int    `json:"changed"`
+	Deleted  int    `json:"deleted"`
+	Snapshot string `json:"snapshot,omitempty"`
+}
+
+// ProjectListed writes a project_listed event.
+func (r *jsonlReporter) ProjectListed(p ListedProject) {
+	r.write(projectListedEvent{Type: EventProjectListed, Kind: p.Kind, Project: p.Project, Snapshot: string(p.Snapshot)})
+}
+
+// FilePulled writes a file_pulled event.
+func (r *jsonlReporter) FilePulled(f PulledFile) {
+	r.write(filePulledEvent{Type: EventFilePulled, Project: f.Project, Path: f.Path})
+}
+
+// Diagnostic writes a diagnostic event.
+func (r *jsonlReporter) Diagnostic(d Diagnostic) {
+	r.write(diagnosticEvent{
+		Type:    EventDiagnostic,
+		Level:   d.Level.String(),
+		Project: d.Project,
+		File:    d.File,
+		Line:    d.Line,
+		Column:  d.Column,
+		Rule:    d.Rule,
+		Message: d.Message,
+	})
+}
+
+// Progress writes a progress event.
+func (r *jsonlReporter) Progress(p Progress) {
+	r.write(progressEvent{Type: EventProgress, Command: p.Command, Project: p.Project, Done: p.Done, Total: p.Total})
+}
+
+// Stats writes a stats event.
+func (r *jsonlReporter) Stats(s Stats) {
+	r.write(statsEvent{
+		Type:     EventStats,
+		Command:  s.Command,
+		Projects: s.Projects,
+		Changed:  s.Changed,
+		Deleted:  s.Deleted,
+		Snapshot: string(s.Snapshot),
+	})
+}
+
+// write encodes one event as a line. A failed write has nowhere to be reported, so it is dropped.
+func (r *jsonlReporter) write(event any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_ = r.enc.Encode(event)
+}
+
+// progressCounter reports a Progress event each time a command finishes one of total projects.
+type progressCounter struct {
+	rep     Reporter
+	command string
+	done    int
+	total   int
+}
+
+// projectDone reports that project is finished.
+func (p *progressCounter) projectDone(project string) {
+	p.done++
+	p.rep.Progress(Progress{Command: p.command, Project: project, Done: p.done, Total: p.total})
+}
+
+// reporter returns the configured reporter: GlobalOptions.Reporter if set, JSON lines on
+// stdout with --jsonl-events, or else a console reporter writing to stdout.
+func (g *GlobalOptions) reporter(ctx context.Context) Reporter {
+	if g != nil && g.Reporter != nil {
+		return g.Reporter
+	}
+	if g != nil && g.JSONLEvents {
+		return NewJSONLReporter(os.Stdout)
+	}
+	return newConsoleReporter(ctx, os.Stdout)
+}
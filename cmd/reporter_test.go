@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestConsoleReporterProjectListed(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []ListedProject
+		want   string
+	}{
+		{
+			name: "local projects grouped under headings",
+			events: []ListedProject{
+				{Kind: ProjectKindOwned, Project: "team/a"},
+				{Kind: ProjectKindOwned, Project: "team/b"},
+				{Kind: ProjectKindPulled, Project: "other/c", Snapshot: "abc123def456"},
+			},
+			want: "Owned projects:\n  team/a\n  team/b\nPulled projects:\n  other/c (snapshot: abc123d)\n",
+		},
+		{
+			name: "registry projects without heading",
+			events: []ListedProject{
+				{Kind: ProjectKindRegistry, Project: "team/a"},
+				{Kind: ProjectKindRegistry, Project: "team/b"},
+			},
+			want: "team/a\nteam/b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			r := newConsoleReporter(testContext(), &buf)
+			for _, e := range tt.events {
+				r.ProjectListed(e)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsoleReporterDiagnostic(t *testing.T) {
+	var buf bytes.Buffer
+	r := newConsoleReporter(testContext(), &buf)
+
+	r.Diagnostic(Diagnostic{Level: zerolog.NoLevel, Message: "No projects found"})
+	r.Diagnostic(Diagnostic{Level: zerolog.WarnLevel, Project: "team/a", Message: "logged, not printed"})
+
+	if got, want := buf.String(), "No projects found\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestGlobalOptionsReporter(t *testing.T) {
+	ctx := testContext()
+
+	if _, ok := (&GlobalOptions{}).reporter(ctx).(*consoleReporter); !ok {
+		t.Error("reporter() without a configured reporter should render to the console")
+	}
+
+	custom := newConsoleReporter(ctx, &bytes.Buffer{})
+	if got := (&GlobalOptions{Reporter: custom}).reporter(ctx); got != custom {
+		t.Error("reporter() should return the configured reporter")
+	}
+	if got := (&GlobalOptions{Reporter: custom, JSONLEvents: true}).reporter(ctx); got != custom {
+		t.Error("reporter() should prefer the configured reporter over --jsonl-events")
+	}
+
+	if _, ok := (&GlobalOptions{JSONLEvents: true}).reporter(ctx).(*jsonlReporter); !ok {
+		t.Error("reporter() with --jsonl-events should write JSON lines")
+	}
+}
+
+func TestJSONLReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewJSONLReporter(&buf)
+
+	r.ProjectListed(ListedProject{Kind: ProjectKindPulled, Project: "team/a", Snapshot: "abc123"})
+	r.FilePulled(PulledFile{Project: "team/a", Path: "v1/api.proto"})
+	r.Diagnostic(Diagnostic{Level: zerolog.WarnLevel, Project: "team/a", Message: "Failed to delete file"})
+	r.Diagnostic(Diagnostic{Level: zerolog.NoLevel, Message: "No projects found"})
+	r.Progress(Progress{Command: "pull", Project: "team/a", Done: 1, Total: 2})
+	r.Stats(Stats{Command: "pull", Projects: 1, Changed: 1})
+
+	want := `{"type":"project_listed","kind":"pulled","project":"team/a","snapshot":"abc123"}
+{"type":"file_pulled","project":"team/a","path":"v1/api.proto"}
+{"type":"diagnostic","level":"warn","project":"team/a","message":"Failed to delete file"}
+{"type":"diagnostic","message":"No projects found"}
+{"type":"progress","command":"pull","project":"team/a","done":1,"total":2}
+{"type":"stats","command":"pull","projects":1,"changed":1,"deleted":0}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestProgressCounter(t *testing.T) {
+	var buf bytes.Buffer
+	progress := &progressCounter{rep: NewJSONLReporter(&buf), command: "push", total: 2}
+	progress.projectDone("team/a")
+	progress.projectDone("team/b")
+
+	want := `{"type":"progress","command":"push","project":"team/a","done":1,"total":2}
+{"type":"progress","command":"push","project":"team/b","done":2,"total":2}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+}
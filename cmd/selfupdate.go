@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/internal/selfupdate"
+)
+
+// SelfUpdateCmd replaces the running binary with the latest release.
+type SelfUpdateCmd struct {
+	ReleaseURL string `help:"Release feed URL (GitHub releases API)" default:"https://api.github.com/repos/rahulagarwal0605/protato/releases/latest" env:"PROTATO_RELEASE_URL"`
+	Force      bool   `help:"Install the latest release even if it is not newer" short:"f"`
+
+	source selfupdate.ReleaseSource // Overrides the release feed (for tests)
+}
+
+// Run executes the self-update command.
+func (c *SelfUpdateCmd) Run(globals *GlobalOptions, ctx context.Context, build *BuildInfo) error {
+	if build.Version == "" && !c.Force {
+		return fmt.Errorf("current version is unknown (development build), use --force to update anyway")
+	}
+
+	binaryPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(binaryPath); err == nil {
+		binaryPath = resolved
+	}
+
+	source := c.source
+	if source == nil {
+		source = &selfupdate.GitHubSource{URL: c.ReleaseURL}
+	}
+
+	res, err := selfupdate.Update(ctx, &selfupdate.UpdateRequest{
+		CurrentVersion: build.Version,
+		BinaryPath:     binaryPath,
+		Source:         source,
+		GOOS:           runtime.GOOS,
+		GOARCH:         runtime.GOARCH,
+		Force:          c.Force,
+	})
+	if err != nil {
+		return fmt.Errorf("self-update: %w", err)
+	}
+
+	if !res.Updated {
+		logger.Log(ctx).Info().Str("version", build.Version).Str("latest", res.Version).Msg("Already up to date")
+		return nil
+	}
+
+	logger.Log(ctx).Info().
+		Str("from", build.Version).
+		Str("to", res.Version).
+		Str("path", binaryPath).
+		Msg("Updated protato")
+	return nil
+}
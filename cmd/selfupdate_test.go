@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/internal/selfupdate"
+)
+
+// staticSource reports a fixed latest release and serves no assets.
+type staticSource struct{ version string }
+
+func (s *staticSource) Latest(ctx context.Context) (*selfupdate.Release, error) {
+	return &selfupdate.Release{Version: s.version}, nil
+}
+
+func (s *staticSource) Download(ctx context.Context, url string) ([]byte, error) {
+	return nil, context.Canceled
+}
+
+func TestSelfUpdateCmd_Run(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		latest  string
+		wantErr bool
+	}{
+		{name: "up to date", current: "v1.2.0", latest: "v1.2.0"},
+		{name: "unknown version without force", current: "", latest: "v1.2.0", wantErr: true},
+		// The asset is missing, so a newer release must fail before touching the binary
+		{name: "newer release without assets", current: "v1.0.0", latest: "v1.2.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &SelfUpdateCmd{source: &staticSource{version: tt.latest}}
+			err := c.Run(&GlobalOptions{}, testContext(), &BuildInfo{Version: tt.current})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
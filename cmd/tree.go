@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/rs/zerolog"
+
+	"github.com/rahulagarwal0605/protato/internal/registry"
+)
+
+// TreeCmd prints the registry projects as a tree of path segments.
+type TreeCmd struct {
+	Prefix  string `help:"Only show projects under this path (e.g., team)"`
+	Owners  bool   `help:"Show the owning repository and commit of each project"`
+	Offline bool   `help:"Don't refresh registry"`
+}
+
+// treeStyle holds the connectors drawn in front of tree entries.
+type treeStyle struct {
+	branch string // Entry with more siblings below it
+	last   string // Last entry among its siblings
+	pipe   string // Indent under an entry with more siblings below it
+	space  string // Indent under a last entry
+}
+
+var (
+	// boxTreeStyle draws the tree with box-drawing characters, for terminals.
+	boxTreeStyle = treeStyle{branch: "├── ", last: "└── ", pipe: "│   ", space: "    "}
+	// asciiTreeStyle draws the tree with plain ASCII, for pipes and files.
+	asciiTreeStyle = treeStyle{branch: "|-- ", last: "`-- ", pipe: "|   ", space: "    "}
+)
+
+// projectTreeNode is one path segment of the project tree.
+type projectTreeNode struct {
+	name     string
+	project  bool   // The path up to this segment is a project
+	note     string // Shown after the name of a project, e.g. its owner
+	children map[string]*projectTreeNode
+}
+
+// Run executes the tree command.
+func (c *TreeCmd) Run(globals *GlobalOptions, ctx context.Context) error {
+	reg, err := OpenRegistryWithRefresh(ctx, globals, c.Offline)
+	if err != nil {
+		return err
+	}
+	defer reg.Close()
+
+	prefix := strings.Trim(c.Prefix, "/")
+	projects, err := reg.ListProjects(ctx, &registry.ListProjectsOptions{Prefix: prefix})
+	if err != nil {
+		return fmt.Errorf("list projects: %w", err)
+	}
+	if len(projects) == 0 {
+		globals.reporter(ctx).Diagnostic(Diagnostic{Level: zerolog.NoLevel, Message: "No projects in registry"})
+		return nil
+	}
+
+	var notes map[registry.ProjectPath]string
+	if c.Owners {
+		if notes, err = projectOwnerNotes(ctx, reg, projects); err != nil {
+			return err
+		}
+	}
+
+	style := asciiTreeStyle
+	if isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd()) {
+		style = boxTreeStyle
+	}
+	renderProjectTree(os.Stdout, prefix, projects, notes, style)
+	return nil
+}
+
+// projectOwnerNotes describes the owning repository and commit of each project.
+func projectOwnerNotes(ctx context.Context, reg registry.CacheInterface, projects []registry.ProjectPath) (map[registry.ProjectPath]string, error) {
+	notes := make(map[registry.ProjectPath]string, len(projects))
+	for _, p := range projects {
+		res, err := reg.LookupProject(ctx, &registry.LookupProjectRequest{Path: string(p)})
+		if err != nil {
+			return nil, fmt.Errorf("look up %s: %w", p, err)
+		}
+		notes[p] = fmt.Sprintf("%s@%s", res.Project.RepositoryURL, res.Project.Commit.Short())
+	}
+	return notes, nil
+}
+
+// renderProjectTree writes projects as a tree rooted at prefix ("." when empty).
+// notes holds the text shown after each project's name; it may be nil.
+func renderProjectTree(w io.Writer, prefix string, projects []registry.ProjectPath, notes map[registry.ProjectPath]string, style treeStyle) {
+	root := &projectTreeNode{name: prefix, children: make(map[string]*projectTreeNode)}
+	if root.name == "" {
+		root.name = "."
+	}
+	for _, p := range projects {
+		rel := strings.TrimPrefix(strings.TrimPrefix(string(p), prefix), "/")
+		node := root
+		if rel != "" {
+			for _, segment := range strings.Split(rel, "/") {
+				child, ok := node.children[segment]
+				if !ok {
+					child = &projectTreeNode{name: segment, children: make(map[string]*projectTreeNode)}
+					node.children[segment] = child
+				}
+				node = child
+			}
+		}
+		node.project = true
+		node.note = notes[p]
+	}
+
+	fmt.Fprintln(w, root.label())
+	root.render(w, "", style)
+}
+
+// render writes the children of n, each line starting with indent.
+func (n *projectTreeNode) render(w io.Writer, indent string, style treeStyle) {
+	names := make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		child := n.children[name]
+		connector, childIndent := style.branch, indent+style.pipe
+		if i == len(names)-1 {
+			connector, childIndent = style.last, indent+style.space
+		}
+		fmt.Fprintf(w, "%s%s%s\n", indent, connector, child.label())
+		child.render(w, childIndent, style)
+	}
+}
+
+// label returns the name of n, followed by its note if it has one.
+func (n *projectTreeNode) label() string {
+	if n.project && n.note != "" {
+		return n.name + " (" + n.note + ")"
+	}
+	return n.name
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/internal/registry"
+)
+
+func TestRenderProjectTree(t *testing.T) {
+	projects := []registry.ProjectPath{"other/x", "team/a", "team/a/v2", "team/b/api"}
+
+	tests := []struct {
+		name     string
+		prefix   string
+		projects []registry.ProjectPath
+		notes    map[registry.ProjectPath]string
+		style    treeStyle
+		want     string
+	}{
+		{
+			name:     "ascii",
+			projects: projects,
+			style:    asciiTreeStyle,
+			want: ".\n" +
+				"|-- other\n" +
+				"|   `-- x\n" +
+				"`-- team\n" +
+				"    |-- a\n" +
+				"    |   `-- v2\n" +
+				"    `-- b\n" +
+				"        `-- api\n",
+		},
+		{
+			name:     "box drawing",
+			projects: projects,
+			style:    boxTreeStyle,
+			want: ".\n" +
+				"├── other\n" +
+				"│   └── x\n" +
+				"└── team\n" +
+				"    ├── a\n" +
+				"    │   └── v2\n" +
+				"    └── b\n" +
+				"        └── api\n",
+		},
+		{
+			name:     "prefix",
+			prefix:   "team",
+			projects: projects[1:],
+			style:    asciiTreeStyle,
+			want: "team\n" +
+				"|-- a\n" +
+				"|   `-- v2\n" +
+				"`-- b\n" +
+				"    `-- api\n",
+		},
+		{
+			name:     "owners",
+			prefix:   "team",
+			projects: []registry.ProjectPath{"team/a", "team/b/api"},
+			notes: map[registry.ProjectPath]string{
+				"team/a":     "github.com/acme/a@1a2b3c4",
+				"team/b/api": "github.com/acme/b@5d6e7f8",
+			},
+			style: asciiTreeStyle,
+			want: "team\n" +
+				"|-- a (github.com/acme/a@1a2b3c4)\n" +
+				"`-- b\n" +
+				"    `-- api (github.com/acme/b@5d6e7f8)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			renderProjectTree(&buf, tt.prefix, tt.projects, tt.notes, tt.style)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("renderProjectTree() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
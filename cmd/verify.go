@@ -7,16 +7,24 @@ import (
 	"fmt"
 	"os"
 
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
 	"github.com/rahulagarwal0605/protato/internal/git"
 	"github.com/rahulagarwal0605/protato/internal/local"
 	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/internal/protoc"
 	"github.com/rahulagarwal0605/protato/internal/registry"
 	"github.com/rahulagarwal0605/protato/internal/utils"
 )
 
 // VerifyCmd verifies workspace integrity.
 type VerifyCmd struct {
-	Offline bool `help:"Don't refresh registry"`
+	Offline        bool   `help:"Don't refresh registry"`
+	Against        string `help:"Check that owned protos compile against the dependencies at this registry branch or snapshot" placeholder:"REF"`
+	ImageOut       string `help:"Write the compiled owned protos to this path as a binary FileDescriptorSet image" placeholder:"PATH" type:"path"`
+	ExcludeImports bool   `help:"Leave imported files, including well-known types, out of the --image-out image"`
+	Lint           bool   `help:"Also check owned protos against the lint rules configured in protato.yaml"`
 }
 
 // verifyCtx holds resources for verification.
@@ -32,6 +40,9 @@ func (c *VerifyCmd) Run(globals *GlobalOptions, ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	if vctx.reg != nil {
+		defer vctx.reg.Close()
+	}
 
 	var hasErrors bool
 
@@ -39,8 +50,15 @@ func (c *VerifyCmd) Run(globals *GlobalOptions, ctx context.Context) error {
 		if err := c.verifyOwnedProjects(ctx, vctx); err != nil {
 			hasErrors = true
 		}
+	}
+
+	if err := c.verifyPulledProjects(ctx, vctx); err != nil {
+		hasErrors = true
+	}
 
-		if err := c.verifyPulledProjects(ctx, vctx); err != nil {
+	if c.Against != "" {
+		if err := c.verifyAgainst(ctx, vctx); err != nil {
+			logger.Log(ctx).Error().Err(err).Str("ref", c.Against).Msg("Owned protos do not compile against registry ref")
 			hasErrors = true
 		}
 	}
@@ -49,6 +67,20 @@ func (c *VerifyCmd) Run(globals *GlobalOptions, ctx context.Context) error {
 		hasErrors = true
 	}
 
+	if c.Lint {
+		if err := c.verifyLint(ctx, globals, vctx.wctx.WS); err != nil {
+			logger.Log(ctx).Error().Err(err).Msg("Lint check failed")
+			hasErrors = true
+		}
+	}
+
+	if c.ImageOut != "" {
+		if err := c.writeImage(ctx, vctx.wctx.WS); err != nil {
+			logger.Log(ctx).Error().Err(err).Str("path", c.ImageOut).Msg("Failed to write image")
+			hasErrors = true
+		}
+	}
+
 	if hasErrors {
 		return fmt.Errorf("verification failed")
 	}
@@ -112,7 +144,124 @@ func (c *VerifyCmd) verifyOwnedProjects(ctx context.Context, vctx *verifyCtx) er
 	return nil
 }
 
+// verifyAgainst compiles the owned protos with dependencies taken from the --against snapshot.
+func (c *VerifyCmd) verifyAgainst(ctx context.Context, vctx *verifyCtx) error {
+	if vctx.reg == nil {
+		return fmt.Errorf("--against requires a registry")
+	}
+
+	snapshot, err := c.resolveAgainst(ctx, vctx.reg, c.Against)
+	if err != nil {
+		return err
+	}
+	logger.Log(ctx).Info().Str("ref", c.Against).Str("snapshot", snapshot.Short()).Msg("Checking owned protos against registry snapshot")
+
+	ws := vctx.wctx.WS
+	projects, err := ws.OwnedProjects()
+	if err != nil {
+		return fmt.Errorf("get owned projects: %w", err)
+	}
+	files, owned, err := collectOwnedProtoFiles(ctx, ws, projects)
+	if err != nil {
+		return err
+	}
+	ownedDir, err := ws.OwnedDirName()
+	if err != nil {
+		return fmt.Errorf("get owned directory: %w", err)
+	}
+
+	return protoc.ValidateAgainstSnapshot(ctx, protoc.ValidateProtosConfig{
+		Cache:         vctx.reg,
+		Snapshot:      snapshot,
+		OwnedDir:      ownedDir,
+		Owned:         owned,
+		WorkspaceRoot: ws.Root(),
+		ServiceName:   ws.ServiceName(),
+		GoogleImports: ws.GoogleImports(),
+	}, files)
+}
+
+// verifyLint checks every owned proto against the configured lint rules and reports each issue.
+func (c *VerifyCmd) verifyLint(ctx context.Context, globals *GlobalOptions, ws local.WorkspaceInterface) error {
+	logger.Log(ctx).Info().Msg("Checking lint rules")
+
+	rules, err := lintRules(ws, nil, nil)
+	if err != nil {
+		return err
+	}
+	projects, err := ws.OwnedProjects()
+	if err != nil {
+		return fmt.Errorf("get owned projects: %w", err)
+	}
+
+	issues, _, err := lintOwnedProtos(ctx, ws, projects, rules)
+	if err != nil {
+		return err
+	}
+	reportLintIssues(globals.reporter(ctx), issues)
+	if len(issues) > 0 {
+		return fmt.Errorf("found %d lint issues", len(issues))
+	}
+	return nil
+}
+
+// writeImage compiles the owned protos from the workspace and writes them to --image-out.
+func (c *VerifyCmd) writeImage(ctx context.Context, ws local.WorkspaceInterface) error {
+	projects, err := ws.OwnedProjects()
+	if err != nil {
+		return fmt.Errorf("get owned projects: %w", err)
+	}
+	files, owned, err := collectOwnedProtoFiles(ctx, ws, projects)
+	if err != nil {
+		return err
+	}
+
+	vendor, err := ws.VendorRoots(ctx)
+	if err != nil {
+		vendor = nil // No vendor dir configured, that's OK
+	}
+
+	image := &descriptorpb.FileDescriptorSet{}
+	if len(files) > 0 {
+		resolver := protoc.NewWorkspaceResolver(ctx, owned, vendor)
+		if image, err = protoc.CompileImage(ctx, resolver, files, !c.ExcludeImports); err != nil {
+			return fmt.Errorf("compile image: %w", err)
+		}
+	}
+
+	data, err := proto.Marshal(image)
+	if err != nil {
+		return fmt.Errorf("marshal image: %w", err)
+	}
+	if err := os.WriteFile(c.ImageOut, data, 0644); err != nil {
+		return fmt.Errorf("write image: %w", err)
+	}
+
+	logger.Log(ctx).Info().Str("path", c.ImageOut).Int("files", len(image.File)).Msg("Wrote image")
+	return nil
+}
+
+// resolveAgainst resolves a registry branch or snapshot hash to a snapshot.
+// Branches are refreshed first unless --offline is set.
+func (c *VerifyCmd) resolveAgainst(ctx context.Context, reg registry.CacheInterface, ref string) (git.Hash, error) {
+	if !c.Offline {
+		if err := reg.RefreshBranch(ctx, ref); err != nil {
+			logger.Log(ctx).Debug().Err(err).Str("ref", ref).Msg("Not a registry branch, trying as a snapshot")
+		}
+	}
+	if snapshot, err := reg.BranchSnapshot(ctx, ref); err == nil {
+		return snapshot, nil
+	}
+
+	if err := reg.EnsureSnapshotAvailable(ctx, git.Hash(ref)); err != nil {
+		return "", fmt.Errorf("resolve %s: %w", ref, err)
+	}
+	return git.Hash(ref), nil
+}
+
 // verifyPulledProjects checks integrity of pulled projects.
+// Projects with file checksums in their lock are checked against those, which
+// needs no registry; others are compared with their registry snapshot.
 func (c *VerifyCmd) verifyPulledProjects(ctx context.Context, vctx *verifyCtx) error {
 	logger.Log(ctx).Info().Msg("Checking pulled project integrity")
 
@@ -124,6 +273,16 @@ func (c *VerifyCmd) verifyPulledProjects(ctx context.Context, vctx *verifyCtx) e
 
 	var hasErrors bool
 	for _, received := range receivedProjects {
+		if received.IsLocal() || hasChecksums(vctx.wctx.WS, received.Project) {
+			if err := c.verifyManifest(ctx, vctx.wctx.WS, received.Project); err != nil {
+				hasErrors = true
+			}
+			continue
+		}
+		if vctx.reg == nil {
+			logger.Log(ctx).Debug().Str("project", string(received.Project)).Msg("No file checksums or registry, skipping pulled project")
+			continue
+		}
 		if err := c.verifyReceivedProject(ctx, vctx, received); err != nil {
 			hasErrors = true
 		}
@@ -135,6 +294,34 @@ func (c *VerifyCmd) verifyPulledProjects(ctx context.Context, vctx *verifyCtx) e
 	return nil
 }
 
+// hasChecksums reports whether a received project's lock records the checksums of its files.
+func hasChecksums(ws local.WorkspaceInterface, project local.ProjectPath) bool {
+	lock, err := ws.GetProjectLock(project)
+	return err == nil && len(lock.Files) > 0
+}
+
+// verifyManifest checks a received project against its manifest and lock file checksums.
+// Locally received projects have no registry snapshot, so these are all there is to check.
+func (c *VerifyCmd) verifyManifest(ctx context.Context, ws local.WorkspaceInterface, project local.ProjectPath) error {
+	if _, err := ws.GetProjectManifest(project); err != nil && !hasChecksums(ws, project) {
+		logger.Log(ctx).Debug().Str("project", string(project)).Msg("No manifest, skipping locally received project")
+		return nil
+	}
+
+	mismatched, err := ws.VerifyVendorIntegrity(project)
+	if err != nil {
+		logger.Log(ctx).Error().Str("project", string(project)).Err(err).Msg("Failed to verify manifest")
+		return err
+	}
+	for _, path := range mismatched {
+		logProjectFileError(ctx, registry.ProjectPath(project), path, "File differs from what was received")
+	}
+	if len(mismatched) > 0 {
+		return fmt.Errorf("project %s has local modifications", project)
+	}
+	return nil
+}
+
 // verifyReceivedProject checks a single received project.
 func (c *VerifyCmd) verifyReceivedProject(ctx context.Context, vctx *verifyCtx, received *local.ReceivedProject) error {
 	snapshot := git.Hash(received.ProviderSnapshot)
@@ -172,6 +359,11 @@ func (c *VerifyCmd) verifyReceivedProject(ctx context.Context, vctx *verifyCtx,
 
 // getProjectFiles retrieves files from both registry and local workspace.
 func (c *VerifyCmd) getProjectFiles(ctx context.Context, vctx *verifyCtx, project registry.ProjectPath, snapshot git.Hash) ([]registry.ProjectFile, []local.ProjectFile, error) {
+	if err := vctx.reg.EnsureSnapshotAvailable(ctx, snapshot); err != nil {
+		logProjectError(ctx, err, project, "Locked snapshot is unavailable")
+		return nil, nil, err
+	}
+
 	regFiles, err := vctx.reg.ListProjectFiles(ctx, &registry.ListProjectFilesRequest{
 		Project:  project,
 		Snapshot: snapshot,
@@ -8,12 +8,12 @@ require (
 	github.com/bufbuild/protocompile v0.14.1
 	github.com/mattn/go-isatty v0.0.20
 	github.com/rs/zerolog v1.34.0
+	golang.org/x/sync v0.8.0
+	google.golang.org/protobuf v1.34.2
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
-	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/sys v0.12.0 // indirect
-	google.golang.org/protobuf v1.34.2 // indirect
 )
@@ -5,6 +5,7 @@
 //   - Directory names: Directory paths used in the registry
 //   - File extensions: File extension constants
 //   - Proto-related: Constants specific to protobuf processing
+//   - Lock-related: Constants describing protato.lock contents
 //   - Error message strings: String constants for error matching/comparison
 //   - Validation: Validation error message strings
 package constants
@@ -17,17 +18,38 @@ const (
 	// LockFileName is the name of the protato lock file.
 	LockFileName = "protato.lock"
 
+	// ManifestFileName is the name of the manifest of received files, written next to the lock file.
+	ManifestFileName = "received.manifest.yaml"
+
 	// GitattributesName is the name of the gitattributes file.
 	GitattributesName = ".gitattributes"
 
 	// ProjectMetaFile is the name of the project metadata file in the registry.
 	ProjectMetaFile = "protato.root.yaml"
+
+	// RegistryConfigFile is the name of the registry configuration file at the registry root.
+	RegistryConfigFile = "protato.registry.yaml"
+
+	// KeepFileName is the placeholder file used to track empty directories.
+	KeepFileName = ".gitkeep"
+
+	// BufConfigFileName is the name of the buf module configuration file.
+	BufConfigFileName = "buf.yaml"
+
+	// GitignoreName is the name of the gitignore file.
+	GitignoreName = ".gitignore"
+
+	// IgnoreFileName is the name of the workspace ignore file, read next to protato.yaml.
+	IgnoreFileName = ".protatoignore"
 )
 
 // Directory names
 const (
 	// ProtosDir is the directory name for proto files in the registry.
 	ProtosDir = "protos"
+
+	// BlobCacheDir is the directory in the registry cache that holds file contents by blob hash.
+	BlobCacheDir = "protato-blobs"
 )
 
 // File extensions
@@ -42,8 +64,22 @@ const (
 	// These are provided by protocompile and should not be resolved from the registry.
 	GoogleProtobufPrefix = "google/protobuf/"
 
-	// ImportKeyword is the "import " keyword used in proto files.
-	ImportKeyword = "import "
+	// GooglePrefix is the import path prefix shared by all Google-published protos.
+	// Imports under it are checked against the well-known import allowlist during validation.
+	GooglePrefix = "google/"
+
+	// ImportKeyword is the keyword that starts an import statement in proto files.
+	ImportKeyword = "import"
+
+	// PackageKeyword is the keyword that starts a package statement in proto files.
+	PackageKeyword = "package"
+)
+
+// Lock-related constants
+const (
+	// LocalSnapshotPrefix prefixes the synthetic snapshot written to the lock file
+	// of projects received from a local directory instead of the registry.
+	LocalSnapshotPrefix = "local-"
 )
 
 // Error message strings (for error matching/comparison)
@@ -59,6 +95,18 @@ const (
 
 	// ErrMsgCompilationFailed is the error message for proto compilation failures.
 	ErrMsgCompilationFailed = "proto compilation failed"
+
+	// ErrMsgFileTooLarge is the error message for files exceeding the push size limit.
+	ErrMsgFileTooLarge = "files exceed size limit"
+
+	// ErrMsgAmend is the error message for registry commits that cannot be amended.
+	ErrMsgAmend = "cannot amend registry commit"
+
+	// ErrMsgSymlink is the error message for symlinked files that cannot be published.
+	ErrMsgSymlink = "cannot publish symlink"
+
+	// ErrMsgBreakingChanges is the error message for pushes rejected by --check-breaking.
+	ErrMsgBreakingChanges = "breaking changes found"
 )
 
 // Validation error messages
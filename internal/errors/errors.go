@@ -3,6 +3,7 @@
 // Errors are organized by domain:
 //   - Workspace errors: Related to local workspace operations
 //   - Registry errors: Related to registry operations
+//   - Claim errors: Reasons a project cannot be claimed
 package errors
 
 import "errors"
@@ -23,10 +24,64 @@ var (
 
 	// ErrNotInitialized is returned when trying to open a non-initialized workspace.
 	ErrNotInitialized = errors.New("workspace not initialized")
+
+	// ErrOwnedDirsOverlap is returned when one configured owned directory is inside another.
+	ErrOwnedDirsOverlap = errors.New("owned directories overlap")
+
+	// ErrDuplicateOwnedProject is returned when a project is found in more than one owned directory.
+	ErrDuplicateOwnedProject = errors.New("project is in more than one owned directory")
+
+	// ErrVendorContainsOwned is returned when cleaning a vendor directory that contains the owned directory.
+	ErrVendorContainsOwned = errors.New("vendor directory contains the owned directory")
 )
 
 // Registry errors are returned by registry-related operations.
 var (
 	// ErrNotFound is returned when a project is not found.
 	ErrNotFound = errors.New("project not found")
+
+	// ErrSnapshotNotFound is returned when a snapshot commit does not exist in the registry.
+	ErrSnapshotNotFound = errors.New("snapshot not found in registry")
+
+	// ErrDisallowedFile is returned when a pushed file's extension is not allowed by the registry.
+	ErrDisallowedFile = errors.New("file type not allowed by registry")
+
+	// ErrApprovalRequired is returned when a project that requires approval is pushed without one.
+	ErrApprovalRequired = errors.New("project requires an approval to push")
+
+	// ErrSelfApproval is returned when the approval of a push names its own author.
+	ErrSelfApproval = errors.New("push cannot be approved by its author")
+
+	// ErrNoMergeBase is returned when two snapshots share no history.
+	ErrNoMergeBase = errors.New("snapshots have no common ancestor")
+
+	// ErrObjectMissing is returned for an object that is not in the registry cache.
+	ErrObjectMissing = errors.New("object missing from repository")
+
+	// ErrUnsupportedConfigVersion is returned for a registry config newer than this build supports.
+	ErrUnsupportedConfigVersion = errors.New("unsupported registry config version")
+
+	// ErrImportCycle is returned when registry projects import each other in a cycle.
+	ErrImportCycle = errors.New("circular project dependency")
+
+	// ErrCacheBusy is returned when another process holds the registry cache lock for too long.
+	ErrCacheBusy = errors.New("registry cache busy")
+)
+
+// Claim errors explain why a project cannot be claimed.
+var (
+	// ErrOwnershipConflict is returned when the project is owned by another repository.
+	ErrOwnershipConflict = errors.New("project is owned by another repository")
+
+	// ErrSubprojectConflict is returned when existing projects are nested under the path.
+	ErrSubprojectConflict = errors.New("existing projects are nested under the path")
+
+	// ErrParentProjectExists is returned when a parent of the path is already a project.
+	ErrParentProjectExists = errors.New("parent project already exists")
+
+	// ErrProjectExists is returned when the path is already a project of the same repository.
+	ErrProjectExists = errors.New("project already exists")
+
+	// ErrCaseConflict is returned when the path differs from an existing project only by case.
+	ErrCaseConflict = errors.New("path differs from an existing project only by case")
 )
@@ -55,6 +55,16 @@ func TestWorkspaceErrors(t *testing.T) {
 				ErrNotInitialized.Error(), "workspace not initialized")
 		}
 	})
+
+	t.Run("ErrVendorContainsOwned", func(t *testing.T) {
+		if ErrVendorContainsOwned == nil {
+			t.Error("ErrVendorContainsOwned should not be nil")
+		}
+		if ErrVendorContainsOwned.Error() != "vendor directory contains the owned directory" {
+			t.Errorf("ErrVendorContainsOwned.Error() = %v, want %v",
+				ErrVendorContainsOwned.Error(), "vendor directory contains the owned directory")
+		}
+	})
 }
 
 func TestRegistryErrors(t *testing.T) {
@@ -67,6 +77,13 @@ func TestRegistryErrors(t *testing.T) {
 				ErrNotFound.Error(), "project not found")
 		}
 	})
+
+	t.Run("ErrSnapshotNotFound", func(t *testing.T) {
+		if ErrSnapshotNotFound.Error() != "snapshot not found in registry" {
+			t.Errorf("ErrSnapshotNotFound.Error() = %v, want %v",
+				ErrSnapshotNotFound.Error(), "snapshot not found in registry")
+		}
+	})
 }
 
 func TestErrorsAreDistinct(t *testing.T) {
@@ -76,7 +93,13 @@ func TestErrorsAreDistinct(t *testing.T) {
 		ErrServiceNotConfigured,
 		ErrAlreadyInitialized,
 		ErrNotInitialized,
+		ErrVendorContainsOwned,
 		ErrNotFound,
+		ErrSnapshotNotFound,
+		ErrOwnershipConflict,
+		ErrSubprojectConflict,
+		ErrParentProjectExists,
+		ErrCaseConflict,
 	}
 
 	for i, err1 := range errs {
@@ -98,7 +121,9 @@ func TestErrorWrapping(t *testing.T) {
 		{"ErrServiceNotConfigured", ErrServiceNotConfigured},
 		{"ErrAlreadyInitialized", ErrAlreadyInitialized},
 		{"ErrNotInitialized", ErrNotInitialized},
+		{"ErrVendorContainsOwned", ErrVendorContainsOwned},
 		{"ErrNotFound", ErrNotFound},
+		{"ErrSnapshotNotFound", ErrSnapshotNotFound},
 	}
 
 	for _, tt := range tests {
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -11,7 +13,9 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	protatoerrors "github.com/rahulagarwal0605/protato/internal/errors"
 	"github.com/rahulagarwal0605/protato/internal/logger"
 	"github.com/rahulagarwal0605/protato/internal/utils"
 )
@@ -22,30 +26,58 @@ type RepositoryInterface interface {
 	GitDir() string
 	IsBare() bool
 	Fetch(context.Context, FetchOptions) error
+	Unshallow(context.Context) error
 	Push(context.Context, PushOptions) error
 	RevHash(context.Context, string) (Hash, error)
 	RevExists(context.Context, string) bool
 	ReadTree(context.Context, Treeish, ReadTreeOptions) ([]TreeEntry, error)
+	WalkTree(context.Context, Treeish, ReadTreeOptions, func(TreeEntry) error) error
 	WriteObject(context.Context, io.Reader, WriteObjectOptions) (Hash, error)
+	WriteObjectBatch(context.Context, []io.Reader) ([]Hash, error)
 	ReadObject(context.Context, ObjectType, Hash, io.Writer) error
+	CatFileBatch(context.Context, []Hash, func(Hash, ObjectType, io.Reader) error) error
+	Diff(context.Context, Hash, Hash) (string, error)
+	DiffTree(context.Context, Treeish, Treeish, DiffOptions) ([]DiffEntry, error)
 	UpdateTree(context.Context, UpdateTreeRequest) (Hash, error)
 	CommitTree(context.Context, CommitTreeRequest) (Hash, error)
 	UpdateRef(context.Context, string, Hash, Hash) error
 	GetRemoteURL(context.Context, string) (string, error)
+	LsRemote(context.Context, LsRemoteOptions) ([]RemoteRef, error)
+	SymbolicRef(context.Context, string) (string, error)
+	MergeBase(context.Context, Treeish, Treeish) (Hash, error)
+	Log(context.Context, LogOptions) ([]Commit, error)
 	GetUser(context.Context) (Author, error)
 	GetRepoURL(context.Context) (string, error)
+	ConfigList(context.Context) (map[string]string, error)
+	Gc(context.Context, GcOptions) error
 }
 
 // Repository represents a Git repository.
 type Repository struct {
-	gitDir  string // .git directory
-	bare    bool   // Bare repository flag
-	rootDir string // Working directory
-	exec    Execer // Command executor
+	gitDir  string   // .git directory
+	bare    bool     // Bare repository flag
+	rootDir string   // Working directory
+	exec    Execer   // Command executor
+	authEnv []string // Environment carrying the remote credentials; never logged
 }
 
 // Clone clones a repository.
 func Clone(ctx context.Context, url, path string, opts CloneOptions) (*Repository, error) {
+	args := cloneArgs(url, path, opts)
+	authEnv, err := opts.Auth.env()
+	if err != nil {
+		return nil, err
+	}
+	cmd := newGitCmd(args...).Env(authEnv...)
+	if err := cmd.Run(ctx, GetExecer(ctx)); err != nil {
+		return nil, fmt.Errorf("clone: %w", err)
+	}
+
+	return Open(ctx, path, OpenOptions{Bare: opts.Bare, Auth: opts.Auth})
+}
+
+// cloneArgs builds the git clone arguments.
+func cloneArgs(url, path string, opts CloneOptions) []string {
 	args := []string{"clone"}
 	if opts.Bare {
 		args = append(args, "--bare")
@@ -56,14 +88,30 @@ func Clone(ctx context.Context, url, path string, opts CloneOptions) (*Repositor
 	if opts.Depth > 0 {
 		args = append(args, "--depth", strconv.Itoa(opts.Depth))
 	}
-	args = append(args, url, path)
+	if opts.Branch != "" {
+		args = append(args, "--branch", opts.Branch, "--single-branch")
+	}
+	return append(args, url, path)
+}
 
-	cmd := newGitCmd(args...)
+// InitBare initializes a new bare repository at path.
+func InitBare(ctx context.Context, path string, opts InitOptions) (*Repository, error) {
+	cmd := newGitCmd(initBareArgs(path, opts)...)
 	if err := cmd.Run(ctx, GetExecer(ctx)); err != nil {
-		return nil, fmt.Errorf("clone: %w", err)
+		return nil, fmt.Errorf("init: %w", err)
 	}
 
-	return Open(ctx, path, OpenOptions{Bare: opts.Bare})
+	return Open(ctx, path, OpenOptions{Bare: true})
+}
+
+// initBareArgs builds the git init arguments for a bare repository.
+func initBareArgs(path string, opts InitOptions) []string {
+	var args []string
+	if opts.InitialBranch != "" {
+		// Set via config so older git versions without --initial-branch still work
+		args = append(args, "-c", "init.defaultBranch="+opts.InitialBranch)
+	}
+	return append(args, "init", "--bare", path)
 }
 
 // Open opens an existing repository.
@@ -73,9 +121,14 @@ func Open(ctx context.Context, path string, opts OpenOptions) (*Repository, erro
 		return nil, fmt.Errorf("abs path: %w", err)
 	}
 
+	authEnv, err := opts.Auth.env()
+	if err != nil {
+		return nil, err
+	}
 	repo := &Repository{
-		exec: GetExecer(ctx),
-		bare: opts.Bare,
+		exec:    GetExecer(ctx),
+		bare:    opts.Bare,
+		authEnv: authEnv,
 	}
 
 	if opts.Bare {
@@ -118,7 +171,7 @@ func (r *Repository) IsBare() bool {
 
 // gitCmd creates a new Git command.
 func (r *Repository) gitCmd(args ...string) *gitCmd {
-	cmd := newGitCmd(args...)
+	cmd := newGitCmd(args...).Env(r.authEnv...)
 	if r.bare {
 		cmd.env = append(cmd.env, "GIT_DIR="+r.gitDir)
 		cmd.dir = r.gitDir // Also set working directory for bare repos
@@ -140,6 +193,15 @@ func (r *Repository) Fetch(ctx context.Context, opts FetchOptions) error {
 	if opts.Force {
 		args = append(args, "--force")
 	}
+	if opts.NoWriteFetchHead {
+		args = append(args, "--no-write-fetch-head")
+	}
+	if opts.Unshallow {
+		args = append(args, "--unshallow")
+	}
+	if opts.Deepen > 0 {
+		args = append(args, "--deepen", strconv.Itoa(opts.Deepen))
+	}
 	if opts.Remote != "" {
 		args = append(args, opts.Remote)
 	}
@@ -148,6 +210,19 @@ func (r *Repository) Fetch(ctx context.Context, opts FetchOptions) error {
 	return r.gitCmd(args...).Run(ctx, r.exec)
 }
 
+// Unshallow fetches the complete history from origin. A repository that is not
+// shallow is left as it is, since git refuses to unshallow it.
+func (r *Repository) Unshallow(ctx context.Context) error {
+	out, err := r.gitCmd("rev-parse", "--is-shallow-repository").Output(ctx, r.exec)
+	if err != nil {
+		return fmt.Errorf("rev-parse --is-shallow-repository: %w", err)
+	}
+	if utils.TrimOutputToString(out) != "true" {
+		return nil
+	}
+	return r.Fetch(ctx, FetchOptions{Remote: "origin", Unshallow: true, NoWriteFetchHead: true})
+}
+
 // Push pushes to a remote.
 func (r *Repository) Push(ctx context.Context, opts PushOptions) error {
 	args := []string{"push"}
@@ -157,6 +232,9 @@ func (r *Repository) Push(ctx context.Context, opts PushOptions) error {
 	if opts.Force {
 		args = append(args, "--force")
 	}
+	for _, lease := range opts.ForceWithLease {
+		args = append(args, fmt.Sprintf("--force-with-lease=%s:%s", lease.Ref, lease.Expect))
+	}
 	if opts.Remote != "" {
 		args = append(args, opts.Remote)
 	}
@@ -165,6 +243,35 @@ func (r *Repository) Push(ctx context.Context, opts PushOptions) error {
 	return r.gitCmd(args...).Run(ctx, r.exec)
 }
 
+// Gc compacts the repository: loose objects are packed and unreachable ones pruned.
+// With RepackOnly, objects are repacked into a single pack and nothing is pruned.
+func (r *Repository) Gc(ctx context.Context, opts GcOptions) error {
+	return r.gitCmd(gcArgs(opts)...).Run(ctx, r.exec)
+}
+
+// gcArgs returns the git arguments for compacting with opts.
+func gcArgs(opts GcOptions) []string {
+	if opts.RepackOnly {
+		args := []string{"repack", "-a", "-d", "-q"}
+		if opts.Aggressive {
+			args = append(args, "-f")
+		}
+		return args
+	}
+
+	args := []string{"gc", "--quiet"}
+	if opts.Auto {
+		args = append(args, "--auto")
+	}
+	if opts.Aggressive {
+		args = append(args, "--aggressive")
+	}
+	if opts.Prune != "" {
+		args = append(args, "--prune="+opts.Prune)
+	}
+	return args
+}
+
 // trimOutputToHash converts command output to a Hash.
 func trimOutputToHash(out []byte) Hash {
 	return Hash(utils.TrimOutputToString(out))
@@ -178,6 +285,36 @@ func appendRefspecs(args []string, refspecs []Refspec) []string {
 	return args
 }
 
+// env returns the environment that passes the credentials to git. Credentials go through
+// the environment rather than arguments or config files, so they are neither logged
+// nor written to the user's git config.
+func (a AuthOptions) env() ([]string, error) {
+	if a.Token != "" && (a.Username != "" || a.Password != "") {
+		return nil, fmt.Errorf("auth: token and username/password are mutually exclusive")
+	}
+
+	var env []string
+	var header string
+	switch {
+	case a.Token != "":
+		header = "Authorization: Bearer " + a.Token
+	case a.Username != "" || a.Password != "":
+		header = "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(a.Username+":"+a.Password))
+	}
+	if header != "" {
+		env = append(env, "GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader", "GIT_CONFIG_VALUE_0="+header)
+	}
+	if a.SSHKeyPath != "" {
+		env = append(env, "GIT_SSH_COMMAND=ssh -i "+shellQuote(a.SSHKeyPath)+" -o IdentitiesOnly=yes")
+	}
+	return env, nil
+}
+
+// shellQuote quotes s for the shell that runs GIT_SSH_COMMAND.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 // appendEnvToCmd appends environment variables to a git command.
 func appendEnvToCmd(cmd *gitCmd, env []string) {
 	cmd.env = append(cmd.env, env...)
@@ -260,9 +397,81 @@ func (r *Repository) RevExists(ctx context.Context, rev string) bool {
 	return err == nil
 }
 
+// CheckIntegrity runs a quick check that the repository is readable.
+// A clone interrupted before git set up the repository fails here.
+func (r *Repository) CheckIntegrity(ctx context.Context) error {
+	if err := r.gitCmd("rev-parse", "--git-dir").Run(ctx, r.exec); err != nil {
+		return fmt.Errorf("rev-parse: %w", err)
+	}
+	return nil
+}
+
+// CheckConnectivity checks that every ref points at objects that are all present.
+// It walks the whole object graph, so it is much slower than CheckIntegrity.
+func (r *Repository) CheckConnectivity(ctx context.Context) error {
+	if err := r.gitCmd("fsck", "--connectivity-only", "--no-progress").Run(ctx, r.exec); err != nil {
+		return fmt.Errorf("fsck: %w", err)
+	}
+	return nil
+}
+
 // ReadTree reads a tree's contents.
 func (r *Repository) ReadTree(ctx context.Context, treeish Treeish, opts ReadTreeOptions) ([]TreeEntry, error) {
-	args := []string{"ls-tree"}
+	out, err := r.gitCmd(readTreeArgs(treeish, opts)...).Output(ctx, r.exec)
+	if err != nil {
+		return nil, fmt.Errorf("ls-tree: %w", err)
+	}
+
+	return parseTreeOutput(out)
+}
+
+// WalkTree reads a tree's contents like ReadTree, but parses the ls-tree output as it
+// arrives and calls fn for each entry instead of collecting them, so memory stays flat
+// on very large trees. Walking stops at the first error returned by fn, which WalkTree
+// returns as is, or when ctx is cancelled.
+func (r *Repository) WalkTree(ctx context.Context, treeish Treeish, opts ReadTreeOptions, fn func(TreeEntry) error) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := r.gitCmd(readTreeArgs(treeish, opts)...).RunWithStdout(runCtx, r.exec, pw)
+		pw.CloseWithError(err) // A nil error closes the pipe with io.EOF
+		done <- err
+	}()
+
+	var fnErr error
+	readErr := walkTreeOutput(pr, func(entry TreeEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fnErr = fn(entry)
+		return fnErr
+	})
+	if readErr != nil {
+		// Stop git and unblock its writes before waiting for it
+		cancel()
+		pr.CloseWithError(readErr)
+	}
+	runErr := <-done
+
+	switch {
+	case fnErr != nil:
+		return fnErr
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case runErr != nil:
+		return fmt.Errorf("ls-tree: %w", runErr)
+	case readErr != nil:
+		return fmt.Errorf("ls-tree: %w", readErr)
+	}
+	return nil
+}
+
+// readTreeArgs returns the ls-tree arguments for reading treeish with opts.
+func readTreeArgs(treeish Treeish, opts ReadTreeOptions) []string {
+	args := []string{"ls-tree", "-z"}
 	if opts.Recurse {
 		args = append(args, "-r")
 	}
@@ -271,55 +480,86 @@ func (r *Repository) ReadTree(ctx context.Context, treeish Treeish, opts ReadTre
 		args = append(args, "--")
 		args = append(args, opts.Paths...)
 	}
+	return args
+}
 
-	out, err := r.gitCmd(args...).Output(ctx, r.exec)
-	if err != nil {
-		return nil, fmt.Errorf("ls-tree: %w", err)
+// walkTreeOutput parses NUL-terminated ls-tree records from r one at a time and calls fn for each.
+func walkTreeOutput(r io.Reader, fn func(TreeEntry) error) error {
+	br := bufio.NewReader(r)
+	for {
+		record, err := br.ReadBytes(0)
+		if len(record) > 0 {
+			if entry, ok := parseTreeRecord(string(bytes.TrimSuffix(record, []byte{0})), false); ok {
+				if err := fn(entry); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
 	}
-
-	return parseTreeOutput(out)
 }
 
 // parseTreeOutput parses the output of git ls-tree.
+// Records are NUL-terminated as printed with -z, which leaves paths unquoted.
+// Newline-terminated output is accepted too, with git's C-style quoted paths decoded.
 func parseTreeOutput(data []byte) ([]TreeEntry, error) {
+	sep, quoted := byte('\n'), true
+	if bytes.IndexByte(data, 0) >= 0 {
+		sep, quoted = 0, false
+	}
+
 	var entries []TreeEntry
-	scanner := bufio.NewScanner(bytes.NewReader(data))
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "" {
-			continue
+	for _, record := range bytes.Split(data, []byte{sep}) {
+		if entry, ok := parseTreeRecord(string(record), quoted); ok {
+			entries = append(entries, entry)
 		}
+	}
+	return entries, nil
+}
 
-		// Format: <mode> <type> <hash>\t<path>
-		parts := strings.SplitN(line, "\t", 2)
-		if len(parts) != 2 {
-			continue
-		}
+// parseTreeRecord parses one ls-tree record: <mode> <type> <hash>\t<path>.
+// Malformed records are skipped.
+func parseTreeRecord(record string, quoted bool) (TreeEntry, bool) {
+	metaPart, path, ok := strings.Cut(record, "\t")
+	if !ok {
+		return TreeEntry{}, false
+	}
 
-		meta := strings.Fields(parts[0])
-		if len(meta) != 3 {
-			continue
-		}
+	meta := strings.Fields(metaPart)
+	if len(meta) != 3 {
+		return TreeEntry{}, false
+	}
 
-		mode, err := strconv.ParseUint(meta[0], 8, 32)
-		if err != nil {
-			continue
-		}
+	mode, err := strconv.ParseUint(meta[0], 8, 32)
+	if err != nil {
+		return TreeEntry{}, false
+	}
 
-		objType, err := ParseObjectType(meta[1])
+	objType, err := ParseObjectType(meta[1])
+	if err != nil {
+		return TreeEntry{}, false
+	}
+
+	if quoted && strings.HasPrefix(path, `"`) {
+		// Git escapes special characters the same way Go string literals do (\t, \", \ooo octal bytes)
+		unquoted, err := strconv.Unquote(path)
 		if err != nil {
-			continue
+			return TreeEntry{}, false
 		}
-
-		entries = append(entries, TreeEntry{
-			Mode: uint32(mode),
-			Type: objType,
-			Hash: Hash(meta[2]),
-			Path: parts[1],
-		})
+		path = unquoted
 	}
 
-	return entries, scanner.Err()
+	return TreeEntry{
+		Mode: uint32(mode),
+		Type: objType,
+		Hash: Hash(meta[2]),
+		Path: path,
+	}, true
 }
 
 // WriteObject writes an object to the store.
@@ -336,12 +576,421 @@ func (r *Repository) WriteObject(ctx context.Context, body io.Reader, opts Write
 	return r.executeGitOutputToHashWithStdin(ctx, cmd, body, "hash-object")
 }
 
+// WriteObjectBatch writes blobs to the store with a single git process.
+// The returned hashes are in the order of bodies. Bodies are staged in a
+// temporary directory and handed to `git hash-object --stdin-paths`.
+func (r *Repository) WriteObjectBatch(ctx context.Context, bodies []io.Reader) ([]Hash, error) {
+	if len(bodies) == 0 {
+		return nil, nil
+	}
+
+	dir, err := os.MkdirTemp("", "protato-objects-")
+	if err != nil {
+		return nil, fmt.Errorf("create staging dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	paths, err := stageObjectBodies(dir, bodies)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := r.gitCmd("hash-object", "-w", "--no-filters", "--stdin-paths")
+	out, err := cmd.OutputWithStdin(ctx, r.exec, strings.NewReader(strings.Join(paths, "\n")+"\n"))
+	if err != nil {
+		return nil, fmt.Errorf("hash-object: %w", err)
+	}
+	return parseBatchHashes(out, len(bodies))
+}
+
+// stageObjectBodies writes each body to its own file in dir and returns the file paths.
+func stageObjectBodies(dir string, bodies []io.Reader) ([]string, error) {
+	paths := make([]string, len(bodies))
+	for i, body := range bodies {
+		paths[i] = filepath.Join(dir, strconv.Itoa(i))
+		f, err := os.Create(paths[i])
+		if err != nil {
+			return nil, fmt.Errorf("stage object: %w", err)
+		}
+		_, err = io.Copy(f, body)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("stage object: %w", err)
+		}
+	}
+	return paths, nil
+}
+
+// parseBatchHashes parses one hash per line and checks that there are want of them.
+func parseBatchHashes(out []byte, want int) ([]Hash, error) {
+	lines := strings.Fields(string(out))
+	if len(lines) != want {
+		return nil, fmt.Errorf("hash-object: got %d hashes for %d objects", len(lines), want)
+	}
+	hashes := make([]Hash, len(lines))
+	for i, line := range lines {
+		hashes[i] = Hash(line)
+	}
+	return hashes, nil
+}
+
 // ReadObject reads an object from the store.
 func (r *Repository) ReadObject(ctx context.Context, objType ObjectType, hash Hash, writer io.Writer) error {
 	cmd := r.gitCmd("cat-file", objType.String(), hash.String())
 	return cmd.RunWithStdout(ctx, r.exec, writer)
 }
 
+// CatFileBatch reads many objects through a single git cat-file --batch process.
+// fn is called for each object in the order of hashes, with a reader over its content
+// that is only valid until fn returns. Objects that do not exist are skipped and reported
+// together in the returned error, each wrapping protatoerrors.ErrObjectMissing. An error
+// from fn stops the read and is returned as is.
+func (r *Repository) CatFileBatch(ctx context.Context, hashes []Hash, fn func(Hash, ObjectType, io.Reader) error) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	var stdin strings.Builder
+	for _, h := range hashes {
+		stdin.WriteString(string(h) + "\n")
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		cmd := r.gitCmd("cat-file", "--batch")
+		execCmd := cmd.toExecCmd(runCtx)
+		execCmd.Stdin = strings.NewReader(stdin.String())
+		execCmd.Stdout = pw
+		cmd.logGitCommand(ctx, "Executing git command with stdin")
+		err := r.exec.Run(execCmd)
+		pw.CloseWithError(err) // A nil error closes the pipe with io.EOF
+		done <- err
+	}()
+
+	var fnErr error
+	missing, readErr := parseCatFileBatch(pr, hashes, func(h Hash, t ObjectType, content io.Reader) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fnErr = fn(h, t, content)
+		return fnErr
+	})
+	if readErr != nil {
+		// Stop git and unblock its writes before waiting for it
+		cancel()
+		pr.CloseWithError(readErr)
+	}
+	runErr := <-done
+
+	switch {
+	case fnErr != nil:
+		return fnErr
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case runErr != nil:
+		return fmt.Errorf("cat-file --batch: %w", runErr)
+	case readErr != nil:
+		return fmt.Errorf("cat-file --batch: %w", readErr)
+	}
+	return missing
+}
+
+// parseCatFileBatch parses git cat-file --batch output for the requested hashes, in order.
+// Each object is a "<hash> <type> <size>" header line, size bytes of content and a newline;
+// an object that cannot be read is a single "<name> missing" (or "ambiguous") line.
+// Unreadable objects are returned as one joined error rather than stopping the parse.
+func parseCatFileBatch(r io.Reader, hashes []Hash, fn func(Hash, ObjectType, io.Reader) error) (missing error, err error) {
+	br := bufio.NewReader(r)
+	var missingErrs []error
+	for _, h := range hashes {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("read header for %s: %w", h, unexpectedEOF(err))
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 2 && (fields[1] == "missing" || fields[1] == "ambiguous") {
+			missingErrs = append(missingErrs, fmt.Errorf("object %s %s: %w", h, fields[1], protatoerrors.ErrObjectMissing))
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("malformed header for %s: %q", h, strings.TrimSpace(line))
+		}
+		objType, err := ParseObjectType(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("header for %s: %w", h, err)
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("malformed size for %s: %q", h, fields[2])
+		}
+
+		content := &io.LimitedReader{R: br, N: size}
+		if err := fn(h, objType, content); err != nil {
+			return nil, err
+		}
+		// Skip whatever fn left unread, then the newline after the content
+		if _, err := io.Copy(io.Discard, content); err != nil {
+			return nil, fmt.Errorf("read %s: %w", h, err)
+		}
+		if content.N > 0 {
+			return nil, fmt.Errorf("read %s: %w", h, io.ErrUnexpectedEOF)
+		}
+		if b, err := br.ReadByte(); err != nil || b != '\n' {
+			return nil, fmt.Errorf("missing newline after %s", h)
+		}
+	}
+	return errors.Join(missingErrs...), nil
+}
+
+// unexpectedEOF turns io.EOF into io.ErrUnexpectedEOF, for output that ended early.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// ParseCommit parses the raw content of a commit object, as printed by `git cat-file commit`.
+// The commit has its tree, parents, author and message set, but not its hash or date.
+func ParseCommit(data []byte) (*Commit, error) {
+	header, message, _ := strings.Cut(string(data), "\n\n")
+	commit := &Commit{Message: message}
+
+	for _, line := range strings.Split(header, "\n") {
+		field, value, _ := strings.Cut(line, " ")
+		switch field {
+		case "tree":
+			commit.Tree = Hash(value)
+		case "parent":
+			commit.Parents = append(commit.Parents, Hash(value))
+		case "author":
+			commit.Author = parseSignature(value)
+		}
+	}
+
+	if commit.Tree == "" {
+		return nil, fmt.Errorf("parse commit: missing tree")
+	}
+	return commit, nil
+}
+
+// parseSignature parses the "Name <email> timestamp tz" value of an author or committer line.
+func parseSignature(value string) Author {
+	name, rest, ok := strings.Cut(value, " <")
+	if !ok {
+		return Author{Name: value}
+	}
+	email, _, _ := strings.Cut(rest, ">")
+	return Author{Name: name, Email: email}
+}
+
+// Diff returns the unified diff between two blob objects.
+// Identical hashes short-circuit to an empty diff without invoking git.
+func (r *Repository) Diff(ctx context.Context, oldHash, newHash Hash) (string, error) {
+	if oldHash == newHash {
+		return "", nil
+	}
+
+	out, err := r.gitCmd(diffArgs(oldHash, newHash)...).Output(ctx, r.exec)
+	if err != nil {
+		return "", fmt.Errorf("diff %s %s: %w", oldHash.Short(), newHash.Short(), err)
+	}
+	return string(out), nil
+}
+
+// diffArgs builds the git diff arguments for comparing two blobs.
+func diffArgs(oldHash, newHash Hash) []string {
+	return []string{"diff", "--no-color", "--no-ext-diff", oldHash.String(), newHash.String()}
+}
+
+// DiffTree returns the paths that differ between two tree-ishes, in git's path order.
+// Only files are reported; changes inside subtrees are listed by their full path.
+func (r *Repository) DiffTree(ctx context.Context, oldTree, newTree Treeish, opts DiffOptions) ([]DiffEntry, error) {
+	out, err := r.gitCmd(diffTreeArgs(oldTree, newTree, opts)...).Output(ctx, r.exec)
+	if err != nil {
+		return nil, fmt.Errorf("diff-tree %s %s: %w", oldTree, newTree, err)
+	}
+	entries, err := parseDiffOutput(out)
+	if err != nil {
+		return nil, fmt.Errorf("diff-tree %s %s: %w", oldTree, newTree, err)
+	}
+	return entries, nil
+}
+
+// diffTreeArgs builds the git diff-tree arguments for comparing two tree-ishes.
+func diffTreeArgs(oldTree, newTree Treeish, opts DiffOptions) []string {
+	args := []string{"diff-tree", "-r", "-z", "--name-status"}
+	if opts.FindRenames {
+		args = append(args, "-M")
+	}
+	args = append(args, oldTree.String(), newTree.String())
+	if len(opts.Paths) > 0 {
+		args = append(args, "--")
+		args = append(args, opts.Paths...)
+	}
+	return args
+}
+
+// parseDiffOutput parses the output of git diff-tree --name-status.
+// Fields are NUL-separated as printed with -z, which leaves paths unquoted.
+// Newline-terminated output, with tab-separated fields and C-style quoted paths, is accepted too.
+func parseDiffOutput(data []byte) ([]DiffEntry, error) {
+	if bytes.IndexByte(data, 0) < 0 {
+		var entries []DiffEntry
+		for _, line := range strings.Split(string(data), "\n") {
+			if line == "" {
+				continue
+			}
+			fields := strings.Split(line, "\t")
+			for i, field := range fields[1:] {
+				if strings.HasPrefix(field, `"`) {
+					unquoted, err := strconv.Unquote(field)
+					if err != nil {
+						return nil, fmt.Errorf("parse diff path %s: %w", field, err)
+					}
+					fields[i+1] = unquoted
+				}
+			}
+			entry, n, err := parseDiffRecord(fields)
+			if err != nil {
+				return nil, err
+			}
+			if n != len(fields) {
+				return nil, fmt.Errorf("parse diff: unexpected fields in %q", line)
+			}
+			entries = append(entries, entry)
+		}
+		return entries, nil
+	}
+
+	fields := strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00")
+	var entries []DiffEntry
+	for len(fields) > 0 {
+		entry, n, err := parseDiffRecord(fields)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+		fields = fields[n:]
+	}
+	return entries, nil
+}
+
+// parseDiffRecord parses the record at the start of fields: a status followed by one path,
+// or two for a rename. It returns the entry and the number of fields it used.
+func parseDiffRecord(fields []string) (DiffEntry, int, error) {
+	status := fields[0]
+	if status == "" {
+		return DiffEntry{}, 0, fmt.Errorf("parse diff: empty status")
+	}
+
+	var entry DiffEntry
+	paths := 1
+	switch status[0] {
+	case 'A':
+		entry.Status = DiffAdded
+	case 'M', 'T':
+		entry.Status = DiffModified
+	case 'D':
+		entry.Status = DiffDeleted
+	case 'R':
+		entry.Status = DiffRenamed
+		paths = 2
+		similarity, err := strconv.Atoi(status[1:])
+		if err != nil {
+			return DiffEntry{}, 0, fmt.Errorf("parse diff: invalid rename score %q", status)
+		}
+		entry.Similarity = similarity
+	default:
+		return DiffEntry{}, 0, fmt.Errorf("parse diff: unknown status %q", status)
+	}
+
+	if len(fields) < 1+paths {
+		return DiffEntry{}, 0, fmt.Errorf("parse diff: %s record is missing a path", status)
+	}
+	entry.Path = fields[paths]
+	if paths == 2 {
+		entry.OldPath = fields[1]
+	}
+	return entry, 1 + paths, nil
+}
+
+// logFormat prints the hash, author name, author email, author date and message of a commit,
+// each ended by a NUL, which cannot appear in them. git separates commits with a newline.
+const logFormat = "%H%x00%an%x00%ae%x00%aI%x00%B%x00"
+
+// logFields is the number of fields logFormat prints per commit.
+const logFields = 5
+
+// Log lists commits, newest first, as git log does. The commits have their hash, author,
+// date and message set, but not their tree or parents.
+// Returns no commits when the starting commit has no history touching the paths.
+func (r *Repository) Log(ctx context.Context, opts LogOptions) ([]Commit, error) {
+	opts.Format = logFormat
+	out, err := r.gitCmd(logArgs(opts)...).Output(ctx, r.exec)
+	if err != nil {
+		return nil, fmt.Errorf("log: %w", err)
+	}
+	return parseLogOutput(out)
+}
+
+// parseLogOutput parses git log output printed with logFormat.
+func parseLogOutput(data []byte) ([]Commit, error) {
+	fields := strings.Split(string(data), "\x00")
+	// Only the newline ending the output follows the last NUL
+	fields = fields[:len(fields)-1]
+	if len(fields)%logFields != 0 {
+		return nil, fmt.Errorf("parse log: %d fields is not a whole number of commits", len(fields))
+	}
+
+	var commits []Commit
+	for i := 0; i < len(fields); i += logFields {
+		hash := strings.TrimLeft(fields[i], "\n")
+		date, err := time.Parse(time.RFC3339, fields[i+3])
+		if err != nil {
+			return nil, fmt.Errorf("parse log: commit %s: %w", hash, err)
+		}
+		commits = append(commits, Commit{
+			Hash:    Hash(hash),
+			Author:  Author{Name: fields[i+1], Email: fields[i+2]},
+			Date:    date,
+			Message: strings.TrimRight(fields[i+4], "\n"),
+		})
+	}
+	return commits, nil
+}
+
+// logArgs builds the git log arguments for the given options.
+func logArgs(opts LogOptions) []string {
+	args := []string{"log"}
+	if opts.Format != "" {
+		args = append(args, "--format="+opts.Format)
+	}
+	if opts.MaxCount > 0 {
+		args = append(args, "--max-count="+strconv.Itoa(opts.MaxCount))
+	}
+	if opts.DiffFilter != "" {
+		args = append(args, "--diff-filter="+opts.DiffFilter)
+	}
+	if opts.Follow {
+		args = append(args, "--follow")
+	}
+	if opts.Rev != "" {
+		args = append(args, string(opts.Rev))
+	}
+	if len(opts.Paths) > 0 {
+		args = append(args, "--")
+		args = append(args, opts.Paths...)
+	}
+	return args
+}
+
 // UpdateTree updates a tree with the given changes.
 func (r *Repository) UpdateTree(ctx context.Context, req UpdateTreeRequest) (Hash, error) {
 	// Create temporary index file
@@ -351,6 +1000,8 @@ func (r *Repository) UpdateTree(ctx context.Context, req UpdateTreeRequest) (Has
 	}
 	indexPath := indexFile.Name()
 	indexFile.Close()
+	// Git rejects a zero-length index, so let it create the file itself
+	os.Remove(indexPath)
 	defer os.Remove(indexPath)
 
 	env := []string{"GIT_INDEX_FILE=" + indexPath}
@@ -371,11 +1022,17 @@ func (r *Repository) UpdateTree(ctx context.Context, req UpdateTreeRequest) (Has
 		}
 	}
 
-	// Apply deletes
-	for _, del := range req.Deletes {
-		cmd := r.gitCmd("update-index", "--remove", del)
-		if err := runCmdWithEnv(cmd, env, ctx, r.exec, "update-index remove"); err != nil {
-			return "", err
+	// Apply deletes. --remove needs a work tree, which the bare cache lacks,
+	// so feed --index-info entries with mode 0, which drop the path from the index.
+	if len(req.Deletes) > 0 {
+		var info strings.Builder
+		for _, del := range req.Deletes {
+			fmt.Fprintf(&info, "0 %s\t%s\n", strings.Repeat("0", 40), del)
+		}
+		cmd := r.gitCmd("update-index", "--index-info")
+		appendEnvToCmd(cmd, env)
+		if _, err := cmd.OutputWithStdin(ctx, r.exec, strings.NewReader(info.String())); err != nil {
+			return "", fmt.Errorf("update-index remove: %w", err)
 		}
 	}
 
@@ -418,6 +1075,73 @@ func (r *Repository) GetRemoteURL(ctx context.Context, remote string) (string, e
 	return r.executeGitOutput(ctx, "get remote url", "remote", "get-url", remote)
 }
 
+// LsRemote lists the refs of a remote without fetching them, in the order the remote
+// advertises them. It contacts the remote, so it doubles as a connectivity check.
+func (r *Repository) LsRemote(ctx context.Context, opts LsRemoteOptions) ([]RemoteRef, error) {
+	args := []string{"ls-remote"}
+	if opts.Heads {
+		args = append(args, "--heads")
+	}
+	if opts.Tags {
+		args = append(args, "--tags")
+	}
+	if opts.Symref {
+		args = append(args, "--symref")
+	}
+	if opts.Remote != "" {
+		args = append(args, opts.Remote)
+	}
+	args = append(args, opts.Patterns...)
+
+	out, err := r.gitCmd(args...).Output(ctx, r.exec)
+	if err != nil {
+		return nil, fmt.Errorf("ls-remote: %w", err)
+	}
+	return parseLsRemoteOutput(string(out)), nil
+}
+
+// parseLsRemoteOutput parses ls-remote lines of the form <hash>\t<ref>, and the
+// ref: <target>\t<ref> lines --symref adds before the line of a symbolic ref.
+func parseLsRemoteOutput(out string) []RemoteRef {
+	var refs []RemoteRef
+	targets := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		value, name, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok {
+			continue
+		}
+		if target, ok := strings.CutPrefix(value, "ref: "); ok {
+			targets[name] = target
+			continue
+		}
+		refs = append(refs, RemoteRef{Name: name, Hash: Hash(value), Target: targets[name]})
+	}
+	return refs
+}
+
+// SymbolicRef returns the ref a symbolic ref such as HEAD points to (e.g. "refs/heads/main").
+func (r *Repository) SymbolicRef(ctx context.Context, ref string) (string, error) {
+	return r.executeGitOutput(ctx, "symbolic-ref", "symbolic-ref", ref)
+}
+
+// MergeBase returns the best common ancestor of two commits.
+// Returns ErrNoMergeBase when they share no history.
+func (r *Repository) MergeBase(ctx context.Context, a, b Treeish) (Hash, error) {
+	out, err := r.gitCmd("merge-base", string(a), string(b)).Output(ctx, r.exec)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return "", protatoerrors.ErrNoMergeBase // merge-base exits 1 without output
+	}
+	if err != nil {
+		return "", fmt.Errorf("merge-base: %w", err)
+	}
+	base := trimOutputToHash(out)
+	if base == "" {
+		return "", protatoerrors.ErrNoMergeBase
+	}
+	return base, nil
+}
+
 // GetUser gets the current Git user (name and email) from git config.
 func (r *Repository) GetUser(ctx context.Context) (Author, error) {
 	var author Author
@@ -437,6 +1161,36 @@ func (r *Repository) GetUser(ctx context.Context) (Author, error) {
 	return author, nil
 }
 
+// ConfigList returns the effective git config of the repository, as seen by git commands run in it.
+// Values of multi-valued keys are joined with newlines in the order git reports them.
+func (r *Repository) ConfigList(ctx context.Context) (map[string]string, error) {
+	out, err := r.gitCmd("config", "--list").Output(ctx, r.exec)
+	if err != nil {
+		return nil, fmt.Errorf("config --list: %w", err)
+	}
+	return parseConfigList(string(out)), nil
+}
+
+// parseConfigList parses `git config --list` output.
+// Keys without a value (implicit booleans) map to "true".
+func parseConfigList(out string) map[string]string {
+	config := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			value = "true"
+		}
+		if existing, seen := config[key]; seen {
+			value = existing + "\n" + value
+		}
+		config[key] = value
+	}
+	return config
+}
+
 // gitCmd is a helper for executing git commands.
 type gitCmd struct {
 	args []string
@@ -9,9 +9,14 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	protatoerrors "github.com/rahulagarwal0605/protato/internal/errors"
 	"github.com/rahulagarwal0605/protato/internal/logger"
 	"github.com/rs/zerolog"
 )
@@ -28,16 +33,28 @@ func testContext() context.Context {
 
 type mockExecer struct {
 	runErr     error
+	stdout     []byte // Written to the command's stdout by Run, when it has one
 	output     []byte
 	outputErr  error
 	outputFunc func() ([]byte, error)
+	calls      [][]string // Recorded command arguments (excluding "git")
+	envs       [][]string // Recorded command environments (nil when inherited)
 }
 
 func (m *mockExecer) Run(cmd *exec.Cmd) error {
+	m.calls = append(m.calls, cmd.Args[1:])
+	m.envs = append(m.envs, cmd.Env)
+	if cmd.Stdout != nil && len(m.stdout) > 0 {
+		if _, err := cmd.Stdout.Write(m.stdout); err != nil {
+			return err
+		}
+	}
 	return m.runErr
 }
 
 func (m *mockExecer) Output(cmd *exec.Cmd) ([]byte, error) {
+	m.calls = append(m.calls, cmd.Args[1:])
+	m.envs = append(m.envs, cmd.Env)
 	if m.outputFunc != nil {
 		return m.outputFunc()
 	}
@@ -116,6 +133,33 @@ func TestHash_Short(t *testing.T) {
 // Treeish Tests
 // =============================================================================
 
+func TestBlobHash(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    Hash
+	}{
+		{
+			name:    "empty",
+			content: nil,
+			want:    "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391",
+		},
+		{
+			name:    "text",
+			content: []byte("hello\n"),
+			want:    "ce013625030ba8dba906f756967f9e9ca394464a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BlobHash(tt.content); got != tt.want {
+				t.Errorf("BlobHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestTreeish_String(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -871,6 +915,51 @@ func TestParseTreeOutput_EntryDetails(t *testing.T) {
 	}
 }
 
+func TestParseTreeOutput_SpecialPaths(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "NUL-terminated paths are taken verbatim",
+			data: "100644 blob abc123\tteam/my service/api.proto\x00100644 blob def456\tteam/caf\u00e9/api.proto\x00",
+			want: []string{"team/my service/api.proto", "team/caf\u00e9/api.proto"},
+		},
+		{
+			name: "NUL-terminated path starting with a quote",
+			data: "100644 blob abc123\t\"quoted\".proto\x00",
+			want: []string{`"quoted".proto`},
+		},
+		{
+			name: "quoted paths are decoded",
+			data: "100644 blob abc123\t\"team/tab\\there.proto\"\n100644 blob def456\t\"team/caf\\303\\251/api.proto\"\n",
+			want: []string{"team/tab\there.proto", "team/caf\u00e9/api.proto"},
+		},
+		{
+			name: "unquoted path with a space",
+			data: "100644 blob abc123\tteam/my service/api.proto\n",
+			want: []string{"team/my service/api.proto"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := parseTreeOutput([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("parseTreeOutput() error = %v", err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTreeOutput() paths = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 // =============================================================================
 // Repository Method Tests with Mocks
 // =============================================================================
@@ -883,6 +972,7 @@ func TestRepository_Fetch_WithMock(t *testing.T) {
 		opts    FetchOptions
 		mockErr error
 		wantErr bool
+		wantArg string
 	}{
 		{
 			name:    "successful fetch",
@@ -908,6 +998,21 @@ func TestRepository_Fetch_WithMock(t *testing.T) {
 			mockErr: nil,
 			wantErr: false,
 		},
+		{
+			name:    "fetch without writing FETCH_HEAD",
+			opts:    FetchOptions{Remote: "origin", NoWriteFetchHead: true},
+			wantArg: "--no-write-fetch-head",
+		},
+		{
+			name:    "fetch unshallow",
+			opts:    FetchOptions{Remote: "origin", Unshallow: true},
+			wantArg: "--unshallow",
+		},
+		{
+			name:    "fetch deepen",
+			opts:    FetchOptions{Remote: "origin", Deepen: 50},
+			wantArg: "--deepen 50",
+		},
 	}
 
 	for _, tt := range tests {
@@ -923,6 +1028,65 @@ func TestRepository_Fetch_WithMock(t *testing.T) {
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if tt.wantArg != "" && !strings.Contains(strings.Join(mock.calls[0], " "), tt.wantArg) {
+				t.Errorf("Fetch() args = %v, want %s", mock.calls[0], tt.wantArg)
+			}
+		})
+	}
+}
+
+func TestRepository_Unshallow_WithMock(t *testing.T) {
+	tests := []struct {
+		name      string
+		shallow   string // Output of rev-parse --is-shallow-repository
+		wantFetch bool
+	}{
+		{name: "shallow repository", shallow: "true\n", wantFetch: true},
+		{name: "complete repository", shallow: "false\n", wantFetch: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockExecer{output: []byte(tt.shallow)}
+			repo := &Repository{gitDir: "/path/to/repo.git", bare: true, exec: mock}
+
+			if err := repo.Unshallow(testContext()); err != nil {
+				t.Fatalf("Unshallow() error = %v", err)
+			}
+			if got := len(mock.calls) == 2; got != tt.wantFetch {
+				t.Fatalf("Unshallow() calls = %v, want fetch %v", mock.calls, tt.wantFetch)
+			}
+			if tt.wantFetch && strings.Join(mock.calls[1], " ") != "fetch --no-write-fetch-head --unshallow origin" {
+				t.Errorf("Unshallow() fetch = %v", mock.calls[1])
+			}
+		})
+	}
+}
+
+func TestRepository_Gc_WithMock(t *testing.T) {
+	tests := []struct {
+		name string
+		opts GcOptions
+		want string
+	}{
+		{name: "full gc", want: "gc --quiet"},
+		{name: "auto", opts: GcOptions{Auto: true}, want: "gc --quiet --auto"},
+		{name: "aggressive prune", opts: GcOptions{Aggressive: true, Prune: "now"}, want: "gc --quiet --aggressive --prune=now"},
+		{name: "repack only", opts: GcOptions{RepackOnly: true, Prune: "now"}, want: "repack -a -d -q"},
+		{name: "aggressive repack", opts: GcOptions{RepackOnly: true, Aggressive: true}, want: "repack -a -d -q -f"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockExecer{}
+			repo := &Repository{gitDir: "/path/to/repo.git", bare: true, exec: mock}
+
+			if err := repo.Gc(testContext(), tt.opts); err != nil {
+				t.Fatalf("Gc() error = %v", err)
+			}
+			if len(mock.calls) != 1 || strings.Join(mock.calls[0], " ") != tt.want {
+				t.Errorf("Gc() calls = %v, want %s", mock.calls, tt.want)
+			}
 		})
 	}
 }
@@ -1019,6 +1183,71 @@ func TestRepository_RevExists_WithMock(t *testing.T) {
 	}
 }
 
+func TestRepository_CheckIntegrity_WithMock(t *testing.T) {
+	ctx := testContext()
+
+	tests := []struct {
+		name      string
+		mockErr   error
+		full      bool
+		wantErr   bool
+		wantCalls [][]string
+	}{
+		{
+			name:      "healthy repository",
+			wantCalls: [][]string{{"rev-parse", "--git-dir"}},
+		},
+		{
+			name:      "corrupt repository",
+			mockErr:   errors.New("fatal: not a git repository"),
+			wantErr:   true,
+			wantCalls: [][]string{{"rev-parse", "--git-dir"}},
+		},
+		{
+			name:      "connectivity",
+			full:      true,
+			wantCalls: [][]string{{"fsck", "--connectivity-only", "--no-progress"}},
+		},
+		{
+			name:      "broken connectivity",
+			full:      true,
+			mockErr:   errors.New("error: HEAD: invalid sha1 pointer"),
+			wantErr:   true,
+			wantCalls: [][]string{{"fsck", "--connectivity-only", "--no-progress"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockExecer{runErr: tt.mockErr}
+			repo := &Repository{
+				gitDir:  "/path/to/repo",
+				rootDir: "/path/to/repo",
+				bare:    true,
+				exec:    mock,
+			}
+
+			check := repo.CheckIntegrity
+			if tt.full {
+				check = repo.CheckConnectivity
+			}
+			err := check(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("check error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(mock.calls) != len(tt.wantCalls) {
+				t.Fatalf("check ran %v, want %v", mock.calls, tt.wantCalls)
+			}
+			for i, want := range tt.wantCalls {
+				got := mock.calls[i][len(mock.calls[i])-len(want):]
+				if strings.Join(got, " ") != strings.Join(want, " ") {
+					t.Errorf("call %d = %v, want %v", i, mock.calls[i], want)
+				}
+			}
+		})
+	}
+}
+
 func TestRepository_RevHash_WithMock(t *testing.T) {
 	ctx := testContext()
 
@@ -1725,64 +1954,43 @@ func TestRepository_ReadObject_WithMock(t *testing.T) {
 	}
 }
 
-func TestRepository_CommitTree_WithMock(t *testing.T) {
+func TestRepository_Diff_WithMock(t *testing.T) {
 	ctx := testContext()
 
 	tests := []struct {
-		name     string
-		req      CommitTreeRequest
-		mockOut  []byte
-		mockErr  error
-		wantHash Hash
-		wantErr  bool
+		name      string
+		oldHash   Hash
+		newHash   Hash
+		mockOut   []byte
+		mockErr   error
+		want      string
+		wantArgs  []string
+		wantCalls int
+		wantErr   bool
 	}{
 		{
-			name: "commit without parents",
-			req: CommitTreeRequest{
-				Tree:    Hash("tree123"),
-				Message: "Initial commit",
-				Author:  Author{Name: "Test User", Email: "test@example.com"},
-			},
-			mockOut:  []byte("commit123\n"),
-			mockErr:  nil,
-			wantHash: Hash("commit123"),
-			wantErr:  false,
-		},
-		{
-			name: "commit with parent",
-			req: CommitTreeRequest{
-				Tree:    Hash("tree456"),
-				Parents: []Hash{Hash("parent123")},
-				Message: "Second commit",
-				Author:  Author{Name: "Test User", Email: "test@example.com"},
-			},
-			mockOut:  []byte("commit456\n"),
-			mockErr:  nil,
-			wantHash: Hash("commit456"),
-			wantErr:  false,
+			name:      "different blobs",
+			oldHash:   Hash("abc123"),
+			newHash:   Hash("def456"),
+			mockOut:   []byte("@@ -1 +1 @@\n-old\n+new\n"),
+			want:      "@@ -1 +1 @@\n-old\n+new\n",
+			wantArgs:  []string{"diff", "--no-color", "--no-ext-diff", "abc123", "def456"},
+			wantCalls: 1,
 		},
 		{
-			name: "commit with multiple parents",
-			req: CommitTreeRequest{
-				Tree:    Hash("tree789"),
-				Parents: []Hash{Hash("parent1"), Hash("parent2")},
-				Message: "Merge commit",
-				Author:  Author{Name: "Test User", Email: "test@example.com"},
-			},
-			mockOut:  []byte("mergecommit\n"),
-			mockErr:  nil,
-			wantHash: Hash("mergecommit"),
-			wantErr:  false,
+			name:      "identical hashes",
+			oldHash:   Hash("abc123"),
+			newHash:   Hash("abc123"),
+			want:      "",
+			wantCalls: 0,
 		},
 		{
-			name: "commit failure",
-			req: CommitTreeRequest{
-				Tree:    Hash("tree123"),
-				Message: "Test commit",
-				Author:  Author{Name: "Test User", Email: "test@example.com"},
-			},
-			mockErr: errors.New("commit failed"),
-			wantErr: true,
+			name:      "diff failure",
+			oldHash:   Hash("abc123"),
+			newHash:   Hash("missing"),
+			mockErr:   errors.New("bad object"),
+			wantCalls: 1,
+			wantErr:   true,
 		},
 	}
 
@@ -1795,113 +2003,523 @@ func TestRepository_CommitTree_WithMock(t *testing.T) {
 				exec:    mock,
 			}
 
-			got, err := repo.CommitTree(ctx, tt.req)
+			got, err := repo.Diff(ctx, tt.oldHash, tt.newHash)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("CommitTree() error = %v, wantErr %v", err, tt.wantErr)
-				return
+				t.Fatalf("Diff() error = %v, wantErr %v", err, tt.wantErr)
 			}
-			if !tt.wantErr && got != tt.wantHash {
-				t.Errorf("CommitTree() = %v, want %v", got, tt.wantHash)
+			if got != tt.want {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+			if len(mock.calls) != tt.wantCalls {
+				t.Fatalf("Diff() made %d git calls, want %d", len(mock.calls), tt.wantCalls)
+			}
+			if tt.wantArgs != nil && strings.Join(mock.calls[0], " ") != strings.Join(tt.wantArgs, " ") {
+				t.Errorf("Diff() args = %v, want %v", mock.calls[0], tt.wantArgs)
 			}
 		})
 	}
 }
 
-func TestExecuteGitOutputToHash(t *testing.T) {
-	ctx := testContext()
-
+func TestParseDiffOutput(t *testing.T) {
 	tests := []struct {
-		name      string
-		env       []string
-		mockOut   []byte
-		mockErr   error
-		operation string
-		wantHash  Hash
-		wantErr   bool
+		name    string
+		data    string
+		want    []DiffEntry
+		wantErr bool
 	}{
 		{
-			name:      "successful with env",
-			env:       []string{"GIT_INDEX_FILE=/tmp/index"},
-			mockOut:   []byte("treehash123\n"),
-			mockErr:   nil,
-			operation: "write-tree",
-			wantHash:  Hash("treehash123"),
-			wantErr:   false,
+			name: "NUL-separated statuses",
+			data: "M\x00protos/a/api.proto\x00A\x00protos/a/new.proto\x00D\x00protos/b/old.proto\x00",
+			want: []DiffEntry{
+				{Status: DiffModified, Path: "protos/a/api.proto"},
+				{Status: DiffAdded, Path: "protos/a/new.proto"},
+				{Status: DiffDeleted, Path: "protos/b/old.proto"},
+			},
 		},
 		{
-			name:      "successful without env",
-			env:       nil,
-			mockOut:   []byte("hash456\n"),
-			mockErr:   nil,
-			operation: "test-op",
-			wantHash:  Hash("hash456"),
-			wantErr:   false,
+			name: "NUL-separated renames",
+			data: "R100\x00protos/a/v1.proto\x00protos/a/v2.proto\x00R087\x00protos/b/x.proto\x00protos/c/x.proto\x00M\x00protos/d.proto\x00",
+			want: []DiffEntry{
+				{Status: DiffRenamed, Path: "protos/a/v2.proto", OldPath: "protos/a/v1.proto", Similarity: 100},
+				{Status: DiffRenamed, Path: "protos/c/x.proto", OldPath: "protos/b/x.proto", Similarity: 87},
+				{Status: DiffModified, Path: "protos/d.proto"},
+			},
 		},
 		{
-			name:      "failure",
-			env:       nil,
-			mockErr:   errors.New("command failed"),
-			operation: "test-op",
-			wantErr:   true,
+			name: "NUL-separated paths are taken verbatim",
+			data: "A\x00team/my service/\"quoted\".proto\x00",
+			want: []DiffEntry{{Status: DiffAdded, Path: `team/my service/"quoted".proto`}},
+		},
+		{
+			name: "type change is a modification",
+			data: "T\x00protos/link.proto\x00",
+			want: []DiffEntry{{Status: DiffModified, Path: "protos/link.proto"}},
+		},
+		{
+			name: "newline-terminated with rename and quoted path",
+			data: "R100\tprotos/a/v1.proto\tprotos/a/v2.proto\nM\t\"protos/caf\\303\\251.proto\"\n",
+			want: []DiffEntry{
+				{Status: DiffRenamed, Path: "protos/a/v2.proto", OldPath: "protos/a/v1.proto", Similarity: 100},
+				{Status: DiffModified, Path: "protos/caf\u00e9.proto"},
+			},
+		},
+		{
+			name: "empty output",
+			data: "",
+			want: nil,
 		},
+		{name: "unknown status", data: "X\x00protos/a.proto\x00", wantErr: true},
+		{name: "rename missing new path", data: "R100\x00protos/a.proto\x00", wantErr: true},
+		{name: "rename without score", data: "R\x00protos/a.proto\x00protos/b.proto\x00", wantErr: true},
+		{name: "extra field on a line", data: "M\tprotos/a.proto\tprotos/b.proto\n", wantErr: true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mock := &mockExecer{output: tt.mockOut, outputErr: tt.mockErr}
-			repo := &Repository{
-				gitDir:  "/path/to/repo/.git",
-				rootDir: "/path/to/repo",
-				exec:    mock,
-			}
-
-			cmd := repo.gitCmd("write-tree")
-			got, err := repo.executeGitOutputToHash(ctx, cmd, tt.env, tt.operation)
+			got, err := parseDiffOutput([]byte(tt.data))
 			if (err != nil) != tt.wantErr {
-				t.Errorf("executeGitOutputToHash() error = %v, wantErr %v", err, tt.wantErr)
-				return
+				t.Fatalf("parseDiffOutput() error = %v, wantErr %v", err, tt.wantErr)
 			}
-			if !tt.wantErr && got != tt.wantHash {
-				t.Errorf("executeGitOutputToHash() = %v, want %v", got, tt.wantHash)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDiffOutput() = %+v, want %+v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestExecuteGitOutputToHashWithStdin(t *testing.T) {
-	ctx := testContext()
-
+func TestDiffTreeArgs(t *testing.T) {
 	tests := []struct {
-		name      string
-		stdin     string
-		mockOut   []byte
-		mockErr   error
-		operation string
-		wantHash  Hash
-		wantErr   bool
+		name string
+		opts DiffOptions
+		want []string
 	}{
 		{
-			name:      "successful with stdin",
-			stdin:     "test content",
-			mockOut:   []byte("blobhash123\n"),
-			mockErr:   nil,
-			operation: "hash-object",
-			wantHash:  Hash("blobhash123"),
-			wantErr:   false,
+			name: "no options",
+			want: []string{"diff-tree", "-r", "-z", "--name-status", "abc123", "def456"},
 		},
 		{
-			name:      "failure with stdin",
-			stdin:     "test content",
-			mockErr:   errors.New("hash-object failed"),
-			operation: "hash-object",
-			wantErr:   true,
+			name: "renames and paths",
+			opts: DiffOptions{FindRenames: true, Paths: []string{"protos/team"}},
+			want: []string{"diff-tree", "-r", "-z", "--name-status", "-M", "abc123", "def456", "--", "protos/team"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mock := &mockExecer{output: tt.mockOut, outputErr: tt.mockErr}
-			repo := &Repository{
+			if got := diffTreeArgs("abc123", "def456", tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffTreeArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_DiffTree_WithMock(t *testing.T) {
+	mock := &mockExecer{output: []byte("M\x00protos/a.proto\x00")}
+	repo := &Repository{gitDir: "/path/to/repo/.git", rootDir: "/path/to/repo", exec: mock}
+
+	got, err := repo.DiffTree(testContext(), "abc123", "def456", DiffOptions{})
+	if err != nil {
+		t.Fatalf("DiffTree() error = %v", err)
+	}
+	if want := []DiffEntry{{Status: DiffModified, Path: "protos/a.proto"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffTree() = %+v, want %+v", got, want)
+	}
+
+	mock = &mockExecer{outputErr: errors.New("bad object")}
+	repo.exec = mock
+	if _, err := repo.DiffTree(testContext(), "abc123", "missing", DiffOptions{}); err == nil {
+		t.Error("DiffTree() expected error for a failed git call")
+	}
+}
+
+func TestLogArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts LogOptions
+		want []string
+	}{
+		{
+			name: "no options",
+			opts: LogOptions{},
+			want: []string{"log"},
+		},
+		{
+			name: "path filter",
+			opts: LogOptions{Paths: []string{"protos/team/svc"}},
+			want: []string{"log", "--", "protos/team/svc"},
+		},
+		{
+			name: "include deleted",
+			opts: LogOptions{
+				Paths:      []string{"protos/team/svc/v1/old.proto"},
+				DiffFilter: "D",
+				Follow:     true,
+			},
+			want: []string{"log", "--diff-filter=D", "--follow", "--", "protos/team/svc/v1/old.proto"},
+		},
+		{
+			name: "history of a path from a commit",
+			opts: LogOptions{
+				Rev:      "abc123",
+				Paths:    []string{"protos/team/svc"},
+				MaxCount: 10,
+				Format:   "%H",
+			},
+			want: []string{"log", "--format=%H", "--max-count=10", "abc123", "--", "protos/team/svc"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := logArgs(tt.opts)
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("logArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLogOutput(t *testing.T) {
+	date := time.Date(2024, 3, 1, 12, 30, 0, 0, time.FixedZone("", 2*60*60))
+
+	tests := []struct {
+		name    string
+		output  string
+		want    []Commit
+		wantErr bool
+	}{
+		{
+			name:   "no commits",
+			output: "",
+		},
+		{
+			name: "multi-line bodies",
+			output: "aaa111\x00Alice\x00alice@example.com\x002024-03-01T12:30:00+02:00\x00Update team/svc\n\nAdd a field.\n\nAnd a second paragraph.\n\x00\n" +
+				"bbb222\x00Bob\x00bob@example.com\x002024-03-01T12:30:00+02:00\x00Create team/svc\n\x00\n",
+			want: []Commit{
+				{Hash: "aaa111", Author: Author{Name: "Alice", Email: "alice@example.com"}, Date: date, Message: "Update team/svc\n\nAdd a field.\n\nAnd a second paragraph."},
+				{Hash: "bbb222", Author: Author{Name: "Bob", Email: "bob@example.com"}, Date: date, Message: "Create team/svc"},
+			},
+		},
+		{
+			name:   "empty message",
+			output: "ccc333\x00Carol\x00carol@example.com\x002024-03-01T12:30:00+02:00\x00\x00\n",
+			want: []Commit{
+				{Hash: "ccc333", Author: Author{Name: "Carol", Email: "carol@example.com"}, Date: date},
+			},
+		},
+		{
+			name:    "missing fields",
+			output:  "aaa111\x00Alice\x00\n",
+			wantErr: true,
+		},
+		{
+			name:    "bad date",
+			output:  "aaa111\x00Alice\x00alice@example.com\x00yesterday\x00msg\n\x00\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLogOutput([]byte(tt.output))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLogOutput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseLogOutput() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Hash != tt.want[i].Hash || got[i].Author != tt.want[i].Author ||
+					!got[i].Date.Equal(tt.want[i].Date) || got[i].Message != tt.want[i].Message {
+					t.Errorf("commit %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCommit_SubjectBody(t *testing.T) {
+	tests := []struct {
+		message     string
+		wantSubject string
+		wantBody    string
+	}{
+		{message: "", wantSubject: "", wantBody: ""},
+		{message: "Create team/svc", wantSubject: "Create team/svc", wantBody: ""},
+		{message: "Update team/svc\n\nAdd a field.\n\nMore.", wantSubject: "Update team/svc", wantBody: "Add a field.\n\nMore."},
+		{message: "\nSubject after a blank line\nbody", wantSubject: "Subject after a blank line", wantBody: "body"},
+	}
+
+	for _, tt := range tests {
+		commit := &Commit{Message: tt.message}
+		if got := commit.Subject(); got != tt.wantSubject {
+			t.Errorf("Subject() of %q = %q, want %q", tt.message, got, tt.wantSubject)
+		}
+		if got := commit.Body(); got != tt.wantBody {
+			t.Errorf("Body() of %q = %q, want %q", tt.message, got, tt.wantBody)
+		}
+	}
+}
+
+func TestRepository_Log_WithMock(t *testing.T) {
+	mock := &mockExecer{output: []byte("aaa111\x00Alice\x00alice@example.com\x002024-03-01T12:30:00Z\x00msg\n\x00\n")}
+	repo := &Repository{gitDir: "/path/to/repo", rootDir: "/path/to/repo", bare: true, exec: mock}
+
+	got, err := repo.Log(testContext(), LogOptions{Rev: "v1..main", Paths: []string{"protos/team/svc"}, MaxCount: 5})
+	if err != nil {
+		t.Fatalf("Log() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Hash != "aaa111" || got[0].Message != "msg" {
+		t.Errorf("Log() = %+v, want the single commit aaa111", got)
+	}
+	want := []string{"log", "--format=" + logFormat, "--max-count=5", "v1..main", "--", "protos/team/svc"}
+	if len(mock.calls) != 1 || !reflect.DeepEqual(mock.calls[0][len(mock.calls[0])-len(want):], want) {
+		t.Errorf("Log() ran %v, want args ending in %v", mock.calls, want)
+	}
+
+	repo.exec = &mockExecer{outputErr: errors.New("bad revision")}
+	if _, err := repo.Log(testContext(), LogOptions{Rev: "missing"}); err == nil {
+		t.Error("Log() expected error for a failed git call")
+	}
+}
+
+func TestRepository_Log(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := testContext()
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "Alice"},
+		{"config", "user.email", "alice@example.com"},
+		{"commit", "-q", "--allow-empty", "-m", "Unrelated"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v, output: %s", args, err, out)
+		}
+	}
+	for i, msg := range []string{"Create api", "Update api\n\nWith a body"} {
+		if err := os.MkdirAll(filepath.Join(dir, "protos"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "protos", "api.proto"), []byte(strconv.Itoa(i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{{"add", "-A"}, {"commit", "-q", "-m", msg}} {
+			if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+				t.Fatalf("git %v: %v, output: %s", args, err, out)
+			}
+		}
+	}
+
+	repo, err := Open(ctx, dir, OpenOptions{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	entries, err := repo.Log(ctx, LogOptions{Rev: "HEAD", Paths: []string{"protos"}})
+	if err != nil {
+		t.Fatalf("Log() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Log() = %+v, want 2 commits touching protos", entries)
+	}
+	if entries[0].Subject() != "Update api" || entries[0].Body() != "With a body" || entries[1].Message != "Create api" {
+		t.Errorf("Log() messages = %q, %q, want newest first", entries[0].Message, entries[1].Message)
+	}
+	if entries[0].Author != (Author{Name: "Alice", Email: "alice@example.com"}) || entries[0].Date.IsZero() {
+		t.Errorf("Log() entry = %+v, want author and date", entries[0])
+	}
+
+	entries, err = repo.Log(ctx, LogOptions{Rev: "HEAD", Paths: []string{"protos"}, MaxCount: 1})
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Log() with MaxCount 1 = %+v, %v, want 1 commit", entries, err)
+	}
+
+	entries, err = repo.Log(ctx, LogOptions{Rev: "HEAD~1..HEAD"})
+	if err != nil || len(entries) != 1 || entries[0].Subject() != "Update api" {
+		t.Errorf("Log() of HEAD~1..HEAD = %+v, %v, want the last commit", entries, err)
+	}
+}
+
+func TestRepository_CommitTree_WithMock(t *testing.T) {
+	ctx := testContext()
+
+	tests := []struct {
+		name     string
+		req      CommitTreeRequest
+		mockOut  []byte
+		mockErr  error
+		wantHash Hash
+		wantErr  bool
+	}{
+		{
+			name: "commit without parents",
+			req: CommitTreeRequest{
+				Tree:    Hash("tree123"),
+				Message: "Initial commit",
+				Author:  Author{Name: "Test User", Email: "test@example.com"},
+			},
+			mockOut:  []byte("commit123\n"),
+			mockErr:  nil,
+			wantHash: Hash("commit123"),
+			wantErr:  false,
+		},
+		{
+			name: "commit with parent",
+			req: CommitTreeRequest{
+				Tree:    Hash("tree456"),
+				Parents: []Hash{Hash("parent123")},
+				Message: "Second commit",
+				Author:  Author{Name: "Test User", Email: "test@example.com"},
+			},
+			mockOut:  []byte("commit456\n"),
+			mockErr:  nil,
+			wantHash: Hash("commit456"),
+			wantErr:  false,
+		},
+		{
+			name: "commit with multiple parents",
+			req: CommitTreeRequest{
+				Tree:    Hash("tree789"),
+				Parents: []Hash{Hash("parent1"), Hash("parent2")},
+				Message: "Merge commit",
+				Author:  Author{Name: "Test User", Email: "test@example.com"},
+			},
+			mockOut:  []byte("mergecommit\n"),
+			mockErr:  nil,
+			wantHash: Hash("mergecommit"),
+			wantErr:  false,
+		},
+		{
+			name: "commit failure",
+			req: CommitTreeRequest{
+				Tree:    Hash("tree123"),
+				Message: "Test commit",
+				Author:  Author{Name: "Test User", Email: "test@example.com"},
+			},
+			mockErr: errors.New("commit failed"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockExecer{output: tt.mockOut, outputErr: tt.mockErr}
+			repo := &Repository{
+				gitDir:  "/path/to/repo/.git",
+				rootDir: "/path/to/repo",
+				exec:    mock,
+			}
+
+			got, err := repo.CommitTree(ctx, tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CommitTree() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.wantHash {
+				t.Errorf("CommitTree() = %v, want %v", got, tt.wantHash)
+			}
+		})
+	}
+}
+
+func TestExecuteGitOutputToHash(t *testing.T) {
+	ctx := testContext()
+
+	tests := []struct {
+		name      string
+		env       []string
+		mockOut   []byte
+		mockErr   error
+		operation string
+		wantHash  Hash
+		wantErr   bool
+	}{
+		{
+			name:      "successful with env",
+			env:       []string{"GIT_INDEX_FILE=/tmp/index"},
+			mockOut:   []byte("treehash123\n"),
+			mockErr:   nil,
+			operation: "write-tree",
+			wantHash:  Hash("treehash123"),
+			wantErr:   false,
+		},
+		{
+			name:      "successful without env",
+			env:       nil,
+			mockOut:   []byte("hash456\n"),
+			mockErr:   nil,
+			operation: "test-op",
+			wantHash:  Hash("hash456"),
+			wantErr:   false,
+		},
+		{
+			name:      "failure",
+			env:       nil,
+			mockErr:   errors.New("command failed"),
+			operation: "test-op",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockExecer{output: tt.mockOut, outputErr: tt.mockErr}
+			repo := &Repository{
+				gitDir:  "/path/to/repo/.git",
+				rootDir: "/path/to/repo",
+				exec:    mock,
+			}
+
+			cmd := repo.gitCmd("write-tree")
+			got, err := repo.executeGitOutputToHash(ctx, cmd, tt.env, tt.operation)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("executeGitOutputToHash() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.wantHash {
+				t.Errorf("executeGitOutputToHash() = %v, want %v", got, tt.wantHash)
+			}
+		})
+	}
+}
+
+func TestExecuteGitOutputToHashWithStdin(t *testing.T) {
+	ctx := testContext()
+
+	tests := []struct {
+		name      string
+		stdin     string
+		mockOut   []byte
+		mockErr   error
+		operation string
+		wantHash  Hash
+		wantErr   bool
+	}{
+		{
+			name:      "successful with stdin",
+			stdin:     "test content",
+			mockOut:   []byte("blobhash123\n"),
+			mockErr:   nil,
+			operation: "hash-object",
+			wantHash:  Hash("blobhash123"),
+			wantErr:   false,
+		},
+		{
+			name:      "failure with stdin",
+			stdin:     "test content",
+			mockErr:   errors.New("hash-object failed"),
+			operation: "hash-object",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockExecer{output: tt.mockOut, outputErr: tt.mockErr}
+			repo := &Repository{
 				gitDir:  "/path/to/repo/.git",
 				rootDir: "/path/to/repo",
 				exec:    mock,
@@ -1921,25 +2539,187 @@ func TestExecuteGitOutputToHashWithStdin(t *testing.T) {
 	}
 }
 
-func TestClone(t *testing.T) {
+func TestClone(t *testing.T) {
+	ctx := testContext()
+
+	t.Run("clone options building", func(t *testing.T) {
+		// We can't fully test Clone without a real git repo,
+		// but we can test that it builds the correct command args
+		// by testing with a mock that returns an error
+		mock := &mockExecer{runErr: errors.New("clone test")}
+		ctx := WithExecer(ctx, mock)
+
+		_, err := Clone(ctx, "https://example.com/repo.git", "/tmp/test", CloneOptions{
+			Bare:   true,
+			NoTags: true,
+			Depth:  1,
+		})
+		if err == nil {
+			t.Error("Clone() expected error from mock")
+		}
+	})
+}
+
+func TestCloneArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts CloneOptions
+		want []string
+	}{
+		{
+			name: "remote default branch",
+			opts: CloneOptions{Bare: true, NoTags: true, Depth: 1},
+			want: []string{"clone", "--bare", "--no-tags", "--depth", "1", "https://example.com/repo.git", "/tmp/test"},
+		},
+		{
+			name: "single branch",
+			opts: CloneOptions{Bare: true, Depth: 1, Branch: "registry"},
+			want: []string{"clone", "--bare", "--depth", "1", "--branch", "registry", "--single-branch", "https://example.com/repo.git", "/tmp/test"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cloneArgs("https://example.com/repo.git", "/tmp/test", tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cloneArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthOptions_env(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    AuthOptions
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "no credentials",
+		},
+		{
+			name: "bearer token",
+			auth: AuthOptions{Token: "s3cret"},
+			want: []string{"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader", "GIT_CONFIG_VALUE_0=Authorization: Bearer s3cret"},
+		},
+		{
+			name: "basic auth",
+			auth: AuthOptions{Username: "bot", Password: "pa55"},
+			want: []string{"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader", "GIT_CONFIG_VALUE_0=Authorization: Basic Ym90OnBhNTU="},
+		},
+		{
+			name: "ssh key",
+			auth: AuthOptions{SSHKeyPath: "/home/ci/keys/it's"},
+			want: []string{`GIT_SSH_COMMAND=ssh -i '/home/ci/keys/it'\''s' -o IdentitiesOnly=yes`},
+		},
+		{
+			name:    "token and basic auth",
+			auth:    AuthOptions{Token: "s3cret", Username: "bot"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.auth.env()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("env() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("env() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClone_Auth(t *testing.T) {
+	var logs bytes.Buffer
+	log := zerolog.New(&logs).Level(zerolog.DebugLevel)
+	mock := &mockExecer{runErr: errors.New("clone test")}
+	ctx := WithExecer(logger.WithLogger(context.Background(), &log), mock)
+
+	_, err := Clone(ctx, "https://example.com/registry.git", "/tmp/test", CloneOptions{Bare: true, Auth: AuthOptions{Token: "s3cret"}})
+	if err == nil {
+		t.Fatal("Clone() expected error from mock")
+	}
+
+	header := "GIT_CONFIG_VALUE_0=Authorization: Bearer s3cret"
+	if !slices.Contains(mock.envs[0], header) {
+		t.Errorf("Clone() env = %q, want it to contain %q", mock.envs[0], header)
+	}
+	if strings.Contains(strings.Join(mock.calls[0], " "), "s3cret") {
+		t.Errorf("Clone() passed the token as an argument: %v", mock.calls[0])
+	}
+	if logs.Len() == 0 || strings.Contains(logs.String(), "s3cret") {
+		t.Errorf("debug logs = %q, want git commands logged without the token", logs.String())
+	}
+}
+
+func TestRepository_Auth_WithMock(t *testing.T) {
+	tmpDir := t.TempDir()
+	mock := &mockExecer{}
+	ctx := WithExecer(testContext(), mock)
+
+	repo, err := Open(ctx, tmpDir, OpenOptions{Bare: true, Auth: AuthOptions{SSHKeyPath: "/keys/registry"}})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := repo.Fetch(ctx, FetchOptions{Remote: "origin"}); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if _, err := repo.LsRemote(ctx, LsRemoteOptions{Remote: "origin"}); err != nil {
+		t.Fatalf("LsRemote() error = %v", err)
+	}
+
+	want := "GIT_SSH_COMMAND=ssh -i '/keys/registry' -o IdentitiesOnly=yes"
+	for i, env := range mock.envs {
+		if !slices.Contains(env, want) {
+			t.Errorf("git %v env = %q, want it to contain %q", mock.calls[i], env, want)
+		}
+	}
+
+	if _, err := Open(ctx, tmpDir, OpenOptions{Bare: true, Auth: AuthOptions{Token: "t", Password: "p"}}); err == nil {
+		t.Error("Open() with token and password expected error")
+	}
+}
+
+func TestInitBare(t *testing.T) {
 	ctx := testContext()
 
-	t.Run("clone options building", func(t *testing.T) {
-		// We can't fully test Clone without a real git repo,
-		// but we can test that it builds the correct command args
-		// by testing with a mock that returns an error
-		mock := &mockExecer{runErr: errors.New("clone test")}
-		ctx := WithExecer(ctx, mock)
+	tests := []struct {
+		name     string
+		opts     InitOptions
+		wantArgs []string
+	}{
+		{
+			name:     "default branch",
+			opts:     InitOptions{},
+			wantArgs: []string{"init", "--bare", "/tmp/registry.git"},
+		},
+		{
+			name:     "initial branch",
+			opts:     InitOptions{InitialBranch: "main"},
+			wantArgs: []string{"-c", "init.defaultBranch=main", "init", "--bare", "/tmp/registry.git"},
+		},
+	}
 
-		_, err := Clone(ctx, "https://example.com/repo.git", "/tmp/test", CloneOptions{
-			Bare:   true,
-			NoTags: true,
-			Depth:  1,
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockExecer{runErr: errors.New("init test")}
+			ctx := WithExecer(ctx, mock)
+
+			_, err := InitBare(ctx, "/tmp/registry.git", tt.opts)
+			if err == nil {
+				t.Fatal("InitBare() expected error from mock")
+			}
+			if len(mock.calls) != 1 {
+				t.Fatalf("InitBare() made %d calls, want 1", len(mock.calls))
+			}
+			if strings.Join(mock.calls[0], " ") != strings.Join(tt.wantArgs, " ") {
+				t.Errorf("InitBare() args = %v, want %v", mock.calls[0], tt.wantArgs)
+			}
 		})
-		if err == nil {
-			t.Error("Clone() expected error from mock")
-		}
-	})
+	}
 }
 
 func TestTreeEntry_Fields(t *testing.T) {
@@ -1977,3 +2757,559 @@ func TestAuthor_Fields(t *testing.T) {
 		t.Errorf("Email = %v, want test@example.com", author.Email)
 	}
 }
+
+func TestParseConfigList(t *testing.T) {
+	out := "core.bare=true\n" +
+		"remote.origin.url=https://example.com/registry.git\n" +
+		"remote.origin.fetch=+refs/heads/main:refs/remotes/origin/main\n" +
+		"remote.origin.fetch=+refs/heads/release:refs/remotes/origin/release\n" +
+		"core.sshcommand=ssh -o Opt=value\n" +
+		"http.sslverify\n"
+
+	got := parseConfigList(out)
+	want := map[string]string{
+		"core.bare":           "true",
+		"remote.origin.url":   "https://example.com/registry.git",
+		"remote.origin.fetch": "+refs/heads/main:refs/remotes/origin/main\n+refs/heads/release:refs/remotes/origin/release",
+		"core.sshcommand":     "ssh -o Opt=value",
+		"http.sslverify":      "true",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseConfigList() = %v, want %v", got, want)
+	}
+}
+
+func TestRepository_ConfigList_WithMock(t *testing.T) {
+	ctx := testContext()
+	mock := &mockExecer{output: []byte("user.name=Test User\nuser.email=test@example.com\n")}
+	repo := &Repository{gitDir: "/path/to/repo", rootDir: "/path/to/repo", bare: true, exec: mock}
+
+	got, err := repo.ConfigList(ctx)
+	if err != nil {
+		t.Fatalf("ConfigList() error = %v", err)
+	}
+	if got["user.name"] != "Test User" || got["user.email"] != "test@example.com" {
+		t.Errorf("ConfigList() = %v", got)
+	}
+
+	mock.outputErr = errors.New("config failed")
+	if _, err := repo.ConfigList(ctx); err == nil {
+		t.Error("ConfigList() expected error")
+	}
+}
+
+func TestRepository_LsRemote_WithMock(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     LsRemoteOptions
+		output   string
+		wantArgs []string
+		want     []RemoteRef
+	}{
+		{
+			name:     "branches",
+			opts:     LsRemoteOptions{Remote: "origin", Heads: true},
+			output:   "abc123\trefs/heads/main\ndef456\trefs/heads/release\n\n",
+			wantArgs: []string{"ls-remote", "--heads", "origin"},
+			want: []RemoteRef{
+				{Name: "refs/heads/main", Hash: "abc123"},
+				{Name: "refs/heads/release", Hash: "def456"},
+			},
+		},
+		{
+			name:     "tags with peeled entries",
+			opts:     LsRemoteOptions{Remote: "origin", Tags: true, Patterns: []string{"v1.*"}},
+			output:   "aaa111\trefs/tags/v1.0\nbbb222\trefs/tags/v1.0^{}\n",
+			wantArgs: []string{"ls-remote", "--tags", "origin", "v1.*"},
+			want: []RemoteRef{
+				{Name: "refs/tags/v1.0", Hash: "aaa111"},
+				{Name: "refs/tags/v1.0^{}", Hash: "bbb222"},
+			},
+		},
+		{
+			name:     "symbolic HEAD",
+			opts:     LsRemoteOptions{Remote: "origin", Symref: true, Patterns: []string{"HEAD"}},
+			output:   "ref: refs/heads/trunk\tHEAD\nabc123\tHEAD\n",
+			wantArgs: []string{"ls-remote", "--symref", "origin", "HEAD"},
+			want:     []RemoteRef{{Name: "HEAD", Hash: "abc123", Target: "refs/heads/trunk"}},
+		},
+		{
+			name:     "no matching refs",
+			opts:     LsRemoteOptions{Remote: "origin", Patterns: []string{"refs/heads/missing"}},
+			wantArgs: []string{"ls-remote", "origin", "refs/heads/missing"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockExecer{output: []byte(tt.output)}
+			repo := &Repository{gitDir: "/path/to/repo", rootDir: "/path/to/repo", bare: true, exec: mock}
+
+			got, err := repo.LsRemote(testContext(), tt.opts)
+			if err != nil {
+				t.Fatalf("LsRemote() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LsRemote() = %+v, want %+v", got, tt.want)
+			}
+			args := mock.calls[0]
+			if !reflect.DeepEqual(args[len(args)-len(tt.wantArgs):], tt.wantArgs) {
+				t.Errorf("LsRemote() ran git %v, want it to end with %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestRepository_LsRemote_Error(t *testing.T) {
+	mock := &mockExecer{outputErr: errors.New("could not read from remote repository")}
+	repo := &Repository{gitDir: "/path/to/repo", rootDir: "/path/to/repo", bare: true, exec: mock}
+
+	if _, err := repo.LsRemote(testContext(), LsRemoteOptions{Remote: "origin"}); err == nil {
+		t.Error("LsRemote() expected error")
+	}
+}
+
+func TestRepository_SymbolicRef_WithMock(t *testing.T) {
+	ctx := testContext()
+	mock := &mockExecer{output: []byte("refs/heads/main\n")}
+	repo := &Repository{gitDir: "/path/to/repo", rootDir: "/path/to/repo", bare: true, exec: mock}
+
+	got, err := repo.SymbolicRef(ctx, "HEAD")
+	if err != nil {
+		t.Fatalf("SymbolicRef() error = %v", err)
+	}
+	if got != "refs/heads/main" {
+		t.Errorf("SymbolicRef() = %q, want %q", got, "refs/heads/main")
+	}
+}
+
+func TestRepository_MergeBase_WithMock(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		outputErr error
+		want      Hash
+		wantErr   error // Checked with errors.Is
+		anyErr    bool  // Any error other than ErrNoMergeBase
+	}{
+		{
+			name:   "common ancestor",
+			output: "abc123def456\n",
+			want:   "abc123def456",
+		},
+		{
+			name:    "empty output",
+			output:  "",
+			wantErr: protatoerrors.ErrNoMergeBase,
+		},
+		{
+			name:      "git failure",
+			outputErr: errors.New("exit status 128"),
+			anyErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := testContext()
+			mock := &mockExecer{output: []byte(tt.output), outputErr: tt.outputErr}
+			repo := &Repository{gitDir: "/path/to/repo", rootDir: "/path/to/repo", bare: true, exec: mock}
+
+			got, err := repo.MergeBase(ctx, "feature", "main")
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("MergeBase() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.anyErr:
+				if err == nil || errors.Is(err, protatoerrors.ErrNoMergeBase) {
+					t.Fatalf("MergeBase() error = %v, want a git error", err)
+				}
+			case err != nil:
+				t.Fatalf("MergeBase() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("MergeBase() = %q, want %q", got, tt.want)
+			}
+
+			want := []string{"merge-base", "feature", "main"}
+			if len(mock.calls) != 1 || !reflect.DeepEqual(mock.calls[0][len(mock.calls[0])-len(want):], want) {
+				t.Errorf("MergeBase() ran %v, want args ending in %v", mock.calls, want)
+			}
+		})
+	}
+}
+
+func TestRepository_Push_ForceWithLease(t *testing.T) {
+	ctx := testContext()
+	mock := &mockExecer{}
+	repo := &Repository{gitDir: "/path/to/repo", rootDir: "/path/to/repo", bare: true, exec: mock}
+
+	err := repo.Push(ctx, PushOptions{
+		Remote:         "origin",
+		RefSpecs:       []Refspec{"abc123:refs/heads/main"},
+		ForceWithLease: []Lease{{Ref: "refs/heads/main", Expect: "def456"}},
+	})
+	if err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	want := []string{"push", "--force-with-lease=refs/heads/main:def456", "origin", "abc123:refs/heads/main"}
+	if len(mock.calls) != 1 || !reflect.DeepEqual(mock.calls[0][len(mock.calls[0])-len(want):], want) {
+		t.Errorf("Push() ran %v, want args ending in %v", mock.calls, want)
+	}
+}
+
+func TestParseCommit(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *Commit
+		wantErr bool
+	}{
+		{
+			name: "single parent",
+			data: "tree 1111111111111111111111111111111111111111\n" +
+				"parent 2222222222222222222222222222222222222222\n" +
+				"author Test User <test@example.com> 1700000000 +0000\n" +
+				"committer Other <other@example.com> 1700000001 +0000\n" +
+				"\n" +
+				"team/service: 3 files\n",
+			want: &Commit{
+				Tree:    "1111111111111111111111111111111111111111",
+				Parents: []Hash{"2222222222222222222222222222222222222222"},
+				Author:  Author{Name: "Test User", Email: "test@example.com"},
+				Message: "team/service: 3 files\n",
+			},
+		},
+		{
+			name: "root commit",
+			data: "tree 1111111111111111111111111111111111111111\n" +
+				"author Test User <test@example.com> 1700000000 +0000\n" +
+				"\n" +
+				"Initial commit\n",
+			want: &Commit{
+				Tree:    "1111111111111111111111111111111111111111",
+				Author:  Author{Name: "Test User", Email: "test@example.com"},
+				Message: "Initial commit\n",
+			},
+		},
+		{
+			name: "merge commit",
+			data: "tree 1111111111111111111111111111111111111111\n" +
+				"parent 2222222222222222222222222222222222222222\n" +
+				"parent 3333333333333333333333333333333333333333\n" +
+				"author Test User <test@example.com> 1700000000 +0000\n" +
+				"\n" +
+				"Merge\n",
+			want: &Commit{
+				Tree:    "1111111111111111111111111111111111111111",
+				Parents: []Hash{"2222222222222222222222222222222222222222", "3333333333333333333333333333333333333333"},
+				Author:  Author{Name: "Test User", Email: "test@example.com"},
+				Message: "Merge\n",
+			},
+		},
+		{
+			name:    "not a commit",
+			data:    "garbage",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCommit([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCommit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCommit() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_WriteObjectBatch_WithMock(t *testing.T) {
+	tests := []struct {
+		name      string
+		bodies    []string
+		output    string
+		outputErr error
+		want      []Hash
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "hashes in input order",
+			bodies:    []string{"first", "second"},
+			output:    "aaa111\nbbb222\n",
+			want:      []Hash{"aaa111", "bbb222"},
+			wantCalls: 1,
+		},
+		{
+			name:      "no bodies",
+			wantCalls: 0,
+		},
+		{
+			name:      "hash count mismatch",
+			bodies:    []string{"first", "second"},
+			output:    "aaa111\n",
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "git error",
+			bodies:    []string{"first"},
+			outputErr: errors.New("hash-object failed"),
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockExecer{output: []byte(tt.output), outputErr: tt.outputErr}
+			repo := &Repository{gitDir: "/path/to/repo", rootDir: "/path/to/repo", bare: true, exec: mock}
+
+			var bodies []io.Reader
+			for _, b := range tt.bodies {
+				bodies = append(bodies, strings.NewReader(b))
+			}
+
+			got, err := repo.WriteObjectBatch(testContext(), bodies)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WriteObjectBatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WriteObjectBatch() = %v, want %v", got, tt.want)
+			}
+			if len(mock.calls) != tt.wantCalls {
+				t.Fatalf("WriteObjectBatch() ran %d git commands, want %d", len(mock.calls), tt.wantCalls)
+			}
+			if tt.wantCalls > 0 && !strings.Contains(strings.Join(mock.calls[0], " "), "hash-object -w --no-filters --stdin-paths") {
+				t.Errorf("WriteObjectBatch() ran %v", mock.calls[0])
+			}
+		})
+	}
+}
+
+func TestRepository_WalkTree_WithMock(t *testing.T) {
+	ctx := testContext()
+	out := []byte("100644 blob abc123\ta.proto\x00040000 tree def456\tdir\x00100644 blob 789abc\tdir/b.proto\x00")
+	errStop := errors.New("stop")
+
+	tests := []struct {
+		name      string
+		stdout    []byte
+		runErr    error
+		stopAfter int // Callback fails on this entry (1-based); 0 never fails
+		wantPaths []string
+		wantErr   error
+	}{
+		{
+			name:      "every entry",
+			stdout:    out,
+			wantPaths: []string{"a.proto", "dir", "dir/b.proto"},
+		},
+		{
+			name:      "callback stops the walk",
+			stdout:    out,
+			stopAfter: 2,
+			wantPaths: []string{"a.proto", "dir"},
+			wantErr:   errStop,
+		},
+		{
+			name:    "git failure",
+			runErr:  errors.New("not a valid object name"),
+			wantErr: errors.New("ls-tree: not a valid object name"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockExecer{stdout: tt.stdout, runErr: tt.runErr}
+			repo := &Repository{gitDir: "/path/to/repo/.git", rootDir: "/path/to/repo", exec: mock}
+
+			var paths []string
+			err := repo.WalkTree(ctx, "HEAD", ReadTreeOptions{Recurse: true}, func(entry TreeEntry) error {
+				paths = append(paths, entry.Path)
+				if len(paths) == tt.stopAfter {
+					return errStop
+				}
+				return nil
+			})
+
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("WalkTree() error = %v", err)
+			case tt.wantErr == errStop && err != errStop:
+				t.Fatalf("WalkTree() error = %v, want the callback's error", err)
+			case tt.wantErr != nil && (err == nil || err.Error() != tt.wantErr.Error()):
+				t.Fatalf("WalkTree() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Errorf("WalkTree() visited %q, want %q", paths, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestRepository_WalkTree_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(testContext())
+	mock := &mockExecer{stdout: []byte("100644 blob abc123\ta.proto\x00100644 blob def456\tb.proto\x00")}
+	repo := &Repository{gitDir: "/path/to/repo/.git", rootDir: "/path/to/repo", exec: mock}
+
+	calls := 0
+	err := repo.WalkTree(ctx, "HEAD", ReadTreeOptions{}, func(TreeEntry) error {
+		calls++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WalkTree() error = %v, want context.Canceled", err)
+	}
+	if calls != 1 {
+		t.Errorf("callback called %d times after cancel, want 1", calls)
+	}
+}
+
+func TestParseCatFileBatch(t *testing.T) {
+	hashes := []Hash{"aaa111", "bbb222"}
+	errStop := errors.New("stop")
+
+	tests := []struct {
+		name        string
+		out         string
+		readAll     bool // Callback reads the whole content; otherwise only the first byte
+		stopAt      Hash // Callback fails on this object
+		want        map[Hash]string
+		wantMissing []Hash
+		wantErr     bool
+	}{
+		{
+			name:    "every object",
+			out:     "aaa111 blob 5\nhello\nbbb222 blob 0\n\n",
+			readAll: true,
+			want:    map[Hash]string{"aaa111": "hello", "bbb222": ""},
+		},
+		{
+			name: "unread content is skipped",
+			out:  "aaa111 blob 5\nhello\nbbb222 tree 3\nabc\n",
+			want: map[Hash]string{"aaa111": "h", "bbb222": "a"},
+		},
+		{
+			name:        "missing object",
+			out:         "aaa111 missing\nbbb222 blob 2\nhi\n",
+			readAll:     true,
+			want:        map[Hash]string{"bbb222": "hi"},
+			wantMissing: []Hash{"aaa111"},
+		},
+		{
+			name:        "ambiguous name",
+			out:         "aaa111 ambiguous\nbbb222 missing\n",
+			want:        map[Hash]string{},
+			wantMissing: []Hash{"aaa111", "bbb222"},
+		},
+		{
+			name:    "content with newlines",
+			out:     "aaa111 blob 4\na\nb\n\nbbb222 blob 1\n\n\n",
+			readAll: true,
+			want:    map[Hash]string{"aaa111": "a\nb\n", "bbb222": "\n"},
+		},
+		{
+			name:    "truncated content",
+			out:     "aaa111 blob 10\nhello",
+			readAll: true,
+			wantErr: true,
+		},
+		{
+			name:    "output ends early",
+			out:     "aaa111 blob 5\nhello\n",
+			readAll: true,
+			wantErr: true,
+		},
+		{
+			name:    "malformed header",
+			out:     "aaa111 blob\n",
+			wantErr: true,
+		},
+		{
+			name:    "callback error",
+			out:     "aaa111 blob 5\nhello\nbbb222 blob 2\nhi\n",
+			stopAt:  "aaa111",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[Hash]string)
+			missing, err := parseCatFileBatch(strings.NewReader(tt.out), hashes, func(h Hash, _ ObjectType, content io.Reader) error {
+				if h == tt.stopAt {
+					return errStop
+				}
+				var buf []byte
+				var readErr error
+				if tt.readAll {
+					buf, readErr = io.ReadAll(content)
+				} else {
+					buf = make([]byte, 1)
+					_, readErr = io.ReadFull(content, buf)
+				}
+				got[h] = string(buf)
+				return readErr
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseCatFileBatch() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCatFileBatch() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCatFileBatch() read %q, want %q", got, tt.want)
+			}
+			if len(tt.wantMissing) == 0 {
+				if missing != nil {
+					t.Errorf("parseCatFileBatch() missing = %v, want nil", missing)
+				}
+				return
+			}
+			if !errors.Is(missing, protatoerrors.ErrObjectMissing) {
+				t.Errorf("parseCatFileBatch() missing = %v, want ErrObjectMissing", missing)
+			}
+			for _, h := range tt.wantMissing {
+				if !strings.Contains(missing.Error(), string(h)) {
+					t.Errorf("parseCatFileBatch() missing = %v, want it to name %s", missing, h)
+				}
+			}
+		})
+	}
+}
+
+func TestRepository_CatFileBatch_WithMock(t *testing.T) {
+	mock := &mockExecer{stdout: []byte("aaa111 blob 5\nhello\nbbb222 missing\n")}
+	repo := &Repository{gitDir: "/path/to/repo/.git", rootDir: "/path/to/repo", exec: mock}
+
+	var read []string
+	err := repo.CatFileBatch(testContext(), []Hash{"aaa111", "bbb222"}, func(h Hash, objType ObjectType, content io.Reader) error {
+		data, err := io.ReadAll(content)
+		read = append(read, fmt.Sprintf("%s %s %s", h, objType, data))
+		return err
+	})
+	if !errors.Is(err, protatoerrors.ErrObjectMissing) {
+		t.Fatalf("CatFileBatch() error = %v, want ErrObjectMissing for bbb222", err)
+	}
+	if want := []string{"aaa111 blob hello"}; !reflect.DeepEqual(read, want) {
+		t.Errorf("CatFileBatch() read %q, want %q", read, want)
+	}
+	if len(mock.calls) != 1 || !slices.Contains(mock.calls[0], "--batch") {
+		t.Errorf("CatFileBatch() ran %v, want one cat-file --batch", mock.calls)
+	}
+
+	if err := repo.CatFileBatch(testContext(), nil, nil); err != nil || len(mock.calls) != 1 {
+		t.Errorf("CatFileBatch() with no hashes = %v and ran git, want nil without running git", err)
+	}
+}
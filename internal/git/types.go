@@ -4,9 +4,12 @@ package git
 import (
 	"bytes"
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
 	"os/exec"
 	"strings"
+	"time"
 )
 
 // Hash represents a Git commit/tree/blob hash.
@@ -25,6 +28,14 @@ func (h Hash) Short() string {
 	return string(h)
 }
 
+// BlobHash returns the hash Git assigns to a blob with the given content.
+func BlobHash(content []byte) Hash {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write(content)
+	return Hash(hex.EncodeToString(h.Sum(nil)))
+}
+
 // Treeish represents a commit-ish reference.
 type Treeish string
 
@@ -122,14 +133,32 @@ func (e *DefaultExecer) Output(cmd *exec.Cmd) ([]byte, error) {
 
 // CloneOptions contains options for cloning a repository.
 type CloneOptions struct {
-	Bare   bool // Clone as bare repository
-	NoTags bool // Don't clone tags
-	Depth  int  // Shallow clone depth
+	Bare   bool        // Clone as bare repository
+	NoTags bool        // Don't clone tags
+	Depth  int         // Shallow clone depth
+	Branch string      // Only clone this branch; the remote's HEAD when empty
+	Auth   AuthOptions // Credentials for the remote; kept by the returned repository
+}
+
+// AuthOptions contains explicit credentials for a remote, used instead of ambient git credentials.
+// Token and Username/Password apply to HTTPS remotes and are mutually exclusive;
+// SSHKeyPath applies to SSH remotes.
+type AuthOptions struct {
+	Token      string // Bearer token sent in an Authorization header
+	Username   string // Basic auth username
+	Password   string // Basic auth password
+	SSHKeyPath string // Private key used by ssh
+}
+
+// InitOptions contains options for initializing a repository.
+type InitOptions struct {
+	InitialBranch string // Name of the initial branch (default: git's configured default)
 }
 
 // OpenOptions contains options for opening a repository.
 type OpenOptions struct {
-	Bare bool // Open as bare repository
+	Bare bool        // Open as bare repository
+	Auth AuthOptions // Credentials for the repository's remotes
 }
 
 // FetchOptions contains options for fetching.
@@ -139,6 +168,34 @@ type FetchOptions struct {
 	Depth    int       // Fetch depth
 	Prune    bool      // Prune remote tracking refs
 	Force    bool      // Force update refs (allow non-fast-forward)
+
+	NoWriteFetchHead bool // Leave FETCH_HEAD untouched
+	Unshallow        bool // Convert a shallow repository to a complete one
+	Deepen           int  // Fetch this many more commits behind the shallow boundary
+}
+
+// GcOptions contains options for compacting a repository.
+type GcOptions struct {
+	Auto       bool   // Only compact when git's gc.auto thresholds (loose objects, packs) are exceeded
+	Aggressive bool   // Recompute deltas; slower, but produces smaller packs
+	Prune      string // Expiry of unreachable loose objects (e.g., "now"); git's default when empty
+	RepackOnly bool   // Pack objects with repack -a -d instead of running a full gc
+}
+
+// LsRemoteOptions contains options for listing the refs of a remote.
+type LsRemoteOptions struct {
+	Remote   string   // Remote name or URL; required when Patterns are given
+	Heads    bool     // Limit to branches (refs/heads)
+	Tags     bool     // Limit to tags (refs/tags)
+	Symref   bool     // Report the target of symbolic refs such as HEAD
+	Patterns []string // Ref patterns to match (e.g., "HEAD", "refs/heads/*")
+}
+
+// RemoteRef is a ref advertised by a remote.
+type RemoteRef struct {
+	Name   string // Full ref name (e.g., refs/heads/main)
+	Hash   Hash   // Object the ref points to
+	Target string // Ref a symbolic ref points to; only set with LsRemoteOptions.Symref
 }
 
 // PushOptions contains options for pushing.
@@ -147,6 +204,49 @@ type PushOptions struct {
 	RefSpecs []Refspec // Refspecs to push
 	Atomic   bool      // Atomic push
 	Force    bool      // Force push
+
+	ForceWithLease []Lease // Force push only while each remote ref still has its expected value
+}
+
+// Lease is the value a remote ref must still have for a force push to proceed.
+type Lease struct {
+	Ref    string // Remote ref (e.g., refs/heads/main)
+	Expect Hash   // Expected current value
+}
+
+// DiffStatus is the kind of change a DiffEntry records.
+type DiffStatus string
+
+// Diff statuses, as printed by git diff-tree --name-status.
+const (
+	DiffAdded    DiffStatus = "A"
+	DiffModified DiffStatus = "M" // Also reported for type changes (a file replaced by a symlink)
+	DiffDeleted  DiffStatus = "D"
+	DiffRenamed  DiffStatus = "R"
+)
+
+// DiffEntry is one changed path between two trees.
+type DiffEntry struct {
+	Status     DiffStatus
+	Path       string // Path in the new tree; in the old tree for deletions
+	OldPath    string // Path in the old tree for renames; empty otherwise
+	Similarity int    // Percentage of content kept by a rename (100 for an exact rename)
+}
+
+// DiffOptions contains options for diffing two trees.
+type DiffOptions struct {
+	Paths       []string // Limit to these paths
+	FindRenames bool     // Report a deleted and added path with similar content as a rename
+}
+
+// LogOptions contains options for git log.
+type LogOptions struct {
+	Rev        Treeish  // Commit to start from, or a range such as "a..b" (defaults to HEAD)
+	Paths      []string // Limit to commits touching these paths
+	DiffFilter string   // Limit by change type (e.g. "D" for deletions)
+	Follow     bool     // Follow renames of a single path
+	MaxCount   int      // Stop after this many commits (0 for all)
+	Format     string   // Pretty format of each commit (set by Log)
 }
 
 // ReadTreeOptions contains options for reading a tree.
@@ -183,6 +283,30 @@ type CommitTreeRequest struct {
 	Author  Author // Author/committer
 }
 
+// Commit holds the fields of a commit object. Its producers each fill only some of them:
+// ParseCommit reads a commit object's content, which does not hold its hash, and Log
+// prints neither trees nor parents. Fields a producer leaves unset are zero.
+type Commit struct {
+	Hash    Hash      // Set by Log
+	Tree    Hash      // Set by ParseCommit
+	Parents []Hash    // Set by ParseCommit
+	Author  Author    // Set by both
+	Date    time.Time // Author date, set by Log
+	Message string    // Set by both
+}
+
+// Subject returns the first line of the message.
+func (c *Commit) Subject() string {
+	subject, _, _ := strings.Cut(strings.TrimLeft(c.Message, "\n"), "\n")
+	return subject
+}
+
+// Body returns the message after the subject and the blank lines following it.
+func (c *Commit) Body() string {
+	_, body, _ := strings.Cut(strings.TrimLeft(c.Message, "\n"), "\n")
+	return strings.TrimLeft(body, "\n")
+}
+
 // RevParseOptions contains options for git rev-parse.
 type RevParseOptions struct {
 	Verify bool // Verify the object exists
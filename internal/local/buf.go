@@ -0,0 +1,192 @@
+package local
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+)
+
+// Buf configuration versions understood by UpdateBufConfig.
+const (
+	bufVersionV1Beta1 = "v1beta1"
+	bufVersionV1      = "v1"
+	bufVersionV2      = "v2"
+)
+
+// UpdateBufConfig makes the vendor directory part of the buf.yaml at the workspace root,
+// so buf build and lint see pulled projects. A missing buf.yaml is created as a v2
+// configuration with the workspace root, where owned imports (e.g., "proto/team/...") start,
+// and the vendor directory as modules; the root module excludes the vendor directory. An
+// existing file gains the vendor directory as a v2 module or a v1 build root; everything else in it
+// is kept. With the registry-path vendor layout the module is its protos directory; the
+// flat layout drops the service from paths, so its files do not resolve as buf imports.
+// Reports whether the file was written.
+func (ws *Workspace) UpdateBufConfig() (bool, error) {
+	vendorDir, err := ws.config.VendorDir()
+	if err != nil {
+		return false, fmt.Errorf("get vendor directory: %w", err)
+	}
+	layout, err := ws.vendorLayout()
+	if err != nil {
+		return false, err
+	}
+	if layout == VendorLayoutRegistryPath {
+		// Import paths start below the protos directory of this layout
+		vendorDir = filepath.Join(vendorDir, constants.ProtosDir)
+	}
+	vendorDir = bufPath(vendorDir)
+
+	path := filepath.Join(ws.root, constants.BufConfigFileName)
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+		setMappingValue(doc.Content[0], "version", scalarNode(bufVersionV2))
+	case err != nil:
+		return false, fmt.Errorf("read %s: %w", constants.BufConfigFileName, err)
+	default:
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return false, fmt.Errorf("parse %s: %w", constants.BufConfigFileName, err)
+		}
+		if len(doc.Content) == 0 {
+			doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+		}
+		if doc.Content[0].Kind != yaml.MappingNode {
+			return false, fmt.Errorf("parse %s: top level is not a mapping", constants.BufConfigFileName)
+		}
+	}
+
+	changed, err := addBufVendorPath(doc.Content[0], vendorDir)
+	if err != nil || !changed {
+		return false, err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return false, fmt.Errorf("encode %s: %w", constants.BufConfigFileName, err)
+	}
+	if err := enc.Close(); err != nil {
+		return false, fmt.Errorf("encode %s: %w", constants.BufConfigFileName, err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return false, fmt.Errorf("write %s: %w", constants.BufConfigFileName, err)
+	}
+	return true, nil
+}
+
+// addBufVendorPath adds vendorDir to the modules (v2) or build roots (v1) of a buf.yaml mapping.
+// When the list is absent, it is created with the workspace root first, excluding vendorDir,
+// since buf would otherwise stop treating the root as the module the owned protos live in.
+func addBufVendorPath(root *yaml.Node, vendorDir string) (bool, error) {
+	version := bufVersionV1Beta1 // buf's default when version is omitted
+	if v := mappingValue(root, "version"); v != nil {
+		version = v.Value
+	}
+
+	switch version {
+	case bufVersionV2:
+		modules := mappingValue(root, "modules")
+		if modules == nil {
+			rootModule := bufModuleNode(".")
+			setMappingValue(rootModule, "excludes", sequenceNode(vendorDir))
+			modules = sequenceNode()
+			modules.Content = append(modules.Content, rootModule)
+			setMappingValue(root, "modules", modules)
+		}
+		if modules.Kind != yaml.SequenceNode {
+			return false, fmt.Errorf("%s: modules is not a list", constants.BufConfigFileName)
+		}
+		for _, m := range modules.Content {
+			if p := mappingValue(m, "path"); p != nil && bufPath(p.Value) == vendorDir {
+				return false, nil
+			}
+		}
+		modules.Content = append(modules.Content, bufModuleNode(vendorDir))
+		return true, nil
+
+	case bufVersionV1, bufVersionV1Beta1:
+		build := mappingValue(root, "build")
+		if build == nil {
+			build = &yaml.Node{Kind: yaml.MappingNode}
+			setMappingValue(root, "build", build)
+		}
+		if build.Kind != yaml.MappingNode {
+			return false, fmt.Errorf("%s: build is not a mapping", constants.BufConfigFileName)
+		}
+		roots := mappingValue(build, "roots")
+		if roots == nil {
+			roots = sequenceNode(".")
+			setMappingValue(build, "roots", roots)
+			if mappingValue(build, "excludes") == nil {
+				setMappingValue(build, "excludes", sequenceNode(vendorDir))
+			}
+		}
+		if roots.Kind != yaml.SequenceNode {
+			return false, fmt.Errorf("%s: build.roots is not a list", constants.BufConfigFileName)
+		}
+		for _, r := range roots.Content {
+			if bufPath(r.Value) == vendorDir {
+				return false, nil
+			}
+		}
+		roots.Content = append(roots.Content, scalarNode(vendorDir))
+		return true, nil
+
+	default:
+		return false, fmt.Errorf("%s: unsupported version %q", constants.BufConfigFileName, version)
+	}
+}
+
+// bufPath normalizes a workspace-relative directory the way buf.yaml spells it ("" becomes ".").
+func bufPath(dir string) string {
+	return filepath.ToSlash(filepath.Clean(dir))
+}
+
+// bufModuleNode returns a v2 module entry for dir.
+func bufModuleNode(dir string) *yaml.Node {
+	module := &yaml.Node{Kind: yaml.MappingNode}
+	setMappingValue(module, "path", scalarNode(dir))
+	return module
+}
+
+// sequenceNode returns a list node of plain strings.
+func sequenceNode(values ...string) *yaml.Node {
+	seq := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, v := range values {
+		seq.Content = append(seq.Content, scalarNode(v))
+	}
+	return seq
+}
+
+// scalarNode returns a plain string node.
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue appends key with value to a mapping node.
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	m.Content = append(m.Content, scalarNode(key), value)
+}
@@ -0,0 +1,147 @@
+package local
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+)
+
+func TestWorkspace_UpdateBufConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    string // buf.yaml content; "" means no file
+		want        string
+		wantChanged bool
+		wantErr     bool
+	}{
+		{
+			name:        "creates v2 config",
+			want:        "version: v2\nmodules:\n  - path: .\n    excludes:\n      - vendor-proto\n  - path: vendor-proto\n",
+			wantChanged: true,
+		},
+		{
+			name: "adds module to v2 config and keeps other entries",
+			existing: `version: v2
+# Our modules
+modules:
+  - path: proto
+    name: buf.build/acme/api
+lint:
+  use:
+    - STANDARD
+`,
+			want: `version: v2
+# Our modules
+modules:
+  - path: proto
+    name: buf.build/acme/api
+  - path: vendor-proto
+lint:
+  use:
+    - STANDARD
+`,
+			wantChanged: true,
+		},
+		{
+			name:     "v2 module already present",
+			existing: "version: v2\nmodules:\n  - path: ./vendor-proto/\n",
+			want:     "version: v2\nmodules:\n  - path: ./vendor-proto/\n",
+		},
+		{
+			name: "adds root to v1 config",
+			existing: `version: v1
+build:
+  roots:
+    - proto
+breaking:
+  use:
+    - FILE
+`,
+			want: `version: v1
+build:
+  roots:
+    - proto
+    - vendor-proto
+breaking:
+  use:
+    - FILE
+`,
+			wantChanged: true,
+		},
+		{
+			name:        "v1 without roots",
+			existing:    "version: v1\nname: buf.build/acme/api\n",
+			want:        "version: v1\nname: buf.build/acme/api\nbuild:\n  roots:\n    - .\n    - vendor-proto\n  excludes:\n    - vendor-proto\n",
+			wantChanged: true,
+		},
+		{
+			name:     "unsupported version",
+			existing: "version: v3\n",
+			want:     "version: v3\n",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, ws := setupTestWorkspaceWithConfig(t, &Config{Service: "test", Directories: DefaultDirectoryConfig()})
+			path := filepath.Join(tmpDir, constants.BufConfigFileName)
+			if tt.existing != "" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			changed, err := ws.UpdateBufConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateBufConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("UpdateBufConfig() changed = %v, want %v", changed, tt.wantChanged)
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("buf.yaml =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkspace_UpdateBufConfig_BufBuild(t *testing.T) {
+	if _, err := exec.LookPath("buf"); err != nil {
+		t.Skip("buf CLI not found")
+	}
+	tmpDir, ws := setupTestWorkspaceWithConfig(t, &Config{Service: "test", Directories: DefaultDirectoryConfig()})
+
+	// Owned protos import each other through the owned directory; received ones by project path
+	files := map[string]string{
+		"proto/team/a/a.proto":                  "syntax = \"proto3\";\npackage team.a;\nimport \"proto/team/b/b.proto\";\nimport \"other-svc/team/c/c.proto\";\nmessage A {\n  team.b.B b = 1;\n  team.c.C c = 2;\n}\n",
+		"proto/team/b/b.proto":                  "syntax = \"proto3\";\npackage team.b;\nmessage B {}\n",
+		"vendor-proto/other-svc/team/c/c.proto": "syntax = \"proto3\";\npackage team.c;\nmessage C {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := ws.UpdateBufConfig(); err != nil {
+		t.Fatalf("UpdateBufConfig() error = %v", err)
+	}
+	build := exec.Command("buf", "build")
+	build.Dir = tmpDir
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Errorf("buf build with the generated buf.yaml: %v\n%s", err, out)
+	}
+}
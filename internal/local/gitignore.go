@@ -0,0 +1,92 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+)
+
+// UpdateGitignore brings the vendor directory's entry in the workspace root .gitignore
+// in line with vendor_gitignore: "ignore" adds it and "track" removes it. Other lines,
+// comments and blank lines are kept as they are. An unset mode leaves .gitignore alone.
+// Reports whether the file was written.
+func (ws *Workspace) UpdateGitignore() (bool, error) {
+	mode := ""
+	if ws.config != nil {
+		mode = ws.config.VendorGitignore
+	}
+	if mode == "" {
+		return false, nil
+	}
+	if mode != VendorGitignoreIgnore && mode != VendorGitignoreTrack {
+		return false, fmt.Errorf("invalid vendor_gitignore %q (want %s or %s)", mode, VendorGitignoreIgnore, VendorGitignoreTrack)
+	}
+
+	vendorDir, err := ws.config.VendorDir()
+	if err != nil {
+		return false, fmt.Errorf("get vendor directory: %w", err)
+	}
+	vendorDir = filepath.ToSlash(filepath.Clean(vendorDir))
+	if vendorDir == "." || vendorDir == "" {
+		if mode == VendorGitignoreIgnore {
+			return false, fmt.Errorf("cannot gitignore the vendor directory: it is the workspace root")
+		}
+		return false, nil
+	}
+
+	path := filepath.Join(ws.root, constants.GitignoreName)
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("read %s: %w", constants.GitignoreName, err)
+	}
+
+	content := string(data)
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+
+	var kept []string
+	found := false
+	for _, line := range lines {
+		if isVendorGitignoreEntry(line, vendorDir) {
+			found = true
+			if mode == VendorGitignoreTrack {
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+
+	if found == (mode == VendorGitignoreIgnore) {
+		return false, nil
+	}
+	if mode == VendorGitignoreIgnore {
+		kept = append(kept, "/"+vendorDir+"/")
+	}
+
+	updated := strings.Join(kept, "\n")
+	if updated != "" {
+		updated += "\n"
+	}
+	if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
+		return false, fmt.Errorf("write %s: %w", constants.GitignoreName, err)
+	}
+	return true, nil
+}
+
+// isVendorGitignoreEntry reports whether a .gitignore line ignores exactly the vendor directory,
+// in any of the equivalent forms: vendor, vendor/, /vendor or /vendor/.
+func isVendorGitignoreEntry(line, vendorDir string) bool {
+	entry := strings.TrimSpace(line)
+	if entry == "" || strings.HasPrefix(entry, "#") || strings.HasPrefix(entry, "!") {
+		return false
+	}
+	entry = strings.TrimSuffix(strings.TrimPrefix(entry, "/"), "/")
+	return entry == vendorDir
+}
@@ -0,0 +1,119 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+)
+
+func TestWorkspace_UpdateGitignore(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		existing    string // .gitignore content; "" means no file
+		want        string // "" means no file
+		wantChanged bool
+		wantErr     bool
+	}{
+		{
+			name:        "ignore creates .gitignore",
+			mode:        VendorGitignoreIgnore,
+			want:        "/vendor-proto/\n",
+			wantChanged: true,
+		},
+		{
+			name:        "ignore appends and keeps other entries",
+			mode:        VendorGitignoreIgnore,
+			existing:    "# build output\nbin/\n\n*.log",
+			want:        "# build output\nbin/\n\n*.log\n/vendor-proto/\n",
+			wantChanged: true,
+		},
+		{
+			name:     "ignore with equivalent entry present",
+			mode:     VendorGitignoreIgnore,
+			existing: "bin/\nvendor-proto\n",
+			want:     "bin/\nvendor-proto\n",
+		},
+		{
+			name:        "track removes every form of the entry",
+			mode:        VendorGitignoreTrack,
+			existing:    "bin/\n/vendor-proto/\n# vendor-proto\nvendor-proto\n!vendor-proto/keep\n",
+			want:        "bin/\n# vendor-proto\n!vendor-proto/keep\n",
+			wantChanged: true,
+		},
+		{
+			name:     "track without entry",
+			mode:     VendorGitignoreTrack,
+			existing: "bin/\nvendor-proto/sub/\n",
+			want:     "bin/\nvendor-proto/sub/\n",
+		},
+		{
+			name: "track without .gitignore",
+			mode: VendorGitignoreTrack,
+		},
+		{
+			name:     "unset leaves .gitignore alone",
+			existing: "bin/\n",
+			want:     "bin/\n",
+		},
+		{
+			name:     "invalid mode",
+			mode:     "skip",
+			existing: "bin/\n",
+			want:     "bin/\n",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, ws := setupTestWorkspaceWithConfig(t, &Config{
+				Service:         "test",
+				Directories:     DefaultDirectoryConfig(),
+				VendorGitignore: tt.mode,
+			})
+			path := filepath.Join(tmpDir, constants.GitignoreName)
+			if tt.existing != "" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			changed, err := ws.UpdateGitignore()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateGitignore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("UpdateGitignore() changed = %v, want %v", changed, tt.wantChanged)
+			}
+
+			data, err := os.ReadFile(path)
+			if tt.want == "" {
+				if !os.IsNotExist(err) {
+					t.Errorf(".gitignore exists with %q, want no file", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf(".gitignore = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkspace_UpdateGitignore_VendorAtRoot(t *testing.T) {
+	_, ws := setupTestWorkspaceWithConfig(t, &Config{
+		Service:         "test",
+		Directories:     DirectoryConfig{Owned: "proto", Vendor: "."},
+		VendorGitignore: VendorGitignoreIgnore,
+	})
+
+	if _, err := ws.UpdateGitignore(); err == nil {
+		t.Error("UpdateGitignore() succeeded, want error for a vendor dir at the workspace root")
+	}
+}
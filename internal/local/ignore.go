@@ -0,0 +1,90 @@
+package local
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+	"github.com/rahulagarwal0605/protato/internal/utils"
+)
+
+// ignoreRule is one ignore pattern, matched against paths relative to the owned directory.
+type ignoreRule struct {
+	pattern string // Doublestar pattern
+	negate  bool   // Re-includes paths an earlier rule ignored
+}
+
+// parseIgnoreRules turns gitignore-style lines into rules. Blank lines and lines starting
+// with # are skipped, a leading ! negates the pattern, and a leading backslash keeps a
+// literal # or !. Leading and trailing slashes are dropped since every pattern is already
+// relative to the owned directory.
+func parseIgnoreRules(lines []string) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if pattern, ok := strings.CutPrefix(line, "!"); ok {
+			rule.negate = true
+			line = pattern
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+		rule.pattern = strings.Trim(line, "/")
+		if rule.pattern != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// readIgnoreFile reads the rules of the .protatoignore file at the workspace root.
+// A missing file has no rules.
+func readIgnoreFile(root string) ([]ignoreRule, error) {
+	data, err := os.ReadFile(filepath.Join(root, constants.IgnoreFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", constants.IgnoreFileName, err)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", constants.IgnoreFileName, err)
+	}
+	return parseIgnoreRules(lines), nil
+}
+
+// loadIgnores returns the rules of Config.Ignores followed by those of .protatoignore,
+// so the file can override or re-include what the config ignores.
+func loadIgnores(root string, config *Config) ([]ignoreRule, error) {
+	fileRules, err := readIgnoreFile(root)
+	if err != nil {
+		return nil, err
+	}
+	return append(parseIgnoreRules(config.Ignores), fileRules...), nil
+}
+
+// isIgnored reports whether a project or file path (relative to the owned directory) is
+// ignored. As in gitignore, the last rule that matches decides.
+func (ws *Workspace) isIgnored(p string) bool {
+	ignored := false
+	for _, rule := range ws.ignores {
+		if utils.MatchPattern(rule.pattern, p) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
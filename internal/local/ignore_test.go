@@ -0,0 +1,111 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+)
+
+func TestParseIgnoreRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []ignoreRule
+	}{
+		{
+			name:  "comments and blank lines",
+			lines: []string{"# generated code", "", "   ", "gen/**", "  # indented comment"},
+			want:  []ignoreRule{{pattern: "gen/**"}},
+		},
+		{
+			name:  "negation",
+			lines: []string{"**/test/**", "!team/test/**"},
+			want:  []ignoreRule{{pattern: "**/test/**"}, {pattern: "team/test/**", negate: true}},
+		},
+		{
+			name:  "escaped literals",
+			lines: []string{`\#notes`, `\!important`},
+			want:  []ignoreRule{{pattern: "#notes"}, {pattern: "!important"}},
+		},
+		{
+			name:  "slashes trimmed",
+			lines: []string{"/legacy/", "!/"},
+			want:  []ignoreRule{{pattern: "legacy"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseIgnoreRules(tt.lines); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIgnoreRules() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkspace_ProtatoIgnore(t *testing.T) {
+	cfg := &Config{
+		Service:      "test-service",
+		AutoDiscover: true,
+		Directories: DirectoryConfig{
+			Owned:  "proto",
+			Vendor: "vendor-proto",
+		},
+		Ignores: []string{"**/deprecated/**"},
+	}
+	root, _ := setupTestWorkspaceWithConfig(t, cfg)
+
+	ignoreFile := "# colocated ignores\n\n**/test/**\n!team/test\n!team/deprecated/**\nteam/service/*.bak\n"
+	if err := os.WriteFile(filepath.Join(root, constants.IgnoreFileName), []byte(ignoreFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{
+		"team/service/api.proto",
+		"team/service/api.bak",
+		"team/test/api.proto",
+		"other/test/api.proto",
+		"team/deprecated/api.proto",
+		"other/deprecated/api.proto",
+	} {
+		full := filepath.Join(root, "proto", filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte("syntax = \"proto3\";\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ws, err := Open(context.Background(), root)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "team/service", want: false},
+		{path: "team/service/api.bak", want: true},
+		{path: "other/test", want: true},
+		{path: "team/test", want: false},                 // Re-included by the file
+		{path: "other/deprecated", want: true},           // From Config.Ignores
+		{path: "team/deprecated/api.proto", want: false}, // File overrides config
+	}
+	for _, tt := range tests {
+		if got := ws.isIgnored(tt.path); got != tt.want {
+			t.Errorf("isIgnored(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if got := ws.applyFileIgnores([]ProjectFile{{Path: "api.proto"}, {Path: "api.bak"}}, "team/service"); len(got) != 1 || got[0].Path != "api.proto" {
+		t.Errorf("applyFileIgnores() = %+v, want only api.proto", got)
+	}
+	if !ws.IsProjectOwned("team/test") {
+		t.Error("IsProjectOwned(team/test) = false, want true after negation")
+	}
+}
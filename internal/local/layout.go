@@ -0,0 +1,103 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+	"github.com/rahulagarwal0605/protato/internal/protoc"
+)
+
+// vendorLayout returns the configured layout of received projects.
+func (ws *Workspace) vendorLayout() (string, error) {
+	if ws.config == nil || ws.config.Vendor.Layout == "" {
+		return VendorLayoutNamespaced, nil
+	}
+	switch layout := ws.config.Vendor.Layout; layout {
+	case VendorLayoutNamespaced, VendorLayoutFlat, VendorLayoutRegistryPath:
+		return layout, nil
+	default:
+		return "", fmt.Errorf("invalid vendor.layout %q (want %s, %s or %s)",
+			layout, VendorLayoutNamespaced, VendorLayoutFlat, VendorLayoutRegistryPath)
+	}
+}
+
+// vendorRelDir returns the directory of a received project in the vendor dir, slash-separated
+// and relative to it.
+func vendorRelDir(layout string, project ProjectPath) string {
+	switch layout {
+	case VendorLayoutFlat:
+		if _, rest, ok := strings.Cut(string(project), "/"); ok {
+			return rest
+		}
+	case VendorLayoutRegistryPath:
+		return path.Join(constants.ProtosDir, string(project))
+	}
+	return string(project)
+}
+
+// vendorProject returns the received project whose lock file is in relDir of the vendor dir.
+// The flat layout drops the service, so the project comes from the lock file there.
+// Returns "" when relDir holds no project of the layout.
+func vendorProject(layout, relDir string, lock *LockFile) ProjectPath {
+	switch layout {
+	case VendorLayoutFlat:
+		return ProjectPath(lock.Project)
+	case VendorLayoutRegistryPath:
+		project, ok := strings.CutPrefix(relDir, constants.ProtosDir+"/")
+		if !ok {
+			return ""
+		}
+		return ProjectPath(project)
+	}
+	return ProjectPath(relDir)
+}
+
+// vendorProjectDir returns the absolute directory of a received project.
+func (ws *Workspace) vendorProjectDir(project ProjectPath) (string, error) {
+	vendorDir, err := ws.VendorDir()
+	if err != nil {
+		return "", err
+	}
+	layout, err := ws.vendorLayout()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(vendorDir, filepath.FromSlash(vendorRelDir(layout, project))), nil
+}
+
+// VendorRoots returns the directories to load received files from, each with the import prefix
+// of its files. Received files are imported by registry project path whatever the layout, so
+// flat projects each get a root of their own.
+func (ws *Workspace) VendorRoots(ctx context.Context) ([]protoc.VendorRoot, error) {
+	vendorDir, err := ws.VendorDir()
+	if err != nil {
+		return nil, err
+	}
+	layout, err := ws.vendorLayout()
+	if err != nil {
+		return nil, err
+	}
+
+	switch layout {
+	case VendorLayoutRegistryPath:
+		return []protoc.VendorRoot{{Dir: filepath.Join(vendorDir, constants.ProtosDir)}}, nil
+	case VendorLayoutFlat:
+		received, err := ws.ReceivedProjects(ctx)
+		if err != nil {
+			return nil, err
+		}
+		roots := make([]protoc.VendorRoot, len(received))
+		for i, r := range received {
+			roots[i] = protoc.VendorRoot{
+				Dir:          filepath.Join(vendorDir, filepath.FromSlash(vendorRelDir(layout, r.Project))),
+				ImportPrefix: string(r.Project),
+			}
+		}
+		return roots, nil
+	}
+	return []protoc.VendorRoot{{Dir: vendorDir}}, nil
+}
@@ -0,0 +1,151 @@
+package local
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/internal/protoc"
+	"github.com/rahulagarwal0605/protato/internal/utils"
+)
+
+// setupLayoutWorkspace returns a workspace with the vendor layout and the project received into it.
+func setupLayoutWorkspace(t *testing.T, layout string, project ProjectPath) (string, *Workspace) {
+	t.Helper()
+	tmpDir, ws := setupTestWorkspaceWithConfig(t, &Config{
+		Service:     "my-svc",
+		Directories: DirectoryConfig{Owned: "proto", Vendor: "vendor-proto"},
+		Vendor:      VendorConfig{Layout: layout},
+	})
+	receiveTestFile(t, ws, project)
+	return tmpDir, ws
+}
+
+// receiveTestFile receives project with a single v1/api.proto.
+func receiveTestFile(t *testing.T, ws *Workspace, project ProjectPath) {
+	t.Helper()
+	receiver, err := ws.ReceiveProject(&ReceiveProjectRequest{Project: project, Snapshot: "abc123"})
+	if err != nil {
+		t.Fatalf("ReceiveProject() error = %v", err)
+	}
+	w, err := receiver.CreateFile("v1/api.proto")
+	if err != nil {
+		t.Fatalf("CreateFile() error = %v", err)
+	}
+	w.Write([]byte("syntax = \"proto3\";\n"))
+	w.Close()
+	if _, err := receiver.Finish(); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+}
+
+func TestWorkspace_ReceiveProject_Layouts(t *testing.T) {
+	tests := []struct {
+		layout    string
+		wantDir   string
+		wantRoots []protoc.VendorRoot
+	}{
+		{
+			layout:    "",
+			wantDir:   "vendor-proto/other-svc/team/api",
+			wantRoots: []protoc.VendorRoot{{Dir: "vendor-proto"}},
+		},
+		{
+			layout:    VendorLayoutFlat,
+			wantDir:   "vendor-proto/team/api",
+			wantRoots: []protoc.VendorRoot{{Dir: "vendor-proto/team/api", ImportPrefix: "other-svc/team/api"}},
+		},
+		{
+			layout:    VendorLayoutRegistryPath,
+			wantDir:   "vendor-proto/protos/other-svc/team/api",
+			wantRoots: []protoc.VendorRoot{{Dir: "vendor-proto/protos"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			ctx := context.Background()
+			tmpDir, ws := setupLayoutWorkspace(t, tt.layout, "other-svc/team/api")
+
+			if !utils.FileExists(filepath.Join(tmpDir, tt.wantDir, "v1/api.proto")) {
+				t.Errorf("ReceiveProject() did not write %s/v1/api.proto", tt.wantDir)
+			}
+
+			received, err := ws.ReceivedProjects(ctx)
+			if err != nil {
+				t.Fatalf("ReceivedProjects() error = %v", err)
+			}
+			if len(received) != 1 || received[0].Project != "other-svc/team/api" {
+				t.Errorf("ReceivedProjects() = %v, want other-svc/team/api", received)
+			}
+
+			lock, err := ws.GetProjectLock("other-svc/team/api")
+			if err != nil {
+				t.Fatalf("GetProjectLock() error = %v", err)
+			}
+			if lock.Snapshot != "abc123" {
+				t.Errorf("GetProjectLock() snapshot = %q, want abc123", lock.Snapshot)
+			}
+			files, err := ws.ListVendorProjectFiles("other-svc/team/api")
+			if err != nil || len(files) != 1 {
+				t.Errorf("ListVendorProjectFiles() = %v, %v, want the received file", files, err)
+			}
+
+			roots, err := ws.VendorRoots(ctx)
+			if err != nil {
+				t.Fatalf("VendorRoots() error = %v", err)
+			}
+			for i := range tt.wantRoots {
+				tt.wantRoots[i].Dir = filepath.Join(tmpDir, tt.wantRoots[i].Dir)
+			}
+			if !reflect.DeepEqual(roots, tt.wantRoots) {
+				t.Errorf("VendorRoots() = %v, want %v", roots, tt.wantRoots)
+			}
+
+			orphans, err := ws.OrphanedFiles(ctx)
+			if err != nil {
+				t.Fatalf("OrphanedFiles() error = %v", err)
+			}
+			if len(orphans) != 0 {
+				t.Errorf("OrphanedFiles() = %v, want none", orphans)
+			}
+		})
+	}
+}
+
+func TestWorkspace_ReceiveProject_FlatLayout(t *testing.T) {
+	_, ws := setupLayoutWorkspace(t, VendorLayoutFlat, "other-svc/team/api")
+
+	// Updating the project and its lock keeps the recorded project
+	receiveTestFile(t, ws, "other-svc/team/api")
+	if err := ws.SetProjectLock("other-svc/team/api", &LockFile{Snapshot: "def456"}); err != nil {
+		t.Fatalf("SetProjectLock() error = %v", err)
+	}
+	lock, err := ws.GetProjectLock("other-svc/team/api")
+	if err != nil {
+		t.Fatalf("GetProjectLock() error = %v", err)
+	}
+	if lock.Project != "other-svc/team/api" {
+		t.Errorf("GetProjectLock() project = %q, want other-svc/team/api", lock.Project)
+	}
+
+	if _, err := ws.ReceiveProject(&ReceiveProjectRequest{Project: "third-svc/team/api", Snapshot: "abc123"}); err == nil {
+		t.Error("ReceiveProject() of a project with the same flat path error = nil, want error")
+	}
+}
+
+func TestWorkspace_ReceiveProject_InvalidLayout(t *testing.T) {
+	_, ws := setupTestWorkspaceWithConfig(t, &Config{
+		Service:     "my-svc",
+		Directories: DirectoryConfig{Owned: "proto", Vendor: "vendor-proto"},
+		Vendor:      VendorConfig{Layout: "nested"},
+	})
+
+	if _, err := ws.ReceiveProject(&ReceiveProjectRequest{Project: "other-svc/team/api", Snapshot: "abc123"}); err == nil {
+		t.Error("ReceiveProject() error = nil, want invalid layout error")
+	}
+	if _, err := ws.ReceivedProjects(context.Background()); err == nil {
+		t.Error("ReceivedProjects() error = nil, want invalid layout error")
+	}
+}
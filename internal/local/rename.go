@@ -0,0 +1,302 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+
+	"github.com/rahulagarwal0605/protato/internal/errors"
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/protoc"
+	"github.com/rahulagarwal0605/protato/internal/registry"
+	"github.com/rahulagarwal0605/protato/internal/utils"
+)
+
+// ServiceMover moves pushed projects in the registry; registry.CacheInterface satisfies it.
+type ServiceMover interface {
+	ListProjects(context.Context, *registry.ListProjectsOptions) ([]registry.ProjectPath, error)
+	LookupProject(context.Context, *registry.LookupProjectRequest) (*registry.LookupProjectResponse, error)
+	RenameProject(context.Context, *registry.RenameProjectRequest) (*registry.RenameProjectResponse, error)
+}
+
+// RenameServiceOptions moves the owned projects in the registry along with the service.
+type RenameServiceOptions struct {
+	Registry      ServiceMover // Registry cache to move the projects in
+	RepositoryURL string       // Workspace repository; must own the projects and may not collide with another owner
+	Snapshot      git.Hash     // Base snapshot ("" for the current one)
+	Author        *git.Author  // Required: Git author/committer for the rename commits
+}
+
+// RenameServiceResponse contains the result of renaming the service.
+type RenameServiceResponse struct {
+	Snapshot       git.Hash // Registry snapshot with the projects moved; "" when the registry was not touched
+	ProjectsMoved  int      // Owned projects moved in the registry
+	FilesRewritten int      // Owned proto files whose imports were rewritten
+}
+
+// RenameService changes the service the owned projects are namespaced under. Owned proto imports
+// written with the old service prefix are rewritten to the new one and Config.Service is updated.
+// With opts, each owned project that was pushed is also moved under the new service in the
+// registry, one commit per project; the caller is responsible for pushing the returned snapshot.
+// The rename is refused, before anything is changed, when another repository owns a project
+// under the new service.
+func (ws *Workspace) RenameService(ctx context.Context, newName string, opts *RenameServiceOptions) (*RenameServiceResponse, error) {
+	oldName := ws.ServiceName()
+	if oldName == "" {
+		return nil, errors.ErrServiceNotConfigured
+	}
+	if err := utils.ValidateProjectPath(newName); err != nil {
+		return nil, fmt.Errorf("invalid service name %q: %w", newName, err)
+	}
+	if newName == oldName {
+		return nil, fmt.Errorf("service is already %s", newName)
+	}
+
+	projects, err := ws.OwnedProjects()
+	if err != nil {
+		return nil, fmt.Errorf("list owned projects: %w", err)
+	}
+
+	res := &RenameServiceResponse{}
+	if opts != nil {
+		if err := checkServiceCollision(ctx, opts, newName); err != nil {
+			return nil, err
+		}
+		if err := moveServiceProjects(ctx, opts, oldName, newName, projects, res); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, project := range projects {
+		n, err := ws.rewriteServiceImports(project, oldName, newName)
+		if err != nil {
+			return nil, err
+		}
+		res.FilesRewritten += n
+	}
+
+	ws.config.Service = newName
+	if err := writeConfig(ConfigPath(ws.root), ws.config); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// checkServiceCollision refuses a new service namespace that holds, or sits under, a project
+// owned by another repository.
+func checkServiceCollision(ctx context.Context, opts *RenameServiceOptions, newName string) error {
+	paths := []string{newName}
+	existing, err := opts.Registry.ListProjects(ctx, &registry.ListProjectsOptions{Prefix: newName, Snapshot: opts.Snapshot})
+	if err != nil {
+		return fmt.Errorf("list projects under %s: %w", newName, err)
+	}
+	for _, p := range existing {
+		paths = append(paths, string(p))
+	}
+
+	for _, p := range paths {
+		res, err := opts.Registry.LookupProject(ctx, &registry.LookupProjectRequest{Path: p, Snapshot: opts.Snapshot})
+		if stderrors.Is(err, errors.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("look up %s: %w", p, err)
+		}
+		if res.Project.RepositoryURL != opts.RepositoryURL {
+			return fmt.Errorf("cannot rename service to %s: %w", newName, &registry.OwnershipError{
+				Path:         res.Project.Path,
+				OwnerURL:     res.Project.RepositoryURL,
+				RequesterURL: opts.RepositoryURL,
+			})
+		}
+	}
+	return nil
+}
+
+// moveServiceProjects moves each owned project that exists in the registry from the old
+// service to the new one, chaining the rename commits. Projects never pushed are skipped.
+// Each moved project has its imports of every owned project moved too, so the projects
+// import each other at their new paths once all of them are moved.
+func moveServiceProjects(ctx context.Context, opts *RenameServiceOptions, oldName, newName string, projects []ProjectPath, res *RenameServiceResponse) error {
+	moveImports := func(content []byte, _, _ string) []byte {
+		for _, project := range projects {
+			content = protoc.MoveImports(content,
+				utils.BuildServicePrefixedPath(oldName, string(project)),
+				utils.BuildServicePrefixedPath(newName, string(project)))
+		}
+		return content
+	}
+
+	snapshot := opts.Snapshot
+	for _, project := range projects {
+		from := registry.ProjectPath(utils.BuildServicePrefixedPath(oldName, string(project)))
+		moved, err := opts.Registry.RenameProject(ctx, &registry.RenameProjectRequest{
+			From:          from,
+			To:            registry.ProjectPath(utils.BuildServicePrefixedPath(newName, string(project))),
+			RepositoryURL: opts.RepositoryURL,
+			Snapshot:      snapshot,
+			Author:        opts.Author,
+			MoveImports:   moveImports,
+		})
+		if stderrors.Is(err, errors.ErrNotFound) {
+			continue // Never pushed
+		}
+		if err != nil {
+			return fmt.Errorf("move %s: %w", from, err)
+		}
+		snapshot = moved.Snapshot
+		res.Snapshot = moved.Snapshot
+		res.ProjectsMoved++
+	}
+	return nil
+}
+
+// rewriteServiceImports rewrites the imports of an owned project's proto files that carry the
+// old service prefix to the new one. It returns how many files changed.
+func (ws *Workspace) rewriteServiceImports(project ProjectPath, oldName, newName string) (int, error) {
+	// Imports under oldName/ have that prefix swapped for newName/; others are left alone
+	return ws.rewriteProjectImports(project, func(content []byte) []byte {
+		return protoc.TransformImports(content, oldName, newName)
+	})
+}
+
+// rewriteProjectImports replaces the content of each of an owned project's proto files with
+// what rewrite returns for it. It returns how many files changed.
+func (ws *Workspace) rewriteProjectImports(project ProjectPath, rewrite func(content []byte) []byte) (int, error) {
+	files, err := ws.ListOwnedProjectFilesWithExtensions(project, []string{".proto"})
+	if err != nil {
+		return 0, fmt.Errorf("list files of %s: %w", project, err)
+	}
+
+	rewritten := 0
+	for _, f := range files {
+		content, err := os.ReadFile(f.AbsolutePath)
+		if err != nil {
+			return rewritten, fmt.Errorf("read %s: %w", f.AbsolutePath, err)
+		}
+		updated := rewrite(content)
+		if bytes.Equal(updated, content) {
+			continue
+		}
+		info, err := os.Stat(f.AbsolutePath)
+		if err != nil {
+			return rewritten, err
+		}
+		if err := os.WriteFile(f.AbsolutePath, updated, info.Mode().Perm()); err != nil {
+			return rewritten, fmt.Errorf("write %s: %w", f.AbsolutePath, err)
+		}
+		rewritten++
+	}
+	return rewritten, nil
+}
+
+// MoveProject moves an owned project to a new path in the same owned directory and updates
+// Config.Projects. Imports of the project's files in owned protos, written with the service
+// prefix, the owned directory or neither, are rewritten to the new path. It returns how many
+// files were rewritten. The registry is not touched: a pushed project stays at its old path
+// until the project is pushed again.
+func (ws *Workspace) MoveProject(from, to ProjectPath) (int, error) {
+	if err := utils.ValidateProjectPath(string(to)); err != nil {
+		return 0, fmt.Errorf("invalid project path %q: %w", to, err)
+	}
+	if ws.isIgnored(string(to)) {
+		return 0, fmt.Errorf("cannot move %s to %s: the new path is ignored", from, to)
+	}
+	projects, err := ws.OwnedProjects()
+	if err != nil {
+		return 0, fmt.Errorf("list owned projects: %w", err)
+	}
+	if !slices.Contains(projects, from) {
+		return 0, fmt.Errorf("project %s is not owned by this workspace", from)
+	}
+	paths := []string{string(to)}
+	for _, p := range projects {
+		paths = append(paths, string(p))
+	}
+	if err := utils.ProjectsOverlap(paths); err != nil {
+		return 0, fmt.Errorf("cannot move %s to %s: %w", from, to, err)
+	}
+
+	ownedDirs, err := ws.OwnedDirs()
+	if err != nil {
+		return 0, err
+	}
+	var fromDir, toDir string
+	for _, ownedDir := range ownedDirs {
+		if dir := projectPathJoin(ownedDir, from); !utils.DirNotExists(dir) {
+			fromDir, toDir = dir, projectPathJoin(ownedDir, to)
+			break
+		}
+	}
+	if fromDir == "" {
+		return 0, fmt.Errorf("project %s has no directory", from)
+	}
+	if _, err := os.Lstat(toDir); err == nil {
+		return 0, fmt.Errorf("cannot move %s to %s: %s already exists: %w", from, to, ws.relToRoot(toDir), fs.ErrExist)
+	}
+	if err := utils.CreateDir(filepath.Dir(toDir), "project"); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(fromDir, toDir); err != nil {
+		return 0, fmt.Errorf("move %s to %s: %w", from, to, err)
+	}
+
+	for i, p := range ws.config.Projects {
+		if p == string(from) {
+			ws.config.Projects[i] = string(to)
+		}
+	}
+	if !ws.claimsProject(to) {
+		ws.config.Projects = append(ws.config.Projects, string(to))
+	}
+	if err := writeConfig(ConfigPath(ws.root), ws.config); err != nil {
+		return 0, err
+	}
+
+	prefixes, err := ws.projectImportPrefixes()
+	if err != nil {
+		return 0, err
+	}
+	if projects, err = ws.OwnedProjects(); err != nil {
+		return 0, fmt.Errorf("list owned projects: %w", err)
+	}
+	rewritten := 0
+	for _, project := range projects {
+		n, err := ws.rewriteProjectImports(project, func(content []byte) []byte {
+			for _, prefix := range prefixes {
+				content = protoc.MoveImports(content, path.Join(prefix, string(from)), path.Join(prefix, string(to)))
+			}
+			return content
+		})
+		if err != nil {
+			return rewritten, err
+		}
+		rewritten += n
+	}
+	return rewritten, nil
+}
+
+// projectImportPrefixes returns what owned project paths can be prefixed with in imports: the
+// service, each owned directory and nothing.
+func (ws *Workspace) projectImportPrefixes() ([]string, error) {
+	ownedDirs, err := ws.config.OwnedDirs()
+	if err != nil {
+		return nil, err
+	}
+	var prefixes []string
+	if service := ws.ServiceName(); service != "" {
+		prefixes = append(prefixes, service)
+	}
+	for _, dir := range ownedDirs {
+		if dir != "" {
+			prefixes = append(prefixes, filepath.ToSlash(dir))
+		}
+	}
+	return append(prefixes, ""), nil
+}
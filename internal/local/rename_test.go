@@ -0,0 +1,288 @@
+package local
+
+import (
+	"context"
+	stderrors "errors"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/internal/errors"
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/registry"
+)
+
+// mockServiceMover is a registry holding projects by path, with the repository URL owning each.
+type mockServiceMover struct {
+	owners  map[registry.ProjectPath]string
+	renames []registry.RenameProjectRequest
+}
+
+func (m *mockServiceMover) ListProjects(_ context.Context, opts *registry.ListProjectsOptions) ([]registry.ProjectPath, error) {
+	var projects []registry.ProjectPath
+	for p := range m.owners {
+		if strings.HasPrefix(string(p), opts.Prefix+"/") {
+			projects = append(projects, p)
+		}
+	}
+	return projects, nil
+}
+
+func (m *mockServiceMover) LookupProject(_ context.Context, req *registry.LookupProjectRequest) (*registry.LookupProjectResponse, error) {
+	for p := req.Path; p != "."; p = path.Dir(p) {
+		if owner, ok := m.owners[registry.ProjectPath(p)]; ok {
+			return &registry.LookupProjectResponse{Project: &registry.Project{Path: registry.ProjectPath(p), RepositoryURL: owner}}, nil
+		}
+	}
+	return nil, &registry.ProjectNotFoundError{Path: req.Path}
+}
+
+func (m *mockServiceMover) RenameProject(_ context.Context, req *registry.RenameProjectRequest) (*registry.RenameProjectResponse, error) {
+	if _, ok := m.owners[req.From]; !ok {
+		return nil, &registry.ProjectNotFoundError{Path: string(req.From)}
+	}
+	m.renames = append(m.renames, *req)
+	return &registry.RenameProjectResponse{Snapshot: git.Hash("snapshot-" + string(req.To))}, nil
+}
+
+// setupRenameWorkspace creates a workspace for service old-svc owning team/service and team/common.
+func setupRenameWorkspace(t *testing.T) (string, *Workspace) {
+	t.Helper()
+	root, ws := setupTestWorkspaceWithConfig(t, &Config{
+		Service:  "old-svc",
+		Projects: []string{"team/service", "team/common"},
+		Directories: DirectoryConfig{
+			Owned:  "proto",
+			Vendor: "vendor-proto",
+		},
+	})
+	files := map[string]string{
+		"team/service/api.proto": strings.Join([]string{
+			`syntax = "proto3";`,
+			`package team.service.v1;`,
+			`import "old-svc/team/common/v1/types.proto";`,
+			`import public "old-svc/team/common/v1/enums.proto";`,
+			`import "team/common/v1/local.proto";`,
+			`import "other-svc/billing/v1/invoice.proto";`,
+			`import "google/protobuf/timestamp.proto";`,
+			"",
+		}, "\n"),
+		"team/common/types.proto": "syntax = \"proto3\";\npackage team.common.v1;\n",
+	}
+	for name, content := range files {
+		full := filepath.Join(root, "proto", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root, ws
+}
+
+func TestWorkspace_RenameService(t *testing.T) {
+	root, ws := setupRenameWorkspace(t)
+
+	res, err := ws.RenameService(context.Background(), "new-svc", nil)
+	if err != nil {
+		t.Fatalf("RenameService() error = %v", err)
+	}
+	if res.FilesRewritten != 1 || res.ProjectsMoved != 0 || res.Snapshot != "" {
+		t.Errorf("RenameService() = %+v, want 1 file rewritten and nothing moved", res)
+	}
+
+	reopened, err := Open(context.Background(), root)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got := reopened.ServiceName(); got != "new-svc" {
+		t.Errorf("ServiceName() = %q, want new-svc", got)
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "proto", "team", "service", "api.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		`import "new-svc/team/common/v1/types.proto";`,
+		`import public "new-svc/team/common/v1/enums.proto";`,
+		`import "team/common/v1/local.proto";`,
+		`import "other-svc/billing/v1/invoice.proto";`,
+		`import "google/protobuf/timestamp.proto";`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("api.proto missing %s, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "old-svc") {
+		t.Errorf("api.proto still imports old-svc, got:\n%s", got)
+	}
+}
+
+func TestWorkspace_RenameService_Registry(t *testing.T) {
+	const ours = "https://github.com/org/ours.git"
+
+	tests := []struct {
+		name        string
+		owners      map[registry.ProjectPath]string
+		wantErr     error
+		wantRenames []registry.ProjectPath // Destinations, in order
+	}{
+		{
+			name: "moves pushed projects",
+			owners: map[registry.ProjectPath]string{
+				"old-svc/team/service": ours,
+				"old-svc/team/common":  ours,
+			},
+			wantRenames: []registry.ProjectPath{"new-svc/team/service", "new-svc/team/common"},
+		},
+		{
+			name:        "skips projects never pushed",
+			owners:      map[registry.ProjectPath]string{"old-svc/team/service": ours},
+			wantRenames: []registry.ProjectPath{"new-svc/team/service"},
+		},
+		{
+			name: "refuses a namespace owned by another repository",
+			owners: map[registry.ProjectPath]string{
+				"old-svc/team/service": ours,
+				"new-svc/payments":     "https://github.com/org/theirs.git",
+			},
+			wantErr: errors.ErrOwnershipConflict,
+		},
+		{
+			name: "refuses a namespace under another repository's project",
+			owners: map[registry.ProjectPath]string{
+				"old-svc/team/service": ours,
+				"new-svc":              "https://github.com/org/theirs.git",
+			},
+			wantErr: errors.ErrOwnershipConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, ws := setupRenameWorkspace(t)
+			mover := &mockServiceMover{owners: tt.owners}
+
+			res, err := ws.RenameService(context.Background(), "new-svc", &RenameServiceOptions{
+				Registry:      mover,
+				RepositoryURL: ours,
+				Author:        &git.Author{Name: "Test", Email: "test@example.com"},
+			})
+			if tt.wantErr != nil {
+				if !stderrors.Is(err, tt.wantErr) {
+					t.Fatalf("RenameService() error = %v, want %v", err, tt.wantErr)
+				}
+				if len(mover.renames) != 0 {
+					t.Errorf("RenameService() moved %d projects before refusing", len(mover.renames))
+				}
+				if reopened, _ := Open(context.Background(), root); reopened.ServiceName() != "old-svc" {
+					t.Errorf("ServiceName() = %q after refusal, want old-svc", reopened.ServiceName())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenameService() error = %v", err)
+			}
+
+			var got []registry.ProjectPath
+			for _, r := range mover.renames {
+				got = append(got, r.To)
+			}
+			if !reflect.DeepEqual(got, tt.wantRenames) {
+				t.Errorf("RenameService() moved to %v, want %v", got, tt.wantRenames)
+			}
+			if res.ProjectsMoved != len(tt.wantRenames) || res.Snapshot != git.Hash("snapshot-"+string(tt.wantRenames[len(tt.wantRenames)-1])) {
+				t.Errorf("RenameService() = %+v", res)
+			}
+			// Each rename builds on the previous one
+			for i := 1; i < len(mover.renames); i++ {
+				if want := git.Hash("snapshot-" + string(mover.renames[i-1].To)); mover.renames[i].Snapshot != want {
+					t.Errorf("rename %d based on %s, want %s", i, mover.renames[i].Snapshot, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkspace_MoveProject(t *testing.T) {
+	root, ws := setupRenameWorkspace(t)
+
+	rewritten, err := ws.MoveProject("team/common", "team/shared")
+	if err != nil {
+		t.Fatalf("MoveProject() error = %v", err)
+	}
+	if rewritten != 1 {
+		t.Errorf("MoveProject() rewritten = %d, want 1", rewritten)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "proto", "team", "shared", "types.proto")); err != nil {
+		t.Errorf("MoveProject() did not move types.proto: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "proto", "team", "common")); !os.IsNotExist(err) {
+		t.Errorf("MoveProject() left the old directory: %v", err)
+	}
+
+	reopened, err := Open(context.Background(), root)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	projects, err := reopened.OwnedProjects()
+	if err != nil {
+		t.Fatalf("OwnedProjects() error = %v", err)
+	}
+	if want := []ProjectPath{"team/service", "team/shared"}; !reflect.DeepEqual(projects, want) {
+		t.Errorf("OwnedProjects() = %v, want %v", projects, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "proto", "team", "service", "api.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		`import "old-svc/team/shared/v1/types.proto";`,
+		`import public "old-svc/team/shared/v1/enums.proto";`,
+		`import "team/shared/v1/local.proto";`,
+		`import "other-svc/billing/v1/invoice.proto";`,
+		`import "google/protobuf/timestamp.proto";`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("api.proto missing %s, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "team/common") {
+		t.Errorf("api.proto still imports team/common, got:\n%s", got)
+	}
+}
+
+func TestWorkspace_MoveProject_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to ProjectPath
+	}{
+		{name: "not owned", from: "team/missing", to: "team/other"},
+		{name: "invalid path", from: "team/common", to: "../common"},
+		{name: "existing project", from: "team/common", to: "team/service"},
+		{name: "inside another project", from: "team/common", to: "team/service/common"},
+		{name: "inside itself", from: "team/common", to: "team/common/v2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, ws := setupRenameWorkspace(t)
+
+			if _, err := ws.MoveProject(tt.from, tt.to); err == nil {
+				t.Fatal("MoveProject() error = nil, want error")
+			}
+			if _, err := os.Stat(filepath.Join(root, "proto", "team", "common", "types.proto")); err != nil {
+				t.Errorf("MoveProject() moved files before failing: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package local
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+	"github.com/rahulagarwal0605/protato/internal/utils"
+)
+
+// Built-in project templates of ScaffoldProject.
+const (
+	TemplateService = "service" // A service with one RPC, its request and response, and a resource message
+	TemplateMessage = "message" // A single message
+)
+
+// scaffoldVersionDir is the versioned directory built-in templates create their file in.
+const scaffoldVersionDir = "v1"
+
+// builtinTemplates holds the starter proto of each built-in template, rendered with ScaffoldData.
+var builtinTemplates = map[string]string{
+	TemplateService: `syntax = "proto3";
+
+package {{.Package}};
+
+option go_package = "{{.GoPackage}}";
+
+// {{.Name}}Service serves {{.Name}} resources.
+service {{.Name}}Service {
+  // Get{{.Name}} returns a {{.Name}} by ID.
+  rpc Get{{.Name}}(Get{{.Name}}Request) returns (Get{{.Name}}Response);
+}
+
+message Get{{.Name}}Request {
+  string id = 1;
+}
+
+message Get{{.Name}}Response {
+  {{.Name}} {{.Field}} = 1;
+}
+
+// {{.Name}} is the resource served by {{.Name}}Service.
+message {{.Name}} {
+  string id = 1;
+}
+`,
+	TemplateMessage: `syntax = "proto3";
+
+package {{.Package}};
+
+option go_package = "{{.GoPackage}}";
+
+// {{.Name}} is the main message of {{.Project}}.
+message {{.Name}} {
+  string id = 1;
+}
+`,
+}
+
+// ScaffoldData is what project template files are rendered with.
+type ScaffoldData struct {
+	Project   string // Local project path (e.g., "team/payments")
+	Package   string // Proto package of the file's directory (e.g., "my_svc.team.payments.v1")
+	GoPackage string // Registry path of the file's directory (e.g., "my-svc/team/payments/v1")
+	Name      string // PascalCase name from the last project path element (e.g., "Payments")
+	Field     string // lower_snake_case name from the last project path element (e.g., "payments")
+}
+
+// ScaffoldProject creates the starter files of a template inside an owned project and returns
+// their paths relative to the project, sorted. from is a built-in template (TemplateService or
+// TemplateMessage), which creates v1/<name>.proto, or a local directory whose .proto files are
+// copied into the project, keeping their layout, as text/templates rendered with ScaffoldData.
+// Files go into the owned directory holding the project. Nothing is written if any file exists.
+func (ws *Workspace) ScaffoldProject(project ProjectPath, from string) ([]string, error) {
+	templates, err := loadProjectTemplate(project, from)
+	if err != nil {
+		return nil, err
+	}
+	registryPath, err := ws.RegistryProjectPath(project)
+	if err != nil {
+		return nil, err
+	}
+
+	relPaths := utils.SortedKeys(templates)
+	filePaths := make([]string, len(relPaths))
+	for i, relPath := range relPaths {
+		filePath, err := ws.ownedProtoFilePath(project, relPath)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Lstat(filePath); err == nil {
+			return nil, fmt.Errorf("scaffold %s: %s already exists: %w", project, relPath, fs.ErrExist)
+		}
+		filePaths[i] = filePath
+	}
+
+	for i, relPath := range relPaths {
+		goPackage := path.Join(string(registryPath), path.Dir(relPath))
+		content, err := renderProjectTemplate(relPath, templates[relPath], newScaffoldData(project, goPackage))
+		if err != nil {
+			return nil, err
+		}
+		if err := createProtoFile(filePaths[i], content); err != nil {
+			return nil, fmt.Errorf("scaffold %s: %w", project, err)
+		}
+	}
+	return relPaths, nil
+}
+
+// loadProjectTemplate returns the template text of each file a template creates, by path
+// relative to the project.
+func loadProjectTemplate(project ProjectPath, from string) (map[string]string, error) {
+	if text, ok := builtinTemplates[from]; ok {
+		name := path.Base(string(project)) + constants.ProtoFileExt
+		return map[string]string{path.Join(scaffoldVersionDir, name): text}, nil
+	}
+	if utils.DirNotExists(from) {
+		return nil, fmt.Errorf("unknown template %q: not %s, %s or a directory", from, TemplateService, TemplateMessage)
+	}
+
+	templates := make(map[string]string)
+	err := filepath.WalkDir(from, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(p, constants.ProtoFileExt) {
+			return nil
+		}
+		rel, err := filepath.Rel(from, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		templates[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read template %s: %w", from, err)
+	}
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("template %s has no .proto files", from)
+	}
+	return templates, nil
+}
+
+// renderProjectTemplate renders the template text of a file. Unknown fields are errors.
+func renderProjectTemplate(relPath, text string, data ScaffoldData) (string, error) {
+	tmpl, err := template.New(relPath).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parse template %s: %w", relPath, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("render template %s: %w", relPath, err)
+	}
+	return b.String(), nil
+}
+
+// newScaffoldData returns the template data of a file of project in the registry directory goPackage.
+func newScaffoldData(project ProjectPath, goPackage string) ScaffoldData {
+	words := strings.FieldsFunc(path.Base(string(project)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var name strings.Builder
+	for _, word := range words {
+		runes := []rune(strings.ToLower(word))
+		runes[0] = unicode.ToUpper(runes[0])
+		name.WriteString(string(runes))
+	}
+	return ScaffoldData{
+		Project:   string(project),
+		Package:   protoPackageFromPath(goPackage),
+		GoPackage: goPackage,
+		Name:      name.String(),
+		Field:     strings.ToLower(strings.Join(words, "_")),
+	}
+}
@@ -0,0 +1,159 @@
+package local
+
+import (
+	stderrors "errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// scaffoldTestConfig is the workspace configuration of the scaffold tests.
+var scaffoldTestConfig = &Config{
+	Service:  "test-service",
+	Projects: []string{"team/*"},
+	Directories: DirectoryConfig{
+		Owned:  "protos",
+		Vendor: "vendor-proto",
+	},
+}
+
+func TestWorkspace_ScaffoldProject_Builtin(t *testing.T) {
+	tests := []struct {
+		name        string
+		project     ProjectPath
+		from        string
+		wantFile    string
+		wantPackage string
+		wantLines   []string
+	}{
+		{
+			name:        "service template",
+			project:     "team/service",
+			from:        TemplateService,
+			wantFile:    "v1/service.proto",
+			wantPackage: "package test_service.team.service.v1;",
+			wantLines: []string{
+				`option go_package = "test-service/team/service/v1";`,
+				"service ServiceService {",
+				"  rpc GetService(GetServiceRequest) returns (GetServiceResponse);",
+			},
+		},
+		{
+			name:        "message template with dashed project",
+			project:     "team/payment-methods",
+			from:        TemplateMessage,
+			wantFile:    "v1/payment-methods.proto",
+			wantPackage: "package test_service.team.payment_methods.v1;",
+			wantLines:   []string{"message PaymentMethods {"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, ws := setupTestWorkspaceWithConfig(t, scaffoldTestConfig)
+
+			files, err := ws.ScaffoldProject(tt.project, tt.from)
+			if err != nil {
+				t.Fatalf("ScaffoldProject() error = %v", err)
+			}
+			if !reflect.DeepEqual(files, []string{tt.wantFile}) {
+				t.Errorf("ScaffoldProject() files = %v, want [%s]", files, tt.wantFile)
+			}
+
+			content, err := os.ReadFile(filepath.Join(tmpDir, "protos", string(tt.project), tt.wantFile))
+			if err != nil {
+				t.Fatalf("Failed to read scaffolded file: %v", err)
+			}
+			for _, line := range append([]string{tt.wantPackage}, tt.wantLines...) {
+				if !strings.Contains(string(content), line+"\n") {
+					t.Errorf("ScaffoldProject() content = %q, want line %q", content, line)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkspace_ScaffoldProject_Directory(t *testing.T) {
+	tmpDir, ws := setupTestWorkspaceWithConfig(t, scaffoldTestConfig)
+	templateDir := filepath.Join(tmpDir, "templates")
+	createTestProject(t, tmpDir, "templates", map[string]string{
+		"v2/api.proto":   "syntax = \"proto3\";\n\npackage {{.Package}};\n\nmessage {{.Name}} {}\n",
+		"v2/types.proto": "syntax = \"proto3\";\n\npackage {{.Package}};\n",
+		"README.md":      "not copied",
+	})
+
+	files, err := ws.ScaffoldProject("team/billing", templateDir)
+	if err != nil {
+		t.Fatalf("ScaffoldProject() error = %v", err)
+	}
+	if want := []string{"v2/api.proto", "v2/types.proto"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("ScaffoldProject() files = %v, want %v", files, want)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "protos/team/billing/v2/api.proto"))
+	if err != nil {
+		t.Fatalf("Failed to read scaffolded file: %v", err)
+	}
+	want := "syntax = \"proto3\";\n\npackage test_service.team.billing.v2;\n\nmessage Billing {}\n"
+	if string(content) != want {
+		t.Errorf("ScaffoldProject() content = %q, want %q", content, want)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "protos/team/billing/README.md")); !os.IsNotExist(err) {
+		t.Errorf("ScaffoldProject() copied a non-proto file: %v", err)
+	}
+}
+
+func TestWorkspace_ScaffoldProject_RefusesOverwrite(t *testing.T) {
+	tmpDir, ws := setupTestWorkspaceWithConfig(t, scaffoldTestConfig)
+	createTestProject(t, tmpDir, "protos/team/service/v1", map[string]string{
+		"service.proto": "// hand written",
+	})
+
+	_, err := ws.ScaffoldProject("team/service", TemplateService)
+	if !stderrors.Is(err, fs.ErrExist) {
+		t.Fatalf("ScaffoldProject() error = %v, want fs.ErrExist", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "protos/team/service/v1/service.proto"))
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != "// hand written" {
+		t.Errorf("ScaffoldProject() overwrote existing file: %q", content)
+	}
+}
+
+func TestWorkspace_ScaffoldProject_Errors(t *testing.T) {
+	tmpDir, ws := setupTestWorkspaceWithConfig(t, scaffoldTestConfig)
+	createTestProject(t, tmpDir, "bad-template", map[string]string{
+		"api.proto": "package {{.Unknown}};\n",
+	})
+	createTestProject(t, tmpDir, "empty-template", map[string]string{
+		"README.md": "no protos",
+	})
+
+	tests := []struct {
+		name    string
+		project ProjectPath
+		from    string
+	}{
+		{name: "unknown template", project: "team/service", from: "grpc"},
+		{name: "unknown template field", project: "team/service", from: filepath.Join(tmpDir, "bad-template")},
+		{name: "template without protos", project: "team/service", from: filepath.Join(tmpDir, "empty-template")},
+		{name: "project not owned", project: "other/service", from: TemplateService},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ws.ScaffoldProject(tt.project, tt.from); err == nil {
+				t.Error("ScaffoldProject() error = nil, want error")
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "protos/team/service")); !os.IsNotExist(err) {
+		t.Errorf("ScaffoldProject() left files after failing: %v", err)
+	}
+}
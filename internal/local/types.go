@@ -2,11 +2,21 @@
 package local
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"hash"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
 
+	"gopkg.in/yaml.v3"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
 	"github.com/rahulagarwal0605/protato/internal/errors"
 	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/registry"
 )
 
 // ProjectPath represents a project path in the registry.
@@ -18,20 +28,99 @@ func (p ProjectPath) String() string {
 }
 
 // DirectoryConfig specifies directory paths for owned and vendor protos.
+// In YAML, owned is either one directory or a list of them.
 type DirectoryConfig struct {
-	Owned  string `yaml:"owned,omitempty"`  // Directory for owned protos (default: "proto")
-	Vendor string `yaml:"vendor,omitempty"` // Directory for consumed protos (default: "vendor-proto")
+	Owned     string   `yaml:"-"` // Directory for owned protos (default: "proto")
+	OwnedDirs []string `yaml:"-"` // Several directories for owned protos; takes precedence over Owned
+	Vendor    string   `yaml:"-"` // Directory for consumed protos (default: "vendor-proto")
+}
+
+// directoryConfigYAML is the YAML form of DirectoryConfig.
+type directoryConfigYAML struct {
+	Owned  any    `yaml:"owned,omitempty"` // string, or []string for several owned directories
+	Vendor string `yaml:"vendor,omitempty"`
+}
+
+// MarshalYAML writes owned as a scalar for one directory and as a list for several.
+func (d DirectoryConfig) MarshalYAML() (any, error) {
+	out := directoryConfigYAML{Vendor: d.Vendor}
+	if len(d.OwnedDirs) > 0 {
+		out.Owned = d.OwnedDirs
+	} else if d.Owned != "" {
+		out.Owned = d.Owned
+	}
+	return out, nil
+}
+
+// UnmarshalYAML reads owned as either a single directory or a list of directories.
+func (d *DirectoryConfig) UnmarshalYAML(value *yaml.Node) error {
+	var in struct {
+		Owned  yaml.Node `yaml:"owned"`
+		Vendor string    `yaml:"vendor"`
+	}
+	if err := value.Decode(&in); err != nil {
+		return err
+	}
+	*d = DirectoryConfig{Vendor: in.Vendor}
+	switch in.Owned.Kind {
+	case 0:
+		return nil
+	case yaml.ScalarNode:
+		return in.Owned.Decode(&d.Owned)
+	case yaml.SequenceNode:
+		return in.Owned.Decode(&d.OwnedDirs)
+	default:
+		return fmt.Errorf("line %d: owned must be a directory or a list of directories", in.Owned.Line)
+	}
 }
 
 // Config represents the protato.yaml configuration.
 type Config struct {
-	Service      string          `yaml:"service,omitempty"`       // Service name for registry namespacing
-	Directories  DirectoryConfig `yaml:"directories,omitempty"`   // Directory configuration
-	AutoDiscover bool            `yaml:"auto_discover,omitempty"` // Auto-discover projects from owned directory
-	Projects     []string        `yaml:"projects,omitempty"`      // Project patterns (glob) - when auto_discover=false: find projects matching these patterns within owned directory
-	Ignores      []string        `yaml:"ignores,omitempty"`       // Ignore patterns (glob) - ignore projects/files matching these patterns within owned directory
+	Service         string          `yaml:"service,omitempty"`          // Service name for registry namespacing
+	Directories     DirectoryConfig `yaml:"directories,omitempty"`      // Directory configuration
+	AutoDiscover    bool            `yaml:"auto_discover,omitempty"`    // Auto-discover projects from owned directory
+	Projects        []string        `yaml:"projects,omitempty"`         // Project patterns (glob) - when auto_discover=false: find projects matching these patterns within owned directory
+	Ignores         []string        `yaml:"ignores,omitempty"`          // Ignore patterns (glob) - ignore projects/files matching these patterns within owned directory
+	GoogleImports   []string        `yaml:"google_imports,omitempty"`   // Allowed google/* import patterns (glob) - defaults to google/protobuf/** when empty
+	MaxFileSize     int64           `yaml:"max_file_size,omitempty"`    // Maximum size in bytes of a pushed file - defaults to DefaultMaxFileSize when unset
+	Vendor          VendorConfig    `yaml:"vendor,omitempty"`           // Settings for received projects
+	RequireApproval []string        `yaml:"require_approval,omitempty"` // Project patterns (glob) whose pushes need a recorded approval
+	VendorGitignore string          `yaml:"vendor_gitignore,omitempty"` // "ignore" or "track" the vendor dir in the root .gitignore - unset leaves .gitignore alone
+	Lint            LintConfig      `yaml:"lint,omitempty"`             // Lint rules checked by lint and verify --lint
+	MetadataFiles   []string        `yaml:"metadata_files,omitempty"`   // Extra file names every file walker skips as metadata (e.g., OWNERS) - protato's own are always skipped
 }
 
+// Values of vendor_gitignore.
+const (
+	VendorGitignoreIgnore = "ignore" // Keep the vendor dir in .gitignore
+	VendorGitignoreTrack  = "track"  // Keep the vendor dir out of .gitignore
+)
+
+// VendorConfig holds settings for received projects.
+type VendorConfig struct {
+	Gitattributes *string `yaml:"gitattributes,omitempty"` // Content of each received project's .gitattributes - defaults to DefaultGitattributes, empty disables it
+	Layout        string  `yaml:"layout,omitempty"`        // Where received projects go in the vendor dir - defaults to VendorLayoutNamespaced
+}
+
+// Values of vendor.layout, for a received project "payments-svc/team/api".
+const (
+	VendorLayoutNamespaced   = "namespaced"    // <vendor>/payments-svc/team/api, as imported
+	VendorLayoutFlat         = "flat"          // <vendor>/team/api, without the producing service
+	VendorLayoutRegistryPath = "registry-path" // <vendor>/protos/payments-svc/team/api, as in the registry repository
+)
+
+// LintConfig selects the lint rules of the workspace.
+type LintConfig struct {
+	Rules   []string `yaml:"rules,omitempty"`   // Only run these rules - defaults to all rules when empty
+	Disable []string `yaml:"disable,omitempty"` // Rules to skip
+}
+
+// DefaultGitattributes marks received files as generated so code hosts collapse them in diffs.
+const DefaultGitattributes = "* linguist-generated=true\n"
+
+// DefaultMaxFileSize is the push size limit used when max_file_size is not configured.
+const DefaultMaxFileSize int64 = 1 << 20
+
 // DefaultDirectoryConfig returns the default directory configuration.
 func DefaultDirectoryConfig() DirectoryConfig {
 	return DirectoryConfig{
@@ -40,17 +129,59 @@ func DefaultDirectoryConfig() DirectoryConfig {
 	}
 }
 
-// OwnedDir returns the owned directory.
+// OwnedDir returns the first owned directory.
 // If the configured directory is ".", returns "" (empty string) to represent root.
 func (c *Config) OwnedDir() (string, error) {
-	if c.Directories.Owned == "" {
-		return "", errors.ErrOwnedDirNotSet
+	dirs, err := c.OwnedDirs()
+	if err != nil {
+		return "", err
 	}
-	// Treat "." as root directory (empty string)
-	if c.Directories.Owned == "." {
-		return "", nil
+	return dirs[0], nil
+}
+
+// OwnedDirs returns the owned directories in configured order.
+// A directory configured as "." is returned as "" (empty string) to represent root.
+func (c *Config) OwnedDirs() ([]string, error) {
+	configured := c.Directories.OwnedDirs
+	if len(configured) == 0 {
+		configured = []string{c.Directories.Owned}
+	}
+
+	dirs := make([]string, len(configured))
+	for i, dir := range configured {
+		if dir == "" {
+			return nil, errors.ErrOwnedDirNotSet
+		}
+		// Treat "." as root directory (empty string)
+		if dir == "." {
+			dir = ""
+		}
+		dirs[i] = dir
+	}
+	return dirs, nil
+}
+
+// validateOwnedDirs returns an error if an owned directory is, or is inside, another one.
+func (c *Config) validateOwnedDirs() error {
+	dirs, err := c.OwnedDirs()
+	if err != nil {
+		return err
+	}
+	for i, a := range dirs {
+		for _, b := range dirs[i+1:] {
+			if ownedDirsOverlap(a, b) {
+				return fmt.Errorf("%w: %q and %q", errors.ErrOwnedDirsOverlap, a, b)
+			}
+		}
 	}
-	return c.Directories.Owned, nil
+	return nil
+}
+
+// ownedDirsOverlap reports whether two owned directories are the same or one contains the other.
+// The root directory ("") contains every other directory.
+func ownedDirsOverlap(a, b string) bool {
+	a, b = path.Clean("/"+filepath.ToSlash(a)), path.Clean("/"+filepath.ToSlash(b))
+	return a == "/" || b == "/" || a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
 }
 
 // VendorDir returns the vendor directory.
@@ -67,8 +198,27 @@ func (c *Config) VendorDir() (string, error) {
 }
 
 // LockFile represents the protato.lock file.
+// A project with a branch tracks that registry branch and is updated to its tip;
+// without one it is pinned to the snapshot commit.
 type LockFile struct {
-	Snapshot string `yaml:"snapshot"`
+	Snapshot       string            `yaml:"snapshot"`
+	Branch         string            `yaml:"branch,omitempty"`
+	Ref            string            `yaml:"ref,omitempty"`             // Registry tag or branch the project was pinned to
+	ResolvedCommit string            `yaml:"resolved_commit,omitempty"` // Commit Ref pointed to when the project was received
+	Files          map[string]string `yaml:"files,omitempty"`           // Relative path to hex SHA-256 of the received content
+	Project        string            `yaml:"project,omitempty"`         // Registry project path, recorded when the vendor layout does not show it
+}
+
+// Manifest represents the received.manifest.yaml file of a received project.
+type Manifest struct {
+	Files []ManifestEntry `yaml:"files"`
+}
+
+// ManifestEntry records a single received file.
+type ManifestEntry struct {
+	Path string `yaml:"path"` // Relative to project root
+	Hash string `yaml:"hash"` // Git blob hash of the file content
+	Mode string `yaml:"mode"` // Git file mode in octal (e.g., "100644")
 }
 
 // ProjectFile represents a file in a project.
@@ -81,12 +231,32 @@ type ProjectFile struct {
 type ReceivedProject struct {
 	Project          ProjectPath
 	ProviderSnapshot string // Registry Git commit hash
+	Branch           string // Tracked registry branch; empty if pinned to the snapshot
+	Ref              string // Registry tag or branch the project was pinned to, if any
+	ResolvedCommit   string // Commit Ref pointed to when received; differs from Ref's current commit once it moves
+}
+
+// IsLocal reports whether the project was received from a local directory
+// rather than the registry (its lock holds a synthetic snapshot).
+func (p *ReceivedProject) IsLocal() bool {
+	return strings.HasPrefix(p.ProviderSnapshot, constants.LocalSnapshotPrefix)
+}
+
+// ProjectLookup finds projects in the registry; registry.CacheInterface satisfies it.
+type ProjectLookup interface {
+	EnsureSnapshotAvailable(context.Context, git.Hash) error
+	BranchSnapshot(context.Context, string) (git.Hash, error)
+	LookupProject(context.Context, *registry.LookupProjectRequest) (*registry.LookupProjectResponse, error)
 }
 
 // ReceiveProjectRequest contains parameters for receiving a project.
 type ReceiveProjectRequest struct {
-	Project  ProjectPath // Project to receive
-	Snapshot git.Hash    // Registry snapshot
+	Project         ProjectPath // Project to receive
+	Snapshot        git.Hash    // Registry snapshot
+	Branch          string      // Registry branch to track; empty pins the snapshot
+	Ref             string      // Registry tag or branch Snapshot was resolved from (e.g., "v1.2.0"); recorded in the lock file
+	NoGitattributes bool        // Don't write .gitattributes regardless of config
+	OutputDir       string      // Write into this directory instead of the vendor dir, without lock file, manifest or .gitattributes
 }
 
 // ReceiveStats contains statistics about a receive operation.
@@ -97,12 +267,21 @@ type ReceiveStats struct {
 
 // ProjectReceiver handles receiving files for a project.
 type ProjectReceiver struct {
-	ws          WorkspaceInterface
-	project     ProjectPath
-	projectRoot string
-	snapshot    git.Hash
-	changed     int
-	deleted     int
+	ws            WorkspaceInterface
+	project       ProjectPath
+	projectRoot   string
+	stagingDir    string // Files are written here and moved into projectRoot by Finish
+	snapshot      git.Hash
+	branch        string
+	ref           string
+	gitattributes string // Content written to .gitattributes; empty skips the file
+	ephemeral     bool   // Only write the files; the project is not tracked by the workspace
+	recordProject bool   // Record the project in the lock file, for vendor layouts that do not show it
+	changed       int
+	deleted       int
+	toDelete      []string // Relative paths removed from projectRoot by Finish
+	manifest      []ManifestEntry
+	checksums     map[string]string // Relative path to hex SHA-256 of each written file
 }
 
 // ProjectFileWriter handles writing a project file.
@@ -110,5 +289,6 @@ type ProjectFileWriter struct {
 	file         *os.File
 	hash         hash.Hash
 	existingHash []byte
-	onClose      func(changed bool)
+	content      bytes.Buffer
+	onClose      func(changed bool, content, sum []byte)
 }
@@ -3,16 +3,23 @@ package local
 import (
 	"context"
 	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/rahulagarwal0605/protato/internal/constants"
 	"github.com/rahulagarwal0605/protato/internal/errors"
+	"github.com/rahulagarwal0605/protato/internal/git"
 	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/internal/protoc"
+	"github.com/rahulagarwal0605/protato/internal/registry"
 	"github.com/rahulagarwal0605/protato/internal/utils"
 )
 
@@ -20,62 +27,97 @@ import (
 type WorkspaceInterface interface {
 	Root() string
 	OwnedDir() (string, error)
+	OwnedDirs() ([]string, error)
 	OwnedDirName() (string, error)
+	OwnedDirFor(project ProjectPath) (string, error)
+	OwnedRoots() ([]protoc.OwnedRoot, error)
 	VendorDir() (string, error)
+	VendorRoots(ctx context.Context) ([]protoc.VendorRoot, error)
 	ServiceName() string
+	GoogleImports() []string
+	MaxFileSize() int64
+	LintConfig() LintConfig
+	RequiresApproval(project ProjectPath) bool
 	RegistryProjectPath(localProject ProjectPath) (ProjectPath, error)
 	LocalProjectPath(registryProject ProjectPath) ProjectPath
 	OwnedProjects() ([]ProjectPath, error)
 	ReceivedProjects(ctx context.Context) ([]*ReceivedProject, error)
 	AddOwnedProjects(projects []string) error
+	RemoveOwnedProject(project ProjectPath, deleteFiles bool) error
 	ReceiveProject(req *ReceiveProjectRequest) (*ProjectReceiver, error)
+	RemoveReceivedProject(project ProjectPath) error
 	ListOwnedProjectFiles(project ProjectPath) ([]ProjectFile, error)
+	ListOwnedProjectFilesWithExtensions(project ProjectPath, exts []string) ([]ProjectFile, error)
 	ListVendorProjectFiles(project ProjectPath) ([]ProjectFile, error)
+	ListDirFiles(dir string) ([]ProjectFile, error)
 	IsProjectOwned(project ProjectPath) bool
+	AddProtoFile(project ProjectPath, relPath string, pkg string) error
+	RemoveProtoFile(project ProjectPath, relPath string) error
+	ScaffoldProject(project ProjectPath, from string) ([]string, error)
 	GetProjectLock(project ProjectPath) (*LockFile, error)
+	IsReceivedProjectStale(ctx context.Context, project ProjectPath, cache ProjectLookup) (bool, git.Hash, error)
+	GetProjectManifest(project ProjectPath) (*Manifest, error)
+	SetProjectLock(project ProjectPath, lock *LockFile) error
+	VerifyVendorIntegrity(project ProjectPath) ([]string, error)
+	CompareVendorFiles(project ProjectPath, want []ManifestEntry) ([]string, error)
 	OrphanedFiles(ctx context.Context) ([]string, error)
+	CleanVendor() error
+	UpdateBufConfig() (bool, error)
+	UpdateGitignore() (bool, error)
 	GetRegistryPath(projectPath string) (ProjectPath, error)
 	GetRegistryPathForProject(project ProjectPath) (ProjectPath, error)
 }
 
 // Workspace represents a local protato workspace.
 type Workspace struct {
-	root   string  // Repository root directory
-	config *Config // Loaded configuration
+	root    string       // Repository root directory
+	config  *Config      // Loaded configuration
+	ignores []ignoreRule // Config.Ignores followed by the .protatoignore rules
 }
 
 // Init initializes a new workspace.
 func Init(ctx context.Context, root string, config *Config, force bool) (*Workspace, error) {
 	configPath := ConfigPath(root)
 
-	// Write config file
-	if err := writeConfig(configPath, config); err != nil {
-		return nil, fmt.Errorf("write config: %w", err)
-	}
-
-	// Create directories
-	ownedDir, err := config.OwnedDir()
+	ownedDirs, err := config.OwnedDirs()
 	if err != nil {
 		return nil, fmt.Errorf("get owned directory: %w", err)
 	}
+	if err := config.validateOwnedDirs(); err != nil {
+		return nil, err
+	}
 	vendorDir, err := config.VendorDir()
 	if err != nil {
 		return nil, fmt.Errorf("get vendor directory: %w", err)
 	}
-	if err := utils.CreateDir(filepath.Join(root, ownedDir), "owned protos"); err != nil {
-		return nil, err
+
+	// Write config file
+	if err := writeConfig(configPath, config); err != nil {
+		return nil, fmt.Errorf("write config: %w", err)
+	}
+
+	// Create directories
+	for _, ownedDir := range ownedDirs {
+		if err := utils.CreateDir(filepath.Join(root, ownedDir), "owned protos"); err != nil {
+			return nil, err
+		}
 	}
 	if err := utils.CreateDir(filepath.Join(root, vendorDir), "vendor protos"); err != nil {
 		return nil, err
 	}
 
+	ignores, err := loadIgnores(root, config)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Workspace{
-		root:   root,
-		config: config,
+		root:    root,
+		config:  config,
+		ignores: ignores,
 	}, nil
 }
 
-
 // Open opens an existing workspace.
 func Open(ctx context.Context, root string) (*Workspace, error) {
 	configPath := ConfigPath(root)
@@ -91,9 +133,15 @@ func Open(ctx context.Context, root string) (*Workspace, error) {
 		return nil, fmt.Errorf("read config: %w", err)
 	}
 
+	ignores, err := loadIgnores(root, config)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Workspace{
-		root:   root,
-		config: config,
+		root:    root,
+		config:  config,
+		ignores: ignores,
 	}, nil
 }
 
@@ -127,11 +175,88 @@ func ConfigPath(root string) string {
 }
 
 // OwnedDir returns the absolute directory path for owned (producer) protos.
+// With several owned directories it is the first one, which new projects are created in
+// and which owned imports are resolved against.
 func (ws *Workspace) OwnedDir() (string, error) {
 	return ws.getDirPath(ws.config.OwnedDir, "owned")
 }
 
-// OwnedDirName returns just the owned directory name (e.g., "proto") without the root path.
+// OwnedDirs returns the absolute paths of every owned directory, in configured order.
+func (ws *Workspace) OwnedDirs() ([]string, error) {
+	dirs, err := ws.config.OwnedDirs()
+	if err != nil {
+		return nil, fmt.Errorf("get owned directory: %w", err)
+	}
+	paths := make([]string, len(dirs))
+	for i, dir := range dirs {
+		paths[i] = filepath.Join(ws.root, dir)
+	}
+	return paths, nil
+}
+
+// ownedProjectDir returns the absolute directory of an owned project: the first owned
+// directory that contains it, or its place in the first owned directory if none does.
+func (ws *Workspace) ownedProjectDir(project ProjectPath) (string, error) {
+	ownedDirs, err := ws.OwnedDirs()
+	if err != nil {
+		return "", err
+	}
+	return projectPathJoin(ownedDirs[ownedDirIndex(ownedDirs, project)], project), nil
+}
+
+// ownedDirIndex returns the index of the first of the absolute ownedDirs that contains
+// project, or 0 if none does.
+func ownedDirIndex(ownedDirs []string, project ProjectPath) int {
+	for i, ownedDir := range ownedDirs {
+		if !utils.DirNotExists(projectPathJoin(ownedDir, project)) {
+			return i
+		}
+	}
+	return 0
+}
+
+// OwnedDirFor returns the name of the owned directory an owned project is in (e.g., "proto"),
+// which its owned imports start with. A project not created yet goes in the first one.
+func (ws *Workspace) OwnedDirFor(project ProjectPath) (string, error) {
+	names, err := ws.config.OwnedDirs()
+	if err != nil {
+		return "", err
+	}
+	ownedDirs, err := ws.OwnedDirs()
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(names[ownedDirIndex(ownedDirs, project)]), nil
+}
+
+// OwnedRoots returns every owned directory with the owned projects in it, for resolving
+// owned imports.
+func (ws *Workspace) OwnedRoots() ([]protoc.OwnedRoot, error) {
+	names, err := ws.config.OwnedDirs()
+	if err != nil {
+		return nil, fmt.Errorf("get owned directory: %w", err)
+	}
+	ownedDirs, err := ws.OwnedDirs()
+	if err != nil {
+		return nil, err
+	}
+	projects, err := ws.OwnedProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	roots := make([]protoc.OwnedRoot, len(ownedDirs))
+	for i, dir := range ownedDirs {
+		roots[i] = protoc.OwnedRoot{Dir: dir, ImportPrefix: filepath.ToSlash(names[i])}
+	}
+	for _, project := range projects {
+		i := ownedDirIndex(ownedDirs, project)
+		roots[i].Projects = append(roots[i].Projects, string(project))
+	}
+	return roots, nil
+}
+
+// OwnedDirName returns just the first owned directory name (e.g., "proto") without the root path.
 func (ws *Workspace) OwnedDirName() (string, error) {
 	return ws.config.OwnedDir()
 }
@@ -149,6 +274,48 @@ func (ws *Workspace) ServiceName() string {
 	return ""
 }
 
+// Gitattributes returns the .gitattributes content for received projects.
+// Returns DefaultGitattributes when unset and "" when disabled in config.
+func (ws *Workspace) Gitattributes() string {
+	if ws.config == nil || ws.config.Vendor.Gitattributes == nil {
+		return DefaultGitattributes
+	}
+	content := *ws.config.Vendor.Gitattributes
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content
+}
+
+// GoogleImports returns the configured allowlist of google/* import patterns.
+func (ws *Workspace) GoogleImports() []string {
+	if ws.config != nil {
+		return ws.config.GoogleImports
+	}
+	return nil
+}
+
+// MaxFileSize returns the configured push size limit in bytes.
+func (ws *Workspace) MaxFileSize() int64 {
+	if ws.config != nil && ws.config.MaxFileSize > 0 {
+		return ws.config.MaxFileSize
+	}
+	return DefaultMaxFileSize
+}
+
+// LintConfig returns the configured lint rule selection.
+func (ws *Workspace) LintConfig() LintConfig {
+	if ws.config != nil {
+		return ws.config.Lint
+	}
+	return LintConfig{}
+}
+
+// RequiresApproval reports whether an owned project matches a require_approval pattern.
+func (ws *Workspace) RequiresApproval(project ProjectPath) bool {
+	return ws.config != nil && ws.matchesPattern(string(project), ws.config.RequireApproval)
+}
+
 // RegistryProjectPath returns the full registry path for a local project.
 // It prefixes the project path with the service name.
 func (ws *Workspace) RegistryProjectPath(localProject ProjectPath) (ProjectPath, error) {
@@ -204,24 +371,45 @@ func (ws *Workspace) discoverProjects() ([]ProjectPath, error) {
 	return ws.scanProjects(nil)
 }
 
-// scanProjects scans the owned directory and finds projects.
+// scanProjects scans the owned directories and finds projects.
 // filterPattern: optional glob pattern to filter projects (nil = return all projects)
 // Always filters out pulled projects (projects with protato.lock)
-// Returns paths relative to the owned directory.
+// Returns paths relative to the owned directory each project is in; a project
+// found in more than one owned directory is an error.
 func (ws *Workspace) scanProjects(filterPattern *string) ([]ProjectPath, error) {
-	ownedPath, err := ws.OwnedDir()
+	ownedDirs, err := ws.OwnedDirs()
 	if err != nil {
 		return nil, err
 	}
 
+	projects := []ProjectPath{}
+	foundIn := make(map[ProjectPath]string)
+	for _, ownedPath := range ownedDirs {
+		found, err := ws.scanOwnedDir(ownedPath, filterPattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range found {
+			if other, ok := foundIn[project]; ok {
+				return nil, fmt.Errorf("%w: %s is in %s and %s", errors.ErrDuplicateOwnedProject, project, ws.relToRoot(other), ws.relToRoot(ownedPath))
+			}
+			foundIn[project] = ownedPath
+		}
+		projects = append(projects, found...)
+	}
+	return projects, nil
+}
+
+// scanOwnedDir finds the projects in one owned directory, relative to it.
+func (ws *Workspace) scanOwnedDir(ownedPath string, filterPattern *string) ([]ProjectPath, error) {
 	if utils.DirNotExists(ownedPath) {
-		return []ProjectPath{}, nil
+		return nil, nil
 	}
 
 	var projects []ProjectPath
 	seen := make(map[string]bool)
 
-	err = filepath.WalkDir(ownedPath, func(p string, d fs.DirEntry, err error) error {
+	err := filepath.WalkDir(ownedPath, func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -236,9 +424,17 @@ func (ws *Workspace) scanProjects(filterPattern *string) ([]ProjectPath, error)
 	return projects, err
 }
 
+// relToRoot returns a path relative to the workspace root for messages, or the path itself if it is outside.
+func (ws *Workspace) relToRoot(p string) string {
+	if rel, err := filepath.Rel(ws.root, p); err == nil {
+		return rel
+	}
+	return p
+}
+
 // processProtoFile processes a proto file entry and returns the project path if valid.
 func (ws *Workspace) processProtoFile(p string, d fs.DirEntry, ownedPath string, filterPattern *string, seen map[string]bool) string {
-	if d.IsDir() || !strings.HasSuffix(d.Name(), constants.ProtoFileExt) {
+	if d.IsDir() || !ws.specialFiles().IsProjectFile(d.Name()) {
 		return ""
 	}
 
@@ -257,7 +453,7 @@ func (ws *Workspace) processProtoFile(p string, d fs.DirEntry, ownedPath string,
 	}
 	seen[relToOwned] = true
 
-	isPulled, err := ws.isPulledProject(relToOwned)
+	isPulled, err := ws.isPulledProject(ownedPath, relToOwned)
 	if err != nil || isPulled {
 		return ""
 	}
@@ -291,13 +487,13 @@ func (ws *Workspace) discoverProjectsByPattern() ([]ProjectPath, error) {
 // applyProjectIgnores filters projects by ignore patterns.
 // Ignore patterns are matched against project paths (relative to owned directory).
 func (ws *Workspace) applyProjectIgnores(projects []ProjectPath) []ProjectPath {
-	if len(ws.config.Ignores) == 0 {
+	if len(ws.ignores) == 0 {
 		return projects
 	}
 
 	var filtered []ProjectPath
 	for _, p := range projects {
-		if !ws.matchesPattern(string(p), ws.config.Ignores) {
+		if !ws.isIgnored(string(p)) {
 			filtered = append(filtered, p)
 		}
 	}
@@ -309,7 +505,7 @@ func (ws *Workspace) applyProjectIgnores(projects []ProjectPath) []ProjectPath {
 // project: project path relative to owned directory (e.g., "api/v1")
 // Returns filtered slice of files that don't match ignore patterns.
 func (ws *Workspace) applyFileIgnores(files []ProjectFile, project ProjectPath) []ProjectFile {
-	if len(ws.config.Ignores) == 0 {
+	if len(ws.ignores) == 0 {
 		return files
 	}
 
@@ -317,7 +513,7 @@ func (ws *Workspace) applyFileIgnores(files []ProjectFile, project ProjectPath)
 	for _, f := range files {
 		// Construct full path (project/file) relative to owned directory
 		fullPath := path.Join(string(project), f.Path)
-		if !ws.matchesPattern(fullPath, ws.config.Ignores) {
+		if !ws.isIgnored(fullPath) {
 			filtered = append(filtered, f)
 		}
 	}
@@ -334,13 +530,9 @@ func (ws *Workspace) matchesPattern(projectPath string, patterns []string) bool
 	return false
 }
 
-// isPulledProject checks if a project is a pulled project (has protato.lock file).
+// isPulledProject checks if a project in ownedDir is a pulled project (has protato.lock file).
 // This is used to distinguish owned vs pulled projects when both directories are the same.
-func (ws *Workspace) isPulledProject(projectPath string) (bool, error) {
-	ownedDir, err := ws.OwnedDir()
-	if err != nil {
-		return false, err
-	}
+func (ws *Workspace) isPulledProject(ownedDir, projectPath string) (bool, error) {
 	vendorDir, err := ws.VendorDir()
 	if err != nil {
 		return false, err
@@ -371,8 +563,13 @@ func (ws *Workspace) ReceivedProjects(ctx context.Context) ([]*ReceivedProject,
 		return []*ReceivedProject{}, nil
 	}
 
+	layout, err := ws.vendorLayout()
+	if err != nil {
+		return nil, err
+	}
+
 	owned := ws.buildOwnedProjectsMap()
-	return ws.findReceivedProjectsInVendor(ctx, vendorPath, owned)
+	return ws.findReceivedProjectsInVendor(ctx, vendorPath, layout, owned)
 }
 
 // buildOwnedProjectsMap builds a map of owned project paths for filtering.
@@ -392,7 +589,7 @@ func (ws *Workspace) buildOwnedProjectsMap() map[string]bool {
 }
 
 // findReceivedProjectsInVendor finds received projects in the vendor directory.
-func (ws *Workspace) findReceivedProjectsInVendor(ctx context.Context, vendorPath string, owned map[string]bool) ([]*ReceivedProject, error) {
+func (ws *Workspace) findReceivedProjectsInVendor(ctx context.Context, vendorPath, layout string, owned map[string]bool) ([]*ReceivedProject, error) {
 	var received []*ReceivedProject
 
 	// Walk vendor directory looking for lock files
@@ -404,17 +601,11 @@ func (ws *Workspace) findReceivedProjectsInVendor(ctx context.Context, vendorPat
 			return nil
 		}
 
-		// Get project path from lock file location
-		projectPath, err := utils.RelPathToSlash(vendorPath, filepath.Dir(p))
+		relDir, err := utils.RelPathToSlash(vendorPath, filepath.Dir(p))
 		if err != nil {
 			return nil
 		}
 
-		// Skip owned projects
-		if owned[projectPath] {
-			return nil
-		}
-
 		// Read lock file
 		lock, err := readLockFile(p)
 		if err != nil {
@@ -422,9 +613,18 @@ func (ws *Workspace) findReceivedProjectsInVendor(ctx context.Context, vendorPat
 			return nil
 		}
 
+		// Get project path from lock file location, skipping owned projects
+		project := vendorProject(layout, relDir, lock)
+		if project == "" || owned[string(project)] {
+			return nil
+		}
+
 		received = append(received, &ReceivedProject{
-			Project:          ProjectPath(projectPath),
+			Project:          project,
 			ProviderSnapshot: lock.Snapshot,
+			Branch:           lock.Branch,
+			Ref:              lock.Ref,
+			ResolvedCommit:   lock.ResolvedCommit,
 		})
 
 		return nil
@@ -445,11 +645,10 @@ func (ws *Workspace) AddOwnedProjects(projects []string) error {
 		}
 
 		// Create project directory in owned directory
-		ownedDir, err := ws.OwnedDir()
+		projectPath, err := ws.ownedProjectDir(ProjectPath(ps))
 		if err != nil {
 			return err
 		}
-		projectPath := filepath.Join(ownedDir, ps)
 		if err := utils.CreateDir(projectPath, "project"); err != nil {
 			return err
 		}
@@ -459,25 +658,106 @@ func (ws *Workspace) AddOwnedProjects(projects []string) error {
 	return writeConfig(ConfigPath(ws.root), ws.config)
 }
 
+// RemoveOwnedProject removes a project from the configured owned projects and, with
+// deleteFiles, deletes its directory from the owned directory. A project matched by a
+// pattern or auto-discovery stays owned until its files are deleted.
+func (ws *Workspace) RemoveOwnedProject(project ProjectPath, deleteFiles bool) error {
+	if project == "" {
+		return fmt.Errorf("project path is empty")
+	}
+
+	kept := make([]string, 0, len(ws.config.Projects))
+	for _, p := range ws.config.Projects {
+		if p != string(project) {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) != len(ws.config.Projects) {
+		ws.config.Projects = kept
+		if err := writeConfig(ConfigPath(ws.root), ws.config); err != nil {
+			return err
+		}
+	}
+
+	if !deleteFiles {
+		return nil
+	}
+	projectDir, err := ws.ownedProjectDir(project)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(projectDir); err != nil {
+		return fmt.Errorf("remove project files: %w", err)
+	}
+	return nil
+}
+
+// RemoveReceivedProject deletes a received project from the vendor directory, lock file included.
+func (ws *Workspace) RemoveReceivedProject(project ProjectPath) error {
+	if project == "" {
+		return fmt.Errorf("project path is empty")
+	}
+	projectDir, err := ws.vendorProjectDir(project)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(projectDir); err != nil {
+		return fmt.Errorf("remove received project: %w", err)
+	}
+	return nil
+}
+
 // ReceiveProject starts receiving a project (into vendor directory).
 func (ws *Workspace) ReceiveProject(req *ReceiveProjectRequest) (*ProjectReceiver, error) {
-	// Received projects go into the vendor directory
-	vendorDir, err := ws.VendorDir()
-	if err != nil {
-		return nil, err
+	// Received projects go into the vendor directory unless exported elsewhere
+	projectRoot := projectPathJoin(req.OutputDir, req.Project)
+	var recordProject bool
+	if req.OutputDir == "" {
+		layout, err := ws.vendorLayout()
+		if err != nil {
+			return nil, err
+		}
+		if projectRoot, err = ws.vendorProjectDir(req.Project); err != nil {
+			return nil, err
+		}
+		// The flat layout drops the service, so projects of two services can clash
+		if recordProject = layout == VendorLayoutFlat; recordProject {
+			if lock, err := readLockFile(lockFilePath(projectRoot, "")); err == nil && lock.Project != string(req.Project) {
+				return nil, fmt.Errorf("cannot receive %s: %s holds received project %s", req.Project, ws.relToRoot(projectRoot), lock.Project)
+			}
+		}
+	}
+
+	gitattributes := ws.Gitattributes()
+	if req.NoGitattributes || req.OutputDir != "" {
+		gitattributes = ""
 	}
-	projectRoot := projectPathJoin(vendorDir, req.Project)
+
+	// Files are written to a staging dir next to the project and moved in by Finish.
+	// Whatever an interrupted receive left there is discarded.
+	stagingDir := filepath.Join(filepath.Dir(projectRoot), ".protato-receive-"+filepath.Base(projectRoot))
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return nil, fmt.Errorf("clear staging dir: %w", err)
+	}
+
 	return &ProjectReceiver{
-		ws:          ws,
-		project:     req.Project,
-		projectRoot: projectRoot,
-		snapshot:    req.Snapshot,
+		ws:            ws,
+		project:       req.Project,
+		projectRoot:   projectRoot,
+		stagingDir:    stagingDir,
+		snapshot:      req.Snapshot,
+		branch:        req.Branch,
+		ref:           req.Ref,
+		gitattributes: gitattributes,
+		ephemeral:     req.OutputDir != "",
+		recordProject: recordProject,
 	}, nil
 }
 
 // Write writes data to the file.
 func (w *ProjectFileWriter) Write(p []byte) (int, error) {
 	w.hash.Write(p)
+	w.content.Write(p)
 	return w.file.Write(p)
 }
 
@@ -488,30 +768,58 @@ func (w *ProjectFileWriter) Close() error {
 
 	// Check if file changed
 	changed := len(w.existingHash) == 0 || !utils.HashEqual(newHash, w.existingHash)
-	w.onClose(changed)
+	w.onClose(changed, w.content.Bytes(), newHash)
 
 	return err
 }
 
-// listProjectFiles lists files in a project directory.
-func (ws *Workspace) listProjectFiles(projectPath string, project ProjectPath, applyIgnores bool) ([]ProjectFile, error) {
+// listProjectFiles lists the files in a project directory for which match returns true.
+func (ws *Workspace) listProjectFiles(projectPath string, project ProjectPath, applyIgnores bool, match func(name string) bool) ([]ProjectFile, error) {
+	files, err := listDirFiles(projectPath, match)
+	if err != nil {
+		return files, err
+	}
+
+	// Apply ignores if requested
+	if applyIgnores {
+		files = ws.applyFileIgnores(files, project)
+	}
+
+	return files, nil
+}
+
+// ListDirFiles lists all proto files under a directory, relative to that directory.
+// A missing directory yields an empty list.
+func (ws *Workspace) ListDirFiles(dir string) ([]ProjectFile, error) {
+	return listDirFiles(dir, ws.specialFiles().IsProjectFile)
+}
+
+// specialFiles returns the files every walker skips: protato's metadata files plus metadata_files.
+func (ws *Workspace) specialFiles() utils.SpecialFiles {
+	if ws.config == nil {
+		return utils.SpecialFiles{}
+	}
+	return utils.NewSpecialFiles(ws.config.MetadataFiles)
+}
+
+// listDirFiles lists the files under a directory whose name match accepts, relative to that directory.
+func listDirFiles(dir string, match func(name string) bool) ([]ProjectFile, error) {
 	var files []ProjectFile
 
-	if utils.DirNotExists(projectPath) {
+	if utils.DirNotExists(dir) {
 		return files, nil
 	}
 
-	err := filepath.WalkDir(projectPath, func(p string, d fs.DirEntry, err error) error {
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		// Only process .proto files (skip directories and non-proto files)
-		if d.IsDir() || !strings.HasSuffix(d.Name(), constants.ProtoFileExt) {
+		if d.IsDir() || !match(d.Name()) {
 			return nil
 		}
 
 		// Get relative path
-		relPath, err := utils.RelPathToSlash(projectPath, p)
+		relPath, err := utils.RelPathToSlash(dir, p)
 		if err != nil {
 			return nil
 		}
@@ -523,35 +831,44 @@ func (ws *Workspace) listProjectFiles(projectPath string, project ProjectPath, a
 
 		return nil
 	})
-	if err != nil {
-		return files, err
-	}
-
-	// Apply ignores if requested
-	if applyIgnores {
-		files = ws.applyFileIgnores(files, project)
-	}
 
-	return files, nil
+	return files, err
 }
 
 // ListOwnedProjectFiles lists all files in an owned project.
 // project: path relative to the owned directory (e.g., "api/v1")
 func (ws *Workspace) ListOwnedProjectFiles(project ProjectPath) ([]ProjectFile, error) {
-	ownedDir, err := ws.OwnedDir()
+	projectDir, err := ws.ownedProjectDir(project)
+	if err != nil {
+		return nil, err
+	}
+	return ws.listProjectFiles(projectDir, project, true, ws.specialFiles().IsProjectFile)
+}
+
+// ListOwnedProjectFilesWithExtensions lists the files in an owned project whose extension is one of exts.
+// Protato metadata files are never included.
+func (ws *Workspace) ListOwnedProjectFilesWithExtensions(project ProjectPath, exts []string) ([]ProjectFile, error) {
+	projectDir, err := ws.ownedProjectDir(project)
 	if err != nil {
 		return nil, err
 	}
-	return ws.listProjectFiles(projectPathJoin(ownedDir, project), project, true)
+	match := func(name string) bool {
+		return !ws.specialFiles().Contains(name) && slices.Contains(exts, filepath.Ext(name))
+	}
+	return ws.listProjectFiles(projectDir, project, true, match)
 }
 
 // ListVendorProjectFiles lists all files in a vendor project.
+// Every non-metadata file is listed, since registries may allow files other than protos.
 func (ws *Workspace) ListVendorProjectFiles(project ProjectPath) ([]ProjectFile, error) {
-	vendorDir, err := ws.VendorDir()
+	projectDir, err := ws.vendorProjectDir(project)
 	if err != nil {
 		return nil, err
 	}
-	return ws.listProjectFiles(projectPathJoin(vendorDir, project), project, false)
+	special := ws.specialFiles()
+	return ws.listProjectFiles(projectDir, project, false, func(name string) bool {
+		return !special.Contains(name)
+	})
 }
 
 // IsProjectOwned returns true if the project is owned by this workspace.
@@ -568,14 +885,336 @@ func (ws *Workspace) IsProjectOwned(project ProjectPath) bool {
 	return false
 }
 
+// AddProtoFile creates relPath inside an owned project with a syntax, package and
+// go_package header. An empty pkg is derived from the registry path of the file's directory.
+// Parent directories are created as needed; an existing file is never overwritten.
+func (ws *Workspace) AddProtoFile(project ProjectPath, relPath string, pkg string) error {
+	filePath, err := ws.ownedProtoFilePath(project, relPath)
+	if err != nil {
+		return err
+	}
+
+	registryPath, err := ws.RegistryProjectPath(project)
+	if err != nil {
+		return err
+	}
+	goPackage := path.Join(string(registryPath), path.Dir(relPath))
+	if pkg == "" {
+		pkg = protoPackageFromPath(goPackage)
+	}
+
+	content := fmt.Sprintf("syntax = \"proto3\";\n\npackage %s;\n\noption go_package = %q;\n", pkg, goPackage)
+	if err := createProtoFile(filePath, content); err != nil {
+		return fmt.Errorf("add proto file: %w", err)
+	}
+	return nil
+}
+
+// createProtoFile writes a new proto file, creating its parent directories.
+// An existing file is never overwritten; the error then wraps fs.ErrExist.
+func createProtoFile(filePath, content string) error {
+	if err := utils.CreateDir(filepath.Dir(filePath), "proto file"); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("%s already exists: %w", filePath, fs.ErrExist)
+		}
+		return err
+	}
+
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return fmt.Errorf("write proto file: %w", err)
+	}
+	return f.Close()
+}
+
+// RemoveProtoFile deletes relPath from an owned project and prunes the
+// directories it leaves empty, stopping at the project root.
+func (ws *Workspace) RemoveProtoFile(project ProjectPath, relPath string) error {
+	filePath, err := ws.ownedProtoFilePath(project, relPath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		return fmt.Errorf("remove proto file: %w", err)
+	}
+
+	projectDir, err := ws.ownedProjectDir(project)
+	if err != nil {
+		return err
+	}
+	for dir := filepath.Dir(filePath); dir != projectDir; dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break // Not empty
+		}
+	}
+	return nil
+}
+
+// ownedProtoFilePath validates relPath and returns its absolute path inside an owned project.
+// A project without files yet counts as owned when the configured patterns would claim it.
+func (ws *Workspace) ownedProtoFilePath(project ProjectPath, relPath string) (string, error) {
+	if !ws.IsProjectOwned(project) && !ws.claimsProject(project) {
+		return "", fmt.Errorf("project %s is not owned by this workspace", project)
+	}
+	if err := utils.ValidateProjectPath(relPath); err != nil {
+		return "", fmt.Errorf("invalid file path %q: %w", relPath, err)
+	}
+	if !strings.HasSuffix(relPath, ".proto") {
+		return "", fmt.Errorf("invalid file path %q: not a .proto file", relPath)
+	}
+
+	projectDir, err := ws.ownedProjectDir(project)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(projectDir, filepath.FromSlash(relPath)), nil
+}
+
+// claimsProject reports whether the configured projects and ignores would make project owned.
+func (ws *Workspace) claimsProject(project ProjectPath) bool {
+	if ws.config == nil || utils.ValidateProjectPath(string(project)) != nil {
+		return false
+	}
+	if ws.isIgnored(string(project)) {
+		return false
+	}
+	return ws.config.AutoDiscover || ws.matchesPattern(string(project), ws.config.Projects)
+}
+
+// protoPackageFromPath converts a slash-separated path into a proto package name,
+// e.g. "my-service/team/v1" becomes "my_service.team.v1".
+func protoPackageFromPath(p string) string {
+	return strings.NewReplacer("/", ".", "-", "_").Replace(p)
+}
+
 // GetProjectLock returns the lock file for a vendor project.
 func (ws *Workspace) GetProjectLock(project ProjectPath) (*LockFile, error) {
-	vendorDir, err := ws.VendorDir()
+	projectDir, err := ws.vendorProjectDir(project)
 	if err != nil {
 		return nil, err
 	}
-	lockPath := lockFilePath(vendorDir, string(project))
-	return readLockFile(lockPath)
+	return readLockFile(lockFilePath(projectDir, ""))
+}
+
+// IsReceivedProjectStale reports whether the registry has changed a received project since
+// the snapshot in its lock file. The project is compared at the registry's current snapshot,
+// or at the tip of the branch it tracks (which the caller must have refreshed); when it changed,
+// that snapshot is returned. A project removed from the registry is reported as an error
+// wrapping errors.ErrNotFound. Projects received from a local directory are never stale.
+func (ws *Workspace) IsReceivedProjectStale(ctx context.Context, project ProjectPath, cache ProjectLookup) (bool, git.Hash, error) {
+	lock, err := ws.GetProjectLock(project)
+	if err != nil {
+		return false, "", fmt.Errorf("read lock of %s: %w", project, err)
+	}
+	if strings.HasPrefix(lock.Snapshot, constants.LocalSnapshotPrefix) {
+		return false, "", nil
+	}
+
+	var current git.Hash
+	if lock.Branch != "" {
+		if current, err = cache.BranchSnapshot(ctx, lock.Branch); err != nil {
+			return false, "", fmt.Errorf("resolve branch %s: %w", lock.Branch, err)
+		}
+	}
+	latest, err := lookupReceivedProject(ctx, cache, project, current)
+	if err != nil {
+		return false, "", err
+	}
+	if latest == nil {
+		return false, "", fmt.Errorf("project %s was removed from the registry: %w", project, errors.ErrNotFound)
+	}
+	if latest.Snapshot == git.Hash(lock.Snapshot) {
+		return false, "", nil
+	}
+
+	if err := cache.EnsureSnapshotAvailable(ctx, git.Hash(lock.Snapshot)); err != nil {
+		return false, "", fmt.Errorf("fetch locked snapshot of %s: %w", project, err)
+	}
+	locked, err := lookupReceivedProject(ctx, cache, project, git.Hash(lock.Snapshot))
+	if err != nil {
+		return false, "", err
+	}
+	if locked != nil && locked.ProjectHash == latest.ProjectHash {
+		return false, "", nil
+	}
+	return true, latest.Snapshot, nil
+}
+
+// lookupReceivedProject looks up exactly project at snapshot ("" for the current one).
+// It returns nil when the registry has no project at that path.
+func lookupReceivedProject(ctx context.Context, cache ProjectLookup, project ProjectPath, snapshot git.Hash) (*registry.LookupProjectResponse, error) {
+	res, err := cache.LookupProject(ctx, &registry.LookupProjectRequest{Path: string(project), Snapshot: snapshot})
+	if stderrors.Is(err, errors.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("look up %s: %w", project, err)
+	}
+	// A lookup falls back to a parent project; that is not the project that was received
+	if res.Project == nil || res.Project.Path != registry.ProjectPath(project) {
+		return nil, nil
+	}
+	return res, nil
+}
+
+// SetProjectLock rewrites the lock file of a received project, leaving its files untouched.
+// The project recorded by the previous lock file is kept.
+func (ws *Workspace) SetProjectLock(project ProjectPath, lock *LockFile) error {
+	projectDir, err := ws.vendorProjectDir(project)
+	if err != nil {
+		return err
+	}
+	lockPath := lockFilePath(projectDir, "")
+	existing, err := readLockFile(lockPath)
+	if err != nil {
+		return fmt.Errorf("project %s is not received: %w", project, err)
+	}
+	lock.Project = existing.Project
+	return writeLockFile(lockPath, lock)
+}
+
+// GetProjectManifest returns the manifest of received files for a vendor project.
+func (ws *Workspace) GetProjectManifest(project ProjectPath) (*Manifest, error) {
+	projectDir, err := ws.vendorProjectDir(project)
+	if err != nil {
+		return nil, err
+	}
+	return readManifest(filepath.Join(projectDir, constants.ManifestFileName))
+}
+
+// VerifyVendorIntegrity checks a vendor project against its manifest and the
+// file checksums recorded in its lock file. It returns the paths of files that
+// were modified, deleted, added or had their mode changed since the project was
+// received. Either record may be missing, e.g. for projects received by older
+// versions, but not both.
+func (ws *Workspace) VerifyVendorIntegrity(project ProjectPath) ([]string, error) {
+	lock, err := ws.GetProjectLock(project)
+	if err != nil {
+		return nil, fmt.Errorf("read lock file: %w", err)
+	}
+	manifest, err := ws.GetProjectManifest(project)
+	if err != nil && len(lock.Files) == 0 {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+
+	mismatched := make(map[string]bool)
+	if manifest != nil {
+		paths, err := ws.CompareVendorFiles(project, manifest.Files)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			mismatched[path] = true
+		}
+	}
+	if len(lock.Files) > 0 {
+		paths, err := ws.compareVendorChecksums(project, lock.Files)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			mismatched[path] = true
+		}
+	}
+
+	if len(mismatched) == 0 {
+		return nil, nil
+	}
+	return utils.SortedKeys(mismatched), nil
+}
+
+// compareVendorChecksums checks a vendor project's files against SHA-256 checksums.
+// It returns the paths of files whose content differs, that are missing, or that
+// are present but have no checksum.
+func (ws *Workspace) compareVendorChecksums(project ProjectPath, checksums map[string]string) ([]string, error) {
+	localFiles, err := ws.ListVendorProjectFiles(project)
+	if err != nil {
+		return nil, err
+	}
+
+	var mismatched []string
+	seen := make(map[string]bool, len(localFiles))
+	for _, f := range localFiles {
+		seen[f.Path] = true
+		want, ok := checksums[f.Path]
+		if !ok || !fileMatchesChecksum(f.AbsolutePath, want) {
+			mismatched = append(mismatched, f.Path)
+		}
+	}
+	for path := range checksums {
+		if !seen[path] {
+			mismatched = append(mismatched, path)
+		}
+	}
+
+	sort.Strings(mismatched)
+	return mismatched, nil
+}
+
+// fileMatchesChecksum reports whether a file's content has the given hex SHA-256.
+func fileMatchesChecksum(absPath, want string) bool {
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]) == want
+}
+
+// CompareVendorFiles checks a vendor project's files against the expected entries.
+// It returns the paths of files whose content or mode differ, that are missing,
+// or that are present but not expected.
+func (ws *Workspace) CompareVendorFiles(project ProjectPath, want []ManifestEntry) ([]string, error) {
+	localFiles, err := ws.ListVendorProjectFiles(project)
+	if err != nil {
+		return nil, err
+	}
+	localByPath := make(map[string]ProjectFile, len(localFiles))
+	for _, f := range localFiles {
+		localByPath[f.Path] = f
+	}
+
+	var mismatched []string
+	for _, entry := range want {
+		f, ok := localByPath[entry.Path]
+		if !ok {
+			mismatched = append(mismatched, entry.Path)
+			continue
+		}
+		delete(localByPath, entry.Path)
+		if !fileMatchesEntry(f.AbsolutePath, entry) {
+			mismatched = append(mismatched, entry.Path)
+		}
+	}
+	for path := range localByPath {
+		mismatched = append(mismatched, path)
+	}
+
+	sort.Strings(mismatched)
+	return mismatched, nil
+}
+
+// fileMatchesEntry reports whether a file's content and mode match a manifest entry.
+func fileMatchesEntry(absPath string, entry ManifestEntry) bool {
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return false
+	}
+	mode := regularFileMode
+	if info.Mode().Perm()&0111 != 0 {
+		mode = executableFileMode
+	}
+	return git.BlobHash(data).String() == entry.Hash && formatFileMode(mode) == entry.Mode
 }
 
 // receiverPathJoin joins a path relative to the project receiver root.
@@ -583,74 +1222,179 @@ func (r *ProjectReceiver) receiverPathJoin(relPath string) string {
 	return filepath.Join(r.projectRoot, relPath)
 }
 
-// CreateFile creates a file in the project.
+// CreateFile creates a regular (non-executable) file in the project.
 func (r *ProjectReceiver) CreateFile(relPath string) (*ProjectFileWriter, error) {
-	absPath := r.receiverPathJoin(relPath)
+	return r.CreateFileWithMode(relPath, regularFileMode)
+}
+
+// CreateFileWithMode creates a file in the project with the given Git file mode.
+// The file is staged until Finish and recorded in the project manifest when the writer is closed.
+func (r *ProjectReceiver) CreateFileWithMode(relPath string, mode uint32) (*ProjectFileWriter, error) {
+	if mode == 0 {
+		mode = regularFileMode
+	}
+	stagedPath := filepath.Join(r.stagingDir, relPath)
 
 	// Create directory if needed
-	dir := filepath.Dir(absPath)
+	dir := filepath.Dir(stagedPath)
 	if err := utils.CreateDir(dir, "file"); err != nil {
 		return nil, err
 	}
 
 	// Read existing file hash if exists
 	var existingHash []byte
-	if data, err := os.ReadFile(absPath); err == nil {
+	if data, err := os.ReadFile(r.receiverPathJoin(relPath)); err == nil {
 		h := sha256.Sum256(data)
 		existingHash = h[:]
 	}
 
 	// Create file
-	f, err := os.Create(absPath)
+	perm := filePerm(mode)
+	f, err := os.OpenFile(stagedPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
 	if err != nil {
 		return nil, fmt.Errorf("create file: %w", err)
 	}
+	// OpenFile keeps the mode of an existing file
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("chmod file: %w", err)
+	}
 
 	return &ProjectFileWriter{
 		file:         f,
 		hash:         sha256.New(),
 		existingHash: existingHash,
-		onClose: func(changed bool) {
+		onClose: func(changed bool, content, sum []byte) {
 			if changed {
 				r.changed++
 			}
+			if r.checksums == nil {
+				r.checksums = make(map[string]string)
+			}
+			r.checksums[relPath] = hex.EncodeToString(sum)
+			r.manifest = append(r.manifest, ManifestEntry{
+				Path: relPath,
+				Hash: git.BlobHash(content).String(),
+				Mode: formatFileMode(mode),
+			})
 		},
 	}, nil
 }
 
-// DeleteFile deletes a file from the project.
-func (r *ProjectReceiver) DeleteFile(relPath string) error {
-	absPath := r.receiverPathJoin(relPath)
-	if err := os.Remove(absPath); err != nil && !os.IsNotExist(err) {
-		return err
+// NewManifestEntry returns the manifest entry of a file with the given blob hash and Git file mode.
+// A zero mode is a regular file, as in CreateFileWithMode.
+func NewManifestEntry(relPath string, hash git.Hash, mode uint32) ManifestEntry {
+	if mode == 0 {
+		mode = regularFileMode
 	}
+	return ManifestEntry{Path: relPath, Hash: string(hash), Mode: formatFileMode(mode)}
+}
+
+// Git file modes of received files.
+const (
+	regularFileMode    uint32 = 0100644
+	executableFileMode uint32 = 0100755
+)
+
+// filePerm returns the filesystem permissions for a Git file mode.
+func filePerm(mode uint32) os.FileMode {
+	if mode&0111 != 0 {
+		return 0755
+	}
+	return 0644
+}
+
+// formatFileMode formats a Git file mode the way Git prints it.
+func formatFileMode(mode uint32) string {
+	return fmt.Sprintf("%06o", mode)
+}
+
+// DeleteFile deletes a file from the project when the receive finishes.
+func (r *ProjectReceiver) DeleteFile(relPath string) error {
+	r.toDelete = append(r.toDelete, relPath)
 	r.deleted++
 	return nil
 }
 
 // Finish completes the receive operation.
+// The project is only modified here, so a receive that stops before Finish leaves it as it was.
 func (r *ProjectReceiver) Finish() (*ReceiveStats, error) {
 	// Ensure project directory exists
 	if err := utils.CreateDir(r.projectRoot, "project"); err != nil {
 		return nil, err
 	}
+	if err := r.applyStaged(); err != nil {
+		return nil, err
+	}
+
+	stats := &ReceiveStats{
+		FilesChanged: r.changed,
+		FilesDeleted: r.deleted,
+	}
+	if r.ephemeral {
+		return stats, nil
+	}
 
 	// Write lock file
 	lockPath := r.receiverPathJoin(constants.LockFileName)
-	if err := writeLockFile(lockPath, &LockFile{Snapshot: string(r.snapshot)}); err != nil {
+	if err := writeLockFile(lockPath, r.lock()); err != nil {
 		return nil, fmt.Errorf("write lock file: %w", err)
 	}
 
-	// Write .gitattributes
-	gitattrsPath := r.receiverPathJoin(constants.GitattributesName)
-	if err := os.WriteFile(gitattrsPath, []byte("* linguist-generated=true\n"), 0644); err != nil {
-		return nil, fmt.Errorf("write gitattributes: %w", err)
+	// Write manifest
+	manifestPath := r.receiverPathJoin(constants.ManifestFileName)
+	sort.Slice(r.manifest, func(i, j int) bool { return r.manifest[i].Path < r.manifest[j].Path })
+	if err := writeManifest(manifestPath, &Manifest{Files: r.manifest}); err != nil {
+		return nil, fmt.Errorf("write manifest: %w", err)
 	}
 
-	return &ReceiveStats{
-		FilesChanged: r.changed,
-		FilesDeleted: r.deleted,
-	}, nil
+	// Write .gitattributes unless disabled
+	if r.gitattributes != "" {
+		gitattrsPath := r.receiverPathJoin(constants.GitattributesName)
+		if err := os.WriteFile(gitattrsPath, []byte(r.gitattributes), 0644); err != nil {
+			return nil, fmt.Errorf("write gitattributes: %w", err)
+		}
+	}
+
+	return stats, nil
+}
+
+// lock returns the lock file contents for the received snapshot.
+// A pinned ref is recorded together with the commit it resolved to,
+// and every received file with the checksum of its content.
+func (r *ProjectReceiver) lock() *LockFile {
+	lock := &LockFile{Snapshot: string(r.snapshot), Branch: r.branch, Files: r.checksums}
+	if r.recordProject {
+		lock.Project = string(r.project)
+	}
+	if r.ref != "" {
+		lock.Ref = r.ref
+		lock.ResolvedCommit = string(r.snapshot)
+	}
+	return lock
+}
+
+// applyStaged moves the staged files into the project, deletes the removed ones
+// and drops the staging dir.
+func (r *ProjectReceiver) applyStaged() error {
+	for _, entry := range r.manifest {
+		absPath := r.receiverPathJoin(entry.Path)
+		if err := utils.CreateDir(filepath.Dir(absPath), "file"); err != nil {
+			return err
+		}
+		if err := os.Rename(filepath.Join(r.stagingDir, entry.Path), absPath); err != nil {
+			return fmt.Errorf("move received file %s: %w", entry.Path, err)
+		}
+	}
+	for _, relPath := range r.toDelete {
+		if err := os.Remove(r.receiverPathJoin(relPath)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.RemoveAll(r.stagingDir); err != nil {
+		return fmt.Errorf("remove staging dir: %w", err)
+	}
+	return nil
 }
 
 // readConfig reads the protato.yaml config file.
@@ -673,8 +1417,51 @@ func writeLockFile(path string, lock *LockFile) error {
 	return utils.WriteYAML(path, lock)
 }
 
+// readManifest reads a received.manifest.yaml file.
+func readManifest(path string) (*Manifest, error) {
+	return utils.ReadYAMLFile[Manifest](path)
+}
+
+// writeManifest writes a received.manifest.yaml file.
+func writeManifest(path string, manifest *Manifest) error {
+	return utils.WriteYAML(path, manifest)
+}
+
+// CleanVendor removes everything inside the vendor directory (received projects,
+// locks, manifests and gitattributes). The owned directories are never touched: if the
+// vendor directory is, or contains, an owned directory, nothing is removed.
+func (ws *Workspace) CleanVendor() error {
+	vendorDir, err := ws.VendorDir()
+	if err != nil {
+		return err
+	}
+	ownedDirs, err := ws.OwnedDirs()
+	if err != nil {
+		return err
+	}
+	for _, ownedDir := range ownedDirs {
+		if utils.PathBelongsToAny(ownedDir, map[string]bool{vendorDir: true}) {
+			return errors.ErrVendorContainsOwned
+		}
+	}
+
+	entries, err := os.ReadDir(vendorDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read vendor directory: %w", err)
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(vendorDir, entry.Name())); err != nil {
+			return fmt.Errorf("remove %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
 // OrphanedFiles finds files that don't belong to any known project.
-// Checks both owned and vendor directories.
+// Checks the vendor directory and every owned directory.
 func (ws *Workspace) OrphanedFiles(ctx context.Context) ([]string, error) {
 	var orphaned []string
 
@@ -690,30 +1477,35 @@ func (ws *Workspace) OrphanedFiles(ctx context.Context) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	receivedSet := ws.receivedProjectsToMap(received)
-
 	// Check vendor directory for orphaned files first
 	vendorDir, err := ws.VendorDir()
 	if err != nil {
 		return nil, err
 	}
+	layout, err := ws.vendorLayout()
+	if err != nil {
+		return nil, err
+	}
+	receivedSet := ws.receivedProjectsToMap(received, layout)
 	vendorOrphans, err := ws.findOrphanedInDir(vendorDir, receivedSet, "")
 	if err != nil {
 		return nil, err
 	}
 	orphaned = append(orphaned, vendorOrphans...)
 
-	// Check owned directory for orphaned files
+	// Check owned directories for orphaned files
 	// Exclude vendor directory from owned directory walk to avoid checking vendor files against owned projects
-	ownedDir, err := ws.OwnedDir()
+	ownedDirs, err := ws.OwnedDirs()
 	if err != nil {
 		return nil, err
 	}
-	ownedOrphans, err := ws.findOrphanedInDir(ownedDir, ownedSet, vendorDir)
-	if err != nil {
-		return nil, err
+	for _, ownedDir := range ownedDirs {
+		ownedOrphans, err := ws.findOrphanedInDir(ownedDir, ownedSet, vendorDir)
+		if err != nil {
+			return nil, err
+		}
+		orphaned = append(orphaned, ownedOrphans...)
 	}
-	orphaned = append(orphaned, ownedOrphans...)
 
 	return orphaned, nil
 }
@@ -762,7 +1554,7 @@ func (ws *Workspace) findOrphanedInDir(dirPath string, knownProjects map[string]
 
 // checkIfOrphaned checks if a file is orphaned and returns its repo-relative path if so.
 func (ws *Workspace) checkIfOrphaned(filePath, absDirPath, fileName string, knownProjects map[string]bool) string {
-	if !strings.HasSuffix(fileName, constants.ProtoFileExt) {
+	if !ws.specialFiles().IsProjectFile(fileName) {
 		return ""
 	}
 
@@ -786,10 +1578,11 @@ func (ws *Workspace) projectPathsToMap(projects []ProjectPath) map[string]bool {
 	})
 }
 
-// receivedProjectsToMap converts a slice of ReceivedProject to a map for fast lookups.
-func (ws *Workspace) receivedProjectsToMap(projects []*ReceivedProject) map[string]bool {
+// receivedProjectsToMap converts a slice of ReceivedProject to a map of their directories
+// in the vendor layout for fast lookups.
+func (ws *Workspace) receivedProjectsToMap(projects []*ReceivedProject, layout string) map[string]bool {
 	return utils.SliceToMap(projects, func(r *ReceivedProject) string {
-		return string(r.Project)
+		return vendorRelDir(layout, r.Project)
 	})
 }
 
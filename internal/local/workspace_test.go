@@ -2,11 +2,22 @@ package local
 
 import (
 	"context"
+	stderrors "errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
+	"gopkg.in/yaml.v3"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
 	"github.com/rahulagarwal0605/protato/internal/errors"
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/registry"
 )
 
 // Helper functions to avoid import cycle with testhelpers
@@ -159,6 +170,45 @@ ignores:
 	}
 }
 
+func TestWorkspace_Init_OverlappingOwnedDirs(t *testing.T) {
+	tests := []struct {
+		name      string
+		ownedDirs []string
+		wantErr   bool
+	}{
+		{name: "separate roots", ownedDirs: []string{"apis", "internal-apis"}},
+		{name: "sibling with shared name prefix", ownedDirs: []string{"apis", "apis-internal"}},
+		{name: "same root twice", ownedDirs: []string{"apis", "apis/"}, wantErr: true},
+		{name: "nested root", ownedDirs: []string{"apis", "apis/internal"}, wantErr: true},
+		{name: "repository root", ownedDirs: []string{".", "apis"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			cfg := &Config{Directories: DirectoryConfig{OwnedDirs: tt.ownedDirs, Vendor: "vendor-proto"}}
+			_, err := Init(context.Background(), tmpDir, cfg, false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Init() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !stderrors.Is(err, errors.ErrOwnedDirsOverlap) {
+					t.Errorf("Init() error = %v, want %v", err, errors.ErrOwnedDirsOverlap)
+				}
+				if fileExists(ConfigPath(tmpDir)) {
+					t.Error("Init() wrote the config for overlapping owned directories")
+				}
+				return
+			}
+			for _, dir := range tt.ownedDirs {
+				if !fileExists(filepath.Join(tmpDir, dir)) {
+					t.Errorf("Init() did not create owned directory %s", dir)
+				}
+			}
+		})
+	}
+}
+
 func TestWorkspace_Open(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -289,6 +339,119 @@ func TestWorkspace_OwnedProjects(t *testing.T) {
 	}
 }
 
+func TestWorkspace_MultipleOwnedDirs(t *testing.T) {
+	cfg := &Config{
+		Service:      "test-service",
+		AutoDiscover: true,
+		Directories: DirectoryConfig{
+			OwnedDirs: []string{"apis", "internal-apis"},
+			Vendor:    "vendor-proto",
+		},
+	}
+	tmpDir, _ := setupTestWorkspaceWithConfig(t, cfg)
+	createTestProject(t, tmpDir, "apis/billing", map[string]string{"v1/billing.proto": "syntax = \"proto3\";"})
+	createTestProject(t, tmpDir, "internal-apis/audit", map[string]string{"v1/audit.proto": "syntax = \"proto3\";"})
+
+	// The list form survives a reload of the config file
+	ws, err := Open(context.Background(), tmpDir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	dirs, err := ws.OwnedDirs()
+	if err != nil {
+		t.Fatalf("OwnedDirs() error = %v", err)
+	}
+	wantDirs := []string{filepath.Join(tmpDir, "apis"), filepath.Join(tmpDir, "internal-apis")}
+	if !reflect.DeepEqual(dirs, wantDirs) {
+		t.Errorf("OwnedDirs() = %v, want %v", dirs, wantDirs)
+	}
+	if ownedDir, _ := ws.OwnedDir(); ownedDir != wantDirs[0] {
+		t.Errorf("OwnedDir() = %s, want the first owned directory %s", ownedDir, wantDirs[0])
+	}
+
+	projects, err := ws.OwnedProjects()
+	if err != nil {
+		t.Fatalf("OwnedProjects() error = %v", err)
+	}
+	if want := []ProjectPath{"billing/v1", "audit/v1"}; !reflect.DeepEqual(projects, want) {
+		t.Errorf("OwnedProjects() = %v, want %v", projects, want)
+	}
+
+	files, err := ws.ListOwnedProjectFiles("audit/v1")
+	if err != nil {
+		t.Fatalf("ListOwnedProjectFiles() error = %v", err)
+	}
+	if len(files) != 1 || files[0].AbsolutePath != filepath.Join(tmpDir, "internal-apis/audit/v1/audit.proto") {
+		t.Errorf("ListOwnedProjectFiles() = %v, want audit.proto from internal-apis", files)
+	}
+
+	// Projects claimed by pattern leave files elsewhere in either root orphaned
+	ws.config.AutoDiscover = false
+	ws.config.Projects = []string{"billing/v1", "audit/v1"}
+	createTestProject(t, tmpDir, "internal-apis/stray", map[string]string{"stray.proto": "syntax = \"proto3\";"})
+	orphaned, err := ws.OrphanedFiles(context.Background())
+	if err != nil {
+		t.Fatalf("OrphanedFiles() error = %v", err)
+	}
+	if want := []string{filepath.Join("internal-apis", "stray", "stray.proto")}; !reflect.DeepEqual(orphaned, want) {
+		t.Errorf("OrphanedFiles() = %v, want %v", orphaned, want)
+	}
+
+	// The same project in both roots is ambiguous
+	createTestProject(t, tmpDir, "internal-apis/billing", map[string]string{"v1/billing.proto": "syntax = \"proto3\";"})
+	if _, err := ws.OwnedProjects(); !stderrors.Is(err, errors.ErrDuplicateOwnedProject) {
+		t.Errorf("OwnedProjects() error = %v, want %v", err, errors.ErrDuplicateOwnedProject)
+	}
+}
+
+func TestDirectoryConfig_YAML(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want DirectoryConfig
+	}{
+		{
+			name: "single owned directory",
+			yaml: "owned: proto\nvendor: vendor-proto\n",
+			want: DirectoryConfig{Owned: "proto", Vendor: "vendor-proto"},
+		},
+		{
+			name: "list of owned directories",
+			yaml: "owned:\n    - apis\n    - internal-apis\nvendor: vendor-proto\n",
+			want: DirectoryConfig{OwnedDirs: []string{"apis", "internal-apis"}, Vendor: "vendor-proto"},
+		},
+		{
+			name: "vendor only",
+			yaml: "vendor: vendor-proto\n",
+			want: DirectoryConfig{Vendor: "vendor-proto"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got DirectoryConfig
+			if err := yaml.Unmarshal([]byte(tt.yaml), &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Unmarshal() = %+v, want %+v", got, tt.want)
+			}
+			out, err := yaml.Marshal(got)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(out) != tt.yaml {
+				t.Errorf("Marshal() = %q, want %q", out, tt.yaml)
+			}
+		})
+	}
+
+	var got DirectoryConfig
+	if err := yaml.Unmarshal([]byte("owned:\n  dir: apis\n"), &got); err == nil {
+		t.Error("Unmarshal() of a mapping for owned expected error")
+	}
+}
+
 func TestWorkspace_AddOwnedProjects(t *testing.T) {
 	// Use workspace with auto-discover disabled to test explicit project addition
 	cfg := &Config{
@@ -356,6 +519,58 @@ func TestWorkspace_AddOwnedProjects(t *testing.T) {
 	}
 }
 
+func TestWorkspace_RemoveOwnedProject(t *testing.T) {
+	tests := []struct {
+		name         string
+		deleteFiles  bool
+		wantProjects []string
+		wantFiles    bool
+	}{
+		{
+			name:         "un-claim only",
+			wantProjects: []string{"team/other"},
+			wantFiles:    true,
+		},
+		{
+			name:         "un-claim and delete files",
+			deleteFiles:  true,
+			wantProjects: []string{"team/other"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				Service:     "test-service",
+				Directories: DirectoryConfig{Owned: "proto", Vendor: "vendor-proto"},
+			}
+			tmpDir, ws := setupTestWorkspaceWithConfig(t, cfg)
+			if err := ws.AddOwnedProjects([]string{"team/service", "team/other"}); err != nil {
+				t.Fatalf("AddOwnedProjects() error = %v", err)
+			}
+			createTestProject(t, tmpDir, "proto/team/service", map[string]string{"v1/api.proto": "syntax = \"proto3\";"})
+
+			if err := ws.RemoveOwnedProject("team/service", tt.deleteFiles); err != nil {
+				t.Fatalf("RemoveOwnedProject() error = %v", err)
+			}
+
+			reloaded, err := Open(context.Background(), tmpDir)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			if !reflect.DeepEqual(reloaded.config.Projects, tt.wantProjects) {
+				t.Errorf("projects = %v, want %v", reloaded.config.Projects, tt.wantProjects)
+			}
+			if got := fileExists(filepath.Join(tmpDir, "proto/team/service/v1/api.proto")); got != tt.wantFiles {
+				t.Errorf("project files exist = %v, want %v", got, tt.wantFiles)
+			}
+			if !fileExists(filepath.Join(tmpDir, "proto/team/other")) {
+				t.Error("RemoveOwnedProject() touched another project")
+			}
+		})
+	}
+}
+
 func TestWorkspace_RegistryProjectPath(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -630,6 +845,29 @@ func TestWorkspace_OwnedDirName(t *testing.T) {
 	}
 }
 
+func TestWorkspace_RequiresApproval(t *testing.T) {
+	cfg := &Config{
+		Service:         "test-service",
+		Directories:     DefaultDirectoryConfig(),
+		RequireApproval: []string{"payments/**"},
+	}
+	_, ws := setupTestWorkspaceWithConfig(t, cfg)
+
+	tests := []struct {
+		project ProjectPath
+		want    bool
+	}{
+		{project: "payments/api", want: true},
+		{project: "payments/api/v2", want: true},
+		{project: "billing/api", want: false},
+	}
+	for _, tt := range tests {
+		if got := ws.RequiresApproval(tt.project); got != tt.want {
+			t.Errorf("RequiresApproval(%q) = %v, want %v", tt.project, got, tt.want)
+		}
+	}
+}
+
 func TestWorkspace_IsProjectOwned(t *testing.T) {
 	cfg := &Config{
 		Service:      "test-service",
@@ -663,6 +901,141 @@ func TestWorkspace_IsProjectOwned(t *testing.T) {
 	}
 }
 
+func TestWorkspace_AddProtoFile(t *testing.T) {
+	cfg := &Config{
+		Service:  "test-service",
+		Projects: []string{"team/*"},
+		Directories: DirectoryConfig{
+			Owned:  "proto",
+			Vendor: "vendor-proto",
+		},
+	}
+
+	tests := []struct {
+		name        string
+		project     ProjectPath
+		relPath     string
+		pkg         string
+		wantPackage string
+		wantErr     bool
+	}{
+		{
+			name:        "derives package from registry path",
+			project:     "team/payments",
+			relPath:     "v1/payments.proto",
+			wantPackage: "package test_service.team.payments.v1;",
+		},
+		{
+			name:        "explicit package",
+			project:     "team/payments",
+			relPath:     "api.proto",
+			pkg:         "acme.payments",
+			wantPackage: "package acme.payments;",
+		},
+		{
+			name:    "project not owned",
+			project: "other/payments",
+			relPath: "api.proto",
+			wantErr: true,
+		},
+		{
+			name:    "path escapes project",
+			project: "team/payments",
+			relPath: "../api.proto",
+			wantErr: true,
+		},
+		{
+			name:    "not a proto file",
+			project: "team/payments",
+			relPath: "README.md",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, ws := setupTestWorkspaceWithConfig(t, cfg)
+
+			err := ws.AddProtoFile(tt.project, tt.relPath, tt.pkg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AddProtoFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			content, err := os.ReadFile(filepath.Join(tmpDir, "proto", string(tt.project), tt.relPath))
+			if err != nil {
+				t.Fatalf("Failed to read created file: %v", err)
+			}
+			if !strings.Contains(string(content), tt.wantPackage+"\n") {
+				t.Errorf("AddProtoFile() content = %q, want line %q", content, tt.wantPackage)
+			}
+			if !strings.HasPrefix(string(content), "syntax = \"proto3\";") {
+				t.Errorf("AddProtoFile() content = %q, want proto3 syntax first", content)
+			}
+		})
+	}
+}
+
+func TestWorkspace_AddProtoFile_RefusesOverwrite(t *testing.T) {
+	cfg := &Config{
+		Service:  "test-service",
+		Projects: []string{"team/*"},
+		Directories: DirectoryConfig{
+			Owned:  "proto",
+			Vendor: "vendor-proto",
+		},
+	}
+	tmpDir, ws := setupTestWorkspaceWithConfig(t, cfg)
+	createTestProject(t, tmpDir, "proto/team/payments", map[string]string{
+		"api.proto": "// hand written",
+	})
+
+	err := ws.AddProtoFile("team/payments", "api.proto", "")
+	if !stderrors.Is(err, fs.ErrExist) {
+		t.Fatalf("AddProtoFile() error = %v, want fs.ErrExist", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "proto/team/payments/api.proto"))
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != "// hand written" {
+		t.Errorf("AddProtoFile() overwrote existing file: %q", content)
+	}
+}
+
+func TestWorkspace_RemoveProtoFile(t *testing.T) {
+	cfg := &Config{
+		Service:  "test-service",
+		Projects: []string{"team/*"},
+		Directories: DirectoryConfig{
+			Owned:  "proto",
+			Vendor: "vendor-proto",
+		},
+	}
+	tmpDir, ws := setupTestWorkspaceWithConfig(t, cfg)
+	createTestProject(t, tmpDir, "proto/team/payments", map[string]string{
+		"api.proto":            "syntax = \"proto3\";",
+		"internal/types.proto": "syntax = \"proto3\";",
+	})
+
+	if err := ws.RemoveProtoFile("team/payments", "internal/types.proto"); err != nil {
+		t.Fatalf("RemoveProtoFile() error = %v", err)
+	}
+	if fileExists(filepath.Join(tmpDir, "proto/team/payments/internal")) {
+		t.Error("RemoveProtoFile() left empty directory behind")
+	}
+	if !fileExists(filepath.Join(tmpDir, "proto/team/payments/api.proto")) {
+		t.Error("RemoveProtoFile() removed an unrelated file")
+	}
+
+	if err := ws.RemoveProtoFile("team/payments", "missing.proto"); err == nil {
+		t.Error("RemoveProtoFile() error = nil, want error for missing file")
+	}
+}
+
 func TestWorkspace_ListOwnedProjectFiles(t *testing.T) {
 	cfg := &Config{
 		Service:      "test-service",
@@ -691,6 +1064,37 @@ func TestWorkspace_ListOwnedProjectFiles(t *testing.T) {
 	}
 }
 
+func TestWorkspace_ListOwnedProjectFilesWithExtensions(t *testing.T) {
+	cfg := &Config{
+		Service: "test-service",
+		Directories: DirectoryConfig{
+			Owned:  "proto",
+			Vendor: "vendor-proto",
+		},
+		Projects: []string{"team/service"},
+	}
+	tmpDir, ws := setupTestWorkspaceWithConfig(t, cfg)
+	createTestProject(t, tmpDir, "proto/team/service", map[string]string{
+		"v1/api.proto":            "syntax = \"proto3\";",
+		"v1/schema.yaml":          "kind: schema",
+		"README.md":               "# docs",
+		constants.ProjectMetaFile: "",
+	})
+
+	files, err := ws.ListOwnedProjectFilesWithExtensions("team/service", []string{".proto", ".yaml"})
+	if err != nil {
+		t.Fatalf("ListOwnedProjectFilesWithExtensions() error = %v", err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, f.Path)
+	}
+	want := []string{"v1/api.proto", "v1/schema.yaml"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ListOwnedProjectFilesWithExtensions() = %v, want %v", got, want)
+	}
+}
+
 func TestWorkspace_ReceiveProject(t *testing.T) {
 	cfg := &Config{
 		Service: "test-service",
@@ -744,6 +1148,341 @@ func TestWorkspace_ReceiveProject(t *testing.T) {
 	}
 }
 
+func TestWorkspace_ReceiveProject_Manifest(t *testing.T) {
+	cfg := &Config{
+		Service: "test-service",
+		Directories: DirectoryConfig{
+			Owned:  "proto",
+			Vendor: "vendor-proto",
+		},
+	}
+	tmpDir, ws := setupTestWorkspaceWithConfig(t, cfg)
+	project := ProjectPath("external/service")
+
+	receiver, err := ws.ReceiveProject(&ReceiveProjectRequest{Project: project, Snapshot: "abc123"})
+	if err != nil {
+		t.Fatalf("ReceiveProject() error = %v", err)
+	}
+
+	files := []struct {
+		path    string
+		mode    uint32
+		content string
+	}{
+		{path: "v1/b.proto", mode: 0100755, content: "syntax = \"proto3\";\n"},
+		{path: "v1/a.proto", mode: 0100644, content: "hello\n"},
+	}
+	for _, f := range files {
+		w, err := receiver.CreateFileWithMode(f.path, f.mode)
+		if err != nil {
+			t.Fatalf("CreateFileWithMode() error = %v", err)
+		}
+		if _, err := w.Write([]byte(f.content)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	}
+	if _, err := receiver.Finish(); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+
+	manifest, err := ws.GetProjectManifest(project)
+	if err != nil {
+		t.Fatalf("GetProjectManifest() error = %v", err)
+	}
+	want := []ManifestEntry{
+		{Path: "v1/a.proto", Hash: "ce013625030ba8dba906f756967f9e9ca394464a", Mode: "100644"},
+		{Path: "v1/b.proto", Hash: string(git.BlobHash([]byte(files[0].content))), Mode: "100755"},
+	}
+	if !reflect.DeepEqual(manifest.Files, want) {
+		t.Errorf("manifest files = %+v, want %+v", manifest.Files, want)
+	}
+
+	info, err := os.Stat(filepath.Join(tmpDir, "vendor-proto/external/service/v1/b.proto"))
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("file mode = %v, want 0755", info.Mode().Perm())
+	}
+
+	mismatched, err := ws.VerifyVendorIntegrity(project)
+	if err != nil {
+		t.Fatalf("VerifyVendorIntegrity() error = %v", err)
+	}
+	if len(mismatched) != 0 {
+		t.Errorf("VerifyVendorIntegrity() = %v, want none", mismatched)
+	}
+}
+
+func TestWorkspace_ReceiveProject_Staged(t *testing.T) {
+	cfg := &Config{
+		Service:     "test-service",
+		Directories: DirectoryConfig{Owned: "proto", Vendor: "vendor-proto"},
+	}
+	tmpDir, ws := setupTestWorkspaceWithConfig(t, cfg)
+	project := ProjectPath("external/service")
+	projectDir := filepath.Join(tmpDir, "vendor-proto/external/service")
+	createTestProject(t, tmpDir, "vendor-proto/external/service", map[string]string{
+		"v1/api.proto":     "old",
+		"v1/removed.proto": "removed",
+	})
+
+	// receive writes v1/api.proto and v2/new.proto and deletes v1/removed.proto
+	receive := func() *ProjectReceiver {
+		t.Helper()
+		receiver, err := ws.ReceiveProject(&ReceiveProjectRequest{Project: project, Snapshot: "abc123"})
+		if err != nil {
+			t.Fatalf("ReceiveProject() error = %v", err)
+		}
+		for _, rel := range []string{"v1/api.proto", "v2/new.proto"} {
+			w, err := receiver.CreateFile(rel)
+			if err != nil {
+				t.Fatalf("CreateFile() error = %v", err)
+			}
+			if _, err := w.Write([]byte("syntax = \"proto3\";")); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+		}
+		if err := receiver.DeleteFile("v1/removed.proto"); err != nil {
+			t.Fatalf("DeleteFile() error = %v", err)
+		}
+		return receiver
+	}
+	projectFiles := func() map[string]string {
+		t.Helper()
+		files, err := ws.ListVendorProjectFiles(project)
+		if err != nil {
+			t.Fatalf("ListVendorProjectFiles() error = %v", err)
+		}
+		got := make(map[string]string)
+		for _, f := range files {
+			data, err := os.ReadFile(f.AbsolutePath)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			got[f.Path] = string(data)
+		}
+		return got
+	}
+
+	// A receive that never finishes leaves the project as it was
+	receive()
+	want := map[string]string{"v1/api.proto": "old", "v1/removed.proto": "removed"}
+	if got := projectFiles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("files after an unfinished receive = %v, want %v", got, want)
+	}
+
+	stats, err := receive().Finish()
+	if err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	if stats.FilesChanged != 2 || stats.FilesDeleted != 1 {
+		t.Errorf("Finish() = %+v, want 2 changed and 1 deleted", stats)
+	}
+	want = map[string]string{"v1/api.proto": "syntax = \"proto3\";", "v2/new.proto": "syntax = \"proto3\";"}
+	if got := projectFiles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("files after Finish() = %v, want %v", got, want)
+	}
+	entries, err := os.ReadDir(filepath.Dir(projectDir))
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("vendor-proto/external holds %d entries after Finish(), want only the project", len(entries))
+	}
+}
+
+func TestWorkspace_ReceiveProject_Gitattributes(t *testing.T) {
+	custom := "*.proto -diff"
+	disabled := ""
+
+	tests := []struct {
+		name            string
+		gitattributes   *string
+		noGitattributes bool
+		want            string // Expected content; "" means no file
+	}{
+		{
+			name: "default content",
+			want: DefaultGitattributes,
+		},
+		{
+			name:          "custom content",
+			gitattributes: &custom,
+			want:          "*.proto -diff\n",
+		},
+		{
+			name:          "disabled in config",
+			gitattributes: &disabled,
+		},
+		{
+			name:            "disabled by request",
+			noGitattributes: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, _ := setupTestWorkspaceWithConfig(t, &Config{
+				Service:     "test-service",
+				Directories: DirectoryConfig{Owned: "proto", Vendor: "vendor-proto"},
+				Vendor:      VendorConfig{Gitattributes: tt.gitattributes},
+			})
+			// Reopen so the setting goes through protato.yaml
+			ws, err := Open(context.Background(), tmpDir)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+
+			receiver, err := ws.ReceiveProject(&ReceiveProjectRequest{
+				Project:         "external/service",
+				Snapshot:        "abc123",
+				NoGitattributes: tt.noGitattributes,
+			})
+			if err != nil {
+				t.Fatalf("ReceiveProject() error = %v", err)
+			}
+			w, err := receiver.CreateFile("v1/api.proto")
+			if err != nil {
+				t.Fatalf("CreateFile() error = %v", err)
+			}
+			w.Write([]byte("syntax = \"proto3\";"))
+			w.Close()
+			if _, err := receiver.Finish(); err != nil {
+				t.Fatalf("Finish() error = %v", err)
+			}
+
+			path := filepath.Join(tmpDir, "vendor-proto/external/service", constants.GitattributesName)
+			content, err := os.ReadFile(path)
+			if tt.want == "" {
+				if !os.IsNotExist(err) {
+					t.Errorf("%s exists, want no file", path)
+				}
+			} else if string(content) != tt.want {
+				t.Errorf("gitattributes = %q, want %q", content, tt.want)
+			}
+
+			// The file is never treated as a vendored proto
+			mismatched, err := ws.VerifyVendorIntegrity("external/service")
+			if err != nil {
+				t.Fatalf("VerifyVendorIntegrity() error = %v", err)
+			}
+			if len(mismatched) != 0 {
+				t.Errorf("VerifyVendorIntegrity() = %v, want none", mismatched)
+			}
+		})
+	}
+}
+
+func TestWorkspace_VerifyVendorIntegrity(t *testing.T) {
+	cfg := &Config{
+		Service: "test-service",
+		Directories: DirectoryConfig{
+			Owned:  "proto",
+			Vendor: "vendor-proto",
+		},
+	}
+	projectDir := "vendor-proto/external/service"
+
+	tests := []struct {
+		name   string
+		modify func(t *testing.T, dir string)
+		want   []string
+	}{
+		{
+			name:   "unchanged",
+			modify: func(t *testing.T, dir string) {},
+		},
+		{
+			name: "modified content",
+			modify: func(t *testing.T, dir string) {
+				os.WriteFile(filepath.Join(dir, "v1/api.proto"), []byte("changed"), 0644)
+			},
+			want: []string{"v1/api.proto"},
+		},
+		{
+			name: "changed mode",
+			modify: func(t *testing.T, dir string) {
+				os.Chmod(filepath.Join(dir, "v1/api.proto"), 0755)
+			},
+			want: []string{"v1/api.proto"},
+		},
+		{
+			name: "deleted and added",
+			modify: func(t *testing.T, dir string) {
+				os.Remove(filepath.Join(dir, "v1/api.proto"))
+				os.WriteFile(filepath.Join(dir, "v1/extra.proto"), []byte("extra"), 0644)
+			},
+			want: []string{"v1/api.proto", "v1/extra.proto"},
+		},
+		{
+			name: "modified without manifest",
+			modify: func(t *testing.T, dir string) {
+				os.Remove(filepath.Join(dir, constants.ManifestFileName))
+				os.WriteFile(filepath.Join(dir, "v1/api.proto"), []byte("changed"), 0644)
+			},
+			want: []string{"v1/api.proto"},
+		},
+		{
+			name: "deleted without manifest",
+			modify: func(t *testing.T, dir string) {
+				os.Remove(filepath.Join(dir, constants.ManifestFileName))
+				os.Remove(filepath.Join(dir, "v1/api.proto"))
+			},
+			want: []string{"v1/api.proto"},
+		},
+		{
+			name: "unchanged without manifest",
+			modify: func(t *testing.T, dir string) {
+				os.Remove(filepath.Join(dir, constants.ManifestFileName))
+			},
+		},
+		{
+			name: "modified without checksums",
+			modify: func(t *testing.T, dir string) {
+				os.WriteFile(filepath.Join(dir, constants.LockFileName), []byte("snapshot: abc123\n"), 0644)
+				os.WriteFile(filepath.Join(dir, "v1/api.proto"), []byte("changed"), 0644)
+			},
+			want: []string{"v1/api.proto"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, ws := setupTestWorkspaceWithConfig(t, cfg)
+			receiver, err := ws.ReceiveProject(&ReceiveProjectRequest{Project: "external/service", Snapshot: "abc123"})
+			if err != nil {
+				t.Fatalf("ReceiveProject() error = %v", err)
+			}
+			w, err := receiver.CreateFile("v1/api.proto")
+			if err != nil {
+				t.Fatalf("CreateFile() error = %v", err)
+			}
+			w.Write([]byte("syntax = \"proto3\";"))
+			w.Close()
+			if _, err := receiver.Finish(); err != nil {
+				t.Fatalf("Finish() error = %v", err)
+			}
+
+			tt.modify(t, filepath.Join(tmpDir, projectDir))
+
+			got, err := ws.VerifyVendorIntegrity("external/service")
+			if err != nil {
+				t.Fatalf("VerifyVendorIntegrity() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("VerifyVendorIntegrity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestWorkspace_ReceivedProjects(t *testing.T) {
 	cfg := &Config{
 		Service:      "test-service",
@@ -805,6 +1544,204 @@ func TestWorkspace_GetProjectLock(t *testing.T) {
 	}
 }
 
+func TestWorkspace_SetProjectLock(t *testing.T) {
+	tmpDir, ws := setupTestWorkspaceWithConfig(t, &Config{Service: "test-service", Directories: DefaultDirectoryConfig()})
+
+	if err := ws.SetProjectLock(ProjectPath("external/service"), &LockFile{Snapshot: "def456"}); err == nil {
+		t.Error("SetProjectLock() succeeded for a project that was never received")
+	}
+
+	createTestProject(t, tmpDir, "vendor-proto/external/service", map[string]string{
+		"v1/api.proto": "syntax = \"proto3\";",
+		"protato.lock": "snapshot: abc123\n",
+	})
+	if err := ws.SetProjectLock(ProjectPath("external/service"), &LockFile{Snapshot: "def456", Branch: "next"}); err != nil {
+		t.Fatalf("SetProjectLock() error = %v", err)
+	}
+
+	lock, err := ws.GetProjectLock(ProjectPath("external/service"))
+	if err != nil {
+		t.Fatalf("GetProjectLock() error = %v", err)
+	}
+	if lock.Snapshot != "def456" || lock.Branch != "next" {
+		t.Errorf("GetProjectLock() = %+v, want snapshot def456 on branch next", lock)
+	}
+	if got, _ := os.ReadFile(filepath.Join(tmpDir, "vendor-proto/external/service/v1/api.proto")); string(got) != "syntax = \"proto3\";" {
+		t.Errorf("SetProjectLock() changed a received file: %q", got)
+	}
+}
+
+func TestWorkspace_ReceiveProject_PinnedRef(t *testing.T) {
+	files := map[string]string{"v1/api.proto": "bf7901a1fd23ec4cee4e5e86db1e2586f5c6c623526319eb2ff68b74d15edad3"}
+	tests := []struct {
+		name string
+		ref  string
+		want LockFile
+	}{
+		{name: "snapshot only", want: LockFile{Snapshot: "abc123", Files: files}},
+		{name: "pinned to tag", ref: "v1.2.0", want: LockFile{Snapshot: "abc123", Ref: "v1.2.0", ResolvedCommit: "abc123", Files: files}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ws := setupTestWorkspaceWithConfig(t, &Config{Service: "test-service", Directories: DefaultDirectoryConfig()})
+			project := ProjectPath("external/service")
+
+			receiver, err := ws.ReceiveProject(&ReceiveProjectRequest{Project: project, Snapshot: "abc123", Ref: tt.ref})
+			if err != nil {
+				t.Fatalf("ReceiveProject() error = %v", err)
+			}
+			writer, err := receiver.CreateFile("v1/api.proto")
+			if err != nil {
+				t.Fatalf("CreateFile() error = %v", err)
+			}
+			writer.Write([]byte("syntax = \"proto3\";"))
+			writer.Close()
+			if _, err := receiver.Finish(); err != nil {
+				t.Fatalf("Finish() error = %v", err)
+			}
+
+			lock, err := ws.GetProjectLock(project)
+			if err != nil {
+				t.Fatalf("GetProjectLock() error = %v", err)
+			}
+			if !reflect.DeepEqual(*lock, tt.want) {
+				t.Errorf("GetProjectLock() = %+v, want %+v", *lock, tt.want)
+			}
+
+			received, err := ws.ReceivedProjects(context.Background())
+			if err != nil {
+				t.Fatalf("ReceivedProjects() error = %v", err)
+			}
+			if len(received) != 1 || received[0].Ref != tt.want.Ref || received[0].ResolvedCommit != tt.want.ResolvedCommit {
+				t.Errorf("ReceivedProjects() = %+v, want ref %q resolved to %q", received, tt.want.Ref, tt.want.ResolvedCommit)
+			}
+		})
+	}
+}
+
+// mockProjectLookup serves project tree hashes by snapshot.
+type mockProjectLookup struct {
+	current  git.Hash                                       // Snapshot used for lookups at ""
+	branches map[string]git.Hash                            // Branch tips
+	trees    map[git.Hash]map[registry.ProjectPath]git.Hash // Project tree hashes by snapshot
+}
+
+func (m *mockProjectLookup) EnsureSnapshotAvailable(context.Context, git.Hash) error { return nil }
+
+func (m *mockProjectLookup) BranchSnapshot(_ context.Context, branch string) (git.Hash, error) {
+	if tip, ok := m.branches[branch]; ok {
+		return tip, nil
+	}
+	return "", fmt.Errorf("unknown branch %s", branch)
+}
+
+func (m *mockProjectLookup) LookupProject(_ context.Context, req *registry.LookupProjectRequest) (*registry.LookupProjectResponse, error) {
+	snapshot := req.Snapshot
+	if snapshot == "" {
+		snapshot = m.current
+	}
+	// Fall back to parent projects, as the registry does
+	for p := req.Path; p != "."; p = path.Dir(p) {
+		if tree, ok := m.trees[snapshot][registry.ProjectPath(p)]; ok {
+			return &registry.LookupProjectResponse{Project: &registry.Project{Path: registry.ProjectPath(p)}, Snapshot: snapshot, ProjectHash: tree}, nil
+		}
+	}
+	return nil, errors.ErrNotFound
+}
+
+func TestWorkspace_IsReceivedProjectStale(t *testing.T) {
+	tests := []struct {
+		name         string
+		lock         string
+		lookup       *mockProjectLookup
+		wantStale    bool
+		wantSnapshot git.Hash
+		wantRemoved  bool
+	}{
+		{
+			name:   "locked at the current snapshot",
+			lock:   "snapshot: s1\n",
+			lookup: &mockProjectLookup{current: "s1", trees: map[git.Hash]map[registry.ProjectPath]git.Hash{"s1": {"team/service": "t1"}}},
+		},
+		{
+			name: "registry moved but project unchanged",
+			lock: "snapshot: s1\n",
+			lookup: &mockProjectLookup{current: "s2", trees: map[git.Hash]map[registry.ProjectPath]git.Hash{
+				"s1": {"team/service": "t1"},
+				"s2": {"team/service": "t1", "team/other": "t9"},
+			}},
+		},
+		{
+			name: "project changed",
+			lock: "snapshot: s1\n",
+			lookup: &mockProjectLookup{current: "s2", trees: map[git.Hash]map[registry.ProjectPath]git.Hash{
+				"s1": {"team/service": "t1"},
+				"s2": {"team/service": "t2"},
+			}},
+			wantStale:    true,
+			wantSnapshot: "s2",
+		},
+		{
+			name: "project removed",
+			lock: "snapshot: s1\n",
+			lookup: &mockProjectLookup{current: "s2", trees: map[git.Hash]map[registry.ProjectPath]git.Hash{
+				"s1": {"team/service": "t1"},
+				"s2": {"team/other": "t9"},
+			}},
+			wantRemoved: true,
+		},
+		{
+			name: "replaced by a parent project",
+			lock: "snapshot: s1\n",
+			lookup: &mockProjectLookup{current: "s2", trees: map[git.Hash]map[registry.ProjectPath]git.Hash{
+				"s1": {"team/service": "t1"},
+				"s2": {"team": "t3"},
+			}},
+			wantRemoved: true,
+		},
+		{
+			name: "tracked branch moved",
+			lock: "snapshot: s1\nbranch: next\n",
+			lookup: &mockProjectLookup{current: "s1", branches: map[string]git.Hash{"next": "b2"}, trees: map[git.Hash]map[registry.ProjectPath]git.Hash{
+				"s1": {"team/service": "t1"},
+				"b2": {"team/service": "t2"},
+			}},
+			wantStale:    true,
+			wantSnapshot: "b2",
+		},
+		{
+			name:   "received from a local directory",
+			lock:   "snapshot: " + constants.LocalSnapshotPrefix + "abc\n",
+			lookup: &mockProjectLookup{current: "s2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, ws := setupTestWorkspaceWithConfig(t, &Config{Service: "test-service", Directories: DefaultDirectoryConfig()})
+			createTestProject(t, tmpDir, "vendor-proto/team/service", map[string]string{
+				"v1/api.proto": "syntax = \"proto3\";",
+				"protato.lock": tt.lock,
+			})
+
+			stale, snapshot, err := ws.IsReceivedProjectStale(context.Background(), "team/service", tt.lookup)
+			if tt.wantRemoved {
+				if !stderrors.Is(err, errors.ErrNotFound) {
+					t.Fatalf("IsReceivedProjectStale() error = %v, want ErrNotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("IsReceivedProjectStale() error = %v", err)
+			}
+			if stale != tt.wantStale || snapshot != tt.wantSnapshot {
+				t.Errorf("IsReceivedProjectStale() = %v, %q, want %v, %q", stale, snapshot, tt.wantStale, tt.wantSnapshot)
+			}
+		})
+	}
+}
+
 func TestWorkspace_DeleteFile(t *testing.T) {
 	cfg := &Config{
 		Service: "test-service",
@@ -835,6 +1772,9 @@ func TestWorkspace_DeleteFile(t *testing.T) {
 	if err != nil {
 		t.Fatalf("DeleteFile() error = %v", err)
 	}
+	if _, err := receiver.Finish(); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
 
 	// Verify file was deleted
 	expectedPath := tmpDir + "/vendor-proto/external/service/v1/api.proto"
@@ -843,6 +1783,69 @@ func TestWorkspace_DeleteFile(t *testing.T) {
 	}
 }
 
+func TestWorkspace_CleanVendor(t *testing.T) {
+	tests := []struct {
+		name    string
+		dirs    DirectoryConfig
+		wantErr error
+	}{
+		{
+			name: "separate directories",
+			dirs: DirectoryConfig{Owned: "proto", Vendor: "vendor-proto"},
+		},
+		{
+			name: "owned at root",
+			dirs: DirectoryConfig{Owned: ".", Vendor: "vendor-proto"},
+		},
+		{
+			name:    "vendor contains owned",
+			dirs:    DirectoryConfig{Owned: "protos/owned", Vendor: "protos"},
+			wantErr: errors.ErrVendorContainsOwned,
+		},
+		{
+			name:    "vendor is owned",
+			dirs:    DirectoryConfig{Owned: "proto", Vendor: "proto"},
+			wantErr: errors.ErrVendorContainsOwned,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, ws := setupTestWorkspaceWithConfig(t, &Config{Service: "test-service", Directories: tt.dirs})
+			ownedFile := filepath.Join(tmpDir, tt.dirs.Owned, "team/service/api.proto")
+			createTestProject(t, tmpDir, filepath.Join(tt.dirs.Owned, "team/service"), map[string]string{
+				"api.proto": "syntax = \"proto3\";",
+			})
+			vendorProject := filepath.Join(tmpDir, tt.dirs.Vendor, "external/service")
+			createTestProject(t, tmpDir, filepath.Join(tt.dirs.Vendor, "external/service"), map[string]string{
+				"v1/api.proto": "syntax = \"proto3\";",
+				"protato.lock": "snapshot: abc123",
+			})
+
+			err := ws.CleanVendor()
+			if err != tt.wantErr {
+				t.Fatalf("CleanVendor() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if !fileExists(ownedFile) {
+				t.Errorf("owned file %s was removed", ownedFile)
+			}
+			if tt.wantErr != nil {
+				if !fileExists(vendorProject) {
+					t.Errorf("vendor project removed despite error")
+				}
+				return
+			}
+			if fileExists(vendorProject) {
+				t.Errorf("vendor project %s was not removed", vendorProject)
+			}
+			if !fileExists(filepath.Join(tmpDir, tt.dirs.Vendor)) {
+				t.Errorf("vendor directory itself was removed")
+			}
+		})
+	}
+}
+
 func TestMatchesPattern(t *testing.T) {
 	cfg := &Config{
 		Service: "test-service",
@@ -923,6 +1926,76 @@ func TestWorkspace_ListVendorProjectFiles(t *testing.T) {
 	}
 }
 
+func TestWorkspace_WalkersSkipSpecialFiles(t *testing.T) {
+	cfg := &Config{
+		Service: "test-service",
+		Directories: DirectoryConfig{
+			Owned:  "proto",
+			Vendor: "vendor-proto",
+		},
+		Projects:      []string{"team/service"},
+		MetadataFiles: []string{"generated.proto"},
+	}
+	tmpDir, ws := setupTestWorkspaceWithConfig(t, cfg)
+
+	projectFiles := func(metadata ...string) map[string]string {
+		files := map[string]string{
+			"v1/api.proto":       "syntax = \"proto3\";",
+			"v1/generated.proto": "syntax = \"proto3\";",
+		}
+		for _, name := range metadata {
+			files[name] = ""
+		}
+		return files
+	}
+	createTestProject(t, tmpDir, "proto/team/service", projectFiles(constants.ProjectMetaFile, constants.GitattributesName))
+	createTestProject(t, tmpDir, "vendor-proto/external/service", projectFiles(constants.ManifestFileName, constants.GitattributesName))
+	createTestProject(t, tmpDir, "proto/stray", map[string]string{"generated.proto": "syntax = \"proto3\";"})
+
+	wantFiles := []string{"v1/api.proto"}
+	filePaths := func(files []ProjectFile) []string {
+		var paths []string
+		for _, f := range files {
+			paths = append(paths, f.Path)
+		}
+		return paths
+	}
+
+	owned, err := ws.ListOwnedProjectFiles("team/service")
+	if err != nil {
+		t.Fatalf("ListOwnedProjectFiles() error = %v", err)
+	}
+	if got := filePaths(owned); !reflect.DeepEqual(got, wantFiles) {
+		t.Errorf("ListOwnedProjectFiles() = %v, want %v", got, wantFiles)
+	}
+
+	vendor, err := ws.ListVendorProjectFiles("external/service")
+	if err != nil {
+		t.Fatalf("ListVendorProjectFiles() error = %v", err)
+	}
+	if got := filePaths(vendor); !reflect.DeepEqual(got, wantFiles) {
+		t.Errorf("ListVendorProjectFiles() = %v, want %v", got, wantFiles)
+	}
+
+	dir, err := ws.ListDirFiles(filepath.Join(tmpDir, "proto", "team", "service"))
+	if err != nil {
+		t.Fatalf("ListDirFiles() error = %v", err)
+	}
+	if got := filePaths(dir); !reflect.DeepEqual(got, wantFiles) {
+		t.Errorf("ListDirFiles() = %v, want %v", got, wantFiles)
+	}
+
+	orphaned, err := ws.OrphanedFiles(context.Background())
+	if err != nil {
+		t.Fatalf("OrphanedFiles() error = %v", err)
+	}
+	for _, p := range orphaned {
+		if filepath.Base(p) == "generated.proto" {
+			t.Errorf("OrphanedFiles() reported special file %s", p)
+		}
+	}
+}
+
 func TestWorkspace_GetRegistryPath(t *testing.T) {
 	cfg := &Config{
 		Service: "test-service",
@@ -1039,7 +2112,7 @@ func TestWorkspace_receivedProjectsToMap(t *testing.T) {
 		{Project: "external/service2"},
 	}
 
-	m := ws.receivedProjectsToMap(projects)
+	m := ws.receivedProjectsToMap(projects, VendorLayoutNamespaced)
 
 	if !m["external/service1"] {
 		t.Error("receivedProjectsToMap() missing external/service1")
@@ -0,0 +1,111 @@
+package protoc
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rahulagarwal0605/protato/internal/git"
+)
+
+// blobCache keeps file contents by Git blob hash, in memory and, when dir is set, on disk.
+// A blob's content never changes, so entries are only ever looked up by hash, never by
+// path, and are never invalidated. The on-disk store is shared by every resolver of the
+// registry cache and trimmed, least recently used first, when that cache is compacted.
+type blobCache struct {
+	dir string // On-disk store; "" keeps blobs in memory only
+
+	mu    sync.Mutex
+	blobs map[git.Hash][]byte
+}
+
+// newBlobCache creates a blob cache backed by dir, or by memory only if dir is empty.
+func newBlobCache(dir string) *blobCache {
+	return &blobCache{dir: dir, blobs: make(map[git.Hash][]byte)}
+}
+
+// get returns the content of the blob, reading it from disk if it is not in memory.
+// A file on disk whose content does not hash to the blob hash is treated as a miss.
+func (c *blobCache) get(h git.Hash) ([]byte, bool) {
+	c.mu.Lock()
+	data, ok := c.blobs[h]
+	c.mu.Unlock()
+	if ok {
+		return data, true
+	}
+	if c.dir == "" {
+		return nil, false
+	}
+
+	blobPath := c.path(h)
+	data, err := os.ReadFile(blobPath)
+	if err != nil || blobHash(h, data) != h {
+		return nil, false
+	}
+	// Mark the blob as used, so trimming the store keeps it over unused ones
+	now := time.Now()
+	_ = os.Chtimes(blobPath, now, now)
+	c.mu.Lock()
+	c.blobs[h] = data
+	c.mu.Unlock()
+	return data, true
+}
+
+// put stores the content of the blob in memory and on disk.
+// The disk write goes through a temporary file so concurrent readers and writers never see a partial blob.
+func (c *blobCache) put(h git.Hash, data []byte) error {
+	c.mu.Lock()
+	c.blobs[h] = data
+	c.mu.Unlock()
+	if c.dir == "" {
+		return nil
+	}
+
+	blobPath := c.path(h)
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		return fmt.Errorf("create blob dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(blobPath), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create blob file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write blob %s: %w", h.Short(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write blob %s: %w", h.Short(), err)
+	}
+	if err := os.Rename(tmp.Name(), blobPath); err != nil {
+		return fmt.Errorf("write blob %s: %w", h.Short(), err)
+	}
+	return nil
+}
+
+// path returns where the blob is stored on disk, fanned out by the first two hex digits like Git's object store.
+func (c *blobCache) path(h git.Hash) string {
+	s := string(h)
+	if len(s) <= 2 {
+		return filepath.Join(c.dir, s)
+	}
+	return filepath.Join(c.dir, s[:2], s[2:])
+}
+
+// blobHash returns the Git blob hash of data, using SHA-256 if like is a SHA-256 hash and SHA-1 otherwise.
+func blobHash(like git.Hash, data []byte) git.Hash {
+	var hasher hash.Hash
+	if len(like) == sha256.Size*2 {
+		hasher = sha256.New()
+	} else {
+		hasher = sha1.New()
+	}
+	fmt.Fprintf(hasher, "blob %d\x00", len(data))
+	hasher.Write(data)
+	return git.Hash(fmt.Sprintf("%x", hasher.Sum(nil)))
+}
@@ -0,0 +1,143 @@
+package protoc
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/internal/registry"
+)
+
+func TestBlobHash(t *testing.T) {
+	// git hash-object of "hello\n"
+	if got := blobHash("", []byte("hello\n")); got != "ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("blobHash() = %s, want ce013625030ba8dba906f756967f9e9ca394464a", got)
+	}
+	if got := blobHash(git.Hash(make([]byte, 64)), []byte("hello\n")); len(got) != 64 {
+		t.Errorf("blobHash() for a SHA-256 hash = %s, want 64 hex digits", got)
+	}
+}
+
+func TestBlobCache(t *testing.T) {
+	content := []byte("syntax = \"proto3\";")
+	hash := blobHash("", content)
+
+	tests := []struct {
+		name    string
+		onDisk  []byte // Replaces the stored blob before a fresh cache reads it
+		wantHit bool
+	}{
+		{name: "stored blob", wantHit: true},
+		{name: "corrupt blob", onDisk: []byte("syntax = \"proto2\";"), wantHit: false},
+		{name: "truncated blob", onDisk: content[:6], wantHit: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := newBlobCache(dir).put(hash, content); err != nil {
+				t.Fatalf("put() error = %v", err)
+			}
+			cache := newBlobCache(dir)
+			if tt.onDisk != nil {
+				if err := os.WriteFile(cache.path(hash), tt.onDisk, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, ok := cache.get(hash)
+			if ok != tt.wantHit {
+				t.Fatalf("get() hit = %v, want %v", ok, tt.wantHit)
+			}
+			if ok && string(got) != string(content) {
+				t.Errorf("get() = %q, want %q", got, content)
+			}
+		})
+	}
+}
+
+func TestBlobCache_MarksUse(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("syntax = \"proto3\";")
+	hash := blobHash("", content)
+	if err := newBlobCache(dir).put(hash, content); err != nil {
+		t.Fatalf("put() error = %v", err)
+	}
+	cache := newBlobCache(dir)
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(cache.path(hash), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := cache.get(hash); !ok {
+		t.Fatal("get() missed a stored blob")
+	}
+	info, err := os.Stat(cache.path(hash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().After(old) {
+		t.Errorf("get() left the blob modified at %v, want it marked as used", info.ModTime())
+	}
+}
+
+func TestBlobCache_MemoryOnly(t *testing.T) {
+	cache := newBlobCache("")
+	if _, ok := cache.get("abc123"); ok {
+		t.Fatal("get() hit on an empty cache")
+	}
+	if err := cache.put("abc123", []byte("data")); err != nil {
+		t.Fatalf("put() error = %v", err)
+	}
+	if got, ok := cache.get("abc123"); !ok || string(got) != "data" {
+		t.Errorf("get() = %q, %v, want %q, true", got, ok, "data")
+	}
+}
+
+func TestRegistryResolver_ReadFileCachesByHash(t *testing.T) {
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	content := []byte("syntax = \"proto3\";")
+	hash := blobHash("", content)
+
+	reads := 0
+	cache := &mockCache{
+		blobDir: t.TempDir(),
+		readProjectFileFunc: func(ctx context.Context, file registry.ProjectFile, w io.Writer) error {
+			reads++
+			_, err := w.Write(content)
+			return err
+		},
+	}
+
+	files := []registry.ProjectFile{
+		{Project: "team/a", Path: "v1/api.proto", Hash: hash},
+		{Project: "team/b", Path: "v1/copy.proto", Hash: hash}, // Same content at another path
+	}
+	first := NewRegistryResolver(ctx, cache, "abc123")
+	for _, file := range files {
+		got, err := first.readFile(ctx, file)
+		if err != nil {
+			t.Fatalf("readFile() error = %v", err)
+		}
+		if string(got) != string(content) {
+			t.Errorf("readFile() = %q, want %q", got, content)
+		}
+	}
+	if reads != 1 {
+		t.Errorf("registry read %d times, want 1", reads)
+	}
+
+	// A later run at another snapshot finds the blob on disk
+	second := NewRegistryResolver(ctx, cache, "def456")
+	if _, err := second.readFile(ctx, files[0]); err != nil {
+		t.Fatalf("readFile() error = %v", err)
+	}
+	if reads != 1 {
+		t.Errorf("registry read %d times after a new resolver, want 1", reads)
+	}
+}
@@ -0,0 +1,56 @@
+package protoc
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/linker"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/registry"
+)
+
+// DetectBreaking compiles the proto files of projects at both registry snapshots and reports
+// changes in newSnapshot that break consumers of oldSnapshot: the wire incompatibilities of
+// CheckCompatibility plus removed messages, enums, fields and RPCs, and any field type change.
+// Elements are matched by fully-qualified name, so moving them between files is allowed.
+// Projects missing from oldSnapshot are new and have nothing to break.
+func DetectBreaking(ctx context.Context, cache registry.CacheInterface, oldSnapshot, newSnapshot git.Hash, projects []registry.ProjectPath) ([]CompatIssue, error) {
+	oldFiles, err := compileSnapshot(ctx, cache, oldSnapshot, projects)
+	if err != nil {
+		return nil, fmt.Errorf("compile %s: %w", oldSnapshot.Short(), err)
+	}
+	if len(oldFiles) == 0 {
+		return nil, nil
+	}
+	newFiles, err := compileSnapshot(ctx, cache, newSnapshot, projects)
+	if err != nil {
+		return nil, fmt.Errorf("compile %s: %w", newSnapshot.Short(), err)
+	}
+	return compareFiles(oldFiles, newFiles, true), nil
+}
+
+// compileSnapshot compiles the proto files of projects as stored in the registry at snapshot.
+// Imports resolve against the other projects of the same snapshot.
+func compileSnapshot(ctx context.Context, cache registry.CacheInterface, snapshot git.Hash, projects []registry.ProjectPath) (linker.Files, error) {
+	var protoFiles []string
+	for _, project := range projects {
+		res, err := cache.ListProjectFiles(ctx, &registry.ListProjectFilesRequest{Project: project, Snapshot: snapshot})
+		if err != nil {
+			return nil, fmt.Errorf("list files %s: %w", project, err)
+		}
+		for _, file := range res.Files {
+			if strings.HasSuffix(file.Path, constants.ProtoFileExt) {
+				protoFiles = append(protoFiles, path.Join(string(project), file.Path))
+			}
+		}
+	}
+	if len(protoFiles) == 0 {
+		return nil, nil
+	}
+	return compileForAnalysis(ctx, NewRegistryResolver(ctx, cache, snapshot), protoFiles, protocompile.SourceInfoNone)
+}
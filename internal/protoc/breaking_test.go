@@ -0,0 +1,204 @@
+package protoc
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/linker"
+	"github.com/rs/zerolog"
+
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/internal/registry"
+)
+
+// breakingFiles compiles files, keyed by import path.
+func breakingFiles(t *testing.T, files map[string]string) linker.Files {
+	t.Helper()
+	contents := make(map[string][]byte, len(files))
+	var paths []string
+	for p, body := range files {
+		contents[p] = []byte("syntax = \"proto3\";\npackage team;\n" + body)
+		paths = append(paths, p)
+	}
+	compiled, err := compileForAnalysis(context.Background(), NewMemoryResolver(context.Background(), contents), paths, protocompile.SourceInfoNone)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	return compiled
+}
+
+func TestCompareFiles_Breaking(t *testing.T) {
+	tests := []struct {
+		name        string
+		old         map[string]string
+		new         map[string]string
+		wantRule    string
+		wantElement string
+	}{
+		{
+			name: "unchanged",
+			old:  map[string]string{"team/api.proto": "message User { string name = 1; }"},
+			new:  map[string]string{"team/api.proto": "message User { string name = 1; }"},
+		},
+		{
+			name: "field renamed",
+			old:  map[string]string{"team/api.proto": "message User { string name = 1; }"},
+			new:  map[string]string{"team/api.proto": "message User { string full_name = 1; }"},
+		},
+		{
+			name: "message moved to another file",
+			old:  map[string]string{"team/api.proto": "message User { string name = 1; }"},
+			new: map[string]string{
+				"team/api.proto":  "import \"team/user.proto\";\nmessage Req { User user = 1; }",
+				"team/user.proto": "message User { string name = 1; }",
+			},
+		},
+		{
+			name:        "message removed",
+			old:         map[string]string{"team/api.proto": "message User {}\nmessage Group {}"},
+			new:         map[string]string{"team/api.proto": "message User {}"},
+			wantRule:    CompatRuleMessageRemoved,
+			wantElement: "team.Group",
+		},
+		{
+			name:        "nested message removed",
+			old:         map[string]string{"team/api.proto": "message User { message Address {} }"},
+			new:         map[string]string{"team/api.proto": "message User {}"},
+			wantRule:    CompatRuleMessageRemoved,
+			wantElement: "team.User.Address",
+		},
+		{
+			name:        "enum removed",
+			old:         map[string]string{"team/api.proto": "enum Role { ROLE_UNSPECIFIED = 0; }"},
+			new:         map[string]string{"team/api.proto": ""},
+			wantRule:    CompatRuleEnumRemoved,
+			wantElement: "team.Role",
+		},
+		{
+			name:        "field removed",
+			old:         map[string]string{"team/api.proto": "message User { string name = 1; int32 age = 2; }"},
+			new:         map[string]string{"team/api.proto": "message User { string name = 1; reserved 2; }"},
+			wantRule:    CompatRuleFieldRemoved,
+			wantElement: "team.User.age",
+		},
+		{
+			name:        "field number changed",
+			old:         map[string]string{"team/api.proto": "message User { string name = 1; }"},
+			new:         map[string]string{"team/api.proto": "message User { string name = 2; }"},
+			wantRule:    CompatRuleFieldNumberChanged,
+			wantElement: "team.User.name",
+		},
+		{
+			name:        "field type changed",
+			old:         map[string]string{"team/api.proto": "message User { string id = 1; }"},
+			new:         map[string]string{"team/api.proto": "message User { int64 id = 1; }"},
+			wantRule:    CompatRuleFieldTypeChanged,
+			wantElement: "team.User.id",
+		},
+		{
+			name:        "field made repeated",
+			old:         map[string]string{"team/api.proto": "message User { string tag = 1; }"},
+			new:         map[string]string{"team/api.proto": "message User { repeated string tag = 1; }"},
+			wantRule:    CompatRuleFieldCardinality,
+			wantElement: "team.User.tag",
+		},
+		{
+			name:        "map value type changed",
+			old:         map[string]string{"team/api.proto": "message User { map<string, string> labels = 1; }"},
+			new:         map[string]string{"team/api.proto": "message User { map<string, int32> labels = 1; }"},
+			wantRule:    CompatRuleFieldTypeChanged,
+			wantElement: "team.User.LabelsEntry.value",
+		},
+		{
+			name:        "reserved number reused",
+			old:         map[string]string{"team/api.proto": "message User { reserved 2; }"},
+			new:         map[string]string{"team/api.proto": "message User { string email = 2; }"},
+			wantRule:    CompatRuleReservedReused,
+			wantElement: "team.User.email",
+		},
+		{
+			name:        "rpc removed",
+			old:         map[string]string{"team/api.proto": "message M {}\nservice Users { rpc Get(M) returns (M); rpc List(M) returns (M); }"},
+			new:         map[string]string{"team/api.proto": "message M {}\nservice Users { rpc Get(M) returns (M); }"},
+			wantRule:    CompatRuleRPCRemoved,
+			wantElement: "team.Users.List",
+		},
+		{
+			name:        "service removed",
+			old:         map[string]string{"team/api.proto": "message M {}\nservice Users { rpc Get(M) returns (M); }"},
+			new:         map[string]string{"team/api.proto": "message M {}"},
+			wantRule:    CompatRuleRPCRemoved,
+			wantElement: "team.Users.Get",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := compareFiles(breakingFiles(t, tt.old), breakingFiles(t, tt.new), true)
+			if tt.wantRule == "" {
+				if len(changes) != 0 {
+					t.Errorf("compareFiles() = %v, want no changes", changes)
+				}
+				return
+			}
+			if len(changes) != 1 {
+				t.Fatalf("compareFiles() = %v, want one %s change", changes, tt.wantRule)
+			}
+			got := changes[0]
+			if got.Rule != tt.wantRule || got.Element != tt.wantElement || got.File != "team/api.proto" {
+				t.Errorf("change = %+v, want rule %s at team/api.proto %s", got, tt.wantRule, tt.wantElement)
+			}
+		})
+	}
+}
+
+// snapshotCache serves the files of one project per snapshot.
+func snapshotCache(project registry.ProjectPath, snapshots map[git.Hash]map[string]string) *mockCache {
+	return &mockCache{
+		lookupProjectFunc: func(ctx context.Context, req *registry.LookupProjectRequest) (*registry.LookupProjectResponse, error) {
+			if !strings.HasPrefix(req.Path, string(project)+"/") {
+				return nil, nil
+			}
+			return &registry.LookupProjectResponse{Project: &registry.Project{Path: project}}, nil
+		},
+		listProjectFilesFunc: func(ctx context.Context, req *registry.ListProjectFilesRequest) (*registry.ListProjectFilesResponse, error) {
+			res := &registry.ListProjectFilesResponse{}
+			for name := range snapshots[req.Snapshot] {
+				res.Files = append(res.Files, registry.ProjectFile{Snapshot: req.Snapshot, Project: project, Path: name, Hash: git.Hash(name)})
+			}
+			return res, nil
+		},
+		readProjectFileFunc: func(ctx context.Context, file registry.ProjectFile, w io.Writer) error {
+			_, err := io.WriteString(w, "syntax = \"proto3\";\npackage team;\n"+snapshots[file.Snapshot][file.Path])
+			return err
+		},
+	}
+}
+
+func TestDetectBreaking(t *testing.T) {
+	log := zerolog.New(io.Discard)
+	ctx := logger.WithLogger(context.Background(), &log)
+	project := registry.ProjectPath("team/api")
+	cache := snapshotCache(project, map[git.Hash]map[string]string{
+		"old": {"v1/api.proto": "message User { string name = 1; int32 age = 2; }"},
+		"new": {"v1/api.proto": "message User { string name = 1; }"},
+	})
+
+	changes, err := DetectBreaking(ctx, cache, "old", "new", []registry.ProjectPath{project})
+	if err != nil {
+		t.Fatalf("DetectBreaking() error = %v", err)
+	}
+	if len(changes) != 1 || changes[0].Rule != CompatRuleFieldRemoved || changes[0].File != "team/api/v1/api.proto" {
+		t.Errorf("DetectBreaking() = %v, want field-removed in team/api/v1/api.proto", changes)
+	}
+
+	// A project that is not in the old snapshot is new and cannot break anything
+	changes, err = DetectBreaking(ctx, cache, "empty", "new", []registry.ProjectPath{project})
+	if err != nil || len(changes) != 0 {
+		t.Errorf("DetectBreaking() for a new project = %v, %v, want no changes", changes, err)
+	}
+}
@@ -0,0 +1,160 @@
+package protoc
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+)
+
+// bufDirCache holds the last buf.yaml scan of each workspace root, so repeated
+// validations (as in watch mode) don't walk the whole workspace again.
+var bufDirCache = struct {
+	sync.Mutex
+	scans map[string]*bufDirScan
+}{scans: make(map[string]*bufDirScan)}
+
+// bufDirScan is the result of walking a workspace for buf.yaml files.
+type bufDirScan struct {
+	dirs   []string             // Directories containing a buf.yaml with deps
+	stamps map[string]fileStamp // Every walked directory and buf.yaml, to detect changes
+}
+
+// fileStamp is the cheap fingerprint of a file or directory.
+// Adding or removing an entry changes its directory's modification time.
+type fileStamp struct {
+	modTime int64 // Unix nanoseconds
+	size    int64
+}
+
+// findAllBufYamlWithDeps searches for all buf.yaml files with deps in the workspace.
+// Returns a list of directories containing buf.yaml with deps.
+// The result is cached per workspace root and reused until a walked directory or
+// buf.yaml changes, which a stat of each is enough to tell.
+func findAllBufYamlWithDeps(workspaceRoot string) []string {
+	bufDirCache.Lock()
+	scan, ok := bufDirCache.scans[workspaceRoot]
+	bufDirCache.Unlock()
+
+	if !ok || !scan.fresh() {
+		scan = scanBufDirs(workspaceRoot)
+		bufDirCache.Lock()
+		bufDirCache.scans[workspaceRoot] = scan
+		bufDirCache.Unlock()
+	}
+	return slices.Clone(scan.dirs)
+}
+
+// scanBufDirs walks the workspace for buf.yaml files, one goroutine per top-level directory.
+func scanBufDirs(root string) *bufDirScan {
+	scan := &bufDirScan{stamps: make(map[string]fileStamp)}
+	info, err := os.Stat(root)
+	if err != nil {
+		return scan
+	}
+	scan.stamps[root] = stampOf(info)
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return scan
+	}
+
+	subs := make([]*bufDirScan, len(entries))
+	var wg sync.WaitGroup
+	for i, entry := range entries {
+		path := filepath.Join(root, entry.Name())
+		if !entry.IsDir() {
+			scan.visitFile(path, entry)
+			continue
+		}
+		if skipBufScanDir(entry.Name()) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			subs[i] = walkBufDirs(path)
+		}()
+	}
+	wg.Wait()
+
+	for _, sub := range subs {
+		if sub == nil {
+			continue
+		}
+		scan.dirs = append(scan.dirs, sub.dirs...)
+		for path, stamp := range sub.stamps {
+			scan.stamps[path] = stamp
+		}
+	}
+	slices.Sort(scan.dirs)
+	return scan
+}
+
+// walkBufDirs walks one directory tree for buf.yaml files.
+func walkBufDirs(dir string) *bufDirScan {
+	scan := &bufDirScan{stamps: make(map[string]fileStamp)}
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip errors
+		}
+		if d.IsDir() {
+			if path != dir && skipBufScanDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			if info, err := d.Info(); err == nil {
+				scan.stamps[path] = stampOf(info)
+			}
+			return nil
+		}
+		scan.visitFile(path, d)
+		return nil
+	})
+	return scan
+}
+
+// visitFile records a buf.yaml and, if it has a deps section, its directory.
+func (s *bufDirScan) visitFile(path string, d fs.DirEntry) {
+	if d.Name() != constants.BufConfigFileName {
+		return
+	}
+	info, err := d.Info()
+	if err != nil {
+		return
+	}
+	s.stamps[path] = stampOf(info)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	if strings.Contains(string(content), "deps:") {
+		s.dirs = append(s.dirs, filepath.Dir(path))
+	}
+}
+
+// fresh reports whether nothing the scan walked has changed since.
+func (s *bufDirScan) fresh() bool {
+	for path, stamp := range s.stamps {
+		info, err := os.Stat(path)
+		if err != nil || stampOf(info) != stamp {
+			return false
+		}
+	}
+	return true
+}
+
+// skipBufScanDir reports whether a directory is left out of the buf.yaml scan:
+// hidden directories and common non-proto directories.
+func skipBufScanDir(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor"
+}
+
+// stampOf returns the fingerprint of a stat result.
+func stampOf(info fs.FileInfo) fileStamp {
+	return fileStamp{modTime: info.ModTime().UnixNano(), size: info.Size()}
+}
@@ -0,0 +1,78 @@
+package protoc
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeBufYaml writes a buf.yaml into dir, creating the directory.
+func writeBufYaml(t *testing.T, dir, content string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "buf.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFindAllBufYamlWithDeps(t *testing.T) {
+	root := t.TempDir()
+	writeBufYaml(t, root, "version: v2\ndeps:\n  - buf.build/googleapis/googleapis\n")
+	writeBufYaml(t, filepath.Join(root, "proto", "api"), "version: v2\ndeps:\n  - buf.build/acme/common\n")
+	writeBufYaml(t, filepath.Join(root, "proto", "nodeps"), "version: v2\n")
+	writeBufYaml(t, filepath.Join(root, ".git", "hooks"), "deps:\n")
+	writeBufYaml(t, filepath.Join(root, "web", "node_modules", "pkg"), "deps:\n")
+	writeBufYaml(t, filepath.Join(root, "vendor", "x"), "deps:\n")
+
+	got := findAllBufYamlWithDeps(root)
+	want := []string{root, filepath.Join(root, "proto", "api")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findAllBufYamlWithDeps() = %v, want %v", got, want)
+	}
+}
+
+func TestFindAllBufYamlWithDeps_Cache(t *testing.T) {
+	root := t.TempDir()
+	apiDir := filepath.Join(root, "proto", "api")
+	bufYaml := writeBufYaml(t, apiDir, "version: v2\ndeps:\n  - buf.build/acme/common\n")
+
+	if got := findAllBufYamlWithDeps(root); !reflect.DeepEqual(got, []string{apiDir}) {
+		t.Fatalf("first findAllBufYamlWithDeps() = %v, want %v", got, []string{apiDir})
+	}
+
+	// Drop the deps without changing the file's size or modification time:
+	// only a cached result still reports the directory.
+	info, err := os.Stat(bufYaml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bufYaml, []byte("version: v2\ndepz:\n  - buf.build/acme/common\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(bufYaml, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if got := findAllBufYamlWithDeps(root); !reflect.DeepEqual(got, []string{apiDir}) {
+		t.Errorf("second findAllBufYamlWithDeps() = %v, want cached %v", got, []string{apiDir})
+	}
+
+	// Adding a buf.yaml changes its directory, which invalidates the cache.
+	eventsDir := filepath.Join(root, "proto", "events")
+	writeBufYaml(t, eventsDir, "version: v2\ndeps:\n  - buf.build/acme/common\n")
+	if got := findAllBufYamlWithDeps(root); !reflect.DeepEqual(got, []string{eventsDir}) {
+		t.Errorf("findAllBufYamlWithDeps() after adding buf.yaml = %v, want %v", got, []string{eventsDir})
+	}
+
+	// Removing it does too.
+	if err := os.Remove(filepath.Join(eventsDir, "buf.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if got := findAllBufYamlWithDeps(root); got != nil {
+		t.Errorf("findAllBufYamlWithDeps() after removing buf.yaml = %v, want none", got)
+	}
+}
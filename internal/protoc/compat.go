@@ -0,0 +1,256 @@
+package protoc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/linker"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// CheckCompatibility compiles the baseline and current protos and reports changes that
+// break wire compatibility with the baseline. Field numbers, wire types and cardinality
+// must stay stable, removed fields must be reserved, baseline reservations must not be
+// reused, and top-level messages and enums must not be removed. Messages and enums are
+// matched by fully-qualified name, so moving them between files is allowed; types added
+// on the current side, and nested types removed, are not compared.
+func CheckCompatibility(ctx context.Context, baseline, current CompatSource) ([]CompatIssue, error) {
+	baseFiles, err := compileForAnalysis(ctx, baseline.Resolver, baseline.Files, protocompile.SourceInfoNone)
+	if err != nil {
+		return nil, fmt.Errorf("compile baseline: %w", err)
+	}
+	curFiles, err := compileForAnalysis(ctx, current.Resolver, current.Files, protocompile.SourceInfoNone)
+	if err != nil {
+		return nil, fmt.Errorf("compile current: %w", err)
+	}
+	return compareFiles(baseFiles, curFiles, false), nil
+}
+
+// compareFiles reports the issues from base to cur, sorted by file, element and rule.
+// Removed top-level messages and enums are always reported. In breaking mode it also
+// reports removed nested types, fields and RPCs, and compares field types exactly, since
+// generated code breaks on changes the wire format allows.
+func compareFiles(base, cur linker.Files, breaking bool) []CompatIssue {
+	baseIndex, curIndex := indexTypes(base), indexTypes(cur)
+
+	c := &compatChecker{breaking: breaking}
+	for name, b := range baseIndex.messages {
+		if m, ok := curIndex.messages[name]; ok {
+			c.checkMessage(b, m)
+		} else if (breaking || isTopLevel(b)) && !b.IsMapEntry() {
+			// A removed map entry is reported on its map field
+			c.report(b, CompatRuleMessageRemoved, "message was removed")
+		}
+	}
+	for name, b := range baseIndex.enums {
+		if e, ok := curIndex.enums[name]; ok {
+			c.checkEnum(b, e)
+		} else if breaking || isTopLevel(b) {
+			c.report(b, CompatRuleEnumRemoved, "enum was removed")
+		}
+	}
+	for name, b := range baseIndex.methods {
+		if _, ok := curIndex.methods[name]; !ok && breaking {
+			c.report(b, CompatRuleRPCRemoved, "rpc was removed")
+		}
+	}
+
+	sort.SliceStable(c.issues, func(i, j int) bool {
+		a, b := c.issues[i], c.issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Element != b.Element {
+			return a.Element < b.Element
+		}
+		return a.Rule < b.Rule
+	})
+	return c.issues
+}
+
+// isTopLevel reports whether desc is declared at file level rather than inside a message.
+func isTopLevel(desc protoreflect.Descriptor) bool {
+	_, ok := desc.Parent().(protoreflect.FileDescriptor)
+	return ok
+}
+
+// typeIndex maps the messages, enums and methods of a set of files by full name.
+type typeIndex struct {
+	messages map[protoreflect.FullName]protoreflect.MessageDescriptor
+	enums    map[protoreflect.FullName]protoreflect.EnumDescriptor
+	methods  map[protoreflect.FullName]protoreflect.MethodDescriptor
+}
+
+// indexTypes indexes every message, enum and method in files, including nested ones.
+// Map entry messages are indexed too, so a changed map key or value type is reported on the entry.
+func indexTypes(files linker.Files) typeIndex {
+	index := typeIndex{
+		messages: make(map[protoreflect.FullName]protoreflect.MessageDescriptor),
+		enums:    make(map[protoreflect.FullName]protoreflect.EnumDescriptor),
+		methods:  make(map[protoreflect.FullName]protoreflect.MethodDescriptor),
+	}
+
+	var addEnums func(protoreflect.EnumDescriptors)
+	addEnums = func(list protoreflect.EnumDescriptors) {
+		for i := 0; i < list.Len(); i++ {
+			index.enums[list.Get(i).FullName()] = list.Get(i)
+		}
+	}
+	var addMessages func(protoreflect.MessageDescriptors)
+	addMessages = func(list protoreflect.MessageDescriptors) {
+		for i := 0; i < list.Len(); i++ {
+			msg := list.Get(i)
+			index.messages[msg.FullName()] = msg
+			addMessages(msg.Messages())
+			addEnums(msg.Enums())
+		}
+	}
+
+	for _, file := range files {
+		addMessages(file.Messages())
+		addEnums(file.Enums())
+		services := file.Services()
+		for i := 0; i < services.Len(); i++ {
+			methods := services.Get(i).Methods()
+			for j := 0; j < methods.Len(); j++ {
+				index.methods[methods.Get(j).FullName()] = methods.Get(j)
+			}
+		}
+	}
+	return index
+}
+
+// compatChecker collects compatibility issues.
+type compatChecker struct {
+	breaking bool // Report removals and exact type changes; see compareFiles
+	issues   []CompatIssue
+}
+
+// report records an issue against a descriptor.
+func (c *compatChecker) report(desc protoreflect.Descriptor, rule, format string, args ...interface{}) {
+	c.issues = append(c.issues, CompatIssue{
+		File:    desc.ParentFile().Path(),
+		Element: string(desc.FullName()),
+		Rule:    rule,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// checkMessage compares the fields and reservations of a message.
+func (c *compatChecker) checkMessage(base, cur protoreflect.MessageDescriptor) {
+	baseFields, curFields := base.Fields(), cur.Fields()
+
+	for i := 0; i < baseFields.Len(); i++ {
+		bf := baseFields.Get(i)
+		if cf := curFields.ByName(bf.Name()); cf != nil && cf.Number() != bf.Number() {
+			c.report(bf, CompatRuleFieldNumberChanged, "field number changed from %d to %d", bf.Number(), cf.Number())
+			if c.breaking {
+				continue
+			}
+		}
+		switch cf := curFields.ByNumber(bf.Number()); {
+		case cf != nil:
+			c.checkField(bf, cf)
+		case c.breaking:
+			c.report(bf, CompatRuleFieldRemoved, "field %d was removed", bf.Number())
+		case !cur.ReservedRanges().Has(bf.Number()):
+			c.report(bf, CompatRuleFieldNotReserved, "field %d was removed without reserving its number", bf.Number())
+		}
+	}
+
+	for i := 0; i < curFields.Len(); i++ {
+		cf := curFields.Get(i)
+		if base.ReservedRanges().Has(cf.Number()) {
+			c.report(cf, CompatRuleReservedReused, "field uses number %d reserved in the baseline", cf.Number())
+		}
+		if base.ReservedNames().Has(cf.Name()) {
+			c.report(cf, CompatRuleReservedReused, "field uses name %q reserved in the baseline", cf.Name())
+		}
+	}
+}
+
+// checkField compares two fields sharing a number.
+func (c *compatChecker) checkField(base, cur protoreflect.FieldDescriptor) {
+	if base.IsList() != cur.IsList() || base.IsMap() != cur.IsMap() {
+		c.report(base, CompatRuleFieldCardinality, "field %d changed from %s to %s", base.Number(), cardinality(base), cardinality(cur))
+		return
+	}
+	changed := wireGroup(base) != wireGroup(cur) || messageName(base) != messageName(cur)
+	if c.breaking {
+		changed = typeName(base) != typeName(cur)
+	}
+	if changed {
+		c.report(base, CompatRuleFieldTypeChanged, "field %d changed type from %s to %s", base.Number(), typeName(base), typeName(cur))
+	}
+}
+
+// checkEnum compares the values and reservations of an enum.
+func (c *compatChecker) checkEnum(base, cur protoreflect.EnumDescriptor) {
+	baseValues, curValues := base.Values(), cur.Values()
+
+	for i := 0; i < baseValues.Len(); i++ {
+		bv := baseValues.Get(i)
+		if cv := curValues.ByName(bv.Name()); cv != nil && cv.Number() != bv.Number() {
+			c.report(bv, CompatRuleEnumValueChanged, "enum value number changed from %d to %d", bv.Number(), cv.Number())
+		}
+	}
+
+	for i := 0; i < curValues.Len(); i++ {
+		cv := curValues.Get(i)
+		if base.ReservedRanges().Has(cv.Number()) {
+			c.report(cv, CompatRuleReservedReused, "enum value uses number %d reserved in the baseline", cv.Number())
+		}
+		if base.ReservedNames().Has(cv.Name()) {
+			c.report(cv, CompatRuleReservedReused, "enum value uses name %q reserved in the baseline", cv.Name())
+		}
+	}
+}
+
+// wireGroup returns a key shared by field kinds that can read each other's encoding.
+func wireGroup(fd protoreflect.FieldDescriptor) string {
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Int64Kind, protoreflect.Uint32Kind,
+		protoreflect.Uint64Kind, protoreflect.BoolKind, protoreflect.EnumKind:
+		return "varint"
+	case protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+		return "zigzag"
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind:
+		return "fixed32"
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind:
+		return "fixed64"
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return "bytes"
+	default:
+		return fd.Kind().String()
+	}
+}
+
+// messageName returns the message type of a field, or "" for scalar fields.
+func messageName(fd protoreflect.FieldDescriptor) protoreflect.FullName {
+	if fd.Message() == nil {
+		return ""
+	}
+	return fd.Message().FullName()
+}
+
+// typeName describes a field's type for reports.
+func typeName(fd protoreflect.FieldDescriptor) string {
+	if name := messageName(fd); name != "" {
+		return string(name)
+	}
+	return fd.Kind().String()
+}
+
+// cardinality describes whether a field is a map, repeated or singular.
+func cardinality(fd protoreflect.FieldDescriptor) string {
+	switch {
+	case fd.IsMap():
+		return "map"
+	case fd.IsList():
+		return "repeated"
+	default:
+		return "singular"
+	}
+}
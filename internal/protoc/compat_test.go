@@ -0,0 +1,166 @@
+package protoc
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// compatSource builds a CompatSource serving a single api.proto with the given body.
+func compatSource(body string) CompatSource {
+	files := map[string][]byte{
+		"team/api.proto": []byte("syntax = \"proto3\";\npackage team;\n" + body),
+	}
+	return CompatSource{
+		Resolver: NewMemoryResolver(context.Background(), files),
+		Files:    []string{"team/api.proto"},
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	tests := []struct {
+		name        string
+		baseline    string
+		current     string
+		wantRule    string
+		wantElement string
+	}{
+		{
+			name:     "unchanged",
+			baseline: "message User { string name = 1; }",
+			current:  "message User { string name = 1; }",
+		},
+		{
+			name:     "compatible additions and type widening",
+			baseline: "message User { int32 id = 1; }",
+			current:  "message User { int64 id = 1; string email = 2; }",
+		},
+		{
+			name:     "removed field with reserved number",
+			baseline: "message User { string name = 1; string email = 2; }",
+			current:  "message User { string name = 1; reserved 2; }",
+		},
+		{
+			name:        "changed field number",
+			baseline:    "message User { string name = 1; string email = 2; }",
+			current:     "message User { string name = 1; reserved 2; string email = 3; }",
+			wantRule:    CompatRuleFieldNumberChanged,
+			wantElement: "team.User.email",
+		},
+		{
+			name:        "changed wire type",
+			baseline:    "message User { int32 id = 1; }",
+			current:     "message User { string id = 1; }",
+			wantRule:    CompatRuleFieldTypeChanged,
+			wantElement: "team.User.id",
+		},
+		{
+			name:        "changed message type",
+			baseline:    "message A {} message B {} message User { A ref = 1; }",
+			current:     "message A {} message B {} message User { B ref = 1; }",
+			wantRule:    CompatRuleFieldTypeChanged,
+			wantElement: "team.User.ref",
+		},
+		{
+			name:        "singular to repeated",
+			baseline:    "message User { string tag = 1; }",
+			current:     "message User { repeated string tag = 1; }",
+			wantRule:    CompatRuleFieldCardinality,
+			wantElement: "team.User.tag",
+		},
+		{
+			name:        "removed field not reserved",
+			baseline:    "message User { string name = 1; string email = 2; }",
+			current:     "message User { string name = 1; }",
+			wantRule:    CompatRuleFieldNotReserved,
+			wantElement: "team.User.email",
+		},
+		{
+			name:        "reused reserved number",
+			baseline:    "message User { reserved 2; }",
+			current:     "message User { string email = 2; }",
+			wantRule:    CompatRuleReservedReused,
+			wantElement: "team.User.email",
+		},
+		{
+			name:        "reused reserved name",
+			baseline:    "message User { reserved \"email\"; }",
+			current:     "message User { string email = 3; }",
+			wantRule:    CompatRuleReservedReused,
+			wantElement: "team.User.email",
+		},
+		{
+			name:        "changed enum value number",
+			baseline:    "enum Status { STATUS_UNKNOWN = 0; STATUS_ACTIVE = 1; }",
+			current:     "enum Status { STATUS_UNKNOWN = 0; STATUS_ACTIVE = 2; }",
+			wantRule:    CompatRuleEnumValueChanged,
+			wantElement: "team.STATUS_ACTIVE",
+		},
+		{
+			name:        "removed message",
+			baseline:    "message User { string name = 1; } message Account { string id = 1; }",
+			current:     "message User { string name = 1; }",
+			wantRule:    CompatRuleMessageRemoved,
+			wantElement: "team.Account",
+		},
+		{
+			name:        "removed enum",
+			baseline:    "enum Status { STATUS_UNKNOWN = 0; } message User { string name = 1; }",
+			current:     "message User { string name = 1; }",
+			wantRule:    CompatRuleEnumRemoved,
+			wantElement: "team.Status",
+		},
+		{
+			name:     "removed unused nested message",
+			baseline: "message Outer { message Inner { int32 id = 1; } }",
+			current:  "message Outer {}",
+		},
+		{
+			name:        "nested message",
+			baseline:    "message Outer { message Inner { int32 id = 1; } }",
+			current:     "message Outer { message Inner { double id = 1; } }",
+			wantRule:    CompatRuleFieldTypeChanged,
+			wantElement: "team.Outer.Inner.id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := CheckCompatibility(context.Background(), compatSource(tt.baseline), compatSource(tt.current))
+			if err != nil {
+				t.Fatalf("CheckCompatibility() error = %v", err)
+			}
+			if tt.wantRule == "" {
+				if len(issues) != 0 {
+					t.Errorf("CheckCompatibility() = %v, want no issues", issues)
+				}
+				return
+			}
+			if len(issues) != 1 {
+				t.Fatalf("CheckCompatibility() = %v, want 1 issue", issues)
+			}
+			if issues[0].Rule != tt.wantRule || issues[0].Element != tt.wantElement {
+				t.Errorf("issue = %s %s, want %s %s", issues[0].Element, issues[0].Rule, tt.wantElement, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestCheckCompatibility_AllFilesRemoved(t *testing.T) {
+	current := CompatSource{Resolver: NewMemoryResolver(context.Background(), nil)}
+	issues, err := CheckCompatibility(context.Background(), compatSource("message User {} enum Status { STATUS_UNKNOWN = 0; }"), current)
+	if err != nil {
+		t.Fatalf("CheckCompatibility() error = %v", err)
+	}
+	if len(issues) != 2 || issues[0].Rule != CompatRuleEnumRemoved || issues[1].Rule != CompatRuleMessageRemoved {
+		t.Errorf("CheckCompatibility() = %v, want the enum and message removed", issues)
+	}
+}
+
+func TestCheckCompatibility_CompileError(t *testing.T) {
+	_, err := CheckCompatibility(context.Background(), compatSource("message {"), compatSource(""))
+	var compileErr *CompileError
+	if !errors.As(err, &compileErr) {
+		t.Errorf("CheckCompatibility() error = %v, want CompileError", err)
+	}
+}
@@ -0,0 +1,173 @@
+package protoc
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+	"github.com/rahulagarwal0605/protato/internal/errors"
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/internal/registry"
+)
+
+// ProjectGraph is a project dependency graph: each project maps to the projects its
+// proto files import, sorted. Every project reached is a key, even one with no imports.
+type ProjectGraph map[registry.ProjectPath][]registry.ProjectPath
+
+// Projects returns the projects of the graph, sorted.
+func (g ProjectGraph) Projects() []registry.ProjectPath {
+	return slices.Sorted(maps.Keys(g))
+}
+
+// Cycles returns the import cycles of the graph. Each is the ordered path around the cycle,
+// starting and ending at its smallest project (e.g., [a b a]); cycles are sorted and each is
+// reported once, however many of its projects it was reached from.
+func (g ProjectGraph) Cycles() [][]registry.ProjectPath {
+	const (
+		unvisited = iota
+		onStack
+		done
+	)
+	state := make(map[registry.ProjectPath]int, len(g))
+	seen := make(map[string]bool)
+	var stack []registry.ProjectPath
+	var cycles [][]registry.ProjectPath
+
+	var visit func(p registry.ProjectPath)
+	visit = func(p registry.ProjectPath) {
+		state[p] = onStack
+		stack = append(stack, p)
+		for _, dep := range g[p] {
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case onStack:
+				// A back edge closes the cycle from dep down the stack to p
+				cycle := rotateCycle(stack[slices.Index(stack, dep):])
+				key := fmt.Sprint(cycle)
+				if !seen[key] {
+					seen[key] = true
+					cycles = append(cycles, cycle)
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[p] = done
+	}
+	for _, p := range g.Projects() {
+		if state[p] == unvisited {
+			visit(p)
+		}
+	}
+
+	slices.SortFunc(cycles, func(a, b []registry.ProjectPath) int {
+		return slices.Compare(a, b)
+	})
+	return cycles
+}
+
+// rotateCycle returns the cycle of projects starting at its smallest one, closed by
+// repeating that project at the end.
+func rotateCycle(projects []registry.ProjectPath) []registry.ProjectPath {
+	start := slices.Index(projects, slices.Min(projects))
+	cycle := make([]registry.ProjectPath, 0, len(projects)+1)
+	cycle = append(cycle, projects[start:]...)
+	cycle = append(cycle, projects[:start]...)
+	return append(cycle, cycle[0])
+}
+
+// DiscoverDependencyGraph follows the imports of the proto files of projects through the
+// registry at snapshot, transitively, and returns the graph of every project reached.
+// Imports no registry project covers, such as google/protobuf or buf dependencies, are skipped.
+// A project whose files cannot be read, or an import whose project cannot be looked up, is
+// logged and skipped too, so one broken project does not hide the rest of the graph; only a
+// canceled ctx fails the walk.
+func DiscoverDependencyGraph(ctx context.Context, cache registry.CacheInterface, snapshot git.Hash, projects []registry.ProjectPath) (ProjectGraph, error) {
+	graph := make(ProjectGraph)
+	owners := make(map[string]registry.ProjectPath) // Import directory to the project covering it; "" for none
+
+	queue := slices.Clone(projects)
+	for len(queue) > 0 {
+		project := queue[0]
+		queue = queue[1:]
+		if _, ok := graph[project]; ok {
+			continue
+		}
+
+		imports, err := projectImports(ctx, cache, snapshot, project)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			logger.Log(ctx).Warn().Err(err).Str("project", string(project)).Msg("Failed to read project imports, skipping its dependencies")
+		}
+		deps := make(map[registry.ProjectPath]bool)
+		for _, imp := range imports {
+			dir := path.Dir(imp)
+			dep, ok := owners[dir]
+			if !ok {
+				if dep, err = lookupImportProject(ctx, cache, snapshot, dir); err != nil {
+					if ctx.Err() != nil {
+						return nil, ctx.Err()
+					}
+					logger.Log(ctx).Warn().Err(err).Str("import", imp).Msg("Failed to look up imported project, skipping import")
+				}
+				owners[dir] = dep
+			}
+			if dep != "" && dep != project {
+				deps[dep] = true
+			}
+		}
+
+		graph[project] = slices.Sorted(maps.Keys(deps))
+		queue = append(queue, graph[project]...)
+	}
+	return graph, nil
+}
+
+// projectImports returns the imports of a project's proto files in the registry at snapshot.
+func projectImports(ctx context.Context, cache registry.CacheInterface, snapshot git.Hash, project registry.ProjectPath) ([]string, error) {
+	res, err := cache.ListProjectFiles(ctx, &registry.ListProjectFilesRequest{Project: project, Snapshot: snapshot})
+	if err != nil {
+		return nil, fmt.Errorf("list files %s: %w", project, err)
+	}
+	var protoFiles []registry.ProjectFile
+	for _, file := range res.Files {
+		if strings.HasSuffix(file.Path, constants.ProtoFileExt) {
+			protoFiles = append(protoFiles, file)
+		}
+	}
+
+	var imports []string
+	err = cache.ReadProjectFiles(ctx, protoFiles, func(file registry.ProjectFile, r io.Reader) error {
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("read %s/%s: %w", project, file.Path, err)
+		}
+		imports = append(imports, extractImportsFromContent(content)...)
+		return nil
+	})
+	return imports, err
+}
+
+// lookupImportProject returns the registry project covering an import directory, or "" if none does.
+func lookupImportProject(ctx context.Context, cache registry.CacheInterface, snapshot git.Hash, dir string) (registry.ProjectPath, error) {
+	if dir == "." {
+		return "", nil
+	}
+	res, err := cache.LookupProject(ctx, &registry.LookupProjectRequest{Path: dir, Snapshot: snapshot})
+	if stderrors.Is(err, errors.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("look up %s: %w", dir, err)
+	}
+	return res.Project.Path, nil
+}
@@ -0,0 +1,235 @@
+package protoc
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"maps"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/rahulagarwal0605/protato/internal/errors"
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/internal/registry"
+)
+
+// graphCache returns a mock cache holding projects, each a map of file path to content.
+func graphCache(projects map[registry.ProjectPath]map[string]string) *mockCache {
+	return &mockCache{
+		lookupProjectFunc: func(ctx context.Context, req *registry.LookupProjectRequest) (*registry.LookupProjectResponse, error) {
+			for p := req.Path; p != "."; p = path.Dir(p) {
+				if _, ok := projects[registry.ProjectPath(p)]; ok {
+					return &registry.LookupProjectResponse{Project: &registry.Project{Path: registry.ProjectPath(p)}}, nil
+				}
+			}
+			return nil, errors.ErrNotFound
+		},
+		listProjectFilesFunc: func(ctx context.Context, req *registry.ListProjectFilesRequest) (*registry.ListProjectFilesResponse, error) {
+			var files []registry.ProjectFile
+			for name := range projects[req.Project] {
+				files = append(files, registry.ProjectFile{Project: req.Project, Path: name})
+			}
+			return &registry.ListProjectFilesResponse{Files: files}, nil
+		},
+		readProjectFileFunc: func(ctx context.Context, file registry.ProjectFile, w io.Writer) error {
+			_, err := io.WriteString(w, projects[file.Project][file.Path])
+			return err
+		},
+	}
+}
+
+func TestDiscoverDependencyGraph(t *testing.T) {
+	cache := graphCache(map[registry.ProjectPath]map[string]string{
+		"svc/orders": {
+			"orders.proto": `import "svc/common/types.proto";` + "\n" +
+				`import "svc/billing/invoice.proto";` + "\n" +
+				`import "svc/orders/item.proto";` + "\n" +
+				`import "google/protobuf/timestamp.proto";` + "\n" +
+				`import "buf/validate/validate.proto";`,
+			"item.proto": `import "svc/common/types.proto";`,
+			"README.md":  `import "svc/unused/x.proto";`,
+		},
+		"svc/billing": {"invoice.proto": `import "svc/common/money/money.proto";`},
+		"svc/common":  {"types.proto": `syntax = "proto3";`, "money/money.proto": `syntax = "proto3";`},
+		"svc/unused":  {"x.proto": `syntax = "proto3";`},
+	})
+
+	graph, err := DiscoverDependencyGraph(context.Background(), cache, git.Hash("abc123"), []registry.ProjectPath{"svc/orders"})
+	if err != nil {
+		t.Fatalf("DiscoverDependencyGraph() error = %v", err)
+	}
+	want := ProjectGraph{
+		"svc/orders":  {"svc/billing", "svc/common"},
+		"svc/billing": {"svc/common"},
+		"svc/common":  nil,
+	}
+	if !reflect.DeepEqual(graph, want) {
+		t.Errorf("DiscoverDependencyGraph() = %v, want %v", graph, want)
+	}
+	if cycles := graph.Cycles(); len(cycles) != 0 {
+		t.Errorf("Cycles() = %v, want none", cycles)
+	}
+}
+
+func TestDiscoverDependencyGraph_SkipsFailures(t *testing.T) {
+	log := zerolog.Nop()
+	ctx := logger.WithLogger(context.Background(), &log)
+	cache := graphCache(map[registry.ProjectPath]map[string]string{
+		"svc/orders": {
+			"orders.proto": `import "svc/billing/invoice.proto";` + "\n" +
+				`import "svc/common/types.proto";` + "\n" +
+				`import "svc/flaky/x.proto";`,
+		},
+		"svc/billing": {"invoice.proto": `import "svc/common/types.proto";`},
+		"svc/common":  {"types.proto": `syntax = "proto3";`},
+	})
+	list, lookup := cache.listProjectFilesFunc, cache.lookupProjectFunc
+	cache.listProjectFilesFunc = func(ctx context.Context, req *registry.ListProjectFilesRequest) (*registry.ListProjectFilesResponse, error) {
+		if req.Project == "svc/billing" {
+			return nil, stderrors.New("list failed")
+		}
+		return list(ctx, req)
+	}
+	cache.lookupProjectFunc = func(ctx context.Context, req *registry.LookupProjectRequest) (*registry.LookupProjectResponse, error) {
+		if req.Path == "svc/flaky" {
+			return nil, stderrors.New("lookup failed")
+		}
+		return lookup(ctx, req)
+	}
+
+	graph, err := DiscoverDependencyGraph(ctx, cache, git.Hash("abc123"), []registry.ProjectPath{"svc/orders"})
+	if err != nil {
+		t.Fatalf("DiscoverDependencyGraph() error = %v", err)
+	}
+	want := ProjectGraph{
+		"svc/orders":  {"svc/billing", "svc/common"},
+		"svc/billing": nil,
+		"svc/common":  nil,
+	}
+	if !reflect.DeepEqual(graph, want) {
+		t.Errorf("DiscoverDependencyGraph() = %v, want %v", graph, want)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	cache.listProjectFilesFunc = func(ctx context.Context, req *registry.ListProjectFilesRequest) (*registry.ListProjectFilesResponse, error) {
+		return nil, ctx.Err()
+	}
+	if _, err := DiscoverDependencyGraph(canceled, cache, git.Hash("abc123"), []registry.ProjectPath{"svc/orders"}); !stderrors.Is(err, context.Canceled) {
+		t.Errorf("DiscoverDependencyGraph() with a canceled context error = %v, want context.Canceled", err)
+	}
+}
+
+func TestProjectGraph_Cycles(t *testing.T) {
+	tests := []struct {
+		name  string
+		graph ProjectGraph
+		want  [][]registry.ProjectPath
+	}{
+		{
+			name:  "acyclic",
+			graph: ProjectGraph{"a": {"b", "c"}, "b": {"c"}, "c": {}},
+		},
+		{
+			name:  "two projects",
+			graph: ProjectGraph{"a": {"b"}, "b": {"a"}},
+			want:  [][]registry.ProjectPath{{"a", "b", "a"}},
+		},
+		{
+			name:  "rotated to the smallest project",
+			graph: ProjectGraph{"x": {"c"}, "c": {"a"}, "a": {"b"}, "b": {"c"}},
+			want:  [][]registry.ProjectPath{{"a", "b", "c", "a"}},
+		},
+		{
+			name:  "separate cycles",
+			graph: ProjectGraph{"a": {"b"}, "b": {"a", "c"}, "c": {"d"}, "d": {"c"}},
+			want:  [][]registry.ProjectPath{{"a", "b", "a"}, {"c", "d", "c"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.graph.Cycles(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Cycles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// cyclicProjects are svc/a and svc/b importing each other through separate files, which
+// compiles but is a project cycle.
+var cyclicProjects = map[registry.ProjectPath]map[string]string{
+	"svc/a": {
+		"a.proto":    "syntax = \"proto3\";\npackage a;\nimport \"svc/b/b.proto\";\nmessage A { b.B b = 1; }\n",
+		"base.proto": "syntax = \"proto3\";\npackage a;\nmessage Base {}\n",
+	},
+	"svc/b": {"b.proto": "syntax = \"proto3\";\npackage b;\nimport \"svc/a/base.proto\";\nmessage B { a.Base base = 1; }\n"},
+}
+
+func TestDiscoverDependencies_Cycle(t *testing.T) {
+	log := zerolog.New(io.Discard)
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	projects, err := DiscoverDependencies(ctx, graphCache(cyclicProjects), git.Hash("abc123"), []registry.ProjectPath{"svc/b"})
+	if want := []registry.ProjectPath{"svc/a", "svc/b"}; !reflect.DeepEqual(projects, want) {
+		t.Errorf("DiscoverDependencies() = %v, want %v", projects, want)
+	}
+
+	var cycleErr *CycleError
+	if !stderrors.As(err, &cycleErr) {
+		t.Fatalf("DiscoverDependencies() error = %v, want a CycleError", err)
+	}
+	if !stderrors.Is(err, errors.ErrImportCycle) {
+		t.Errorf("DiscoverDependencies() error = %v, want errors.ErrImportCycle", err)
+	}
+	if want := [][]registry.ProjectPath{{"svc/a", "svc/b", "svc/a"}}; !reflect.DeepEqual(cycleErr.Cycles, want) {
+		t.Errorf("CycleError.Cycles = %v, want %v", cycleErr.Cycles, want)
+	}
+	if want := "circular project dependency: svc/a -> svc/b -> svc/a"; err.Error() != want {
+		t.Errorf("CycleError.Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestValidateProtos_Cycle(t *testing.T) {
+	log := zerolog.New(io.Discard)
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	err := ValidateProtos(ctx, ValidateProtosConfig{
+		Cache:       graphCache(cyclicProjects),
+		Snapshot:    git.Hash("abc123"),
+		Projects:    []registry.ProjectPath{"svc/a"},
+		OwnedDir:    "proto",
+		ServiceName: "svc",
+	})
+	var cycleErr *CycleError
+	if !stderrors.As(err, &cycleErr) {
+		t.Fatalf("ValidateProtos() error = %v, want a CycleError", err)
+	}
+	if want := [][]registry.ProjectPath{{"svc/a", "svc/b", "svc/a"}}; !reflect.DeepEqual(cycleErr.Cycles, want) {
+		t.Errorf("CycleError.Cycles = %v, want %v", cycleErr.Cycles, want)
+	}
+}
+
+func TestCheckProjectCycles_DependencyCycle(t *testing.T) {
+	log := zerolog.New(io.Discard)
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	// other/c only depends on the svc/a <-> svc/b cycle, which is left to its owners
+	projects := maps.Clone(cyclicProjects)
+	projects["other/c"] = map[string]string{
+		"c.proto": "syntax = \"proto3\";\npackage c;\nimport \"svc/a/a.proto\";\nmessage C { a.A a = 1; }\n",
+	}
+	cache := graphCache(projects)
+
+	if err := checkProjectCycles(ctx, cache, git.Hash("abc123"), []registry.ProjectPath{"other/c"}); err != nil {
+		t.Errorf("checkProjectCycles(other/c) error = %v, want nil", err)
+	}
+	err := checkProjectCycles(ctx, cache, git.Hash("abc123"), []registry.ProjectPath{"other/c", "svc/b"})
+	if !stderrors.Is(err, errors.ErrImportCycle) {
+		t.Errorf("checkProjectCycles(other/c, svc/b) error = %v, want errors.ErrImportCycle", err)
+	}
+}
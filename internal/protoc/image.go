@@ -0,0 +1,61 @@
+package protoc
+
+import (
+	"context"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/linker"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// CompileImage compiles files and returns them as a FileDescriptorSet image with source info.
+// With includeImports the image also holds every file they import, transitively, including
+// the well-known types; each file comes after the files it imports, as with buf build.
+// Without it the image holds only files, like buf build --exclude-imports.
+func CompileImage(ctx context.Context, resolver RegistryResolverInterface, files []string, includeImports bool) (*descriptorpb.FileDescriptorSet, error) {
+	compiled, err := compileForAnalysis(ctx, resolver, files, protocompile.SourceInfoStandard)
+	if err != nil {
+		return nil, err
+	}
+	if includeImports {
+		return descriptorSetWithImports(compiled), nil
+	}
+	return descriptorSet(compiled), nil
+}
+
+// descriptorSet converts compiled files to a FileDescriptorSet.
+func descriptorSet(files linker.Files) *descriptorpb.FileDescriptorSet {
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, file := range files {
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(file))
+	}
+	return set
+}
+
+// descriptorSetWithImports converts compiled files and their transitive imports to a
+// FileDescriptorSet, ordered so that every file follows its imports.
+// Placeholders for unresolved weak imports are skipped.
+func descriptorSetWithImports(files linker.Files) *descriptorpb.FileDescriptorSet {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+
+	var add func(file protoreflect.FileDescriptor)
+	add = func(file protoreflect.FileDescriptor) {
+		if seen[file.Path()] || file.IsPlaceholder() {
+			return
+		}
+		seen[file.Path()] = true
+		imports := file.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(file))
+	}
+
+	for _, file := range files {
+		add(file)
+	}
+	return set
+}
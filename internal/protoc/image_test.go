@@ -0,0 +1,59 @@
+package protoc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCompileImage(t *testing.T) {
+	files := map[string][]byte{
+		"team/api.proto": []byte("syntax = \"proto3\";\npackage team;\nimport \"team/types.proto\";\nmessage Order { Money total = 1; }\n"),
+		"team/types.proto": []byte("syntax = \"proto3\";\npackage team;\nimport \"google/protobuf/timestamp.proto\";\n" +
+			"message Money { int64 units = 1; google.protobuf.Timestamp at = 2; }\n"),
+	}
+
+	tests := []struct {
+		name           string
+		files          []string
+		includeImports bool
+		want           []string
+	}{
+		{
+			name:  "both files without imports",
+			files: []string{"team/api.proto", "team/types.proto"},
+			want:  []string{"team/api.proto", "team/types.proto"},
+		},
+		{
+			name:  "imported project file excluded",
+			files: []string{"team/api.proto"},
+			want:  []string{"team/api.proto"},
+		},
+		{
+			name:           "imports come first",
+			files:          []string{"team/api.proto", "team/types.proto"},
+			includeImports: true,
+			want:           []string{"google/protobuf/timestamp.proto", "team/types.proto", "team/api.proto"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := NewMemoryResolver(context.Background(), files)
+			set, err := CompileImage(context.Background(), resolver, tt.files, tt.includeImports)
+			if err != nil {
+				t.Fatalf("CompileImage() error = %v", err)
+			}
+			if len(set.File) != len(tt.want) {
+				t.Fatalf("CompileImage() has %d files, want %d", len(set.File), len(tt.want))
+			}
+			for i, file := range set.File {
+				if file.GetName() != tt.want[i] {
+					t.Errorf("file[%d] = %s, want %s", i, file.GetName(), tt.want[i])
+				}
+				if _, compiled := files[file.GetName()]; compiled && file.GetSourceCodeInfo() == nil {
+					t.Errorf("file %s has no source info", file.GetName())
+				}
+			}
+		})
+	}
+}
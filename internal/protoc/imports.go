@@ -0,0 +1,231 @@
+package protoc
+
+import (
+	"strings"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+)
+
+// importKind is the modifier of an import statement.
+type importKind int
+
+const (
+	importDefault importKind = iota // import "a.proto";
+	importPublic                    // import public "a.proto";
+	importWeak                      // import weak "a.proto";
+)
+
+// importModifiers maps the keywords allowed between "import" and the path to their kind.
+var importModifiers = map[string]importKind{
+	"public": importPublic,
+	"weak":   importWeak,
+}
+
+// protoImport is one import statement found in proto source.
+type protoImport struct {
+	Path  string
+	Kind  importKind
+	Start int // Byte offset of the first byte of Path in the source
+	End   int // Byte offset just past Path, at the closing quote
+}
+
+// scanImports returns the import statements in proto source, in order.
+// Comments and string literals are skipped, so imports that are commented out or
+// appear inside an option value are not reported. An import is only recognized at
+// the start of a statement, with an optional public or weak modifier and a
+// non-empty, terminated string path. Syntax and edition declarations are ordinary
+// statements to the scanner, so editions files are scanned like proto2 and proto3 ones.
+func scanImports(src string) []protoImport {
+	l := &protoLexer{src: src}
+	var imports []protoImport
+	stmtStart := true
+	for {
+		tok := l.next()
+		if tok.kind == tokenEOF {
+			return imports
+		}
+		if stmtStart && tok.kind == tokenIdent && tok.text == constants.ImportKeyword {
+			if imp, ok := l.importStatement(); ok {
+				imports = append(imports, imp)
+			}
+			stmtStart = false
+			continue
+		}
+		stmtStart = tok.kind == tokenPunct && (tok.text == ";" || tok.text == "{" || tok.text == "}")
+	}
+}
+
+// ScanPackage returns the package declared in proto source, or "" if there is none.
+// Like scanImports it skips comments and string literals, so a commented-out package
+// statement is not reported. It satisfies registry.PackageParser.
+func ScanPackage(content []byte) string {
+	l := &protoLexer{src: string(content)}
+	stmtStart := true
+	for {
+		tok := l.next()
+		if tok.kind == tokenEOF {
+			return ""
+		}
+		if stmtStart && tok.kind == tokenIdent && tok.text == constants.PackageKeyword {
+			if pkg, ok := l.packageName(); ok {
+				return pkg
+			}
+			stmtStart = false
+			continue
+		}
+		stmtStart = tok.kind == tokenPunct && (tok.text == ";" || tok.text == "{" || tok.text == "}")
+	}
+}
+
+// tokenKind classifies a token produced by protoLexer.
+type tokenKind int
+
+const (
+	tokenEOF    tokenKind = iota
+	tokenIdent            // Identifier or keyword
+	tokenString           // Terminated string literal; text is the raw body between the quotes
+	tokenPunct            // Single punctuation byte
+	tokenOther            // Number literal or unterminated string
+)
+
+// token is a lexeme and its byte offsets in the source.
+type token struct {
+	kind       tokenKind
+	text       string
+	start, end int
+}
+
+// protoLexer splits proto source into tokens, skipping whitespace and comments.
+// It only knows enough of the grammar to find import and package statements.
+type protoLexer struct {
+	src string
+	pos int
+}
+
+// importStatement parses the rest of an import statement after the keyword.
+// On failure the lexer is left just after the keyword.
+func (l *protoLexer) importStatement() (protoImport, bool) {
+	start := l.pos
+	kind := importDefault
+	tok := l.next()
+	if tok.kind == tokenIdent {
+		modifier, ok := importModifiers[tok.text]
+		if !ok {
+			l.pos = start
+			return protoImport{}, false
+		}
+		kind = modifier
+		tok = l.next()
+	}
+	if tok.kind != tokenString || tok.text == "" {
+		l.pos = start
+		return protoImport{}, false
+	}
+	return protoImport{Path: tok.text, Kind: kind, Start: tok.start, End: tok.end}, true
+}
+
+// packageName parses the dotted name of a package statement after the keyword, up to
+// the closing semicolon.
+func (l *protoLexer) packageName() (string, bool) {
+	var parts []string
+	for {
+		tok := l.next()
+		if tok.kind != tokenIdent {
+			return "", false
+		}
+		parts = append(parts, tok.text)
+		switch tok = l.next(); {
+		case tok.kind == tokenPunct && tok.text == ";":
+			return strings.Join(parts, "."), true
+		case tok.kind != tokenPunct || tok.text != ".":
+			return "", false
+		}
+	}
+}
+
+// next returns the next token, or a tokenEOF token at the end of the source.
+func (l *protoLexer) next() token {
+	l.skipSpaceAndComments()
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, start: l.pos, end: l.pos}
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case isIdentStart(c):
+		for l.pos < len(l.src) && isIdentPart(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokenIdent, text: l.src[start:l.pos], start: start, end: l.pos}
+	case isDigit(c):
+		for l.pos < len(l.src) && (isIdentPart(l.src[l.pos]) || l.src[l.pos] == '.') {
+			l.pos++
+		}
+		return token{kind: tokenOther, text: l.src[start:l.pos], start: start, end: l.pos}
+	case c == '"' || c == '\'':
+		return l.stringLiteral(c)
+	}
+	l.pos++
+	return token{kind: tokenPunct, text: l.src[start:l.pos], start: start, end: l.pos}
+}
+
+// stringLiteral scans a string opened by quote. Backslash escapes are skipped over.
+// A string that reaches a newline or the end of the source is unterminated.
+func (l *protoLexer) stringLiteral(quote byte) token {
+	open := l.pos
+	l.pos++
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case quote:
+			l.pos++
+			return token{kind: tokenString, text: l.src[open+1 : l.pos-1], start: open + 1, end: l.pos - 1}
+		case '\n':
+			return token{kind: tokenOther, text: l.src[open:l.pos], start: open, end: l.pos}
+		case '\\':
+			l.pos++
+		}
+		l.pos++
+	}
+	l.pos = len(l.src)
+	return token{kind: tokenOther, text: l.src[open:], start: open, end: l.pos}
+}
+
+// skipSpaceAndComments advances past whitespace, line comments and block comments.
+// An unterminated block comment runs to the end of the source.
+func (l *protoLexer) skipSpaceAndComments() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "//"):
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				l.pos = len(l.src)
+				return
+			}
+			l.pos += 2 + end + 2
+		default:
+			return
+		}
+	}
+}
+
+// isIdentStart reports whether c can start an identifier.
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isIdentPart reports whether c can continue an identifier.
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+// isDigit reports whether c is an ASCII digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
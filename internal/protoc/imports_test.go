@@ -0,0 +1,109 @@
+package protoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScanImports(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []protoImport
+	}{
+		{
+			name: "plain import",
+			src:  `import "a.proto";`,
+			want: []protoImport{{Path: "a.proto", Kind: importDefault, Start: 8, End: 15}},
+		},
+		{
+			name: "public import",
+			src:  `import public "x.proto";`,
+			want: []protoImport{{Path: "x.proto", Kind: importPublic, Start: 15, End: 22}},
+		},
+		{
+			name: "weak import with single quotes",
+			src:  `import weak 'x.proto';`,
+			want: []protoImport{{Path: "x.proto", Kind: importWeak, Start: 13, End: 20}},
+		},
+		{
+			name: "line comment",
+			src:  "// import \"a.proto\";\nimport \"b.proto\";",
+			want: []protoImport{{Path: "b.proto", Kind: importDefault, Start: 29, End: 36}},
+		},
+		{
+			name: "multi-line block comment",
+			src:  "/*\nimport \"a.proto\";\n*/ import \"b.proto\";",
+			want: []protoImport{{Path: "b.proto", Kind: importDefault, Start: 32, End: 39}},
+		},
+		{
+			name: "trailing comment",
+			src:  `import "a.proto"; // import "b.proto";`,
+			want: []protoImport{{Path: "a.proto", Kind: importDefault, Start: 8, End: 15}},
+		},
+		{
+			name: "comment between tokens",
+			src:  `import /* re-export */ public "a.proto";`,
+			want: []protoImport{{Path: "a.proto", Kind: importPublic, Start: 31, End: 38}},
+		},
+		{
+			name: "unterminated block comment",
+			src:  "/* import \"a.proto\";",
+		},
+		{
+			name: "import in a string",
+			src:  `option go_package = "x; import \"a.proto\"";`,
+		},
+		{
+			name: "field named import",
+			src:  `message M { string import = 1; }`,
+		},
+		{
+			name: "unknown modifier",
+			src:  `import strong "a.proto";`,
+		},
+		{
+			name: "unterminated path",
+			src:  "import \"a.proto;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scanImports(tt.src)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scanImports() = %+v, want %+v", got, tt.want)
+			}
+			for _, imp := range got {
+				if tt.src[imp.Start:imp.End] != imp.Path {
+					t.Errorf("src[%d:%d] = %q, want %q", imp.Start, imp.End, tt.src[imp.Start:imp.End], imp.Path)
+				}
+			}
+		})
+	}
+}
+
+func TestScanPackage(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{name: "package", src: "syntax = \"proto3\";\npackage team.v1;", want: "team.v1"},
+		{name: "spaces and comments in the name", src: "package team . /* x */ v1 ;", want: "team.v1"},
+		{name: "line comment", src: "// package old.v1;\npackage team.v1;", want: "team.v1"},
+		{name: "block comment", src: "/*\npackage old.v1;\n*/", want: ""},
+		{name: "package in a string", src: `option go_package = "package x;";`, want: ""},
+		{name: "field named package", src: `message M { string package = 1; }`, want: ""},
+		{name: "unterminated", src: "package team.", want: ""},
+		{name: "no package", src: `syntax = "proto3";`, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ScanPackage([]byte(tt.src)); got != tt.want {
+				t.Errorf("ScanPackage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package protoc
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/linker"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+)
+
+var (
+	lowerSnakeCase = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	pascalCase     = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+
+	// filePackagePath is the source path of the package statement (FileDescriptorProto field 2).
+	filePackagePath = protoreflect.SourcePath{2}
+)
+
+// Name parts required by the naming rules.
+const (
+	serviceSuffix  = "Service"
+	enumZeroSuffix = "UNSPECIFIED"
+)
+
+// DefaultLintRules returns a rule set with every rule enabled.
+func DefaultLintRules() LintRules {
+	rules := make(LintRules, len(AllLintRules))
+	for _, rule := range AllLintRules {
+		rules[rule] = true
+	}
+	return rules
+}
+
+// ParseLintRules builds a rule set from rule names to enable and disable.
+// An empty enable list starts from all rules. Unknown rule names are an error.
+func ParseLintRules(enable, disable []string) (LintRules, error) {
+	known := DefaultLintRules()
+	for _, rule := range append(append([]string{}, enable...), disable...) {
+		if !known[rule] {
+			return nil, fmt.Errorf("unknown lint rule: %s", rule)
+		}
+	}
+
+	rules := known
+	if len(enable) > 0 {
+		rules = make(LintRules, len(enable))
+		for _, rule := range enable {
+			rules[rule] = true
+		}
+	}
+	for _, rule := range disable {
+		delete(rules, rule)
+	}
+	return rules, nil
+}
+
+// LintProtos compiles the given files and checks them against the enabled rules.
+// Issues are sorted by file and position. Files that fail to compile return a CompileError.
+func LintProtos(ctx context.Context, resolver RegistryResolverInterface, files []string, rules LintRules) ([]LintIssue, error) {
+	compiled, err := compileForAnalysis(ctx, resolver, files, protocompile.SourceInfoStandard)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &linter{rules: rules}
+	for _, file := range compiled {
+		l.lintFile(file)
+	}
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		a, b := l.issues[i], l.issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return l.issues, nil
+}
+
+// compileForAnalysis compiles files so their descriptors can be inspected.
+// Compilation failures are returned as a CompileError.
+func compileForAnalysis(ctx context.Context, resolver RegistryResolverInterface, files []string, sourceInfo protocompile.SourceInfoMode) (linker.Files, error) {
+	rep := &LogReporter{Log: logger.Log(ctx)}
+	compiler := protocompile.Compiler{
+		Resolver:       protocompile.WithStandardImports(resolver),
+		Reporter:       rep,
+		SourceInfoMode: sourceInfo,
+	}
+
+	compiled, err := compiler.Compile(ctx, files...)
+	if rep.Failed() {
+		return nil, &CompileError{Message: constants.ErrMsgCompilationFailed}
+	}
+	if err != nil {
+		return nil, &CompileError{Message: err.Error()}
+	}
+	return compiled, nil
+}
+
+// linter collects issues for the enabled rules.
+type linter struct {
+	rules  LintRules
+	file   linker.File
+	issues []LintIssue
+}
+
+// report records an issue at a source location if the rule is enabled.
+func (l *linter) report(rule string, loc protoreflect.SourceLocation, format string, args ...interface{}) {
+	if !l.rules[rule] {
+		return
+	}
+	issue := LintIssue{
+		File:    l.file.Path(),
+		Rule:    rule,
+		Message: fmt.Sprintf(format, args...),
+	}
+	if loc.Path != nil {
+		issue.Line = loc.StartLine + 1
+		issue.Column = loc.StartColumn + 1
+	}
+	l.issues = append(l.issues, issue)
+}
+
+// location returns the source location of a descriptor in the current file.
+func (l *linter) location(desc protoreflect.Descriptor) protoreflect.SourceLocation {
+	return l.file.SourceLocations().ByDescriptor(desc)
+}
+
+// lintFile checks a single compiled file.
+func (l *linter) lintFile(file linker.File) {
+	l.file = file
+	l.lintPackage()
+
+	messages := file.Messages()
+	for i := 0; i < messages.Len(); i++ {
+		l.lintMessage(messages.Get(i))
+	}
+	l.lintEnums(file.Enums())
+
+	services := file.Services()
+	for i := 0; i < services.Len(); i++ {
+		l.lintService(services.Get(i))
+	}
+}
+
+// lintPackage checks the package name and its relation to the file's directory.
+func (l *linter) lintPackage() {
+	pkg := string(l.file.Package())
+	if pkg == "" {
+		return
+	}
+
+	loc := l.file.SourceLocations().ByPath(filePackagePath)
+	for _, part := range strings.Split(pkg, ".") {
+		if !lowerSnakeCase.MatchString(part) {
+			l.report(LintRulePackageLowerSnakeCase, loc, "package %q should be lower_snake_case", pkg)
+			break
+		}
+	}
+
+	dir := path.Dir(l.file.Path())
+	pkgDir := strings.ReplaceAll(pkg, ".", "/")
+	if dir != pkgDir && !strings.HasSuffix(dir, "/"+pkgDir) {
+		l.report(LintRulePackageDirectoryMatch, loc, "package %q does not match directory %q", pkg, dir)
+	}
+}
+
+// lintMessage checks a message, its fields and nested messages.
+func (l *linter) lintMessage(msg protoreflect.MessageDescriptor) {
+	if msg.IsMapEntry() {
+		return
+	}
+	if !pascalCase.MatchString(string(msg.Name())) {
+		l.report(LintRuleMessagePascalCase, l.location(msg), "message %q should be PascalCase", msg.Name())
+	}
+
+	fields := msg.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if !lowerSnakeCase.MatchString(string(field.Name())) {
+			l.report(LintRuleFieldLowerSnakeCase, l.location(field), "field %q should be lower_snake_case", field.FullName())
+		}
+	}
+
+	nested := msg.Messages()
+	for i := 0; i < nested.Len(); i++ {
+		l.lintMessage(nested.Get(i))
+	}
+	l.lintEnums(msg.Enums())
+}
+
+// lintEnums checks the zero value of each enum. The first value is the enum's default, and
+// the zero value in proto3, so it should say that nothing was set.
+func (l *linter) lintEnums(enums protoreflect.EnumDescriptors) {
+	for i := 0; i < enums.Len(); i++ {
+		value := enums.Get(i).Values().Get(0)
+		name := string(value.Name())
+		if name != enumZeroSuffix && !strings.HasSuffix(name, "_"+enumZeroSuffix) {
+			l.report(LintRuleEnumZeroUnspecified, l.location(value), "enum zero value %q should end in %s", value.FullName(), enumZeroSuffix)
+		}
+	}
+}
+
+// lintService checks a service and its RPCs.
+func (l *linter) lintService(svc protoreflect.ServiceDescriptor) {
+	if !pascalCase.MatchString(string(svc.Name())) {
+		l.report(LintRuleServicePascalCase, l.location(svc), "service %q should be PascalCase", svc.Name())
+	}
+	if !strings.HasSuffix(string(svc.Name()), serviceSuffix) {
+		l.report(LintRuleServiceSuffix, l.location(svc), "service %q should end in %s", svc.Name(), serviceSuffix)
+	}
+
+	methods := svc.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		method := methods.Get(i)
+		if !pascalCase.MatchString(string(method.Name())) {
+			l.report(LintRuleRPCPascalCase, l.location(method), "rpc %q should be PascalCase", method.FullName())
+		}
+	}
+}
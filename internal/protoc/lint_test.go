@@ -0,0 +1,211 @@
+package protoc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/internal/git"
+)
+
+// newLintResolver creates a preloaded resolver serving the given files.
+func newLintResolver(files map[string]string) *RegistryResolver {
+	resolver := NewRegistryResolver(context.Background(), &mockCache{}, git.Hash("abc123"))
+	for path, content := range files {
+		resolver.cacheFile(path, []byte(content))
+	}
+	resolver.preloaded = true
+	return resolver
+}
+
+func TestLintProtos(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		content  string
+		wantRule string
+		wantLine int
+	}{
+		{
+			name:    "clean file",
+			path:    "team/service/v1/api.proto",
+			content: "syntax = \"proto3\";\npackage team.service.v1;\nmessage GetRequest { string user_id = 1; }\nservice UserService { rpc GetUser(GetRequest) returns (GetRequest); }\n",
+		},
+		{
+			name:     "package not lower snake case",
+			path:     "team/Service/v1/api.proto",
+			content:  "syntax = \"proto3\";\npackage team.Service.v1;\n",
+			wantRule: LintRulePackageLowerSnakeCase,
+			wantLine: 2,
+		},
+		{
+			name:     "package does not match directory",
+			path:     "team/service/v1/api.proto",
+			content:  "syntax = \"proto3\";\npackage team.other.v1;\n",
+			wantRule: LintRulePackageDirectoryMatch,
+			wantLine: 2,
+		},
+		{
+			name:     "message not pascal case",
+			path:     "team/service/v1/api.proto",
+			content:  "syntax = \"proto3\";\npackage team.service.v1;\nmessage get_request {}\n",
+			wantRule: LintRuleMessagePascalCase,
+			wantLine: 3,
+		},
+		{
+			name:     "nested message not pascal case",
+			path:     "team/service/v1/api.proto",
+			content:  "syntax = \"proto3\";\npackage team.service.v1;\nmessage Outer {\n  message inner {}\n}\n",
+			wantRule: LintRuleMessagePascalCase,
+			wantLine: 4,
+		},
+		{
+			name:     "field not lower snake case",
+			path:     "team/service/v1/api.proto",
+			content:  "syntax = \"proto3\";\npackage team.service.v1;\nmessage User {\n  string userId = 1;\n}\n",
+			wantRule: LintRuleFieldLowerSnakeCase,
+			wantLine: 4,
+		},
+		{
+			name:     "service not pascal case",
+			path:     "team/service/v1/api.proto",
+			content:  "syntax = \"proto3\";\npackage team.service.v1;\nmessage Req {}\nservice userService { rpc Get(Req) returns (Req); }\n",
+			wantRule: LintRuleServicePascalCase,
+			wantLine: 4,
+		},
+		{
+			name:     "service without suffix",
+			path:     "team/service/v1/api.proto",
+			content:  "syntax = \"proto3\";\npackage team.service.v1;\nmessage Req {}\nservice Users { rpc Get(Req) returns (Req); }\n",
+			wantRule: LintRuleServiceSuffix,
+			wantLine: 4,
+		},
+		{
+			name:    "enum zero value unspecified",
+			path:    "team/service/v1/api.proto",
+			content: "syntax = \"proto3\";\npackage team.service.v1;\nenum Status {\n  STATUS_UNSPECIFIED = 0;\n  STATUS_ACTIVE = 1;\n}\nenum Kind {\n  UNSPECIFIED = 0;\n}\n",
+		},
+		{
+			name:     "enum zero value not unspecified",
+			path:     "team/service/v1/api.proto",
+			content:  "syntax = \"proto3\";\npackage team.service.v1;\nenum Status {\n  STATUS_ACTIVE = 0;\n  STATUS_INACTIVE = 1;\n}\n",
+			wantRule: LintRuleEnumZeroUnspecified,
+			wantLine: 4,
+		},
+		{
+			name:     "nested enum zero value not unspecified",
+			path:     "team/service/v1/api.proto",
+			content:  "syntax = \"proto3\";\npackage team.service.v1;\nmessage User {\n  enum Role {\n    ROLE_NONE = 0;\n  }\n}\n",
+			wantRule: LintRuleEnumZeroUnspecified,
+			wantLine: 5,
+		},
+		{
+			name:     "rpc not pascal case",
+			path:     "team/service/v1/api.proto",
+			content:  "syntax = \"proto3\";\npackage team.service.v1;\nmessage Req {}\nservice UserService {\n  rpc get_user(Req) returns (Req);\n}\n",
+			wantRule: LintRuleRPCPascalCase,
+			wantLine: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			resolver := newLintResolver(map[string]string{tt.path: tt.content})
+
+			issues, err := LintProtos(ctx, resolver, []string{tt.path}, DefaultLintRules())
+			if err != nil {
+				t.Fatalf("LintProtos() error = %v", err)
+			}
+			if tt.wantRule == "" {
+				if len(issues) != 0 {
+					t.Errorf("LintProtos() = %v, want no issues", issues)
+				}
+				return
+			}
+			if len(issues) != 1 {
+				t.Fatalf("LintProtos() = %v, want 1 issue", issues)
+			}
+			if issues[0].Rule != tt.wantRule {
+				t.Errorf("issue rule = %v, want %v", issues[0].Rule, tt.wantRule)
+			}
+			if issues[0].File != tt.path || issues[0].Line != tt.wantLine {
+				t.Errorf("issue position = %s:%d, want %s:%d", issues[0].File, issues[0].Line, tt.path, tt.wantLine)
+			}
+
+			// The rule can be suppressed on its own
+			rules, err := ParseLintRules(nil, []string{tt.wantRule})
+			if err != nil {
+				t.Fatalf("ParseLintRules() error = %v", err)
+			}
+			issues, err = LintProtos(ctx, resolver, []string{tt.path}, rules)
+			if err != nil {
+				t.Fatalf("LintProtos() error = %v", err)
+			}
+			if len(issues) != 0 {
+				t.Errorf("LintProtos() with %s disabled = %v, want no issues", tt.wantRule, issues)
+			}
+		})
+	}
+}
+
+func TestLintProtos_CompileError(t *testing.T) {
+	resolver := newLintResolver(map[string]string{"bad.proto": "syntax = \"proto3\";\nmessage {"})
+
+	_, err := LintProtos(context.Background(), resolver, []string{"bad.proto"}, DefaultLintRules())
+	var compileErr *CompileError
+	if !errors.As(err, &compileErr) {
+		t.Errorf("LintProtos() error = %v, want CompileError", err)
+	}
+}
+
+func TestParseLintRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		enable  []string
+		disable []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "defaults to all rules",
+			want: AllLintRules,
+		},
+		{
+			name:   "enable only selected rules",
+			enable: []string{LintRuleMessagePascalCase, LintRuleRPCPascalCase},
+			want:   []string{LintRuleMessagePascalCase, LintRuleRPCPascalCase},
+		},
+		{
+			name:    "disable from selected rules",
+			enable:  []string{LintRuleMessagePascalCase, LintRuleRPCPascalCase},
+			disable: []string{LintRuleRPCPascalCase},
+			want:    []string{LintRuleMessagePascalCase},
+		},
+		{
+			name:    "unknown rule",
+			disable: []string{"no-such-rule"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLintRules(tt.enable, tt.disable)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLintRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("ParseLintRules() = %v, want %v", got, tt.want)
+			}
+			for _, rule := range tt.want {
+				if !got[rule] {
+					t.Errorf("ParseLintRules() missing %s", rule)
+				}
+			}
+		})
+	}
+}
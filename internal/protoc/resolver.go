@@ -4,19 +4,23 @@ package protoc
 import (
 	"bytes"
 	"context"
+	stderrors "errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/linker"
 	"github.com/bufbuild/protocompile/reporter"
 	"github.com/rs/zerolog"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/rahulagarwal0605/protato/internal/constants"
 	"github.com/rahulagarwal0605/protato/internal/errors"
@@ -56,14 +60,18 @@ func (r *RegistryResolver) cacheFile(path string, content []byte) {
 // RegistryResolverInterface defines the interface for proto import resolution.
 type RegistryResolverInterface interface {
 	SetImportPrefix(prefix string)
-	PreloadFiles(ctx context.Context, projects []registry.ProjectPath, cacheAtRegistryPath bool) error
+	PreloadFiles(ctx context.Context, projects []registry.ProjectPath, cacheAtRegistryPath bool) ([]ProjectError, error)
 	FindFileByPath(filePath string) (protocompile.SearchResult, error)
 	SetServicePrefix(prefix string)
 	DiscoveredProjects() []registry.ProjectPath
 }
 
+// defaultPreloadConcurrency is the number of registry reads PreloadFiles runs at once by default.
+const defaultPreloadConcurrency = 8
+
 // RegistryResolver resolves proto imports from the registry.
 type RegistryResolver struct {
+	ctx      context.Context // Used for registry reads from FindFileByPath, which protocompile calls without a context
 	cache    registry.CacheInterface
 	snapshot git.Hash
 
@@ -73,6 +81,13 @@ type RegistryResolver struct {
 	// fileCache caches resolved files - pre-loaded before compilation
 	fileCache map[string][]byte
 
+	// blobs caches file contents by blob hash: in memory for this resolver and, on disk
+	// in the registry cache, across resolvers and runs
+	blobs *blobCache
+
+	// concurrency bounds the registry reads PreloadFiles runs at once; 0 uses defaultPreloadConcurrency
+	concurrency int
+
 	// servicePrefix is used to map import paths to registry paths
 	// e.g., "payment-service" maps "proto/common/..." to "payment-service/common/..."
 	servicePrefix string
@@ -81,17 +96,30 @@ type RegistryResolver struct {
 	// e.g., "proto" if imports use "proto/common/address.proto"
 	importPrefix string
 
+	// projectPrefixes maps local project paths to the import prefix of the owned directory
+	// they are in, when there are several; other projects use importPrefix
+	projectPrefixes map[string]string
+
 	// preloaded indicates if all files have been pre-loaded into cache
 	preloaded bool
+
+	// loads coalesces concurrent registry reads of a file that was not preloaded, by import path
+	loads singleflight.Group
 }
 
 // NewRegistryResolver creates a new registry resolver.
 func NewRegistryResolver(ctx context.Context, cache registry.CacheInterface, snapshot git.Hash) *RegistryResolver {
+	blobDir := ""
+	if cache != nil {
+		blobDir = cache.BlobDir()
+	}
 	return &RegistryResolver{
+		ctx:       ctx,
 		cache:     cache,
 		snapshot:  snapshot,
 		projects:  make(map[registry.ProjectPath]struct{}),
 		fileCache: make(map[string][]byte),
+		blobs:     newBlobCache(blobDir),
 	}
 }
 
@@ -105,66 +133,142 @@ func (r *RegistryResolver) SetImportPrefix(prefix string) {
 // concurrent git access issues.
 // If cacheAtRegistryPath is true, files are cached at both registry paths and import paths.
 // This is needed for dependency discovery where files are compiled using registry paths.
-func (r *RegistryResolver) PreloadFiles(ctx context.Context, projects []registry.ProjectPath, cacheAtRegistryPath bool) error {
-	for _, project := range projects {
-		if err := r.preloadProjectFiles(ctx, project, cacheAtRegistryPath); err != nil {
-			logger.Log(ctx).Warn().Err(err).Str("project", string(project)).Msg("Failed to preload project files")
+// Projects or files that fail to load are skipped and reported in the returned []ProjectError.
+// File lists and contents are read with up to SetConcurrency reads in flight; the cache is
+// then filled in project and file order, so the result does not depend on read timing.
+func (r *RegistryResolver) PreloadFiles(ctx context.Context, projects []registry.ProjectPath, cacheAtRegistryPath bool) ([]ProjectError, error) {
+	listed := r.listPreloadFiles(ctx, projects)
+
+	var files []preloadedFile
+	for _, l := range listed {
+		for _, file := range l.files {
+			files = append(files, preloadedFile{project: l.project, file: file})
+		}
+	}
+	r.readPreloadFiles(ctx, files)
+
+	var failures []ProjectError
+	next := 0
+	for _, l := range listed {
+		if l.err != nil {
+			logger.Log(ctx).Warn().Err(l.err).Str("project", string(l.project)).Msg("Failed to preload project files")
+			failures = append(failures, ProjectError{Project: l.project, Err: fmt.Errorf("list files: %w", l.err)})
 			continue
 		}
+		for _, f := range files[next : next+len(l.files)] {
+			if f.err != nil {
+				logger.Log(ctx).Warn().Err(f.err).Str("file", f.file.Path).Msg("Failed to preload file")
+				failures = append(failures, ProjectError{Project: f.project, Err: fmt.Errorf("read %s: %w", f.file.Path, f.err)})
+				continue
+			}
+			r.storePreloadedFile(ctx, f, cacheAtRegistryPath)
+		}
+		next += len(l.files)
 	}
 
 	r.preloaded = true
 	logger.Log(ctx).Debug().Int("files", len(r.fileCache)).Msg("Pre-loaded proto files into memory")
-	return nil
+	return failures, nil
 }
 
-// preloadProjectFiles loads all files from a single project into the cache.
-func (r *RegistryResolver) preloadProjectFiles(ctx context.Context, project registry.ProjectPath, cacheAtRegistryPath bool) error {
-	filesRes, err := r.cache.ListProjectFiles(ctx, &registry.ListProjectFilesRequest{
-		Project:  project,
-		Snapshot: r.snapshot,
-	})
-	if err != nil {
-		return err
-	}
+// SetConcurrency sets how many registry reads PreloadFiles runs at once; n < 1 restores the default.
+func (r *RegistryResolver) SetConcurrency(n int) {
+	r.concurrency = n
+}
 
-	if filesRes == nil {
-		return nil
+// preloadLimit returns the number of concurrent reads PreloadFiles may run.
+func (r *RegistryResolver) preloadLimit() int {
+	if r.concurrency < 1 {
+		return defaultPreloadConcurrency
 	}
+	return r.concurrency
+}
 
-	for _, file := range filesRes.Files {
-		if err := r.preloadFile(ctx, project, file, cacheAtRegistryPath); err != nil {
-			logger.Log(ctx).Warn().Err(err).Str("file", file.Path).Msg("Failed to preload file")
-			continue
-		}
-	}
+// preloadListing is the file list of one project being preloaded.
+type preloadListing struct {
+	project registry.ProjectPath
+	files   []registry.ProjectFile
+	err     error
+}
 
-	return nil
+// preloadedFile is one file being preloaded and its content once read.
+type preloadedFile struct {
+	project registry.ProjectPath
+	file    registry.ProjectFile
+	content []byte
+	err     error
 }
 
-// preloadFile loads a single file into the cache.
-func (r *RegistryResolver) preloadFile(ctx context.Context, project registry.ProjectPath, file registry.ProjectFile, cacheAtRegistryPath bool) error {
-	registryPath := path.Join(string(project), file.Path)
+// listPreloadFiles lists the files of each project concurrently, in the order of projects.
+func (r *RegistryResolver) listPreloadFiles(ctx context.Context, projects []registry.ProjectPath) []preloadListing {
+	listed := make([]preloadListing, len(projects))
+	var g errgroup.Group
+	g.SetLimit(r.preloadLimit())
+	for i, project := range projects {
+		g.Go(func() error {
+			listed[i].project = project
+			res, err := r.cache.ListProjectFiles(ctx, &registry.ListProjectFilesRequest{
+				Project:  project,
+				Snapshot: r.snapshot,
+			})
+			switch {
+			case err != nil:
+				listed[i].err = err
+			case res != nil:
+				listed[i].files = res.Files
+			}
+			return nil
+		})
+	}
+	g.Wait()
+	return listed
+}
 
-	var buf bytes.Buffer
-	if err := r.cache.ReadProjectFile(ctx, file, &buf); err != nil {
-		return err
+// readPreloadFiles reads the content of each file concurrently.
+func (r *RegistryResolver) readPreloadFiles(ctx context.Context, files []preloadedFile) {
+	var g errgroup.Group
+	g.SetLimit(r.preloadLimit())
+	for i := range files {
+		g.Go(func() error {
+			files[i].content, files[i].err = r.readFile(ctx, files[i].file)
+			return nil
+		})
 	}
+	g.Wait()
+}
 
-	content := buf.Bytes()
+// storePreloadedFile adds a preloaded file to the cache.
+func (r *RegistryResolver) storePreloadedFile(ctx context.Context, f preloadedFile, cacheAtRegistryPath bool) {
+	registryPath := path.Join(string(f.project), f.file.Path)
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	if utils.HasServicePrefix(registryPath, r.servicePrefix) {
-		r.cacheFileWithServicePrefix(ctx, registryPath, content, cacheAtRegistryPath)
+		r.cacheFileWithServicePrefix(ctx, registryPath, f.content, cacheAtRegistryPath)
 	} else {
-		r.fileCache[registryPath] = content
+		r.fileCache[registryPath] = f.content
 	}
 
 	// Register project (already holding lock, so don't call registerProject)
-	r.projects[project] = struct{}{}
-	return nil
+	r.projects[f.project] = struct{}{}
+}
+
+// readFile returns the content of a registry file, from the blob cache when it has been read before.
+func (r *RegistryResolver) readFile(ctx context.Context, file registry.ProjectFile) ([]byte, error) {
+	if content, ok := r.blobs.get(file.Hash); ok {
+		return content, nil
+	}
+
+	var buf bytes.Buffer
+	if err := r.cache.ReadProjectFile(ctx, file, &buf); err != nil {
+		return nil, err
+	}
+	content := buf.Bytes()
+	if err := r.blobs.put(file.Hash, content); err != nil {
+		logger.Log(ctx).Debug().Err(err).Str("file", file.Path).Msg("Failed to cache file content")
+	}
+	return content, nil
 }
 
 // cacheFileWithServicePrefix caches a file that has a service prefix.
@@ -188,10 +292,28 @@ func (r *RegistryResolver) cacheFileWithServicePrefix(ctx context.Context, regis
 	}
 }
 
-// buildImportCachePath builds the cache path for an import based on the import prefix.
+// SetOwnedRoots makes the projects of each owned directory resolve under its import prefix.
+func (r *RegistryResolver) SetOwnedRoots(roots []OwnedRoot) {
+	r.projectPrefixes = make(map[string]string)
+	for _, root := range roots {
+		for _, project := range root.Projects {
+			r.projectPrefixes[project] = root.ImportPrefix
+		}
+	}
+}
+
+// buildImportCachePath builds the cache path for an import based on the import prefix
+// of the owned directory its project is in.
 func (r *RegistryResolver) buildImportCachePath(subPath string) string {
-	if r.importPrefix != "" {
-		return r.importPrefix + "/" + subPath
+	prefix := r.importPrefix
+	for project, projectPrefix := range r.projectPrefixes {
+		if subPath == project || strings.HasPrefix(subPath, project+"/") {
+			prefix = projectPrefix
+			break
+		}
+	}
+	if prefix != "" {
+		return prefix + "/" + subPath
 	}
 	return subPath
 }
@@ -235,21 +357,37 @@ func (r *RegistryResolver) FindFileByPath(filePath string) (protocompile.SearchR
 		return protocompile.SearchResult{}, errors.ErrNotFound
 	}
 
-	// Fallback to loading from git (only used if not preloaded)
-	return r.loadFileFromGit(filePath)
+	// Fallback to loading from git (only used if not preloaded). protocompile looks files up
+	// from several goroutines, so concurrent lookups of a file share one read.
+	content, err, _ := r.loads.Do(filePath, func() (interface{}, error) {
+		// An earlier lookup may have cached the file while this one was waiting
+		if cached, ok := r.getCachedFile(filePath); ok && cached != nil {
+			return cached, nil
+		}
+		return r.loadFileFromGit(filePath)
+	})
+	if err != nil {
+		return protocompile.SearchResult{}, err
+	}
+	return protocompile.SearchResult{
+		Source: bytes.NewReader(content.([]byte)),
+	}, nil
 }
 
-// loadFileFromGit loads a file directly from the git repository.
-// This is only used when files are not preloaded.
-func (r *RegistryResolver) loadFileFromGit(filePath string) (protocompile.SearchResult, error) {
-	ctx := context.Background()
-
-	// Safety checks
-	if r == nil {
-		return protocompile.SearchResult{}, fmt.Errorf("resolver is nil")
-	}
+// loadFileFromGit loads a file directly from the git repository and caches it.
+// This is only used when files are not preloaded. protocompile passes FindFileByPath no
+// context, so registry reads use the one the resolver was created with.
+func (r *RegistryResolver) loadFileFromGit(filePath string) ([]byte, error) {
 	if r.cache == nil {
-		return protocompile.SearchResult{}, fmt.Errorf("cache is nil")
+		return nil, fmt.Errorf("cache is nil")
+	}
+	ctx := r.ctx
+	if ctx == nil {
+		return nil, fmt.Errorf("resolver has no context")
+	}
+	// A cancelled run, e.g. interrupted from the CLI, reads no more files
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	// Use original path for project lookup (don't map - we need the full registry path)
@@ -262,12 +400,12 @@ func (r *RegistryResolver) loadFileFromGit(filePath string) (protocompile.Search
 	})
 	if err != nil {
 		logger.Log(ctx).Debug().Err(err).Str("filePath", filePath).Msg("loadFileFromGit: lookup failed")
-		return protocompile.SearchResult{}, err
+		return nil, err
 	}
 
 	if res == nil || res.Project == nil {
 		logger.Log(ctx).Debug().Str("filePath", filePath).Msg("loadFileFromGit: project not found")
-		return protocompile.SearchResult{}, errors.ErrNotFound
+		return nil, errors.ErrNotFound
 	}
 
 	// Record discovered project
@@ -283,11 +421,11 @@ func (r *RegistryResolver) loadFileFromGit(filePath string) (protocompile.Search
 		Snapshot: r.snapshot,
 	})
 	if err != nil {
-		return protocompile.SearchResult{}, err
+		return nil, err
 	}
 
 	if filesRes == nil {
-		return protocompile.SearchResult{}, errors.ErrNotFound
+		return nil, errors.ErrNotFound
 	}
 
 	// Find the file
@@ -299,27 +437,23 @@ func (r *RegistryResolver) loadFileFromGit(filePath string) (protocompile.Search
 		}
 	}
 	if fileHash == "" {
-		return protocompile.SearchResult{}, errors.ErrNotFound
+		return nil, errors.ErrNotFound
 	}
 
 	// Read file content
-	var buf bytes.Buffer
-	if err := r.cache.ReadProjectFile(ctx, registry.ProjectFile{
+	fileContent, err := r.readFile(ctx, registry.ProjectFile{
 		Snapshot: r.snapshot,
 		Project:  res.Project.Path,
 		Path:     relPath,
 		Hash:     fileHash,
-	}, &buf); err != nil {
-		return protocompile.SearchResult{}, err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Cache the file content
-	fileContent := buf.Bytes()
 	r.cacheFile(filePath, fileContent)
-
-	return protocompile.SearchResult{
-		Source: bytes.NewReader(fileContent),
-	}, nil
+	return fileContent, nil
 }
 
 // SetServicePrefix sets the service prefix for import path mapping.
@@ -367,7 +501,7 @@ func (r *RegistryResolver) untransformImportLine(line string) (string, bool) {
 
 	subPath := utils.TrimServicePrefix(importPath, r.servicePrefix)
 	newImportPath := r.buildImportCachePath(subPath)
-	return utils.ReplaceStringInLine(line, importPath, newImportPath), true
+	return replaceImportPath(line, newImportPath), true
 }
 
 func (r *RegistryResolver) mapImportPath(importPath string) string {
@@ -431,270 +565,24 @@ func (r *LogReporter) Failed() bool {
 	return r.failed
 }
 
-// DiscoverDependencies discovers all transitive dependencies for the given proto files.
+// DiscoverDependencies returns the given projects and every registry project their proto
+// files import, transitively, at snapshot. When projects import each other in a cycle, the
+// projects are returned along with a *CycleError listing each cycle.
 func DiscoverDependencies(
 	ctx context.Context,
 	cache registry.CacheInterface,
 	snapshot git.Hash,
 	projects []registry.ProjectPath,
 ) ([]registry.ProjectPath, error) {
-	resolver := NewRegistryResolver(ctx, cache, snapshot)
-	setupServicePrefixForDiscovery(resolver, projects)
-
-	protoFiles := buildProtoFilesListForDiscovery(ctx, cache, snapshot, projects, resolver)
-	if len(protoFiles) == 0 {
-		logger.Log(ctx).Debug().Msg("No proto files to compile for dependency discovery")
-		return projects, nil
-	}
-
-	logger.Log(ctx).Debug().Int("count", len(protoFiles)).Strs("files", protoFiles).Msg("Compiling files for dependency discovery")
-
-	preloadFilesForDiscovery(ctx, resolver, projects)
-	discoverProjectsFromImports(ctx, resolver, protoFiles)
-
-	logger.Log(ctx).Debug().Int("discovered", len(resolver.projects)).Msg("Dependency discovery complete")
-	return resolver.DiscoveredProjects(), nil
-}
-
-// setupServicePrefixForDiscovery extracts and sets the service prefix from project paths.
-func setupServicePrefixForDiscovery(resolver *RegistryResolver, projects []registry.ProjectPath) {
-	if len(projects) == 0 {
-		return
-	}
-	if prefix := utils.ExtractServicePrefixFromProject(string(projects[0])); prefix != "" {
-		resolver.SetServicePrefix(prefix)
-	}
-}
-
-// buildProtoFilesListForDiscovery builds the list of proto files from projects.
-func buildProtoFilesListForDiscovery(
-	ctx context.Context,
-	cache registry.CacheInterface,
-	snapshot git.Hash,
-	projects []registry.ProjectPath,
-	resolver *RegistryResolver,
-) []string {
-	var protoFiles []string
-	for _, project := range projects {
-		filesRes, err := cache.ListProjectFiles(ctx, &registry.ListProjectFilesRequest{
-			Project:  project,
-			Snapshot: snapshot,
-		})
-		if err != nil {
-			continue
-		}
-
-		ensureServicePrefixSet(resolver, project)
-		protoFiles = append(protoFiles, buildImportPathsForProject(project, filesRes.Files, resolver.servicePrefix)...)
-
-		// Mark requested projects as discovered
-		resolver.registerProject(project)
-	}
-	return protoFiles
-}
-
-// ensureServicePrefixSet ensures the service prefix is set from the project if not already set.
-func ensureServicePrefixSet(resolver *RegistryResolver, project registry.ProjectPath) {
-	if resolver.servicePrefix != "" {
-		return
-	}
-	if prefix := utils.ExtractServicePrefixFromProject(string(project)); prefix != "" {
-		resolver.SetServicePrefix(prefix)
-	}
-}
-
-// buildImportPathsForProject builds import paths for files in a project.
-func buildImportPathsForProject(project registry.ProjectPath, files []registry.ProjectFile, servicePrefix string) []string {
-	var paths []string
-	projectStr := string(project)
-	for _, f := range files {
-		if utils.HasServicePrefix(projectStr, servicePrefix) {
-			subPath := utils.TrimServicePrefix(projectStr, servicePrefix)
-			paths = append(paths, path.Join(subPath, f.Path))
-		} else {
-			paths = append(paths, path.Join(projectStr, f.Path))
-		}
-	}
-	return paths
-}
-
-// preloadFilesForDiscovery preloads files and resets the preloaded flag for discovery.
-func preloadFilesForDiscovery(ctx context.Context, resolver *RegistryResolver, projects []registry.ProjectPath) {
-	if err := resolver.PreloadFiles(ctx, projects, true); err != nil {
-		logger.Log(ctx).Debug().Err(err).Msg("Failed to preload files for dependency discovery")
-	}
-
-	resolver.mu.Lock()
-	resolver.preloaded = false
-	logger.Log(ctx).Debug().
-		Int("cachedFiles", len(resolver.fileCache)).
-		Msg("Cache contents before compilation")
-	for path := range resolver.fileCache {
-		logger.Log(ctx).Debug().Str("cachedPath", path).Msg("Cached file path")
-	}
-	resolver.mu.Unlock()
-}
-
-// discoverProjectsFromImports discovers projects by parsing imports from proto files.
-func discoverProjectsFromImports(ctx context.Context, resolver *RegistryResolver, protoFiles []string) {
-	logger.Log(ctx).Debug().Strs("files", protoFiles).Msg("Parsing proto files for dependency discovery")
-
-	for _, protoFile := range protoFiles {
-		content := getFileContentFromCache(resolver, protoFile)
-		if content == nil {
-			logger.Log(ctx).Debug().Str("file", protoFile).Msg("File not found in cache, skipping")
-			continue
-		}
-
-		imports := extractImportsFromContent(content)
-		logger.Log(ctx).Debug().Str("file", protoFile).Int("importCount", len(imports)).Msg("Extracted imports from file")
-
-		for _, imp := range imports {
-			if isGoogleProtobufImport(imp) {
-				continue
-			}
-			discoverProjectFromImport(ctx, resolver, imp)
-		}
-	}
-}
-
-// getFileContentFromCache retrieves file content from the resolver's cache.
-func getFileContentFromCache(resolver *RegistryResolver, protoFile string) []byte {
-	if resolver.servicePrefix != "" {
-		registryPath := utils.BuildServicePrefixedPath(resolver.servicePrefix, protoFile)
-		if content, ok := resolver.getCachedFile(registryPath); ok {
-			return content
-		}
-	}
-	if content, ok := resolver.getCachedFile(protoFile); ok {
-		return content
-	}
-	return nil
-}
-
-// discoverProjectFromImport attempts to discover a project from an import path.
-func discoverProjectFromImport(ctx context.Context, resolver *RegistryResolver, imp string) {
-	logger.Log(ctx).Debug().Str("import", imp).Msg("Found import")
-
-	if !utils.HasServicePrefix(imp, resolver.servicePrefix) {
-		logger.Log(ctx).Debug().
-			Str("import", imp).
-			Str("servicePrefix", resolver.servicePrefix).
-			Msg("Import does not start with service prefix")
-		return
-	}
-
-	projectPath := extractProjectPathFromImport(imp)
-	if projectPath == "" {
-		logger.Log(ctx).Debug().Str("import", imp).Msg("Import path too short to extract project")
-		return
-	}
-
-	logger.Log(ctx).Debug().
-		Str("import", imp).
-		Str("projectPath", projectPath).
-		Msg("Attempting to discover project")
-
-	if isProjectAlreadyDiscovered(resolver, projectPath) {
-		logger.Log(ctx).Debug().
-			Str("import", imp).
-			Str("projectPath", projectPath).
-			Msg("Project already discovered")
-		return
+	graph, err := DiscoverDependencyGraph(ctx, cache, snapshot, projects)
+	if err != nil {
+		return nil, err
 	}
-
-	lookupAndRegisterProject(ctx, resolver, imp, projectPath)
-}
-
-// extractProjectPathFromImport extracts the project path from an import path.
-func extractProjectPathFromImport(imp string) string {
-	return utils.ExtractParentPath(imp)
-}
-
-// isProjectAlreadyDiscovered checks if a project has already been discovered.
-func isProjectAlreadyDiscovered(resolver *RegistryResolver, projectPath string) bool {
-	resolver.mu.Lock()
-	defer resolver.mu.Unlock()
-	_, exists := resolver.projects[registry.ProjectPath(projectPath)]
-	return exists
-}
-
-// lookupAndRegisterProject looks up a project and registers it if found.
-func lookupAndRegisterProject(ctx context.Context, resolver *RegistryResolver, imp, projectPath string) {
-	lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	logger.Log(ctx).Debug().
-		Str("import", imp).
-		Str("projectPath", projectPath).
-		Msg("Calling LookupProject")
-
-	res, err := resolver.cache.LookupProject(lookupCtx, &registry.LookupProjectRequest{
-		Path:     projectPath,
-		Snapshot: resolver.snapshot,
-	})
-
-	logger.Log(ctx).Debug().
-		Str("import", imp).
-		Str("projectPath", projectPath).
-		Err(err).
-		Bool("resIsNil", res == nil).
-		Msg("LookupProject completed")
-
-	if err == nil && res != nil && res.Project != nil {
-		resolver.registerProject(res.Project.Path)
-		logger.Log(ctx).Debug().
-			Str("import", imp).
-			Str("project", string(res.Project.Path)).
-			Msg("Discovered project from import")
-	} else {
-		logger.Log(ctx).Debug().
-			Str("import", imp).
-			Str("projectPath", projectPath).
-			Err(err).
-			Msg("Project not found in registry")
+	logger.Log(ctx).Debug().Int("discovered", len(graph)).Msg("Dependency discovery complete")
+	if cycles := graph.Cycles(); len(cycles) > 0 {
+		return graph.Projects(), &CycleError{Cycles: cycles}
 	}
-}
-
-// findAllBufYamlWithDeps searches for all buf.yaml files with deps in the workspace.
-// Returns a list of directories containing buf.yaml with deps.
-func findAllBufYamlWithDeps(workspaceRoot string) []string {
-	var dirs []string
-
-	// Walk the workspace to find all buf.yaml files
-	filepath.WalkDir(workspaceRoot, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return nil // Skip errors
-		}
-
-		// Skip hidden directories and common non-proto directories
-		if d.IsDir() {
-			name := d.Name()
-			if strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor" {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-
-		// Check for buf.yaml
-		if d.Name() != "buf.yaml" {
-			return nil
-		}
-
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return nil
-		}
-
-		// Check if it has deps section
-		if strings.Contains(string(content), "deps:") {
-			dirs = append(dirs, filepath.Dir(path))
-		}
-
-		return nil
-	})
-
-	return dirs
+	return graph.Projects(), nil
 }
 
 // exportBufDependencies runs `buf export` to get all proto files including BSR dependencies.
@@ -730,8 +618,9 @@ func exportBufDependencies(ctx context.Context, bufDir string) string {
 }
 
 // loadProtoFilesFromDir loads proto files from a directory into the resolver cache.
+// Files are cached at importPrefix joined with their path relative to dir.
 // skipIfExists: if true, skip files that already exist in cache; if false, always cache
-func (r *RegistryResolver) loadProtoFilesFromDir(ctx context.Context, dir string, skipIfExists bool, logPrefix string) error {
+func (r *RegistryResolver) loadProtoFilesFromDir(ctx context.Context, dir, importPrefix string, skipIfExists bool, logPrefix string) error {
 	if dir == "" {
 		return nil
 	}
@@ -746,15 +635,16 @@ func (r *RegistryResolver) loadProtoFilesFromDir(ctx context.Context, dir string
 		if err != nil {
 			return nil // Skip errors
 		}
-		if d.IsDir() || !strings.HasSuffix(filePath, ".proto") {
+		if d.IsDir() || !utils.IsProjectFile(d.Name()) {
 			return nil
 		}
 
 		// Get relative path (this is the import path)
-		importPath, err := utils.RelPathToSlash(dir, filePath)
+		relPath, err := utils.RelPathToSlash(dir, filePath)
 		if err != nil {
 			return nil
 		}
+		importPath := path.Join(importPrefix, relPath)
 
 		r.mu.Lock()
 		if skipIfExists {
@@ -798,26 +688,89 @@ func (r *RegistryResolver) loadProtoFilesFromDir(ctx context.Context, dir string
 
 // loadExportedFiles loads proto files from the buf export directory into the resolver cache.
 func (r *RegistryResolver) loadExportedFiles(ctx context.Context, exportDir string) error {
-	return r.loadProtoFilesFromDir(ctx, exportDir, true, "BSR")
+	return r.loadProtoFilesFromDir(ctx, exportDir, "", true, "BSR")
 }
 
 // loadVendorFiles loads proto files from the local vendor directory into the resolver cache.
-// This allows owned protos to import pulled dependencies during validation.
-func (r *RegistryResolver) loadVendorFiles(ctx context.Context, vendorDir string) error {
-	return r.loadProtoFilesFromDir(ctx, vendorDir, false, "vendor")
+// This allows owned protos to import pulled dependencies during validation. Each root is
+// walked the same way, with its import prefix, so any vendor layout yields the same import paths.
+func (r *RegistryResolver) loadVendorFiles(ctx context.Context, roots []VendorRoot) error {
+	for _, root := range roots {
+		if err := r.loadProtoFilesFromDir(ctx, root.Dir, root.ImportPrefix, false, "vendor"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadOwnedFiles loads owned proto files from the local owned directories into the resolver cache.
+// Local files already use import paths, so no import transformation is needed. Files already
+// loaded from the registry take precedence; this only fills in owned files the snapshot lacks.
+func (r *RegistryResolver) loadOwnedFiles(ctx context.Context, roots []OwnedRoot) error {
+	for _, root := range roots {
+		if err := r.loadProtoFilesFromDir(ctx, root.Dir, root.ImportPrefix, true, "owned"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ownedRoots returns the owned directories of a validation: config.Owned, or else
+// config.OwnedDir in the workspace root.
+func ownedRoots(config ValidateProtosConfig) []OwnedRoot {
+	if len(config.Owned) > 0 {
+		return config.Owned
+	}
+	if config.WorkspaceRoot == "" {
+		return nil
+	}
+	return []OwnedRoot{{Dir: filepath.Join(config.WorkspaceRoot, config.OwnedDir), ImportPrefix: config.OwnedDir}}
+}
+
+// NewWorkspaceResolver creates a resolver over the workspace's owned and vendor files only.
+// Owned files are cached under the import prefix of their owned directory; imports not found
+// locally fail rather than falling back to the registry.
+func NewWorkspaceResolver(ctx context.Context, owned []OwnedRoot, vendor []VendorRoot) *RegistryResolver {
+	resolver := NewRegistryResolver(ctx, nil, "")
+	if len(owned) > 0 {
+		resolver.SetImportPrefix(owned[0].ImportPrefix)
+	}
+	resolver.SetOwnedRoots(owned)
+	if err := resolver.loadOwnedFiles(ctx, owned); err != nil {
+		logger.Log(ctx).Warn().Err(err).Msg("Failed to load owned files")
+	}
+	if err := resolver.loadVendorFiles(ctx, vendor); err != nil {
+		logger.Log(ctx).Warn().Err(err).Msg("Failed to load vendor dependencies")
+	}
+	resolver.preloaded = true
+	return resolver
+}
+
+// NewMemoryResolver creates a resolver serving only the given files, keyed by import path.
+func NewMemoryResolver(ctx context.Context, files map[string][]byte) *RegistryResolver {
+	resolver := NewRegistryResolver(ctx, nil, "")
+	for importPath, content := range files {
+		resolver.cacheFile(importPath, content)
+	}
+	resolver.preloaded = true
+	return resolver
 }
 
 // ValidateProtos validates that the proto files compile successfully.
 func ValidateProtos(ctx context.Context, config ValidateProtosConfig) error {
 	resolver := NewRegistryResolver(ctx, config.Cache, config.Snapshot)
 	configureResolver(resolver, config.OwnedDir, config.ServiceName)
+	resolver.SetOwnedRoots(config.Owned)
 
-	if err := preloadProtoFiles(ctx, resolver, config.Projects); err != nil {
-		return err
+	loadFailures := preloadProtoFiles(ctx, resolver, config.Projects)
+
+	// Load owned files from the workspace so imports between owned projects resolve locally
+	if err := resolver.loadOwnedFiles(ctx, ownedRoots(config)); err != nil {
+		logger.Log(ctx).Warn().Err(err).Msg("Failed to load owned files")
 	}
 
 	// Load pulled dependencies from vendor directory
-	if err := resolver.loadVendorFiles(ctx, config.VendorDir); err != nil {
+	if err := resolver.loadVendorFiles(ctx, config.Vendor); err != nil {
 		logger.Log(ctx).Warn().Err(err).Msg("Failed to load vendor dependencies")
 	}
 
@@ -834,12 +787,152 @@ func ValidateProtos(ctx context.Context, config ValidateProtosConfig) error {
 		}
 	}
 
-	protoFiles := buildProtoFileList(ctx, config.Cache, config.Snapshot, config.Projects, resolver)
+	if err := checkProjectCycles(ctx, config.Cache, config.Snapshot, config.Projects); err != nil {
+		return err
+	}
+
+	protoFiles, listFailures := buildProtoFileList(ctx, config.Cache, config.Snapshot, config.Projects, resolver)
+	if err := reportLoadFailures(ctx, append(loadFailures, listFailures...), config.FailOnLoadErrors); err != nil {
+		return err
+	}
+	if len(protoFiles) == 0 {
+		return nil
+	}
+
+	if err := checkGoogleImports(ctx, resolver, protoFiles, config.GoogleImports); err != nil {
+		return err
+	}
+
+	return compileProtoFiles(ctx, resolver, protoFiles, config.StrictSyntax)
+}
+
+// checkProjectCycles fails validation when the projects take part in an import cycle, so the
+// cycle is reported as such rather than as whatever compile error it leads to. Cycles among
+// dependencies only are logged: fixing them is up to their owners. Discovery failures are
+// only logged too: the compile that follows reports missing files on its own.
+func checkProjectCycles(ctx context.Context, cache registry.CacheInterface, snapshot git.Hash, projects []registry.ProjectPath) error {
+	if cache == nil || len(projects) == 0 {
+		return nil
+	}
+	_, err := DiscoverDependencies(ctx, cache, snapshot, projects)
+	var cycleErr *CycleError
+	if stderrors.As(err, &cycleErr) {
+		var own [][]registry.ProjectPath
+		for _, cycle := range cycleErr.Cycles {
+			if slices.ContainsFunc(cycle, func(p registry.ProjectPath) bool { return slices.Contains(projects, p) }) {
+				logger.Log(ctx).Error().Str("cycle", cyclePath(cycle)).Msg("Projects import each other in a cycle")
+				own = append(own, cycle)
+				continue
+			}
+			logger.Log(ctx).Warn().Str("cycle", cyclePath(cycle)).Msg("Dependencies import each other in a cycle")
+		}
+		if len(own) > 0 {
+			return &CycleError{Cycles: own}
+		}
+		return nil
+	}
+	if err != nil {
+		logger.Log(ctx).Warn().Err(err).Msg("Failed to discover project dependencies, skipping cycle check")
+	}
+	return nil
+}
+
+// ValidateRegistryProject compiles a project's proto files as stored in the registry at a snapshot.
+// Imports resolve against the other projects of the same snapshot. It satisfies registry.ProjectValidator.
+func ValidateRegistryProject(ctx context.Context, reg registry.CacheInterface, snapshot git.Hash, project registry.ProjectPath) error {
+	res, err := reg.ListProjectFiles(ctx, &registry.ListProjectFilesRequest{Project: project, Snapshot: snapshot})
+	if err != nil {
+		return fmt.Errorf("list files: %w", err)
+	}
+
+	var protoFiles []string
+	for _, file := range res.Files {
+		if strings.HasSuffix(file.Path, constants.ProtoFileExt) {
+			protoFiles = append(protoFiles, path.Join(string(project), file.Path))
+		}
+	}
+	if len(protoFiles) == 0 {
+		return nil
+	}
+
+	return compileProtoFiles(ctx, NewRegistryResolver(ctx, reg, snapshot), protoFiles, false)
+}
+
+// ValidateAgainstSnapshot compiles the given owned files, as import paths, from the workspace.
+// Imports outside the owned directory resolve from the registry at config.Snapshot rather than
+// the vendor directory, so the result reflects that snapshot's dependencies.
+// config.Projects and config.Vendor are ignored.
+func ValidateAgainstSnapshot(ctx context.Context, config ValidateProtosConfig, protoFiles []string) error {
+	resolver := NewRegistryResolver(ctx, config.Cache, config.Snapshot)
+	configureResolver(resolver, config.OwnedDir, config.ServiceName)
+	resolver.SetOwnedRoots(config.Owned)
+
+	if err := resolver.loadOwnedFiles(ctx, ownedRoots(config)); err != nil {
+		return fmt.Errorf("load owned files: %w", err)
+	}
 	if len(protoFiles) == 0 {
 		return nil
 	}
 
-	return compileProtoFiles(ctx, resolver, protoFiles)
+	if err := checkGoogleImports(ctx, resolver, protoFiles, config.GoogleImports); err != nil {
+		return err
+	}
+
+	return compileProtoFiles(ctx, resolver, protoFiles, config.StrictSyntax)
+}
+
+// isGoogleImport checks if an import path is under the google/ namespace.
+func isGoogleImport(importPath string) bool {
+	return strings.HasPrefix(importPath, constants.GooglePrefix)
+}
+
+// isAllowedGoogleImport checks if a google/* import matches the allowlist.
+func isAllowedGoogleImport(importPath string, allowlist []string) bool {
+	for _, pattern := range allowlist {
+		if utils.MatchPattern(pattern, importPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkGoogleImports reports google/* imports that are neither allowlisted nor resolvable.
+// Without this check such imports surface later as a confusing "file not found" compile error.
+// An empty allowlist falls back to DefaultGoogleImports.
+func checkGoogleImports(ctx context.Context, resolver *RegistryResolver, protoFiles []string, allowlist []string) error {
+	if len(allowlist) == 0 {
+		allowlist = DefaultGoogleImports
+	}
+
+	var rejected []string
+	for _, protoFile := range protoFiles {
+		content, ok := resolver.getCachedFile(protoFile)
+		if !ok {
+			continue
+		}
+
+		for _, parsed := range scanImports(string(content)) {
+			imp := parsed.Path
+			if !isGoogleImport(imp) || isAllowedGoogleImport(imp, allowlist) {
+				continue
+			}
+			if _, ok := resolver.getCachedFile(resolver.mapImportPath(imp)); ok {
+				continue
+			}
+
+			logger.Log(ctx).Error().
+				Str("file", protoFile).
+				Str("import", imp).
+				Strs("allowed", allowlist).
+				Msg("Import is not an allowed well-known import and was not found in the registry, vendor directory or buf dependencies")
+			rejected = append(rejected, fmt.Sprintf("%s imports %s", protoFile, imp))
+		}
+	}
+
+	if len(rejected) > 0 {
+		return &CompileError{Message: fmt.Sprintf("%s: disallowed google imports: %s", constants.ErrMsgCompilationFailed, strings.Join(rejected, "; "))}
+	}
+	return nil
 }
 
 // configureResolver sets up the resolver with import and service prefixes.
@@ -852,25 +945,28 @@ func configureResolver(resolver *RegistryResolver, ownedDir, serviceName string)
 }
 
 // preloadProtoFiles pre-loads all proto files into memory to avoid concurrent git access.
-func preloadProtoFiles(ctx context.Context, resolver *RegistryResolver, projects []registry.ProjectPath) error {
+// Returns the projects that could not be (fully) loaded.
+func preloadProtoFiles(ctx context.Context, resolver *RegistryResolver, projects []registry.ProjectPath) []ProjectError {
 	logger.Log(ctx).Debug().Int("projects", len(projects)).Msg("Pre-loading proto files into memory")
 	// Pass cacheAtRegistryPath=false for validation - only cache at import paths
-	if err := resolver.PreloadFiles(ctx, projects, false); err != nil {
+	failures, err := resolver.PreloadFiles(ctx, projects, false)
+	if err != nil {
 		logger.Log(ctx).Warn().Err(err).Msg("Failed to preload files, skipping validation")
-		return nil
 	}
-	return nil
+	return failures
 }
 
 // buildProtoFileList builds the list of proto files to compile using import paths.
+// Projects whose files cannot be listed are skipped and reported in the returned []ProjectError.
 func buildProtoFileList(
 	ctx context.Context,
 	cache registry.CacheInterface,
 	snapshot git.Hash,
 	projects []registry.ProjectPath,
 	resolver *RegistryResolver,
-) []string {
+) ([]string, []ProjectError) {
 	var protoFiles []string
+	var failures []ProjectError
 
 	for _, project := range projects {
 		filesRes, err := cache.ListProjectFiles(ctx, &registry.ListProjectFilesRequest{
@@ -878,13 +974,37 @@ func buildProtoFileList(
 			Snapshot: snapshot,
 		})
 		if err != nil {
+			failures = append(failures, ProjectError{Project: project, Err: fmt.Errorf("list files: %w", err)})
+			continue
+		}
+		if filesRes == nil {
 			continue
 		}
 
 		protoFiles = append(protoFiles, buildProjectProtoFiles(project, filesRes.Files, resolver)...)
 	}
 
-	return protoFiles
+	return protoFiles, failures
+}
+
+// reportLoadFailures summarizes projects that could not be loaded.
+// Returns a *LoadError when failOnErrors is set, otherwise only warns.
+func reportLoadFailures(ctx context.Context, failures []ProjectError, failOnErrors bool) error {
+	if len(failures) == 0 {
+		return nil
+	}
+
+	failed := countFailedProjects(failures)
+	for _, pe := range failures {
+		logger.Log(ctx).Warn().Err(pe.Err).Str("project", string(pe.Project)).Msg("Project could not be loaded")
+	}
+
+	if failOnErrors {
+		return &LoadError{Errors: failures}
+	}
+
+	logger.Log(ctx).Warn().Int("projects", failed).Msgf("%d projects could not be loaded, validation results may be incomplete", failed)
+	return nil
 }
 
 // buildProjectProtoFiles builds proto file paths for a single project.
@@ -917,10 +1037,7 @@ func buildImportPath(projectStr, filePath string, resolver *RegistryResolver) st
 		// Build path based on how imports work in proto files
 		// For ownedDir="" (root): proto files import as "subPath/filePath" (e.g., "buf/validate/validate.proto")
 		// For ownedDir="proto": proto files import as "proto/subPath/filePath" (e.g., "proto/common/account.proto")
-		if resolver.importPrefix == "" {
-			return subPath + "/" + filePath
-		}
-		return resolver.importPrefix + "/" + subPath + "/" + filePath
+		return resolver.buildImportCachePath(subPath + "/" + filePath)
 	}
 
 	// Fallback to registry path if no service prefix
@@ -928,7 +1045,10 @@ func buildImportPath(projectStr, filePath string, resolver *RegistryResolver) st
 }
 
 // compileProtoFiles compiles the proto files and handles errors.
-func compileProtoFiles(ctx context.Context, resolver *RegistryResolver, protoFiles []string) error {
+// With strictSyntax, imports across syntaxes fail validation instead of only warning.
+// Editions files compile up to edition 2023, the newest protocompile supports; the go, cpp
+// and java feature-set imports come with the standard imports.
+func compileProtoFiles(ctx context.Context, resolver *RegistryResolver, protoFiles []string, strictSyntax bool) error {
 	rep := &LogReporter{Log: logger.Log(ctx)}
 
 	compiler := protocompile.Compiler{
@@ -938,7 +1058,7 @@ func compileProtoFiles(ctx context.Context, resolver *RegistryResolver, protoFil
 
 	logger.Log(ctx).Info().Int("files", len(protoFiles)).Msg("Validating proto files")
 
-	_, err := compiler.Compile(ctx, protoFiles...)
+	files, err := compiler.Compile(ctx, protoFiles...)
 	if rep.Failed() {
 		return &CompileError{Message: constants.ErrMsgCompilationFailed}
 	}
@@ -947,10 +1067,48 @@ func compileProtoFiles(ctx context.Context, resolver *RegistryResolver, protoFil
 		return handleCompileError(ctx, err)
 	}
 
+	if err := checkSyntaxMismatches(ctx, files, strictSyntax); err != nil {
+		return err
+	}
+
 	logger.Log(ctx).Info().Msg("Proto validation completed successfully")
 	return nil
 }
 
+// checkSyntaxMismatches reports files that import a file of another syntax (proto2, proto3 or editions).
+// Mismatches are warnings unless strict is set. google/protobuf imports are skipped:
+// descriptor.proto is proto2 and is imported by proto3 files for custom options, and editions
+// files import the feature sets of google/protobuf/*_features.proto.
+func checkSyntaxMismatches(ctx context.Context, files linker.Files, strict bool) error {
+	var mismatches []string
+	for _, file := range files {
+		imports := file.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			imp := imports.Get(i)
+			if imp.IsPlaceholder() || isGoogleProtobufImport(imp.Path()) || imp.Syntax() == file.Syntax() {
+				continue
+			}
+
+			level := zerolog.WarnLevel
+			if strict {
+				level = zerolog.ErrorLevel
+			}
+			logger.Log(ctx).WithLevel(level).
+				Str("file", file.Path()).
+				Str("syntax", file.Syntax().String()).
+				Str("import", imp.Path()).
+				Str("import_syntax", imp.Syntax().String()).
+				Msg("File imports a file of a different syntax")
+			mismatches = append(mismatches, fmt.Sprintf("%s (%s) imports %s (%s)", file.Path(), file.Syntax(), imp.Path(), imp.Syntax()))
+		}
+	}
+
+	if strict && len(mismatches) > 0 {
+		return &CompileError{Message: fmt.Sprintf("%s: mixed syntax imports: %s", constants.ErrMsgCompilationFailed, strings.Join(mismatches, "; "))}
+	}
+	return nil
+}
+
 // handleCompileError handles compilation errors, including panic recovery.
 func handleCompileError(ctx context.Context, err error) error {
 	errStr := err.Error()
@@ -972,6 +1130,8 @@ func TransformImports(content []byte, ownedDir, servicePrefix string) []byte {
 // For owned imports without ownedDir: common/... -> servicePrefix/common/...
 // For pulled imports: ownedDir/other-svc/... -> other-svc/... (just strip ownedDir)
 // pulledPrefixes contains the service names of pulled projects (e.g., ["lcs-svc", "payment-svc"])
+// Only import paths are rewritten: syntax and edition declarations and feature options pass
+// through untouched, so editions files transform like proto2 and proto3 ones.
 func TransformImportsWithPulled(content []byte, ownedDir, servicePrefix string, pulledPrefixes []string) []byte {
 	if servicePrefix == "" {
 		return content
@@ -988,6 +1148,30 @@ func TransformImportsWithPulled(content []byte, ownedDir, servicePrefix string,
 	return utils.JoinLines(result)
 }
 
+// TransformOwnedImportsWithPulled is TransformImportsWithPulled for a workspace with several
+// owned directories: each import is transformed with the owned directory it starts with, or
+// the first one when it starts with none.
+func TransformOwnedImportsWithPulled(content []byte, ownedDirs []string, servicePrefix string, pulledPrefixes []string) []byte {
+	if servicePrefix == "" || len(ownedDirs) == 0 {
+		return content
+	}
+
+	lines := utils.SplitContentToLines(content)
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		ownedDir := ownedDirs[0]
+		importPath := extractImportPathFromLine(line)
+		for _, dir := range ownedDirs {
+			if dir != "" && strings.HasPrefix(importPath, dir+"/") {
+				ownedDir = dir
+				break
+			}
+		}
+		result[i] = transformImportLine(line, ownedDir, servicePrefix, pulledPrefixes)
+	}
+	return utils.JoinLines(result)
+}
+
 // transformImportLine transforms a single import line.
 // Handles both owned imports (add service prefix) and pulled imports (just strip ownedDir).
 func transformImportLine(line, ownedDir, servicePrefix string, pulledPrefixes []string) string {
@@ -1029,7 +1213,7 @@ func isPulledProject(pathToTransform string, pulledPrefixes []string) bool {
 // handlePulledProject handles transformation for pulled project imports.
 func handlePulledProject(line, importPath, pathToTransform, ownedDir string) string {
 	if ownedDir != "" {
-		return utils.ReplaceStringInLine(line, importPath, pathToTransform)
+		return replaceImportPath(line, pathToTransform)
 	}
 	return line
 }
@@ -1037,51 +1221,70 @@ func handlePulledProject(line, importPath, pathToTransform, ownedDir string) str
 // transformOwnedProject transforms an owned project import by adding the service prefix.
 func transformOwnedProject(line, importPath, pathToTransform, servicePrefix string) string {
 	newImportPath := utils.BuildServicePrefixedPath(servicePrefix, pathToTransform)
-	return utils.ReplaceStringInLine(line, importPath, newImportPath)
+	return replaceImportPath(line, newImportPath)
 }
 
-// extractImportsFromContent extracts all import statements from proto file content.
-func extractImportsFromContent(content []byte) []string {
-	var imports []string
+// MoveImports rewrites import paths below the from directory to the same paths below to,
+// e.g. with from "team/service" and to "team/newservice", "team/service/v1/api.proto"
+// becomes "team/newservice/v1/api.proto". Other imports and lines pass through untouched.
+func MoveImports(content []byte, from, to string) []byte {
 	lines := utils.SplitContentToLines(content)
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		result[i] = moveImportLine(line, from, to)
+	}
+	return utils.JoinLines(result)
+}
 
-	for _, line := range lines {
-		importPath := extractImportPathFromLine(line)
-		if importPath != "" && !isGoogleProtobufImport(importPath) {
-			imports = append(imports, importPath)
-		}
+// moveImportLine rewrites the import path of a single line when it is below from.
+func moveImportLine(line, from, to string) string {
+	importPath := extractImportPathFromLine(line)
+	rest, ok := strings.CutPrefix(importPath, from+"/")
+	if !ok {
+		return line
 	}
+	return replaceImportPath(line, path.Join(to, rest))
+}
 
+// extractImportsFromContent extracts the non-google/protobuf import paths from proto file content.
+// Public and weak imports are included; commented-out imports are not.
+func extractImportsFromContent(content []byte) []string {
+	var imports []string
+	for _, imp := range scanImports(string(content)) {
+		if !isGoogleProtobufImport(imp.Path) {
+			imports = append(imports, imp.Path)
+		}
+	}
 	return imports
 }
 
 // extractImportPathFromLine extracts the import path from a single line if it's an import statement.
 func extractImportPathFromLine(line string) string {
-	trimmed := strings.TrimSpace(line)
-	if !strings.HasPrefix(trimmed, constants.ImportKeyword) {
+	start, end, ok := importPathSpan(line)
+	if !ok {
 		return ""
 	}
+	return line[start:end]
+}
 
-	// Parse import statement: import "path/to/file.proto";
-	// or: import 'path/to/file.proto';
-	var quote byte
-	startIdx := 0
-	endIdx := 0
-
-	for i := len(constants.ImportKeyword); i < len(trimmed); i++ {
-		if trimmed[i] == '"' || trimmed[i] == '\'' {
-			if quote == 0 {
-				quote = trimmed[i]
-				startIdx = i + 1
-			} else if trimmed[i] == quote {
-				endIdx = i
-				break
-			}
-		}
+// importPathSpan returns the byte offsets of the quoted path of the first import in a line.
+// It accepts `import [public|weak] "path";` with either quote style, any whitespace
+// between tokens and comments around the statement. Lines with no closing quote or an
+// empty path are rejected.
+func importPathSpan(line string) (start, end int, ok bool) {
+	imports := scanImports(line)
+	if len(imports) == 0 {
+		return 0, 0, false
 	}
+	return imports[0].Start, imports[0].End, true
+}
 
-	if startIdx > 0 && endIdx > 0 {
-		return trimmed[startIdx:endIdx]
+// replaceImportPath replaces the path of an import line, leaving the rest of the line untouched.
+// Lines that are not imports are returned unchanged.
+func replaceImportPath(line, newPath string) string {
+	start, end, ok := importPathSpan(line)
+	if !ok {
+		return line
 	}
-	return ""
+	return line[:start] + newPath + line[end:]
 }
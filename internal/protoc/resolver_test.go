@@ -1,9 +1,18 @@
 package protoc
 
 import (
+	"bytes"
 	"context"
+	stderrors "errors"
 	"io"
+	"os"
+	"path/filepath"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/rahulagarwal0605/protato/internal/errors"
 	"github.com/rahulagarwal0605/protato/internal/git"
@@ -17,6 +26,7 @@ type mockCache struct {
 	lookupProjectFunc    func(ctx context.Context, req *registry.LookupProjectRequest) (*registry.LookupProjectResponse, error)
 	listProjectFilesFunc func(ctx context.Context, req *registry.ListProjectFilesRequest) (*registry.ListProjectFilesResponse, error)
 	readProjectFileFunc  func(ctx context.Context, file registry.ProjectFile, w io.Writer) error
+	blobDir              string
 }
 
 func (m *mockCache) Close() error                                    { return nil }
@@ -27,16 +37,72 @@ func (m *mockCache) GetSnapshot(context.Context) (git.Hash, error)  { return git
 func (m *mockCache) RefreshAndGetSnapshot(context.Context) (git.Hash, error) {
 	return git.Hash("abc123"), nil
 }
-func (m *mockCache) Push(context.Context, git.Hash) error            { return nil }
+func (m *mockCache) Push(_ context.Context, hash git.Hash) (git.Hash, error) { return hash, nil }
 func (m *mockCache) SetProject(context.Context, *registry.SetProjectRequest) (*registry.SetProjectResponse, error) {
 	return nil, nil
 }
+func (m *mockCache) SetProjects(context.Context, []*registry.SetProjectRequest) (*registry.SetProjectResponse, error) {
+	return nil, nil
+}
+func (m *mockCache) PlanProjects(context.Context, []*registry.SetProjectRequest) ([]registry.FileChange, error) {
+	return nil, nil
+}
 func (m *mockCache) ListProjects(context.Context, *registry.ListProjectsOptions) ([]registry.ProjectPath, error) {
 	return nil, nil
 }
+func (m *mockCache) ListProjectsPage(context.Context, *registry.ListProjectsOptions) (*registry.ListProjectsResponse, error) {
+	return &registry.ListProjectsResponse{}, nil
+}
 func (m *mockCache) CheckProjectClaim(context.Context, git.Hash, string, string) error {
 	return nil
 }
+func (m *mockCache) RefreshBranch(context.Context, string) error { return nil }
+func (m *mockCache) BranchSnapshot(context.Context, string) (git.Hash, error) {
+	return "", nil
+}
+func (m *mockCache) ResolveRef(context.Context, string) (git.Hash, error) {
+	return "", nil
+}
+func (m *mockCache) EnsureSnapshotAvailable(context.Context, git.Hash) error {
+	return nil
+}
+func (m *mockCache) Config(context.Context, git.Hash) (*registry.Config, error) {
+	return &registry.Config{}, nil
+}
+func (m *mockCache) Diagnose(context.Context) []registry.Finding {
+	return nil
+}
+func (m *mockCache) GetProjectHistory(context.Context, *registry.ProjectHistoryRequest) ([]registry.ProjectRevision, error) {
+	return nil, nil
+}
+func (m *mockCache) SnapshotDiff(context.Context, git.Hash, git.Hash) ([]registry.ProjectPath, error) {
+	return nil, nil
+}
+func (m *mockCache) GitConfig(context.Context) (map[string]string, error) {
+	return nil, nil
+}
+func (m *mockCache) BlobDir() string { return m.blobDir }
+func (m *mockCache) AmendBase(context.Context, git.Hash, git.Author, string, []registry.ProjectPath) (git.Hash, error) {
+	return "", nil
+}
+func (m *mockCache) PushAmend(context.Context, git.Hash, git.Hash) error {
+	return nil
+}
+func (m *mockCache) PruneOrphans(context.Context, git.Hash, func(string) bool) ([]registry.ProjectPath, error) {
+	return nil, nil
+}
+func (m *mockCache) DeleteProjects(context.Context, *registry.DeleteProjectsRequest) (git.Hash, error) {
+	return "", nil
+}
+func (m *mockCache) DeleteProject(context.Context, *registry.DeleteProjectRequest) (*registry.DeleteProjectResponse, error) {
+	return nil, nil
+}
+func (m *mockCache) RenameProject(context.Context, *registry.RenameProjectRequest) (*registry.RenameProjectResponse, error) {
+	return nil, nil
+}
+func (m *mockCache) FindSymbol(context.Context, *registry.FindSymbolRequest) (registry.ProjectPath, string, error) {
+	return "", "", errors.ErrNotFound
+}
 
 func (m *mockCache) LookupProject(ctx context.Context, req *registry.LookupProjectRequest) (*registry.LookupProjectResponse, error) {
 	if m.lookupProjectFunc != nil {
@@ -59,6 +125,19 @@ func (m *mockCache) ReadProjectFile(ctx context.Context, file registry.ProjectFi
 	return nil
 }
 
+func (m *mockCache) ReadProjectFiles(ctx context.Context, files []registry.ProjectFile, fn func(registry.ProjectFile, io.Reader) error) error {
+	for _, file := range files {
+		var buf bytes.Buffer
+		if err := m.ReadProjectFile(ctx, file, &buf); err != nil {
+			return err
+		}
+		if err := fn(file, &buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func TestNewRegistryResolver(t *testing.T) {
 	ctx := context.Background()
 	cache := &mockCache{}
@@ -295,6 +374,83 @@ func TestRegistryResolver_FindFileByPath_NilResolver(t *testing.T) {
 	}
 }
 
+func TestRegistryResolver_FindFileByPath_ConcurrentLoads(t *testing.T) {
+	log := zerolog.New(io.Discard)
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	var reads, maxInFlight atomic.Int32
+	cache := concurrencyCache(1, 20*time.Millisecond, &maxInFlight)
+	read := cache.readProjectFileFunc
+	cache.readProjectFileFunc = func(ctx context.Context, file registry.ProjectFile, w io.Writer) error {
+		reads.Add(1)
+		return read(ctx, file, w)
+	}
+	cache.lookupProjectFunc = func(ctx context.Context, req *registry.LookupProjectRequest) (*registry.LookupProjectResponse, error) {
+		return &registry.LookupProjectResponse{Project: &registry.Project{Path: "team/a"}}, nil
+	}
+	resolver := NewRegistryResolver(ctx, cache, "abc123")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := resolver.FindFileByPath("team/a/v1/f0.proto")
+			if err != nil {
+				errs <- err
+				return
+			}
+			if content, _ := io.ReadAll(res.Source); !strings.Contains(string(content), "team/a-0") {
+				errs <- fmt.Errorf("content = %q", content)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("FindFileByPath() error = %v", err)
+	}
+	if got := reads.Load(); got != 1 {
+		t.Errorf("ReadProjectFile called %d times, want 1", got)
+	}
+}
+
+func TestRegistryResolver_FindFileByPath_Cancelled(t *testing.T) {
+	log := zerolog.New(io.Discard)
+	ctx, cancel := context.WithCancel(logger.WithLogger(context.Background(), &log))
+	defer cancel()
+
+	var reads, maxInFlight atomic.Int32
+	cache := concurrencyCache(2, 0, &maxInFlight)
+	read := cache.readProjectFileFunc
+	cache.readProjectFileFunc = func(ctx context.Context, file registry.ProjectFile, w io.Writer) error {
+		reads.Add(1)
+		return read(ctx, file, w)
+	}
+	cache.lookupProjectFunc = func(ctx context.Context, req *registry.LookupProjectRequest) (*registry.LookupProjectResponse, error) {
+		return &registry.LookupProjectResponse{Project: &registry.Project{Path: "team/a"}}, nil
+	}
+	resolver := NewRegistryResolver(ctx, cache, "abc123")
+
+	if _, err := resolver.FindFileByPath("team/a/v1/f0.proto"); err != nil {
+		t.Fatalf("FindFileByPath() error = %v", err)
+	}
+	cancel()
+
+	if _, err := resolver.FindFileByPath("team/a/v1/f1.proto"); !stderrors.Is(err, context.Canceled) {
+		t.Errorf("FindFileByPath() after cancel error = %v, want context.Canceled", err)
+	}
+	if got := reads.Load(); got != 1 {
+		t.Errorf("ReadProjectFile called %d times, want 1", got)
+	}
+	// Files already loaded are still served from memory
+	if _, err := resolver.FindFileByPath("team/a/v1/f0.proto"); err != nil {
+		t.Errorf("FindFileByPath() of a loaded file after cancel error = %v", err)
+	}
+}
+
 func TestIsGoogleProtobufImport(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -338,20 +494,25 @@ func TestRegistryResolver_PreloadFiles(t *testing.T) {
 	ctx := logger.WithLogger(context.Background(), &log)
 	cache := &mockCache{}
 
+	var mu sync.Mutex // PreloadFiles reads concurrently
 	var filesListed []registry.ProjectPath
 	var filesRead []registry.ProjectFile
 
 	cache.listProjectFilesFunc = func(ctx context.Context, req *registry.ListProjectFilesRequest) (*registry.ListProjectFilesResponse, error) {
+		mu.Lock()
+		defer mu.Unlock()
 		filesListed = append(filesListed, req.Project)
 		return &registry.ListProjectFilesResponse{
 			Files: []registry.ProjectFile{
-				{Path: "v1/api.proto", Hash: git.Hash("hash1")},
-				{Path: "v1/messages.proto", Hash: git.Hash("hash2")},
+				{Path: "v1/api.proto", Hash: git.Hash(string(req.Project) + "-hash1")},
+				{Path: "v1/messages.proto", Hash: git.Hash(string(req.Project) + "-hash2")},
 			},
 		}, nil
 	}
 
 	cache.readProjectFileFunc = func(ctx context.Context, file registry.ProjectFile, w io.Writer) error {
+		mu.Lock()
+		defer mu.Unlock()
 		filesRead = append(filesRead, file)
 		w.Write([]byte("syntax = \"proto3\";"))
 		return nil
@@ -362,10 +523,13 @@ func TestRegistryResolver_PreloadFiles(t *testing.T) {
 	resolver.SetImportPrefix("proto")
 
 	projects := []registry.ProjectPath{"team/service1", "team/service2"}
-	err := resolver.PreloadFiles(ctx, projects, false)
+	failures, err := resolver.PreloadFiles(ctx, projects, false)
 	if err != nil {
 		t.Fatalf("PreloadFiles() error = %v", err)
 	}
+	if len(failures) != 0 {
+		t.Errorf("PreloadFiles() failures = %v, want none", failures)
+	}
 
 	if !resolver.preloaded {
 		t.Error("PreloadFiles() preloaded = false, want true")
@@ -387,6 +551,167 @@ func TestRegistryResolver_PreloadFiles(t *testing.T) {
 	}
 }
 
+// concurrencyCache is a mockCache whose reads take a moment and record how many ran at once.
+func concurrencyCache(filesPerProject int, delay time.Duration, maxInFlight *atomic.Int32) *mockCache {
+	var inFlight atomic.Int32
+	return &mockCache{
+		listProjectFilesFunc: func(ctx context.Context, req *registry.ListProjectFilesRequest) (*registry.ListProjectFilesResponse, error) {
+			files := make([]registry.ProjectFile, filesPerProject)
+			for i := range files {
+				files[i] = registry.ProjectFile{Path: fmt.Sprintf("v1/f%d.proto", i), Hash: git.Hash(fmt.Sprintf("%s-%d", req.Project, i))}
+			}
+			return &registry.ListProjectFilesResponse{Files: files}, nil
+		},
+		readProjectFileFunc: func(ctx context.Context, file registry.ProjectFile, w io.Writer) error {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				max := maxInFlight.Load()
+				if n <= max || maxInFlight.CompareAndSwap(max, n) {
+					break
+				}
+			}
+			time.Sleep(delay)
+			_, err := fmt.Fprintf(w, "syntax = \"proto3\"; // %s", file.Hash)
+			return err
+		},
+	}
+}
+
+func TestRegistryResolver_PreloadFiles_BoundedConcurrency(t *testing.T) {
+	log := zerolog.New(io.Discard)
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	for _, limit := range []int{1, 3} {
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			var maxInFlight atomic.Int32
+			resolver := NewRegistryResolver(ctx, concurrencyCache(4, 5*time.Millisecond, &maxInFlight), "abc123")
+			resolver.SetConcurrency(limit)
+
+			projects := []registry.ProjectPath{"team/a", "team/b", "team/c"}
+			failures, err := resolver.PreloadFiles(ctx, projects, false)
+			if err != nil || len(failures) != 0 {
+				t.Fatalf("PreloadFiles() = %v, %v", failures, err)
+			}
+			if got := int(maxInFlight.Load()); got != limit {
+				t.Errorf("ReadProjectFile ran %d at once, want %d", got, limit)
+			}
+			if got := len(resolver.fileCache); got != 12 {
+				t.Errorf("PreloadFiles() cached %d files, want 12", got)
+			}
+		})
+	}
+}
+
+func TestRegistryResolver_PreloadFiles_CacheOrder(t *testing.T) {
+	log := zerolog.New(io.Discard)
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	// Both files have the registry path test-service/common/v1/api.proto. The later project
+	// wins, as it did when projects were loaded one after another, even though its read
+	// finishes first.
+	files := map[registry.ProjectPath]string{
+		"test-service/common":    "v1/api.proto",
+		"test-service/common/v1": "api.proto",
+	}
+	cache := &mockCache{
+		listProjectFilesFunc: func(ctx context.Context, req *registry.ListProjectFilesRequest) (*registry.ListProjectFilesResponse, error) {
+			return &registry.ListProjectFilesResponse{
+				Files: []registry.ProjectFile{{Path: files[req.Project], Hash: git.Hash(req.Project)}},
+			}, nil
+		},
+		readProjectFileFunc: func(ctx context.Context, file registry.ProjectFile, w io.Writer) error {
+			if file.Hash == "test-service/common" {
+				time.Sleep(10 * time.Millisecond)
+			}
+			_, err := w.Write([]byte(file.Hash))
+			return err
+		},
+	}
+	resolver := NewRegistryResolver(ctx, cache, "abc123")
+	resolver.SetServicePrefix("test-service")
+	resolver.SetImportPrefix("proto")
+
+	if _, err := resolver.PreloadFiles(ctx, []registry.ProjectPath{"test-service/common", "test-service/common/v1"}, false); err != nil {
+		t.Fatalf("PreloadFiles() error = %v", err)
+	}
+	if got, _ := resolver.getCachedFile("proto/common/v1/api.proto"); string(got) != "test-service/common/v1" {
+		t.Errorf("cached content = %q, want %q", got, "test-service/common/v1")
+	}
+}
+
+func BenchmarkRegistryResolver_PreloadFiles(b *testing.B) {
+	log := zerolog.New(io.Discard)
+	ctx := logger.WithLogger(context.Background(), &log)
+	projects := []registry.ProjectPath{"team/a", "team/b", "team/c", "team/d"}
+
+	for _, limit := range []int{1, defaultPreloadConcurrency} {
+		b.Run(fmt.Sprintf("concurrency %d", limit), func(b *testing.B) {
+			var maxInFlight atomic.Int32
+			cache := concurrencyCache(50, 100*time.Microsecond, &maxInFlight)
+			for i := 0; i < b.N; i++ {
+				resolver := NewRegistryResolver(ctx, cache, "abc123")
+				resolver.SetConcurrency(limit)
+				if _, err := resolver.PreloadFiles(ctx, projects, false); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistryResolver_PreloadFiles_PartialFailure(t *testing.T) {
+	log := zerolog.New(io.Discard)
+	ctx := logger.WithLogger(context.Background(), &log)
+	cache := &mockCache{
+		listProjectFilesFunc: func(ctx context.Context, req *registry.ListProjectFilesRequest) (*registry.ListProjectFilesResponse, error) {
+			if req.Project == "team/broken" {
+				return nil, errors.ErrNotFound
+			}
+			return &registry.ListProjectFilesResponse{
+				Files: []registry.ProjectFile{{Path: "v1/api.proto", Hash: git.Hash("hash1")}},
+			}, nil
+		},
+		readProjectFileFunc: func(ctx context.Context, file registry.ProjectFile, w io.Writer) error {
+			w.Write([]byte("syntax = \"proto3\";"))
+			return nil
+		},
+	}
+
+	resolver := NewRegistryResolver(ctx, cache, git.Hash("abc123"))
+	projects := []registry.ProjectPath{"team/service", "team/broken"}
+
+	failures, err := resolver.PreloadFiles(ctx, projects, false)
+	if err != nil {
+		t.Fatalf("PreloadFiles() error = %v", err)
+	}
+	if len(failures) != 1 || failures[0].Project != "team/broken" {
+		t.Fatalf("PreloadFiles() failures = %v, want one for team/broken", failures)
+	}
+
+	protoFiles, listFailures := buildProtoFileList(ctx, cache, git.Hash("abc123"), projects, resolver)
+	if len(protoFiles) != 1 {
+		t.Errorf("buildProtoFileList() files = %v, want 1", protoFiles)
+	}
+	if len(listFailures) != 1 || listFailures[0].Project != "team/broken" {
+		t.Fatalf("buildProtoFileList() failures = %v, want one for team/broken", listFailures)
+	}
+
+	all := append(failures, listFailures...)
+	if err := reportLoadFailures(ctx, all, false); err != nil {
+		t.Errorf("reportLoadFailures() without fail = %v, want nil", err)
+	}
+
+	err = reportLoadFailures(ctx, all, true)
+	var loadErr *LoadError
+	if !stderrors.As(err, &loadErr) {
+		t.Fatalf("reportLoadFailures() = %v, want *LoadError", err)
+	}
+	if !strings.HasPrefix(loadErr.Error(), "1 projects could not be loaded") {
+		t.Errorf("LoadError.Error() = %q, want summary of 1 project", loadErr.Error())
+	}
+}
+
 func TestRegistryResolver_untransformImports(t *testing.T) {
 	ctx := context.Background()
 	resolver := NewRegistryResolver(ctx, &mockCache{}, git.Hash("abc123"))
@@ -547,6 +872,61 @@ func TestExtractImportPathFromLine(t *testing.T) {
 			line: `import "google/protobuf/timestamp.proto";`,
 			want: "google/protobuf/timestamp.proto",
 		},
+		{
+			name: "tab after import",
+			line: "import\t\"common/address.proto\";",
+			want: "common/address.proto",
+		},
+		{
+			name: "no space before quote",
+			line: `import"common/address.proto";`,
+			want: "common/address.proto",
+		},
+		{
+			name: "public import",
+			line: `import public "common/address.proto";`,
+			want: "common/address.proto",
+		},
+		{
+			name: "weak import named like the modifier",
+			line: `import weak "weak";`,
+			want: "weak",
+		},
+		{
+			name: "mismatched quotes",
+			line: `import "common/address.proto';`,
+			want: "",
+		},
+		{
+			name: "import with no path",
+			line: `import ;`,
+			want: "",
+		},
+		{
+			name: "empty path",
+			line: `import "";`,
+			want: "",
+		},
+		{
+			name: "identifier starting with import",
+			line: `imports "common/address.proto";`,
+			want: "",
+		},
+		{
+			name: "unknown token before path",
+			line: `import foo "common/address.proto";`,
+			want: "",
+		},
+		{
+			name: "trailing comment",
+			line: `import "common/address.proto"; // was "common/old.proto"`,
+			want: "common/address.proto",
+		},
+		{
+			name: "commented-out import",
+			line: `// import "common/address.proto";`,
+			want: "",
+		},
 	}
 
 	for _, tt := range tests {
@@ -616,6 +996,13 @@ func TestTransformImports(t *testing.T) {
 			servicePrefix: "my-service",
 			want:          "syntax = \"proto3\";\npackage test;",
 		},
+		{
+			name:          "public and weak imports",
+			content:       "import public \"proto/common/address.proto\";\nimport weak \"proto/common/types.proto\";",
+			ownedDir:      "proto",
+			servicePrefix: "my-service",
+			want:          "import public \"my-service/common/address.proto\";\nimport weak \"my-service/common/types.proto\";",
+		},
 	}
 
 	for _, tt := range tests {
@@ -628,6 +1015,29 @@ func TestTransformImports(t *testing.T) {
 	}
 }
 
+func TestMoveImports(t *testing.T) {
+	content := strings.Join([]string{
+		`syntax = "proto3";`,
+		`import "team/service/v1/api.proto";`,
+		`import public 'team/service/types.proto'; // shared`,
+		`import "team/servicex/v1/api.proto";`,
+		`import "my-svc/team/service/v1/api.proto";`,
+		`// see team/service/v1/api.proto`,
+	}, "\n")
+	want := strings.Join([]string{
+		`syntax = "proto3";`,
+		`import "team/newservice/v1/api.proto";`,
+		`import public 'team/newservice/types.proto'; // shared`,
+		`import "team/servicex/v1/api.proto";`,
+		`import "my-svc/team/service/v1/api.proto";`,
+		`// see team/service/v1/api.proto`,
+	}, "\n")
+
+	if got := string(MoveImports([]byte(content), "team/service", "team/newservice")); got != want {
+		t.Errorf("MoveImports() = %q, want %q", got, want)
+	}
+}
+
 func TestTransformImportsWithPulled(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -661,6 +1071,13 @@ func TestTransformImportsWithPulled(t *testing.T) {
 			pulledPrefixes: []string{"other-svc"},
 			want:           `import "google/protobuf/timestamp.proto";`,
 		},
+		{
+			name:           "editions file keeps its edition line",
+			content:        "edition = \"2023\";\n\npackage my.common;\n\nimport \"proto/common/address.proto\";\nimport \"google/protobuf/go_features.proto\";\n\noption features.(pb.go).api_level = API_OPAQUE;",
+			ownedDir:       "proto",
+			servicePrefix:  "my-service",
+			want:           "edition = \"2023\";\n\npackage my.common;\n\nimport \"my-service/common/address.proto\";\nimport \"google/protobuf/go_features.proto\";\n\noption features.(pb.go).api_level = API_OPAQUE;",
+		},
 		{
 			name:           "mixed imports",
 			content:        "import \"proto/common/address.proto\";\nimport \"proto/other-svc/types.proto\";",
@@ -719,6 +1136,21 @@ func TestTransformImportLine(t *testing.T) {
 			pulledPrefixes: []string{"other-svc"},
 			want:           `import "other-svc/types.proto";`,
 		},
+		{
+			name:          "public import keeps modifier",
+			line:          `import public "proto/common/address.proto";`,
+			ownedDir:      "proto",
+			servicePrefix: "my-service",
+			want:          `import public "my-service/common/address.proto";`,
+		},
+		{
+			name:           "weak import of pulled project keeps modifier",
+			line:           `import weak "proto/other-svc/types.proto";`,
+			ownedDir:       "proto",
+			servicePrefix:  "my-service",
+			pulledPrefixes: []string{"other-svc"},
+			want:           `import weak "other-svc/types.proto";`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -897,6 +1329,31 @@ func TestExtractImportsFromContent(t *testing.T) {
 			content: "syntax = \"proto3\";\npackage test;",
 			want:    nil,
 		},
+		{
+			name:    "editions file",
+			content: "edition = \"2023\";\npackage test;\nimport \"common/address.proto\";\nimport \"google/protobuf/go_features.proto\";\noption features.field_presence = IMPLICIT;",
+			want:    []string{"common/address.proto"},
+		},
+		{
+			name:    "public and weak imports",
+			content: "import public \"common/address.proto\";\nimport weak \"common/types.proto\";",
+			want:    []string{"common/address.proto", "common/types.proto"},
+		},
+		{
+			name:    "commented-out imports skipped",
+			content: "// import \"common/old.proto\";\n/*\nimport \"common/older.proto\";\n*/\nimport \"common/address.proto\";",
+			want:    []string{"common/address.proto"},
+		},
+		{
+			name:    "import with trailing comment",
+			content: "import \"common/address.proto\"; // import \"common/types.proto\";",
+			want:    []string{"common/address.proto"},
+		},
+		{
+			name:    "import-like text in an option value",
+			content: "option go_package = \"import \\\"common/types.proto\\\"\";\nimport public \"common/address.proto\";",
+			want:    []string{"common/address.proto"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -915,6 +1372,151 @@ func TestExtractImportsFromContent(t *testing.T) {
 	}
 }
 
+func TestCheckGoogleImports(t *testing.T) {
+	log := zerolog.New(io.Discard)
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	tests := []struct {
+		name      string
+		content   string
+		allowlist []string
+		cached    map[string]string
+		wantErr   bool
+	}{
+		{
+			name:    "default allowlist permits well-known types",
+			content: "import \"google/protobuf/timestamp.proto\";",
+		},
+		{
+			name:    "default allowlist rejects other google imports",
+			content: "import \"google/type/money.proto\";",
+			wantErr: true,
+		},
+		{
+			name:      "configured allowlist permits google api",
+			content:   "import \"google/api/annotations.proto\";",
+			allowlist: []string{"google/protobuf/**", "google/api/**"},
+		},
+		{
+			name:      "configured allowlist rejects unlisted import",
+			content:   "import \"google/rpc/status.proto\";",
+			allowlist: []string{"google/protobuf/**", "google/api/**"},
+			wantErr:   true,
+		},
+		{
+			name:    "resolvable import is accepted without allowlisting",
+			content: "import \"google/type/money.proto\";",
+			cached:  map[string]string{"google/type/money.proto": "syntax = \"proto3\";"},
+		},
+		{
+			name:    "non-google imports are ignored",
+			content: "import \"common/address.proto\";",
+		},
+		{
+			name:    "commented-out google import is ignored",
+			content: "/* import \"google/type/money.proto\"; */",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := NewRegistryResolver(ctx, &mockCache{}, "abc123")
+			resolver.cacheFile("proto/svc/file.proto", []byte(tt.content))
+			for p, c := range tt.cached {
+				resolver.cacheFile(p, []byte(c))
+			}
+
+			err := checkGoogleImports(ctx, resolver, []string{"proto/svc/file.proto"}, tt.allowlist)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkGoogleImports() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateProtos_LocalOwnedImports(t *testing.T) {
+	log := zerolog.New(io.Discard)
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	// svc/a is in the snapshot and imports svc/b, which only exists in the workspace
+	cache := &mockCache{
+		listProjectFilesFunc: func(ctx context.Context, req *registry.ListProjectFilesRequest) (*registry.ListProjectFilesResponse, error) {
+			if req.Project != "svc/a" {
+				return &registry.ListProjectFilesResponse{}, nil
+			}
+			return &registry.ListProjectFilesResponse{
+				Files: []registry.ProjectFile{{Project: req.Project, Path: "a.proto", Hash: "hash-a"}},
+			}, nil
+		},
+		readProjectFileFunc: func(ctx context.Context, file registry.ProjectFile, w io.Writer) error {
+			_, err := w.Write([]byte("syntax = \"proto3\";\npackage a;\nimport \"svc/b/b.proto\";\nmessage A { b.B b = 1; }\n"))
+			return err
+		},
+	}
+
+	tests := []struct {
+		name      string
+		localB    bool
+		wantError bool
+	}{
+		{name: "sibling owned project resolves locally", localB: true},
+		{name: "missing sibling fails", localB: false, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			if tt.localB {
+				dir := filepath.Join(root, "proto", "b")
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				content := "syntax = \"proto3\";\npackage b;\nmessage B {}\n"
+				if err := os.WriteFile(filepath.Join(dir, "b.proto"), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := ValidateProtos(ctx, ValidateProtosConfig{
+				Cache:         cache,
+				Snapshot:      git.Hash("abc123"),
+				Projects:      []registry.ProjectPath{"svc/a"},
+				OwnedDir:      "proto",
+				WorkspaceRoot: root,
+				ServiceName:   "svc",
+			})
+			if (err != nil) != tt.wantError {
+				t.Errorf("ValidateProtos() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestNewWorkspaceResolver_VendorRoots(t *testing.T) {
+	log := zerolog.New(io.Discard)
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	// A flat vendor root holds other-svc/team/api without its service
+	root := t.TempDir()
+	dir := filepath.Join(root, "vendor", "team", "api", "v1")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "api.proto"), []byte("syntax = \"proto3\";\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := NewWorkspaceResolver(ctx, []OwnedRoot{{Dir: filepath.Join(root, "proto"), ImportPrefix: "proto"}}, []VendorRoot{
+		{Dir: filepath.Join(root, "vendor", "team", "api"), ImportPrefix: "other-svc/team/api"},
+	})
+	if _, err := resolver.FindFileByPath("other-svc/team/api/v1/api.proto"); err != nil {
+		t.Errorf("FindFileByPath() error = %v", err)
+	}
+	if _, err := resolver.FindFileByPath("v1/api.proto"); err == nil {
+		t.Error("FindFileByPath() of the path relative to the root error = nil, want error")
+	}
+}
+
 func TestCompileError_Error(t *testing.T) {
 	err := &CompileError{Message: "syntax error at line 10"}
 	got := err.Error()
@@ -948,3 +1550,181 @@ func TestLogReporterInit(t *testing.T) {
 		t.Error("LogReporter.failed should be false by default")
 	}
 }
+
+func TestReplaceImportPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		newPath string
+		want    string
+	}{
+		{
+			name:    "replaces path only",
+			line:    `import "common/a.proto"; // common/a.proto`,
+			newPath: "svc/common/a.proto",
+			want:    `import "svc/common/a.proto"; // common/a.proto`,
+		},
+		{
+			name:    "path equal to modifier",
+			line:    `import weak "weak";`,
+			newPath: "svc/weak",
+			want:    `import weak "svc/weak";`,
+		},
+		{
+			name:    "not an import",
+			line:    `syntax = "proto3";`,
+			newPath: "x",
+			want:    `syntax = "proto3";`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replaceImportPath(tt.line, tt.newPath); got != tt.want {
+				t.Errorf("replaceImportPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// FuzzImportLineParsing checks the import line parsers never panic, only ever
+// rewrite the quoted path of an import, and leave every other line untouched.
+func FuzzImportLineParsing(f *testing.F) {
+	for _, seed := range []string{
+		`import "common/address.proto";`,
+		`import 'common/address.proto';`,
+		`  import public "proto/common/a.proto";`,
+		`import weak "weak";`,
+		"import\t\"a.proto\";",
+		`import "a.proto';`,
+		`import "`,
+		`import`,
+		`import ;`,
+		`syntax = "proto3";`,
+		`// import "commented.proto";`,
+		`option go_package = "import \"x\"";`,
+		`svc/common/a.proto`,
+	} {
+		f.Add(seed)
+	}
+
+	resolver := NewRegistryResolver(context.Background(), &mockCache{}, git.Hash("abc123"))
+	resolver.SetServicePrefix("svc")
+	resolver.SetImportPrefix("proto")
+
+	f.Fuzz(func(t *testing.T, line string) {
+		start, end, ok := importPathSpan(line)
+		results := []string{
+			transformImportLine(line, "proto", "svc", []string{"other-svc"}),
+			transformImportLine(line, "", "svc", nil),
+		}
+		untransformed, changed := resolver.untransformImportLine(line)
+		results = append(results, untransformed)
+
+		if !ok {
+			if extractImportPathFromLine(line) != "" {
+				t.Fatalf("extractImportPathFromLine(%q) returned a path for a non-import line", line)
+			}
+			if changed {
+				t.Fatalf("untransformImportLine(%q) reported a change for a non-import line", line)
+			}
+			for _, got := range results {
+				if got != line {
+					t.Fatalf("non-import line %q was rewritten to %q", line, got)
+				}
+			}
+			return
+		}
+
+		if start <= 0 || end <= start || end >= len(line) {
+			t.Fatalf("importPathSpan(%q) = %d, %d out of range", line, start, end)
+		}
+		for _, got := range results {
+			if !strings.HasPrefix(got, line[:start]) || !strings.HasSuffix(got, line[end:]) {
+				t.Fatalf("import line %q was rewritten outside its path: %q", line, got)
+			}
+		}
+	})
+}
+
+func TestCompileProtoFiles_Editions(t *testing.T) {
+	files := map[string][]byte{
+		"svc/types.proto": []byte("edition = \"2023\";\npackage types;\noption features.field_presence = IMPLICIT;\nmessage Types { string name = 1; }\n"),
+		"svc/api.proto": []byte("edition = \"2023\";\npackage api;\nimport \"svc/types.proto\";\nimport \"google/protobuf/go_features.proto\";\n" +
+			"option features.(pb.go).legacy_unmarshal_json_enum = false;\n" +
+			"message Request { types.Types types = 1; string id = 2 [features.field_presence = EXPLICIT]; }\n" +
+			"enum Kind { option features.enum_type = CLOSED; KIND_UNSPECIFIED = 0; }\n"),
+		"svc/legacy.proto": []byte("syntax = \"proto3\";\npackage legacy;\nimport \"svc/types.proto\";\nmessage Legacy { types.Types types = 1; }\n"),
+	}
+
+	tests := []struct {
+		name        string
+		file        string
+		strict      bool
+		wantWarning bool
+		wantErr     bool
+	}{
+		{name: "editions importing editions", file: "svc/api.proto", strict: true},
+		{name: "proto3 importing editions warns", file: "svc/legacy.proto", wantWarning: true},
+		{name: "proto3 importing editions fails when strict", file: "svc/legacy.proto", strict: true, wantWarning: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			log := zerolog.New(&out)
+			ctx := logger.WithLogger(context.Background(), &log)
+
+			err := compileProtoFiles(ctx, NewMemoryResolver(ctx, files), []string{tt.file}, tt.strict)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compileProtoFiles() error = %v, wantErr %v; log: %s", err, tt.wantErr, out.String())
+			}
+
+			gotWarning := strings.Contains(out.String(), "different syntax")
+			if gotWarning != tt.wantWarning {
+				t.Errorf("syntax mismatch logged = %v, want %v; log: %s", gotWarning, tt.wantWarning, out.String())
+			}
+		})
+	}
+}
+
+func TestCompileProtoFiles_SyntaxMismatch(t *testing.T) {
+	files := map[string][]byte{
+		"svc/legacy.proto":  []byte("syntax = \"proto2\";\npackage legacy;\nmessage Legacy { optional string id = 1; }\n"),
+		"svc/types.proto":   []byte("syntax = \"proto3\";\npackage types;\nmessage Types {}\n"),
+		"svc/mixed.proto":   []byte("syntax = \"proto3\";\npackage mixed;\nimport \"svc/legacy.proto\";\nmessage Mixed { legacy.Legacy l = 1; }\n"),
+		"svc/same.proto":    []byte("syntax = \"proto3\";\npackage same;\nimport \"svc/types.proto\";\nmessage Same { types.Types t = 1; }\n"),
+		"svc/options.proto": []byte("syntax = \"proto3\";\npackage options;\nimport \"google/protobuf/descriptor.proto\";\nextend google.protobuf.FieldOptions { string tag = 50000; }\n"),
+	}
+
+	tests := []struct {
+		name        string
+		file        string
+		strict      bool
+		wantWarning bool
+		wantErr     bool
+	}{
+		{name: "proto3 importing proto2 warns", file: "svc/mixed.proto", wantWarning: true},
+		{name: "proto3 importing proto2 fails when strict", file: "svc/mixed.proto", strict: true, wantWarning: true, wantErr: true},
+		{name: "same syntax is silent", file: "svc/same.proto", strict: true},
+		{name: "google/protobuf imports are skipped", file: "svc/options.proto", strict: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			log := zerolog.New(&out)
+			ctx := logger.WithLogger(context.Background(), &log)
+
+			err := compileProtoFiles(ctx, NewMemoryResolver(ctx, files), []string{tt.file}, tt.strict)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compileProtoFiles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			gotWarning := strings.Contains(out.String(), "different syntax")
+			if gotWarning != tt.wantWarning {
+				t.Errorf("syntax mismatch logged = %v, want %v; log: %s", gotWarning, tt.wantWarning, out.String())
+			}
+		})
+	}
+}
@@ -2,21 +2,48 @@
 package protoc
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+	"github.com/rahulagarwal0605/protato/internal/errors"
 	"github.com/rahulagarwal0605/protato/internal/git"
 	"github.com/rahulagarwal0605/protato/internal/registry"
 )
 
 // ValidateProtosConfig holds configuration for ValidateProtos.
 type ValidateProtosConfig struct {
-	Cache         registry.CacheInterface
-	Snapshot      git.Hash
-	Projects      []registry.ProjectPath
-	OwnedDir      string // Local directory prefix used in proto imports (e.g., "proto")
-	VendorDir     string // Directory containing pulled dependencies
-	WorkspaceRoot string // Root directory of the workspace (for finding buf.yaml)
-	ServiceName   string // Service name from workspace configuration (e.g., "lcs-svc")
+	Cache            registry.CacheInterface
+	Snapshot         git.Hash
+	Projects         []registry.ProjectPath
+	OwnedDir         string       // Local directory prefix used in proto imports (e.g., "proto")
+	Owned            []OwnedRoot  // Every owned directory with its projects; empty uses OwnedDir under WorkspaceRoot
+	Vendor           []VendorRoot // Directories containing pulled dependencies
+	WorkspaceRoot    string       // Root directory of the workspace (for finding buf.yaml)
+	ServiceName      string       // Service name from workspace configuration (e.g., "lcs-svc")
+	GoogleImports    []string     // Allowed google/* import patterns (defaults to DefaultGoogleImports)
+	FailOnLoadErrors bool         // Fail validation if any project could not be loaded
+	StrictSyntax     bool         // Fail validation when a file imports a file of another syntax
+}
+
+// OwnedRoot is an owned directory and the local projects in it. Owned imports of its files
+// start with ImportPrefix.
+type OwnedRoot struct {
+	Dir          string   // Absolute directory
+	ImportPrefix string   // Owned directory name (e.g., "proto"; empty for the workspace root)
+	Projects     []string // Local project paths in Dir (e.g., "team/service")
 }
 
+// VendorRoot is a directory of pulled files and the import path prefix of the files below it.
+type VendorRoot struct {
+	Dir          string // Absolute directory
+	ImportPrefix string // Prepended to paths relative to Dir (empty when they are import paths)
+}
+
+// DefaultGoogleImports is the google/* import allowlist used when none is configured.
+// It covers the well-known types bundled with protocompile.
+var DefaultGoogleImports = []string{constants.GoogleProtobufPrefix + "**"}
+
 // CompileError represents a compilation error.
 type CompileError struct {
 	Message string
@@ -25,3 +52,141 @@ type CompileError struct {
 func (e *CompileError) Error() string {
 	return e.Message
 }
+
+// ProjectError records a project that could not be (fully) loaded.
+type ProjectError struct {
+	Project registry.ProjectPath
+	Err     error
+}
+
+func (e ProjectError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Project, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ProjectError) Unwrap() error {
+	return e.Err
+}
+
+// LoadError reports projects that could not be loaded for validation.
+type LoadError struct {
+	Errors []ProjectError
+}
+
+func (e *LoadError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, pe := range e.Errors {
+		msgs[i] = pe.Error()
+	}
+	return fmt.Sprintf("%d projects could not be loaded: %s", countFailedProjects(e.Errors), strings.Join(msgs, "; "))
+}
+
+// CycleError reports projects whose proto files import each other in a cycle.
+// It matches errors.ErrImportCycle with errors.Is.
+type CycleError struct {
+	Cycles [][]registry.ProjectPath // Ordered path of each cycle, closed by its first project (e.g., [a b a])
+}
+
+// Error returns the message.
+func (e *CycleError) Error() string {
+	msgs := make([]string, len(e.Cycles))
+	for i, cycle := range e.Cycles {
+		msgs[i] = cyclePath(cycle)
+	}
+	return fmt.Sprintf("%s: %s", errors.ErrImportCycle, strings.Join(msgs, "; "))
+}
+
+// Is matches errors.ErrImportCycle.
+func (e *CycleError) Is(target error) bool {
+	return target == errors.ErrImportCycle
+}
+
+// cyclePath formats a cycle as "a -> b -> a".
+func cyclePath(cycle []registry.ProjectPath) string {
+	parts := make([]string, len(cycle))
+	for i, p := range cycle {
+		parts[i] = string(p)
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// countFailedProjects counts the distinct projects in a list of project errors.
+func countFailedProjects(errs []ProjectError) int {
+	seen := make(map[registry.ProjectPath]bool)
+	for _, pe := range errs {
+		seen[pe.Project] = true
+	}
+	return len(seen)
+}
+
+// Lint rule names.
+const (
+	LintRulePackageLowerSnakeCase = "package-lower-snake-case"    // Package components are lower_snake_case
+	LintRulePackageDirectoryMatch = "package-directory-match"     // File directory ends with the package path
+	LintRuleMessagePascalCase     = "message-pascal-case"         // Message names are PascalCase
+	LintRuleFieldLowerSnakeCase   = "field-lower-snake-case"      // Field names are lower_snake_case
+	LintRuleServicePascalCase     = "service-pascal-case"         // Service names are PascalCase
+	LintRuleServiceSuffix         = "service-suffix"              // Service names end in Service
+	LintRuleRPCPascalCase         = "rpc-pascal-case"             // RPC names are PascalCase
+	LintRuleEnumZeroUnspecified   = "enum-zero-value-unspecified" // The zero value of an enum ends in UNSPECIFIED
+)
+
+// AllLintRules lists every available lint rule.
+var AllLintRules = []string{
+	LintRulePackageLowerSnakeCase,
+	LintRulePackageDirectoryMatch,
+	LintRuleMessagePascalCase,
+	LintRuleFieldLowerSnakeCase,
+	LintRuleServicePascalCase,
+	LintRuleServiceSuffix,
+	LintRuleRPCPascalCase,
+	LintRuleEnumZeroUnspecified,
+}
+
+// LintRules is the set of enabled lint rules.
+type LintRules map[string]bool
+
+// LintIssue is a single lint finding.
+type LintIssue struct {
+	File    string // Import path of the file
+	Line    int    // 1-based line (0 if unknown)
+	Column  int    // 1-based column (0 if unknown)
+	Rule    string // Rule that reported the issue
+	Message string
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", i.File, i.Line, i.Column, i.Message, i.Rule)
+}
+
+// Compatibility rule names.
+const (
+	CompatRuleFieldNumberChanged = "field-number-changed"     // A field kept its name but changed number
+	CompatRuleFieldTypeChanged   = "field-type-changed"       // A field number's wire type or message type changed
+	CompatRuleFieldCardinality   = "field-cardinality"        // A field number switched between repeated and singular
+	CompatRuleFieldNotReserved   = "field-removed-unreserved" // A removed field number was not reserved
+	CompatRuleReservedReused     = "reserved-reused"          // A baseline reserved number or name is used again
+	CompatRuleEnumValueChanged   = "enum-value-changed"       // An enum value kept its name but changed number
+	CompatRuleMessageRemoved     = "message-removed"          // A message was removed
+	CompatRuleEnumRemoved        = "enum-removed"             // An enum was removed
+	CompatRuleFieldRemoved       = "field-removed"            // A field number was removed from a message, reserved or not
+	CompatRuleRPCRemoved         = "rpc-removed"              // A method was removed from a service, or its service was removed
+)
+
+// CompatSource is one side of a compatibility check: files compiled with a resolver.
+type CompatSource struct {
+	Resolver RegistryResolverInterface
+	Files    []string // Import paths of the files to compile
+}
+
+// CompatIssue is a single incompatibility between a baseline and the current protos.
+type CompatIssue struct {
+	File    string // Import path of the file that defined the element in the baseline
+	Element string // Fully-qualified name of the affected message, field, enum or method
+	Rule    string // Rule that reported the issue
+	Message string
+}
+
+func (i CompatIssue) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", i.File, i.Element, i.Message, i.Rule)
+}
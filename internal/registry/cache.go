@@ -4,15 +4,21 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
+	stderrors "errors"
 	"fmt"
 	"io"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
+	"golang.org/x/sync/singleflight"
 	"gopkg.in/yaml.v3"
 
 	"github.com/rahulagarwal0605/protato/internal/constants"
@@ -57,6 +63,11 @@ func buildRemoteBranchRef(branch string) string {
 	return "refs/remotes/origin/" + branch
 }
 
+// buildTagRef builds a tag ref: refs/tags/{tag}
+func buildTagRef(tag string) string {
+	return "refs/tags/" + tag
+}
+
 // writeObject writes an object to the git repository.
 func (r *Cache) writeObject(ctx context.Context, reader io.Reader) (git.Hash, error) {
 	return r.repo.WriteObject(ctx, reader, git.WriteObjectOptions{})
@@ -86,17 +97,38 @@ func (r *Cache) checkHashMatch(ctx context.Context, rev string, hash git.Hash) b
 type CacheInterface interface {
 	Close() error
 	Refresh(context.Context) error
+	RefreshBranch(context.Context, string) error
+	BranchSnapshot(context.Context, string) (git.Hash, error)
+	ResolveRef(context.Context, string) (git.Hash, error)
+	EnsureSnapshotAvailable(context.Context, git.Hash) error
 	Snapshot(context.Context) (git.Hash, error)
 	LookupProject(context.Context, *LookupProjectRequest) (*LookupProjectResponse, error)
 	ListProjects(context.Context, *ListProjectsOptions) ([]ProjectPath, error)
+	ListProjectsPage(context.Context, *ListProjectsOptions) (*ListProjectsResponse, error)
+	SnapshotDiff(context.Context, git.Hash, git.Hash) ([]ProjectPath, error)
+	GetProjectHistory(context.Context, *ProjectHistoryRequest) ([]ProjectRevision, error)
+	Config(context.Context, git.Hash) (*Config, error)
+	Diagnose(context.Context) []Finding
 	ListProjectFiles(context.Context, *ListProjectFilesRequest) (*ListProjectFilesResponse, error)
 	ReadProjectFile(context.Context, ProjectFile, io.Writer) error
+	ReadProjectFiles(context.Context, []ProjectFile, func(ProjectFile, io.Reader) error) error
 	SetProject(context.Context, *SetProjectRequest) (*SetProjectResponse, error)
-	Push(context.Context, git.Hash) error
+	SetProjects(context.Context, []*SetProjectRequest) (*SetProjectResponse, error)
+	PlanProjects(context.Context, []*SetProjectRequest) ([]FileChange, error)
+	Push(context.Context, git.Hash) (git.Hash, error)
+	AmendBase(context.Context, git.Hash, git.Author, string, []ProjectPath) (git.Hash, error)
+	PushAmend(context.Context, git.Hash, git.Hash) error
 	URL() string
+	BlobDir() string
 	GetSnapshot(context.Context) (git.Hash, error)
 	RefreshAndGetSnapshot(context.Context) (git.Hash, error)
 	CheckProjectClaim(context.Context, git.Hash, string, string) error
+	PruneOrphans(context.Context, git.Hash, func(string) bool) ([]ProjectPath, error)
+	DeleteProjects(context.Context, *DeleteProjectsRequest) (git.Hash, error)
+	DeleteProject(context.Context, *DeleteProjectRequest) (*DeleteProjectResponse, error)
+	RenameProject(context.Context, *RenameProjectRequest) (*RenameProjectResponse, error)
+	FindSymbol(context.Context, *FindSymbolRequest) (ProjectPath, string, error)
+	GitConfig(context.Context) (map[string]string, error)
 }
 
 // Cache manages the local cache of the remote registry.
@@ -105,67 +137,179 @@ type Cache struct {
 	repo     git.RepositoryInterface   // Bare Git repository
 	url      string                    // Registry URL
 	mu       sync.Mutex                // Protects concurrent access to git operations
-	lockFile *os.File                  // File lock for cross-process synchronization
+	lockFile *os.File                  // Shared lock held while the cache is open, so it is not removed
+	ops      *opLock                   // Cross-process lock taken by each cache operation
+
+	refreshGroup singleflight.Group // Coalesces concurrent refreshes into one fetch
+
+	configMu sync.Mutex            // Protects configs
+	configs  map[git.Hash]*Config // Registry config by snapshot; snapshots never change
+
+	branch         string    // Configured registry branch; empty follows the remote's default branch
+	remoteHeadOnce sync.Once // Asks the remote for its default branch once per process
+	remoteHead     string    // Branch the remote's HEAD points to; empty if unknown
+}
+
+// refreshKey is the singleflight key for registry refreshes.
+const refreshKey = "refresh"
+
+// checkCacheIntegrity verifies that an existing cache repository is usable, and with
+// verifyObjects that its refs reach only objects it has.
+// It is a variable so tests can simulate a corrupt cache.
+var checkCacheIntegrity = func(ctx context.Context, repo *git.Repository, verifyObjects bool) error {
+	if err := repo.CheckIntegrity(ctx); err != nil {
+		return err
+	}
+	if verifyObjects {
+		return repo.CheckConnectivity(ctx)
+	}
+	return nil
+}
+
+// OpenOptions contains options for opening the registry cache.
+type OpenOptions struct {
+	Auth          git.AuthOptions // Credentials for a private registry; ambient git credentials are used when empty
+	Branch        string          // Branch the registry lives on; the remote's default branch when empty
+	LockTimeout   time.Duration   // How long operations wait for other processes using the cache; DefaultLockTimeout when zero
+	VerifyObjects bool            // Also check with git fsck that an existing cache has every object its refs reach; slow, so set only after a failed operation or by doctor
 }
 
 // Open opens or initializes the registry cache.
-func Open(ctx context.Context, cacheDir string, registryURL string) (*Cache, error) {
-	// Create cache directory hash from URL
-	urlHash := sha256.Sum256([]byte(registryURL))
-	cacheRoot := filepath.Join(cacheDir, fmt.Sprintf("%x", urlHash[:8]))
+// A cache that fails its integrity check is removed and re-cloned once.
+func Open(ctx context.Context, cacheDir string, registryURL string, opts OpenOptions) (*Cache, error) {
+	cacheRoot := cacheRootPath(cacheDir, registryURL)
 
 	var repo *git.Repository
+	var lockFile *os.File
 	var err error
 
 	// Check if cache exists
 	if _, statErr := os.Stat(cacheRoot); os.IsNotExist(statErr) {
-		// Clone the repository
-		logger.Log(ctx).Info().Msg("Cloning registry")
-		repo, err = git.Clone(ctx, registryURL, cacheRoot, git.CloneOptions{
-			Bare:   true,
-			NoTags: true,
-			Depth:  1,
-		})
-		if err != nil {
-			return nil, fmt.Errorf("clone registry: %w", err)
-		}
+		repo, lockFile, err = cloneCache(ctx, registryURL, cacheRoot, opts)
 	} else {
-		// Open existing cache
-		repo, err = git.Open(ctx, cacheRoot, git.OpenOptions{Bare: true})
-		if err != nil {
-			return nil, fmt.Errorf("open registry cache: %w", err)
-		}
+		repo, lockFile, err = openExistingCache(ctx, registryURL, cacheRoot, opts)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	cache := &Cache{
-		root: cacheRoot,
-		repo: repo,
-		url:  registryURL,
+		root:     cacheRoot,
+		repo:     repo,
+		url:      registryURL,
+		lockFile: lockFile,
+		ops:      newOpLock(cacheRoot, opts.LockTimeout),
+		branch:   opts.Branch,
+	}
+	logger.Log(ctx).Debug().Str("lock", lockFile.Name()).Msg("Acquired cache lock")
+
+	return cache, nil
+}
+
+// cloneCache clones the registry into cacheRoot and locks it.
+func cloneCache(ctx context.Context, registryURL, cacheRoot string, opts OpenOptions) (*git.Repository, *os.File, error) {
+	logger.Log(ctx).Info().Msg("Cloning registry")
+	repo, err := git.Clone(ctx, registryURL, cacheRoot, git.CloneOptions{
+		Bare:   true,
+		NoTags: true,
+		Depth:  1,
+		Branch: opts.Branch,
+		Auth:   opts.Auth,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("clone registry: %w", err)
+	}
+
+	// Hold a shared lock so no other process removes the cache while it is open
+	lockFile, err := lockCacheRoot(cacheRoot, syscall.LOCK_SH)
+	if err != nil {
+		return nil, nil, err
 	}
+	return repo, lockFile, nil
+}
+
+// openExistingCache locks and opens an existing cache. If the cache fails its
+// integrity check it is removed and cloned again.
+func openExistingCache(ctx context.Context, registryURL, cacheRoot string, opts OpenOptions) (*git.Repository, *os.File, error) {
+	// Lock before checking so we never remove a cache another process is using
+	lockFile, err := lockCacheRoot(cacheRoot, syscall.LOCK_SH)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	repo, err := git.Open(ctx, cacheRoot, git.OpenOptions{Bare: true, Auth: opts.Auth})
+	if err == nil {
+		err = checkCacheIntegrity(ctx, repo, opts.VerifyObjects)
+	}
+	if err == nil {
+		return repo, lockFile, nil
+	}
+
+	// Removing the cache needs it to ourselves
+	if lockErr := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); lockErr != nil {
+		lockFile.Close()
+		return nil, nil, fmt.Errorf("registry cache is corrupt but in use by another protato process: %w", err)
+	}
+	logger.Log(ctx).Warn().Err(err).Str("path", cacheRoot).Msg("Registry cache is corrupt, re-cloning")
+	removeErr := os.RemoveAll(cacheRoot)
+	lockFile.Close()
+	if removeErr != nil {
+		return nil, nil, fmt.Errorf("remove corrupt registry cache: %w", removeErr)
+	}
+	return cloneCache(ctx, registryURL, cacheRoot, opts)
+}
+
+// cacheRootPath returns the cache directory for a registry URL.
+func cacheRootPath(cacheDir, registryURL string) string {
+	urlHash := sha256.Sum256([]byte(registryURL))
+	return filepath.Join(cacheDir, fmt.Sprintf("%x", urlHash[:8]))
+}
 
-	// Acquire file lock to prevent concurrent access from multiple processes
+// lockCacheRoot takes the cross-process lock on a cache directory without blocking.
+// Open caches hold it shared (syscall.LOCK_SH); removing a cache takes it exclusively
+// (syscall.LOCK_EX), so it fails while any process has the cache open.
+func lockCacheRoot(cacheRoot string, how int) (*os.File, error) {
 	lockPath := filepath.Join(cacheRoot, ".protato.lock")
 	lockFile, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
 	if err != nil {
 		return nil, fmt.Errorf("create lock file: %w", err)
 	}
 
-	// Try to acquire exclusive lock (non-blocking)
-	err = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
-	if err != nil {
+	// Try to acquire the lock (non-blocking)
+	if err := syscall.Flock(int(lockFile.Fd()), how|syscall.LOCK_NB); err != nil {
 		lockFile.Close()
 		return nil, fmt.Errorf("cache is locked by another protato process (try: pkill protato or killall protato)")
 	}
+	return lockFile, nil
+}
 
-	cache.lockFile = lockFile
-	logger.Log(ctx).Debug().Str("lock", lockPath).Msg("Acquired cache lock")
+// RemoveCache deletes the local cache of a registry. A missing cache is not an error;
+// a cache held open by another process is.
+func RemoveCache(ctx context.Context, cacheDir, registryURL string) error {
+	cacheRoot := cacheRootPath(cacheDir, registryURL)
+	if utils.DirNotExists(cacheRoot) {
+		return nil
+	}
 
-	return cache, nil
+	lockFile, err := lockCacheRoot(cacheRoot, syscall.LOCK_EX)
+	if err != nil {
+		return err
+	}
+	defer lockFile.Close()
+
+	logger.Log(ctx).Debug().Str("path", cacheRoot).Msg("Removing registry cache")
+	if err := os.RemoveAll(cacheRoot); err != nil {
+		return fmt.Errorf("remove registry cache: %w", err)
+	}
+	return nil
 }
 
-// Close releases the cache lock and closes resources.
-// The lock is automatically released when the process exits, but this allows explicit cleanup.
+// Close releases the cache locks and closes resources.
+// The locks are automatically released when the process exits, but this allows explicit cleanup.
 func (r *Cache) Close() error {
+	if r.ops != nil {
+		r.ops.close()
+	}
 	if r.lockFile != nil {
 		syscall.Flock(int(r.lockFile.Fd()), syscall.LOCK_UN)
 		return r.lockFile.Close()
@@ -173,11 +317,58 @@ func (r *Cache) Close() error {
 	return nil
 }
 
+// GitConfig returns the effective git config of the cache repository.
+func (r *Cache) GitConfig(ctx context.Context) (map[string]string, error) {
+	return r.repo.ConfigList(ctx)
+}
+
 // Refresh refreshes the cache from remote.
+// Concurrent calls share a single fetch and all receive its result.
 func (r *Cache) Refresh(ctx context.Context) error {
+	_, err, shared := r.refreshGroup.Do(refreshKey, func() (interface{}, error) {
+		return nil, r.fetch(ctx)
+	})
+	if shared {
+		logger.Log(ctx).Debug().Msg("Shared in-flight registry refresh")
+	}
+	return err
+}
+
+// fetch fetches the default branch from remote, retrying transient failures.
+func (r *Cache) fetch(ctx context.Context) error {
+	ctx, unlock, err := r.lock(ctx, lockExclusive)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	logger.Log(ctx).Debug().Msg("Refreshing registry cache")
 	branch := r.getDefaultBranch(ctx)
-	return r.repo.Fetch(ctx, git.FetchOptions{
+	return r.withRetry(ctx, "fetch", func() error {
+		return r.repo.Fetch(ctx, branchFetchOptions(branch))
+	})
+}
+
+// RefreshBranch fetches a registry branch from remote.
+// FETCH_HEAD is left untouched so the default snapshot does not move.
+func (r *Cache) RefreshBranch(ctx context.Context, branch string) error {
+	ctx, unlock, err := r.lock(ctx, lockExclusive)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	logger.Log(ctx).Debug().Str("branch", branch).Msg("Refreshing registry branch")
+	opts := branchFetchOptions(branch)
+	opts.NoWriteFetchHead = true
+	return r.withRetry(ctx, "fetch", func() error {
+		return r.repo.Fetch(ctx, opts)
+	})
+}
+
+// branchFetchOptions returns the options for fetching a branch into its remote-tracking ref.
+func branchFetchOptions(branch string) git.FetchOptions {
+	return git.FetchOptions{
 		Remote: "origin",
 		RefSpecs: []git.Refspec{
 			buildRefspec(buildBranchRef(branch), buildRemoteBranchRef(branch)),
@@ -185,11 +376,194 @@ func (r *Cache) Refresh(ctx context.Context) error {
 		Depth: 1,
 		Prune: true,
 		Force: true, // Force update to handle non-fast-forward (cache can be reset)
-	})
+	}
+}
+
+// EnsureSnapshotAvailable makes sure a snapshot commit is present in the cache.
+// The cache is a shallow clone, so a commit recorded in a lock file may be missing;
+// it is fetched by hash, falling back, while the cache is still shallow, to deepening it and,
+// if the registry config opts in with deepen.unshallow, to fetching its whole history.
+// The snapshot is looked for under a shared lock; only fetching it takes the lock exclusively,
+// so the caller must not hold the lock shared.
+func (r *Cache) EnsureSnapshotAvailable(ctx context.Context, snapshot git.Hash) error {
+	present, err := r.hasSnapshot(ctx, snapshot)
+	if err != nil || present {
+		return err
+	}
+
+	ctx, unlock, err := r.lock(ctx, lockExclusive)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.ensureSnapshot(ctx, snapshot)
+}
+
+// hasSnapshot reports whether a snapshot commit is present in the cache.
+func (r *Cache) hasSnapshot(ctx context.Context, snapshot git.Hash) (bool, error) {
+	ctx, unlock, err := r.lock(ctx, lockShared)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	return r.repo.RevExists(ctx, string(snapshot)), nil
+}
+
+// ensureSnapshot does the work of EnsureSnapshotAvailable; the caller holds r.mu and the
+// lock exclusively. A snapshot fetched by another process meanwhile is not fetched again.
+func (r *Cache) ensureSnapshot(ctx context.Context, snapshot git.Hash) error {
+	if r.repo.RevExists(ctx, string(snapshot)) {
+		return nil
+	}
+
+	logger.Log(ctx).Debug().Str("snapshot", snapshot.Short()).Msg("Snapshot missing from cache, fetching")
+	if err := r.repo.Fetch(ctx, commitFetchOptions(snapshot)); err != nil {
+		if !r.isShallow() {
+			// The whole history is here already, so the registry does not have the snapshot
+			logger.Log(ctx).Debug().Err(err).Msg("Fetching snapshot by hash failed in a complete cache")
+			return &SnapshotNotFoundError{Hash: snapshot}
+		}
+		logger.Log(ctx).Debug().Err(err).Msg("Fetching snapshot by hash failed, deepening cache")
+		policy := r.deepenPolicy(ctx)
+		if !r.deepenTo(ctx, policy, snapshot) {
+			if !policy.Unshallow {
+				return &SnapshotNotFoundError{Hash: snapshot}
+			}
+			logger.Log(ctx).Debug().Msg("Snapshot is beyond the deepened history, unshallowing cache")
+			if err := r.repo.Unshallow(ctx); err != nil {
+				return fmt.Errorf("fetch snapshot %s: %w", snapshot.Short(), err)
+			}
+		}
+	}
+
+	if !r.repo.RevExists(ctx, string(snapshot)) {
+		return &SnapshotNotFoundError{Hash: snapshot}
+	}
+	return nil
+}
+
+// deepenTo fetches more history of the default branch, one step of policy at a time,
+// until snapshot is present or the steps run out. It reports whether snapshot was reached.
+func (r *Cache) deepenTo(ctx context.Context, policy DeepenConfig, snapshot git.Hash) bool {
+	branch := r.getDefaultBranch(ctx)
+	for step := 1; step <= policy.maxSteps(); step++ {
+		if err := r.repo.Fetch(ctx, deepenFetchOptions(branch, policy.step())); err != nil {
+			logger.Log(ctx).Debug().Err(err).Int("step", step).Msg("Deepening cache failed")
+			return false
+		}
+		if r.repo.RevExists(ctx, string(snapshot)) {
+			return true
+		}
+	}
+	return false
+}
+
+// deepenPolicy returns the deepen settings of the registry config at the current snapshot.
+// The defaults apply when there is no snapshot yet or its config cannot be read.
+func (r *Cache) deepenPolicy(ctx context.Context) DeepenConfig {
+	config, err := r.Config(ctx, "")
+	if err != nil {
+		return DeepenConfig{}
+	}
+	return config.Deepen
+}
+
+// deepenFetchOptions returns the options for fetching n more commits of branch.
+func deepenFetchOptions(branch string, n int) git.FetchOptions {
+	return git.FetchOptions{
+		Remote: "origin",
+		RefSpecs: []git.Refspec{
+			buildRefspec(buildBranchRef(branch), buildRemoteBranchRef(branch)),
+		},
+		Deepen:           n,
+		NoWriteFetchHead: true,
+	}
+}
+
+// commitFetchOptions returns the options for fetching a single commit by hash.
+func commitFetchOptions(snapshot git.Hash) git.FetchOptions {
+	return git.FetchOptions{
+		Remote:           "origin",
+		RefSpecs:         []git.Refspec{git.Refspec(snapshot)},
+		Depth:            1,
+		NoWriteFetchHead: true,
+	}
+}
+
+// BranchSnapshot returns the commit at the tip of a registry branch.
+// The remote-tracking ref is preferred over a local branch of the same name.
+func (r *Cache) BranchSnapshot(ctx context.Context, branch string) (git.Hash, error) {
+	ctx, unlock, err := r.lock(ctx, lockShared)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	for _, ref := range []string{buildRemoteBranchRef(branch), buildBranchRef(branch)} {
+		if hash, err := r.repo.RevHash(ctx, ref); err == nil {
+			return hash, nil
+		}
+	}
+	return "", fmt.Errorf("branch not found: %s", branch)
+}
+
+// commitRefPattern matches a full or abbreviated commit hash.
+var commitRefPattern = regexp.MustCompile(`^[0-9a-f]{4,40}$`)
+
+// ResolveRef resolves a registry tag or commit hash (full or abbreviated) to a snapshot.
+// Tags are fetched from the registry on demand since the cache is cloned without them.
+// A commit missing from the shallow cache is fetched, deepening the cache if needed.
+func (r *Cache) ResolveRef(ctx context.Context, ref string) (git.Hash, error) {
+	ctx, unlock, err := r.lock(ctx, lockExclusive)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tag := buildTagRef(ref)
+	if err := r.repo.Fetch(ctx, tagFetchOptions(ref)); err != nil {
+		logger.Log(ctx).Debug().Err(err).Str("ref", ref).Msg("Not a registry tag")
+	} else if hash, err := r.repo.RevHash(ctx, tag+"^{commit}"); err == nil {
+		return hash, nil
+	}
+
+	if !commitRefPattern.MatchString(ref) {
+		return "", fmt.Errorf("%s is not a registry tag or commit", ref)
+	}
+	if err := r.ensureSnapshot(ctx, git.Hash(ref)); err != nil {
+		return "", err
+	}
+	// Expand an abbreviated hash
+	return r.repo.RevHash(ctx, ref+"^{commit}")
+}
+
+// tagFetchOptions returns the options for fetching a single registry tag.
+func tagFetchOptions(tag string) git.FetchOptions {
+	return git.FetchOptions{
+		Remote:           "origin",
+		RefSpecs:         []git.Refspec{buildRefspec(buildTagRef(tag), buildTagRef(tag))},
+		Depth:            1,
+		Force:            true,
+		NoWriteFetchHead: true,
+	}
 }
 
 // Snapshot returns the current registry state (Git commit hash).
 func (r *Cache) Snapshot(ctx context.Context) (git.Hash, error) {
+	ctx, unlock, err := r.lock(ctx, lockShared)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
 	// Try FETCH_HEAD first (for bare repos after fetch)
 	hash, err := r.repo.RevHash(ctx, "FETCH_HEAD")
 	if err == nil {
@@ -200,39 +574,44 @@ func (r *Cache) Snapshot(ctx context.Context) (git.Hash, error) {
 	return r.repo.RevHash(ctx, "HEAD")
 }
 
-// LookupProject finds a project by path.
+// LookupProject finds a project by path. A snapshot missing from the cache is fetched first.
 func (r *Cache) LookupProject(ctx context.Context, req *LookupProjectRequest) (*LookupProjectResponse, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
 	snapshot, err := r.getOrCreateSnapshot(ctx, req.Snapshot)
 	if err != nil {
 		return nil, err
 	}
+	if err := r.EnsureSnapshotAvailable(ctx, snapshot); err != nil {
+		return nil, err
+	}
 
-	if !r.repo.RevExists(ctx, string(snapshot)) {
-		return nil, fmt.Errorf("snapshot not found: %s", snapshot)
+	ctx, unlock, err := r.lock(ctx, lockShared)
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
 	return r.findProjectByPath(ctx, snapshot, req.Path)
 }
 
 // findProjectByPath searches for a project by walking up the path hierarchy.
 func (r *Cache) findProjectByPath(ctx context.Context, snapshot git.Hash, projectPath string) (*LookupProjectResponse, error) {
-	for {
-		response := r.tryFindProjectAtPath(ctx, snapshot, projectPath)
+	for p := projectPath; ; {
+		response := r.tryFindProjectAtPath(ctx, snapshot, p)
 		if response != nil {
 			return response, nil
 		}
 
-		parent := path.Dir(projectPath)
-		if parent == "." || parent == projectPath {
+		parent := path.Dir(p)
+		if parent == "." || parent == p {
 			break
 		}
-		projectPath = parent
+		p = parent
 	}
 
-	return nil, errors.ErrNotFound
+	return nil, &ProjectNotFoundError{Path: projectPath}
 }
 
 // tryFindProjectAtPath attempts to find a project at the given path.
@@ -284,120 +663,479 @@ func (r *Cache) readProjectMeta(ctx context.Context, hash git.Hash) (*Project, e
 	}
 
 	return &Project{
-		Commit:        git.Hash(meta.Git.Commit),
-		RepositoryURL: meta.Git.URL,
+		Commit:          git.Hash(meta.Git.Commit),
+		RepositoryURL:   meta.Git.URL,
+		RequireApproval: meta.RequireApproval,
 	}, nil
 }
 
-// ListProjects lists all projects in the registry.
+// ListProjects lists the projects in the registry, sorted by path.
+// A registry without a protos/ tree, such as a freshly initialized one, has no projects;
+// only failures to read the snapshot itself are returned as errors.
 func (r *Cache) ListProjects(ctx context.Context, opts *ListProjectsOptions) ([]ProjectPath, error) {
-	snapshot := git.Hash("")
-	if opts != nil {
-		snapshot = opts.Snapshot
+	page, err := r.ListProjectsPage(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return page.Projects, nil
+}
+
+// ListProjectsPage lists one page of the projects in the registry: those sorted after
+// opts.After, skipping opts.Offset of them, up to opts.Limit of them. The response's Next
+// cursor, passed back as After, continues the listing.
+func (r *Cache) ListProjectsPage(ctx context.Context, opts *ListProjectsOptions) (*ListProjectsResponse, error) {
+	ctx, unlock, err := r.lock(ctx, lockShared)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if opts == nil {
+		opts = &ListProjectsOptions{}
 	}
-	snapshot, err := r.getOrCreateSnapshot(ctx, snapshot)
+	snapshot, err := r.getOrCreateSnapshot(ctx, opts.Snapshot)
 	if err != nil {
 		return nil, err
 	}
 
 	// Determine search path: use prefix if provided, otherwise scan entire protos/
 	searchPath := constants.ProtosDir
-	if opts != nil && opts.Prefix != "" {
+	if opts.Prefix != "" {
 		searchPath = protosPath(opts.Prefix)
 	}
 
-	// List all files in search path
-	entries, err := r.repo.ReadTree(ctx, git.Treeish(snapshot), git.ReadTreeOptions{
-		Recurse: true,
-		Paths:   []string{searchPath},
-	})
-	if err != nil {
-		return nil, readTreeError(err)
+	owner := ""
+	if opts.Owner != "" {
+		owner = utils.NormalizeGitURL(opts.Owner)
 	}
 
-	// Find all project root files
-	projectSet := make(map[string]bool)
-	for _, entry := range entries {
+	// Find project root files, streaming the listing since protos/ can be very large.
+	// With a limit only the first offset+limit+1 projects in path order are kept, one
+	// more than the page so Next can be set.
+	keep := 0
+	if opts.Limit > 0 {
+		keep = opts.Offset + opts.Limit + 1
+	}
+	var projects []ProjectPath
+	var walkErr error
+	err = r.repo.WalkTree(ctx, git.Treeish(snapshot), git.ReadTreeOptions{
+		Recurse: true,
+		Paths:   []string{searchPath},
+	}, func(entry git.TreeEntry) error {
 		if !isBlobType(entry.Type) {
-			continue
+			return nil
 		}
 		if path.Base(entry.Path) != constants.ProjectMetaFile {
-			continue
+			return nil
 		}
 
 		// Extract project path
 		dir := path.Dir(entry.Path)
-		projectPath := trimProtosPrefix(dir)
+		projectPath := ProjectPath(trimProtosPrefix(dir))
+		full := keep > 0 && len(projects) == keep
+		switch {
+		case opts.After != "" && projectPath <= opts.After:
+			// Before the cursor
+		case opts.MaxDepth > 0 && projectDepth(projectPath, opts.Prefix) > opts.MaxDepth:
+			// Too deep under the prefix
+		case full && projectPath > projects[keep-1]:
+			// Sorts after the kept projects, so it cannot be on the page; skip the owner check
+		default:
+			if owner != "" {
+				owned, err := r.isOwnedBy(ctx, entry.Hash, owner)
+				if err != nil {
+					walkErr = fmt.Errorf("project %s: %w", projectPath, err)
+					return walkErr
+				}
+				if !owned {
+					return nil
+				}
+			}
+			i, found := slices.BinarySearch(projects, projectPath)
+			if !found {
+				projects = slices.Insert(projects, i, projectPath)
+				if keep > 0 && len(projects) > keep {
+					projects = projects[:keep]
+				}
+			}
+		}
 
-		projectSet[projectPath] = true
+		if keep > 0 && len(projects) == keep && !canPrecedeLater(projects[keep-1], entry.Path) {
+			walkErr = errListingComplete
+			return walkErr
+		}
+		return nil
+	})
+	if walkErr != nil && walkErr != errListingComplete {
+		return nil, walkErr
+	}
+	if err != nil && walkErr == nil {
+		return nil, readTreeError(err)
 	}
 
-	// Convert to slice
-	var projects []ProjectPath
-	for p := range projectSet {
-		projects = append(projects, ProjectPath(p))
+	page := &ListProjectsResponse{}
+	if opts.Offset >= len(projects) {
+		return page, nil
+	}
+	page.Projects = projects[opts.Offset:]
+	if opts.Limit > 0 && len(page.Projects) > opts.Limit {
+		page.Projects = page.Projects[:opts.Limit]
+		page.Next = page.Projects[opts.Limit-1]
 	}
+	return page, nil
+}
 
-	return projects, nil
+// errListingComplete stops a tree walk once the rest of the listing cannot change the page.
+var errListingComplete = fmt.Errorf("listing complete")
+
+// canPrecedeLater reports whether a project whose root file ls-tree lists after rootFile
+// could still sort before last. ls-tree lists paths in byte order, which differs from project
+// path order only when a project path is a prefix of another followed by a byte below '/'
+// ("a" sorts before "a.v2", but "a.v2/protato.root.yaml" is listed first). Projects cannot
+// nest, so those prefixes of last are the only projects that can still sort before it.
+func canPrecedeLater(last ProjectPath, rootFile string) bool {
+	for i := 1; i < len(last); i++ {
+		if last[i] < '/' && protosPath(string(last[:i]), constants.ProjectMetaFile) > rootFile {
+			return true
+		}
+	}
+	return false
 }
 
-// ListProjectFiles lists all files in a project.
-func (r *Cache) ListProjectFiles(ctx context.Context, req *ListProjectFilesRequest) (*ListProjectFilesResponse, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// projectDepth returns how many path components project has below prefix.
+func projectDepth(project ProjectPath, prefix string) int {
+	rel := string(project)
+	if prefix = strings.Trim(prefix, "/"); prefix != "" {
+		if rel == prefix {
+			return 0
+		}
+		rel = utils.TrimPathPrefix(rel, prefix)
+	}
+	return strings.Count(rel, "/") + 1
+}
 
-	snapshot, err := r.getOrCreateSnapshot(ctx, req.Snapshot)
+// isOwnedBy reports whether the project metadata blob at hash names owner, a normalized URL, as its repository.
+func (r *Cache) isOwnedBy(ctx context.Context, hash git.Hash, owner string) (bool, error) {
+	project, err := r.readProjectMeta(ctx, hash)
+	if err != nil {
+		return false, err
+	}
+	return utils.NormalizeGitURL(project.RepositoryURL) == owner, nil
+}
+
+// SnapshotDiff returns the projects that were added, removed or changed between two snapshots, sorted by path.
+// Either snapshot missing from the cache is fetched first.
+// A project changed when a file in its tree, including metadata and nested projects, differs
+// between the snapshots.
+// The diff starts at the merge base of the snapshots, so for diverged branches only the changes
+// on to's side are reported; snapshots without common history are compared directly.
+func (r *Cache) SnapshotDiff(ctx context.Context, from, to git.Hash) ([]ProjectPath, error) {
+	for _, snapshot := range []git.Hash{from, to} {
+		if err := r.EnsureSnapshotAvailable(ctx, snapshot); err != nil {
+			return nil, err
+		}
+	}
+	base, err := r.diffBase(ctx, from, to)
 	if err != nil {
 		return nil, err
 	}
+	from = base
 
-	projectPath := protosPath(string(req.Project))
-	entries, err := r.repo.ReadTree(ctx, git.Treeish(snapshot), git.ReadTreeOptions{
-		Recurse: true,
-		Paths:   []string{projectPath},
-	})
+	ctx, unlock, err := r.lock(ctx, lockShared)
 	if err != nil {
-		return nil, readTreeError(err)
+		return nil, err
 	}
+	defer unlock()
 
-	var files []ProjectFile
-	for _, entry := range entries {
-		if !isBlobType(entry.Type) {
-			continue
+	projectSet := make(map[string]bool)
+	for _, snapshot := range []git.Hash{from, to} {
+		projects, err := r.ListProjects(ctx, &ListProjectsOptions{Snapshot: snapshot})
+		if err != nil {
+			return nil, fmt.Errorf("list projects at %s: %w", snapshot.Short(), err)
 		}
+		for _, p := range projects {
+			projectSet[string(p)] = true
+		}
+	}
 
-		// Only include .proto files
-		if !strings.HasSuffix(entry.Path, constants.ProtoFileExt) {
-			continue
+	// One diff of the protos tree finds every changed file; each belongs to the projects above it
+	diff, err := r.repo.DiffTree(ctx, git.Treeish(from), git.Treeish(to), git.DiffOptions{
+		Paths: []string{constants.ProtosDir},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("diff %s..%s: %w", from.Short(), to.Short(), err)
+	}
+	changedSet := make(map[string]bool)
+	for _, entry := range diff {
+		for dir := path.Dir(entry.Path); dir != "." && dir != constants.ProtosDir; dir = path.Dir(dir) {
+			if p := strings.TrimPrefix(dir, constants.ProtosDir+"/"); projectSet[p] {
+				changedSet[p] = true
+			}
 		}
+	}
 
-		// Get relative path
-		relPath := utils.TrimPathPrefix(entry.Path, projectPath)
+	var changed []ProjectPath
+	for _, p := range utils.SortedKeys(changedSet) {
+		changed = append(changed, ProjectPath(p))
+	}
+	return changed, nil
+}
 
-		files = append(files, ProjectFile{
-			Snapshot: snapshot,
-			Project:  req.Project,
-			Path:     relPath,
-			Hash:     entry.Hash,
-		})
+// diffBase returns the merge base SnapshotDiff starts from. The cache is a shallow clone
+// that may lack the common history, so it is unshallowed before concluding there is none;
+// snapshots without common history are diffed from from itself.
+func (r *Cache) diffBase(ctx context.Context, from, to git.Hash) (git.Hash, error) {
+	base, err := r.mergeBase(ctx, from, to)
+	if err == errors.ErrNoMergeBase && r.isShallow() {
+		logger.Log(ctx).Debug().Msg("No merge base in shallow cache, unshallowing")
+		if err := r.unshallow(ctx); err != nil {
+			return "", fmt.Errorf("fetch history: %w", err)
+		}
+		base, err = r.mergeBase(ctx, from, to)
 	}
 
-	return &ListProjectFilesResponse{
-		Files:    files,
-		Snapshot: snapshot,
-	}, nil
+	switch {
+	case err == errors.ErrNoMergeBase:
+		return from, nil
+	case err != nil:
+		return "", fmt.Errorf("merge base of %s and %s: %w", from.Short(), to.Short(), err)
+	}
+	return base, nil
 }
 
-// ReadProjectFile reads a file from the registry.
-func (r *Cache) ReadProjectFile(ctx context.Context, file ProjectFile, writer io.Writer) error {
+// mergeBase returns the merge base of two snapshots.
+func (r *Cache) mergeBase(ctx context.Context, from, to git.Hash) (git.Hash, error) {
+	ctx, unlock, err := r.lock(ctx, lockShared)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	return r.repo.MergeBase(ctx, git.Treeish(from), git.Treeish(to))
+}
+
+// isShallow reports whether the cache is a shallow clone, missing older history.
+func (r *Cache) isShallow() bool {
+	return utils.FileExists(filepath.Join(r.repo.GitDir(), "shallow"))
+}
+
+// unshallow fetches the full history of the cache. It rewrites the shallow boundary, so it
+// takes the lock exclusively; a cache another process unshallowed meanwhile is left alone.
+func (r *Cache) unshallow(ctx context.Context) error {
+	ctx, unlock, err := r.lock(ctx, lockExclusive)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if !r.isShallow() {
+		return nil
+	}
+	return r.repo.Unshallow(ctx)
+}
+
+// GetProjectHistory lists the registry commits that changed a project, or one file of it,
+// newest first, from the current snapshot back to the project's creation. A file no longer
+// in the project is only traced with IncludeDeleted, from the commit that deleted it.
+// The shallow cache would show its oldest commit as creating every project, so it is
+// unshallowed first.
+func (r *Cache) GetProjectHistory(ctx context.Context, req *ProjectHistoryRequest) ([]ProjectRevision, error) {
+	project := req.Project
+	if r.isShallow() {
+		logger.Log(ctx).Debug().Str("project", string(project)).Msg("Unshallowing cache for project history")
+		if err := r.unshallow(ctx); err != nil {
+			return nil, fmt.Errorf("fetch history: %w", err)
+		}
+	}
+
+	ctx, unlock, err := r.lock(ctx, lockShared)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot, err := r.getOrCreateSnapshot(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := git.LogOptions{
+		Rev:      git.Treeish(snapshot),
+		Paths:    []string{protosPath(string(project))},
+		MaxCount: req.Limit,
+	}
+	if req.File != "" {
+		filePath := protosPath(string(project), req.File)
+		if opts.Rev, err = r.fileHistoryStart(ctx, snapshot, filePath, req.IncludeDeleted); err != nil {
+			return nil, err
+		}
+		opts.Paths = []string{filePath}
+		opts.Follow = true
+	}
+
+	commits, err := r.repo.Log(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("history of %s: %w", project, err)
+	}
+
+	revisions := make([]ProjectRevision, len(commits))
+	for i, commit := range commits {
+		revisions[i] = ProjectRevision{
+			Snapshot: commit.Hash,
+			Author:   commit.Author,
+			Date:     commit.Date,
+			Message:  commit.Message,
+		}
+	}
+	return revisions, nil
+}
+
+// fileHistoryStart returns the commit to list the history of a registry file from: snapshot
+// while the file is in it, or else, with includeDeleted, the last commit that deleted it.
+func (r *Cache) fileHistoryStart(ctx context.Context, snapshot git.Hash, filePath string, includeDeleted bool) (git.Treeish, error) {
+	entries, err := r.repo.ReadTree(ctx, git.Treeish(snapshot), git.ReadTreeOptions{Paths: []string{filePath}})
+	if err != nil {
+		return "", readTreeError(err)
+	}
+	if len(entries) > 0 {
+		return git.Treeish(snapshot), nil
+	}
+	if !includeDeleted {
+		return "", fmt.Errorf("file %s: %w", filePath, errors.ErrNotFound)
+	}
+
+	deletions, err := r.repo.Log(ctx, git.LogOptions{
+		Rev:        git.Treeish(snapshot),
+		Paths:      []string{filePath},
+		DiffFilter: "D",
+		MaxCount:   1,
+	})
+	if err != nil {
+		return "", fmt.Errorf("find deletion of %s: %w", filePath, err)
+	}
+	if len(deletions) == 0 {
+		return "", fmt.Errorf("file %s was never deleted: %w", filePath, errors.ErrNotFound)
+	}
+	return git.Treeish(deletions[0].Hash), nil
+}
+
+// ListProjectFiles lists all files in a project.
+func (r *Cache) ListProjectFiles(ctx context.Context, req *ListProjectFilesRequest) (*ListProjectFilesResponse, error) {
+	ctx, unlock, err := r.lock(ctx, lockShared)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot, err := r.getOrCreateSnapshot(ctx, req.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	projectPath := protosPath(string(req.Project))
+	entries, err := r.repo.ReadTree(ctx, git.Treeish(snapshot), git.ReadTreeOptions{
+		Recurse: true,
+		Paths:   []string{projectPath},
+	})
+	if err != nil {
+		return nil, readTreeError(err)
+	}
+
+	config, err := r.Config(ctx, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []ProjectFile
+	for _, entry := range entries {
+		if !isBlobType(entry.Type) {
+			continue
+		}
+
+		// Only include files the registry allows
+		if !config.AllowsFile(entry.Path) {
+			continue
+		}
+
+		// Get relative path
+		relPath := utils.TrimPathPrefix(entry.Path, projectPath)
+
+		files = append(files, ProjectFile{
+			Snapshot: snapshot,
+			Project:  req.Project,
+			Path:     relPath,
+			Hash:     entry.Hash,
+			Mode:     entry.Mode,
+		})
+	}
+
+	return &ListProjectFilesResponse{
+		Files:    files,
+		Snapshot: snapshot,
+	}, nil
+}
+
+// ReadProjectFile reads a file from the registry.
+// It holds the operation lock shared, so another process cannot compact the cache away
+// from under the read.
+func (r *Cache) ReadProjectFile(ctx context.Context, file ProjectFile, writer io.Writer) error {
+	ctx, unlock, err := r.lock(ctx, lockShared)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	return r.repo.ReadObject(ctx, git.BlobType, file.Hash, writer)
 }
 
+// ReadProjectFiles reads many files from the registry through one git process, calling fn
+// with the content of each file in order. Files whose objects are missing from the cache are
+// skipped and reported together in the returned error, each wrapping errors.ErrObjectMissing.
+func (r *Cache) ReadProjectFiles(ctx context.Context, files []ProjectFile, fn func(ProjectFile, io.Reader) error) error {
+	ctx, unlock, err := r.lock(ctx, lockShared)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	hashes := make([]git.Hash, len(files))
+	for i, file := range files {
+		hashes[i] = file.Hash
+	}
+
+	// Objects come back in request order with missing ones left out, so the next file
+	// with the object's hash is the one it belongs to
+	next := 0
+	return r.repo.CatFileBatch(ctx, hashes, func(hash git.Hash, _ git.ObjectType, content io.Reader) error {
+		for next < len(files) && files[next].Hash != hash {
+			next++
+		}
+		if next == len(files) {
+			return fmt.Errorf("unexpected object %s", hash)
+		}
+		file := files[next]
+		next++
+		return fn(file, content)
+	})
+}
+
 // SetProject updates a project in the registry.
 func (r *Cache) SetProject(ctx context.Context, req *SetProjectRequest) (*SetProjectResponse, error) {
+	ctx, unlock, err := r.lock(ctx, lockExclusive)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	snapshot, err := r.getOrCreateSnapshot(ctx, req.Snapshot)
 	if err != nil {
 		return nil, err
@@ -408,37 +1146,391 @@ func (r *Cache) SetProject(ctx context.Context, req *SetProjectRequest) (*SetPro
 		return nil, fmt.Errorf("get current tree: %w", err)
 	}
 
-	projectPrefix := protosPath(string(req.Project.Path))
-	upserts, err := r.prepareUpserts(ctx, req.Project, req.Files, projectPrefix)
+	config, err := r.Config(ctx, snapshot)
 	if err != nil {
 		return nil, err
 	}
-
-	deletes, err := r.prepareDeletes(ctx, req.Project.Path, req.Files, snapshot, projectPrefix)
+	staged, err := r.stageProject(ctx, config, req, snapshot)
 	if err != nil {
 		return nil, err
 	}
 
 	newTree, err := r.repo.UpdateTree(ctx, git.UpdateTreeRequest{
 		Tree:    currentTree,
-		Upserts: upserts,
-		Deletes: deletes,
+		Upserts: staged.upserts,
+		Deletes: staged.deletes,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("update tree: %w", err)
 	}
 
-	newCommit, err := r.createProjectCommit(ctx, req, snapshot, newTree)
+	newCommit, err := r.createProjectCommit(ctx, req.Author, projectCommitMessage(req, staged.approval), snapshot, newTree)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := r.validateOnPush(ctx, req, snapshot, newCommit); err != nil {
+		return nil, err
+	}
+
 	return &SetProjectResponse{
 		Snapshot:     newCommit,
 		FilesChanged: len(req.Files),
 	}, nil
 }
 
+// SetProjects updates several projects in the registry with a single commit on one base snapshot.
+// Every project's claim is checked against that base before anything is staged, so a single
+// project the caller cannot claim rejects the whole batch. The requests must share their base
+// snapshot; the commit is authored by the first request's author. The caller is responsible
+// for pushing the new snapshot.
+func (r *Cache) SetProjects(ctx context.Context, reqs []*SetProjectRequest) (*SetProjectResponse, error) {
+	ctx, unlock, err := r.lock(ctx, lockExclusive)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	snapshot, stagedProjects, err := r.stageProjects(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	currentTree, err := r.repo.RevHash(ctx, string(snapshot)+"^{tree}")
+	if err != nil {
+		return nil, fmt.Errorf("get current tree: %w", err)
+	}
+
+	update := git.UpdateTreeRequest{Tree: currentTree}
+	approvals := make([]string, len(reqs))
+	filesChanged := 0
+	for i, staged := range stagedProjects {
+		update.Upserts = append(update.Upserts, staged.upserts...)
+		update.Deletes = append(update.Deletes, staged.deletes...)
+		approvals[i] = staged.approval
+		filesChanged += len(reqs[i].Files)
+	}
+
+	newTree, err := r.repo.UpdateTree(ctx, update)
+	if err != nil {
+		return nil, fmt.Errorf("update tree: %w", err)
+	}
+
+	newCommit, err := r.createProjectCommit(ctx, reqs[0].Author, projectsCommitMessage(reqs, approvals), snapshot, newTree)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, req := range reqs {
+		if err := r.validateOnPush(ctx, req, snapshot, newCommit); err != nil {
+			return nil, err
+		}
+	}
+
+	return &SetProjectResponse{
+		Snapshot:     newCommit,
+		FilesChanged: filesChanged,
+	}, nil
+}
+
+// PlanProjects returns the file changes SetProjects would make for reqs, sorted by project and
+// path, without creating a commit. It runs the same checks and writes the same file objects,
+// so requests SetProjects rejects fail here too. Project metadata is not reported.
+func (r *Cache) PlanProjects(ctx context.Context, reqs []*SetProjectRequest) ([]FileChange, error) {
+	ctx, unlock, err := r.lock(ctx, lockExclusive)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	snapshot, stagedProjects, err := r.stageProjects(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FileChange
+	for i, staged := range stagedProjects {
+		projectChanges, err := r.planProject(ctx, snapshot, reqs[i].Project.Path, staged)
+		if err != nil {
+			return nil, fmt.Errorf("project %s: %w", reqs[i].Project.Path, err)
+		}
+		changes = append(changes, projectChanges...)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Project != changes[j].Project {
+			return changes[i].Project < changes[j].Project
+		}
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// planProject compares the staged changes of a project with its files at snapshot.
+// Upserts of unchanged content and of the project metadata are left out.
+func (r *Cache) planProject(ctx context.Context, snapshot git.Hash, project ProjectPath, staged *stagedProject) ([]FileChange, error) {
+	projectPrefix := protosPath(string(project))
+	entries, err := r.repo.ReadTree(ctx, git.Treeish(snapshot), git.ReadTreeOptions{
+		Recurse: true,
+		Paths:   []string{projectPrefix},
+	})
+	if err != nil {
+		return nil, readTreeError(err)
+	}
+	existing := make(map[string]git.Hash, len(entries))
+	for _, entry := range entries {
+		if isBlobType(entry.Type) {
+			existing[entry.Path] = entry.Hash
+		}
+	}
+
+	var changes []FileChange
+	for _, upsert := range staged.upserts {
+		if utils.IsSpecialFile(upsert.Path) {
+			continue
+		}
+		change := FileChange{Project: project, Path: utils.TrimPathPrefix(upsert.Path, projectPrefix)}
+		old, ok := existing[upsert.Path]
+		switch {
+		case !ok:
+			change.Status = git.DiffAdded
+		case old != upsert.Blob:
+			change.Status = git.DiffModified
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	for _, path := range staged.deletes {
+		changes = append(changes, FileChange{Project: project, Path: utils.TrimPathPrefix(path, projectPrefix), Status: git.DiffDeleted})
+	}
+	return changes, nil
+}
+
+// stageProjects checks a multi-project update and prepares the tree changes of each project,
+// in request order, on the base snapshot the requests share. Every project's claim is checked
+// before anything is staged.
+func (r *Cache) stageProjects(ctx context.Context, reqs []*SetProjectRequest) (git.Hash, []*stagedProject, error) {
+	if len(reqs) == 0 {
+		return "", nil, fmt.Errorf("no projects to set")
+	}
+	for _, req := range reqs[1:] {
+		if req.Snapshot != reqs[0].Snapshot {
+			return "", nil, fmt.Errorf("projects %s and %s have different base snapshots", reqs[0].Project.Path, req.Project.Path)
+		}
+	}
+
+	snapshot, err := r.getOrCreateSnapshot(ctx, reqs[0].Snapshot)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, req := range reqs {
+		if err := r.CheckProjectClaim(ctx, snapshot, req.Project.RepositoryURL, string(req.Project.Path)); err != nil {
+			return "", nil, err
+		}
+	}
+
+	config, err := r.Config(ctx, snapshot)
+	if err != nil {
+		return "", nil, err
+	}
+
+	stagedProjects := make([]*stagedProject, len(reqs))
+	for i, req := range reqs {
+		staged, err := r.stageProject(ctx, config, req, snapshot)
+		if err != nil {
+			return "", nil, fmt.Errorf("project %s: %w", req.Project.Path, err)
+		}
+		stagedProjects[i] = staged
+	}
+	return snapshot, stagedProjects, nil
+}
+
+// stagedProject holds the tree changes of a project update.
+type stagedProject struct {
+	upserts  []git.TreeUpsert
+	deletes  []string
+	approval string // Approval recorded in the commit message; "" if none is required
+}
+
+// stageProject checks a project update against the registry config at snapshot
+// and prepares its tree changes, writing the file objects.
+func (r *Cache) stageProject(ctx context.Context, config *Config, req *SetProjectRequest, snapshot git.Hash) (*stagedProject, error) {
+	if err := checkAllowedFiles(config, req.Files); err != nil {
+		return nil, err
+	}
+	approval, err := r.checkApproval(ctx, req, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	projectPrefix := protosPath(string(req.Project.Path))
+	upserts, err := r.prepareUpserts(ctx, req.Project, req.Files, projectPrefix, req.MaxFileSize, req.Symlinks)
+	if err != nil {
+		return nil, err
+	}
+
+	deletes, err := r.prepareDeletes(ctx, config, req.Files, snapshot, projectPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stagedProject{upserts: upserts, deletes: deletes, approval: approval}, nil
+}
+
+// checkApproval returns the approval for a push of a project that requires one, either as
+// published at the base snapshot or as requested, and "" for projects that do not.
+// The published flag counts too, so dropping it takes an approved push. The approver must
+// be someone other than the push's author.
+func (r *Cache) checkApproval(ctx context.Context, req *SetProjectRequest, snapshot git.Hash) (string, error) {
+	required := req.Project.RequireApproval
+	if !required {
+		if existing := r.tryFindProjectAtPath(ctx, snapshot, string(req.Project.Path)); existing != nil {
+			required = existing.Project.RequireApproval
+		}
+	}
+	if !required {
+		return "", nil
+	}
+
+	if req.Approval == nil {
+		return "", fmt.Errorf("%w: %s", errors.ErrApprovalRequired, req.Project.Path)
+	}
+	approval, err := req.Approval(ctx, req.Project.Path)
+	if err != nil {
+		return "", fmt.Errorf("get approval for %s: %w", req.Project.Path, err)
+	}
+	approval = strings.TrimSpace(approval)
+	if approval == "" {
+		return "", fmt.Errorf("%w: %s", errors.ErrApprovalRequired, req.Project.Path)
+	}
+	if approvedByAuthor(approval, req.Author) {
+		return "", fmt.Errorf("%w: %s approved by %s", errors.ErrSelfApproval, req.Project.Path, approval)
+	}
+	return approval, nil
+}
+
+// approvedByAuthor reports whether approval names author, by name, by email or as
+// "Name <email>".
+func approvedByAuthor(approval string, author *git.Author) bool {
+	if author == nil {
+		return false
+	}
+	name, email := approval, ""
+	if before, after, ok := strings.Cut(approval, "<"); ok {
+		name, email = strings.TrimSpace(before), strings.TrimSuffix(strings.TrimSpace(after), ">")
+	}
+	matches := func(s string) bool {
+		return s != "" && (strings.EqualFold(s, author.Name) || strings.EqualFold(s, author.Email))
+	}
+	return matches(name) || matches(email)
+}
+
+// validateOnPush compiles the project at the candidate commit when the registry config at
+// the base snapshot asks for it. The candidate holds the incoming files plus every other
+// project of the base snapshot, so the project is checked against the deps it will be published with.
+func (r *Cache) validateOnPush(ctx context.Context, req *SetProjectRequest, base, candidate git.Hash) error {
+	return r.validateProject(ctx, req.Validate, req.Project.Path, base, candidate)
+}
+
+// validateProject compiles project at the candidate commit with validate when the registry
+// config at the base snapshot sets validateOnPush.
+func (r *Cache) validateProject(ctx context.Context, validate ProjectValidator, project ProjectPath, base, candidate git.Hash) error {
+	config, err := r.Config(ctx, base)
+	if err != nil {
+		return err
+	}
+	if !config.ValidateOnPush {
+		return nil
+	}
+	if validate == nil {
+		logger.Log(ctx).Warn().Str("project", string(project)).Msg("Registry requires validation on push but no validator was supplied")
+		return nil
+	}
+
+	logger.Log(ctx).Info().Str("project", string(project)).Msg("Validating project against registry")
+	if err := validate(ctx, r, candidate, project); err != nil {
+		return fmt.Errorf("%s: registry rejected %s: %w", constants.ErrMsgValidationFailed, project, err)
+	}
+	return nil
+}
+
+// Config returns the registry configuration at a snapshot ("" for the current one).
+// Configs are cached per snapshot.
+func (r *Cache) Config(ctx context.Context, snapshot git.Hash) (*Config, error) {
+	ctx, unlock, err := r.lock(ctx, lockShared)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	snapshot, err = r.getOrCreateSnapshot(ctx, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	r.configMu.Lock()
+	defer r.configMu.Unlock()
+
+	if config, ok := r.configs[snapshot]; ok {
+		return config, nil
+	}
+	config, err := r.loadConfig(ctx, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if r.configs == nil {
+		r.configs = make(map[git.Hash]*Config)
+	}
+	r.configs[snapshot] = config
+	return config, nil
+}
+
+// checkAllowedFiles rejects files whose extension the registry config does not allow.
+func checkAllowedFiles(config *Config, files []LocalProjectFile) error {
+	var disallowed []string
+	for _, f := range files {
+		if !config.AllowsFile(f.Path) {
+			disallowed = append(disallowed, f.Path)
+		}
+	}
+	if len(disallowed) > 0 {
+		return fmt.Errorf("%w: %s (allowed extensions: %s)", errors.ErrDisallowedFile,
+			strings.Join(disallowed, ", "), strings.Join(config.Extensions(), ", "))
+	}
+	return nil
+}
+
+// loadConfig reads protato.registry.yaml at a snapshot, migrated to CurrentConfigAPIVersion.
+// Registries without one use the default Config.
+func (r *Cache) loadConfig(ctx context.Context, snapshot git.Hash) (*Config, error) {
+	entries, err := r.repo.ReadTree(ctx, git.Treeish(snapshot), git.ReadTreeOptions{
+		Paths: []string{constants.RegistryConfigFile},
+	})
+	if err != nil {
+		return nil, readTreeError(err)
+	}
+
+	config := &Config{}
+	for _, entry := range entries {
+		if entry.Path != constants.RegistryConfigFile || !isBlobType(entry.Type) {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := r.repo.ReadObject(ctx, git.BlobType, entry.Hash, &buf); err != nil {
+			return nil, fmt.Errorf("read %s: %w", constants.RegistryConfigFile, err)
+		}
+		if err := yaml.Unmarshal(buf.Bytes(), config); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", constants.RegistryConfigFile, err)
+		}
+	}
+	if err := config.migrate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", constants.RegistryConfigFile, err)
+	}
+	return config, nil
+}
+
 // getOrCreateSnapshot gets the snapshot from request or creates a new one.
 func (r *Cache) getOrCreateSnapshot(ctx context.Context, snapshot git.Hash) (git.Hash, error) {
 	if snapshot != "" {
@@ -452,84 +1544,217 @@ func (r *Cache) getOrCreateSnapshot(ctx context.Context, snapshot git.Hash) (git
 }
 
 // prepareUpserts prepares tree upserts for project metadata and files.
-func (r *Cache) prepareUpserts(ctx context.Context, project *Project, files []LocalProjectFile, projectPrefix string) ([]git.TreeUpsert, error) {
-	var upserts []git.TreeUpsert
+// Files larger than maxFileSize are rejected before any object is written.
+func (r *Cache) prepareUpserts(ctx context.Context, project *Project, files []LocalProjectFile, projectPrefix string, maxFileSize int64, symlinks SymlinkPolicy) ([]git.TreeUpsert, error) {
+	if err := checkSymlinks(files, symlinks); err != nil {
+		return nil, err
+	}
+	if err := checkFileSizes(files, maxFileSize); err != nil {
+		return nil, err
+	}
 
-	// Write project metadata
+	// Project metadata first, then the files, all written in one batch
 	metaContent := fmt.Sprintf("git:\n  commit: %s\n  url: %s\n", project.Commit, project.RepositoryURL)
-	metaHash, err := r.writeObject(ctx, strings.NewReader(metaContent))
-	if err != nil {
-		return nil, fmt.Errorf("write project meta: %w", err)
+	if project.RequireApproval {
+		metaContent += "requireApproval: true\n"
 	}
-	upserts = append(upserts, createTreeUpsert(projectPathJoin(projectPrefix, constants.ProjectMetaFile), metaHash))
+	paths := []string{projectPathJoin(projectPrefix, constants.ProjectMetaFile)}
+	bodies := []io.Reader{strings.NewReader(metaContent)}
 
-	// Write files
 	for _, file := range files {
-		var hash git.Hash
-		var err error
-
-		if file.Content != nil {
-			// Use provided content (e.g., transformed imports)
-			hash, err = r.writeObject(ctx, bytes.NewReader(file.Content))
-			if err != nil {
-				return nil, fmt.Errorf("write transformed object: %w", err)
-			}
-		} else {
-			// Read from local file
-			f, err := os.Open(file.LocalPath)
+		content := file.Content
+		if content == nil {
+			// Read from local file; otherwise use provided content (e.g., transformed imports)
+			var err error
+			content, err = os.ReadFile(file.LocalPath)
 			if err != nil {
 				return nil, fmt.Errorf("open file %s: %w", file.LocalPath, err)
 			}
-
-			hash, err = r.writeObject(ctx, f)
-			f.Close()
-			if err != nil {
-				return nil, fmt.Errorf("write object: %w", err)
-			}
 		}
+		paths = append(paths, projectPathJoin(projectPrefix, file.Path))
+		bodies = append(bodies, bytes.NewReader(content))
+	}
 
-		upserts = append(upserts, createTreeUpsert(projectPathJoin(projectPrefix, file.Path), hash))
+	hashes, err := r.repo.WriteObjectBatch(ctx, bodies)
+	if err != nil {
+		return nil, fmt.Errorf("write objects: %w", err)
 	}
 
+	upserts := make([]git.TreeUpsert, len(paths))
+	for i, p := range paths {
+		upserts[i] = createTreeUpsert(p, hashes[i])
+	}
 	return upserts, nil
 }
 
-// prepareDeletes prepares which files should be deleted from the registry.
-func (r *Cache) prepareDeletes(ctx context.Context, projectPath ProjectPath, newFiles []LocalProjectFile, snapshot git.Hash, projectPrefix string) ([]string, error) {
-	existingFiles, _ := r.ListProjectFiles(ctx, &ListProjectFilesRequest{
-		Project:  projectPath,
-		Snapshot: snapshot,
+// checkSymlinks rejects symlinked files the policy does not publish, and symlinks whose
+// target resolves outside the policy root. Files are read through their links afterwards,
+// so this is what keeps a push from publishing content from elsewhere on the machine.
+func checkSymlinks(files []LocalProjectFile, policy SymlinkPolicy) error {
+	root := policy.Root
+	if root != "" {
+		resolved, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			return fmt.Errorf("resolve %s: %w", root, err)
+		}
+		root = resolved
+	}
+
+	for _, file := range files {
+		if file.LocalPath == "" {
+			continue
+		}
+		info, err := os.Lstat(file.LocalPath)
+		if err != nil {
+			return fmt.Errorf("stat file %s: %w", file.LocalPath, err)
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+
+		if !policy.Dereference && !strings.HasSuffix(file.Path, constants.ProtoFileExt) {
+			return fmt.Errorf("%s %s: only .proto symlinks are published by default", constants.ErrMsgSymlink, file.Path)
+		}
+		if root == "" {
+			continue
+		}
+		target, err := filepath.EvalSymlinks(file.LocalPath)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", constants.ErrMsgSymlink, file.Path, err)
+		}
+		if rel, err := filepath.Rel(root, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("%s %s: target %s is outside %s", constants.ErrMsgSymlink, file.Path, target, policy.Root)
+		}
+	}
+	return nil
+}
+
+// nonProtoSizeDivisor scales down the size limit for non-proto files.
+// Anything other than a .proto that large is most likely generated output or a binary.
+const nonProtoSizeDivisor = 8
+
+// checkFileSizes returns an error listing every file over its size limit.
+// Non-proto files are limited to maxFileSize/nonProtoSizeDivisor.
+func checkFileSizes(files []LocalProjectFile, maxFileSize int64) error {
+	if maxFileSize <= 0 {
+		return nil
+	}
+
+	var oversized []string
+	for _, file := range files {
+		size, err := localFileSize(file)
+		if err != nil {
+			return err
+		}
+
+		limit := maxFileSize
+		if !strings.HasSuffix(file.Path, constants.ProtoFileExt) {
+			limit = maxFileSize / nonProtoSizeDivisor
+		}
+		if size > limit {
+			oversized = append(oversized, fmt.Sprintf("%s (%d bytes, limit %d)", file.Path, size, limit))
+		}
+	}
+
+	if len(oversized) > 0 {
+		return fmt.Errorf("%s: %s", constants.ErrMsgFileTooLarge, strings.Join(oversized, ", "))
+	}
+	return nil
+}
+
+// localFileSize returns the size of the content that would be uploaded for a file.
+func localFileSize(file LocalProjectFile) (int64, error) {
+	if file.Content != nil {
+		return int64(len(file.Content)), nil
+	}
+	info, err := os.Stat(file.LocalPath)
+	if err != nil {
+		return 0, fmt.Errorf("stat file %s: %w", file.LocalPath, err)
+	}
+	return info.Size(), nil
+}
+
+// prepareDeletes prepares which files should be deleted from the registry: every file of
+// the project at snapshot that newFiles drops, except protato's metadata files. The tree is
+// read unfiltered, so files of a type config no longer allows are deleted too.
+func (r *Cache) prepareDeletes(ctx context.Context, config *Config, newFiles []LocalProjectFile, snapshot git.Hash, projectPrefix string) ([]string, error) {
+	entries, err := r.repo.ReadTree(ctx, git.Treeish(snapshot), git.ReadTreeOptions{
+		Recurse: true,
+		Paths:   []string{projectPrefix},
 	})
+	if err != nil {
+		return nil, readTreeError(err)
+	}
 
 	newFilesMap := make(map[string]bool)
 	for _, f := range newFiles {
 		newFilesMap[f.Path] = true
 	}
 
+	special := config.SpecialFiles()
 	var deletes []string
-	if existingFiles != nil {
-		for _, f := range existingFiles.Files {
-			if !newFilesMap[f.Path] {
-				deletes = append(deletes, projectPathJoin(projectPrefix, f.Path))
-			}
+	for _, entry := range entries {
+		if !isBlobType(entry.Type) || special.Contains(entry.Path) {
+			continue
+		}
+		if !newFilesMap[utils.TrimPathPrefix(entry.Path, projectPrefix)] {
+			deletes = append(deletes, entry.Path)
 		}
 	}
 
 	return deletes, nil
 }
 
-// createProjectCommit creates a commit for the project update.
-func (r *Cache) createProjectCommit(ctx context.Context, req *SetProjectRequest, snapshot git.Hash, tree git.Hash) (git.Hash, error) {
-	if req.Author == nil {
+// approvalTrailer records the approval of a push in the registry commit message.
+const approvalTrailer = "Approved-by:"
+
+// projectCommitMessage returns the commit message of a project update, with the approval as a trailer when there is one.
+func projectCommitMessage(req *SetProjectRequest, approval string) string {
+	message := fmt.Sprintf("%s: %d files", req.Project.Path, len(req.Files))
+	if approval != "" {
+		message += fmt.Sprintf("\n\n%s %s", approvalTrailer, approval)
+	}
+	return message
+}
+
+// projectsCommitMessage returns the commit message of a multi-project update. A single project
+// keeps the message of projectCommitMessage; several are listed one per line under a summary.
+func projectsCommitMessage(reqs []*SetProjectRequest, approvals []string) string {
+	if len(reqs) == 1 {
+		return projectCommitMessage(reqs[0], approvals[0])
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Update %d projects\n", len(reqs))
+	for _, req := range reqs {
+		fmt.Fprintf(&b, "\n%s: %d files", req.Project.Path, len(req.Files))
+	}
+
+	seen := make(map[string]bool)
+	var trailers []string
+	for _, approval := range approvals {
+		if approval != "" && !seen[approval] {
+			seen[approval] = true
+			trailers = append(trailers, fmt.Sprintf("%s %s", approvalTrailer, approval))
+		}
+	}
+	if len(trailers) > 0 {
+		b.WriteString("\n\n" + strings.Join(trailers, "\n"))
+	}
+	return b.String()
+}
+
+// createProjectCommit creates a commit of tree on top of snapshot for a project change.
+func (r *Cache) createProjectCommit(ctx context.Context, author *git.Author, message string, snapshot git.Hash, tree git.Hash) (git.Hash, error) {
+	if author == nil {
 		return "", fmt.Errorf("author is required")
 	}
 
-	message := fmt.Sprintf("%s: %d files", req.Project.Path, len(req.Files))
 	newCommit, err := r.repo.CommitTree(ctx, git.CommitTreeRequest{
 		Tree:    tree,
 		Parents: []git.Hash{snapshot},
 		Message: message,
-		Author:  *req.Author,
+		Author:  *author,
 	})
 	if err != nil {
 		return "", fmt.Errorf("create commit: %w", err)
@@ -538,21 +1763,139 @@ func (r *Cache) createProjectCommit(ctx context.Context, req *SetProjectRequest,
 	return newCommit, nil
 }
 
-// Push pushes a commit to the remote registry.
-func (r *Cache) Push(ctx context.Context, hash git.Hash) error {
-	// Get the default branch from HEAD
-	branch := r.getDefaultBranch(ctx)
+// Push pushes a commit to the remote registry and returns the commit that was pushed.
+// The push is atomic, so the remote either takes the whole commit or nothing. Transient
+// failures are retried; when the branch moved meanwhile, the changes of hash are re-applied
+// onto its new tip, so the returned commit differs from hash. Every compactEvery pushes the
+// cache is compacted when git finds it worthwhile.
+func (r *Cache) Push(ctx context.Context, hash git.Hash) (git.Hash, error) {
+	ctx, unlock, err := r.lock(ctx, lockExclusive)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	pushed, err := r.pushWithRetry(ctx, hash)
+	if err != nil {
+		return "", err
+	}
+	r.compactAfterPush(ctx)
+	return pushed, nil
+}
+
+// pushOptions returns the options for pushing hash to branch.
+func pushOptions(branch string, hash git.Hash) git.PushOptions {
+	return git.PushOptions{
+		Remote: "origin",
+		Atomic: true,
+		RefSpecs: []git.Refspec{
+			buildRefspec(string(hash), buildBranchRef(branch)),
+		},
+	}
+}
+
+// AmendBase returns the commit a push --amend builds on: the parent of snapshot.
+// Only one's own last push can be replaced, so the snapshot commit must be authored
+// by author and must update one of projects, each project it updates owned by repoURL.
+func (r *Cache) AmendBase(ctx context.Context, snapshot git.Hash, author git.Author, repoURL string, projects []ProjectPath) (git.Hash, error) {
+	parent, err := r.amendParent(ctx, snapshot, author, repoURL, projects)
+	if err != nil {
+		return "", err
+	}
+	if err := r.EnsureSnapshotAvailable(ctx, parent); err != nil {
+		return "", err
+	}
+	return parent, nil
+}
+
+// amendParent checks that snapshot can be amended and returns its parent.
+func (r *Cache) amendParent(ctx context.Context, snapshot git.Hash, author git.Author, repoURL string, projects []ProjectPath) (git.Hash, error) {
+	ctx, unlock, err := r.lock(ctx, lockShared)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	var buf bytes.Buffer
+	if err := r.repo.ReadObject(ctx, git.CommitType, snapshot, &buf); err != nil {
+		return "", fmt.Errorf("read commit %s: %w", snapshot.Short(), err)
+	}
+	commit, err := git.ParseCommit(buf.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	if len(commit.Parents) != 1 {
+		return "", fmt.Errorf("%s %s: it has %d parents", constants.ErrMsgAmend, snapshot.Short(), len(commit.Parents))
+	}
+	if !strings.EqualFold(commit.Author.Email, author.Email) {
+		return "", fmt.Errorf("%s %s: authored by %s, not %s", constants.ErrMsgAmend, snapshot.Short(), commit.Author.Email, author.Email)
+	}
+	updated := updatedProjects(commit.Message, projects)
+	if len(updated) == 0 {
+		return "", fmt.Errorf("%s %s: it does not update any of the pushed projects", constants.ErrMsgAmend, snapshot.Short())
+	}
+
+	// The same author may push from several repositories; the commit must come from this one.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range updated {
+		res := r.tryFindProjectAtPath(ctx, snapshot, string(p))
+		if res == nil || res.Project.RepositoryURL != repoURL {
+			return "", fmt.Errorf("%s %s: %s is not owned by %s", constants.ErrMsgAmend, snapshot.Short(), p, repoURL)
+		}
+	}
+
+	return commit.Parents[0], nil
+}
+
+// updatedProjects returns the projects a registry commit message names.
+// Project commits are titled "<project>: <n> files"; multi-project commits list one such line per project.
+func updatedProjects(message string, projects []ProjectPath) []ProjectPath {
+	var updated []ProjectPath
+	for _, p := range projects {
+		for _, line := range strings.Split(message, "\n") {
+			if strings.HasPrefix(line, string(p)+": ") {
+				updated = append(updated, p)
+				break
+			}
+		}
+	}
+	return updated
+}
+
+// PushAmend force-pushes hash to the default branch, provided the branch still points at replaced.
+func (r *Cache) PushAmend(ctx context.Context, hash, replaced git.Hash) error {
+	ctx, unlock, err := r.lock(ctx, lockExclusive)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return r.repo.Push(ctx, amendPushOptions(r.getDefaultBranch(ctx), hash, replaced))
+}
 
-	return r.repo.Push(ctx, git.PushOptions{
-		Remote: "origin",
-		RefSpecs: []git.Refspec{
-			buildRefspec(string(hash), buildBranchRef(branch)),
-		},
-	})
+// amendPushOptions returns the options for replacing the tip of branch with hash.
+func amendPushOptions(branch string, hash, replaced git.Hash) git.PushOptions {
+	ref := buildBranchRef(branch)
+	return git.PushOptions{
+		Remote:         "origin",
+		RefSpecs:       []git.Refspec{buildRefspec(string(hash), ref)},
+		ForceWithLease: []git.Lease{{Ref: ref, Expect: replaced}},
+	}
 }
 
 // getDefaultBranch returns the default branch name (main, master, etc.)
+// A configured branch wins, then the branch the remote's HEAD points to; when the
+// remote cannot be asked, it is guessed from the local HEAD.
 func (r *Cache) getDefaultBranch(ctx context.Context) string {
+	if r.branch != "" {
+		return r.branch
+	}
+	if branch := r.remoteDefaultBranch(ctx); branch != "" {
+		return branch
+	}
+
 	headRef, err := r.repo.RevHash(ctx, "HEAD")
 	if err != nil {
 		return "main"
@@ -567,9 +1910,35 @@ func (r *Cache) getDefaultBranch(ctx context.Context) string {
 	return "main"
 }
 
-// findBranchMatchingHash checks common branch names to find one matching the given hash.
+// remoteDefaultBranch returns the branch the remote's HEAD points to, or "" if the
+// remote is unreachable or does not advertise it. The remote is only asked once.
+func (r *Cache) remoteDefaultBranch(ctx context.Context) string {
+	r.remoteHeadOnce.Do(func() {
+		refs, err := r.repo.LsRemote(ctx, git.LsRemoteOptions{Remote: "origin", Symref: true, Patterns: []string{"HEAD"}})
+		if err != nil {
+			logger.Log(ctx).Debug().Err(err).Msg("Could not ask remote for its default branch")
+			return
+		}
+		for _, ref := range refs {
+			if ref.Name != "HEAD" {
+				continue
+			}
+			if branch, ok := strings.CutPrefix(ref.Target, "refs/heads/"); ok {
+				r.remoteHead = branch
+			}
+		}
+	})
+	return r.remoteHead
+}
+
+// findBranchMatchingHash checks the configured branch, then common branch names, to find
+// one matching the given hash.
 func (r *Cache) findBranchMatchingHash(ctx context.Context, hash git.Hash) string {
-	for _, branch := range []string{"main", "master"} {
+	candidates := []string{"main", "master"}
+	if r.branch != "" {
+		candidates = append([]string{r.branch}, candidates...)
+	}
+	for _, branch := range candidates {
 		if r.branchMatchesHash(ctx, branch, hash) {
 			return branch
 		}
@@ -592,6 +1961,11 @@ func (r *Cache) URL() string {
 	return r.url
 }
 
+// BlobDir returns the directory in the cache where file contents can be kept by blob hash.
+func (r *Cache) BlobDir() string {
+	return filepath.Join(r.root, constants.BlobCacheDir)
+}
+
 // GetSnapshot gets the current snapshot from the registry.
 func (r *Cache) GetSnapshot(ctx context.Context) (git.Hash, error) {
 	snapshot, err := r.Snapshot(ctx)
@@ -616,13 +1990,28 @@ func (r *Cache) CheckProjectClaim(
 	repoURL string,
 	projectPath string,
 ) error {
+	if snapshot != "" {
+		if err := r.EnsureSnapshotAvailable(ctx, snapshot); err != nil {
+			return err
+		}
+	}
+
+	ctx, unlock, err := r.lock(ctx, lockShared)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	res, err := r.LookupProject(ctx, &LookupProjectRequest{
 		Path:     projectPath,
 		Snapshot: snapshot,
 	})
 
-	if err == errors.ErrNotFound {
-		return r.checkSubprojectConflicts(ctx, snapshot, projectPath)
+	if stderrors.Is(err, errors.ErrNotFound) {
+		if err := r.checkSubprojectConflicts(ctx, snapshot, projectPath); err != nil {
+			return err
+		}
+		return r.checkCaseConflicts(ctx, snapshot, projectPath)
 	}
 	if err != nil {
 		return fmt.Errorf("lookup project: %w", err)
@@ -633,27 +2022,424 @@ func (r *Cache) CheckProjectClaim(
 
 // checkSubprojectConflicts checks if any subprojects exist under the path.
 func (r *Cache) checkSubprojectConflicts(ctx context.Context, snapshot git.Hash, projectPath string) error {
-	subprojects, _ := r.ListProjects(ctx, &ListProjectsOptions{
+	subprojects, err := r.subprojects(ctx, snapshot, projectPath)
+	if err != nil {
+		return err
+	}
+	if len(subprojects) > 0 {
+		return newClaimError(errors.ErrSubprojectConflict, "%s: cannot create project %q: overlaps with existing projects", constants.ErrMsgProjectClaim, projectPath)
+	}
+	return nil
+}
+
+// subprojects lists the projects nested under the path, excluding a project at the path itself.
+func (r *Cache) subprojects(ctx context.Context, snapshot git.Hash, projectPath string) ([]ProjectPath, error) {
+	projects, err := r.ListProjects(ctx, &ListProjectsOptions{
 		Prefix:   projectPath + "/",
 		Snapshot: snapshot,
 	})
-	if len(subprojects) > 0 {
-		return fmt.Errorf("%s: cannot create project %q: overlaps with existing projects", constants.ErrMsgProjectClaim, projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("list subprojects: %w", err)
+	}
+	var subprojects []ProjectPath
+	for _, p := range projects {
+		if string(p) != projectPath {
+			subprojects = append(subprojects, p)
+		}
+	}
+	return subprojects, nil
+}
+
+// checkCaseConflicts checks if an existing project differs from the path only by case.
+// Such paths collide on case-insensitive filesystems even though lookups treat them as distinct.
+func (r *Cache) checkCaseConflicts(ctx context.Context, snapshot git.Hash, projectPath string) error {
+	projects, err := r.ListProjects(ctx, &ListProjectsOptions{Snapshot: snapshot})
+	if err != nil {
+		return fmt.Errorf("list projects: %w", err)
+	}
+	for _, existing := range projects {
+		if err := utils.ProjectsOverlap([]string{string(existing), projectPath}); err != nil {
+			return newClaimError(errors.ErrCaseConflict, "%s: cannot create project %q: conflicts with existing project %q by case", constants.ErrMsgProjectClaim, projectPath, existing)
+		}
 	}
 	return nil
 }
 
+// newClaimError creates a ClaimError with a formatted message.
+func newClaimError(reason error, format string, args ...interface{}) *ClaimError {
+	return &ClaimError{Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
 // validateOwnership validates project ownership.
 func (r *Cache) validateOwnership(ctx context.Context, res *LookupProjectResponse, repoURL, projectPath string) error {
+	if err := checkOwner(res, repoURL, projectPath); err != nil {
+		return err
+	}
+
+	logger.Log(ctx).Info().Str("project", projectPath).Msg("Project already exists in registry, adding to local config")
+	return nil
+}
+
+// checkOwner checks that the looked-up project is the one at projectPath and, unless
+// repoURL is empty, that repoURL owns it.
+func checkOwner(res *LookupProjectResponse, repoURL, projectPath string) error {
 	if string(res.Project.Path) != projectPath {
-		return fmt.Errorf("%s: cannot create project %q: parent project %q already exists", constants.ErrMsgProjectClaim, projectPath, res.Project.Path)
+		claimErr := newClaimError(errors.ErrParentProjectExists, "%s: cannot create project %q: parent project %q already exists", constants.ErrMsgProjectClaim, projectPath, res.Project.Path)
+		claimErr.Owner = res.Project
+		return claimErr
 	}
 
 	if repoURL != "" && res.Project.RepositoryURL != repoURL {
-		return fmt.Errorf("%s: project %q is owned by %s", constants.ErrMsgOwnership, projectPath, res.Project.RepositoryURL)
+		reason := &OwnershipError{Path: res.Project.Path, OwnerURL: res.Project.RepositoryURL, RequesterURL: repoURL}
+		claimErr := newClaimError(reason, "%s: project %q is owned by %s", constants.ErrMsgOwnership, projectPath, res.Project.RepositoryURL)
+		claimErr.Owner = res.Project
+		return claimErr
 	}
-
-	logger.Log(ctx).Info().Str("project", projectPath).Msg("Project already exists in registry, adding to local config")
 	return nil
 }
 
+// PruneOrphans lists projects whose repository URL fails checkURL.
+// It only reports candidates; pass them to DeleteProjects to remove them.
+// checkURL may reach out to the network, so it runs after the cache locks are released.
+func (r *Cache) PruneOrphans(ctx context.Context, snapshot git.Hash, checkURL func(string) bool) ([]ProjectPath, error) {
+	owners, err := r.projectOwners(ctx, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []ProjectPath
+	for _, owner := range owners {
+		if checkURL(owner.RepositoryURL) {
+			continue
+		}
+
+		logger.Log(ctx).Debug().
+			Str("project", string(owner.Path)).
+			Str("url", owner.RepositoryURL).
+			Msg("Project repository is unreachable")
+		orphans = append(orphans, owner.Path)
+	}
+
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i] < orphans[j] })
+	return orphans, nil
+}
+
+// projectOwners reads the metadata of every project at snapshot, skipping projects whose
+// metadata cannot be read.
+func (r *Cache) projectOwners(ctx context.Context, snapshot git.Hash) ([]*Project, error) {
+	ctx, unlock, err := r.lock(ctx, lockShared)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	snapshot, err = r.getOrCreateSnapshot(ctx, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := r.ListProjects(ctx, &ListProjectsOptions{Snapshot: snapshot})
+	if err != nil {
+		return nil, fmt.Errorf("list projects: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var owners []*Project
+	for _, project := range projects {
+		res := r.tryFindProjectAtPath(ctx, snapshot, string(project))
+		if res == nil {
+			logger.Log(ctx).Warn().Str("project", string(project)).Msg("Could not read project metadata, skipping")
+			continue
+		}
+		owners = append(owners, res.Project)
+	}
+	return owners, nil
+}
+
+// DeleteProjects removes projects from the registry in a single commit. A project with other
+// projects nested under it is refused unless they are deleted too.
+// Returns the new snapshot; the caller is responsible for pushing it.
+func (r *Cache) DeleteProjects(ctx context.Context, req *DeleteProjectsRequest) (git.Hash, error) {
+	ctx, unlock, err := r.lock(ctx, lockExclusive)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	if req.Author == nil {
+		return "", fmt.Errorf("author is required")
+	}
+
+	snapshot, err := r.getOrCreateSnapshot(ctx, req.Snapshot)
+	if err != nil {
+		return "", err
+	}
+
+	currentTree, err := r.repo.RevHash(ctx, string(snapshot)+"^{tree}")
+	if err != nil {
+		return "", fmt.Errorf("get current tree: %w", err)
+	}
+
+	deleting := make(map[ProjectPath]bool, len(req.Projects))
+	for _, project := range req.Projects {
+		deleting[project] = true
+	}
+
+	var deletes []string
+	for _, project := range req.Projects {
+		// Like DeleteProject, refuse to delete a project out from under nested projects,
+		// unless they are deleted along with it
+		subprojects, err := r.subprojects(ctx, snapshot, string(project))
+		if err != nil {
+			return "", err
+		}
+		for _, sub := range subprojects {
+			if !deleting[sub] {
+				return "", fmt.Errorf("cannot delete %s: %w: %s", project, errors.ErrSubprojectConflict, sub)
+			}
+		}
+		if deletedWithParent(project, deleting) {
+			continue
+		}
+
+		paths, err := r.projectBlobPaths(ctx, snapshot, project)
+		if err != nil {
+			return "", err
+		}
+		deletes = append(deletes, paths...)
+	}
+
+	newTree, err := r.repo.UpdateTree(ctx, git.UpdateTreeRequest{
+		Tree:    currentTree,
+		Deletes: deletes,
+	})
+	if err != nil {
+		return "", fmt.Errorf("update tree: %w", err)
+	}
+
+	newCommit, err := r.repo.CommitTree(ctx, git.CommitTreeRequest{
+		Tree:    newTree,
+		Parents: []git.Hash{snapshot},
+		Message: fmt.Sprintf("Delete %d projects", len(req.Projects)),
+		Author:  *req.Author,
+	})
+	if err != nil {
+		return "", fmt.Errorf("create commit: %w", err)
+	}
+
+	return newCommit, nil
+}
+
+// deletedWithParent reports whether a project above project is in deleting, so its files
+// are deleted with that project's.
+func deletedWithParent(project ProjectPath, deleting map[ProjectPath]bool) bool {
+	for p := path.Dir(string(project)); p != "." && p != "/"; p = path.Dir(p) {
+		if deleting[ProjectPath(p)] {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteProject removes a project claimed by the caller's repository from the registry,
+// including its protato.root.yaml. A project with other projects nested under it is refused.
+// Returns ErrNotFound when the path is not a project. The caller is responsible for pushing the new snapshot.
+func (r *Cache) DeleteProject(ctx context.Context, req *DeleteProjectRequest) (*DeleteProjectResponse, error) {
+	ctx, unlock, err := r.lock(ctx, lockExclusive)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if req.Author == nil {
+		return nil, fmt.Errorf("author is required")
+	}
+	projectPath := string(req.Project)
+
+	res, err := r.LookupProject(ctx, &LookupProjectRequest{Path: projectPath, Snapshot: req.Snapshot})
+	if err != nil {
+		return nil, err
+	}
+	if string(res.Project.Path) != projectPath {
+		return nil, &ProjectNotFoundError{Path: projectPath}
+	}
+	if err := checkOwner(res, req.RepositoryURL, projectPath); err != nil {
+		return nil, err
+	}
+	snapshot := res.Snapshot
+
+	subprojects, err := r.subprojects(ctx, snapshot, projectPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(subprojects) > 0 {
+		return nil, fmt.Errorf("cannot delete %s: %w: %v", projectPath, errors.ErrSubprojectConflict, subprojects)
+	}
+
+	currentTree, err := r.repo.RevHash(ctx, string(snapshot)+"^{tree}")
+	if err != nil {
+		return nil, fmt.Errorf("get current tree: %w", err)
+	}
+	deletes, err := r.projectBlobPaths(ctx, snapshot, req.Project)
+	if err != nil {
+		return nil, err
+	}
+	newTree, err := r.repo.UpdateTree(ctx, git.UpdateTreeRequest{
+		Tree:    currentTree,
+		Deletes: deletes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("update tree: %w", err)
+	}
+
+	newCommit, err := r.createProjectCommit(ctx, req.Author, fmt.Sprintf("%s: delete project", projectPath), snapshot, newTree)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeleteProjectResponse{
+		Snapshot:     newCommit,
+		FilesDeleted: len(deletes),
+	}, nil
+}
+
+// RenameProject moves a project claimed by the caller's repository to a new path in a single
+// commit. protato.root.yaml (commit and repository URL) moves unchanged; the proto files move
+// with req.MoveImports applied, so imports between them follow the project. The destination
+// must not be a project and must be claimable by the caller; a project with other projects
+// nested under it is refused, as is moving a project into or above itself. The moved project
+// is validated like a push. The caller is responsible for pushing the new snapshot.
+func (r *Cache) RenameProject(ctx context.Context, req *RenameProjectRequest) (*RenameProjectResponse, error) {
+	ctx, unlock, err := r.lock(ctx, lockExclusive)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if req.Author == nil {
+		return nil, fmt.Errorf("author is required")
+	}
+	from, to := string(req.From), string(req.To)
+	if from == to || strings.HasPrefix(to, from+"/") || strings.HasPrefix(from, to+"/") {
+		return nil, fmt.Errorf("cannot rename %s to %s: paths overlap", from, to)
+	}
+
+	res, err := r.LookupProject(ctx, &LookupProjectRequest{Path: from, Snapshot: req.Snapshot})
+	if err != nil {
+		return nil, err
+	}
+	if string(res.Project.Path) != from {
+		return nil, &ProjectNotFoundError{Path: string(from)}
+	}
+	if err := checkOwner(res, req.RepositoryURL, from); err != nil {
+		return nil, err
+	}
+	snapshot := res.Snapshot
+
+	subprojects, err := r.subprojects(ctx, snapshot, from)
+	if err != nil {
+		return nil, err
+	}
+	if len(subprojects) > 0 {
+		return nil, fmt.Errorf("cannot rename %s: %w: %v", from, errors.ErrSubprojectConflict, subprojects)
+	}
+
+	if dest := r.tryFindProjectAtPath(ctx, snapshot, to); dest != nil {
+		if err := checkOwner(dest, req.RepositoryURL, to); err != nil {
+			return nil, err
+		}
+		claimErr := newClaimError(errors.ErrProjectExists, "%s: project %q already exists", constants.ErrMsgProjectClaim, to)
+		claimErr.Owner = dest.Project
+		return nil, claimErr
+	}
+	if err := r.CheckProjectClaim(ctx, snapshot, req.RepositoryURL, to); err != nil {
+		return nil, err
+	}
+
+	entries, err := r.repo.ReadTree(ctx, git.Treeish(snapshot), git.ReadTreeOptions{
+		Recurse: true,
+		Paths:   []string{protosPath(from)},
+	})
+	if err != nil {
+		return nil, readTreeError(err)
+	}
+	update := git.UpdateTreeRequest{}
+	fromPrefix := protosPath(from) + "/"
+	for _, entry := range entries {
+		if !isBlobType(entry.Type) {
+			continue
+		}
+		blob, err := r.moveBlobImports(ctx, entry, req.MoveImports, from, to)
+		if err != nil {
+			return nil, err
+		}
+		update.Deletes = append(update.Deletes, entry.Path)
+		update.Upserts = append(update.Upserts, git.TreeUpsert{
+			Path: protosPath(to, strings.TrimPrefix(entry.Path, fromPrefix)),
+			Blob: blob,
+			Mode: entry.Mode,
+		})
+	}
+
+	if update.Tree, err = r.repo.RevHash(ctx, string(snapshot)+"^{tree}"); err != nil {
+		return nil, fmt.Errorf("get current tree: %w", err)
+	}
+	newTree, err := r.repo.UpdateTree(ctx, update)
+	if err != nil {
+		return nil, fmt.Errorf("update tree: %w", err)
+	}
+
+	newCommit, err := r.createProjectCommit(ctx, req.Author, fmt.Sprintf("%s: rename from %s", to, from), snapshot, newTree)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.validateProject(ctx, req.Validate, req.To, snapshot, newCommit); err != nil {
+		return nil, err
+	}
+
+	return &RenameProjectResponse{
+		Snapshot:   newCommit,
+		FilesMoved: len(update.Upserts),
+	}, nil
+}
+
+// moveBlobImports returns the blob of a moved project file with move applied to its imports.
+// Files other than protos, and protos whose imports do not change, keep their blob.
+func (r *Cache) moveBlobImports(ctx context.Context, entry git.TreeEntry, move ImportRewriter, from, to string) (git.Hash, error) {
+	if move == nil || !strings.HasSuffix(entry.Path, constants.ProtoFileExt) {
+		return entry.Hash, nil
+	}
+	var buf bytes.Buffer
+	if err := r.repo.ReadObject(ctx, git.BlobType, entry.Hash, &buf); err != nil {
+		return "", fmt.Errorf("read %s: %w", entry.Path, err)
+	}
+	moved := move(buf.Bytes(), from, to)
+	if bytes.Equal(moved, buf.Bytes()) {
+		return entry.Hash, nil
+	}
+	blob, err := r.writeObject(ctx, bytes.NewReader(moved))
+	if err != nil {
+		return "", fmt.Errorf("write %s: %w", entry.Path, err)
+	}
+	return blob, nil
+}
+
+// projectBlobPaths lists the registry paths of every file of a project, including its metadata.
+func (r *Cache) projectBlobPaths(ctx context.Context, snapshot git.Hash, project ProjectPath) ([]string, error) {
+	entries, err := r.repo.ReadTree(ctx, git.Treeish(snapshot), git.ReadTreeOptions{
+		Recurse: true,
+		Paths:   []string{protosPath(string(project))},
+	})
+	if err != nil {
+		return nil, readTreeError(err)
+	}
+	var paths []string
+	for _, entry := range entries {
+		if isBlobType(entry.Type) {
+			paths = append(paths, entry.Path)
+		}
+	}
+	return paths, nil
+}
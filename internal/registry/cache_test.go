@@ -4,12 +4,24 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/rs/zerolog"
 
 	"github.com/rahulagarwal0605/protato/internal/constants"
+	protatoerrors "github.com/rahulagarwal0605/protato/internal/errors"
 	"github.com/rahulagarwal0605/protato/internal/git"
 	"github.com/rahulagarwal0605/protato/internal/logger"
 )
@@ -27,20 +39,37 @@ type mockRepository struct {
 	gitDir       string
 	bare         bool
 	fetchErr     error
+	fetchFunc    func() error
+	fetchCalls   []git.FetchOptions
+	config       map[string]string
+	configErr    error
 	pushErr      error
+	pushFunc     func(opts git.PushOptions) error
+	pushCalls    []git.PushOptions
 	revHashErr   error
 	revHashMap   map[string]git.Hash
 	revExists    map[string]bool
 	readTreeErr  error
 	readTreeResp []git.TreeEntry
+	readTreeFunc func(opts git.ReadTreeOptions) ([]git.TreeEntry, error)
 	writeObjErr  error
 	writeObjHash git.Hash
+	writeObjFunc func(content []byte) git.Hash
 	readObjErr   error
 	readObjData  []byte
+	readObjFunc  func(hash git.Hash) []byte
+	missingObjs  map[git.Hash]bool
+	batchCalls   int
+	diffOut      string
+	diffErr      error
+	diffTreeResp []git.DiffEntry
+	diffTreeOpts git.DiffOptions
 	updateTreeErr error
 	updateTreeHash git.Hash
+	updateTreeReq  git.UpdateTreeRequest
 	commitTreeErr  error
 	commitTreeHash git.Hash
+	commitTreeReq  git.CommitTreeRequest
 	updateRefErr   error
 	remoteURL     string
 	remoteURLErr  error
@@ -48,13 +77,38 @@ type mockRepository struct {
 	userErr      error
 	repoURL      string
 	repoURLErr   error
+	lsRemoteRefs []git.RemoteRef
+	lsRemoteErr  error
+	symbolicRefs map[string]string
+	mergeBaseFunc func(a, b git.Treeish) (git.Hash, error)
+	gcCalls      []git.GcOptions
+	gcFunc       func(opts git.GcOptions) error
+	logCommits   []git.Commit
+	logErr       error
+	logCalls     []git.LogOptions
 }
 
 func (m *mockRepository) Root() string                           { return m.rootDir }
 func (m *mockRepository) GitDir() string                         { return m.gitDir }
 func (m *mockRepository) IsBare() bool                           { return m.bare }
-func (m *mockRepository) Fetch(ctx context.Context, opts git.FetchOptions) error { return m.fetchErr }
-func (m *mockRepository) Push(ctx context.Context, opts git.PushOptions) error { return m.pushErr }
+func (m *mockRepository) Fetch(ctx context.Context, opts git.FetchOptions) error {
+	m.fetchCalls = append(m.fetchCalls, opts)
+	if m.fetchFunc != nil {
+		return m.fetchFunc()
+	}
+	return m.fetchErr
+}
+func (m *mockRepository) Unshallow(ctx context.Context) error {
+	return m.Fetch(ctx, git.FetchOptions{Remote: "origin", Unshallow: true, NoWriteFetchHead: true})
+}
+
+func (m *mockRepository) Push(ctx context.Context, opts git.PushOptions) error {
+	m.pushCalls = append(m.pushCalls, opts)
+	if m.pushFunc != nil {
+		return m.pushFunc(opts)
+	}
+	return m.pushErr
+}
 
 func (m *mockRepository) RevHash(ctx context.Context, rev string) (git.Hash, error) {
 	if m.revHashErr != nil {
@@ -78,9 +132,25 @@ func (m *mockRepository) ReadTree(ctx context.Context, tree git.Treeish, opts gi
 	if m.readTreeErr != nil {
 		return nil, m.readTreeErr
 	}
+	if m.readTreeFunc != nil {
+		return m.readTreeFunc(opts)
+	}
 	return m.readTreeResp, nil
 }
 
+func (m *mockRepository) WalkTree(ctx context.Context, tree git.Treeish, opts git.ReadTreeOptions, fn func(git.TreeEntry) error) error {
+	entries, err := m.ReadTree(ctx, tree, opts)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *mockRepository) WriteObject(ctx context.Context, r io.Reader, opts git.WriteObjectOptions) (git.Hash, error) {
 	if m.writeObjErr != nil {
 		return "", m.writeObjErr
@@ -88,10 +158,32 @@ func (m *mockRepository) WriteObject(ctx context.Context, r io.Reader, opts git.
 	return m.writeObjHash, nil
 }
 
+func (m *mockRepository) WriteObjectBatch(ctx context.Context, bodies []io.Reader) ([]git.Hash, error) {
+	if m.writeObjErr != nil {
+		return nil, m.writeObjErr
+	}
+	hashes := make([]git.Hash, len(bodies))
+	for i, body := range bodies {
+		hashes[i] = m.writeObjHash
+		if m.writeObjFunc != nil {
+			content, err := io.ReadAll(body)
+			if err != nil {
+				return nil, err
+			}
+			hashes[i] = m.writeObjFunc(content)
+		}
+	}
+	return hashes, nil
+}
+
 func (m *mockRepository) ReadObject(ctx context.Context, objType git.ObjectType, hash git.Hash, w io.Writer) error {
 	if m.readObjErr != nil {
 		return m.readObjErr
 	}
+	if m.readObjFunc != nil {
+		_, err := w.Write(m.readObjFunc(hash))
+		return err
+	}
 	if m.readObjData != nil {
 		_, err := w.Write(m.readObjData)
 		return err
@@ -99,7 +191,39 @@ func (m *mockRepository) ReadObject(ctx context.Context, objType git.ObjectType,
 	return nil
 }
 
+func (m *mockRepository) CatFileBatch(ctx context.Context, hashes []git.Hash, fn func(git.Hash, git.ObjectType, io.Reader) error) error {
+	m.batchCalls++
+	var missing []error
+	for _, h := range hashes {
+		if m.missingObjs[h] {
+			missing = append(missing, fmt.Errorf("object %s missing: %w", h, protatoerrors.ErrObjectMissing))
+			continue
+		}
+		var buf bytes.Buffer
+		if err := m.ReadObject(ctx, git.BlobType, h, &buf); err != nil {
+			return err
+		}
+		if err := fn(h, git.BlobType, &buf); err != nil {
+			return err
+		}
+	}
+	return errors.Join(missing...)
+}
+
+func (m *mockRepository) Diff(ctx context.Context, oldHash, newHash git.Hash) (string, error) {
+	if m.diffErr != nil {
+		return "", m.diffErr
+	}
+	return m.diffOut, nil
+}
+
+func (m *mockRepository) DiffTree(ctx context.Context, oldTree, newTree git.Treeish, opts git.DiffOptions) ([]git.DiffEntry, error) {
+	m.diffTreeOpts = opts
+	return m.diffTreeResp, nil
+}
+
 func (m *mockRepository) UpdateTree(ctx context.Context, req git.UpdateTreeRequest) (git.Hash, error) {
+	m.updateTreeReq = req
 	if m.updateTreeErr != nil {
 		return "", m.updateTreeErr
 	}
@@ -107,6 +231,7 @@ func (m *mockRepository) UpdateTree(ctx context.Context, req git.UpdateTreeReque
 }
 
 func (m *mockRepository) CommitTree(ctx context.Context, req git.CommitTreeRequest) (git.Hash, error) {
+	m.commitTreeReq = req
 	if m.commitTreeErr != nil {
 		return "", m.commitTreeErr
 	}
@@ -124,6 +249,32 @@ func (m *mockRepository) GetRemoteURL(ctx context.Context, remote string) (strin
 	return m.remoteURL, nil
 }
 
+func (m *mockRepository) LsRemote(ctx context.Context, opts git.LsRemoteOptions) ([]git.RemoteRef, error) {
+	if m.lsRemoteErr != nil {
+		return nil, m.lsRemoteErr
+	}
+	return m.lsRemoteRefs, nil
+}
+
+func (m *mockRepository) SymbolicRef(ctx context.Context, ref string) (string, error) {
+	if target, ok := m.symbolicRefs[ref]; ok {
+		return target, nil
+	}
+	return "", errors.New("symbolic-ref: ref " + ref + " is not a symbolic ref")
+}
+
+func (m *mockRepository) MergeBase(ctx context.Context, a, b git.Treeish) (git.Hash, error) {
+	if m.mergeBaseFunc != nil {
+		return m.mergeBaseFunc(a, b)
+	}
+	return git.Hash(a), nil // a is an ancestor of b
+}
+
+func (m *mockRepository) Log(ctx context.Context, opts git.LogOptions) ([]git.Commit, error) {
+	m.logCalls = append(m.logCalls, opts)
+	return m.logCommits, m.logErr
+}
+
 func (m *mockRepository) GetUser(ctx context.Context) (git.Author, error) {
 	if m.userErr != nil {
 		return git.Author{}, m.userErr
@@ -131,6 +282,18 @@ func (m *mockRepository) GetUser(ctx context.Context) (git.Author, error) {
 	return m.user, nil
 }
 
+func (m *mockRepository) ConfigList(ctx context.Context) (map[string]string, error) {
+	return m.config, m.configErr
+}
+
+func (m *mockRepository) Gc(ctx context.Context, opts git.GcOptions) error {
+	m.gcCalls = append(m.gcCalls, opts)
+	if m.gcFunc != nil {
+		return m.gcFunc(opts)
+	}
+	return nil
+}
+
 func (m *mockRepository) GetRepoURL(ctx context.Context) (string, error) {
 	if m.repoURLErr != nil {
 		return "", m.repoURLErr
@@ -559,6 +722,101 @@ func TestCache_Refresh(t *testing.T) {
 	}
 }
 
+func TestCache_BranchSnapshot(t *testing.T) {
+	tests := []struct {
+		name       string
+		revHashMap map[string]git.Hash
+		want       git.Hash
+		wantErr    bool
+	}{
+		{
+			name: "remote-tracking ref preferred",
+			revHashMap: map[string]git.Hash{
+				"refs/remotes/origin/staging": "remote123",
+				"refs/heads/staging":          "local123",
+			},
+			want: "remote123",
+		},
+		{
+			name:       "local branch fallback",
+			revHashMap: map[string]git.Hash{"refs/heads/staging": "local123"},
+			want:       "local123",
+		},
+		{
+			name:       "branch not found",
+			revHashMap: map[string]git.Hash{"refs/heads/main": "main123"},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newMockCache(&mockRepository{revHashMap: tt.revHashMap}, "https://github.com/test/registry.git")
+			got, err := cache.BranchSnapshot(testContext(), "staging")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BranchSnapshot() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("BranchSnapshot() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCache_RefreshAndGetSnapshot_Concurrent(t *testing.T) {
+	const callers = 8
+
+	var fetches atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	repo := &mockRepository{
+		revHashMap: map[string]git.Hash{
+			"HEAD":       "abc123",
+			"FETCH_HEAD": "def456",
+		},
+		fetchFunc: func() error {
+			if fetches.Add(1) == 1 {
+				close(started)
+			}
+			<-release
+			return nil
+		},
+	}
+	cache := newMockCache(repo, "https://github.com/test/registry.git")
+	ctx := testContext()
+
+	var wg sync.WaitGroup
+	results := make([]git.Hash, callers)
+	errs := make([]error, callers)
+	call := func(i int) {
+		defer wg.Done()
+		results[i], errs[i] = cache.RefreshAndGetSnapshot(ctx)
+	}
+
+	wg.Add(callers)
+	go call(0)
+	<-started
+	for i := 1; i < callers; i++ {
+		go call(i)
+	}
+	// Give the remaining callers time to join the in-flight fetch
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("Fetch() called %d times, want 1", got)
+	}
+	for i := range results {
+		if errs[i] != nil {
+			t.Errorf("caller %d: RefreshAndGetSnapshot() error = %v", i, errs[i])
+		}
+		if results[i] != "def456" {
+			t.Errorf("caller %d: RefreshAndGetSnapshot() = %v, want def456", i, results[i])
+		}
+	}
+}
+
 func TestCache_RefreshAndGetSnapshot(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -664,11 +922,41 @@ func TestCache_getOrCreateSnapshot(t *testing.T) {
 
 func TestCache_getDefaultBranch(t *testing.T) {
 	tests := []struct {
-		name       string
-		revHashMap map[string]git.Hash
-		revHashErr error
-		want       string
+		name         string
+		revHashMap   map[string]git.Hash
+		revHashErr   error
+		lsRemoteRefs []git.RemoteRef
+		branch       string
+		want         string
 	}{
+		{
+			name: "configured branch wins",
+			revHashMap: map[string]git.Hash{
+				"HEAD":            "abc123",
+				"refs/heads/main": "abc123",
+			},
+			lsRemoteRefs: []git.RemoteRef{{Name: "HEAD", Hash: "def456", Target: "refs/heads/trunk"}},
+			branch:       "registry",
+			want:         "registry",
+		},
+		{
+			name: "remote HEAD wins",
+			revHashMap: map[string]git.Hash{
+				"HEAD":            "abc123",
+				"refs/heads/main": "abc123",
+			},
+			lsRemoteRefs: []git.RemoteRef{{Name: "HEAD", Hash: "def456", Target: "refs/heads/trunk"}},
+			want:         "trunk",
+		},
+		{
+			name: "remote without symbolic HEAD falls back to local refs",
+			revHashMap: map[string]git.Hash{
+				"HEAD":              "abc123",
+				"refs/heads/master": "abc123",
+			},
+			lsRemoteRefs: []git.RemoteRef{{Name: "HEAD", Hash: "abc123"}},
+			want:         "master",
+		},
 		{
 			name: "main branch detected",
 			revHashMap: map[string]git.Hash{
@@ -703,9 +991,11 @@ func TestCache_getDefaultBranch(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := &mockRepository{
-				revHashMap: tt.revHashMap,
+				revHashMap:   tt.revHashMap,
+				lsRemoteRefs: tt.lsRemoteRefs,
 			}
 			cache := newMockCache(repo, "https://github.com/test/registry.git")
+			cache.branch = tt.branch
 			ctx := testContext()
 
 			got := cache.getDefaultBranch(ctx)
@@ -722,8 +1012,29 @@ func TestCache_findBranchMatchingHash(t *testing.T) {
 		name       string
 		hash       git.Hash
 		revHashMap map[string]git.Hash
+		branch     string
 		want       string
 	}{
+		{
+			name: "configured branch checked first",
+			hash: "abc123",
+			revHashMap: map[string]git.Hash{
+				"refs/heads/main":              "abc123",
+				"refs/remotes/origin/registry": "abc123",
+			},
+			branch: "registry",
+			want:   "registry",
+		},
+		{
+			name: "falls back to main when configured branch differs",
+			hash: "abc123",
+			revHashMap: map[string]git.Hash{
+				"refs/heads/main":     "abc123",
+				"refs/heads/registry": "def456",
+			},
+			branch: "registry",
+			want:   "main",
+		},
 		{
 			name: "matches main",
 			hash: "abc123",
@@ -762,6 +1073,7 @@ func TestCache_findBranchMatchingHash(t *testing.T) {
 				revHashMap: tt.revHashMap,
 			}
 			cache := newMockCache(repo, "https://github.com/test/registry.git")
+			cache.branch = tt.branch
 			ctx := testContext()
 
 			got := cache.findBranchMatchingHash(ctx, tt.hash)
@@ -773,6 +1085,32 @@ func TestCache_findBranchMatchingHash(t *testing.T) {
 	}
 }
 
+func TestCache_ConfiguredBranchRefspecs(t *testing.T) {
+	repo := &mockRepository{
+		revHashMap:   map[string]git.Hash{"FETCH_HEAD": "abc123"},
+		lsRemoteRefs: []git.RemoteRef{{Name: "HEAD", Hash: "abc123", Target: "refs/heads/main"}},
+	}
+	cache := newMockCache(repo, "https://github.com/test/registry.git")
+	cache.branch = "registry"
+	ctx := testContext()
+
+	if err := cache.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if _, err := cache.Push(ctx, "def456"); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	wantFetch := buildRefspec("refs/heads/registry", "refs/remotes/origin/registry")
+	if len(repo.fetchCalls) != 1 || !reflect.DeepEqual(repo.fetchCalls[0].RefSpecs, []git.Refspec{wantFetch}) {
+		t.Errorf("Refresh() fetches = %+v, want refspec %s", repo.fetchCalls, wantFetch)
+	}
+	wantPush := buildRefspec("def456", "refs/heads/registry")
+	if len(repo.pushCalls) != 1 || !reflect.DeepEqual(repo.pushCalls[0].RefSpecs, []git.Refspec{wantPush}) {
+		t.Errorf("Push() pushes = %+v, want refspec %s", repo.pushCalls, wantPush)
+	}
+}
+
 func TestCache_branchMatchesHash(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -909,11 +1247,14 @@ func TestCache_Push(t *testing.T) {
 			cache := newMockCache(repo, "https://github.com/test/registry.git")
 			ctx := testContext()
 
-			err := cache.Push(ctx, tt.hash)
+			pushed, err := cache.Push(ctx, tt.hash)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Push() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if !tt.wantErr && pushed != tt.hash {
+				t.Errorf("Push() = %s, want %s", pushed, tt.hash)
+			}
 		})
 	}
 }
@@ -1014,6 +1355,16 @@ func TestCache_ListProjects(t *testing.T) {
 			wantLen: 1,
 			wantErr: false,
 		},
+		{
+			name: "empty registry without protos tree",
+			opts: nil,
+			revHashMap: map[string]git.Hash{
+				"FETCH_HEAD": "snapshot123",
+			},
+			readTreeResp: nil,
+			wantLen:      0,
+			wantErr:      false,
+		},
 		{
 			name: "read tree error",
 			opts: nil,
@@ -1180,6 +1531,37 @@ func TestCache_ReadProjectFile(t *testing.T) {
 	}
 }
 
+func TestCache_ReadProjectFiles(t *testing.T) {
+	files := []ProjectFile{
+		{Path: "a.proto", Hash: "h1"},
+		{Path: "gone.proto", Hash: "h2"},
+		{Path: "copy_of_a.proto", Hash: "h1"}, // Same content as a.proto
+		{Path: "b.proto", Hash: "h3"},
+	}
+	repo := &mockRepository{
+		readObjFunc: func(hash git.Hash) []byte { return []byte("content " + hash) },
+		missingObjs: map[git.Hash]bool{"h2": true},
+	}
+	cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+	var read []string
+	err := cache.ReadProjectFiles(testContext(), files, func(file ProjectFile, content io.Reader) error {
+		data, err := io.ReadAll(content)
+		read = append(read, file.Path+": "+string(data))
+		return err
+	})
+	if !errors.Is(err, protatoerrors.ErrObjectMissing) {
+		t.Errorf("ReadProjectFiles() error = %v, want ErrObjectMissing for gone.proto", err)
+	}
+	want := []string{"a.proto: content h1", "copy_of_a.proto: content h1", "b.proto: content h3"}
+	if !reflect.DeepEqual(read, want) {
+		t.Errorf("ReadProjectFiles() read %q, want %q", read, want)
+	}
+	if repo.batchCalls != 1 {
+		t.Errorf("ReadProjectFiles() ran %d batches, want 1", repo.batchCalls)
+	}
+}
+
 func TestCache_LookupProject(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -1277,6 +1659,17 @@ func TestCache_prepareDeletes(t *testing.T) {
 			},
 			wantDeletes: 0,
 		},
+		{
+			name:        "metadata kept and disallowed files deleted",
+			projectPath: "team/service",
+			newFiles:    []LocalProjectFile{{Path: "api.proto"}},
+			readTreeResp: []git.TreeEntry{
+				{Path: constants.ProtosDir + "/team/service/" + constants.ProjectMetaFile, Type: git.BlobType},
+				{Path: constants.ProtosDir + "/team/service/api.proto", Type: git.BlobType},
+				{Path: constants.ProtosDir + "/team/service/schema.json", Type: git.BlobType},
+			},
+			wantDeletes: 1,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1290,7 +1683,7 @@ func TestCache_prepareDeletes(t *testing.T) {
 			cache := newMockCache(repo, "https://github.com/test/registry.git")
 			ctx := testContext()
 
-			deletes, err := cache.prepareDeletes(ctx, tt.projectPath, tt.newFiles, "snapshot123", protosPath(string(tt.projectPath)))
+			deletes, err := cache.prepareDeletes(ctx, &Config{}, tt.newFiles, "snapshot123", protosPath(string(tt.projectPath)))
 
 			if err != nil {
 				t.Errorf("prepareDeletes() error = %v", err)
@@ -1343,13 +1736,7 @@ func TestCache_createProjectCommit(t *testing.T) {
 			cache := newMockCache(repo, "https://github.com/test/registry.git")
 			ctx := testContext()
 
-			req := &SetProjectRequest{
-				Project: &Project{Path: "team/service"},
-				Files:   []LocalProjectFile{{Path: "api.proto"}},
-				Author:  tt.author,
-			}
-
-			_, err := cache.createProjectCommit(ctx, req, "snapshot123", "tree123")
+			_, err := cache.createProjectCommit(ctx, tt.author, "team/service: 1 files", "snapshot123", "tree123")
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("createProjectCommit() error = %v, wantErr %v", err, tt.wantErr)
@@ -1400,6 +1787,7 @@ func TestCache_CheckProjectClaim(t *testing.T) {
 		revHashMap   map[string]git.Hash
 		revExists    map[string]bool
 		readTreeResp []git.TreeEntry
+		readTreeErr  error
 		readObjData  []byte
 		wantErr      bool
 	}{
@@ -1433,6 +1821,28 @@ func TestCache_CheckProjectClaim(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name:        "empty registry without protos tree",
+			snapshot:    "snapshot123",
+			repoURL:     "https://github.com/test/repo.git",
+			projectPath: "team/service",
+			revExists: map[string]bool{
+				"snapshot123": true,
+			},
+			readTreeResp: nil,
+			wantErr:      false,
+		},
+		{
+			name:        "read tree error is not treated as empty",
+			snapshot:    "snapshot123",
+			repoURL:     "https://github.com/test/repo.git",
+			projectPath: "team/service",
+			revExists: map[string]bool{
+				"snapshot123": true,
+			},
+			readTreeErr: errors.New("read tree failed"),
+			wantErr:     true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1441,6 +1851,7 @@ func TestCache_CheckProjectClaim(t *testing.T) {
 				revHashMap:   tt.revHashMap,
 				revExists:    tt.revExists,
 				readTreeResp: tt.readTreeResp,
+				readTreeErr:  tt.readTreeErr,
 				readObjData:  tt.readObjData,
 			}
 			cache := newMockCache(repo, "https://github.com/test/registry.git")
@@ -1576,6 +1987,101 @@ func TestCache_validateOwnership(t *testing.T) {
 	}
 }
 
+func TestCache_validateOwnership_ConflictMetadata(t *testing.T) {
+	owner := &Project{
+		Path:          "team/service",
+		Commit:        "0123456789abcdef0123456789abcdef01234567",
+		RepositoryURL: "https://github.com/other/repo.git",
+	}
+
+	tests := []struct {
+		name            string
+		projectPath     string
+		wantReason      error
+		wantExplanation string
+	}{
+		{
+			name:            "ownership conflict",
+			projectPath:     "team/service",
+			wantReason:      protatoerrors.ErrOwnershipConflict,
+			wantExplanation: "owned by https://github.com/other/repo.git at 0123456, you are https://github.com/test/repo.git; contact them or choose another path",
+		},
+		{
+			name:            "parent project exists",
+			projectPath:     "team/service/v1",
+			wantReason:      protatoerrors.ErrParentProjectExists,
+			wantExplanation: "owned by https://github.com/other/repo.git at 0123456; contact them or choose another path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newMockCache(&mockRepository{}, "https://github.com/test/registry.git")
+
+			err := cache.validateOwnership(testContext(), &LookupProjectResponse{Project: owner}, "https://github.com/test/repo.git", tt.projectPath)
+			if !errors.Is(err, tt.wantReason) {
+				t.Fatalf("validateOwnership() error = %v, want %v", err, tt.wantReason)
+			}
+
+			var claimErr *ClaimError
+			if !errors.As(err, &claimErr) {
+				t.Fatalf("validateOwnership() error = %T, want *ClaimError", err)
+			}
+			if claimErr.Owner == nil || claimErr.Owner.RepositoryURL != owner.RepositoryURL || claimErr.Owner.Commit != owner.Commit {
+				t.Errorf("ClaimError.Owner = %+v, want %+v", claimErr.Owner, owner)
+			}
+			if got := claimErr.Explanation(); got != tt.wantExplanation {
+				t.Errorf("Explanation() = %q, want %q", got, tt.wantExplanation)
+			}
+		})
+	}
+}
+
+func TestCache_TypedErrors(t *testing.T) {
+	owner := &Project{Path: "team/service", Commit: "abc123", RepositoryURL: "https://github.com/other/repo.git"}
+	cache := newMockCache(&mockRepository{}, "https://github.com/test/registry.git")
+
+	t.Run("ownership", func(t *testing.T) {
+		err := cache.validateOwnership(testContext(), &LookupProjectResponse{Project: owner}, "https://github.com/test/repo.git", "team/service")
+		var ownership *OwnershipError
+		if !errors.As(err, &ownership) {
+			t.Fatalf("validateOwnership() error = %T, want *OwnershipError", err)
+		}
+		want := OwnershipError{Path: "team/service", OwnerURL: "https://github.com/other/repo.git", RequesterURL: "https://github.com/test/repo.git"}
+		if *ownership != want {
+			t.Errorf("OwnershipError = %+v, want %+v", *ownership, want)
+		}
+	})
+
+	t.Run("project not found", func(t *testing.T) {
+		repo := &mockRepository{revExists: map[string]bool{"snapshot123": true}, readTreeResp: []git.TreeEntry{}}
+		cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+		_, err := cache.LookupProject(testContext(), &LookupProjectRequest{Path: "team/service/v1", Snapshot: "snapshot123"})
+		if !errors.Is(err, protatoerrors.ErrNotFound) {
+			t.Errorf("LookupProject() error = %v, want it to match ErrNotFound", err)
+		}
+		var notFound *ProjectNotFoundError
+		if !errors.As(err, &notFound) || notFound.Path != "team/service/v1" {
+			t.Errorf("LookupProject() error = %#v, want *ProjectNotFoundError for team/service/v1", err)
+		}
+	})
+
+	t.Run("snapshot not found", func(t *testing.T) {
+		repo := &mockRepository{revExists: map[string]bool{}}
+		cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+		_, err := cache.LookupProject(testContext(), &LookupProjectRequest{Path: "team/service", Snapshot: "deadbeef"})
+		if !errors.Is(err, protatoerrors.ErrSnapshotNotFound) {
+			t.Errorf("LookupProject() error = %v, want it to match ErrSnapshotNotFound", err)
+		}
+		var missing *SnapshotNotFoundError
+		if !errors.As(err, &missing) || missing.Hash != "deadbeef" {
+			t.Errorf("LookupProject() error = %#v, want *SnapshotNotFoundError for deadbeef", err)
+		}
+	})
+}
+
 func TestCache_tryFindProjectAtPath(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -1815,7 +2321,7 @@ func TestCache_prepareUpserts(t *testing.T) {
 			cache := newMockCache(repo, "https://github.com/test/registry.git")
 			ctx := testContext()
 
-			upserts, err := cache.prepareUpserts(ctx, tt.project, tt.files, "protos/team/service")
+			upserts, err := cache.prepareUpserts(ctx, tt.project, tt.files, "protos/team/service", 0, SymlinkPolicy{})
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("prepareUpserts() error = %v, wantErr %v", err, tt.wantErr)
@@ -1828,6 +2334,42 @@ func TestCache_prepareUpserts(t *testing.T) {
 	}
 }
 
+func TestCache_prepareUpserts_HashesMatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	localPath := filepath.Join(dir, "local.proto")
+	if err := os.WriteFile(localPath, []byte("local"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &mockRepository{
+		writeObjFunc: func(content []byte) git.Hash { return git.Hash("hash-of-" + string(content)) },
+	}
+	cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+	upserts, err := cache.prepareUpserts(testContext(), &Project{Commit: "abc123", RepositoryURL: "https://github.com/test/repo"}, []LocalProjectFile{
+		{Path: "a.proto", Content: []byte("first")},
+		{Path: "b.proto", LocalPath: localPath},
+		{Path: "c.proto", Content: []byte("third")},
+	}, "protos/team/service", 0, SymlinkPolicy{})
+	if err != nil {
+		t.Fatalf("prepareUpserts() error = %v", err)
+	}
+
+	got := make(map[string]git.Hash, len(upserts))
+	for _, u := range upserts {
+		got[u.Path] = u.Blob
+	}
+	want := map[string]git.Hash{
+		"protos/team/service/" + constants.ProjectMetaFile: "hash-of-git:\n  commit: abc123\n  url: https://github.com/test/repo\n",
+		"protos/team/service/a.proto":                      "hash-of-first",
+		"protos/team/service/b.proto":                      "hash-of-local",
+		"protos/team/service/c.proto":                      "hash-of-third",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("prepareUpserts() blobs = %v, want %v", got, want)
+	}
+}
+
 func TestCache_SetProject(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -1900,3 +2442,1918 @@ func TestCache_SetProject(t *testing.T) {
 		})
 	}
 }
+
+func TestProjectsCommitMessage(t *testing.T) {
+	request := func(path string, files int) *SetProjectRequest {
+		return &SetProjectRequest{Project: &Project{Path: ProjectPath(path)}, Files: make([]LocalProjectFile, files)}
+	}
+
+	tests := []struct {
+		name      string
+		reqs      []*SetProjectRequest
+		approvals []string
+		want      string
+	}{
+		{
+			name:      "single project",
+			reqs:      []*SetProjectRequest{request("team/a", 2)},
+			approvals: []string{""},
+			want:      "team/a: 2 files",
+		},
+		{
+			name:      "several projects",
+			reqs:      []*SetProjectRequest{request("team/a", 2), request("team/b", 1)},
+			approvals: []string{"", ""},
+			want:      "Update 2 projects\n\nteam/a: 2 files\nteam/b: 1 files",
+		},
+		{
+			name:      "approvals are listed once",
+			reqs:      []*SetProjectRequest{request("team/a", 1), request("team/b", 1), request("team/c", 1)},
+			approvals: []string{"alice", "", "alice"},
+			want:      "Update 3 projects\n\nteam/a: 1 files\nteam/b: 1 files\nteam/c: 1 files\n\nApproved-by: alice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := projectsCommitMessage(tt.reqs, tt.approvals)
+			if got != tt.want {
+				t.Errorf("projectsCommitMessage() = %q, want %q", got, tt.want)
+			}
+			// --amend recognises its own pushes by these messages
+			for _, req := range tt.reqs {
+				if len(updatedProjects(got, []ProjectPath{req.Project.Path})) != 1 {
+					t.Errorf("updatedProjects(%q, %s) = none, want the project", got, req.Project.Path)
+				}
+			}
+			if updated := updatedProjects(got, []ProjectPath{"team/other"}); len(updated) != 0 {
+				t.Errorf("updatedProjects(%q, team/other) = %v, want none", got, updated)
+			}
+		})
+	}
+}
+
+func TestCache_PruneOrphans(t *testing.T) {
+	ctx := testContext()
+
+	// Two projects: alive points at a reachable repo, dead at an unreachable one
+	mock := &mockRepository{
+		readTreeFunc: func(opts git.ReadTreeOptions) ([]git.TreeEntry, error) {
+			switch opts.Paths[0] {
+			case "protos":
+				return []git.TreeEntry{
+					{Type: git.BlobType, Path: "protos/team/alive/protato.root.yaml", Hash: "meta-alive"},
+					{Type: git.BlobType, Path: "protos/team/alive/v1/api.proto", Hash: "proto-alive"},
+					{Type: git.BlobType, Path: "protos/team/dead/protato.root.yaml", Hash: "meta-dead"},
+				}, nil
+			case "protos/team/alive/protato.root.yaml":
+				return []git.TreeEntry{{Type: git.BlobType, Hash: "meta-alive"}}, nil
+			case "protos/team/dead/protato.root.yaml":
+				return []git.TreeEntry{{Type: git.BlobType, Hash: "meta-dead"}}, nil
+			}
+			return nil, nil
+		},
+		readObjFunc: func(hash git.Hash) []byte {
+			if hash == "meta-dead" {
+				return []byte("git:\n  commit: abc\n  url: https://example.com/gone.git\n")
+			}
+			return []byte("git:\n  commit: abc\n  url: https://example.com/alive.git\n")
+		},
+	}
+	cache := &Cache{repo: mock}
+
+	checkURL := func(url string) bool {
+		// URL checks go to the network, so they must not hold up other cache users
+		if !cache.mu.TryLock() {
+			t.Errorf("checkURL(%s) called with the cache mutex held", url)
+		} else {
+			cache.mu.Unlock()
+		}
+		return url != "https://example.com/gone.git"
+	}
+	got, err := cache.PruneOrphans(ctx, git.Hash("snap123"), checkURL)
+	if err != nil {
+		t.Fatalf("PruneOrphans() error = %v", err)
+	}
+	if len(got) != 1 || got[0] != "team/dead" {
+		t.Errorf("PruneOrphans() = %v, want [team/dead]", got)
+	}
+}
+
+func TestCache_DeleteProjects(t *testing.T) {
+	ctx := testContext()
+	author := &git.Author{Name: "Test", Email: "test@example.com"}
+
+	mock := &mockRepository{
+		revHashMap: map[string]git.Hash{"snap123^{tree}": "tree123"},
+		readTreeResp: []git.TreeEntry{
+			{Type: git.BlobType, Path: "protos/team/dead/protato.root.yaml"},
+			{Type: git.BlobType, Path: "protos/team/dead/v1/api.proto"},
+		},
+		updateTreeHash: "tree456",
+		commitTreeHash: "commit456",
+	}
+	cache := &Cache{repo: mock}
+
+	got, err := cache.DeleteProjects(ctx, &DeleteProjectsRequest{
+		Projects: []ProjectPath{"team/dead"},
+		Snapshot: "snap123",
+		Author:   author,
+	})
+	if err != nil {
+		t.Fatalf("DeleteProjects() error = %v", err)
+	}
+	if got != "commit456" {
+		t.Errorf("DeleteProjects() = %v, want commit456", got)
+	}
+	if len(mock.updateTreeReq.Deletes) != 2 {
+		t.Errorf("UpdateTree deletes = %v, want 2 paths", mock.updateTreeReq.Deletes)
+	}
+	if len(mock.commitTreeReq.Parents) != 1 || mock.commitTreeReq.Parents[0] != "snap123" {
+		t.Errorf("CommitTree parents = %v, want [snap123]", mock.commitTreeReq.Parents)
+	}
+
+	if _, err := cache.DeleteProjects(ctx, &DeleteProjectsRequest{Snapshot: "snap123"}); err == nil {
+		t.Error("DeleteProjects() expected error without author")
+	}
+}
+
+func TestCache_DeleteProjects_Subprojects(t *testing.T) {
+	ctx := testContext()
+	author := &git.Author{Name: "Test", Email: "test@example.com"}
+
+	// team/service has team/service/admin nested under it
+	tree := []git.TreeEntry{
+		{Type: git.BlobType, Path: "protos/team/service/protato.root.yaml"},
+		{Type: git.BlobType, Path: "protos/team/service/v1/api.proto"},
+		{Type: git.BlobType, Path: "protos/team/service/admin/protato.root.yaml"},
+		{Type: git.BlobType, Path: "protos/team/service/admin/v1/admin.proto"},
+	}
+	newCache := func() (*Cache, *mockRepository) {
+		mock := &mockRepository{
+			revHashMap: map[string]git.Hash{"snap123^{tree}": "tree123"},
+			readTreeFunc: func(opts git.ReadTreeOptions) ([]git.TreeEntry, error) {
+				var entries []git.TreeEntry
+				for _, e := range tree {
+					if strings.HasPrefix(e.Path, opts.Paths[0]+"/") {
+						entries = append(entries, e)
+					}
+				}
+				return entries, nil
+			},
+			updateTreeHash: "tree456",
+			commitTreeHash: "commit456",
+		}
+		return &Cache{repo: mock}, mock
+	}
+
+	cache, _ := newCache()
+	_, err := cache.DeleteProjects(ctx, &DeleteProjectsRequest{
+		Projects: []ProjectPath{"team/service"},
+		Snapshot: "snap123",
+		Author:   author,
+	})
+	if !errors.Is(err, protatoerrors.ErrSubprojectConflict) {
+		t.Fatalf("DeleteProjects() of a parent alone error = %v, want %v", err, protatoerrors.ErrSubprojectConflict)
+	}
+
+	cache, mock := newCache()
+	if _, err := cache.DeleteProjects(ctx, &DeleteProjectsRequest{
+		Projects: []ProjectPath{"team/service/admin", "team/service"},
+		Snapshot: "snap123",
+		Author:   author,
+	}); err != nil {
+		t.Fatalf("DeleteProjects() of a parent and its subproject error = %v", err)
+	}
+	if len(mock.updateTreeReq.Deletes) != len(tree) {
+		t.Errorf("UpdateTree deletes = %v, want each of the %d files once", mock.updateTreeReq.Deletes, len(tree))
+	}
+}
+
+func TestCache_DeleteProject(t *testing.T) {
+	ownerURL := "https://github.com/test/repo.git"
+	projectMeta := func(p string) string { return constants.ProtosDir + "/" + p + "/" + constants.ProjectMetaFile }
+
+	tests := []struct {
+		name        string
+		project     ProjectPath
+		repoURL     string
+		nested      bool // team/service/admin is also a project
+		wantErr     error
+		wantDeletes []string
+	}{
+		{
+			name:    "owner deletes project",
+			project: "team/service",
+			repoURL: ownerURL,
+			wantDeletes: []string{
+				projectMeta("team/service"),
+				constants.ProtosDir + "/team/service/v1/api.proto",
+			},
+		},
+		{
+			name:    "ownership mismatch",
+			project: "team/service",
+			repoURL: "https://github.com/other/repo.git",
+			wantErr: protatoerrors.ErrOwnershipConflict,
+		},
+		{
+			name:    "subproject conflict",
+			project: "team/service",
+			repoURL: ownerURL,
+			nested:  true,
+			wantErr: protatoerrors.ErrSubprojectConflict,
+		},
+		{
+			name:    "path inside a project",
+			project: "team/service/v1",
+			repoURL: ownerURL,
+			wantErr: protatoerrors.ErrNotFound,
+		},
+		{
+			name:    "unknown project",
+			project: "team/missing",
+			repoURL: ownerURL,
+			wantErr: protatoerrors.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := []git.TreeEntry{
+				{Path: projectMeta("team/service"), Type: git.BlobType, Hash: "meta1"},
+				{Path: constants.ProtosDir + "/team/service/v1/api.proto", Type: git.BlobType, Hash: "blob1"},
+			}
+			if tt.nested {
+				files = append(files, git.TreeEntry{Path: projectMeta("team/service/admin"), Type: git.BlobType, Hash: "meta2"})
+			}
+			repo := &mockRepository{
+				revHashMap: map[string]git.Hash{"snap123^{tree}": "tree123"},
+				revExists:  map[string]bool{"snap123": true},
+				readTreeFunc: func(opts git.ReadTreeOptions) ([]git.TreeEntry, error) {
+					var entries []git.TreeEntry
+					for _, f := range files {
+						if f.Path == opts.Paths[0] || (opts.Recurse && strings.HasPrefix(f.Path, opts.Paths[0]+"/")) {
+							entries = append(entries, f)
+						}
+					}
+					return entries, nil
+				},
+				readObjData:    []byte("git:\n  url: " + ownerURL + "\n"),
+				updateTreeHash: "tree456",
+				commitTreeHash: "commit456",
+			}
+			cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+			res, err := cache.DeleteProject(testContext(), &DeleteProjectRequest{
+				Project:       tt.project,
+				RepositoryURL: tt.repoURL,
+				Snapshot:      "snap123",
+				Author:        &git.Author{Name: "Test", Email: "test@example.com"},
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("DeleteProject() error = %v, want %v", err, tt.wantErr)
+				}
+				if repo.commitTreeReq.Tree != "" {
+					t.Error("DeleteProject() created a commit despite the error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DeleteProject() error = %v", err)
+			}
+			if res.Snapshot != "commit456" || res.FilesDeleted != len(tt.wantDeletes) {
+				t.Errorf("DeleteProject() = %+v, want snapshot commit456 with %d files deleted", res, len(tt.wantDeletes))
+			}
+			if !reflect.DeepEqual(repo.updateTreeReq.Deletes, tt.wantDeletes) {
+				t.Errorf("UpdateTree deletes = %v, want %v", repo.updateTreeReq.Deletes, tt.wantDeletes)
+			}
+			if len(repo.commitTreeReq.Parents) != 1 || repo.commitTreeReq.Parents[0] != "snap123" {
+				t.Errorf("CommitTree parents = %v, want [snap123]", repo.commitTreeReq.Parents)
+			}
+		})
+	}
+}
+
+func TestCache_RenameProject(t *testing.T) {
+	ownerURL := "https://github.com/test/repo.git"
+	otherURL := "https://github.com/other/repo.git"
+	projectMeta := func(p string) string { return constants.ProtosDir + "/" + p + "/" + constants.ProjectMetaFile }
+
+	tests := []struct {
+		name        string
+		from, to    ProjectPath
+		repoURL     string
+		extra       []string // Other projects in the registry; team/other-owner is owned by otherURL
+		wantErr     error
+		wantUpserts []git.TreeUpsert
+		wantDeletes []string
+	}{
+		{
+			name:    "owner renames project",
+			from:    "team/service",
+			to:      "team/renamed",
+			repoURL: ownerURL,
+			wantUpserts: []git.TreeUpsert{
+				{Path: projectMeta("team/renamed"), Blob: "meta1", Mode: 0100644},
+				{Path: constants.ProtosDir + "/team/renamed/v1/api.proto", Blob: "blob1", Mode: 0100755},
+			},
+			wantDeletes: []string{
+				projectMeta("team/service"),
+				constants.ProtosDir + "/team/service/v1/api.proto",
+			},
+		},
+		{
+			name:    "source not owned",
+			from:    "team/service",
+			to:      "team/renamed",
+			repoURL: otherURL,
+			wantErr: protatoerrors.ErrOwnershipConflict,
+		},
+		{
+			name:    "source is not a project",
+			from:    "team/missing",
+			to:      "team/renamed",
+			repoURL: ownerURL,
+			wantErr: protatoerrors.ErrNotFound,
+		},
+		{
+			name:    "destination is own project",
+			from:    "team/service",
+			to:      "team/taken",
+			repoURL: ownerURL,
+			extra:   []string{"team/taken"},
+			wantErr: protatoerrors.ErrProjectExists,
+		},
+		{
+			name:    "destination owned by another repository",
+			from:    "team/service",
+			to:      "team/other-owner",
+			repoURL: ownerURL,
+			extra:   []string{"team/other-owner"},
+			wantErr: protatoerrors.ErrOwnershipConflict,
+		},
+		{
+			name:    "destination inside another project",
+			from:    "team/service",
+			to:      "team/other-owner/service",
+			repoURL: ownerURL,
+			extra:   []string{"team/other-owner"},
+			wantErr: protatoerrors.ErrParentProjectExists,
+		},
+		{
+			name:    "destination above other projects",
+			from:    "team/service",
+			to:      "org",
+			repoURL: ownerURL,
+			extra:   []string{"org/a"},
+			wantErr: protatoerrors.ErrSubprojectConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := []git.TreeEntry{
+				{Path: projectMeta("team/service"), Type: git.BlobType, Hash: "meta1", Mode: 0100644},
+				{Path: constants.ProtosDir + "/team/service/v1/api.proto", Type: git.BlobType, Hash: "blob1", Mode: 0100755},
+			}
+			for _, p := range tt.extra {
+				files = append(files, git.TreeEntry{Path: projectMeta(p), Type: git.BlobType, Hash: git.Hash("meta-" + p), Mode: 0100644})
+			}
+			repo := &mockRepository{
+				revHashMap: map[string]git.Hash{"snap123^{tree}": "tree123"},
+				revExists:  map[string]bool{"snap123": true},
+				readTreeFunc: func(opts git.ReadTreeOptions) ([]git.TreeEntry, error) {
+					var entries []git.TreeEntry
+					for _, f := range files {
+						if f.Path == opts.Paths[0] || ((opts.Recurse || opts.Paths[0] == constants.ProtosDir) && strings.HasPrefix(f.Path, opts.Paths[0]+"/")) {
+							entries = append(entries, f)
+						}
+					}
+					return entries, nil
+				},
+				readObjFunc: func(hash git.Hash) []byte {
+					if hash == "meta-team/other-owner" {
+						return []byte("git:\n  url: " + otherURL + "\n")
+					}
+					return []byte("git:\n  url: " + ownerURL + "\n")
+				},
+				updateTreeHash: "tree456",
+				commitTreeHash: "commit456",
+			}
+			cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+			res, err := cache.RenameProject(testContext(), &RenameProjectRequest{
+				From:          tt.from,
+				To:            tt.to,
+				RepositoryURL: tt.repoURL,
+				Snapshot:      "snap123",
+				Author:        &git.Author{Name: "Test", Email: "test@example.com"},
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("RenameProject() error = %v, want %v", err, tt.wantErr)
+				}
+				if repo.commitTreeReq.Tree != "" {
+					t.Error("RenameProject() created a commit despite the error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenameProject() error = %v", err)
+			}
+			if res.Snapshot != "commit456" || res.FilesMoved != len(tt.wantUpserts) {
+				t.Errorf("RenameProject() = %+v, want snapshot commit456 with %d files moved", res, len(tt.wantUpserts))
+			}
+			if !reflect.DeepEqual(repo.updateTreeReq.Upserts, tt.wantUpserts) {
+				t.Errorf("UpdateTree upserts = %v, want %v", repo.updateTreeReq.Upserts, tt.wantUpserts)
+			}
+			if !reflect.DeepEqual(repo.updateTreeReq.Deletes, tt.wantDeletes) {
+				t.Errorf("UpdateTree deletes = %v, want %v", repo.updateTreeReq.Deletes, tt.wantDeletes)
+			}
+			if want := "team/renamed: rename from team/service"; repo.commitTreeReq.Message != want {
+				t.Errorf("CommitTree message = %q, want %q", repo.commitTreeReq.Message, want)
+			}
+		})
+	}
+
+	t.Run("overlapping paths", func(t *testing.T) {
+		cache := newMockCache(&mockRepository{}, "https://github.com/test/registry.git")
+		for _, to := range []ProjectPath{"team/service", "team/service/v2", "team"} {
+			if _, err := cache.RenameProject(testContext(), &RenameProjectRequest{
+				From:   "team/service",
+				To:     to,
+				Author: &git.Author{Name: "Test", Email: "test@example.com"},
+			}); err == nil {
+				t.Errorf("RenameProject() to %s expected error", to)
+			}
+		}
+	})
+}
+
+func TestCache_CheckProjectClaim_CaseConflict(t *testing.T) {
+	existingMeta := constants.ProtosDir + "/team/service/" + constants.ProjectMetaFile
+	repo := &mockRepository{
+		revExists: map[string]bool{"snapshot123": true},
+		readTreeFunc: func(opts git.ReadTreeOptions) ([]git.TreeEntry, error) {
+			// Only a full listing sees the existing lower-case project
+			if opts.Recurse && opts.Paths[0] == constants.ProtosDir {
+				return []git.TreeEntry{{Path: existingMeta, Type: git.BlobType}}, nil
+			}
+			return nil, nil
+		},
+	}
+	cache := newMockCache(repo, "https://github.com/test/registry.git")
+	ctx := testContext()
+
+	err := cache.CheckProjectClaim(ctx, "snapshot123", "https://github.com/test/repo.git", "Team/Service")
+	if err == nil {
+		t.Fatal("CheckProjectClaim() expected case conflict error")
+	}
+	if !strings.Contains(err.Error(), "team/service") {
+		t.Errorf("CheckProjectClaim() error = %v, want mention of team/service", err)
+	}
+
+	if err := cache.CheckProjectClaim(ctx, "snapshot123", "https://github.com/test/repo.git", "team/other"); err != nil {
+		t.Errorf("CheckProjectClaim() unexpected error = %v", err)
+	}
+}
+
+func TestCheckFileSizes(t *testing.T) {
+	tmpDir := t.TempDir()
+	onDisk := filepath.Join(tmpDir, "big.proto")
+	if err := os.WriteFile(onDisk, bytes.Repeat([]byte("a"), 2048), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		files       []LocalProjectFile
+		maxFileSize int64
+		wantErr     bool
+		wantInErr   []string
+	}{
+		{
+			name:        "within limit",
+			files:       []LocalProjectFile{{Path: "v1/api.proto", Content: make([]byte, 1024)}},
+			maxFileSize: 1024,
+		},
+		{
+			name:        "proto over limit",
+			files:       []LocalProjectFile{{Path: "v1/api.proto", Content: make([]byte, 1025)}},
+			maxFileSize: 1024,
+			wantErr:     true,
+			wantInErr:   []string{"v1/api.proto"},
+		},
+		{
+			name:        "non-proto has stricter limit",
+			files:       []LocalProjectFile{{Path: "v1/data.bin", Content: make([]byte, 200)}},
+			maxFileSize: 1024,
+			wantErr:     true,
+			wantInErr:   []string{"v1/data.bin"},
+		},
+		{
+			name:        "size read from disk",
+			files:       []LocalProjectFile{{Path: "big.proto", LocalPath: onDisk}},
+			maxFileSize: 1024,
+			wantErr:     true,
+			wantInErr:   []string{"big.proto"},
+		},
+		{
+			name: "all offending files listed",
+			files: []LocalProjectFile{
+				{Path: "a.proto", Content: make([]byte, 2000)},
+				{Path: "ok.proto", Content: make([]byte, 10)},
+				{Path: "b.proto", Content: make([]byte, 2000)},
+			},
+			maxFileSize: 1024,
+			wantErr:     true,
+			wantInErr:   []string{"a.proto", "b.proto"},
+		},
+		{
+			name:        "limit disabled",
+			files:       []LocalProjectFile{{Path: "v1/data.bin", Content: make([]byte, 1 << 21)}},
+			maxFileSize: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkFileSizes(tt.files, tt.maxFileSize)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkFileSizes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.wantInErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("checkFileSizes() error = %v, want mention of %s", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCache_PlanProjects(t *testing.T) {
+	const repoURL = "https://github.com/test/repo.git"
+	repo := &mockRepository{
+		revHashMap: map[string]git.Hash{"FETCH_HEAD": "snapshot123", "snapshot123": "snapshot123", "snapshot123^{tree}": "treehash"},
+		readTreeFunc: func(opts git.ReadTreeOptions) ([]git.TreeEntry, error) {
+			switch opts.Paths[0] {
+			case "protos/team/service":
+				return []git.TreeEntry{
+					{Type: git.BlobType, Path: "protos/team/service/protato.root.yaml", Hash: "meta"},
+					{Type: git.BlobType, Path: "protos/team/service/v1/api.proto", Hash: "h-old"},
+					{Type: git.BlobType, Path: "protos/team/service/v1/same.proto", Hash: "h-same"},
+					{Type: git.BlobType, Path: "protos/team/service/v1/gone.proto", Hash: "h-gone"},
+				}, nil
+			case "protos/team/service/protato.root.yaml":
+				return []git.TreeEntry{{Type: git.BlobType, Hash: "meta"}}, nil
+			}
+			return nil, nil
+		},
+		readObjData:  []byte("git:\n  commit: abc\n  url: " + repoURL + "\n"),
+		writeObjFunc: func(content []byte) git.Hash { return git.Hash("h-" + string(content)) },
+	}
+	cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+	got, err := cache.PlanProjects(testContext(), []*SetProjectRequest{{
+		Project: &Project{Path: "team/service", Commit: "abc123", RepositoryURL: repoURL},
+		Files: []LocalProjectFile{
+			{Path: "v1/api.proto", Content: []byte("changed")},
+			{Path: "v1/same.proto", Content: []byte("same")},
+			{Path: "v1/new.proto", Content: []byte("new")},
+		},
+		Author: &git.Author{Name: "Test User", Email: "test@example.com"},
+	}})
+	if err != nil {
+		t.Fatalf("PlanProjects() error = %v", err)
+	}
+
+	want := []FileChange{
+		{Project: "team/service", Path: "v1/api.proto", Status: git.DiffModified},
+		{Project: "team/service", Path: "v1/gone.proto", Status: git.DiffDeleted},
+		{Project: "team/service", Path: "v1/new.proto", Status: git.DiffAdded},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PlanProjects() = %+v, want %+v", got, want)
+	}
+	if repo.updateTreeReq.Tree != "" || repo.commitTreeReq.Tree != "" {
+		t.Errorf("PlanProjects() built a commit: UpdateTree %+v, CommitTree %+v", repo.updateTreeReq, repo.commitTreeReq)
+	}
+	if len(repo.pushCalls) != 0 {
+		t.Errorf("PlanProjects() pushed: %+v", repo.pushCalls)
+	}
+}
+
+func TestCache_SetProject_MaxFileSize(t *testing.T) {
+	repo := &mockRepository{
+		revHashMap:     map[string]git.Hash{"snap123^{tree}": "tree123"},
+		writeObjHash:   "blob123",
+		updateTreeHash: "tree456",
+		commitTreeHash: "commit456",
+	}
+	cache := &Cache{repo: repo}
+	ctx := testContext()
+
+	req := &SetProjectRequest{
+		Project:     &Project{Path: "team/service"},
+		Files:       []LocalProjectFile{{Path: "v1/api.proto", Content: make([]byte, 4096)}},
+		Snapshot:    "snap123",
+		Author:      &git.Author{Name: "Test", Email: "test@example.com"},
+		MaxFileSize: 1024,
+	}
+	if _, err := cache.SetProject(ctx, req); err == nil {
+		t.Error("SetProject() expected size limit error")
+	}
+
+	// A zero limit (e.g. push --allow-large) lets the file through
+	req.MaxFileSize = 0
+	if _, err := cache.SetProject(ctx, req); err != nil {
+		t.Errorf("SetProject() error = %v", err)
+	}
+}
+
+func TestRemoveCache(t *testing.T) {
+	ctx := testContext()
+	const url = "https://github.com/test/registry.git"
+
+	t.Run("removes cache", func(t *testing.T) {
+		cacheDir := t.TempDir()
+		root := cacheRootPath(cacheDir, url)
+		if err := os.MkdirAll(filepath.Join(root, "objects"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		other := cacheRootPath(cacheDir, "https://github.com/test/other.git")
+		if err := os.MkdirAll(other, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := RemoveCache(ctx, cacheDir, url); err != nil {
+			t.Fatalf("RemoveCache() error = %v", err)
+		}
+		if _, err := os.Stat(root); !os.IsNotExist(err) {
+			t.Errorf("cache %s still exists", root)
+		}
+		if _, err := os.Stat(other); err != nil {
+			t.Errorf("cache of another registry was removed: %v", err)
+		}
+	})
+
+	t.Run("missing cache", func(t *testing.T) {
+		if err := RemoveCache(ctx, t.TempDir(), url); err != nil {
+			t.Errorf("RemoveCache() error = %v", err)
+		}
+	})
+
+	t.Run("locked cache", func(t *testing.T) {
+		cacheDir := t.TempDir()
+		root := cacheRootPath(cacheDir, url)
+		if err := os.MkdirAll(root, 0755); err != nil {
+			t.Fatal(err)
+		}
+		lock, err := lockCacheRoot(root, syscall.LOCK_SH)
+		if err != nil {
+			t.Fatalf("lockCacheRoot() error = %v", err)
+		}
+		defer lock.Close()
+
+		if err := RemoveCache(ctx, cacheDir, url); err == nil {
+			t.Error("RemoveCache() error = nil, want locked error")
+		}
+		if _, err := os.Stat(root); err != nil {
+			t.Errorf("locked cache was removed: %v", err)
+		}
+	})
+}
+
+// newSourceRegistry creates a local git repository with one commit to clone from.
+func newSourceRegistry(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func TestOpen_CorruptCache(t *testing.T) {
+	ctx := testContext()
+	url := newSourceRegistry(t)
+
+	tests := []struct {
+		name          string
+		verifyObjects bool
+		corrupt       bool
+		wantChecks    int
+		wantRecloned  bool
+	}{
+		{
+			name:       "healthy cache is reused",
+			wantChecks: 1,
+		},
+		{
+			name:          "objects verified on request",
+			verifyObjects: true,
+			wantChecks:    1,
+		},
+		{
+			name:         "corrupt cache is re-cloned once",
+			corrupt:      true,
+			wantChecks:   1,
+			wantRecloned: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheDir := t.TempDir()
+			cache, err := Open(ctx, cacheDir, url, OpenOptions{})
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			cache.Close()
+
+			// A marker file survives only if the cache is not re-cloned
+			marker := filepath.Join(cacheRootPath(cacheDir, url), "marker")
+			if err := os.WriteFile(marker, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			checks := 0
+			orig := checkCacheIntegrity
+			checkCacheIntegrity = func(ctx context.Context, repo *git.Repository, verifyObjects bool) error {
+				checks++
+				if verifyObjects != tt.verifyObjects {
+					t.Errorf("integrity check verifyObjects = %v, want %v", verifyObjects, tt.verifyObjects)
+				}
+				if tt.corrupt {
+					return errors.New("fatal: bad object HEAD")
+				}
+				return nil
+			}
+			defer func() { checkCacheIntegrity = orig }()
+
+			cache, err = Open(ctx, cacheDir, url, OpenOptions{VerifyObjects: tt.verifyObjects})
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer cache.Close()
+
+			if checks != tt.wantChecks {
+				t.Errorf("integrity checks = %d, want %d", checks, tt.wantChecks)
+			}
+			_, statErr := os.Stat(marker)
+			if recloned := os.IsNotExist(statErr); recloned != tt.wantRecloned {
+				t.Errorf("re-cloned = %v, want %v", recloned, tt.wantRecloned)
+			}
+			if _, err := cache.Snapshot(ctx); err != nil {
+				t.Errorf("Snapshot() after Open() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestOpen_CorruptCacheRecloneFails(t *testing.T) {
+	ctx := testContext()
+	cacheDir := t.TempDir()
+	url := filepath.Join(t.TempDir(), "missing.git")
+
+	// An interrupted clone leaves a directory git cannot use
+	root := cacheRootPath(cacheDir, url)
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(ctx, cacheDir, url, OpenOptions{}); err == nil {
+		t.Fatal("Open() error = nil, want clone error")
+	}
+	if _, err := os.Stat(root); !os.IsNotExist(err) {
+		t.Errorf("corrupt cache %s was not removed", root)
+	}
+}
+
+func TestCache_EnsureSnapshotAvailable(t *testing.T) {
+	const snapshot = git.Hash("0123456789abcdef0123456789abcdef01234567")
+
+	tests := []struct {
+		name           string
+		present        bool
+		complete       bool   // The cache is not shallow
+		optIn          bool   // The registry config sets deepen.unshallow
+		fetchSucceeds  []bool // per fetch call: whether it makes the commit available
+		fetchErrs      []error
+		wantFetchCalls int
+		wantDeepens    int // Fetches after the one by hash that deepen the default branch
+		wantUnshallow  bool
+		wantErr        error
+	}{
+		{
+			name:           "already present",
+			present:        true,
+			wantFetchCalls: 0,
+		},
+		{
+			name:           "fetched by hash",
+			fetchSucceeds:  []bool{true},
+			fetchErrs:      []error{nil},
+			wantFetchCalls: 1,
+		},
+		{
+			name:           "deepened after fetch by hash fails",
+			fetchSucceeds:  []bool{false, false, true},
+			fetchErrs:      []error{errors.New("server does not allow request for unadvertised object"), nil, nil},
+			wantFetchCalls: 3,
+			wantDeepens:    2,
+		},
+		{
+			name:           "not found when deepening does not reach it",
+			fetchSucceeds:  []bool{false, false, false, false},
+			fetchErrs:      []error{errors.New("server does not allow request for unadvertised object"), nil, nil, nil},
+			wantFetchCalls: 1 + DefaultDeepenMaxSteps,
+			wantDeepens:    DefaultDeepenMaxSteps,
+			wantErr:        protatoerrors.ErrSnapshotNotFound,
+		},
+		{
+			name:           "not found when deepening fails",
+			fetchSucceeds:  []bool{false, false},
+			fetchErrs:      []error{errors.New("server does not allow request for unadvertised object"), errors.New("deepen failed")},
+			wantFetchCalls: 2,
+			wantDeepens:    1,
+			wantErr:        protatoerrors.ErrSnapshotNotFound,
+		},
+		{
+			name:           "unshallow after deepening does not reach it",
+			optIn:          true,
+			fetchSucceeds:  []bool{false, false, false, false, true},
+			fetchErrs:      []error{errors.New("server does not allow request for unadvertised object"), nil, nil, nil, nil},
+			wantFetchCalls: 5,
+			wantDeepens:    DefaultDeepenMaxSteps,
+			wantUnshallow:  true,
+		},
+		{
+			name:           "unshallow when deepening fails",
+			optIn:          true,
+			fetchSucceeds:  []bool{false, false, true},
+			fetchErrs:      []error{errors.New("server does not allow request for unadvertised object"), errors.New("deepen failed"), nil},
+			wantFetchCalls: 3,
+			wantDeepens:    1,
+			wantUnshallow:  true,
+		},
+		{
+			name:           "not found in a complete cache without deepening",
+			complete:       true,
+			optIn:          true,
+			fetchSucceeds:  []bool{false},
+			fetchErrs:      []error{errors.New("server does not allow request for unadvertised object")},
+			wantFetchCalls: 1,
+			wantErr:        protatoerrors.ErrSnapshotNotFound,
+		},
+		{
+			name:           "missing from registry",
+			fetchSucceeds:  []bool{false},
+			fetchErrs:      []error{nil},
+			wantFetchCalls: 1,
+			wantErr:        protatoerrors.ErrSnapshotNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gitDir := t.TempDir()
+			if !tt.complete {
+				if err := os.WriteFile(filepath.Join(gitDir, "shallow"), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			repo := &mockRepository{gitDir: gitDir, revExists: map[string]bool{string(snapshot): tt.present}}
+			repo.fetchFunc = func() error {
+				i := len(repo.fetchCalls) - 1
+				if tt.fetchSucceeds[i] {
+					repo.revExists[string(snapshot)] = true
+				}
+				return tt.fetchErrs[i]
+			}
+			cache := newMockCache(repo, "https://example.com/registry.git")
+			if tt.optIn {
+				repo.revHashMap = map[string]git.Hash{"HEAD": "head"}
+				cache.configs = map[git.Hash]*Config{"head": {Deepen: DeepenConfig{Unshallow: true}}}
+			}
+
+			err := cache.EnsureSnapshotAvailable(testContext(), snapshot)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("EnsureSnapshotAvailable() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Errorf("EnsureSnapshotAvailable() error = %v", err)
+			}
+
+			if len(repo.fetchCalls) != tt.wantFetchCalls {
+				t.Fatalf("fetch calls = %d, want %d", len(repo.fetchCalls), tt.wantFetchCalls)
+			}
+			if tt.wantFetchCalls == 0 {
+				return
+			}
+			first := repo.fetchCalls[0]
+			if len(first.RefSpecs) != 1 || first.RefSpecs[0] != git.Refspec(snapshot) || !first.NoWriteFetchHead {
+				t.Errorf("first fetch = %+v, want fetch of %s without FETCH_HEAD", first, snapshot)
+			}
+			for _, fetch := range repo.fetchCalls[1 : 1+tt.wantDeepens] {
+				if fetch.Deepen != DefaultDeepenStep || fetch.Depth != 0 || !fetch.NoWriteFetchHead {
+					t.Errorf("deepen fetch = %+v, want --deepen %d without FETCH_HEAD", fetch, DefaultDeepenStep)
+				}
+			}
+			if last := repo.fetchCalls[len(repo.fetchCalls)-1]; tt.wantUnshallow != last.Unshallow {
+				t.Errorf("last fetch = %+v, want unshallow %v", last, tt.wantUnshallow)
+			}
+		})
+	}
+}
+
+func TestCache_GetProjectHistory(t *testing.T) {
+	pushed := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	commits := []git.Commit{
+		{Hash: "bbb222", Author: git.Author{Name: "Bob", Email: "bob@example.com"}, Date: pushed.Add(time.Hour), Message: "Update team/service"},
+		{Hash: "aaa111", Author: git.Author{Name: "Alice", Email: "alice@example.com"}, Date: pushed, Message: "Create team/service"},
+	}
+
+	tests := []struct {
+		name          string
+		shallow       bool
+		limit         int
+		logErr        error
+		wantUnshallow bool
+		wantErr       bool
+	}{
+		{name: "full clone", limit: 10},
+		{name: "shallow clone is unshallowed", shallow: true, wantUnshallow: true},
+		{name: "log fails", logErr: errors.New("bad revision"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gitDir := t.TempDir()
+			if tt.shallow {
+				if err := os.WriteFile(filepath.Join(gitDir, "shallow"), []byte("aaa111\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			repo := &mockRepository{
+				gitDir:     gitDir,
+				revHashMap: map[string]git.Hash{"FETCH_HEAD": "snapshot123"},
+				logCommits: commits,
+				logErr:     tt.logErr,
+			}
+			cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+			got, err := cache.GetProjectHistory(testContext(), &ProjectHistoryRequest{Project: "team/service", Limit: tt.limit})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetProjectHistory() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			unshallowed := len(repo.fetchCalls) == 1 && repo.fetchCalls[0].Unshallow
+			if unshallowed != tt.wantUnshallow || len(repo.fetchCalls) > 1 {
+				t.Errorf("fetch calls = %+v, want unshallow %v", repo.fetchCalls, tt.wantUnshallow)
+			}
+			wantLog := git.LogOptions{Rev: "snapshot123", Paths: []string{"protos/team/service"}, MaxCount: tt.limit}
+			if len(repo.logCalls) != 1 || !reflect.DeepEqual(repo.logCalls[0], wantLog) {
+				t.Errorf("log calls = %+v, want %+v", repo.logCalls, wantLog)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			want := []ProjectRevision{
+				{Snapshot: "bbb222", Author: commits[0].Author, Date: commits[0].Date, Message: "Update team/service"},
+				{Snapshot: "aaa111", Author: commits[1].Author, Date: commits[1].Date, Message: "Create team/service"},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("GetProjectHistory() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestCache_GetProjectHistory_File(t *testing.T) {
+	const filePath = "protos/team/service/v1/old.proto"
+	deletion := []git.Commit{{Hash: "ccc333", Message: "Remove old.proto"}}
+
+	tests := []struct {
+		name           string
+		present        bool
+		includeDeleted bool
+		wantLogs       []git.LogOptions
+		wantErr        error
+	}{
+		{
+			name:     "file in the snapshot",
+			present:  true,
+			wantLogs: []git.LogOptions{{Rev: "snapshot123", Paths: []string{filePath}, Follow: true}},
+		},
+		{
+			name:    "deleted file",
+			wantErr: protatoerrors.ErrNotFound,
+		},
+		{
+			name:           "deleted file traced",
+			includeDeleted: true,
+			wantLogs: []git.LogOptions{
+				{Rev: "snapshot123", Paths: []string{filePath}, DiffFilter: "D", MaxCount: 1},
+				{Rev: "ccc333", Paths: []string{filePath}, Follow: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepository{
+				gitDir:     t.TempDir(),
+				revHashMap: map[string]git.Hash{"FETCH_HEAD": "snapshot123"},
+				logCommits: deletion,
+			}
+			if tt.present {
+				repo.readTreeResp = []git.TreeEntry{{Path: filePath, Type: git.BlobType}}
+			}
+			cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+			_, err := cache.GetProjectHistory(testContext(), &ProjectHistoryRequest{
+				Project:        "team/service",
+				File:           "v1/old.proto",
+				IncludeDeleted: tt.includeDeleted,
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("GetProjectHistory() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("GetProjectHistory() error = %v", err)
+			}
+			if !reflect.DeepEqual(repo.logCalls, tt.wantLogs) {
+				t.Errorf("log calls = %+v, want %+v", repo.logCalls, tt.wantLogs)
+			}
+		})
+	}
+}
+
+func TestCache_ResolveRef(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     string
+		revs    map[string]git.Hash
+		want    git.Hash
+		wantErr bool
+	}{
+		{
+			name: "tag",
+			ref:  "v1.2.0",
+			revs: map[string]git.Hash{"refs/tags/v1.2.0^{commit}": "tagged123"},
+			want: "tagged123",
+		},
+		{
+			name: "abbreviated commit",
+			ref:  "abc1234",
+			revs: map[string]git.Hash{"abc1234": "abc1234", "abc1234^{commit}": "abc1234def5678"},
+			want: "abc1234def5678",
+		},
+		{
+			name:    "unknown tag",
+			ref:     "release-next",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepository{revHashMap: tt.revs}
+			cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+			got, err := cache.ResolveRef(testContext(), tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveRef() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveRef() = %s, want %s", got, tt.want)
+			}
+			if len(repo.fetchCalls) == 0 || repo.fetchCalls[0].RefSpecs[0] != git.Refspec("refs/tags/"+tt.ref+":refs/tags/"+tt.ref) {
+				t.Errorf("ResolveRef() fetches = %+v, want the tag fetched first", repo.fetchCalls)
+			}
+		})
+	}
+}
+
+func TestCache_ClaimErrorReasons(t *testing.T) {
+	ctx := testContext()
+	repoURL := "https://github.com/test/repo.git"
+
+	t.Run("ownership conflict", func(t *testing.T) {
+		cache := newMockCache(&mockRepository{}, "https://github.com/test/registry.git")
+		res := &LookupProjectResponse{Project: &Project{Path: "team/service", RepositoryURL: "https://github.com/other/repo.git"}}
+		err := cache.validateOwnership(ctx, res, repoURL, "team/service")
+		if !errors.Is(err, protatoerrors.ErrOwnershipConflict) {
+			t.Errorf("validateOwnership() error = %v, want ErrOwnershipConflict", err)
+		}
+	})
+
+	t.Run("parent exists", func(t *testing.T) {
+		cache := newMockCache(&mockRepository{}, "https://github.com/test/registry.git")
+		res := &LookupProjectResponse{Project: &Project{Path: "team", RepositoryURL: repoURL}}
+		err := cache.validateOwnership(ctx, res, repoURL, "team/service")
+		if !errors.Is(err, protatoerrors.ErrParentProjectExists) {
+			t.Errorf("validateOwnership() error = %v, want ErrParentProjectExists", err)
+		}
+	})
+
+	t.Run("subproject conflict", func(t *testing.T) {
+		repo := &mockRepository{readTreeResp: []git.TreeEntry{
+			{Path: constants.ProtosDir + "/team/service/" + constants.ProjectMetaFile, Type: git.BlobType},
+		}}
+		cache := newMockCache(repo, "https://github.com/test/registry.git")
+		err := cache.checkSubprojectConflicts(ctx, "snapshot123", "team")
+		if !errors.Is(err, protatoerrors.ErrSubprojectConflict) {
+			t.Errorf("checkSubprojectConflicts() error = %v, want ErrSubprojectConflict", err)
+		}
+	})
+
+	t.Run("case conflict", func(t *testing.T) {
+		repo := &mockRepository{readTreeResp: []git.TreeEntry{
+			{Path: constants.ProtosDir + "/team/service/" + constants.ProjectMetaFile, Type: git.BlobType},
+		}}
+		cache := newMockCache(repo, "https://github.com/test/registry.git")
+		err := cache.checkCaseConflicts(ctx, "snapshot123", "Team/Service")
+		if !errors.Is(err, protatoerrors.ErrCaseConflict) {
+			t.Errorf("checkCaseConflicts() error = %v, want ErrCaseConflict", err)
+		}
+		if !strings.HasPrefix(err.Error(), constants.ErrMsgProjectClaim) {
+			t.Errorf("checkCaseConflicts() error = %v, want %q prefix", err, constants.ErrMsgProjectClaim)
+		}
+	})
+}
+
+func TestCache_AmendBase(t *testing.T) {
+	const (
+		tip    = git.Hash("1111111111111111111111111111111111111111")
+		parent = git.Hash("2222222222222222222222222222222222222222")
+		other  = git.Hash("3333333333333333333333333333333333333333")
+	)
+	commitText := func(author string, parents []git.Hash, message string) []byte {
+		var b strings.Builder
+		b.WriteString("tree 4444444444444444444444444444444444444444\n")
+		for _, p := range parents {
+			b.WriteString("parent " + string(p) + "\n")
+		}
+		b.WriteString("author " + author + " 1700000000 +0000\n")
+		b.WriteString("committer " + author + " 1700000000 +0000\n\n")
+		b.WriteString(message + "\n")
+		return []byte(b.String())
+	}
+	me := git.Author{Name: "Test User", Email: "test@example.com"}
+	const myRepo = "https://github.com/org/service.git"
+	ownMeta := []byte("git:\n  commit: abc123\n  url: " + myRepo + "\n")
+
+	tests := []struct {
+		name          string
+		commit        []byte
+		meta          []byte
+		parentPresent bool
+		want          git.Hash
+		wantErr       error
+		wantAmendErr  bool
+	}{
+		{
+			name:          "own commit for pushed project",
+			commit:        commitText("Test User <TEST@example.com>", []git.Hash{parent}, "team/service: 2 files"),
+			meta:          ownMeta,
+			parentPresent: true,
+			want:          parent,
+		},
+		{
+			name:         "same author pushing from another repository",
+			commit:       commitText("Test User <test@example.com>", []git.Hash{parent}, "team/service: 2 files"),
+			meta:         []byte("git:\n  commit: abc123\n  url: https://github.com/org/other.git\n"),
+			wantAmendErr: true,
+		},
+		{
+			name:         "project removed by the commit",
+			commit:       commitText("Test User <test@example.com>", []git.Hash{parent}, "team/service: 2 files"),
+			wantAmendErr: true,
+		},
+		{
+			name:         "authored by someone else",
+			commit:       commitText("Other <other@example.com>", []git.Hash{parent}, "team/service: 2 files"),
+			wantAmendErr: true,
+		},
+		{
+			name:         "updates an unrelated project",
+			commit:       commitText("Test User <test@example.com>", []git.Hash{parent}, "team/other: 2 files"),
+			wantAmendErr: true,
+		},
+		{
+			name:         "merge commit",
+			commit:       commitText("Test User <test@example.com>", []git.Hash{parent, other}, "team/service: 2 files"),
+			wantAmendErr: true,
+		},
+		{
+			name:    "parent missing from registry",
+			commit:  commitText("Test User <test@example.com>", []git.Hash{parent}, "team/service: 2 files"),
+			meta:    ownMeta,
+			wantErr: protatoerrors.ErrSnapshotNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metaPath := protosPath("team/service", constants.ProjectMetaFile)
+			repo := &mockRepository{
+				revExists: map[string]bool{string(parent): tt.parentPresent},
+				readTreeFunc: func(opts git.ReadTreeOptions) ([]git.TreeEntry, error) {
+					if tt.meta == nil || len(opts.Paths) != 1 || opts.Paths[0] != metaPath {
+						return nil, nil
+					}
+					return []git.TreeEntry{{Path: metaPath, Hash: git.Hash(metaPath)}}, nil
+				},
+				readObjFunc: func(hash git.Hash) []byte {
+					if hash == git.Hash(metaPath) {
+						return tt.meta
+					}
+					return tt.commit
+				},
+			}
+			cache := newMockCache(repo, "https://example.com/registry.git")
+
+			got, err := cache.AmendBase(testContext(), tip, me, myRepo, []ProjectPath{"team/service"})
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("AmendBase() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantAmendErr:
+				if err == nil || !strings.Contains(err.Error(), constants.ErrMsgAmend) {
+					t.Fatalf("AmendBase() error = %v, want %q", err, constants.ErrMsgAmend)
+				}
+			case err != nil:
+				t.Fatalf("AmendBase() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("AmendBase() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmendPushOptions(t *testing.T) {
+	got := amendPushOptions("main", "abc123", "def456")
+	want := git.PushOptions{
+		Remote:         "origin",
+		RefSpecs:       []git.Refspec{"abc123:refs/heads/main"},
+		ForceWithLease: []git.Lease{{Ref: "refs/heads/main", Expect: "def456"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("amendPushOptions() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCache_ListProjects_Owner(t *testing.T) {
+	metas := map[git.Hash]string{
+		"meta-a": "git:\n  commit: abc\n  url: https://github.com/org/payments\n",
+		"meta-b": "git:\n  commit: abc\n  url: https://github.com/org/orders\n",
+		"meta-c": "git:\n  commit: abc\n  url: https://github.com/org/payments.git\n",
+	}
+	entries := []git.TreeEntry{
+		{Path: constants.ProtosDir + "/payments/api/" + constants.ProjectMetaFile, Type: git.BlobType, Hash: "meta-a"},
+		{Path: constants.ProtosDir + "/orders/api/" + constants.ProjectMetaFile, Type: git.BlobType, Hash: "meta-b"},
+		{Path: constants.ProtosDir + "/payments/events/" + constants.ProjectMetaFile, Type: git.BlobType, Hash: "meta-c"},
+	}
+
+	tests := []struct {
+		name  string
+		owner string
+		want  []string
+	}{
+		{
+			name: "no filter",
+			want: []string{"orders/api", "payments/api", "payments/events"},
+		},
+		{
+			name:  "https owner",
+			owner: "https://github.com/org/payments",
+			want:  []string{"payments/api", "payments/events"},
+		},
+		{
+			name:  "ssh owner is normalized",
+			owner: "git@github.com:org/orders.git",
+			want:  []string{"orders/api"},
+		},
+		{
+			name:  "unknown owner",
+			owner: "https://github.com/org/unknown",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepository{
+				revHashMap:   map[string]git.Hash{"FETCH_HEAD": "snapshot123"},
+				readTreeResp: entries,
+				readObjFunc:  func(hash git.Hash) []byte { return []byte(metas[hash]) },
+			}
+			cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+			projects, err := cache.ListProjects(testContext(), &ListProjectsOptions{Owner: tt.owner})
+			if err != nil {
+				t.Fatalf("ListProjects() error = %v", err)
+			}
+
+			var got []string
+			for _, p := range projects {
+				got = append(got, string(p))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListProjects() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCache_ListProjectsPage(t *testing.T) {
+	// ls-tree order, which is not path order: "team/a.v2/" is listed before "team/a/"
+	var entries []git.TreeEntry
+	for _, p := range []string{"other/x", "team/a.v2", "team/a", "team/b"} {
+		entries = append(entries, git.TreeEntry{Path: constants.ProtosDir + "/" + p + "/" + constants.ProjectMetaFile, Type: git.BlobType})
+	}
+
+	tests := []struct {
+		name     string
+		opts     ListProjectsOptions
+		want     []ProjectPath
+		wantNext ProjectPath
+	}{
+		{
+			name: "no limit",
+			want: []ProjectPath{"other/x", "team/a", "team/a.v2", "team/b"},
+		},
+		{
+			name:     "first page",
+			opts:     ListProjectsOptions{Limit: 2},
+			want:     []ProjectPath{"other/x", "team/a"},
+			wantNext: "team/a",
+		},
+		{
+			name: "last page",
+			opts: ListProjectsOptions{Limit: 2, After: "team/a"},
+			want: []ProjectPath{"team/a.v2", "team/b"},
+		},
+		{
+			name: "limit equal to remaining",
+			opts: ListProjectsOptions{Limit: 4},
+			want: []ProjectPath{"other/x", "team/a", "team/a.v2", "team/b"},
+		},
+		{
+			name: "cursor past the end",
+			opts: ListProjectsOptions{After: "zzz"},
+			want: nil,
+		},
+		{
+			name:     "offset",
+			opts:     ListProjectsOptions{Limit: 2, Offset: 1},
+			want:     []ProjectPath{"team/a", "team/a.v2"},
+			wantNext: "team/a.v2",
+		},
+		{
+			name: "offset after cursor",
+			opts: ListProjectsOptions{Offset: 1, After: "team/a"},
+			want: []ProjectPath{"team/b"},
+		},
+		{
+			name: "offset past the end",
+			opts: ListProjectsOptions{Limit: 2, Offset: 4},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepository{
+				revHashMap:   map[string]git.Hash{"FETCH_HEAD": "snapshot123"},
+				readTreeResp: entries,
+			}
+			cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+			page, err := cache.ListProjectsPage(testContext(), &tt.opts)
+			if err != nil {
+				t.Fatalf("ListProjectsPage() error = %v", err)
+			}
+			if !reflect.DeepEqual(page.Projects, tt.want) {
+				t.Errorf("ListProjectsPage() projects = %v, want %v", page.Projects, tt.want)
+			}
+			if page.Next != tt.wantNext {
+				t.Errorf("ListProjectsPage() next = %q, want %q", page.Next, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestCache_ListProjectsPage_MaxDepth(t *testing.T) {
+	var entries []git.TreeEntry
+	for _, p := range []string{"team", "team/api", "team/api/v2", "team/billing"} {
+		entries = append(entries, git.TreeEntry{Path: constants.ProtosDir + "/" + p + "/" + constants.ProjectMetaFile, Type: git.BlobType})
+	}
+
+	tests := []struct {
+		name string
+		opts ListProjectsOptions
+		want []ProjectPath
+	}{
+		{
+			name: "any depth",
+			opts: ListProjectsOptions{Prefix: "team"},
+			want: []ProjectPath{"team", "team/api", "team/api/v2", "team/billing"},
+		},
+		{
+			name: "one level under prefix",
+			opts: ListProjectsOptions{Prefix: "team", MaxDepth: 1},
+			want: []ProjectPath{"team", "team/api", "team/billing"},
+		},
+		{
+			name: "no prefix",
+			opts: ListProjectsOptions{MaxDepth: 1},
+			want: []ProjectPath{"team"},
+		},
+		{
+			name: "with limit",
+			opts: ListProjectsOptions{Prefix: "team/", MaxDepth: 1, Limit: 2},
+			want: []ProjectPath{"team", "team/api"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepository{
+				revHashMap:   map[string]git.Hash{"FETCH_HEAD": "snapshot123"},
+				readTreeResp: entries,
+			}
+			cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+			page, err := cache.ListProjectsPage(testContext(), &tt.opts)
+			if err != nil {
+				t.Fatalf("ListProjectsPage() error = %v", err)
+			}
+			if !reflect.DeepEqual(page.Projects, tt.want) {
+				t.Errorf("ListProjectsPage() projects = %v, want %v", page.Projects, tt.want)
+			}
+		})
+	}
+}
+
+func TestCache_ListProjectsPage_StopsAtLimit(t *testing.T) {
+	// ls-tree order; "a" sorts before "a.v2" but is listed after it
+	var entries []git.TreeEntry
+	for _, p := range []string{"a.v2", "a", "b", "c", "d"} {
+		entries = append(entries, git.TreeEntry{Path: constants.ProtosDir + "/" + p + "/" + constants.ProjectMetaFile, Type: git.BlobType})
+	}
+
+	var visited []string
+	repo := &mockRepository{
+		revHashMap:   map[string]git.Hash{"FETCH_HEAD": "snapshot123"},
+		readTreeResp: entries,
+	}
+	cache := newMockCache(repo, "https://github.com/test/registry.git")
+	cache.repo = &walkRecorder{mockRepository: repo, visited: &visited}
+
+	page, err := cache.ListProjectsPage(testContext(), &ListProjectsOptions{Limit: 1})
+	if err != nil {
+		t.Fatalf("ListProjectsPage() error = %v", err)
+	}
+	if want := []ProjectPath{"a"}; !reflect.DeepEqual(page.Projects, want) || page.Next != "a" {
+		t.Errorf("ListProjectsPage() = %v next %q, want %v next %q", page.Projects, page.Next, want, "a")
+	}
+	// "a" must be read even though "a.v2" already fills the page; nothing after it can sort first
+	if len(visited) != 2 {
+		t.Errorf("walked %v, want the walk to stop after %s", visited, entries[1].Path)
+	}
+}
+
+// walkRecorder records the entries a tree walk hands to its callback.
+type walkRecorder struct {
+	*mockRepository
+	visited *[]string
+}
+
+func (w *walkRecorder) WalkTree(ctx context.Context, tree git.Treeish, opts git.ReadTreeOptions, fn func(git.TreeEntry) error) error {
+	return w.mockRepository.WalkTree(ctx, tree, opts, func(entry git.TreeEntry) error {
+		*w.visited = append(*w.visited, entry.Path)
+		return fn(entry)
+	})
+}
+
+func TestProjectDepth(t *testing.T) {
+	tests := []struct {
+		project ProjectPath
+		prefix  string
+		want    int
+	}{
+		{project: "team/api", want: 2},
+		{project: "team/api", prefix: "team", want: 1},
+		{project: "team", prefix: "team", want: 0},
+		{project: "team/team", prefix: "team", want: 1},
+		{project: "team/api/v2", prefix: "team/", want: 2},
+	}
+
+	for _, tt := range tests {
+		if got := projectDepth(tt.project, tt.prefix); got != tt.want {
+			t.Errorf("projectDepth(%q, %q) = %d, want %d", tt.project, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestCache_SnapshotDiff(t *testing.T) {
+	meta := func(p string) git.TreeEntry {
+		return git.TreeEntry{Path: protosPath(p, constants.ProjectMetaFile), Type: git.BlobType}
+	}
+	repo := &mockRepository{
+		revExists:    map[string]bool{"from": true, "to": true},
+		readTreeResp: []git.TreeEntry{meta("team/service"), meta("team/service/admin"), meta("team/other")},
+		diffTreeResp: []git.DiffEntry{
+			{Status: git.DiffModified, Path: "protos/team/service/admin/v1/admin.proto"},
+			{Status: git.DiffAdded, Path: "protos/README.md"},
+		},
+		mergeBaseFunc: func(a, b git.Treeish) (git.Hash, error) { return "from", nil },
+	}
+	cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+	got, err := cache.SnapshotDiff(testContext(), "from", "to")
+	if err != nil {
+		t.Fatalf("SnapshotDiff() error = %v", err)
+	}
+	// A change in a nested project changes the projects above it too
+	want := []ProjectPath{"team/service", "team/service/admin"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SnapshotDiff() = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(repo.diffTreeOpts.Paths, []string{constants.ProtosDir}) {
+		t.Errorf("DiffTree() paths = %v, want [%s]", repo.diffTreeOpts.Paths, constants.ProtosDir)
+	}
+
+	// A to snapshot missing from the cache is fetched before diffing, not handed to diff-tree
+	repo.revExists["to"] = false
+	repo.gitDir = t.TempDir()
+	if _, err := cache.SnapshotDiff(testContext(), "from", "to"); !errors.Is(err, protatoerrors.ErrSnapshotNotFound) {
+		t.Errorf("SnapshotDiff() to a missing snapshot error = %v, want %v", err, protatoerrors.ErrSnapshotNotFound)
+	}
+	if len(repo.fetchCalls) != 1 || len(repo.fetchCalls[0].RefSpecs) != 1 || repo.fetchCalls[0].RefSpecs[0] != "to" {
+		t.Errorf("fetch calls = %+v, want a fetch of to", repo.fetchCalls)
+	}
+}
+
+func TestCache_SnapshotDiff_MergeBase(t *testing.T) {
+	entries := []git.TreeEntry{
+		{Path: constants.ProtosDir + "/team/service/" + constants.ProjectMetaFile, Type: git.BlobType},
+	}
+
+	tests := []struct {
+		name      string
+		shallow   bool
+		mergeBase func(calls int) (git.Hash, error)
+		wantFetch bool
+		wantErr   bool
+	}{
+		{
+			name:      "merge base found",
+			mergeBase: func(int) (git.Hash, error) { return "base", nil },
+		},
+		{
+			name:      "no common history",
+			mergeBase: func(int) (git.Hash, error) { return "", protatoerrors.ErrNoMergeBase },
+		},
+		{
+			name:    "shallow cache is unshallowed",
+			shallow: true,
+			mergeBase: func(calls int) (git.Hash, error) {
+				if calls == 1 {
+					return "", protatoerrors.ErrNoMergeBase
+				}
+				return "base", nil
+			},
+			wantFetch: true,
+		},
+		{
+			name:      "merge-base fails",
+			mergeBase: func(int) (git.Hash, error) { return "", errors.New("merge-base: bad object") },
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gitDir := t.TempDir()
+			if tt.shallow {
+				if err := os.WriteFile(filepath.Join(gitDir, "shallow"), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			calls := 0
+			repo := &mockRepository{
+				gitDir:       gitDir,
+				revExists:    map[string]bool{"from": true, "to": true},
+				readTreeResp: entries,
+				mergeBaseFunc: func(a, b git.Treeish) (git.Hash, error) {
+					calls++
+					return tt.mergeBase(calls)
+				},
+			}
+			cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+			_, err := cache.SnapshotDiff(testContext(), "from", "to")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SnapshotDiff() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if fetched := len(repo.fetchCalls) > 0; fetched != tt.wantFetch {
+				t.Errorf("SnapshotDiff() fetched = %v, want %v", fetched, tt.wantFetch)
+			}
+		})
+	}
+}
+
+func TestCache_loadConfig(t *testing.T) {
+	configEntry := []git.TreeEntry{{Path: constants.RegistryConfigFile, Type: git.BlobType, Hash: "cfg"}}
+
+	const current = CurrentConfigAPIVersion
+
+	tests := []struct {
+		name      string
+		entries   []git.TreeEntry
+		content   string
+		want      Config
+		wantErr   bool
+		wantErrIs error
+	}{
+		{name: "no config file", want: Config{APIVersion: current}},
+		{name: "validate on push", entries: configEntry, content: "validateOnPush: true\n", want: Config{APIVersion: current, ValidateOnPush: true}},
+		{name: "default config", entries: configEntry, content: string(DefaultConfig), want: Config{APIVersion: current}},
+		{name: "allowed extensions", entries: configEntry, content: "allowedExtensions: [\".proto\", \".yaml\"]\n", want: Config{APIVersion: current, AllowedExtensions: []string{".proto", ".yaml"}}},
+		{name: "invalid yaml", entries: configEntry, content: "validateOnPush: [", wantErr: true},
+		{name: "implicit v0 is migrated", entries: configEntry, content: "validateOnPush: true\nallowedExtensions: [\".proto\"]\n", want: Config{APIVersion: current, ValidateOnPush: true, AllowedExtensions: []string{".proto"}}},
+		{name: "current version", entries: configEntry, content: fmt.Sprintf("apiVersion: %d\nvalidateOnPush: true\n", current), want: Config{APIVersion: current, ValidateOnPush: true}},
+		{name: "future version", entries: configEntry, content: fmt.Sprintf("apiVersion: %d\nvalidateOnPush: true\n", current+1), wantErr: true, wantErrIs: protatoerrors.ErrUnsupportedConfigVersion},
+		{name: "negative version", entries: configEntry, content: "apiVersion: -1\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepository{readTreeResp: tt.entries, readObjData: []byte(tt.content)}
+			cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+			got, err := cache.loadConfig(testContext(), "snapshot123")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErrIs != nil && !errors.Is(err, tt.wantErrIs) {
+				t.Errorf("loadConfig() error = %v, want %v", err, tt.wantErrIs)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("loadConfig() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_AllowsFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		file   string
+		want   bool
+	}{
+		{name: "default allows proto", config: &Config{}, file: "v1/api.proto", want: true},
+		{name: "default blocks yaml", config: &Config{}, file: "v1/schema.yaml", want: false},
+		{name: "nil config uses default", config: nil, file: "v1/api.proto", want: true},
+		{name: "extra extension allowed", config: &Config{AllowedExtensions: []string{".proto", ".yaml"}}, file: "v1/schema.yaml", want: true},
+		{name: "unlisted extension blocked", config: &Config{AllowedExtensions: []string{".proto", ".yaml"}}, file: "README.md", want: false},
+		{name: "metadata never allowed", config: &Config{AllowedExtensions: []string{".yaml"}}, file: constants.ProjectMetaFile, want: false},
+		{name: "configured metadata not allowed", config: &Config{MetadataFiles: []string{"OWNERS.proto"}}, file: "v1/OWNERS.proto", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.AllowsFile(tt.file); got != tt.want {
+				t.Errorf("AllowsFile(%q) = %v, want %v", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCache_SetProject_DisallowedExtension(t *testing.T) {
+	repo := &mockRepository{
+		revHashMap: map[string]git.Hash{"snapshot123^{tree}": "tree123"},
+	}
+	cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+	_, err := cache.SetProject(testContext(), &SetProjectRequest{
+		Project:  &Project{Path: "team/service"},
+		Snapshot: "snapshot123",
+		Files: []LocalProjectFile{
+			{Path: "v1/api.proto", Content: []byte("syntax = \"proto3\";")},
+			{Path: "README.md", Content: []byte("# docs")},
+		},
+		Author: &git.Author{Name: "Test", Email: "test@example.com"},
+	})
+	if !errors.Is(err, protatoerrors.ErrDisallowedFile) {
+		t.Fatalf("SetProject() error = %v, want ErrDisallowedFile", err)
+	}
+	if !strings.Contains(err.Error(), "README.md") {
+		t.Errorf("SetProject() error = %v, want it to name README.md", err)
+	}
+}
+
+func TestCache_SetProject_RequireApproval(t *testing.T) {
+	approve := func(name string) ApprovalSource {
+		return func(context.Context, ProjectPath) (string, error) { return name, nil }
+	}
+
+	tests := []struct {
+		name        string
+		published   string // protato.root.yaml of the project at the base snapshot; "" when new
+		require     bool
+		approval    ApprovalSource
+		wantErr     error
+		wantTrailer string
+	}{
+		{
+			name:    "required without approval source",
+			require: true,
+			wantErr: protatoerrors.ErrApprovalRequired,
+		},
+		{
+			name:     "required with empty approval",
+			require:  true,
+			approval: approve(" "),
+			wantErr:  protatoerrors.ErrApprovalRequired,
+		},
+		{
+			name:        "required with approval",
+			require:     true,
+			approval:    approve("alice@example.com"),
+			wantTrailer: "\n\nApproved-by: alice@example.com",
+		},
+		{
+			name:     "approved by the pusher's email",
+			require:  true,
+			approval: approve("TEST@example.com"),
+			wantErr:  protatoerrors.ErrSelfApproval,
+		},
+		{
+			name:     "approved by the pusher's name and email",
+			require:  true,
+			approval: approve("Test <other@example.com>"),
+			wantErr:  protatoerrors.ErrSelfApproval,
+		},
+		{
+			name:      "published project requires approval",
+			published: "git:\n  commit: abc\n  url: github.com/test/repo\nrequireApproval: true\n",
+			wantErr:   protatoerrors.ErrApprovalRequired,
+		},
+		{
+			name:      "published project without approval",
+			published: "git:\n  commit: abc\n  url: github.com/test/repo\n",
+		},
+	}
+
+	metaPath := protosPath("team/service", constants.ProjectMetaFile)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepository{
+				revHashMap:     map[string]git.Hash{"snapshot123^{tree}": "tree123"},
+				writeObjHash:   "newhash",
+				updateTreeHash: "newtree",
+				commitTreeHash: "newcommit",
+				readTreeFunc: func(opts git.ReadTreeOptions) ([]git.TreeEntry, error) {
+					if tt.published != "" && len(opts.Paths) == 1 && opts.Paths[0] == metaPath {
+						return []git.TreeEntry{{Path: metaPath, Type: git.BlobType, Hash: "meta1"}}, nil
+					}
+					return nil, nil
+				},
+				readObjData: []byte(tt.published),
+			}
+			cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+			_, err := cache.SetProject(testContext(), &SetProjectRequest{
+				Project:  &Project{Path: "team/service", Commit: "abc123", RepositoryURL: "github.com/test/repo", RequireApproval: tt.require},
+				Snapshot: "snapshot123",
+				Files:    []LocalProjectFile{{Path: "v1/api.proto", Content: []byte("syntax = \"proto3\";")}},
+				Author:   &git.Author{Name: "Test", Email: "test@example.com"},
+				Approval: tt.approval,
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("SetProject() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetProject() error = %v", err)
+			}
+			if !strings.HasSuffix(repo.commitTreeReq.Message, tt.wantTrailer) || (tt.wantTrailer == "" && strings.Contains(repo.commitTreeReq.Message, approvalTrailer)) {
+				t.Errorf("commit message = %q, want trailer %q", repo.commitTreeReq.Message, tt.wantTrailer)
+			}
+		})
+	}
+}
+
+func TestCheckSymlinks(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	write := func(path, content string) string {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	link := func(target, path string) string {
+		t.Helper()
+		if err := os.Symlink(target, path); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+		return path
+	}
+
+	shared := write(filepath.Join(root, "shared", "common.proto"), "syntax = \"proto3\";")
+	sharedDoc := write(filepath.Join(root, "shared", "README.md"), "# shared")
+	secret := write(filepath.Join(outside, "secret.proto"), "syntax = \"proto3\";")
+	projectDir := filepath.Join(root, "proto", "team", "service")
+	write(filepath.Join(projectDir, "api.proto"), "syntax = \"proto3\";")
+	inside := link(shared, filepath.Join(projectDir, "common.proto"))
+	insideRelative := link(filepath.Join("..", "..", "..", "shared", "common.proto"), filepath.Join(projectDir, "relative.proto"))
+	doc := link(sharedDoc, filepath.Join(projectDir, "README.md"))
+	escaping := link(secret, filepath.Join(projectDir, "secret.proto"))
+
+	tests := []struct {
+		name    string
+		file    LocalProjectFile
+		policy  SymlinkPolicy
+		wantErr bool
+	}{
+		{name: "regular file", file: LocalProjectFile{Path: "api.proto", LocalPath: filepath.Join(projectDir, "api.proto")}, policy: SymlinkPolicy{Root: root}},
+		{name: "proto symlink inside root", file: LocalProjectFile{Path: "common.proto", LocalPath: inside}, policy: SymlinkPolicy{Root: root}},
+		{name: "relative proto symlink inside root", file: LocalProjectFile{Path: "relative.proto", LocalPath: insideRelative}, policy: SymlinkPolicy{Root: root}},
+		{name: "proto symlink escaping root", file: LocalProjectFile{Path: "secret.proto", LocalPath: escaping}, policy: SymlinkPolicy{Root: root}, wantErr: true},
+		{name: "escaping symlink without root", file: LocalProjectFile{Path: "secret.proto", LocalPath: escaping}},
+		{name: "non-proto symlink by default", file: LocalProjectFile{Path: "README.md", LocalPath: doc}, policy: SymlinkPolicy{Root: root}, wantErr: true},
+		{name: "non-proto symlink dereferenced", file: LocalProjectFile{Path: "README.md", LocalPath: doc}, policy: SymlinkPolicy{Dereference: true, Root: root}},
+		{name: "content only", file: LocalProjectFile{Path: "gen.proto", Content: []byte("x")}, policy: SymlinkPolicy{Root: root}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSymlinks([]LocalProjectFile{tt.file}, tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkSymlinks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), constants.ErrMsgSymlink) {
+				t.Errorf("checkSymlinks() error = %v, want %q", err, constants.ErrMsgSymlink)
+			}
+		})
+	}
+}
+
+func TestCache_prepareUpserts_PublishesSymlinkTarget(t *testing.T) {
+	root := t.TempDir()
+	target := filepath.Join(root, "shared", "common.proto")
+	os.MkdirAll(filepath.Dir(target), 0755)
+	if err := os.WriteFile(target, []byte("shared content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	linkPath := filepath.Join(root, "common.proto")
+	if err := os.Symlink(target, linkPath); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	repo := &mockRepository{
+		writeObjFunc: func(content []byte) git.Hash { return git.Hash("hash-of-" + string(content)) },
+	}
+	cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+	upserts, err := cache.prepareUpserts(testContext(), &Project{Commit: "abc123", RepositoryURL: "https://github.com/test/repo"}, []LocalProjectFile{
+		{Path: "common.proto", LocalPath: linkPath},
+	}, "protos/team/service", 0, SymlinkPolicy{Root: root})
+	if err != nil {
+		t.Fatalf("prepareUpserts() error = %v", err)
+	}
+	if got := upserts[1].Blob; got != "hash-of-shared content" {
+		t.Errorf("symlink published as %s, want the target's content", got)
+	}
+	if upserts[1].Mode == 0120000 {
+		t.Error("symlink published as a git symlink")
+	}
+}
@@ -0,0 +1,131 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+)
+
+// compactEvery is how many pushes through a cache pass between automatic compactions.
+const compactEvery = 20
+
+// pushCountFile records, in the cache root, the pushes made since the last automatic compaction.
+const pushCountFile = ".protato.pushes"
+
+// maxBlobStoreSize is the size, in bytes, the blob store is trimmed to when the cache is compacted.
+const maxBlobStoreSize = 256 << 20
+
+// Compact packs the loose objects the cache accumulates over refreshes and pushes and prunes
+// unreachable ones git considers expired. It also trims the blob store to maxBlobStoreSize,
+// least recently used blobs first. It holds the operation lock exclusively, so no other
+// protato process can use the cache meanwhile, and r.mu against git operations of this one.
+func (r *Cache) Compact(ctx context.Context) error {
+	return r.compact(ctx, git.GcOptions{})
+}
+
+// compact runs git gc on the cache with opts.
+func (r *Cache) compact(ctx context.Context, opts git.GcOptions) error {
+	if r.ops == nil {
+		return fmt.Errorf("compact registry cache: cache is not locked")
+	}
+	ctx, unlock, err := r.lock(ctx, lockExclusive)
+	if err != nil {
+		return fmt.Errorf("compact registry cache: %w", err)
+	}
+	defer unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	logger.Log(ctx).Debug().Str("path", r.root).Bool("auto", opts.Auto).Msg("Compacting registry cache")
+	if err := r.repo.Gc(ctx, opts); err != nil {
+		return fmt.Errorf("compact registry cache: %w", err)
+	}
+	if err := trimBlobStore(r.BlobDir(), maxBlobStoreSize); err != nil {
+		return fmt.Errorf("trim blob store: %w", err)
+	}
+	return nil
+}
+
+// trimBlobStore removes blobs from dir, oldest modification time first, until the blobs
+// left take at most maxSize bytes. Readers refresh the modification time of a blob they
+// use, so the least recently used go first. A missing dir is left as it is.
+func trimBlobStore(dir string, maxSize int64) error {
+	type blob struct {
+		path string
+		size int64
+		used time.Time
+	}
+	var blobs []blob
+	var total int64
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // Removed meanwhile
+		}
+		blobs = append(blobs, blob{path: p, size: info.Size(), used: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil || total <= maxSize {
+		return err
+	}
+
+	sort.Slice(blobs, func(i, j int) bool { return blobs[i].used.Before(blobs[j].used) })
+	for _, b := range blobs {
+		if total <= maxSize {
+			break
+		}
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		total -= b.size
+	}
+	return nil
+}
+
+// compactAfterPush counts a push and, every compactEvery pushes, lets git compact the cache
+// if its loose objects or packs exceed git's gc.auto thresholds. Maintenance never fails
+// the push, so errors are only logged. Caches not opened with Open are left alone.
+func (r *Cache) compactAfterPush(ctx context.Context) {
+	if r.ops == nil {
+		return
+	}
+
+	countPath := filepath.Join(r.root, pushCountFile)
+	pushes := 0
+	if data, err := os.ReadFile(countPath); err == nil {
+		pushes, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+	}
+	pushes++
+
+	if pushes >= compactEvery {
+		if err := r.compact(ctx, git.GcOptions{Auto: true}); err != nil {
+			logger.Log(ctx).Warn().Err(err).Msg("Failed to compact registry cache")
+			return // Retried after the next push
+		}
+		pushes = 0
+	}
+	if err := os.WriteFile(countPath, []byte(strconv.Itoa(pushes)), 0644); err != nil {
+		logger.Log(ctx).Warn().Err(err).Msg("Failed to record registry cache pushes")
+	}
+}
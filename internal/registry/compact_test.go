@@ -0,0 +1,130 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rahulagarwal0605/protato/internal/git"
+)
+
+// newLockedMockCache creates a Cache over a mock repository holding the lock on a temporary root.
+func newLockedMockCache(t *testing.T, repo *mockRepository) *Cache {
+	t.Helper()
+	root := t.TempDir()
+	lockFile, err := lockCacheRoot(root, syscall.LOCK_SH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := &Cache{root: root, repo: repo, lockFile: lockFile, ops: newOpLock(root, 0)}
+	t.Cleanup(func() { cache.Close() })
+	return cache
+}
+
+func TestCache_Compact(t *testing.T) {
+	repo := &mockRepository{}
+	cache := newLockedMockCache(t, repo)
+	repo.gcFunc = func(git.GcOptions) error {
+		other := newOpLock(cache.root, time.Millisecond)
+		defer other.close()
+		if _, unlock, err := other.acquire(testContext(), lockShared); err == nil {
+			unlock()
+			t.Error("Gc() ran without the operation lock held exclusively")
+		}
+		if cache.mu.TryLock() {
+			cache.mu.Unlock()
+			t.Error("Gc() ran without r.mu held")
+		}
+		return nil
+	}
+
+	if err := cache.Compact(testContext()); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if want := []git.GcOptions{{}}; !reflect.DeepEqual(repo.gcCalls, want) {
+		t.Errorf("Gc() calls = %+v, want %+v", repo.gcCalls, want)
+	}
+}
+
+func TestCache_Compact_Unlocked(t *testing.T) {
+	repo := &mockRepository{}
+	cache := newMockCache(repo, "https://example.com/registry.git")
+
+	if err := cache.Compact(testContext()); err == nil {
+		t.Error("Compact() error = nil, want an error for an unlocked cache")
+	}
+	if len(repo.gcCalls) != 0 {
+		t.Errorf("Gc() calls = %+v, want none", repo.gcCalls)
+	}
+}
+
+func TestCache_compactAfterPush(t *testing.T) {
+	repo := &mockRepository{}
+	cache := newLockedMockCache(t, repo)
+	ctx := testContext()
+
+	for i := 1; i < compactEvery; i++ {
+		cache.compactAfterPush(ctx)
+	}
+	if len(repo.gcCalls) != 0 {
+		t.Fatalf("Gc() ran after %d pushes, want %d", compactEvery-1, compactEvery)
+	}
+
+	cache.compactAfterPush(ctx)
+	if want := []git.GcOptions{{Auto: true}}; !reflect.DeepEqual(repo.gcCalls, want) {
+		t.Errorf("Gc() calls = %+v, want %+v", repo.gcCalls, want)
+	}
+	data, err := os.ReadFile(filepath.Join(cache.root, pushCountFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "0" {
+		t.Errorf("push count = %q after compacting, want 0", data)
+	}
+}
+
+func TestTrimBlobStore(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	blobs := []struct {
+		name string
+		age  time.Duration
+	}{
+		{name: "aa/old", age: 3 * time.Hour},
+		{name: "bb/recent", age: time.Hour},
+		{name: "aa/new", age: 0},
+	}
+	for _, b := range blobs {
+		p := filepath.Join(dir, b.name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, make([]byte, 10), 0644); err != nil {
+			t.Fatal(err)
+		}
+		used := now.Add(-b.age)
+		if err := os.Chtimes(p, used, used); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := trimBlobStore(dir, 30); err != nil {
+		t.Fatalf("trimBlobStore() within the limit error = %v", err)
+	}
+	if err := trimBlobStore(dir, 25); err != nil {
+		t.Fatalf("trimBlobStore() error = %v", err)
+	}
+	for _, b := range blobs {
+		_, err := os.Stat(filepath.Join(dir, b.name))
+		if kept, want := err == nil, b.name != "aa/old"; kept != want {
+			t.Errorf("%s kept = %v, want %v", b.name, kept, want)
+		}
+	}
+
+	if err := trimBlobStore(filepath.Join(dir, "missing"), 0); err != nil {
+		t.Errorf("trimBlobStore() of a missing dir error = %v", err)
+	}
+}
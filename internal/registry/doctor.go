@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+	"github.com/rahulagarwal0605/protato/internal/git"
+)
+
+// Registry health checks run by Diagnose.
+const (
+	CheckReachable     = "registry reachable"
+	CheckDefaultBranch = "default branch"
+	CheckProtosDir     = "protos dir"
+	CheckConfig        = "registry config"
+)
+
+// Finding is the outcome of one registry health check.
+type Finding struct {
+	Check  string // One of the Check constants
+	Detail string // What was found when the check passed
+	Err    error  // Why the check failed; nil when it passed
+}
+
+// Diagnose checks that the registry is reachable, its default branch can be detected,
+// and the snapshot in the cache has a protos/ directory and a parsable registry config.
+// Every check runs, even when an earlier one fails.
+func (r *Cache) Diagnose(ctx context.Context) []Finding {
+	return []Finding{
+		r.checkReachable(ctx),
+		r.checkDefaultBranch(ctx),
+		r.checkProtosDir(ctx),
+		r.checkConfig(ctx),
+	}
+}
+
+// checkReachable lists the remote's branches, which fails if the URL is wrong or unreachable.
+func (r *Cache) checkReachable(ctx context.Context) Finding {
+	refs, err := r.repo.LsRemote(ctx, git.LsRemoteOptions{Remote: "origin", Heads: true})
+	if err != nil {
+		return Finding{Check: CheckReachable, Err: err}
+	}
+	return Finding{Check: CheckReachable, Detail: fmt.Sprintf("%s (%d branches)", r.url, len(refs))}
+}
+
+// checkDefaultBranch reads the branch HEAD points to in the cache, set from the remote at clone time.
+func (r *Cache) checkDefaultBranch(ctx context.Context) Finding {
+	ref, err := r.repo.SymbolicRef(ctx, "HEAD")
+	if err != nil {
+		return Finding{Check: CheckDefaultBranch, Err: fmt.Errorf("detect default branch: %w", err)}
+	}
+	branch, ok := strings.CutPrefix(ref, "refs/heads/")
+	if !ok {
+		return Finding{Check: CheckDefaultBranch, Err: fmt.Errorf("HEAD points to %s, not a branch", ref)}
+	}
+	return Finding{Check: CheckDefaultBranch, Detail: branch}
+}
+
+// checkProtosDir looks for the protos/ directory in the current snapshot.
+func (r *Cache) checkProtosDir(ctx context.Context) Finding {
+	snapshot, err := r.getOrCreateSnapshot(ctx, "")
+	if err != nil {
+		return Finding{Check: CheckProtosDir, Err: err}
+	}
+	entries, err := r.repo.ReadTree(ctx, git.Treeish(snapshot), git.ReadTreeOptions{
+		Paths: []string{constants.ProtosDir},
+	})
+	if err != nil {
+		return Finding{Check: CheckProtosDir, Err: readTreeError(err)}
+	}
+	for _, entry := range entries {
+		if entry.Path == constants.ProtosDir && entry.Type == git.TreeType {
+			return Finding{Check: CheckProtosDir, Detail: fmt.Sprintf("found at %s", snapshot.Short())}
+		}
+	}
+	return Finding{Check: CheckProtosDir, Err: fmt.Errorf("%s/ not found at %s", constants.ProtosDir, snapshot.Short())}
+}
+
+// checkConfig parses protato.registry.yaml in the current snapshot; a missing file is fine.
+func (r *Cache) checkConfig(ctx context.Context) Finding {
+	snapshot, err := r.getOrCreateSnapshot(ctx, "")
+	if err != nil {
+		return Finding{Check: CheckConfig, Err: err}
+	}
+	config, err := r.loadConfig(ctx, snapshot)
+	if err != nil {
+		return Finding{Check: CheckConfig, Err: err}
+	}
+	return Finding{Check: CheckConfig, Detail: fmt.Sprintf("allowed extensions %s", strings.Join(config.Extensions(), ", "))}
+}
@@ -0,0 +1,109 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+	"github.com/rahulagarwal0605/protato/internal/git"
+)
+
+// healthyDoctorRepo returns a mock registry that passes every Diagnose check.
+func healthyDoctorRepo() *mockRepository {
+	return &mockRepository{
+		revHashMap:   map[string]git.Hash{"FETCH_HEAD": "abc123def456"},
+		lsRemoteRefs: []git.RemoteRef{{Name: "refs/heads/main", Hash: "abc123def456"}},
+		symbolicRefs: map[string]string{"HEAD": "refs/heads/main"},
+		readTreeFunc: func(opts git.ReadTreeOptions) ([]git.TreeEntry, error) {
+			switch opts.Paths[0] {
+			case constants.ProtosDir:
+				return []git.TreeEntry{{Path: constants.ProtosDir, Type: git.TreeType, Hash: "tree1"}}, nil
+			case constants.RegistryConfigFile:
+				return []git.TreeEntry{{Path: constants.RegistryConfigFile, Type: git.BlobType, Hash: "blob1"}}, nil
+			}
+			return nil, nil
+		},
+		readObjData: []byte("allowedExtensions: [.proto, .md]\n"),
+	}
+}
+
+func TestCache_Diagnose(t *testing.T) {
+	tests := []struct {
+		name     string
+		breakFn  func(m *mockRepository)
+		wantFail string // Check expected to fail; "" means all pass
+	}{
+		{
+			name: "healthy registry",
+		},
+		{
+			name: "unreachable",
+			breakFn: func(m *mockRepository) {
+				m.lsRemoteErr = errors.New("ls-remote: could not read from remote repository")
+			},
+			wantFail: CheckReachable,
+		},
+		{
+			name:     "default branch undetectable",
+			breakFn:  func(m *mockRepository) { m.symbolicRefs = nil },
+			wantFail: CheckDefaultBranch,
+		},
+		{
+			name: "protos dir missing",
+			breakFn: func(m *mockRepository) {
+				healthy := m.readTreeFunc
+				m.readTreeFunc = func(opts git.ReadTreeOptions) ([]git.TreeEntry, error) {
+					if opts.Paths[0] == constants.ProtosDir {
+						return nil, nil
+					}
+					return healthy(opts)
+				}
+			},
+			wantFail: CheckProtosDir,
+		},
+		{
+			name:     "config does not parse",
+			breakFn:  func(m *mockRepository) { m.readObjData = []byte("allowedExtensions: {not: a list\n") },
+			wantFail: CheckConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := healthyDoctorRepo()
+			if tt.breakFn != nil {
+				tt.breakFn(repo)
+			}
+			cache := newMockCache(repo, "github.com/org/registry")
+
+			findings := cache.Diagnose(testContext())
+			if len(findings) != 4 {
+				t.Fatalf("Diagnose() returned %d findings, want 4", len(findings))
+			}
+			for _, f := range findings {
+				if f.Check == tt.wantFail {
+					if f.Err == nil {
+						t.Errorf("%s passed (%s), want failure", f.Check, f.Detail)
+					}
+					continue
+				}
+				if f.Err != nil {
+					t.Errorf("%s failed: %v", f.Check, f.Err)
+				}
+				if f.Detail == "" {
+					t.Errorf("%s passed without detail", f.Check)
+				}
+			}
+		})
+	}
+}
+
+func TestCache_Diagnose_DefaultBranchDetail(t *testing.T) {
+	cache := newMockCache(healthyDoctorRepo(), "github.com/org/registry")
+
+	for _, f := range cache.Diagnose(testContext()) {
+		if f.Check == CheckDefaultBranch && f.Detail != "main" {
+			t.Errorf("default branch detail = %q, want %q", f.Detail, "main")
+		}
+	}
+}
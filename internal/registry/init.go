@@ -0,0 +1,110 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+)
+
+// defaultBranch is the branch created for a new registry.
+const defaultBranch = "main"
+
+// initialCommitMessage is the message of the first commit in a new registry.
+const initialCommitMessage = "Initialize protato registry"
+
+// DefaultConfig is the content of protato.registry.yaml in a new registry.
+var DefaultConfig = []byte(`# Protato registry configuration.
+# Projects are stored under protos/<project>/ with a protato.root.yaml marker.
+
+# Schema version of this file.
+apiVersion: 1
+
+# Compile every pushed project against the registry before accepting it.
+# validateOnPush: true
+
+# File extensions projects may contain. Defaults to .proto only.
+# allowedExtensions: [".proto", ".yaml", ".md"]
+`)
+
+// Init creates a new registry repository with an initial commit.
+// The bare repository is created at req.Path; if req.Remote is set the
+// initial commit is also pushed there.
+func Init(ctx context.Context, req *InitRequest) (git.Hash, error) {
+	branch := req.Branch
+	if branch == "" {
+		branch = defaultBranch
+	}
+
+	repo, err := git.InitBare(ctx, req.Path, git.InitOptions{InitialBranch: branch})
+	if err != nil {
+		return "", fmt.Errorf("init registry: %w", err)
+	}
+
+	author := req.Author
+	if author == nil {
+		user, err := repo.GetUser(ctx)
+		if err != nil {
+			return "", fmt.Errorf("get git user: %w", err)
+		}
+		author = &user
+	}
+
+	commit, err := createInitialCommit(ctx, repo, *author)
+	if err != nil {
+		return "", err
+	}
+
+	if err := repo.UpdateRef(ctx, buildBranchRef(branch), commit, ""); err != nil {
+		return "", fmt.Errorf("update ref: %w", err)
+	}
+
+	if req.Remote != "" {
+		logger.Log(ctx).Info().Str("remote", req.Remote).Str("branch", branch).Msg("Pushing initial registry commit")
+		if err := repo.Push(ctx, git.PushOptions{
+			Remote:   req.Remote,
+			RefSpecs: []git.Refspec{buildRefspec(commit.String(), buildBranchRef(branch))},
+		}); err != nil {
+			return "", fmt.Errorf("push: %w", err)
+		}
+	}
+
+	return commit, nil
+}
+
+// createInitialCommit writes the registry skeleton and commits it without parents.
+func createInitialCommit(ctx context.Context, repo git.RepositoryInterface, author git.Author) (git.Hash, error) {
+	keepBlob, err := repo.WriteObject(ctx, bytes.NewReader(nil), git.WriteObjectOptions{})
+	if err != nil {
+		return "", fmt.Errorf("write %s: %w", constants.KeepFileName, err)
+	}
+
+	configBlob, err := repo.WriteObject(ctx, bytes.NewReader(DefaultConfig), git.WriteObjectOptions{})
+	if err != nil {
+		return "", fmt.Errorf("write %s: %w", constants.RegistryConfigFile, err)
+	}
+
+	tree, err := repo.UpdateTree(ctx, git.UpdateTreeRequest{
+		Upserts: []git.TreeUpsert{
+			createTreeUpsert(protosPath(constants.KeepFileName), keepBlob),
+			createTreeUpsert(constants.RegistryConfigFile, configBlob),
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("update tree: %w", err)
+	}
+
+	commit, err := repo.CommitTree(ctx, git.CommitTreeRequest{
+		Tree:    tree,
+		Message: initialCommitMessage,
+		Author:  author,
+	})
+	if err != nil {
+		return "", fmt.Errorf("create commit: %w", err)
+	}
+
+	return commit, nil
+}
@@ -0,0 +1,93 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+	"github.com/rahulagarwal0605/protato/internal/git"
+)
+
+func TestCreateInitialCommit(t *testing.T) {
+	ctx := testContext()
+	author := git.Author{Name: "Test", Email: "test@example.com"}
+
+	tests := []struct {
+		name    string
+		repo    *mockRepository
+		want    git.Hash
+		wantErr bool
+	}{
+		{
+			name: "success",
+			repo: &mockRepository{
+				writeObjHash:   "blob123",
+				updateTreeHash: "tree123",
+				commitTreeHash: "commit123",
+			},
+			want: "commit123",
+		},
+		{
+			name:    "write object error",
+			repo:    &mockRepository{writeObjErr: errors.New("write failed")},
+			wantErr: true,
+		},
+		{
+			name: "update tree error",
+			repo: &mockRepository{
+				writeObjHash:  "blob123",
+				updateTreeErr: errors.New("tree failed"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "commit tree error",
+			repo: &mockRepository{
+				writeObjHash:   "blob123",
+				updateTreeHash: "tree123",
+				commitTreeErr:  errors.New("commit failed"),
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := createInitialCommit(ctx, tt.repo, author)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("createInitialCommit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("createInitialCommit() = %v, want %v", got, tt.want)
+			}
+
+			treeReq := tt.repo.updateTreeReq
+			if treeReq.Tree != "" {
+				t.Errorf("UpdateTree base = %v, want empty tree", treeReq.Tree)
+			}
+			paths := make(map[string]bool)
+			for _, u := range treeReq.Upserts {
+				paths[u.Path] = true
+			}
+			for _, want := range []string{constants.ProtosDir + "/" + constants.KeepFileName, constants.RegistryConfigFile} {
+				if !paths[want] {
+					t.Errorf("UpdateTree upserts missing %s", want)
+				}
+			}
+
+			commitReq := tt.repo.commitTreeReq
+			if commitReq.Tree != "tree123" {
+				t.Errorf("CommitTree tree = %v, want tree123", commitReq.Tree)
+			}
+			if len(commitReq.Parents) != 0 {
+				t.Errorf("CommitTree parents = %v, want none", commitReq.Parents)
+			}
+			if commitReq.Author != author {
+				t.Errorf("CommitTree author = %v, want %v", commitReq.Author, author)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rahulagarwal0605/protato/internal/errors"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+)
+
+// opLockFile is the sentinel, in the cache root, that cache operations lock so that
+// protato processes sharing a cache take turns updating it.
+const opLockFile = ".protato.op.lock"
+
+// DefaultLockTimeout is how long a cache operation waits for other processes by default.
+const DefaultLockTimeout = 30 * time.Second
+
+// lockPollInterval is how often a waiting cache operation retries the lock.
+// It is a variable so tests can speed it up.
+var lockPollInterval = 50 * time.Millisecond
+
+// lockMode is the kind of access a cache operation needs.
+type lockMode int
+
+const (
+	lockShared    lockMode = iota + 1 // Reads; any number of holders
+	lockExclusive                     // Ref and object updates; a single holder
+)
+
+// String returns the mode name.
+func (m lockMode) String() string {
+	if m == lockExclusive {
+		return "exclusive"
+	}
+	return "shared"
+}
+
+// CacheBusyError reports that another process held the cache lock for longer than the timeout.
+// It matches errors.ErrCacheBusy with errors.Is.
+type CacheBusyError struct {
+	Path    string        // Lock file that could not be taken
+	Timeout time.Duration // How long the operation waited
+}
+
+// Error returns the message.
+func (e *CacheBusyError) Error() string {
+	return fmt.Sprintf("%s: another protato process held %s for more than %s", errors.ErrCacheBusy, e.Path, e.Timeout)
+}
+
+// Is matches errors.ErrCacheBusy.
+func (e *CacheBusyError) Is(target error) bool {
+	return target == errors.ErrCacheBusy
+}
+
+// opLock is an advisory lock on the operation sentinel of a cache. Across processes it is an
+// flock on the sentinel; within a process an RWMutex orders the goroutines sharing the file.
+type opLock struct {
+	path    string
+	timeout time.Duration
+
+	rw sync.RWMutex // Orders holders within this process
+
+	mu      sync.Mutex // Protects file and readers
+	file    *os.File   // Open sentinel; nil until first locked
+	readers int        // Shared holders within this process
+}
+
+// newOpLock returns the operation lock of a cache root. A non-positive timeout uses DefaultLockTimeout.
+func newOpLock(cacheRoot string, timeout time.Duration) *opLock {
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+	return &opLock{path: filepath.Join(cacheRoot, opLockFile), timeout: timeout}
+}
+
+// heldLock records in a context the operation lock a call chain holds, so nested cache
+// calls do not try to take it again.
+type heldLock struct {
+	lock *opLock
+	mode lockMode
+}
+
+// heldLockKey is the context key of heldLock.
+type heldLockKey struct{}
+
+// acquire takes the lock in mode, waiting up to the timeout for other holders. It returns a
+// context recording the lock, for nested calls, and the function releasing it. A call chain
+// already holding the lock in mode, or exclusively, gets it without waiting; one holding it
+// shared cannot upgrade to exclusive.
+func (l *opLock) acquire(ctx context.Context, mode lockMode) (context.Context, func(), error) {
+	if held, ok := ctx.Value(heldLockKey{}).(heldLock); ok && held.lock == l {
+		if held.mode >= mode {
+			return ctx, func() {}, nil
+		}
+		return nil, nil, fmt.Errorf("cannot take %s cache lock while holding it %s", mode, held.mode)
+	}
+
+	deadline := time.Now().Add(l.timeout)
+	if err := l.wait(ctx, deadline, func() (bool, error) { return l.tryLocal(mode), nil }); err != nil {
+		return nil, nil, err
+	}
+	if err := l.wait(ctx, deadline, func() (bool, error) { return l.tryFile(mode) }); err != nil {
+		l.unlockLocal(mode)
+		return nil, nil, err
+	}
+
+	logger.Log(ctx).Debug().Str("lock", l.path).Stringer("mode", mode).Msg("Acquired cache operation lock")
+	release := func() {
+		l.unlockFile(mode)
+		l.unlockLocal(mode)
+	}
+	return context.WithValue(ctx, heldLockKey{}, heldLock{lock: l, mode: mode}), release, nil
+}
+
+// wait polls try until it succeeds or fails, the deadline passes or ctx is done.
+func (l *opLock) wait(ctx context.Context, deadline time.Time, try func() (bool, error)) error {
+	for {
+		ok, err := try()
+		if ok || err != nil {
+			return err
+		}
+		if !time.Now().Before(deadline) {
+			return &CacheBusyError{Path: l.path, Timeout: l.timeout}
+		}
+		if err := sleepContext(ctx, lockPollInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// tryLocal takes the in-process side of the lock without blocking.
+func (l *opLock) tryLocal(mode lockMode) bool {
+	if mode == lockExclusive {
+		return l.rw.TryLock()
+	}
+	return l.rw.TryRLock()
+}
+
+// unlockLocal releases the in-process side of the lock.
+func (l *opLock) unlockLocal(mode lockMode) {
+	if mode == lockExclusive {
+		l.rw.Unlock()
+	} else {
+		l.rw.RUnlock()
+	}
+}
+
+// tryFile takes the flock without blocking. Shared holders in this process share one flock,
+// taken by the first and released by the last.
+func (l *opLock) tryFile(mode lockMode) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if mode == lockShared && l.readers > 0 {
+		l.readers++
+		return true, nil
+	}
+	if l.file == nil {
+		file, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return false, fmt.Errorf("create lock file: %w", err)
+		}
+		l.file = file
+	}
+
+	how := syscall.LOCK_SH
+	if mode == lockExclusive {
+		how = syscall.LOCK_EX
+	}
+	if err := syscall.Flock(int(l.file.Fd()), how|syscall.LOCK_NB); err != nil {
+		return false, nil
+	}
+	if mode == lockShared {
+		l.readers++
+	}
+	return true, nil
+}
+
+// unlockFile releases the flock once no holder in this process needs it.
+func (l *opLock) unlockFile(mode lockMode) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if mode == lockShared {
+		l.readers--
+		if l.readers > 0 {
+			return
+		}
+	}
+	syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+}
+
+// close closes the sentinel, dropping any flock still held.
+func (l *opLock) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// lock takes the operation lock of the cache in mode for the call chain of ctx. Caches not
+// opened with Open have no operation lock and are not locked.
+func (r *Cache) lock(ctx context.Context, mode lockMode) (context.Context, func(), error) {
+	if r.ops == nil {
+		return ctx, func() {}, nil
+	}
+	return r.ops.acquire(ctx, mode)
+}
@@ -0,0 +1,234 @@
+package registry
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	protatoerrors "github.com/rahulagarwal0605/protato/internal/errors"
+	"github.com/rahulagarwal0605/protato/internal/git"
+)
+
+// withFastLockPolling makes waiting lock acquisitions retry quickly for the duration of a test.
+func withFastLockPolling(t *testing.T) {
+	t.Helper()
+	orig := lockPollInterval
+	lockPollInterval = time.Millisecond
+	t.Cleanup(func() { lockPollInterval = orig })
+}
+
+func TestOpLock_SerializesExclusive(t *testing.T) {
+	withFastLockPolling(t)
+	root := t.TempDir()
+
+	// Each goroutine has its own lock on the sentinel, as separate processes would
+	var inside, overlaps atomic.Int32
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock := newOpLock(root, time.Second)
+			defer lock.close()
+			for j := 0; j < 5; j++ {
+				_, unlock, err := lock.acquire(testContext(), lockExclusive)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if inside.Add(1) > 1 {
+					overlaps.Add(1)
+				}
+				time.Sleep(2 * time.Millisecond)
+				inside.Add(-1)
+				unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("acquire() error = %v", err)
+	}
+	if n := overlaps.Load(); n != 0 {
+		t.Errorf("exclusive holders overlapped %d times", n)
+	}
+}
+
+func TestOpLock_SharedHolders(t *testing.T) {
+	root := t.TempDir()
+	first := newOpLock(root, time.Millisecond)
+	defer first.close()
+	second := newOpLock(root, time.Millisecond)
+	defer second.close()
+	ctx := testContext()
+
+	_, unlockFirst, err := first.acquire(ctx, lockShared)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	_, unlockSecond, err := second.acquire(ctx, lockShared)
+	if err != nil {
+		t.Fatalf("second shared acquire() error = %v", err)
+	}
+
+	if _, _, err := second.acquire(ctx, lockExclusive); err == nil {
+		t.Error("exclusive acquire() with shared holders error = nil, want busy")
+	}
+	unlockFirst()
+	unlockSecond()
+
+	_, unlock, err := second.acquire(ctx, lockExclusive)
+	if err != nil {
+		t.Fatalf("exclusive acquire() after release error = %v", err)
+	}
+	unlock()
+}
+
+func TestOpLock_Busy(t *testing.T) {
+	withFastLockPolling(t)
+	root := t.TempDir()
+	holder := newOpLock(root, time.Second)
+	defer holder.close()
+	waiter := newOpLock(root, 20*time.Millisecond)
+	defer waiter.close()
+	ctx := testContext()
+
+	_, unlock, err := holder.acquire(ctx, lockExclusive)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	defer unlock()
+
+	_, _, err = waiter.acquire(ctx, lockShared)
+	if !errors.Is(err, protatoerrors.ErrCacheBusy) {
+		t.Fatalf("acquire() error = %v, want ErrCacheBusy", err)
+	}
+	var busy *CacheBusyError
+	if !errors.As(err, &busy) || busy.Timeout != 20*time.Millisecond {
+		t.Errorf("acquire() error = %#v, want CacheBusyError with the timeout", err)
+	}
+}
+
+func TestOpLock_Nested(t *testing.T) {
+	lock := newOpLock(t.TempDir(), time.Millisecond)
+	defer lock.close()
+
+	ctx, unlock, err := lock.acquire(testContext(), lockExclusive)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	if _, nestedUnlock, err := lock.acquire(ctx, lockShared); err != nil {
+		t.Errorf("nested shared acquire() error = %v", err)
+	} else {
+		nestedUnlock()
+	}
+	unlock()
+
+	ctx, unlock, err = lock.acquire(testContext(), lockShared)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	defer unlock()
+	if _, _, err := lock.acquire(ctx, lockExclusive); err == nil || errors.Is(err, protatoerrors.ErrCacheBusy) {
+		t.Errorf("upgrading acquire() error = %v, want an upgrade error", err)
+	}
+}
+
+func TestOpen_SharedByProcesses(t *testing.T) {
+	ctx := testContext()
+	cacheDir := t.TempDir()
+	url := newSourceRegistry(t)
+
+	first, err := Open(ctx, cacheDir, url, OpenOptions{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer first.Close()
+	second, err := Open(ctx, cacheDir, url, OpenOptions{})
+	if err != nil {
+		t.Fatalf("second Open() error = %v", err)
+	}
+	defer second.Close()
+
+	if err := first.Refresh(ctx); err != nil {
+		t.Errorf("Refresh() error = %v", err)
+	}
+	if _, err := second.Snapshot(ctx); err != nil {
+		t.Errorf("Snapshot() error = %v", err)
+	}
+	if err := RemoveCache(ctx, cacheDir, url); err == nil {
+		t.Error("RemoveCache() of an open cache error = nil, want locked error")
+	}
+}
+
+func TestCache_EnsureSnapshotAvailable_LockModes(t *testing.T) {
+	withFastLockPolling(t)
+	ctx := testContext()
+	cacheDir := t.TempDir()
+	url := newSourceRegistry(t)
+
+	reader, err := Open(ctx, cacheDir, url, OpenOptions{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer reader.Close()
+	cache, err := Open(ctx, cacheDir, url, OpenOptions{LockTimeout: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("second Open() error = %v", err)
+	}
+	defer cache.Close()
+	snapshot, err := cache.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+
+	// Another process reading the cache does not stop the check for a present snapshot,
+	// but a missing one waits for the exclusive lock to fetch it
+	_, unlock, err := reader.lock(ctx, lockShared)
+	if err != nil {
+		t.Fatalf("lock() error = %v", err)
+	}
+	defer unlock()
+	if err := cache.EnsureSnapshotAvailable(ctx, snapshot); err != nil {
+		t.Errorf("EnsureSnapshotAvailable() of a present snapshot error = %v", err)
+	}
+	missing := git.Hash("0123456789abcdef0123456789abcdef01234567")
+	if err := cache.EnsureSnapshotAvailable(ctx, missing); !errors.Is(err, protatoerrors.ErrCacheBusy) {
+		t.Errorf("EnsureSnapshotAvailable() of a missing snapshot error = %v, want ErrCacheBusy", err)
+	}
+}
+
+func TestCache_ReadProjectFile_LockMode(t *testing.T) {
+	withFastLockPolling(t)
+	ctx := testContext()
+	cacheDir := t.TempDir()
+	url := newSourceRegistry(t)
+
+	compactor, err := Open(ctx, cacheDir, url, OpenOptions{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer compactor.Close()
+	cache, err := Open(ctx, cacheDir, url, OpenOptions{LockTimeout: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("second Open() error = %v", err)
+	}
+	defer cache.Close()
+
+	// Another process compacting the cache holds the lock exclusively, so reads wait for it
+	_, unlock, err := compactor.lock(ctx, lockExclusive)
+	if err != nil {
+		t.Fatalf("lock() error = %v", err)
+	}
+	defer unlock()
+	file := ProjectFile{Hash: git.Hash("0123456789abcdef0123456789abcdef01234567")}
+	if err := cache.ReadProjectFile(ctx, file, io.Discard); !errors.Is(err, protatoerrors.ErrCacheBusy) {
+		t.Errorf("ReadProjectFile() error = %v, want ErrCacheBusy", err)
+	}
+}
@@ -0,0 +1,351 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/internal/utils"
+)
+
+// Retry defaults for registries that do not configure them.
+const (
+	DefaultRetryAttempts   = 3
+	DefaultRetryBackoff    = 200 * time.Millisecond
+	DefaultRetryMaxBackoff = 5 * time.Second
+)
+
+// RetryConfig controls how fetches and pushes of the registry are retried.
+type RetryConfig struct {
+	Attempts   int           `yaml:"attempts"`   // Total attempts, including the first (defaults to DefaultRetryAttempts)
+	Backoff    time.Duration `yaml:"backoff"`    // Delay before the second attempt, doubled for each one after (defaults to DefaultRetryBackoff)
+	MaxBackoff time.Duration `yaml:"maxBackoff"` // Upper bound of a single delay (defaults to DefaultRetryMaxBackoff)
+}
+
+// attempts returns the total number of attempts.
+func (c RetryConfig) attempts() int {
+	if c.Attempts <= 0 {
+		return DefaultRetryAttempts
+	}
+	return c.Attempts
+}
+
+// delay returns how long to wait after the given failed attempt (1-based): exponential
+// backoff capped at MaxBackoff, with jitter so concurrent pushers do not retry in lockstep.
+func (c RetryConfig) delay(attempt int) time.Duration {
+	backoff, maxBackoff := c.Backoff, c.MaxBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultRetryMaxBackoff
+	}
+
+	d := backoff
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, maxBackoff)
+	// Full delay halved, plus up to the other half at random
+	return d/2 + rand.N(d/2+1)
+}
+
+// gitErrorKind classifies a failed fetch or push.
+type gitErrorKind int
+
+const (
+	gitErrorFatal     gitErrorKind = iota // Retrying cannot help, e.g. bad credentials
+	gitErrorTransient                     // Network trouble or a held lock; retry as is
+	gitErrorRejected                      // The remote branch moved; retry on top of the new tip
+)
+
+// Error output of git, matched the same way PushCmd.isRetryableError matches its errors.
+var (
+	fatalGitErrors = []string{
+		"Authentication failed",
+		"could not read Username",
+		"could not read Password",
+		"terminal prompts disabled",
+		"Permission denied",
+		"returned error: 401",
+		"returned error: 403",
+		"Repository not found",
+		"does not appear to be a git repository",
+	}
+	rejectedGitErrors = []string{
+		"non-fast-forward",
+		"fetch first",
+	}
+	transientGitErrors = []string{
+		"Could not resolve host",
+		"Connection timed out",
+		"Connection reset",
+		"Connection refused",
+		"Operation timed out",
+		"remote end hung up unexpectedly",
+		"early EOF",
+		"RPC failed",
+		"unable to access",
+		"index.lock",
+		"cannot lock ref",
+		"Unable to create",
+		"returned error: 5",
+		"TLS",
+	}
+)
+
+// classifyGitError decides whether a failed fetch or push is worth retrying.
+// Unrecognised errors are fatal.
+func classifyGitError(err error) gitErrorKind {
+	msg := err.Error()
+	switch {
+	case utils.ContainsAny(msg, fatalGitErrors...):
+		return gitErrorFatal
+	case utils.ContainsAny(msg, rejectedGitErrors...):
+		return gitErrorRejected
+	case utils.ContainsAny(msg, transientGitErrors...):
+		return gitErrorTransient
+	default:
+		return gitErrorFatal
+	}
+}
+
+// retryPolicy returns the retry settings of the registry config at the current snapshot.
+// The defaults apply when there is no snapshot yet or its config cannot be read.
+func (r *Cache) retryPolicy(ctx context.Context) RetryConfig {
+	config, err := r.Config(ctx, "")
+	if err != nil {
+		return RetryConfig{}
+	}
+	return config.Retry
+}
+
+// withRetry runs op until it succeeds, fails with an error that is not transient,
+// or runs out of attempts. It returns the last error.
+func (r *Cache) withRetry(ctx context.Context, what string, op func() error) error {
+	policy := r.retryPolicy(ctx)
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= policy.attempts() || classifyGitError(err) != gitErrorTransient {
+			return err
+		}
+		delay := policy.delay(attempt)
+		logger.Log(ctx).Warn().Err(err).Int("attempt", attempt).Dur("delay", delay).Msgf("Registry %s failed, retrying", what)
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// pushWithRetry pushes commit to the default branch, retrying transient failures. When the
+// push is rejected because the branch moved, the registry is re-fetched and the changes of
+// commit are re-applied onto the new tip before retrying. It returns the pushed commit.
+func (r *Cache) pushWithRetry(ctx context.Context, commit git.Hash) (git.Hash, error) {
+	policy := r.retryPolicy(ctx)
+	for attempt := 1; ; attempt++ {
+		err := r.repo.Push(ctx, pushOptions(r.getDefaultBranch(ctx), commit))
+		if err == nil {
+			return commit, nil
+		}
+		kind := classifyGitError(err)
+		if attempt >= policy.attempts() || kind == gitErrorFatal {
+			return "", err
+		}
+
+		delay := policy.delay(attempt)
+		logger.Log(ctx).Warn().Err(err).Int("attempt", attempt).Dur("delay", delay).Msg("Registry push failed, retrying")
+		if err := sleepContext(ctx, delay); err != nil {
+			return "", err
+		}
+
+		if kind == gitErrorRejected {
+			rebased, rebaseErr := r.rebaseOnTip(ctx, commit)
+			if rebaseErr != nil {
+				logger.Log(ctx).Debug().Err(rebaseErr).Msg("Cannot re-apply commit onto the registry tip")
+				return "", err
+			}
+			commit = rebased
+		}
+	}
+}
+
+// rebaseOnTip fetches the registry and re-applies the changes commit makes to its parent onto
+// the new tip. It refuses when the tip changed any project that commit changes, when one of
+// those projects can no longer be claimed, or when the registry validates pushes: the caller
+// has to rebuild and check the commit again in those cases.
+func (r *Cache) rebaseOnTip(ctx context.Context, commit git.Hash) (git.Hash, error) {
+	var buf bytes.Buffer
+	if err := r.repo.ReadObject(ctx, git.CommitType, commit, &buf); err != nil {
+		return "", fmt.Errorf("read commit %s: %w", commit.Short(), err)
+	}
+	parsed, err := git.ParseCommit(buf.Bytes())
+	if err != nil {
+		return "", err
+	}
+	if len(parsed.Parents) != 1 {
+		return "", fmt.Errorf("commit %s has %d parents", commit.Short(), len(parsed.Parents))
+	}
+	base := parsed.Parents[0]
+
+	if err := r.fetch(ctx); err != nil {
+		return "", fmt.Errorf("refresh registry: %w", err)
+	}
+	tip, err := r.Snapshot(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get snapshot: %w", err)
+	}
+	if tip == base {
+		return commit, nil
+	}
+
+	config, err := r.Config(ctx, tip)
+	if err != nil {
+		return "", err
+	}
+	if config.ValidateOnPush {
+		return "", fmt.Errorf("registry validates pushes")
+	}
+
+	ours, err := r.repo.DiffTree(ctx, git.Treeish(base), git.Treeish(commit), git.DiffOptions{})
+	if err != nil {
+		return "", err
+	}
+	theirs, err := r.repo.DiffTree(ctx, git.Treeish(base), git.Treeish(tip), git.DiffOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	projects, err := r.changedProjects(ctx, base, commit, ours)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range theirs {
+		for _, p := range []string{entry.Path, entry.OldPath} {
+			if p == "" {
+				continue
+			}
+			if p == constants.RegistryConfigFile {
+				return "", fmt.Errorf("registry config changed")
+			}
+			for project := range projects {
+				if strings.HasPrefix(p, protosPath(string(project))+"/") {
+					return "", fmt.Errorf("project %s changed at %s", project, tip.Short())
+				}
+			}
+		}
+	}
+	for project, url := range projects {
+		if url == "" {
+			continue // Deleted by commit
+		}
+		if err := r.CheckProjectClaim(ctx, tip, url, string(project)); err != nil {
+			return "", err
+		}
+	}
+
+	update, err := r.replayUpdate(ctx, commit, tip, ours)
+	if err != nil {
+		return "", err
+	}
+	newTree, err := r.repo.UpdateTree(ctx, update)
+	if err != nil {
+		return "", fmt.Errorf("update tree: %w", err)
+	}
+	rebased, err := r.repo.CommitTree(ctx, git.CommitTreeRequest{
+		Tree:    newTree,
+		Parents: []git.Hash{tip},
+		Message: parsed.Message,
+		Author:  parsed.Author,
+	})
+	if err != nil {
+		return "", fmt.Errorf("create commit: %w", err)
+	}
+
+	logger.Log(ctx).Info().Str("from", commit.Short()).Str("to", rebased.Short()).Str("base", tip.Short()).Msg("Re-applied registry commit onto new tip")
+	return rebased, nil
+}
+
+// changedProjects returns the projects that the diff from base to commit touches, with the
+// repository URL each is owned by at commit ("" for projects commit deletes).
+func (r *Cache) changedProjects(ctx context.Context, base, commit git.Hash, diff []git.DiffEntry) (map[ProjectPath]string, error) {
+	projects := make(map[ProjectPath]string)
+	seen := make(map[string]bool)
+	for _, entry := range diff {
+		for _, p := range []string{entry.Path, entry.OldPath} {
+			rel, ok := strings.CutPrefix(p, constants.ProtosDir+"/")
+			if !ok {
+				continue
+			}
+			dir := path.Dir(rel)
+			if seen[dir] {
+				continue
+			}
+			seen[dir] = true
+
+			if res, err := r.findProjectByPath(ctx, commit, dir); err == nil {
+				projects[res.Project.Path] = res.Project.RepositoryURL
+				continue
+			}
+			res, err := r.findProjectByPath(ctx, base, dir)
+			if err != nil {
+				return nil, fmt.Errorf("no project owns %s", p)
+			}
+			if _, ok := projects[res.Project.Path]; !ok {
+				projects[res.Project.Path] = ""
+			}
+		}
+	}
+	return projects, nil
+}
+
+// replayUpdate returns the tree update that applies diff, taken from commit, to the tree of tip.
+func (r *Cache) replayUpdate(ctx context.Context, commit, tip git.Hash, diff []git.DiffEntry) (git.UpdateTreeRequest, error) {
+	tipTree, err := r.repo.RevHash(ctx, string(tip)+"^{tree}")
+	if err != nil {
+		return git.UpdateTreeRequest{}, fmt.Errorf("get current tree: %w", err)
+	}
+	update := git.UpdateTreeRequest{Tree: tipTree}
+
+	var written []string
+	for _, entry := range diff {
+		if entry.OldPath != "" {
+			update.Deletes = append(update.Deletes, entry.OldPath)
+		}
+		if entry.Status == git.DiffDeleted {
+			update.Deletes = append(update.Deletes, entry.Path)
+			continue
+		}
+		written = append(written, entry.Path)
+	}
+	if len(written) == 0 {
+		return update, nil
+	}
+
+	entries, err := r.repo.ReadTree(ctx, git.Treeish(commit), git.ReadTreeOptions{Paths: written})
+	if err != nil {
+		return git.UpdateTreeRequest{}, readTreeError(err)
+	}
+	for _, entry := range entries {
+		update.Upserts = append(update.Upserts, git.TreeUpsert{Path: entry.Path, Blob: entry.Hash, Mode: entry.Mode})
+	}
+	return update, nil
+}
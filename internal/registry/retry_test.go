@@ -0,0 +1,140 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rahulagarwal0605/protato/internal/git"
+)
+
+func TestClassifyGitError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  string
+		want gitErrorKind
+	}{
+		{name: "unresolved host", err: "exit status 128: fatal: unable to access 'https://example.com/registry.git/': Could not resolve host: example.com", want: gitErrorTransient},
+		{name: "hung up", err: "exit status 128: fatal: the remote end hung up unexpectedly", want: gitErrorTransient},
+		{name: "server error", err: "exit status 128: fatal: unable to access 'https://example.com/r.git/': The requested URL returned error: 502", want: gitErrorTransient},
+		{name: "ref lock", err: "exit status 1: error: cannot lock ref 'refs/heads/main': is at abc but expected def", want: gitErrorTransient},
+		{name: "fetch first", err: "exit status 1: ! [rejected]        abc -> main (fetch first)\nerror: failed to push some refs", want: gitErrorRejected},
+		{name: "non-fast-forward", err: "exit status 1: ! [rejected]        abc -> main (non-fast-forward)", want: gitErrorRejected},
+		{name: "bad credentials", err: "exit status 128: remote: HTTP Basic: Access denied\nfatal: Authentication failed for 'https://example.com/r.git/'", want: gitErrorFatal},
+		{name: "forbidden", err: "exit status 128: fatal: unable to access 'https://example.com/r.git/': The requested URL returned error: 403", want: gitErrorFatal},
+		{name: "no credentials", err: "exit status 128: fatal: could not read Username for 'https://example.com': terminal prompts disabled", want: gitErrorFatal},
+		{name: "unknown", err: "push failed", want: gitErrorFatal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyGitError(errors.New(tt.err)); got != tt.want {
+				t.Errorf("classifyGitError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryConfig_Delay(t *testing.T) {
+	policy := RetryConfig{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	tests := []struct {
+		attempt int
+		full    time.Duration
+	}{
+		{attempt: 1, full: 100 * time.Millisecond},
+		{attempt: 2, full: 200 * time.Millisecond},
+		{attempt: 4, full: 800 * time.Millisecond},
+		{attempt: 5, full: time.Second},
+		{attempt: 10, full: time.Second},
+	}
+
+	for _, tt := range tests {
+		for range 20 {
+			if got := policy.delay(tt.attempt); got < tt.full/2 || got > tt.full {
+				t.Errorf("delay(%d) = %v, want between %v and %v", tt.attempt, got, tt.full/2, tt.full)
+			}
+		}
+	}
+
+	if got := (RetryConfig{}).attempts(); got != DefaultRetryAttempts {
+		t.Errorf("attempts() = %d, want default %d", got, DefaultRetryAttempts)
+	}
+}
+
+// newRetryTestCache returns a cache over repo whose registry config at the current
+// snapshot retries up to attempts times without a noticeable delay.
+func newRetryTestCache(repo *mockRepository, attempts int) *Cache {
+	if repo.revHashMap == nil {
+		repo.revHashMap = make(map[string]git.Hash)
+	}
+	repo.revHashMap["FETCH_HEAD"] = "snapshot123"
+	cache := newMockCache(repo, "https://github.com/test/registry.git")
+	cache.configs = map[git.Hash]*Config{
+		"snapshot123": {Retry: RetryConfig{Attempts: attempts, Backoff: time.Millisecond}},
+	}
+	return cache
+}
+
+func TestCache_Push_Retry(t *testing.T) {
+	unreachable := errors.New("exit status 128: fatal: unable to access 'https://github.com/test/registry.git/': Could not resolve host: github.com")
+	denied := errors.New("exit status 128: fatal: Authentication failed for 'https://github.com/test/registry.git/'")
+
+	tests := []struct {
+		name      string
+		errs      []error // Result of each push attempt; attempts past the end succeed
+		attempts  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "succeeds on second attempt", errs: []error{unreachable}, attempts: 3, wantCalls: 2},
+		{name: "gives up after attempts", errs: []error{unreachable, unreachable, unreachable}, attempts: 2, wantCalls: 2, wantErr: true},
+		{name: "auth errors are not retried", errs: []error{denied}, attempts: 3, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepository{}
+			repo.pushFunc = func(git.PushOptions) error {
+				if n := len(repo.pushCalls); n <= len(tt.errs) {
+					return tt.errs[n-1]
+				}
+				return nil
+			}
+			cache := newRetryTestCache(repo, tt.attempts)
+
+			pushed, err := cache.Push(testContext(), "abc123")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Push() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(repo.pushCalls) != tt.wantCalls {
+				t.Errorf("Push() made %d attempts, want %d", len(repo.pushCalls), tt.wantCalls)
+			}
+			if !tt.wantErr && pushed != "abc123" {
+				t.Errorf("Push() = %s, want abc123", pushed)
+			}
+			for _, opts := range repo.pushCalls {
+				if !opts.Atomic {
+					t.Error("Push() did not push atomically")
+				}
+			}
+		})
+	}
+}
+
+func TestCache_Refresh_Retry(t *testing.T) {
+	repo := &mockRepository{}
+	repo.fetchFunc = func() error {
+		if len(repo.fetchCalls) == 1 {
+			return errors.New("exit status 128: fatal: the remote end hung up unexpectedly")
+		}
+		return nil
+	}
+	cache := newRetryTestCache(repo, 3)
+
+	if err := cache.Refresh(testContext()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if len(repo.fetchCalls) != 2 {
+		t.Errorf("Refresh() made %d fetches, want 2", len(repo.fetchCalls))
+	}
+}
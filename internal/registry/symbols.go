@@ -0,0 +1,198 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+	"github.com/rahulagarwal0605/protato/internal/errors"
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+)
+
+// symbolCandidate is a registry file whose package could define a symbol.
+type symbolCandidate struct {
+	project ProjectPath
+	file    string // Relative to project
+	pkg     string
+}
+
+// FindSymbol finds the project and file defining a fully-qualified message, enum or service name
+// (e.g., "payments.v1.Payment"). Files whose directory matches a package prefix of the name are
+// searched first, since proto packages usually mirror their directories; the rest are only read
+// when none of those defines it. Files read are narrowed to those whose package is a prefix of
+// the name, then compiled most-specific package first. Returns errors.ErrNotFound if no file defines it.
+func (r *Cache) FindSymbol(ctx context.Context, req *FindSymbolRequest) (ProjectPath, string, error) {
+	ctx, unlock, err := r.lock(ctx, lockShared)
+	if err != nil {
+		return "", "", err
+	}
+	defer unlock()
+
+	name := strings.TrimPrefix(req.Name, ".")
+	snapshot, err := r.getOrCreateSnapshot(ctx, req.Snapshot)
+	if err != nil {
+		return "", "", err
+	}
+
+	files, err := r.listProtoFiles(ctx, snapshot)
+	if err != nil {
+		return "", "", err
+	}
+
+	likely, rest := partitionByPackageDir(files, name)
+	for _, group := range [][]ProjectFile{likely, rest} {
+		candidates, err := r.findSymbolCandidates(ctx, group, name, req.ParsePackage)
+		if err != nil {
+			return "", "", err
+		}
+		for _, c := range candidates {
+			importPath := path.Join(string(c.project), c.file)
+			found, err := r.fileDefinesSymbol(ctx, snapshot, importPath, protoreflect.FullName(name))
+			if err != nil {
+				logger.Log(ctx).Debug().Err(err).Str("file", importPath).Msg("Skipping file that does not compile")
+				continue
+			}
+			if found {
+				return c.project, c.file, nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("symbol %s: %w", req.Name, errors.ErrNotFound)
+}
+
+// listProtoFiles lists the proto files of every project at snapshot without reading them.
+func (r *Cache) listProtoFiles(ctx context.Context, snapshot git.Hash) ([]ProjectFile, error) {
+	projects, err := r.ListProjects(ctx, &ListProjectsOptions{Snapshot: snapshot})
+	if err != nil {
+		return nil, err
+	}
+
+	var protoFiles []ProjectFile
+	for _, project := range projects {
+		files, err := r.ListProjectFiles(ctx, &ListProjectFilesRequest{Project: project, Snapshot: snapshot})
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files.Files {
+			if path.Ext(f.Path) == constants.ProtoFileExt {
+				protoFiles = append(protoFiles, f)
+			}
+		}
+	}
+	return protoFiles, nil
+}
+
+// partitionByPackageDir splits files into those whose import directory, read as a dotted package,
+// is or ends with a package prefix of name, and the rest.
+func partitionByPackageDir(files []ProjectFile, name string) (likely, rest []ProjectFile) {
+	for _, f := range files {
+		dir := strings.ReplaceAll(path.Dir(path.Join(string(f.Project), f.Path)), "/", ".")
+		if dirMayDefine(dir, name) {
+			likely = append(likely, f)
+		} else {
+			rest = append(rest, f)
+		}
+	}
+	return likely, rest
+}
+
+// dirMayDefine reports whether some package prefix of name is dir or a dotted suffix of it.
+func dirMayDefine(dir, name string) bool {
+	for i := strings.LastIndex(name, "."); i > 0; i = strings.LastIndex(name[:i], ".") {
+		pkg := name[:i]
+		if dir == pkg || strings.HasSuffix(dir, "."+pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// findSymbolCandidates reads files and keeps those whose package is a prefix of name, longest
+// package first. Without parse every file is kept, leaving compilation to decide.
+func (r *Cache) findSymbolCandidates(ctx context.Context, files []ProjectFile, name string, parse PackageParser) ([]symbolCandidate, error) {
+	var candidates []symbolCandidate
+	if parse == nil {
+		for _, f := range files {
+			candidates = append(candidates, symbolCandidate{project: f.Project, file: f.Path})
+		}
+		return candidates, nil
+	}
+
+	err := r.ReadProjectFiles(ctx, files, func(f ProjectFile, content io.Reader) error {
+		data, err := io.ReadAll(content)
+		if err != nil {
+			return fmt.Errorf("read %s/%s: %w", f.Project, f.Path, err)
+		}
+		pkg := parse(data)
+		if packageMayDefine(pkg, name) {
+			candidates = append(candidates, symbolCandidate{project: f.Project, file: f.Path, pkg: pkg})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read proto files: %w", err)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(candidates[i].pkg) > len(candidates[j].pkg)
+	})
+	return candidates, nil
+}
+
+// packageMayDefine reports whether a file in pkg could define the fully-qualified name.
+func packageMayDefine(pkg, name string) bool {
+	if pkg == "" {
+		return true
+	}
+	return strings.HasPrefix(name, pkg+".")
+}
+
+// fileDefinesSymbol compiles a registry file and reports whether it defines name.
+// Imports are resolved from the same snapshot.
+func (r *Cache) fileDefinesSymbol(ctx context.Context, snapshot git.Hash, importPath string, name protoreflect.FullName) (bool, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: func(p string) (io.ReadCloser, error) {
+				return r.openSnapshotFile(ctx, snapshot, p)
+			},
+		}),
+	}
+
+	files, err := compiler.Compile(ctx, importPath)
+	if err != nil {
+		return false, err
+	}
+	return files[0].FindDescriptorByName(name) != nil, nil
+}
+
+// openSnapshotFile opens a proto file by its registry import path at a snapshot.
+func (r *Cache) openSnapshotFile(ctx context.Context, snapshot git.Hash, importPath string) (io.ReadCloser, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries, err := r.repo.ReadTree(ctx, git.Treeish(snapshot), git.ReadTreeOptions{
+		Paths: []string{protosPath(importPath)},
+	})
+	if err != nil {
+		return nil, readTreeError(err)
+	}
+	for _, entry := range entries {
+		if isBlobType(entry.Type) && entry.Path == protosPath(importPath) {
+			var buf bytes.Buffer
+			if err := r.repo.ReadObject(ctx, git.BlobType, entry.Hash, &buf); err != nil {
+				return nil, err
+			}
+			return io.NopCloser(&buf), nil
+		}
+	}
+	return nil, errors.ErrNotFound
+}
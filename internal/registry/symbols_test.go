@@ -0,0 +1,201 @@
+package registry
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	protatoerrors "github.com/rahulagarwal0605/protato/internal/errors"
+	"github.com/rahulagarwal0605/protato/internal/git"
+)
+
+// newTreeMockRepository serves a registry tree from a map of paths to content.
+// Blob hashes are the file paths.
+func newTreeMockRepository(files map[string]string) *mockRepository {
+	return &mockRepository{
+		readTreeFunc: func(opts git.ReadTreeOptions) ([]git.TreeEntry, error) {
+			var entries []git.TreeEntry
+			for p := range files {
+				if p == opts.Paths[0] || strings.HasPrefix(p, opts.Paths[0]+"/") {
+					entries = append(entries, git.TreeEntry{Type: git.BlobType, Path: p, Hash: git.Hash(p)})
+				}
+			}
+			return entries, nil
+		},
+		readObjFunc: func(hash git.Hash) []byte {
+			return []byte(files[string(hash)])
+		},
+	}
+}
+
+// testPackagePattern stands in for protoc.ScanPackage, which registry tests cannot import.
+var testPackagePattern = regexp.MustCompile(`(?m)^package\s+([\w.]+);`)
+
+func testParsePackage(content []byte) string {
+	if m := testPackagePattern.FindSubmatch(content); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+func TestCache_FindSymbol(t *testing.T) {
+	repo := newTreeMockRepository(map[string]string{
+		"protos/team/common/protato.root.yaml": "",
+		"protos/team/common/v1/money.proto": `syntax = "proto3";
+package team.common.v1;
+message Money { int64 units = 1; }
+`,
+		"protos/team/payments/protato.root.yaml": "",
+		"protos/team/payments/v1/payment.proto": `syntax = "proto3";
+package team.payments.v1;
+import "team/common/v1/money.proto";
+import "google/protobuf/timestamp.proto";
+message Payment {
+  enum Status { STATUS_UNSPECIFIED = 0; }
+  team.common.v1.Money amount = 1;
+  google.protobuf.Timestamp created_at = 2;
+}
+`,
+		"protos/team/payments/v1/service.proto": `syntax = "proto3";
+package team.payments.v1;
+import "team/payments/v1/payment.proto";
+service PaymentService { rpc Get(Payment) returns (Payment); }
+`,
+		"protos/team/misc/protato.root.yaml": "",
+		"protos/team/misc/refunds.proto": `syntax = "proto3";
+package team.payments.v1;
+message Refund {}
+`,
+	})
+	cache := newMockCache(repo, "https://github.com/test/registry.git")
+	ctx := testContext()
+
+	tests := []struct {
+		name        string
+		symbol      string
+		wantProject ProjectPath
+		wantFile    string
+		wantErr     error
+	}{
+		{
+			name:        "message",
+			symbol:      "team.payments.v1.Payment",
+			wantProject: "team/payments",
+			wantFile:    "v1/payment.proto",
+		},
+		{
+			name:        "message in another project",
+			symbol:      ".team.common.v1.Money",
+			wantProject: "team/common",
+			wantFile:    "v1/money.proto",
+		},
+		{
+			name:        "nested enum",
+			symbol:      "team.payments.v1.Payment.Status",
+			wantProject: "team/payments",
+			wantFile:    "v1/payment.proto",
+		},
+		{
+			name:        "service",
+			symbol:      "team.payments.v1.PaymentService",
+			wantProject: "team/payments",
+			wantFile:    "v1/service.proto",
+		},
+		{
+			name:        "package not matching its directory",
+			symbol:      "team.payments.v1.Refund",
+			wantProject: "team/misc",
+			wantFile:    "refunds.proto",
+		},
+		{
+			name:    "unknown symbol",
+			symbol:  "team.payments.v1.Chargeback",
+			wantErr: protatoerrors.ErrNotFound,
+		},
+		{
+			name:    "unknown package",
+			symbol:  "other.v1.Thing",
+			wantErr: protatoerrors.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project, file, err := cache.FindSymbol(ctx, &FindSymbolRequest{Name: tt.symbol, Snapshot: "snap123", ParsePackage: testParsePackage})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FindSymbol() error = %v, want %v", err, tt.wantErr)
+			}
+			if project != tt.wantProject || file != tt.wantFile {
+				t.Errorf("FindSymbol() = %s, %s; want %s, %s", project, file, tt.wantProject, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestCache_FindSymbol_ReadsMatchingDirsFirst(t *testing.T) {
+	repo := newTreeMockRepository(map[string]string{
+		"protos/team/common/protato.root.yaml": "",
+		"protos/team/common/v1/money.proto":    "syntax = \"proto3\";\npackage team.common.v1;\nmessage Money {}\n",
+		"protos/team/other/protato.root.yaml":  "",
+		"protos/team/other/v1/other.proto":     "syntax = \"proto3\";\npackage team.other.v1;\nmessage Other {}\n",
+	})
+	var read []string
+	readObj := repo.readObjFunc
+	repo.readObjFunc = func(hash git.Hash) []byte {
+		read = append(read, string(hash))
+		return readObj(hash)
+	}
+	cache := newMockCache(repo, "https://github.com/test/registry.git")
+
+	project, _, err := cache.FindSymbol(testContext(), &FindSymbolRequest{Name: "team.common.v1.Money", Snapshot: "snap123", ParsePackage: testParsePackage})
+	if err != nil || project != "team/common" {
+		t.Fatalf("FindSymbol() = %s, %v; want team/common", project, err)
+	}
+	for _, p := range read {
+		if strings.HasPrefix(p, "protos/team/other/") {
+			t.Errorf("FindSymbol() read %s, outside the directories matching the package", p)
+		}
+	}
+}
+
+func TestDirMayDefine(t *testing.T) {
+	tests := []struct {
+		dir  string
+		name string
+		want bool
+	}{
+		{dir: "team.v1", name: "team.v1.Message", want: true},
+		{dir: "team.v1", name: "team.v1.Message.Nested", want: true},
+		{dir: "acme.team.v1", name: "team.v1.Message", want: true},
+		{dir: "team", name: "team.v1.Message", want: true},
+		{dir: "myteam.v1", name: "team.v1.Message", want: false},
+		{dir: "team.v1", name: "Message", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := dirMayDefine(tt.dir, tt.name); got != tt.want {
+			t.Errorf("dirMayDefine(%q, %q) = %v, want %v", tt.dir, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPackageMayDefine(t *testing.T) {
+	tests := []struct {
+		pkg  string
+		name string
+		want bool
+	}{
+		{pkg: "team.v1", name: "team.v1.Message", want: true},
+		{pkg: "team.v1", name: "team.v1.Message.Nested", want: true},
+		{pkg: "team.v1", name: "team.v10.Message", want: false},
+		{pkg: "team.v1", name: "team.v1", want: false},
+		{pkg: "", name: "Message", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := packageMayDefine(tt.pkg, tt.name); got != tt.want {
+			t.Errorf("packageMayDefine(%q, %q) = %v, want %v", tt.pkg, tt.name, got, tt.want)
+		}
+	}
+}
@@ -2,7 +2,16 @@
 package registry
 
 import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
+	"github.com/rahulagarwal0605/protato/internal/errors"
 	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/utils"
 )
 
 // ProjectPath represents a project path in the registry.
@@ -15,14 +24,96 @@ func (p ProjectPath) String() string {
 
 // Project represents a project in the registry.
 type Project struct {
-	Path          ProjectPath // Project path (e.g., "team/service")
-	Commit        git.Hash    // Source repository commit
-	RepositoryURL string      // Source repository URL
+	Path            ProjectPath // Project path (e.g., "team/service")
+	Commit          git.Hash    // Source repository commit
+	RepositoryURL   string      // Source repository URL
+	RequireApproval bool        // Changes must carry a recorded approval before they are accepted
+}
+
+// ClaimError explains why a project cannot be claimed.
+// Reason is one of the claim errors of the errors package, or an error matching one.
+type ClaimError struct {
+	Reason  error
+	Message string
+	Owner   *Project // Project holding the conflicting path, when it was looked up
+}
+
+// Error returns the message.
+func (e *ClaimError) Error() string {
+	return e.Message
+}
+
+// Explanation tells the user who holds the conflicting path, or returns "" when that is unknown.
+func (e *ClaimError) Explanation() string {
+	if e.Owner == nil {
+		return ""
+	}
+	var ownership *OwnershipError
+	if stderrors.As(e.Reason, &ownership) && ownership.RequesterURL != "" {
+		return fmt.Sprintf("owned by %s at %s, you are %s; contact them or choose another path", e.Owner.RepositoryURL, e.Owner.Commit.Short(), ownership.RequesterURL)
+	}
+	return fmt.Sprintf("owned by %s at %s; contact them or choose another path", e.Owner.RepositoryURL, e.Owner.Commit.Short())
+}
+
+// Unwrap returns the reason, so callers can match it with errors.Is.
+func (e *ClaimError) Unwrap() error {
+	return e.Reason
+}
+
+// OwnershipError reports that a project is owned by another repository than the requester.
+// It matches errors.ErrOwnershipConflict with errors.Is.
+type OwnershipError struct {
+	Path         ProjectPath // Project whose ownership was checked
+	OwnerURL     string      // Repository that owns the project
+	RequesterURL string      // Repository that asked to change it
+}
+
+// Error returns the message.
+func (e *OwnershipError) Error() string {
+	return fmt.Sprintf("%s: %s is owned by %s, not %s", errors.ErrOwnershipConflict, e.Path, e.OwnerURL, e.RequesterURL)
+}
+
+// Is matches errors.ErrOwnershipConflict.
+func (e *OwnershipError) Is(target error) bool {
+	return target == errors.ErrOwnershipConflict
+}
+
+// ProjectNotFoundError reports that no registry project covers a path.
+// It matches errors.ErrNotFound with errors.Is.
+type ProjectNotFoundError struct {
+	Path string // Path that was looked up
+}
+
+// Error returns the message.
+func (e *ProjectNotFoundError) Error() string {
+	return fmt.Sprintf("%s: %s", errors.ErrNotFound, e.Path)
+}
+
+// Is matches errors.ErrNotFound.
+func (e *ProjectNotFoundError) Is(target error) bool {
+	return target == errors.ErrNotFound
+}
+
+// SnapshotNotFoundError reports a snapshot commit that the registry does not have.
+// It matches errors.ErrSnapshotNotFound with errors.Is.
+type SnapshotNotFoundError struct {
+	Hash git.Hash // Snapshot that was asked for
+}
+
+// Error returns the message.
+func (e *SnapshotNotFoundError) Error() string {
+	return fmt.Sprintf("%s: %s", errors.ErrSnapshotNotFound, e.Hash)
+}
+
+// Is matches errors.ErrSnapshotNotFound.
+func (e *SnapshotNotFoundError) Is(target error) bool {
+	return target == errors.ErrSnapshotNotFound
 }
 
 // ProjectMeta represents the protato.root.yaml file.
 type ProjectMeta struct {
-	Git ProjectMetaGit `yaml:"git"`
+	Git             ProjectMetaGit `yaml:"git"`
+	RequireApproval bool           `yaml:"requireApproval,omitempty"`
 }
 
 // ProjectMetaGit contains Git-specific metadata.
@@ -46,8 +137,19 @@ type LookupProjectResponse struct {
 
 // ListProjectsOptions contains options for listing projects.
 type ListProjectsOptions struct {
-	Prefix   string   // Filter by path prefix
-	Snapshot git.Hash // Registry snapshot
+	Prefix   string      // Filter by path prefix
+	Snapshot git.Hash    // Registry snapshot
+	Owner    string      // Filter by owning repository URL (compared normalized)
+	Limit    int         // Maximum number of projects to return (0 for all)
+	Offset   int         // Number of projects to skip, after applying After
+	After    ProjectPath // Cursor: only return projects sorted after this path
+	MaxDepth int         // Maximum path components below Prefix (0 for any depth)
+}
+
+// ListProjectsResponse is one page of projects.
+type ListProjectsResponse struct {
+	Projects []ProjectPath // Sorted by path
+	Next     ProjectPath   // Cursor for the next page; "" when this is the last page
 }
 
 // ListProjectFilesRequest contains parameters for listing project files.
@@ -56,6 +158,22 @@ type ListProjectFilesRequest struct {
 	Snapshot git.Hash
 }
 
+// ProjectHistoryRequest contains parameters for listing the history of a project.
+type ProjectHistoryRequest struct {
+	Project        ProjectPath
+	File           string // File of the project to limit the history to, followed through renames; "" for the whole project
+	IncludeDeleted bool   // Trace a File deleted from the project, from the commit that deleted it
+	Limit          int    // Maximum number of commits to list; 0 lists all
+}
+
+// ProjectRevision is a registry commit that changed a project.
+type ProjectRevision struct {
+	Snapshot git.Hash   // Registry commit
+	Author   git.Author // Who pushed the change
+	Date     time.Time  // When it was pushed
+	Message  string     // Commit message
+}
+
 // ListProjectFilesResponse contains the result of listing project files.
 type ListProjectFilesResponse struct {
 	Files    []ProjectFile
@@ -68,14 +186,191 @@ type ProjectFile struct {
 	Project  ProjectPath // Project path
 	Path     string      // Relative to project
 	Hash     git.Hash    // Blob hash
+	Mode     uint32      // File mode from the registry tree (e.g., 0100644)
 }
 
 // SetProjectRequest contains parameters for updating a project.
 type SetProjectRequest struct {
-	Project  *Project           // Project metadata
-	Files    []LocalProjectFile // Complete file list
-	Snapshot git.Hash           // Base snapshot
-	Author   *git.Author        // Required: Git author/committer for commits
+	Project     *Project           // Project metadata
+	Files       []LocalProjectFile // Complete file list
+	Snapshot    git.Hash           // Base snapshot
+	Author      *git.Author        // Required: Git author/committer for commits
+	MaxFileSize int64              // Optional: reject files larger than this many bytes (0 disables)
+	Validate    ProjectValidator   // Optional: compiles the project before it is accepted when the registry sets validateOnPush
+	Approval    ApprovalSource     // Optional: supplies the approval for projects that require one
+	Symlinks    SymlinkPolicy      // How symlinked local files are published
+}
+
+// SymlinkPolicy decides how symlinked local files are published. A published symlink
+// always carries its target's content; git symlinks are never written to the registry.
+type SymlinkPolicy struct {
+	Dereference bool   // Publish symlinked files of every type; symlinked .proto files are always published
+	Root        string // Targets must resolve inside this directory (e.g., the workspace root); "" allows any target
+}
+
+// ProjectValidator checks that a project compiles at a snapshot.
+// It is supplied by callers because compilation lives in the protoc package, which depends on registry.
+type ProjectValidator func(ctx context.Context, reg CacheInterface, snapshot git.Hash, project ProjectPath) error
+
+// ImportRewriter returns proto file content with imports of files below the from directory
+// rewritten to the same files below to. It is supplied by callers because import parsing lives
+// in the protoc package, which depends on registry.
+type ImportRewriter func(content []byte, from, to string) []byte
+
+// PackageParser returns the package declared in proto source, or "" if there is none.
+// It is supplied by callers because proto parsing lives in the protoc package, which depends on registry.
+type PackageParser func(content []byte) string
+
+// FindSymbolRequest contains parameters for finding the file that defines a symbol.
+type FindSymbolRequest struct {
+	Name         string        // Fully-qualified message, enum or service name (e.g., "payments.v1.Payment")
+	Snapshot     git.Hash      // Optional: snapshot to search; defaults to the current one
+	ParsePackage PackageParser // Optional: narrows the files compiled to those whose package could define Name
+}
+
+// ApprovalSource returns the recorded approval (for example a co-signer's name) for a push
+// of project, or "" when there is none. It is supplied by callers so the approval can come
+// from a flag, a commit trailer or an external review system.
+type ApprovalSource func(ctx context.Context, project ProjectPath) (string, error)
+
+// Config is the registry-wide configuration stored in protato.registry.yaml.
+type Config struct {
+	APIVersion        int          `yaml:"apiVersion"`        // Schema version of the file; absent (0) in registries created before versioning
+	ValidateOnPush    bool         `yaml:"validateOnPush"`    // Compile each pushed project against the registry before accepting it
+	AllowedExtensions []string     `yaml:"allowedExtensions"` // File extensions projects may contain (defaults to DefaultAllowedExtensions)
+	MetadataFiles     []string     `yaml:"metadataFiles"`     // Extra file names that are never project files; protato's own metadata files never are
+	Retry             RetryConfig  `yaml:"retry"`             // How fetches and pushes of the registry are retried
+	Deepen            DeepenConfig `yaml:"deepen"`            // How the shallow cache is deepened to reach an older snapshot
+}
+
+// CurrentConfigAPIVersion is the newest protato.registry.yaml schema this build understands.
+// Configs of older versions are migrated to it when loaded.
+const CurrentConfigAPIVersion = 1
+
+// configMigrations upgrade a config in memory: entry i turns version i into version i+1.
+var configMigrations = []func(*Config){
+	// v0 is the unversioned format, which v1 keeps unchanged
+	func(*Config) {},
+}
+
+// migrate upgrades c to CurrentConfigAPIVersion. A version newer than this build
+// supports is an error, since its settings cannot be read reliably.
+func (c *Config) migrate() error {
+	switch {
+	case c.APIVersion < 0:
+		return fmt.Errorf("invalid apiVersion %d", c.APIVersion)
+	case c.APIVersion > CurrentConfigAPIVersion:
+		return fmt.Errorf("%w: apiVersion %d is newer than the supported %d; upgrade protato",
+			errors.ErrUnsupportedConfigVersion, c.APIVersion, CurrentConfigAPIVersion)
+	}
+	for c.APIVersion < CurrentConfigAPIVersion {
+		configMigrations[c.APIVersion](c)
+		c.APIVersion++
+	}
+	return nil
+}
+
+// Deepen defaults for registries that do not configure them.
+const (
+	DefaultDeepenStep     = 100
+	DefaultDeepenMaxSteps = 3
+)
+
+// DeepenConfig controls how far the shallow registry cache is deepened, one step at a time,
+// to reach a snapshot that is not in it. The whole history is only fetched when Unshallow is set.
+type DeepenConfig struct {
+	Step      int  `yaml:"step"`      // Commits fetched per step (defaults to DefaultDeepenStep)
+	MaxSteps  int  `yaml:"maxSteps"`  // Steps tried before giving up (defaults to DefaultDeepenMaxSteps)
+	Unshallow bool `yaml:"unshallow"` // Fetch the whole history when the steps do not reach the snapshot
+}
+
+// step returns the number of commits fetched per step.
+func (c DeepenConfig) step() int {
+	if c.Step <= 0 {
+		return DefaultDeepenStep
+	}
+	return c.Step
+}
+
+// maxSteps returns the number of steps tried before giving up.
+func (c DeepenConfig) maxSteps() int {
+	if c.MaxSteps <= 0 {
+		return DefaultDeepenMaxSteps
+	}
+	return c.MaxSteps
+}
+
+// DefaultAllowedExtensions are the project file extensions of a registry that does not configure any.
+var DefaultAllowedExtensions = []string{constants.ProtoFileExt}
+
+// Extensions returns the allowed project file extensions.
+func (c *Config) Extensions() []string {
+	if c == nil || len(c.AllowedExtensions) == 0 {
+		return DefaultAllowedExtensions
+	}
+	return c.AllowedExtensions
+}
+
+// SpecialFiles returns the files that are never project files: protato's metadata files
+// plus metadataFiles.
+func (c *Config) SpecialFiles() utils.SpecialFiles {
+	if c == nil {
+		return utils.SpecialFiles{}
+	}
+	return utils.NewSpecialFiles(c.MetadataFiles)
+}
+
+// AllowsFile reports whether a project file path has an allowed extension.
+// Metadata files are never project files.
+func (c *Config) AllowsFile(name string) bool {
+	if c.SpecialFiles().Contains(name) {
+		return false
+	}
+	ext := path.Ext(name)
+	for _, allowed := range c.Extensions() {
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteProjectsRequest contains parameters for removing projects.
+type DeleteProjectsRequest struct {
+	Projects []ProjectPath // Projects to remove
+	Snapshot git.Hash      // Base snapshot
+	Author   *git.Author   // Required: Git author/committer for commits
+}
+
+// DeleteProjectRequest contains parameters for removing a single claimed project.
+type DeleteProjectRequest struct {
+	Project       ProjectPath // Project to remove
+	RepositoryURL string      // Caller's repository; must own the project (empty skips the check)
+	Snapshot      git.Hash    // Base snapshot
+	Author        *git.Author // Required: Git author/committer for commits
+}
+
+// RenameProjectRequest contains parameters for moving a claimed project to a new path.
+type RenameProjectRequest struct {
+	From          ProjectPath      // Project to move
+	To            ProjectPath      // New project path; must not be a project yet
+	RepositoryURL string           // Caller's repository; must own From and be able to claim To (empty skips the ownership check)
+	Snapshot      git.Hash         // Base snapshot
+	Author        *git.Author      // Required: Git author/committer for commits
+	MoveImports   ImportRewriter   // Optional: rewrites imports of From in the moved proto files to To
+	Validate      ProjectValidator // Optional: compiles the moved project before the rename is accepted when the registry sets validateOnPush
+}
+
+// RenameProjectResponse contains the result of moving a project.
+type RenameProjectResponse struct {
+	Snapshot   git.Hash // New snapshot
+	FilesMoved int      // Files moved, including project metadata
+}
+
+// DeleteProjectResponse contains the result of removing a project.
+type DeleteProjectResponse struct {
+	Snapshot     git.Hash // New snapshot
+	FilesDeleted int      // Files removed, including project metadata
 }
 
 // LocalProjectFile represents a local file to upload.
@@ -85,6 +380,13 @@ type LocalProjectFile struct {
 	Content   []byte // Optional: if set, use this content instead of reading LocalPath
 }
 
+// FileChange is a registry file that a project update adds, modifies or deletes.
+type FileChange struct {
+	Project ProjectPath
+	Path    string         // Relative to the project
+	Status  git.DiffStatus // git.DiffAdded, git.DiffModified or git.DiffDeleted
+}
+
 // SetProjectResponse contains the result of updating a project.
 type SetProjectResponse struct {
 	Snapshot     git.Hash // New snapshot
@@ -92,3 +394,11 @@ type SetProjectResponse struct {
 	LinesAdded   int
 	LinesDeleted int
 }
+
+// InitRequest contains parameters for creating a new registry.
+type InitRequest struct {
+	Path   string      // Bare repository path to create
+	Remote string      // Optional: remote URL to push the initial commit to
+	Branch string      // Initial branch (default: main)
+	Author *git.Author // Optional: defaults to the configured git user
+}
@@ -0,0 +1,40 @@
+// Package selfupdate provides release discovery and in-place binary replacement.
+package selfupdate
+
+import (
+	"context"
+)
+
+// ChecksumsAsset is the name of the release asset listing sha256 checksums.
+const ChecksumsAsset = "checksums.txt"
+
+// BinaryName is the name of the executable inside release archives.
+const BinaryName = "protato"
+
+// Release represents a published release.
+type Release struct {
+	Version string            // Release version (e.g., "v1.2.0")
+	Assets  map[string]string // Asset name -> download URL
+}
+
+// ReleaseSource fetches release metadata and assets.
+type ReleaseSource interface {
+	Latest(ctx context.Context) (*Release, error)
+	Download(ctx context.Context, url string) ([]byte, error)
+}
+
+// UpdateRequest contains parameters for updating the running binary.
+type UpdateRequest struct {
+	CurrentVersion string        // Version of the running binary
+	BinaryPath     string        // Path of the binary to replace
+	Source         ReleaseSource // Where to fetch releases from
+	GOOS           string        // Target operating system
+	GOARCH         string        // Target architecture
+	Force          bool          // Reinstall even if not newer
+}
+
+// UpdateResult contains the result of an update.
+type UpdateResult struct {
+	Version string // Version now installed (or latest seen, when not updated)
+	Updated bool   // Whether the binary was replaced
+}
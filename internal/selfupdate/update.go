@@ -0,0 +1,274 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rahulagarwal0605/protato/internal/logger"
+)
+
+// Update replaces the binary at req.BinaryPath with the latest release if it is newer.
+func Update(ctx context.Context, req *UpdateRequest) (*UpdateResult, error) {
+	release, err := req.Source.Latest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetch latest release: %w", err)
+	}
+
+	if !req.Force && CompareVersions(release.Version, req.CurrentVersion) <= 0 {
+		return &UpdateResult{Version: release.Version}, nil
+	}
+
+	assetName, err := archiveName(req.GOOS, req.GOARCH)
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := downloadAsset(ctx, req.Source, release, assetName)
+	if err != nil {
+		return nil, err
+	}
+	checksums, err := downloadAsset(ctx, req.Source, release, ChecksumsAsset)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyChecksum(archive, checksums, assetName); err != nil {
+		return nil, err
+	}
+
+	binary, err := extractBinary(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Log(ctx).Debug().Str("path", req.BinaryPath).Str("version", release.Version).Msg("Replacing binary")
+	if err := replaceBinary(req.BinaryPath, binary); err != nil {
+		return nil, err
+	}
+
+	return &UpdateResult{Version: release.Version, Updated: true}, nil
+}
+
+// archiveName returns the release archive name for a platform.
+func archiveName(goos, goarch string) (string, error) {
+	if goos == "windows" {
+		return "", fmt.Errorf("self-update is not supported on windows")
+	}
+	return fmt.Sprintf("%s-%s-%s.tar.gz", BinaryName, goos, goarch), nil
+}
+
+// downloadAsset downloads a named asset of a release.
+func downloadAsset(ctx context.Context, source ReleaseSource, release *Release, name string) ([]byte, error) {
+	url, ok := release.Assets[name]
+	if !ok {
+		return nil, fmt.Errorf("release %s has no asset %s", release.Version, name)
+	}
+	data, err := source.Download(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// CompareVersions compares two dotted versions, ignoring a leading "v".
+// Returns -1, 0 or 1. A pre-release ("1.2.0-rc1") sorts before its release,
+// and an unparseable version sorts before everything else.
+func CompareVersions(a, b string) int {
+	aNums, aPre, aOK := parseVersion(a)
+	bNums, bPre, bOK := parseVersion(b)
+	switch {
+	case !aOK && !bOK:
+		return 0
+	case !aOK:
+		return -1
+	case !bOK:
+		return 1
+	}
+
+	for i := 0; i < len(aNums) || i < len(bNums); i++ {
+		var x, y int
+		if i < len(aNums) {
+			x = aNums[i]
+		}
+		if i < len(bNums) {
+			y = bNums[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return strings.Compare(aPre, bPre)
+}
+
+// parseVersion splits a version into numeric components and a pre-release suffix.
+func parseVersion(v string) ([]int, string, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	core, pre, _ := strings.Cut(v, "-")
+	if core == "" {
+		return nil, "", false
+	}
+
+	var nums []int
+	for _, part := range strings.Split(core, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, "", false
+		}
+		nums = append(nums, n)
+	}
+	return nums, pre, true
+}
+
+// verifyChecksum checks data against the sha256 listed for name in a checksums file.
+// The file uses the sha256sum format: "<hex>  <name>" per line.
+func verifyChecksum(data, checksums []byte, name string) error {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, fields[0]) {
+			return fmt.Errorf("checksum mismatch for %s: got %s, want %s", name, got, fields[0])
+		}
+		return nil
+	}
+	return fmt.Errorf("no checksum listed for %s", name)
+}
+
+// extractBinary reads the protato executable out of a .tar.gz archive.
+func extractBinary(archive []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("archive does not contain %s", BinaryName)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read archive: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeReg && filepath.Base(hdr.Name) == BinaryName {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// replaceBinary atomically replaces the file at path with content.
+// The new file is written next to the target and renamed over it, keeping the original mode.
+func replaceBinary(path string, content []byte) error {
+	mode := os.FileMode(0755)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".new-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // No-op after a successful rename
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return fmt.Errorf("chmod temp file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// GitHubSource fetches releases from a GitHub releases API endpoint.
+type GitHubSource struct {
+	URL    string       // Latest release endpoint (e.g., https://api.github.com/repos/<owner>/<repo>/releases/latest)
+	Client *http.Client // HTTP client (default: http.DefaultClient)
+}
+
+// githubRelease is the subset of the GitHub release payload we use.
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// Latest implements ReleaseSource.
+func (s *GitHubSource) Latest(ctx context.Context) (*Release, error) {
+	body, err := s.Download(ctx, s.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	var gr githubRelease
+	if err := json.Unmarshal(body, &gr); err != nil {
+		return nil, fmt.Errorf("parse release: %w", err)
+	}
+
+	release := &Release{Version: gr.TagName, Assets: make(map[string]string, len(gr.Assets))}
+	for _, a := range gr.Assets {
+		release.Assets[a.Name] = a.URL
+	}
+	return release, nil
+}
+
+// Download implements ReleaseSource.
+func (s *GitHubSource) Download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,227 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/rahulagarwal0605/protato/internal/logger"
+)
+
+// testContext creates a context with a discarding logger for tests.
+func testContext() context.Context {
+	log := zerolog.New(io.Discard)
+	return logger.WithLogger(context.Background(), &log)
+}
+
+// fakeSource serves a fixed release from memory.
+type fakeSource struct {
+	release *Release
+	files   map[string][]byte
+}
+
+func (f *fakeSource) Latest(ctx context.Context) (*Release, error) { return f.release, nil }
+
+func (f *fakeSource) Download(ctx context.Context, url string) ([]byte, error) {
+	data, ok := f.files[url]
+	if !ok {
+		return nil, fmt.Errorf("not found: %s", url)
+	}
+	return data, nil
+}
+
+// makeArchive builds a .tar.gz containing a single protato binary.
+func makeArchive(t *testing.T, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: BinaryName, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// newFakeSource builds a release for linux/amd64 with a matching checksums file.
+func newFakeSource(t *testing.T, version string, binary []byte, corrupt bool) *fakeSource {
+	t.Helper()
+	archive := makeArchive(t, binary)
+	sum := sha256.Sum256(archive)
+	if corrupt {
+		sum[0] ^= 0xff
+	}
+	name := "protato-linux-amd64.tar.gz"
+	return &fakeSource{
+		release: &Release{
+			Version: version,
+			Assets:  map[string]string{name: "u/" + name, ChecksumsAsset: "u/" + ChecksumsAsset},
+		},
+		files: map[string][]byte{
+			"u/" + name:           archive,
+			"u/" + ChecksumsAsset: []byte(hex.EncodeToString(sum[:]) + "  " + name + "\n"),
+		},
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.0", "v1.2.0", 0},
+		{"1.2.0", "v1.2.0", 0},
+		{"v1.3.0", "v1.2.9", 1},
+		{"v1.2.10", "v1.2.9", 1},
+		{"v2.0.0", "v10.0.0", -1},
+		{"v1.2", "v1.2.0", 0},
+		{"v1.2.1", "v1.2", 1},
+		{"v1.2.0-rc1", "v1.2.0", -1},
+		{"v1.2.0", "v1.2.0-rc1", 1},
+		{"v1.2.0-rc2", "v1.2.0-rc1", 1},
+		{"v1.0.0", "", 1},
+		{"", "v1.0.0", -1},
+		{"garbage", "v1.0.0", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"_vs_"+tt.b, func(t *testing.T) {
+			if got := CompareVersions(tt.a, tt.b); got != tt.want {
+				t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceBinary(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "protato")
+	if err := os.WriteFile(path, []byte("old"), 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := replaceBinary(path, []byte("new")); err != nil {
+		t.Fatalf("replaceBinary() error = %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "new" {
+		t.Errorf("content = %q, want %q", got, "new")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0750 {
+		t.Errorf("mode = %o, want 750", info.Mode().Perm())
+	}
+
+	// No temp files should be left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want 1", len(entries))
+	}
+}
+
+func TestReplaceBinary_MissingDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "protato")
+	if err := replaceBinary(path, []byte("new")); err == nil {
+		t.Error("replaceBinary() expected error for missing directory")
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	data := []byte("archive")
+	sum := sha256.Sum256(data)
+	hexSum := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name      string
+		checksums string
+		wantErr   bool
+	}{
+		{name: "match", checksums: "deadbeef  other.tar.gz\n" + hexSum + "  protato-linux-amd64.tar.gz\n"},
+		{name: "binary mode marker", checksums: hexSum + " *protato-linux-amd64.tar.gz\n"},
+		{name: "mismatch", checksums: "deadbeef  protato-linux-amd64.tar.gz\n", wantErr: true},
+		{name: "not listed", checksums: hexSum + "  other.tar.gz\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyChecksum(data, []byte(tt.checksums), "protato-linux-amd64.tar.gz")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyChecksum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := testContext()
+
+	tests := []struct {
+		name        string
+		current     string
+		latest      string
+		force       bool
+		corrupt     bool
+		wantUpdated bool
+		wantErr     bool
+		wantContent string
+	}{
+		{name: "newer release", current: "v1.0.0", latest: "v1.1.0", wantUpdated: true, wantContent: "new"},
+		{name: "already latest", current: "v1.1.0", latest: "v1.1.0", wantContent: "old"},
+		{name: "running newer", current: "v1.2.0", latest: "v1.1.0", wantContent: "old"},
+		{name: "force reinstall", current: "v1.1.0", latest: "v1.1.0", force: true, wantUpdated: true, wantContent: "new"},
+		{name: "checksum mismatch", current: "v1.0.0", latest: "v1.1.0", corrupt: true, wantErr: true, wantContent: "old"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "protato")
+			if err := os.WriteFile(path, []byte("old"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := Update(ctx, &UpdateRequest{
+				CurrentVersion: tt.current,
+				BinaryPath:     path,
+				Source:         newFakeSource(t, tt.latest, []byte("new"), tt.corrupt),
+				GOOS:           "linux",
+				GOARCH:         "amd64",
+				Force:          tt.force,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Update() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && res.Updated != tt.wantUpdated {
+				t.Errorf("Update() updated = %v, want %v", res.Updated, tt.wantUpdated)
+			}
+
+			got, _ := os.ReadFile(path)
+			if string(got) != tt.wantContent {
+				t.Errorf("binary content = %q, want %q", got, tt.wantContent)
+			}
+		})
+	}
+}
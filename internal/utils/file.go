@@ -3,8 +3,56 @@ package utils
 import (
 	"fmt"
 	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/rahulagarwal0605/protato/internal/constants"
 )
 
+// builtinSpecialFiles are the metadata files protato keeps next to proto files.
+var builtinSpecialFiles = []string{
+	constants.ConfigFileName,
+	constants.LockFileName,
+	constants.ManifestFileName,
+	constants.GitattributesName,
+	constants.ProjectMetaFile,
+	constants.RegistryConfigFile,
+	constants.KeepFileName,
+}
+
+// SpecialFiles decides which files are metadata rather than project files: protato's own
+// metadata files plus any file names a configuration adds. The zero value has only protato's.
+type SpecialFiles struct {
+	extra []string
+}
+
+// NewSpecialFiles returns protato's metadata files plus the extra file names.
+func NewSpecialFiles(extra []string) SpecialFiles {
+	return SpecialFiles{extra: extra}
+}
+
+// Contains reports whether a file name or slash-separated path names a special file.
+func (s SpecialFiles) Contains(name string) bool {
+	base := path.Base(name)
+	return slices.Contains(builtinSpecialFiles, base) || slices.Contains(s.extra, base)
+}
+
+// IsProjectFile reports whether a file name or slash-separated path is a proto file that is not special.
+func (s SpecialFiles) IsProjectFile(name string) bool {
+	return strings.HasSuffix(name, constants.ProtoFileExt) && !s.Contains(name)
+}
+
+// IsSpecialFile reports whether a file name or slash-separated path names a protato metadata file.
+func IsSpecialFile(name string) bool {
+	return SpecialFiles{}.Contains(name)
+}
+
+// IsProjectFile reports whether a file name or slash-separated path is a proto file belonging to a project.
+func IsProjectFile(name string) bool {
+	return SpecialFiles{}.IsProjectFile(name)
+}
+
 // DirNotExists checks if a directory does not exist.
 func DirNotExists(dirPath string) bool {
 	_, err := os.Stat(dirPath)
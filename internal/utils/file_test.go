@@ -122,3 +122,48 @@ func TestCreateDir(t *testing.T) {
 		})
 	}
 }
+
+func TestIsProjectFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		wantSpecial bool
+		wantProject bool
+	}{
+		{name: "api.proto", wantProject: true},
+		{name: "team/service/v1/api.proto", wantProject: true},
+		{name: "README.md"},
+		{name: "protato.lock", wantSpecial: true},
+		{name: "team/service/protato.root.yaml", wantSpecial: true},
+		{name: "received.manifest.yaml", wantSpecial: true},
+		{name: ".gitattributes", wantSpecial: true},
+		{name: ".gitkeep", wantSpecial: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsSpecialFile(tt.name); got != tt.wantSpecial {
+				t.Errorf("IsSpecialFile(%q) = %v, want %v", tt.name, got, tt.wantSpecial)
+			}
+			if got := IsProjectFile(tt.name); got != tt.wantProject {
+				t.Errorf("IsProjectFile(%q) = %v, want %v", tt.name, got, tt.wantProject)
+			}
+		})
+	}
+}
+
+func TestSpecialFiles_Extra(t *testing.T) {
+	special := NewSpecialFiles([]string{"generated.proto"})
+
+	if special.IsProjectFile("v1/generated.proto") {
+		t.Error("IsProjectFile() should skip extra special files")
+	}
+	if !special.IsProjectFile("v1/api.proto") {
+		t.Error("IsProjectFile() should keep other proto files")
+	}
+	if !special.Contains("team/protato.lock") {
+		t.Error("Contains() should keep protato's own metadata files")
+	}
+	if !IsProjectFile("v1/generated.proto") {
+		t.Error("package IsProjectFile() should only skip protato's metadata files")
+	}
+}
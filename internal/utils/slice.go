@@ -1,5 +1,7 @@
 package utils
 
+import "sort"
+
 // StringSliceToMap converts a string slice to a map for fast lookups.
 func StringSliceToMap(items []string) map[string]bool {
 	m := make(map[string]bool)
@@ -74,3 +76,13 @@ func ConvertSlice[T any, R any](items []T, converter func(T) R) []R {
 	}
 	return result
 }
+
+// SortedKeys returns the keys of a string-keyed map in sorted order.
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -331,3 +332,11 @@ func TestSliceToMap(t *testing.T) {
 		})
 	}
 }
+
+func TestSortedKeys(t *testing.T) {
+	got := SortedKeys(map[string]int{"release": 1, "": 2, "main": 3})
+	want := []string{"", "main", "release"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SortedKeys() = %v, want %v", got, want)
+	}
+}
@@ -17,3 +17,8 @@ func NormalizeGitURL(url string) string {
 	url = strings.TrimSuffix(url, ".git")
 	return url
 }
+
+// IsRemoteURL reports whether s looks like a remote Git URL rather than a local path.
+func IsRemoteURL(s string) bool {
+	return strings.Contains(s, "://") || strings.HasPrefix(s, "git@")
+}
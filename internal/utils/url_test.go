@@ -46,3 +46,25 @@ func TestNormalizeGitURL(t *testing.T) {
 		})
 	}
 }
+
+func TestIsRemoteURL(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want bool
+	}{
+		{name: "HTTPS URL", s: "https://github.com/org/registry.git", want: true},
+		{name: "SSH URL", s: "git@github.com:org/registry.git", want: true},
+		{name: "file URL", s: "file:///srv/registry.git", want: true},
+		{name: "absolute path", s: "/srv/registry.git", want: false},
+		{name: "relative path", s: "registry.git", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRemoteURL(tt.s); got != tt.want {
+				t.Errorf("IsRemoteURL(%q) = %v, want %v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
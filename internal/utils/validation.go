@@ -33,13 +33,18 @@ func ValidateProjectPath(p string) error {
 
 // ProjectsOverlap checks if any two project paths overlap.
 // Two paths overlap if one is a prefix of the other (e.g., "a/b" and "a/b/c" overlap).
+// Paths are compared case-insensitively, so "Team/Service" conflicts with "team/service".
 func ProjectsOverlap(projects []string) error {
 	for i, p1 := range projects {
 		for j, p2 := range projects {
 			if i == j {
 				continue
 			}
-			if strings.HasPrefix(p1+"/", p2+"/") || strings.HasPrefix(p2+"/", p1+"/") {
+			if p1 != p2 && strings.EqualFold(p1, p2) {
+				return fmt.Errorf("projects differ only by case: %s and %s", p1, p2)
+			}
+			l1, l2 := strings.ToLower(p1), strings.ToLower(p2)
+			if strings.HasPrefix(l1+"/", l2+"/") || strings.HasPrefix(l2+"/", l1+"/") {
 				return fmt.Errorf("projects overlap: %s and %s", p1, p2)
 			}
 		}
@@ -104,6 +104,16 @@ func TestProjectsOverlap(t *testing.T) {
 			projects: []string{"team1/service", "team2/service"},
 			wantErr:  false,
 		},
+		{
+			name:     "differ only by case",
+			projects: []string{"team/service", "Team/Service"},
+			wantErr:  true,
+		},
+		{
+			name:     "overlapping paths - case-insensitive parent",
+			projects: []string{"Team", "team/service"},
+			wantErr:  true,
+		},
 		{
 			name:     "single project",
 			projects: []string{"team/service"},
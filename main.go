@@ -28,13 +28,30 @@ type mainCmd struct {
 	Verbosity int         `short:"v" type:"counter" help:"Increase verbosity"`
 	Dir       string      `short:"C" help:"Change directory before running"`
 
-	Init   cmd.InitCmd   `cmd:"" help:"Initialize protato in a repository"`
-	New    cmd.NewCmd    `cmd:"" help:"Create a new project (claim ownership)"`
-	Pull   cmd.PullCmd   `cmd:"" help:"Download projects from registry"`
-	Push   cmd.PushCmd   `cmd:"" help:"Publish owned projects to registry"`
-	Verify cmd.VerifyCmd `cmd:"" help:"Verify workspace integrity"`
-	List   cmd.ListCmd   `cmd:"" help:"List available projects"`
-	Mine   cmd.MineCmd   `cmd:"" help:"List files owned by this repository"`
+	Profile       string `hidden:"" enum:",cpu,mem" default:"" help:"Write a pprof profile of the command (cpu or mem)"`
+	ProfileOutput string `hidden:"" default:"protato.pprof" help:"Path the --profile output is written to"`
+
+	Init    cmd.InitCmd    `cmd:"" help:"Initialize protato in a repository"`
+	New     cmd.NewCmd     `cmd:"" help:"Create a new project (claim ownership)"`
+	Pull    cmd.PullCmd    `cmd:"" help:"Download projects from registry"`
+	Receive cmd.ReceiveCmd `cmd:"" help:"Vendor a project from a local directory"`
+	Push    cmd.PushCmd    `cmd:"" help:"Publish owned projects to registry"`
+	Remove  cmd.RemoveCmd  `cmd:"" help:"Delete an owned project from the registry and un-claim it"`
+	Verify  cmd.VerifyCmd  `cmd:"" help:"Verify workspace integrity"`
+	List    cmd.ListCmd    `cmd:"" help:"List available projects"`
+	Tree    cmd.TreeCmd    `cmd:"" help:"Print the registry projects as a tree"`
+	Graph   cmd.GraphCmd   `cmd:"" help:"Print the project dependency graph in DOT or Mermaid"`
+	History cmd.HistoryCmd `cmd:"" help:"List the registry commits that changed a project"`
+	Mine    cmd.MineCmd    `cmd:"" help:"List files owned by this repository"`
+	Lint    cmd.LintCmd    `cmd:"" help:"Check owned protos against style rules"`
+	Compat  cmd.CompatCmd  `cmd:"" help:"Check owned protos for wire compatibility with a baseline ref"`
+	Clean   cmd.CleanCmd   `cmd:"" help:"Remove received projects and cached registry data"`
+	Debug   cmd.DebugCmd   `cmd:"" help:"Diagnose the protato environment"`
+	Doctor  cmd.DoctorCmd  `cmd:"" help:"Check workspace and registry health"`
+
+	Completion cmd.CompletionCmd `cmd:"" help:"Print a shell completion script"`
+
+	SelfUpdate cmd.SelfUpdateCmd `cmd:"" name:"self-update" help:"Update protato to the latest release"`
 }
 
 type versionFlag bool
@@ -57,8 +74,22 @@ func main() {
 	logger.SetLogLevel(cli.Verbosity)
 	configureDirectory(ctx, cli.Dir)
 
+	stopProfile, err := cmd.StartProfile(cli.Profile, cli.ProfileOutput)
+	if err != nil {
+		logger.Log(ctx).Fatal().Err(err).Msg("Failed to start profiling")
+	}
+
 	// Execute command - Kong injects globals and ctx
-	if err := kctx.Run(&cli.GlobalOptions, ctx); err != nil {
+	err = kctx.Run(&cli.GlobalOptions, ctx)
+
+	// Flush the profile before any exit below
+	if perr := stopProfile(); perr != nil {
+		logger.Log(ctx).Warn().Err(perr).Msg("Failed to write profile")
+	} else if cli.Profile != "" {
+		logger.Log(ctx).Info().Str("path", cli.ProfileOutput).Msg("Wrote profile")
+	}
+
+	if err != nil {
 		// If context was cancelled (e.g., Ctrl+C), exit cleanly without error message
 		if err == context.Canceled {
 			os.Exit(130) // Standard exit code for SIGINT (Ctrl+C)
@@ -126,6 +157,7 @@ func setupCLI(ctx context.Context, defaultCacheDir string) (*mainCmd, *kong.Kong
 			"defaultCacheDir": defaultCacheDir, // Used by Kong's default interpolation in struct tags
 		},
 		kong.BindTo(ctx, (*context.Context)(nil)),
+		kong.Bind(&cmd.BuildInfo{Version: version, Commit: commit, Date: date}),
 	)
 
 	return cli, parser
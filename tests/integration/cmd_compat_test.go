@@ -0,0 +1,87 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/cmd"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/tests/testhelpers"
+)
+
+func TestCompatCmd_Run(t *testing.T) {
+	tmpDir, _ := testhelpers.SetupTestWorkspace(t)
+
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(tmpDir)
+
+	git := func(args ...string) {
+		t.Helper()
+		c := exec.Command("git", args...)
+		c.Dir = tmpDir
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v, output: %s", args, err, out)
+		}
+	}
+	git("init")
+	git("config", "user.email", "test@example.com")
+	git("config", "user.name", "Test User")
+
+	testhelpers.CreateTestProject(t, tmpDir, "proto/team/users", map[string]string{
+		"api.proto": "syntax = \"proto3\";\npackage team.users;\nmessage User { string name = 1; string email = 2; }\n",
+	})
+	testhelpers.CreateTestProject(t, tmpDir, "proto/team/orders", map[string]string{
+		"api.proto": "syntax = \"proto3\";\npackage team.orders;\nmessage Order { string id = 1; }\n",
+	})
+	git("add", "-A")
+	git("commit", "--no-verify", "-m", "Baseline")
+	git("tag", "v1")
+
+	// Move email to a new field number without reserving the old one
+	testhelpers.CreateTestProtoFile(t, filepath.Join(tmpDir, "proto", "team", "users"), "api.proto",
+		"syntax = \"proto3\";\npackage team.users;\nmessage User { string name = 1; string email = 3; }\n")
+	// Add a field, which is compatible
+	testhelpers.CreateTestProtoFile(t, filepath.Join(tmpDir, "proto", "team", "orders"), "api.proto",
+		"syntax = \"proto3\";\npackage team.orders;\nmessage Order { string id = 1; int64 total = 2; }\n")
+
+	tests := []struct {
+		name      string
+		compatCmd cmd.CompatCmd
+		wantErr   bool
+	}{
+		{
+			name:      "changed field number is reported",
+			compatCmd: cmd.CompatCmd{Baseline: "v1", Projects: []string{"team/users"}},
+			wantErr:   true,
+		},
+		{
+			name:      "compatible change passes",
+			compatCmd: cmd.CompatCmd{Baseline: "v1", Projects: []string{"team/orders"}},
+		},
+		{
+			name:      "all projects",
+			compatCmd: cmd.CompatCmd{Baseline: "v1"},
+			wantErr:   true,
+		},
+		{
+			name:      "unknown baseline ref",
+			compatCmd: cmd.CompatCmd{Baseline: "no-such-ref"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logger.Init()
+			ctx := logger.WithLogger(context.Background(), &log)
+			err := tt.compatCmd.Run(&cmd.GlobalOptions{}, ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CompatCmd.Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/cmd"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/tests/testhelpers"
+)
+
+func TestDoctorCmd_Registry(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+
+	wsDir, _ := testhelpers.SetupTestWorkspace(t)
+	gitInit := exec.Command("git", "init")
+	gitInit.Dir = wsDir
+	if err := gitInit.Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	rep := &recordingReporter{}
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+		Reporter:    rep,
+	}
+	doctorCmd := cmd.DoctorCmd{Registry: true}
+	if err := doctorCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("DoctorCmd.Run() error = %v, output: %+v", err, rep.diagnostics)
+	}
+
+	var lines []string
+	for _, d := range rep.diagnostics {
+		lines = append(lines, d.Message)
+	}
+	output := strings.Join(lines, "\n")
+	for _, check := range []string{"workspace config", "owned projects", "registry reachable", "default branch", "protos dir", "registry config"} {
+		if !strings.Contains(output, "ok    "+check+": ") {
+			t.Errorf("doctor output missing passing %q check:\n%s", check, output)
+		}
+	}
+
+	// An unreachable registry fails without aborting the other checks
+	rep.diagnostics = nil
+	globals.RegistryURL = filepath.Join(tmpDir, "missing-registry")
+	if err := doctorCmd.Run(globals, ctx); err == nil {
+		t.Fatal("DoctorCmd.Run() with a missing registry succeeded, want error")
+	}
+	if got := rep.diagnostics[len(rep.diagnostics)-1].Message; !strings.HasPrefix(got, "FAIL  registry reachable: ") {
+		t.Errorf("last finding = %q, want a failed reachability check", got)
+	}
+}
@@ -109,6 +109,52 @@ func TestInitCmd_Run(t *testing.T) {
 	}
 }
 
+func TestInitCmd_VendorGitignore(t *testing.T) {
+	tests := []struct {
+		name string
+		mode string
+		want string
+	}{
+		{name: "ignore", mode: "ignore", want: "node_modules/\n/vendor-proto/\n"},
+		{name: "track", mode: "track", want: "node_modules/\n"},
+		{name: "unset", mode: "", want: "node_modules/\nvendor-proto/\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			exec.Command("git", "init", tmpDir).Run()
+			gitignore := filepath.Join(tmpDir, ".gitignore")
+			existing := "node_modules/\n"
+			if tt.mode != "ignore" {
+				existing += "vendor-proto/\n"
+			}
+			if err := os.WriteFile(gitignore, []byte(existing), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			oldWd, _ := os.Getwd()
+			defer os.Chdir(oldWd)
+			os.Chdir(tmpDir)
+
+			log := logger.Init()
+			ctx := logger.WithLogger(context.Background(), &log)
+			initCmd := cmd.InitCmd{SkipPrompts: true, VendorGitignore: tt.mode}
+			if err := initCmd.Run(&cmd.GlobalOptions{}, ctx); err != nil {
+				t.Fatalf("InitCmd.Run() error = %v", err)
+			}
+
+			data, err := os.ReadFile(gitignore)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf(".gitignore = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
 func TestInitCmd_ValidateConfig(t *testing.T) {
 	tests := []struct {
 		name    string
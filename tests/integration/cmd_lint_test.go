@@ -0,0 +1,170 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/cmd"
+	"github.com/rahulagarwal0605/protato/internal/local"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/tests/testhelpers"
+)
+
+func TestLintCmd_Run(t *testing.T) {
+	tmpDir, _ := testhelpers.SetupTestWorkspace(t)
+
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(tmpDir)
+
+	gitInit := exec.Command("git", "init")
+	gitInit.Dir = tmpDir
+	if err := gitInit.Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+
+	testhelpers.CreateTestProject(t, tmpDir, "proto/team/clean", map[string]string{
+		"api.proto": "syntax = \"proto3\";\npackage team.clean;\nmessage User { string user_id = 1; }\n",
+	})
+	testhelpers.CreateTestProject(t, tmpDir, "proto/team/messy", map[string]string{
+		"api.proto": "syntax = \"proto3\";\npackage team.messy;\nmessage User { string userId = 1; }\n",
+	})
+
+	tests := []struct {
+		name    string
+		lintCmd cmd.LintCmd
+		wantErr bool
+	}{
+		{
+			name:    "clean project passes",
+			lintCmd: cmd.LintCmd{Projects: []string{"team/clean"}},
+		},
+		{
+			name:    "all projects report issues",
+			lintCmd: cmd.LintCmd{},
+			wantErr: true,
+		},
+		{
+			name:    "disabled rule is not reported",
+			lintCmd: cmd.LintCmd{Disable: []string{"field-lower-snake-case"}},
+		},
+		{
+			name:    "unowned project",
+			lintCmd: cmd.LintCmd{Projects: []string{"team/unknown"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown rule",
+			lintCmd: cmd.LintCmd{Rules: []string{"no-such-rule"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logger.Init()
+			ctx := logger.WithLogger(context.Background(), &log)
+			err := tt.lintCmd.Run(&cmd.GlobalOptions{}, ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LintCmd.Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLintCmd_JSONLEvents(t *testing.T) {
+	tmpDir, _ := testhelpers.SetupTestWorkspace(t)
+
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(tmpDir)
+
+	gitInit := exec.Command("git", "init")
+	gitInit.Dir = tmpDir
+	if err := gitInit.Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+
+	testhelpers.CreateTestProject(t, tmpDir, "proto/team/messy", map[string]string{
+		"api.proto": "syntax = \"proto3\";\npackage team.messy;\nmessage User {\n  string userId = 1;\n  string userName = 2;\n}\n",
+	})
+
+	var buf bytes.Buffer
+	globals := &cmd.GlobalOptions{Reporter: cmd.NewJSONLReporter(&buf)}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	if err := (&cmd.LintCmd{}).Run(globals, ctx); err == nil {
+		t.Fatal("LintCmd.Run() succeeded, want lint issues")
+	}
+
+	want := `{"type":"diagnostic","file":"proto/team/messy/api.proto","line":4,"column":3,"rule":"field-lower-snake-case","message":"proto/team/messy/api.proto:4:3: field \"team.messy.User.userId\" should be lower_snake_case (field-lower-snake-case)"}
+{"type":"diagnostic","file":"proto/team/messy/api.proto","line":5,"column":3,"rule":"field-lower-snake-case","message":"proto/team/messy/api.proto:5:3: field \"team.messy.User.userName\" should be lower_snake_case (field-lower-snake-case)"}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("events =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLintCmd_ConfigRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		lint    local.LintConfig
+		lintCmd cmd.LintCmd
+		wantErr bool
+	}{
+		{
+			name:    "all rules by default",
+			wantErr: true,
+		},
+		{
+			name: "rule disabled in config",
+			lint: local.LintConfig{Disable: []string{"field-lower-snake-case"}},
+		},
+		{
+			name: "config selects rules",
+			lint: local.LintConfig{Rules: []string{"message-pascal-case"}},
+		},
+		{
+			name:    "flag rules replace config rules",
+			lint:    local.LintConfig{Rules: []string{"message-pascal-case"}},
+			lintCmd: cmd.LintCmd{Rules: []string{"field-lower-snake-case"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown rule in config",
+			lint:    local.LintConfig{Disable: []string{"no-such-rule"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, _ := testhelpers.SetupTestWorkspaceWithConfig(t, &local.Config{
+				Service:      "test-service",
+				Directories:  local.DirectoryConfig{Owned: "proto", Vendor: "vendor-proto"},
+				AutoDiscover: true,
+				Lint:         tt.lint,
+			})
+			testhelpers.CreateTestProject(t, tmpDir, "proto/team/messy", map[string]string{
+				"api.proto": "syntax = \"proto3\";\npackage team.messy;\nmessage User { string userId = 1; }\n",
+			})
+
+			oldWd, _ := os.Getwd()
+			defer os.Chdir(oldWd)
+			os.Chdir(tmpDir)
+			if err := exec.Command("git", "init").Run(); err != nil {
+				t.Fatalf("git init: %v", err)
+			}
+
+			log := logger.Init()
+			ctx := logger.WithLogger(context.Background(), &log)
+			err := tt.lintCmd.Run(&cmd.GlobalOptions{}, ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LintCmd.Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,11 +2,15 @@ package integration
 
 import (
 	"context"
+	"encoding/json"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
 
 	"github.com/rahulagarwal0605/protato/cmd"
+	"github.com/rahulagarwal0605/protato/internal/logger"
 	"github.com/rahulagarwal0605/protato/tests/testhelpers"
 )
 
@@ -66,3 +70,47 @@ func TestListCmd_Run(t *testing.T) {
 
 	_ = ws // Use ws to avoid unused variable
 }
+
+func TestListCmd_JSON(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+	billingDir := filepath.Join(workDir, "protos", "acme", "billing")
+	testhelpers.CreateTestProtoFile(t, billingDir, "protato.root.yaml", "git:\n  commit: 0123456789abcdef0123456789abcdef01234567\n  url: github.com/acme/billing\n")
+	testhelpers.CreateTestProtoFile(t, billingDir, "v1/billing.proto", "syntax = \"proto3\";\npackage acme.billing.v1;")
+	commitAndPush(t, workDir, "Add billing")
+
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	// Capture stdout; logs go to stderr and must not mix with the JSON
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	listCmd := cmd.ListCmd{Format: "json"}
+	err := listCmd.Run(globals, ctx)
+	w.Close()
+	os.Stdout = oldStdout
+	if err != nil {
+		t.Fatalf("ListCmd.Run() --format json error = %v", err)
+	}
+	out, _ := io.ReadAll(r)
+
+	var projects []struct {
+		Project       string `json:"project"`
+		RepositoryURL string `json:"repository_url"`
+		Commit        string `json:"commit"`
+	}
+	if err := json.Unmarshal(out, &projects); err != nil {
+		t.Fatalf("unmarshal %q: %v", out, err)
+	}
+	if len(projects) != 2 || projects[0].Project != "acme/billing" || projects[1].Project != "team/service" {
+		t.Fatalf("projects = %+v, want acme/billing and team/service", projects)
+	}
+	if projects[0].RepositoryURL != "github.com/acme/billing" || projects[0].Commit != "0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("acme/billing = %+v, want its owning repository and commit", projects[0])
+	}
+}
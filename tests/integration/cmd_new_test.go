@@ -4,6 +4,8 @@ import (
 	"context"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/rahulagarwal0605/protato/cmd"
@@ -99,3 +101,68 @@ func TestNewCmd_Run(t *testing.T) {
 		})
 	}
 }
+
+func TestNewCmd_DryRun(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+
+	// Publish a project owned by another repository under the workspace's service prefix
+	ownedDir := filepath.Join(workDir, "protos", "test-service", "billing", "api")
+	testhelpers.CreateTestProtoFile(t, ownedDir, "protato.root.yaml", "git:\n  url: https://example.com/other.git\n")
+	testhelpers.CreateTestProtoFile(t, ownedDir, "v1/api.proto", "syntax = \"proto3\";\npackage billing.api.v1;")
+	commitAndPush(t, workDir, "Add billing project")
+
+	wsDir, _ := testhelpers.SetupTestWorkspace(t)
+	for _, args := range [][]string{
+		{"init"},
+		{"remote", "add", "origin", "https://example.com/repo.git"},
+	} {
+		c := exec.Command("git", args...)
+		c.Dir = wsDir
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v, output: %s", args, err, out)
+		}
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	configBefore := testhelpers.ReadFile(t, filepath.Join(wsDir, "protato.yaml"))
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "payments/api", want: "payments/api: claim would succeed"},
+		{path: "billing/api", want: "billing/api: claim would fail (ownership conflict)", wantErr: true},
+		{path: "billing", want: "billing: claim would fail (subproject conflict)", wantErr: true},
+		{path: "billing/api/v2", want: "billing/api/v2: claim would fail (parent project exists)", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := &recordingReporter{}
+			globals := &cmd.GlobalOptions{
+				CacheDir:    filepath.Join(tmpDir, "cache"),
+				RegistryURL: registryDir,
+				Reporter:    rec,
+			}
+
+			newCmd := cmd.NewCmd{Paths: []string{tt.path}, DryRun: true}
+			err := newCmd.Run(globals, ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewCmd.Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(rec.diagnostics) != 1 || !strings.HasPrefix(rec.diagnostics[0].Message, tt.want) {
+				t.Errorf("diagnostics = %+v, want message starting with %q", rec.diagnostics, tt.want)
+			}
+		})
+	}
+
+	if got := testhelpers.ReadFile(t, filepath.Join(wsDir, "protato.yaml")); got != configBefore {
+		t.Errorf("--dry-run changed protato.yaml:\n%s", got)
+	}
+}
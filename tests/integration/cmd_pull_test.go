@@ -4,9 +4,13 @@ import (
 	"context"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/rahulagarwal0605/protato/cmd"
+	"github.com/rahulagarwal0605/protato/internal/constants"
 	"github.com/rahulagarwal0605/protato/internal/git"
 	"github.com/rahulagarwal0605/protato/internal/local"
 	"github.com/rahulagarwal0605/protato/internal/logger"
@@ -143,3 +147,944 @@ func TestPullCmd_FilterOwnedProjects(t *testing.T) {
 		t.Logf("PullCmd filtered owned projects correctly")
 	}
 }
+
+// commitAndPush commits all changes in a registry work tree and pushes them.
+func commitAndPush(t *testing.T, workDir, message string) {
+	t.Helper()
+	for _, args := range [][]string{
+		{"add", "-A"},
+		{"commit", "--no-verify", "-m", message},
+		{"push", "origin", "HEAD"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = workDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v, output: %s", args, err, out)
+		}
+	}
+}
+
+func TestPullCmd_UpdateAll(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+
+	// Publish a second project
+	otherDir := filepath.Join(workDir, "protos", "team", "other")
+	testhelpers.CreateTestProtoFile(t, otherDir, "protato.root.yaml", "service: test-service\n")
+	testhelpers.CreateTestProtoFile(t, otherDir, "v1/old.proto", "syntax = \"proto3\";\npackage team.other.v1;")
+	commitAndPush(t, workDir, "Add other project")
+
+	wsDir, _ := testhelpers.SetupTestWorkspace(t)
+	gitInit := exec.Command("git", "init")
+	gitInit.Dir = wsDir
+	if err := gitInit.Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	pullCmd := cmd.PullCmd{Projects: []string{"team/service", "team/other"}, NoDeps: true}
+	if err := pullCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() error = %v", err)
+	}
+
+	// Change both projects in the registry
+	testhelpers.CreateTestProtoFile(t, filepath.Join(workDir, "protos", "team", "service"), "v1/api.proto",
+		"syntax = \"proto3\";\npackage team.service.v1;\nmessage Updated {}")
+	os.Remove(filepath.Join(otherDir, "v1", "old.proto"))
+	testhelpers.CreateTestProtoFile(t, otherDir, "v1/new.proto", "syntax = \"proto3\";\npackage team.other.v1;")
+	commitAndPush(t, workDir, "Update projects")
+
+	combined := cmd.PullCmd{Projects: []string{"team/service"}, UpdateAll: true}
+	if err := combined.Run(globals, ctx); err == nil {
+		t.Error("PullCmd.Run() with --update-all and projects should fail")
+	}
+
+	// Removed files are only deleted with --force
+	updateCmd := cmd.PullCmd{UpdateAll: true, NoDeps: true}
+	if err := updateCmd.Run(globals, ctx); err == nil {
+		t.Fatal("PullCmd.Run() --update-all without --force should refuse to delete files")
+	}
+	updateCmd.Force = true
+	if err := updateCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() --update-all error = %v", err)
+	}
+
+	vendorDir := filepath.Join(wsDir, "vendor-proto")
+	if got := testhelpers.ReadFile(t, filepath.Join(vendorDir, "team", "service", "v1", "api.proto")); !strings.Contains(got, "Updated") {
+		t.Errorf("team/service was not updated, content = %q", got)
+	}
+	if !testhelpers.FileExists(filepath.Join(vendorDir, "team", "other", "v1", "new.proto")) {
+		t.Error("team/other new file was not received")
+	}
+	if testhelpers.FileExists(filepath.Join(vendorDir, "team", "other", "v1", "old.proto")) {
+		t.Error("team/other removed file was not deleted")
+	}
+
+	// Both projects are pinned to the same snapshot
+	ws, err := local.Open(ctx, wsDir)
+	if err != nil {
+		t.Fatalf("local.Open() error = %v", err)
+	}
+	serviceLock, err := ws.GetProjectLock("team/service")
+	if err != nil {
+		t.Fatalf("GetProjectLock() error = %v", err)
+	}
+	otherLock, err := ws.GetProjectLock("team/other")
+	if err != nil {
+		t.Fatalf("GetProjectLock() error = %v", err)
+	}
+	head, _ := exec.Command("git", "--git-dir", registryDir, "rev-parse", "HEAD").Output()
+	if serviceLock.Snapshot != strings.TrimSpace(string(head)) || otherLock.Snapshot != serviceLock.Snapshot {
+		t.Errorf("lock snapshots = %s, %s, want %s", serviceLock.Snapshot, otherLock.Snapshot, strings.TrimSpace(string(head)))
+	}
+}
+
+func TestPullCmd_UpdateAllPrune(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+
+	otherDir := filepath.Join(workDir, "protos", "team", "other")
+	testhelpers.CreateTestProtoFile(t, otherDir, "protato.root.yaml", "service: test-service\n")
+	testhelpers.CreateTestProtoFile(t, otherDir, "v1/other.proto", "syntax = \"proto3\";\npackage team.other.v1;")
+	commitAndPush(t, workDir, "Add other project")
+
+	wsDir, _ := testhelpers.SetupTestWorkspace(t)
+	gitInit := exec.Command("git", "init")
+	gitInit.Dir = wsDir
+	if err := gitInit.Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	pullCmd := cmd.PullCmd{Projects: []string{"team/service", "team/other"}, NoDeps: true}
+	if err := pullCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() error = %v", err)
+	}
+
+	// Remove team/other from the registry
+	if err := os.RemoveAll(otherDir); err != nil {
+		t.Fatal(err)
+	}
+	commitAndPush(t, workDir, "Remove other project")
+
+	prune := cmd.PullCmd{Prune: true}
+	if err := prune.Run(globals, ctx); err == nil {
+		t.Error("PullCmd.Run() with --prune and no --update-all should fail")
+	}
+
+	// Without --prune the removed project cannot be pulled
+	update := cmd.PullCmd{UpdateAll: true, NoDeps: true}
+	if err := update.Run(globals, ctx); err == nil {
+		t.Error("PullCmd.Run() --update-all of a removed project should fail without --prune")
+	}
+
+	update.Prune = true
+	if err := update.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() --update-all --prune error = %v", err)
+	}
+
+	vendorDir := filepath.Join(wsDir, "vendor-proto")
+	if testhelpers.FileExists(filepath.Join(vendorDir, "team", "other")) {
+		t.Error("team/other was not pruned")
+	}
+	if !testhelpers.FileExists(filepath.Join(vendorDir, "team", "service", "v1", "api.proto")) {
+		t.Error("team/service was pruned, want it kept")
+	}
+}
+
+func TestPullCmd_SinceSnapshot(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+
+	otherDir := filepath.Join(workDir, "protos", "team", "other")
+	testhelpers.CreateTestProtoFile(t, otherDir, "protato.root.yaml", "service: test-service\n")
+	testhelpers.CreateTestProtoFile(t, otherDir, "v1/other.proto", "syntax = \"proto3\";\npackage team.other.v1;")
+	commitAndPush(t, workDir, "Add other project")
+
+	wsDir, _ := testhelpers.SetupTestWorkspace(t)
+	gitInit := exec.Command("git", "init")
+	gitInit.Dir = wsDir
+	if err := gitInit.Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	pullCmd := cmd.PullCmd{Projects: []string{"team/service", "team/other"}, NoDeps: true}
+	if err := pullCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() error = %v", err)
+	}
+	before, _ := exec.Command("git", "--git-dir", registryDir, "rev-parse", "HEAD").Output()
+	since := strings.TrimSpace(string(before))
+
+	// Only team/service changes
+	testhelpers.CreateTestProtoFile(t, filepath.Join(workDir, "protos", "team", "service"), "v1/api.proto",
+		"syntax = \"proto3\";\npackage team.service.v1;\nmessage Updated {}")
+	commitAndPush(t, workDir, "Update service")
+	after, _ := exec.Command("git", "--git-dir", registryDir, "rev-parse", "HEAD").Output()
+	head := strings.TrimSpace(string(after))
+
+	rep := &recordingReporter{}
+	globals.Reporter = rep
+	// A plain pull moves received projects to the latest snapshot, so the filter applies without --update-all
+	updateCmd := cmd.PullCmd{NoDeps: true, SinceSnapshot: since}
+	if err := updateCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() --since-snapshot error = %v", err)
+	}
+
+	if len(rep.stats) != 1 || rep.stats[0].Projects != 1 {
+		t.Fatalf("stats = %+v, want one batch with one project", rep.stats)
+	}
+	for _, f := range rep.pulled {
+		if f.Project != "team/service" {
+			t.Errorf("pulled file %s from unchanged project %s", f.Path, f.Project)
+		}
+	}
+
+	ws, err := local.Open(ctx, wsDir)
+	if err != nil {
+		t.Fatalf("local.Open() error = %v", err)
+	}
+	serviceLock, err := ws.GetProjectLock("team/service")
+	if err != nil {
+		t.Fatalf("GetProjectLock() error = %v", err)
+	}
+	otherLock, err := ws.GetProjectLock("team/other")
+	if err != nil {
+		t.Fatalf("GetProjectLock() error = %v", err)
+	}
+	if serviceLock.Snapshot != head {
+		t.Errorf("team/service lock snapshot = %s, want %s", serviceLock.Snapshot, head)
+	}
+	if otherLock.Snapshot != since {
+		t.Errorf("team/other lock snapshot = %s, want unchanged %s", otherLock.Snapshot, since)
+	}
+}
+
+// recordingReporter collects the events a command reports.
+type recordingReporter struct {
+	listed      []cmd.ListedProject
+	pulled      []cmd.PulledFile
+	diagnostics []cmd.Diagnostic
+	progress    []cmd.Progress
+	stats       []cmd.Stats
+}
+
+func (r *recordingReporter) ProjectListed(p cmd.ListedProject) { r.listed = append(r.listed, p) }
+func (r *recordingReporter) FilePulled(f cmd.PulledFile)       { r.pulled = append(r.pulled, f) }
+func (r *recordingReporter) Diagnostic(d cmd.Diagnostic)       { r.diagnostics = append(r.diagnostics, d) }
+func (r *recordingReporter) Progress(p cmd.Progress)           { r.progress = append(r.progress, p) }
+func (r *recordingReporter) Stats(s cmd.Stats)                 { r.stats = append(r.stats, s) }
+
+func TestPullCmd_Reporter(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+
+	wsDir, _ := testhelpers.SetupTestWorkspace(t)
+	gitInit := exec.Command("git", "init")
+	gitInit.Dir = wsDir
+	if err := gitInit.Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	rec := &recordingReporter{}
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+		Reporter:    rec,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	pullCmd := cmd.PullCmd{Projects: []string{"team/service"}, NoDeps: true}
+	if err := pullCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() error = %v", err)
+	}
+
+	wantPulled := []cmd.PulledFile{{Project: "team/service", Path: "v1/api.proto"}}
+	if len(rec.pulled) != len(wantPulled) || rec.pulled[0] != wantPulled[0] {
+		t.Errorf("pulled files = %+v, want %+v", rec.pulled, wantPulled)
+	}
+
+	head, _ := exec.Command("git", "--git-dir", registryDir, "rev-parse", "HEAD").Output()
+	wantStats := cmd.Stats{Command: "pull", Projects: 1, Changed: 1, Snapshot: git.Hash(strings.TrimSpace(string(head)))}
+	if len(rec.stats) != 1 || rec.stats[0] != wantStats {
+		t.Errorf("stats = %+v, want [%+v]", rec.stats, wantStats)
+	}
+	wantProgress := []cmd.Progress{{Command: "pull", Project: "team/service", Done: 1, Total: 1}}
+	if len(rec.progress) != len(wantProgress) || rec.progress[0] != wantProgress[0] {
+		t.Errorf("progress = %+v, want %+v", rec.progress, wantProgress)
+	}
+	if len(rec.listed) != 0 || len(rec.diagnostics) != 0 {
+		t.Errorf("unexpected events: listed = %+v, diagnostics = %+v", rec.listed, rec.diagnostics)
+	}
+
+	listCmd := cmd.ListCmd{Local: true}
+	if err := listCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("ListCmd.Run() error = %v", err)
+	}
+	wantListed := []cmd.ListedProject{{Kind: cmd.ProjectKindPulled, Project: "team/service", Snapshot: wantStats.Snapshot}}
+	if len(rec.listed) != len(wantListed) || rec.listed[0] != wantListed[0] {
+		t.Errorf("listed = %+v, want %+v", rec.listed, wantListed)
+	}
+}
+
+func TestPullCmd_RefAsBranch(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+	serviceDir := filepath.Join(workDir, "protos", "team", "service")
+
+	// Publish a second project on the default branch
+	otherDir := filepath.Join(workDir, "protos", "team", "other")
+	testhelpers.CreateTestProtoFile(t, otherDir, "protato.root.yaml", "service: test-service\n")
+	testhelpers.CreateTestProtoFile(t, otherDir, "v1/other.proto", "syntax = \"proto3\";\npackage team.other.v1;")
+	commitAndPush(t, workDir, "Add other project")
+
+	// Publish a release branch that changes team/service
+	checkout := exec.Command("git", "checkout", "-b", "release")
+	checkout.Dir = workDir
+	if out, err := checkout.CombinedOutput(); err != nil {
+		t.Fatalf("git checkout: %v, output: %s", err, out)
+	}
+	testhelpers.CreateTestProtoFile(t, serviceDir, "v1/api.proto", "syntax = \"proto3\";\npackage team.service.v1;\nmessage ReleaseOne {}")
+	commitAndPush(t, workDir, "Release one")
+
+	wsDir, _ := testhelpers.SetupTestWorkspace(t)
+	gitInit := exec.Command("git", "init")
+	gitInit.Dir = wsDir
+	if err := gitInit.Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	tracked := cmd.PullCmd{Projects: []string{"team/service"}, NoDeps: true, RefAsBranch: "release"}
+	if err := tracked.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() --ref-as-branch error = %v", err)
+	}
+	pinned := cmd.PullCmd{Projects: []string{"team/other"}, NoDeps: true}
+	if err := pinned.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() error = %v", err)
+	}
+
+	ws, err := local.Open(ctx, wsDir)
+	if err != nil {
+		t.Fatalf("local.Open() error = %v", err)
+	}
+	revParse := func(rev string) string {
+		out, err := exec.Command("git", "--git-dir", registryDir, "rev-parse", rev).Output()
+		if err != nil {
+			t.Fatalf("git rev-parse %s: %v", rev, err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	serviceLock, err := ws.GetProjectLock("team/service")
+	if err != nil {
+		t.Fatalf("GetProjectLock() error = %v", err)
+	}
+	if serviceLock.Branch != "release" || serviceLock.Snapshot != revParse("refs/heads/release") {
+		t.Errorf("team/service lock = %+v, want branch release at %s", serviceLock, revParse("refs/heads/release"))
+	}
+	otherLock, err := ws.GetProjectLock("team/other")
+	if err != nil {
+		t.Fatalf("GetProjectLock() error = %v", err)
+	}
+	if otherLock.Branch != "" {
+		t.Errorf("team/other lock branch = %q, want none", otherLock.Branch)
+	}
+
+	// Advance the release branch and main; a plain pull follows release for team/service and
+	// moves team/other, which tracks no branch, to the latest default snapshot
+	testhelpers.CreateTestProtoFile(t, serviceDir, "v1/api.proto", "syntax = \"proto3\";\npackage team.service.v1;\nmessage ReleaseTwo {}")
+	commitAndPush(t, workDir, "Release two")
+	checkout = exec.Command("git", "checkout", "master")
+	checkout.Dir = workDir
+	if out, err := checkout.CombinedOutput(); err != nil {
+		t.Fatalf("git checkout: %v, output: %s", err, out)
+	}
+	testhelpers.CreateTestProtoFile(t, otherDir, "v1/other.proto", "syntax = \"proto3\";\npackage team.other.v1;\nmessage MainTwo {}")
+	commitAndPush(t, workDir, "Main two")
+
+	updateCmd := cmd.PullCmd{NoDeps: true}
+	if err := updateCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() update error = %v", err)
+	}
+
+	vendorDir := filepath.Join(wsDir, "vendor-proto")
+	if got := testhelpers.ReadFile(t, filepath.Join(vendorDir, "team", "service", "v1", "api.proto")); !strings.Contains(got, "ReleaseTwo") {
+		t.Errorf("team/service did not follow the release branch, content = %q", got)
+	}
+	serviceLock, _ = ws.GetProjectLock("team/service")
+	if serviceLock.Branch != "release" || serviceLock.Snapshot != revParse("refs/heads/release") {
+		t.Errorf("team/service lock = %+v, want branch release at %s", serviceLock, revParse("refs/heads/release"))
+	}
+	updatedOther, _ := ws.GetProjectLock("team/other")
+	if updatedOther.Snapshot != revParse("refs/heads/master") || updatedOther.Branch != "" {
+		t.Errorf("team/other lock = %+v, want the default snapshot %s", updatedOther, revParse("refs/heads/master"))
+	}
+	if got := testhelpers.ReadFile(t, filepath.Join(vendorDir, "team", "other", "v1", "other.proto")); !strings.Contains(got, "MainTwo") {
+		t.Errorf("team/other did not follow main, content = %q", got)
+	}
+}
+
+func TestPullCmd_PinnedRef(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+	serviceDir := filepath.Join(workDir, "protos", "team", "service")
+
+	// Tag the first snapshot, then change team/service twice
+	registryGit(t, registryDir, "tag", "v1.0.0", "HEAD")
+	tagged := registryGit(t, registryDir, "rev-parse", "v1.0.0^{commit}")
+	testhelpers.CreateTestProtoFile(t, serviceDir, "v1/api.proto", "syntax = \"proto3\";\npackage team.service.v1;\nmessage Two {}")
+	commitAndPush(t, workDir, "Add Two")
+	second := registryGit(t, registryDir, "rev-parse", "HEAD")
+	testhelpers.CreateTestProtoFile(t, serviceDir, "v1/api.proto", "syntax = \"proto3\";\npackage team.service.v1;\nmessage Three {}")
+	commitAndPush(t, workDir, "Add Three")
+
+	wsDir, _ := testhelpers.SetupTestWorkspace(t)
+	gitInit := exec.Command("git", "init")
+	gitInit.Dir = wsDir
+	if err := gitInit.Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	apiFile := filepath.Join(wsDir, "vendor-proto", "team", "service", "v1", "api.proto")
+
+	tests := []struct {
+		ref          string
+		wantSnapshot string
+		want         string
+		notWant      string
+	}{
+		{ref: "v1.0.0", wantSnapshot: tagged, notWant: "message"},
+		{ref: second[:10], wantSnapshot: second, want: "Two"},
+	}
+	for _, tt := range tests {
+		pull := cmd.PullCmd{Projects: []string{"team/service@" + tt.ref}, NoDeps: true, Force: true}
+		if err := pull.Run(globals, ctx); err != nil {
+			t.Fatalf("PullCmd.Run() @%s error = %v", tt.ref, err)
+		}
+
+		got := testhelpers.ReadFile(t, apiFile)
+		if tt.want != "" && !strings.Contains(got, tt.want) {
+			t.Errorf("@%s: content = %q, want %q", tt.ref, got, tt.want)
+		}
+		if tt.notWant != "" && strings.Contains(got, tt.notWant) {
+			t.Errorf("@%s: content = %q, must not contain %q", tt.ref, got, tt.notWant)
+		}
+
+		ws, err := local.Open(ctx, wsDir)
+		if err != nil {
+			t.Fatalf("local.Open() error = %v", err)
+		}
+		lock, err := ws.GetProjectLock("team/service")
+		if err != nil {
+			t.Fatalf("GetProjectLock() error = %v", err)
+		}
+		if lock.Ref != tt.ref || lock.ResolvedCommit != tt.wantSnapshot || lock.Snapshot != tt.wantSnapshot {
+			t.Errorf("@%s: lock = %+v, want ref %s at %s", tt.ref, lock, tt.ref, tt.wantSnapshot)
+		}
+	}
+
+	// A plain pull keeps the project on its pinned ref
+	update := cmd.PullCmd{UpdateAll: true, NoDeps: true}
+	if err := update.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() --update-all error = %v", err)
+	}
+	if got := testhelpers.ReadFile(t, apiFile); strings.Contains(got, "Three") {
+		t.Errorf("pinned project moved to the latest snapshot, content = %q", got)
+	}
+
+	unknown := cmd.PullCmd{Projects: []string{"team/service@no-such-tag"}, NoDeps: true}
+	if err := unknown.Run(globals, ctx); err == nil {
+		t.Error("PullCmd.Run() with an unknown ref should fail")
+	}
+}
+
+func TestPullCmd_OutputDir(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+
+	wsDir, _ := testhelpers.SetupTestWorkspace(t)
+	gitInit := exec.Command("git", "init")
+	gitInit.Dir = wsDir
+	if err := gitInit.Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	outDir := filepath.Join(tmpDir, "export")
+	pull := cmd.PullCmd{Projects: []string{"team/service"}, NoDeps: true, OutputDir: outDir}
+	if err := pull.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() error = %v", err)
+	}
+
+	exported := filepath.Join(outDir, "team", "service")
+	if !testhelpers.FileExists(filepath.Join(exported, "v1", "api.proto")) {
+		t.Error("v1/api.proto was not exported to the output dir")
+	}
+	for _, name := range []string{constants.LockFileName, constants.ManifestFileName, constants.GitattributesName} {
+		if testhelpers.FileExists(filepath.Join(exported, name)) {
+			t.Errorf("%s written to the output dir, want an untracked export", name)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(wsDir, "vendor-proto", "team", "service")); !os.IsNotExist(err) {
+		t.Errorf("configured vendor dir was touched: %v", err)
+	}
+	ws, err := local.Open(ctx, wsDir)
+	if err != nil {
+		t.Fatalf("local.Open() error = %v", err)
+	}
+	received, err := ws.ReceivedProjects(ctx)
+	if err != nil {
+		t.Fatalf("ReceivedProjects() error = %v", err)
+	}
+	if len(received) != 0 {
+		t.Errorf("ReceivedProjects() = %v, want none after an export", received)
+	}
+}
+
+func TestPullCmd_BufYAML(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+
+	wsDir, _ := testhelpers.SetupTestWorkspace(t)
+	gitInit := exec.Command("git", "init")
+	gitInit.Dir = wsDir
+	if err := gitInit.Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	bufPath := filepath.Join(wsDir, "buf.yaml")
+	existing := "version: v2\nmodules:\n  - path: proto\nlint:\n  use:\n    - STANDARD\n"
+	if err := os.WriteFile(bufPath, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	pullCmd := cmd.PullCmd{Projects: []string{"team/service"}, BufYAML: true}
+	if err := pullCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() error = %v", err)
+	}
+
+	got, err := os.ReadFile(bufPath)
+	if err != nil {
+		t.Fatalf("read buf.yaml: %v", err)
+	}
+	want := "version: v2\nmodules:\n  - path: proto\n  - path: vendor-proto\nlint:\n  use:\n    - STANDARD\n"
+	if string(got) != want {
+		t.Errorf("buf.yaml =\n%s\nwant\n%s", got, want)
+	}
+
+	// A second pull leaves the file as is
+	if err := pullCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() second run error = %v", err)
+	}
+	again, _ := os.ReadFile(bufPath)
+	if string(again) != want {
+		t.Errorf("buf.yaml changed on second pull:\n%s", again)
+	}
+}
+
+func TestPullCmd_LockOnly(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+
+	wsDir, _ := testhelpers.SetupTestWorkspace(t)
+	gitInit := exec.Command("git", "init")
+	gitInit.Dir = wsDir
+	if err := gitInit.Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	pullCmd := cmd.PullCmd{Projects: []string{"team/service"}, NoDeps: true}
+	if err := pullCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() error = %v", err)
+	}
+	ws, err := local.Open(ctx, wsDir)
+	if err != nil {
+		t.Fatalf("local.Open() error = %v", err)
+	}
+	registryHead := func() string {
+		head, _ := exec.Command("git", "--git-dir", registryDir, "rev-parse", "HEAD").Output()
+		return strings.TrimSpace(string(head))
+	}
+	lockSnapshot := func() string {
+		lock, err := ws.GetProjectLock("team/service")
+		if err != nil {
+			t.Fatalf("GetProjectLock() error = %v", err)
+		}
+		return lock.Snapshot
+	}
+
+	// A registry change elsewhere leaves team/service identical, so its lock can move
+	otherDir := filepath.Join(workDir, "protos", "team", "other")
+	testhelpers.CreateTestProtoFile(t, otherDir, "protato.root.yaml", "service: test-service\n")
+	testhelpers.CreateTestProtoFile(t, otherDir, "v1/other.proto", "syntax = \"proto3\";\npackage team.other.v1;")
+	commitAndPush(t, workDir, "Add other project")
+
+	protoPath := filepath.Join(wsDir, "vendor-proto", "team", "service", "v1", "api.proto")
+	before, err := os.Stat(protoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// --lock-only alone advances the lock, as a plain pull would
+	initial := lockSnapshot()
+	if err := (&cmd.PullCmd{LockOnly: true}).Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() --lock-only error = %v", err)
+	}
+	if got, want := lockSnapshot(), registryHead(); got != want || got == initial {
+		t.Errorf("lock snapshot = %s, want %s (moved from %s)", got, want, initial)
+	}
+	lockOnly := cmd.PullCmd{UpdateAll: true, LockOnly: true}
+	if after, _ := os.Stat(protoPath); !after.ModTime().Equal(before.ModTime()) {
+		t.Error("--lock-only rewrote a received file")
+	}
+	if testhelpers.FileExists(filepath.Join(wsDir, "vendor-proto", "team", "other")) {
+		t.Error("--lock-only received a new project")
+	}
+
+	// A change to team/service itself is refused and the lock stays put
+	bumped := lockSnapshot()
+	testhelpers.CreateTestProtoFile(t, filepath.Join(workDir, "protos", "team", "service"), "v1/api.proto",
+		"syntax = \"proto3\";\npackage team.service.v1;\nmessage Updated {}")
+	commitAndPush(t, workDir, "Update service")
+
+	err = lockOnly.Run(globals, ctx)
+	if err == nil || !strings.Contains(err.Error(), "v1/api.proto") {
+		t.Fatalf("PullCmd.Run() --lock-only error = %v, want a difference in v1/api.proto", err)
+	}
+	if got := lockSnapshot(); got != bumped {
+		t.Errorf("lock snapshot = %s after refused --lock-only, want %s", got, bumped)
+	}
+	if got := testhelpers.ReadFile(t, protoPath); strings.Contains(got, "Updated") {
+		t.Error("refused --lock-only updated the received file")
+	}
+}
+
+func TestPullCmd_LockOnlySkipsUnreceivedDeps(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+
+	// team/service depends on team/other, which is never received
+	otherDir := filepath.Join(workDir, "protos", "team", "other")
+	testhelpers.CreateTestProtoFile(t, otherDir, "protato.root.yaml", "service: test-service\n")
+	testhelpers.CreateTestProtoFile(t, otherDir, "v1/other.proto", "syntax = \"proto3\";\npackage team.other.v1;\nmessage Other {}")
+	testhelpers.CreateTestProtoFile(t, filepath.Join(workDir, "protos", "team", "service"), "v1/api.proto",
+		"syntax = \"proto3\";\npackage team.service.v1;\nimport \"team/other/v1/other.proto\";\nmessage Service { team.other.v1.Other other = 1; }")
+	commitAndPush(t, workDir, "Add dependency")
+
+	wsDir, _ := testhelpers.SetupTestWorkspace(t)
+	gitInit := exec.Command("git", "init")
+	gitInit.Dir = wsDir
+	if err := gitInit.Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	pullCmd := cmd.PullCmd{Projects: []string{"team/service"}, NoDeps: true}
+	if err := pullCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() error = %v", err)
+	}
+
+	lockOnly := cmd.PullCmd{UpdateAll: true, LockOnly: true}
+	if err := lockOnly.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() --lock-only error = %v, want the unreceived dependency skipped", err)
+	}
+	if testhelpers.FileExists(filepath.Join(wsDir, "vendor-proto", "team", "other")) {
+		t.Error("--lock-only received a dependency")
+	}
+}
+
+func TestPullCmd_CheckOnly(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+
+	for _, name := range []string{"other", "gone"} {
+		dir := filepath.Join(workDir, "protos", "team", name)
+		testhelpers.CreateTestProtoFile(t, dir, "protato.root.yaml", "service: test-service\n")
+		testhelpers.CreateTestProtoFile(t, dir, "v1/api.proto", "syntax = \"proto3\";\npackage team."+name+".v1;")
+	}
+	commitAndPush(t, workDir, "Add projects")
+
+	wsDir, _ := testhelpers.SetupTestWorkspace(t)
+	gitInit := exec.Command("git", "init")
+	gitInit.Dir = wsDir
+	if err := gitInit.Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	rec := &recordingReporter{}
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+		Reporter:    rec,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	pullCmd := cmd.PullCmd{Projects: []string{"team/service", "team/other", "team/gone"}, NoDeps: true}
+	if err := pullCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() error = %v", err)
+	}
+	messages := func() []string {
+		var got []string
+		for _, d := range rec.diagnostics {
+			got = append(got, d.Message)
+		}
+		rec.diagnostics = nil
+		return got
+	}
+
+	check := cmd.PullCmd{CheckOnly: true}
+	if err := check.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() --check-only error = %v", err)
+	}
+	if got := messages(); len(got) != 1 || got[0] != "Received projects are up to date" {
+		t.Errorf("--check-only before any change reported %q", got)
+	}
+
+	// Change team/service, remove team/gone and leave team/other alone
+	testhelpers.CreateTestProtoFile(t, filepath.Join(workDir, "protos", "team", "service"), "v1/api.proto",
+		"syntax = \"proto3\";\npackage team.service.v1;\nmessage Updated {}")
+	os.RemoveAll(filepath.Join(workDir, "protos", "team", "gone"))
+	commitAndPush(t, workDir, "Update service, remove gone")
+	head, _ := exec.Command("git", "--git-dir", registryDir, "rev-parse", "HEAD").Output()
+
+	if err := check.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() --check-only error = %v", err)
+	}
+	got := messages()
+	if len(got) != 2 || got[0] != "team/gone: removed from the registry" ||
+		!strings.HasPrefix(got[1], "team/service: stale") || !strings.HasSuffix(got[1], git.Hash(strings.TrimSpace(string(head))).Short()) {
+		t.Errorf("--check-only reported %q, want team/gone removed and team/service stale", got)
+	}
+	if content := testhelpers.ReadFile(t, filepath.Join(wsDir, "vendor-proto", "team", "service", "v1", "api.proto")); strings.Contains(content, "Updated") {
+		t.Error("--check-only pulled team/service")
+	}
+
+	only := cmd.PullCmd{CheckOnly: true, Projects: []string{"team/other"}}
+	if err := only.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() --check-only team/other error = %v", err)
+	}
+	if got := messages(); len(got) != 1 || got[0] != "Received projects are up to date" {
+		t.Errorf("--check-only team/other reported %q", got)
+	}
+}
+
+func TestPullCmd_RerunAfterPartialPull(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+
+	wsDir, _ := testhelpers.SetupTestWorkspace(t)
+	gitInit := exec.Command("git", "init")
+	gitInit.Dir = wsDir
+	if err := gitInit.Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	pullCmd := cmd.PullCmd{Projects: []string{"team/service"}, NoDeps: true}
+	if err := pullCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() error = %v", err)
+	}
+
+	typesContent := "syntax = \"proto3\";\npackage team.service.v1;\nmessage Types {}"
+	testhelpers.CreateTestProtoFile(t, filepath.Join(workDir, "protos", "team", "service"), "v1/types.proto", typesContent)
+	commitAndPush(t, workDir, "Add types")
+
+	// An interrupted pull wrote half of one file and one that is not upstream, then stopped before Finish
+	ws, err := local.Open(ctx, wsDir)
+	if err != nil {
+		t.Fatalf("local.Open() error = %v", err)
+	}
+	recv, err := ws.ReceiveProject(&local.ReceiveProjectRequest{Project: "team/service", Snapshot: "partial"})
+	if err != nil {
+		t.Fatalf("ReceiveProject() error = %v", err)
+	}
+	for path, content := range map[string]string{"v1/types.proto": "syntax = \"pro", "v2/stale.proto": "syntax = \"proto3\";"} {
+		w, err := recv.CreateFile(path)
+		if err != nil {
+			t.Fatalf("CreateFile() error = %v", err)
+		}
+		w.Write([]byte(content))
+		w.Close()
+	}
+
+	// The re-run needs no --force, since the interrupted pull left the project untouched
+	if err := pullCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() re-run error = %v", err)
+	}
+
+	projectDir := filepath.Join(wsDir, "vendor-proto", "team", "service")
+	files, err := ws.ListVendorProjectFiles("team/service")
+	if err != nil {
+		t.Fatalf("ListVendorProjectFiles() error = %v", err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, f.Path)
+	}
+	if want := []string{"v1/api.proto", "v1/types.proto"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("vendored files = %v, want %v", got, want)
+	}
+	if got := testhelpers.ReadFile(t, filepath.Join(projectDir, "v1", "types.proto")); got != typesContent {
+		t.Errorf("v1/types.proto = %q, want %q", got, typesContent)
+	}
+	mismatched, err := ws.VerifyVendorIntegrity("team/service")
+	if err != nil {
+		t.Fatalf("VerifyVendorIntegrity() error = %v", err)
+	}
+	if len(mismatched) != 0 {
+		t.Errorf("VerifyVendorIntegrity() = %v, want none", mismatched)
+	}
+}
+
+func TestPullCmd_TransitiveDependencies(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+
+	// team/service imports team/mid, which imports a project of another service
+	testhelpers.CreateTestProtoFile(t, filepath.Join(workDir, "protos", "team", "service"), "v1/api.proto",
+		"syntax = \"proto3\";\npackage team.service.v1;\nimport \"team/mid/v1/mid.proto\";")
+	midDir := filepath.Join(workDir, "protos", "team", "mid")
+	testhelpers.CreateTestProtoFile(t, midDir, "protato.root.yaml", "service: test-service\n")
+	testhelpers.CreateTestProtoFile(t, midDir, "v1/mid.proto",
+		"syntax = \"proto3\";\npackage team.mid.v1;\nimport \"other/base/v1/base.proto\";")
+	baseDir := filepath.Join(workDir, "protos", "other", "base")
+	testhelpers.CreateTestProtoFile(t, baseDir, "protato.root.yaml", "service: other-service\n")
+	testhelpers.CreateTestProtoFile(t, baseDir, "v1/base.proto", "syntax = \"proto3\";\npackage other.base.v1;")
+	commitAndPush(t, workDir, "Add dependencies")
+
+	wsDir, _ := testhelpers.SetupTestWorkspace(t)
+	gitInit := exec.Command("git", "init")
+	gitInit.Dir = wsDir
+	if err := gitInit.Run(); err != nil {
+		t.Fatalf("git init: %v", err)
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	pullCmd := cmd.PullCmd{Projects: []string{"team/service"}}
+	if err := pullCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("PullCmd.Run() error = %v", err)
+	}
+
+	ws, err := local.Open(ctx, wsDir)
+	if err != nil {
+		t.Fatalf("local.Open() error = %v", err)
+	}
+	received, err := ws.ReceivedProjects(ctx)
+	if err != nil {
+		t.Fatalf("ReceivedProjects() error = %v", err)
+	}
+	var got []string
+	for _, r := range received {
+		got = append(got, string(r.Project))
+	}
+	sort.Strings(got)
+	if want := []string{"other/base", "team/mid", "team/service"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("received projects = %v, want %v", got, want)
+	}
+}
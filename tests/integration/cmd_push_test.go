@@ -4,9 +4,14 @@ import (
 	"context"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/rahulagarwal0605/protato/cmd"
+	"github.com/rahulagarwal0605/protato/internal/constants"
+	"github.com/rahulagarwal0605/protato/internal/local"
+	"github.com/rahulagarwal0605/protato/internal/logger"
 	"github.com/rahulagarwal0605/protato/tests/testhelpers"
 )
 
@@ -106,3 +111,145 @@ func TestPushCmd_RetryLogic(t *testing.T) {
 
 	_ = ws // Use ws to avoid unused variable
 }
+
+func TestPushCmd_CheckBreaking(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+
+	wsDir, _ := testhelpers.SetupTestWorkspaceWithConfig(t, &local.Config{
+		Service:     "test-service",
+		Directories: local.DirectoryConfig{Owned: "proto", Vendor: "vendor-proto"},
+		Projects:    []string{"billing"},
+	})
+	writeBilling := func(body string) {
+		testhelpers.CreateTestProject(t, wsDir, "proto/billing", map[string]string{
+			"billing.proto": "syntax = \"proto3\";\npackage test_service.billing;\n" + body,
+		})
+	}
+	git := func(args ...string) {
+		c := exec.Command("git", args...)
+		c.Dir = wsDir
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v, output: %s", args, err, out)
+		}
+	}
+	writeBilling("message Invoice { string id = 1; int64 amount = 2; }")
+	git("init")
+	git("config", "user.email", "test@example.com")
+	git("config", "user.name", "Test User")
+	git("remote", "add", "origin", "https://example.com/acme/billing.git")
+	git("add", ".")
+	git("commit", "-m", "Add billing")
+
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	first := cmd.PushCmd{CheckBreaking: true}
+	if err := first.Run(globals, ctx); err != nil {
+		t.Fatalf("first push error = %v", err)
+	}
+
+	// Dropping a field breaks consumers of the pushed version
+	writeBilling("message Invoice { string id = 1; }")
+	git("commit", "-am", "Drop amount")
+
+	checked := cmd.PushCmd{CheckBreaking: true}
+	err := checked.Run(globals, ctx)
+	if err == nil || !strings.Contains(err.Error(), constants.ErrMsgBreakingChanges) {
+		t.Fatalf("push --check-breaking error = %v, want %q", err, constants.ErrMsgBreakingChanges)
+	}
+
+	forced := cmd.PushCmd{CheckBreaking: true, Force: true}
+	if err := forced.Run(globals, ctx); err != nil {
+		t.Fatalf("push --check-breaking --force error = %v", err)
+	}
+	out, err := exec.Command("git", "--git-dir", registryDir, "show", "HEAD:protos/test-service/billing/billing.proto").Output()
+	if err != nil {
+		t.Fatalf("git show: %v", err)
+	}
+	if strings.Contains(string(out), "amount") {
+		t.Errorf("forced push did not publish the change:\n%s", out)
+	}
+}
+
+func TestPushCmd_MultipleOwnedDirs(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+
+	// team/a in the first owned dir imports team/b in the second one
+	wsDir, _ := testhelpers.SetupTestWorkspaceWithConfig(t, &local.Config{
+		Service: "test-service",
+		Directories: local.DirectoryConfig{
+			OwnedDirs: []string{"apis", "internal-apis"},
+			Vendor:    "vendor-proto",
+		},
+		Projects: []string{"team/a", "team/b"},
+		Lint:     local.LintConfig{Rules: []string{"enum-zero-value-unspecified"}},
+	})
+	testhelpers.CreateTestProject(t, wsDir, "apis/team/a", map[string]string{
+		"a.proto": "syntax = \"proto3\";\npackage test_service.team.a;\nimport \"internal-apis/team/b/b.proto\";\nmessage A { test_service.team.b.B b = 1; }\n",
+	})
+	testhelpers.CreateTestProject(t, wsDir, "internal-apis/team/b", map[string]string{
+		"b.proto": "syntax = \"proto3\";\npackage test_service.team.b;\nmessage B { string id = 1; }\n",
+	})
+	git := func(args ...string) {
+		c := exec.Command("git", args...)
+		c.Dir = wsDir
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v, output: %s", args, err, out)
+		}
+	}
+	git("init")
+	git("config", "user.email", "test@example.com")
+	git("config", "user.name", "Test User")
+	git("remote", "add", "origin", "https://example.com/acme/apis.git")
+	git("add", ".")
+	git("commit", "-m", "Add projects")
+
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	lintCmd := cmd.LintCmd{}
+	if err := lintCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("lint error = %v", err)
+	}
+
+	pushCmd := cmd.PushCmd{}
+	if err := pushCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("push error = %v", err)
+	}
+	out, err := exec.Command("git", "--git-dir", registryDir, "show", "HEAD:protos/test-service/team/a/a.proto").Output()
+	if err != nil {
+		t.Fatalf("git show: %v", err)
+	}
+	if !strings.Contains(string(out), `import "test-service/team/b/b.proto";`) {
+		t.Errorf("pushed a.proto does not import team/b by registry path:\n%s", out)
+	}
+
+	snapshot, err := exec.Command("git", "--git-dir", registryDir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		t.Fatalf("git rev-parse: %v", err)
+	}
+	verifyCmd := cmd.VerifyCmd{
+		Lint:     true,
+		Against:  strings.TrimSpace(string(snapshot)),
+		ImageOut: filepath.Join(tmpDir, "image.binpb"),
+	}
+	if err := verifyCmd.Run(globals, ctx); err != nil {
+		t.Fatalf("verify error = %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/cmd"
+	"github.com/rahulagarwal0605/protato/internal/constants"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/tests/testhelpers"
+)
+
+func TestReceiveCmd_FromDir(t *testing.T) {
+	tmpDir, ws := testhelpers.SetupTestWorkspace(t)
+
+	// Setup git repository
+	os.Chdir(tmpDir)
+	exec.Command("git", "init").Run()
+
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+
+	// Sibling repository protos, importing each other through the "proto" owned dir
+	srcDir := t.TempDir()
+	testhelpers.CreateTestProject(t, srcDir, "", map[string]string{
+		"v1/api.proto":   "syntax = \"proto3\";\nimport \"proto/common/v1/types.proto\";\n",
+		"v1/types.proto": "syntax = \"proto3\";\n",
+	})
+
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	receiveCmd := cmd.ReceiveCmd{
+		Project:  "other-svc/common",
+		FromDir:  srcDir,
+		OwnedDir: "proto",
+	}
+	if err := receiveCmd.Run(&cmd.GlobalOptions{}, ctx); err != nil {
+		t.Fatalf("ReceiveCmd.Run() error = %v", err)
+	}
+
+	vendorDir, _ := ws.VendorDir()
+	projectDir := filepath.Join(vendorDir, "other-svc", "common")
+
+	content := testhelpers.ReadFile(t, filepath.Join(projectDir, "v1", "api.proto"))
+	if !strings.Contains(content, `import "other-svc/common/v1/types.proto";`) {
+		t.Errorf("api.proto imports not transformed, got:\n%s", content)
+	}
+	if !testhelpers.FileExists(filepath.Join(projectDir, "v1", "types.proto")) {
+		t.Error("types.proto was not vendored")
+	}
+
+	lock, err := ws.GetProjectLock("other-svc/common")
+	if err != nil {
+		t.Fatalf("GetProjectLock() error = %v", err)
+	}
+	if !strings.HasPrefix(lock.Snapshot, constants.LocalSnapshotPrefix) {
+		t.Errorf("lock snapshot = %q, want prefix %q", lock.Snapshot, constants.LocalSnapshotPrefix)
+	}
+
+	received, err := ws.ReceivedProjects(ctx)
+	if err != nil {
+		t.Fatalf("ReceivedProjects() error = %v", err)
+	}
+	if len(received) != 1 || !received[0].IsLocal() {
+		t.Errorf("ReceivedProjects() = %v, want one local project", received)
+	}
+
+	// Removing a source file requires --force to delete the vendored copy
+	os.Remove(filepath.Join(srcDir, "v1", "types.proto"))
+	if err := receiveCmd.Run(&cmd.GlobalOptions{}, ctx); err == nil {
+		t.Error("ReceiveCmd.Run() should refuse to delete files without --force")
+	}
+	receiveCmd.Force = true
+	if err := receiveCmd.Run(&cmd.GlobalOptions{}, ctx); err != nil {
+		t.Fatalf("ReceiveCmd.Run() with --force error = %v", err)
+	}
+	if testhelpers.FileExists(filepath.Join(projectDir, "v1", "types.proto")) {
+		t.Error("types.proto should have been deleted")
+	}
+}
@@ -0,0 +1,120 @@
+package integration
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/cmd"
+	"github.com/rahulagarwal0605/protato/internal/local"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/internal/utils"
+	"github.com/rahulagarwal0605/protato/tests/testhelpers"
+)
+
+func TestRemoveCmd(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+	repoURL := "https://example.com/acme/billing.git"
+
+	// Publish test-service/billing as owned by the workspace repository
+	billingDir := filepath.Join(workDir, "protos", "test-service", "billing")
+	testhelpers.CreateTestProtoFile(t, billingDir, "protato.root.yaml", "git:\n  url: "+utils.NormalizeGitURL(repoURL)+"\n")
+	testhelpers.CreateTestProtoFile(t, billingDir, "v1/billing.proto", "syntax = \"proto3\";\npackage test_service.billing.v1;")
+	commitAndPush(t, workDir, "Add billing")
+
+	wsDir, _ := testhelpers.SetupTestWorkspaceWithConfig(t, &local.Config{
+		Service:     "test-service",
+		Directories: local.DirectoryConfig{Owned: "proto", Vendor: "vendor-proto"},
+		Projects:    []string{"billing", "draft"},
+	})
+	testhelpers.CreateTestProject(t, wsDir, "proto/billing", map[string]string{"billing.proto": "syntax = \"proto3\";"})
+	testhelpers.CreateTestProject(t, wsDir, "proto/draft", map[string]string{"draft.proto": "syntax = \"proto3\";"})
+	for _, args := range [][]string{
+		{"init"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "Test User"},
+		{"remote", "add", "origin", repoURL},
+	} {
+		git := exec.Command("git", args...)
+		git.Dir = wsDir
+		if out, err := git.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v, output: %s", args, err, out)
+		}
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	registryFiles := func() []string {
+		out, err := exec.Command("git", "--git-dir", registryDir, "ls-tree", "-r", "--name-only", "HEAD").Output()
+		if err != nil {
+			t.Fatalf("git ls-tree: %v", err)
+		}
+		return strings.Fields(string(out))
+	}
+	ownedProjects := func() []string {
+		ws, err := local.Open(ctx, wsDir)
+		if err != nil {
+			t.Fatalf("local.Open() error = %v", err)
+		}
+		projects, err := ws.OwnedProjects()
+		if err != nil {
+			t.Fatalf("OwnedProjects() error = %v", err)
+		}
+		var names []string
+		for _, p := range projects {
+			names = append(names, string(p))
+		}
+		return names
+	}
+	billingProto := "protos/test-service/billing/v1/billing.proto"
+
+	dryRun := cmd.RemoveCmd{Project: "billing", Local: true, DryRun: true}
+	if err := dryRun.Run(globals, ctx); err != nil {
+		t.Fatalf("RemoveCmd.Run() --dry-run error = %v", err)
+	}
+	if !slices.Contains(registryFiles(), billingProto) || !testhelpers.FileExists(filepath.Join(wsDir, "proto/billing")) {
+		t.Fatal("--dry-run removed the project")
+	}
+
+	remove := cmd.RemoveCmd{Project: "billing", Local: true}
+	if err := remove.Run(globals, ctx); err != nil {
+		t.Fatalf("RemoveCmd.Run() error = %v", err)
+	}
+	files := registryFiles()
+	if slices.Contains(files, billingProto) || slices.Contains(files, "protos/test-service/billing/protato.root.yaml") {
+		t.Errorf("registry still has billing: %v", files)
+	}
+	if !slices.Contains(files, "protos/team/service/v1/api.proto") {
+		t.Errorf("registry lost another project: %v", files)
+	}
+	if testhelpers.FileExists(filepath.Join(wsDir, "proto/billing")) {
+		t.Error("--local left the project files")
+	}
+
+	// A project that was never pushed is only un-claimed
+	neverPushed := cmd.RemoveCmd{Project: "draft"}
+	if err := neverPushed.Run(globals, ctx); err != nil {
+		t.Fatalf("RemoveCmd.Run() for an unpushed project error = %v", err)
+	}
+	if got := ownedProjects(); len(got) != 0 {
+		t.Errorf("owned projects = %v, want none", got)
+	}
+
+	notOwned := cmd.RemoveCmd{Project: "team/service"}
+	if err := notOwned.Run(globals, ctx); err == nil {
+		t.Error("RemoveCmd.Run() for a project this repository does not own expected error")
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
 	"github.com/rahulagarwal0605/protato/cmd"
 	"github.com/rahulagarwal0605/protato/internal/git"
 	"github.com/rahulagarwal0605/protato/internal/local"
@@ -121,3 +125,230 @@ func TestVerifyCmd_WithReceivedProjects(t *testing.T) {
 		t.Logf("VerifyCmd.Run() with received projects: %v", err)
 	}
 }
+
+// registryGit runs a git command in a bare registry and returns its trimmed output.
+func registryGit(t *testing.T, registryDir string, args ...string) string {
+	t.Helper()
+	gitCmd := exec.Command("git", args...)
+	gitCmd.Dir = registryDir
+	out, err := gitCmd.Output()
+	if err != nil {
+		t.Fatalf("git %v: %v", args, err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestVerifyCmd_Against(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+	serviceDir := filepath.Join(workDir, "protos", "team", "service")
+
+	testhelpers.CreateTestProtoFile(t, serviceDir, "v1/api.proto",
+		"syntax = \"proto3\";\npackage team.service.v1;\nmessage Money { int64 units = 1; }\n")
+	commitAndPush(t, workDir, "Add Money")
+	withMoney := registryGit(t, registryDir, "rev-parse", "HEAD")
+
+	// The dependency drops the message at the tip
+	testhelpers.CreateTestProtoFile(t, serviceDir, "v1/api.proto",
+		"syntax = \"proto3\";\npackage team.service.v1;\nmessage Amount { int64 units = 1; }\n")
+	commitAndPush(t, workDir, "Rename Money")
+	branch := registryGit(t, registryDir, "symbolic-ref", "--short", "HEAD")
+
+	wsDir, _ := testhelpers.SetupTestWorkspace(t)
+	testhelpers.CreateTestProject(t, wsDir, "proto/consumer/v1", map[string]string{
+		"consumer.proto": "syntax = \"proto3\";\npackage consumer.v1;\nimport \"team/service/v1/api.proto\";\nmessage Order { team.service.v1.Money total = 1; }\n",
+	})
+	for _, args := range [][]string{
+		{"init"},
+		{"remote", "add", "origin", "https://github.com/test/consumer.git"},
+	} {
+		gitCmd := exec.Command("git", args...)
+		gitCmd.Dir = wsDir
+		if out, err := gitCmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v, output: %s", args, err, out)
+		}
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(wsDir)
+
+	globals := &cmd.GlobalOptions{
+		CacheDir:    filepath.Join(tmpDir, "cache"),
+		RegistryURL: registryDir,
+	}
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	tests := []struct {
+		name    string
+		against string
+		wantErr bool
+	}{
+		{name: "snapshot with dependency", against: withMoney, wantErr: false},
+		{name: "branch tip without dependency", against: branch, wantErr: true},
+		{name: "unknown ref", against: "no-such-branch", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifyCmd := cmd.VerifyCmd{Against: tt.against}
+			err := verifyCmd.Run(globals, ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyCmd.Run() --against %s error = %v, wantErr %v", tt.against, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyCmd_ImageOut(t *testing.T) {
+	tmpDir, _ := testhelpers.SetupTestWorkspace(t)
+	testhelpers.CreateTestProject(t, tmpDir, "proto/team/service", map[string]string{
+		"v1/api.proto":   "syntax = \"proto3\";\npackage team.service.v1;\nimport \"proto/team/service/v1/types.proto\";\nmessage Order { Money total = 1; }\n",
+		"v1/types.proto": "syntax = \"proto3\";\npackage team.service.v1;\nimport \"google/protobuf/timestamp.proto\";\nmessage Money { google.protobuf.Timestamp at = 1; }\n",
+	})
+	for _, args := range [][]string{
+		{"init"},
+		{"remote", "add", "origin", "https://github.com/test/service.git"},
+	} {
+		gitCmd := exec.Command("git", args...)
+		gitCmd.Dir = tmpDir
+		if out, err := gitCmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v, output: %s", args, err, out)
+		}
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(tmpDir)
+
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	tests := []struct {
+		name           string
+		excludeImports bool
+		wantFiles      int
+	}{
+		{name: "with imports", wantFiles: 3},
+		{name: "exclude imports", excludeImports: true, wantFiles: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imagePath := filepath.Join(t.TempDir(), "image.binpb")
+			verifyCmd := cmd.VerifyCmd{Offline: true, ImageOut: imagePath, ExcludeImports: tt.excludeImports}
+			if err := verifyCmd.Run(&cmd.GlobalOptions{}, ctx); err != nil {
+				t.Fatalf("VerifyCmd.Run() --image-out error = %v", err)
+			}
+
+			data, err := os.ReadFile(imagePath)
+			if err != nil {
+				t.Fatalf("read image: %v", err)
+			}
+			var image descriptorpb.FileDescriptorSet
+			if err := proto.Unmarshal(data, &image); err != nil {
+				t.Fatalf("unmarshal image: %v", err)
+			}
+			if len(image.File) != tt.wantFiles {
+				t.Errorf("image has %d files, want %d", len(image.File), tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestVerifyCmd_VendorChecksums(t *testing.T) {
+	tmpDir, ws := testhelpers.SetupTestWorkspace(t)
+	receiver, err := ws.ReceiveProject(&local.ReceiveProjectRequest{Project: "external/service", Snapshot: git.Hash("abc123")})
+	if err != nil {
+		t.Fatalf("ReceiveProject() error = %v", err)
+	}
+	writer, err := receiver.CreateFile("v1/api.proto")
+	if err != nil {
+		t.Fatalf("CreateFile() error = %v", err)
+	}
+	writer.Write([]byte("syntax = \"proto3\";"))
+	writer.Close()
+	if _, err := receiver.Finish(); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"init"},
+		{"remote", "add", "origin", "https://github.com/test/consumer.git"},
+	} {
+		gitCmd := exec.Command("git", args...)
+		gitCmd.Dir = tmpDir
+		if out, err := gitCmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v, output: %s", args, err, out)
+		}
+	}
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	os.Chdir(tmpDir)
+
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	// Untouched: the checksums verify without a registry
+	verifyCmd := cmd.VerifyCmd{Offline: true}
+	if err := verifyCmd.Run(&cmd.GlobalOptions{}, ctx); err != nil {
+		t.Fatalf("VerifyCmd.Run() on an untouched project error = %v", err)
+	}
+
+	// Edited vendored file is reported
+	vendored := filepath.Join(tmpDir, "vendor-proto", "external", "service", "v1", "api.proto")
+	if err := os.WriteFile(vendored, []byte("syntax = \"proto3\";\nmessage Tampered {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyCmd.Run(&cmd.GlobalOptions{}, ctx); err == nil {
+		t.Error("VerifyCmd.Run() expected error for an edited vendored file")
+	}
+}
+
+func TestVerifyCmd_Lint(t *testing.T) {
+	tests := []struct {
+		name    string
+		lint    local.LintConfig
+		noLint  bool
+		wantErr bool
+	}{
+		{name: "lint issues fail verification", wantErr: true},
+		{name: "lint not requested", noLint: true},
+		{name: "rule disabled in config", lint: local.LintConfig{Disable: []string{"enum-zero-value-unspecified"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, _ := testhelpers.SetupTestWorkspaceWithConfig(t, &local.Config{
+				Service:      "test-service",
+				Directories:  local.DirectoryConfig{Owned: "proto", Vendor: "vendor-proto"},
+				AutoDiscover: true,
+				Lint:         tt.lint,
+			})
+			testhelpers.CreateTestProject(t, tmpDir, "proto/team/service", map[string]string{
+				"v1/api.proto": "syntax = \"proto3\";\npackage team.service.v1;\nenum Status {\n  STATUS_ACTIVE = 0;\n}\n",
+			})
+
+			for _, args := range [][]string{
+				{"init"},
+				{"remote", "add", "origin", "https://github.com/test/service.git"},
+			} {
+				gitCmd := exec.Command("git", args...)
+				gitCmd.Dir = tmpDir
+				if out, err := gitCmd.CombinedOutput(); err != nil {
+					t.Fatalf("git %v: %v, output: %s", args, err, out)
+				}
+			}
+			oldWd, _ := os.Getwd()
+			defer os.Chdir(oldWd)
+			os.Chdir(tmpDir)
+
+			log := logger.Init()
+			ctx := logger.WithLogger(context.Background(), &log)
+			verifyCmd := cmd.VerifyCmd{Offline: true, Lint: !tt.noLint}
+			err := verifyCmd.Run(&cmd.GlobalOptions{}, ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyCmd.Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
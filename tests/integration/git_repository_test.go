@@ -1,12 +1,19 @@
 package integration
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
+	protatoerrors "github.com/rahulagarwal0605/protato/internal/errors"
 	"github.com/rahulagarwal0605/protato/internal/git"
 	"github.com/rahulagarwal0605/protato/internal/logger"
 )
@@ -179,6 +186,157 @@ func TestGitRepository_ReadTree(t *testing.T) {
 	}
 }
 
+func TestGitRepository_ReadTree_SpecialPaths(t *testing.T) {
+	repoDir := setupTestGitRepo(t)
+
+	want := []string{"protos/my service/api.proto", "protos/caf\u00e9/api.proto"}
+	for _, p := range want {
+		os.MkdirAll(filepath.Join(repoDir, filepath.Dir(p)), 0755)
+		os.WriteFile(filepath.Join(repoDir, p), []byte("syntax = \"proto3\";"), 0644)
+	}
+	for _, args := range [][]string{
+		{"config", "core.quotePath", "true"},
+		{"add", "."},
+		{"commit", "--no-verify", "-m", "Add special paths"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v, output: %s", args, err, out)
+		}
+	}
+
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	repo, err := git.Open(ctx, repoDir, git.OpenOptions{Bare: false})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	entries, err := repo.ReadTree(ctx, "HEAD", git.ReadTreeOptions{Recurse: true, Paths: []string{"protos"}})
+	if err != nil {
+		t.Fatalf("ReadTree() error = %v", err)
+	}
+
+	got := make(map[string]bool)
+	for _, entry := range entries {
+		got[entry.Path] = true
+	}
+	for _, p := range want {
+		if !got[p] {
+			t.Errorf("ReadTree() missing %q, got %v", p, got)
+		}
+	}
+}
+
+func TestGitRepository_WalkTree(t *testing.T) {
+	repoDir := setupTestGitRepo(t)
+	for i := 0; i < 50; i++ {
+		p := filepath.Join(repoDir, "protos", fmt.Sprintf("p%02d", i), "api.proto")
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("syntax = \"proto3\";"), 0644)
+	}
+	for _, args := range [][]string{
+		{"add", "."},
+		{"commit", "--no-verify", "-m", "Add many projects"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v, output: %s", args, err, out)
+		}
+	}
+
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	repo, err := git.Open(ctx, repoDir, git.OpenOptions{Bare: false})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	opts := git.ReadTreeOptions{Recurse: true, Paths: []string{"protos"}}
+
+	want, err := repo.ReadTree(ctx, "HEAD", opts)
+	if err != nil {
+		t.Fatalf("ReadTree() error = %v", err)
+	}
+	var got []git.TreeEntry
+	if err := repo.WalkTree(ctx, "HEAD", opts, func(entry git.TreeEntry) error {
+		got = append(got, entry)
+		return nil
+	}); err != nil {
+		t.Fatalf("WalkTree() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WalkTree() visited %d entries, ReadTree() returned %d", len(got), len(want))
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+	err = repo.WalkTree(ctx, "HEAD", opts, func(git.TreeEntry) error {
+		calls++
+		if calls == 3 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("WalkTree() error = %v, want the callback's error", err)
+	}
+	if calls != 3 {
+		t.Errorf("callback called %d times, want 3", calls)
+	}
+
+	if err := repo.WalkTree(ctx, "nonexistent", opts, func(git.TreeEntry) error { return nil }); err == nil {
+		t.Error("WalkTree() on a missing tree succeeded, want error")
+	}
+}
+
+func TestGitRepository_MergeBase(t *testing.T) {
+	repoDir := setupTestGitRepo(t)
+
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	repo, err := git.Open(ctx, repoDir, git.OpenOptions{Bare: false})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	base, err := repo.RevHash(ctx, "HEAD")
+	if err != nil {
+		t.Fatalf("RevHash() error = %v", err)
+	}
+
+	gitRun := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v, output: %s", args, err, out)
+		}
+	}
+	gitRun("branch", "feature")
+	gitRun("commit", "--no-verify", "--allow-empty", "-m", "Main change")
+	mainTip, err := repo.RevHash(ctx, "HEAD")
+	if err != nil {
+		t.Fatalf("RevHash() error = %v", err)
+	}
+	gitRun("checkout", "feature")
+	gitRun("commit", "--no-verify", "--allow-empty", "-m", "Feature change")
+	gitRun("checkout", "--orphan", "unrelated")
+	gitRun("commit", "--no-verify", "--allow-empty", "-m", "Unrelated root")
+
+	got, err := repo.MergeBase(ctx, "feature", git.Treeish(mainTip))
+	if err != nil {
+		t.Fatalf("MergeBase() error = %v", err)
+	}
+	if got != base {
+		t.Errorf("MergeBase() = %s, want %s", got, base)
+	}
+
+	if _, err := repo.MergeBase(ctx, "feature", "unrelated"); !errors.Is(err, protatoerrors.ErrNoMergeBase) {
+		t.Errorf("MergeBase() of unrelated histories error = %v, want %v", err, protatoerrors.ErrNoMergeBase)
+	}
+}
+
 func TestGitRepository_GetUser(t *testing.T) {
 	repoDir := setupTestGitRepo(t)
 
@@ -225,3 +383,156 @@ func TestGitRepository_GetRepoURL(t *testing.T) {
 		t.Error("GetRepoURL() returned empty URL")
 	}
 }
+
+func TestGitRepository_WriteObjectBatch(t *testing.T) {
+	repoDir := setupTestGitRepo(t)
+
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	repo, err := git.Open(ctx, repoDir, git.OpenOptions{Bare: false})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	contents := []string{"syntax = \"proto3\";\n", "", "second file\n", "syntax = \"proto3\";\n"}
+	var bodies []io.Reader
+	for _, c := range contents {
+		bodies = append(bodies, strings.NewReader(c))
+	}
+
+	hashes, err := repo.WriteObjectBatch(ctx, bodies)
+	if err != nil {
+		t.Fatalf("WriteObjectBatch() error = %v", err)
+	}
+	if len(hashes) != len(contents) {
+		t.Fatalf("WriteObjectBatch() returned %d hashes, want %d", len(hashes), len(contents))
+	}
+
+	for i, c := range contents {
+		want, err := repo.WriteObject(ctx, strings.NewReader(c), git.WriteObjectOptions{})
+		if err != nil {
+			t.Fatalf("WriteObject() error = %v", err)
+		}
+		if hashes[i] != want {
+			t.Errorf("WriteObjectBatch()[%d] = %s, want %s", i, hashes[i], want)
+		}
+
+		var buf bytes.Buffer
+		if err := repo.ReadObject(ctx, git.BlobType, hashes[i], &buf); err != nil {
+			t.Fatalf("ReadObject(%s) error = %v", hashes[i], err)
+		}
+		if buf.String() != c {
+			t.Errorf("object %s = %q, want %q", hashes[i], buf.String(), c)
+		}
+	}
+}
+
+func TestGitRepository_CatFileBatch(t *testing.T) {
+	repoDir := setupTestGitRepo(t)
+
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	repo, err := git.Open(ctx, repoDir, git.OpenOptions{Bare: false})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	contents := []string{"syntax = \"proto3\";\n", "", "no trailing newline"}
+	var bodies []io.Reader
+	for _, c := range contents {
+		bodies = append(bodies, strings.NewReader(c))
+	}
+	hashes, err := repo.WriteObjectBatch(ctx, bodies)
+	if err != nil {
+		t.Fatalf("WriteObjectBatch() error = %v", err)
+	}
+	missing := git.Hash(strings.Repeat("0", len(hashes[0])))
+
+	var got []string
+	err = repo.CatFileBatch(ctx, []git.Hash{hashes[0], missing, hashes[1], hashes[2]}, func(h git.Hash, objType git.ObjectType, content io.Reader) error {
+		if objType != git.BlobType {
+			t.Errorf("object %s type = %v, want blob", h, objType)
+		}
+		data, err := io.ReadAll(content)
+		got = append(got, string(data))
+		return err
+	})
+	if !errors.Is(err, protatoerrors.ErrObjectMissing) {
+		t.Errorf("CatFileBatch() error = %v, want ErrObjectMissing", err)
+	}
+	if !reflect.DeepEqual(got, contents) {
+		t.Errorf("CatFileBatch() read %q, want %q", got, contents)
+	}
+}
+
+func TestGitRepository_DiffTree(t *testing.T) {
+	repoDir := setupTestGitRepo(t)
+
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	repo, err := git.Open(ctx, repoDir, git.OpenOptions{Bare: false})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	gitRun := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v, output: %s", args, err, out)
+		}
+	}
+	writeFile := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(repoDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile("protos/a/api.proto", "syntax = \"proto3\";\npackage a;\nmessage A {}\n")
+	writeFile("protos/a/old.proto", "syntax = \"proto3\";\npackage a;\nmessage Old { string long_enough_to_match = 1; }\n")
+	writeFile("protos/b/gone.proto", "syntax = \"proto3\";\npackage b;\n")
+	gitRun("add", ".")
+	gitRun("commit", "--no-verify", "-m", "Base")
+	gitRun("tag", "base")
+
+	writeFile("protos/a/api.proto", "syntax = \"proto3\";\npackage a;\nmessage A { string name = 1; }\n")
+	gitRun("mv", "protos/a/old.proto", "protos/a/renamed.proto")
+	gitRun("rm", "-q", "protos/b/gone.proto")
+	writeFile("protos/c/new.proto", "syntax = \"proto2\";\npackage c.v1;\nenum Kind { KIND_UNSPECIFIED = 0; }\n")
+	gitRun("add", ".")
+	gitRun("commit", "--no-verify", "-m", "Change")
+
+	got, err := repo.DiffTree(ctx, "base", "HEAD", git.DiffOptions{FindRenames: true})
+	if err != nil {
+		t.Fatalf("DiffTree() error = %v", err)
+	}
+	want := []git.DiffEntry{
+		{Status: git.DiffModified, Path: "protos/a/api.proto"},
+		{Status: git.DiffRenamed, Path: "protos/a/renamed.proto", OldPath: "protos/a/old.proto", Similarity: 100},
+		{Status: git.DiffDeleted, Path: "protos/b/gone.proto"},
+		{Status: git.DiffAdded, Path: "protos/c/new.proto"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffTree() = %+v, want %+v", got, want)
+	}
+
+	// Without rename detection, and limited to a path
+	got, err = repo.DiffTree(ctx, "base", "HEAD", git.DiffOptions{Paths: []string{"protos/a"}})
+	if err != nil {
+		t.Fatalf("DiffTree() error = %v", err)
+	}
+	want = []git.DiffEntry{
+		{Status: git.DiffModified, Path: "protos/a/api.proto"},
+		{Status: git.DiffDeleted, Path: "protos/a/old.proto"},
+		{Status: git.DiffAdded, Path: "protos/a/renamed.proto"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffTree() with a path filter = %+v, want %+v", got, want)
+	}
+}
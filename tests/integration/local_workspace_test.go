@@ -966,6 +966,9 @@ message Request {
 	if err := writer.Close(); err != nil {
 		t.Fatalf("Failed to close file: %v", err)
 	}
+	if _, err := receiver.Finish(); err != nil {
+		t.Fatalf("Failed to finish receive: %v", err)
+	}
 
 	// Verify file was created
 	vendorDir, _ := ws.VendorDir()
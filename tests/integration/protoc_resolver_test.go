@@ -140,7 +140,7 @@ func TestProtocResolver_WithRealCache(t *testing.T) {
 
 	log := logger.Init()
 	ctx := logger.WithLogger(context.Background(), &log)
-	cache, err := registry.Open(ctx, cacheDir, registryDir)
+	cache, err := registry.Open(ctx, cacheDir, registryDir, registry.OpenOptions{})
 	if err != nil {
 		t.Fatalf("Open() error = %v", err)
 	}
@@ -158,7 +158,7 @@ func TestProtocResolver_WithRealCache(t *testing.T) {
 
 	// Preload files
 	projects := []registry.ProjectPath{"test-service/api"}
-	err = resolver.PreloadFiles(ctx, projects, false)
+	_, err = resolver.PreloadFiles(ctx, projects, false)
 	if err != nil {
 		t.Fatalf("PreloadFiles() error = %v", err)
 	}
@@ -316,7 +316,7 @@ func TestProtocResolver_DiscoveredProjects(t *testing.T) {
 
 	log := logger.Init()
 	ctx := logger.WithLogger(context.Background(), &log)
-	cache, err := registry.Open(ctx, cacheDir, registryDir)
+	cache, err := registry.Open(ctx, cacheDir, registryDir, registry.OpenOptions{})
 	if err != nil {
 		t.Fatalf("Open() error = %v", err)
 	}
@@ -331,7 +331,7 @@ func TestProtocResolver_DiscoveredProjects(t *testing.T) {
 
 	// Preload files should discover projects
 	projects := []registry.ProjectPath{"team/service"}
-	err = resolver.PreloadFiles(ctx, projects, false)
+	_, err = resolver.PreloadFiles(ctx, projects, false)
 	if err != nil {
 		t.Fatalf("PreloadFiles() error = %v", err)
 	}
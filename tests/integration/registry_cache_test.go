@@ -3,14 +3,21 @@ package integration
 import (
 	"bytes"
 	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
+	protatoerrors "github.com/rahulagarwal0605/protato/internal/errors"
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/local"
 	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/internal/protoc"
 	"github.com/rahulagarwal0605/protato/internal/registry"
+	"github.com/rahulagarwal0605/protato/tests/testhelpers"
 )
 
 // setupTestRegistry creates a temporary Git repository for testing
@@ -162,7 +169,7 @@ func TestRegistryCache_Open(t *testing.T) {
 
 	log := logger.Init()
 	ctx := logger.WithLogger(context.Background(), &log)
-	cache, err := registry.Open(ctx, cacheDir, registryDir)
+	cache, err := registry.Open(ctx, cacheDir, registryDir, registry.OpenOptions{})
 	if err != nil {
 		t.Fatalf("Open() error = %v", err)
 	}
@@ -183,7 +190,7 @@ func TestRegistryCache_Snapshot(t *testing.T) {
 
 	log := logger.Init()
 	ctx := logger.WithLogger(context.Background(), &log)
-	cache, err := registry.Open(ctx, cacheDir, registryDir)
+	cache, err := registry.Open(ctx, cacheDir, registryDir, registry.OpenOptions{})
 	if err != nil {
 		t.Fatalf("Open() error = %v", err)
 	}
@@ -205,7 +212,7 @@ func TestRegistryCache_LookupProject(t *testing.T) {
 
 	log := logger.Init()
 	ctx := logger.WithLogger(context.Background(), &log)
-	cache, err := registry.Open(ctx, cacheDir, registryDir)
+	cache, err := registry.Open(ctx, cacheDir, registryDir, registry.OpenOptions{})
 	if err != nil {
 		t.Fatalf("Open() error = %v", err)
 	}
@@ -246,7 +253,7 @@ func TestRegistryCache_LookupProject_NotFound(t *testing.T) {
 
 	log := logger.Init()
 	ctx := logger.WithLogger(context.Background(), &log)
-	cache, err := registry.Open(ctx, cacheDir, registryDir)
+	cache, err := registry.Open(ctx, cacheDir, registryDir, registry.OpenOptions{})
 	if err != nil {
 		t.Fatalf("Open() error = %v", err)
 	}
@@ -269,13 +276,48 @@ func TestRegistryCache_LookupProject_NotFound(t *testing.T) {
 	}
 }
 
+func TestRegistryCache_EmptyRegistry(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+
+	// Leave the registry without a protos/ tree
+	os.RemoveAll(filepath.Join(workDir, "protos"))
+	os.WriteFile(filepath.Join(workDir, "README.md"), []byte("registry\n"), 0644)
+	commitAndPush(t, workDir, "Remove all projects")
+
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	cache, err := registry.Open(ctx, filepath.Join(tmpDir, "cache"), registryDir, registry.OpenOptions{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer cache.Close()
+
+	snapshot, err := cache.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+
+	projects, err := cache.ListProjects(ctx, &registry.ListProjectsOptions{Snapshot: snapshot})
+	if err != nil {
+		t.Fatalf("ListProjects() error = %v", err)
+	}
+	if len(projects) != 0 {
+		t.Errorf("ListProjects() = %v, want none", projects)
+	}
+
+	if err := cache.CheckProjectClaim(ctx, snapshot, "https://github.com/test/repo.git", "team/service"); err != nil {
+		t.Errorf("CheckProjectClaim() error = %v, want nil", err)
+	}
+}
+
 func TestRegistryCache_ListProjectFiles(t *testing.T) {
 	tmpDir, registryDir := setupTestRegistry(t)
 	cacheDir := filepath.Join(tmpDir, "cache")
 
 	log := logger.Init()
 	ctx := logger.WithLogger(context.Background(), &log)
-	cache, err := registry.Open(ctx, cacheDir, registryDir)
+	cache, err := registry.Open(ctx, cacheDir, registryDir, registry.OpenOptions{})
 	if err != nil {
 		t.Fatalf("Open() error = %v", err)
 	}
@@ -324,7 +366,7 @@ func TestRegistryCache_ReadProjectFile(t *testing.T) {
 
 	log := logger.Init()
 	ctx := logger.WithLogger(context.Background(), &log)
-	cache, err := registry.Open(ctx, cacheDir, registryDir)
+	cache, err := registry.Open(ctx, cacheDir, registryDir, registry.OpenOptions{})
 	if err != nil {
 		t.Fatalf("Open() error = %v", err)
 	}
@@ -370,7 +412,7 @@ func TestRegistryCache_GetSnapshot(t *testing.T) {
 
 	log := logger.Init()
 	ctx := logger.WithLogger(context.Background(), &log)
-	cache, err := registry.Open(ctx, cacheDir, registryDir)
+	cache, err := registry.Open(ctx, cacheDir, registryDir, registry.OpenOptions{})
 	if err != nil {
 		t.Fatalf("Open() error = %v", err)
 	}
@@ -385,3 +427,823 @@ func TestRegistryCache_GetSnapshot(t *testing.T) {
 		t.Error("GetSnapshot() returned empty hash")
 	}
 }
+
+func TestRegistryCache_ListProjectsOnBranch(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+
+	// Stage an extra project on a separate branch
+	stagedDir := filepath.Join(workDir, "protos", "team", "staged")
+	os.MkdirAll(stagedDir, 0755)
+	os.WriteFile(filepath.Join(stagedDir, "protato.root.yaml"), []byte("service: test-service\n"), 0644)
+	for _, args := range [][]string{
+		{"checkout", "-b", "staging"},
+		{"add", "."},
+		{"commit", "--no-verify", "-m", "Stage project"},
+		{"push", "origin", "staging:staging"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = workDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v, output: %s", args, err, out)
+		}
+	}
+
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	cache, err := registry.Open(ctx, filepath.Join(tmpDir, "cache"), registryDir, registry.OpenOptions{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer cache.Close()
+
+	if err := cache.RefreshBranch(ctx, "staging"); err != nil {
+		t.Fatalf("RefreshBranch() error = %v", err)
+	}
+	branchSnapshot, err := cache.BranchSnapshot(ctx, "staging")
+	if err != nil {
+		t.Fatalf("BranchSnapshot() error = %v", err)
+	}
+
+	projects, err := cache.ListProjects(ctx, &registry.ListProjectsOptions{Snapshot: branchSnapshot})
+	if err != nil {
+		t.Fatalf("ListProjects() error = %v", err)
+	}
+	if len(projects) != 2 {
+		t.Errorf("ListProjects() on branch = %v, want 2 projects", projects)
+	}
+
+	// The default snapshot must not move to the branch
+	snapshot, err := cache.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if snapshot == branchSnapshot {
+		t.Errorf("Snapshot() = branch snapshot %v, want default branch", snapshot)
+	}
+	projects, err = cache.ListProjects(ctx, &registry.ListProjectsOptions{Snapshot: snapshot})
+	if err != nil {
+		t.Fatalf("ListProjects() error = %v", err)
+	}
+	if len(projects) != 1 {
+		t.Errorf("ListProjects() on default = %v, want 1 project", projects)
+	}
+}
+
+func TestRegistryCache_SetProject_ValidateOnPush(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		importPath string
+		wantErr    bool
+	}{
+		{name: "valid push accepted", config: "validateOnPush: true\n", importPath: "team/service/v1/api.proto"},
+		{name: "inconsistent push rejected", config: "validateOnPush: true\n", importPath: "team/service/v1/missing.proto", wantErr: true},
+		{name: "inconsistent push accepted without validateOnPush", config: "", importPath: "team/service/v1/missing.proto"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, registryDir := setupTestRegistry(t)
+			workDir := filepath.Join(tmpDir, "work")
+			os.WriteFile(filepath.Join(workDir, "protato.registry.yaml"), []byte(tt.config), 0644)
+			commitAndPush(t, workDir, "Configure registry")
+
+			log := logger.Init()
+			ctx := logger.WithLogger(context.Background(), &log)
+			cache, err := registry.Open(ctx, filepath.Join(tmpDir, "cache"), registryDir, registry.OpenOptions{})
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer cache.Close()
+			if err := cache.Refresh(ctx); err != nil {
+				t.Fatalf("Refresh() error = %v", err)
+			}
+
+			content := "syntax = \"proto3\";\npackage team.client.v1;\nimport \"" + tt.importPath + "\";\nmessage Client {}\n"
+			_, err = cache.SetProject(ctx, &registry.SetProjectRequest{
+				Project:  &registry.Project{Path: "team/client", Commit: "abc123", RepositoryURL: "https://example.com/client"},
+				Files:    []registry.LocalProjectFile{{Path: "v1/client.proto", Content: []byte(content)}},
+				Author:   &git.Author{Name: "Test User", Email: "test@example.com"},
+				Validate: protoc.ValidateRegistryProject,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetProject() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), "validation failed") {
+				t.Errorf("SetProject() error = %v, want validation failure", err)
+			}
+		})
+	}
+}
+
+func TestRegistryCache_SetProject_AllowedExtensions(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		file      string
+		wantErr   bool
+		wantFiles []string
+	}{
+		{
+			name:      "allowed extra extension",
+			config:    "allowedExtensions: [\".proto\", \".yaml\"]\n",
+			file:      "v1/schema.yaml",
+			wantFiles: []string{"v1/client.proto", "v1/schema.yaml"},
+		},
+		{
+			name:    "blocked by default",
+			config:  "",
+			file:    "v1/notes.md",
+			wantErr: true,
+		},
+		{
+			name:    "blocked by explicit list",
+			config:  "allowedExtensions: [\".proto\", \".yaml\"]\n",
+			file:    "v1/notes.md",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, registryDir := setupTestRegistry(t)
+			workDir := filepath.Join(tmpDir, "work")
+			os.WriteFile(filepath.Join(workDir, "protato.registry.yaml"), []byte(tt.config), 0644)
+			commitAndPush(t, workDir, "Configure registry")
+
+			log := logger.Init()
+			ctx := logger.WithLogger(context.Background(), &log)
+			cache, err := registry.Open(ctx, filepath.Join(tmpDir, "cache"), registryDir, registry.OpenOptions{})
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer cache.Close()
+			if err := cache.Refresh(ctx); err != nil {
+				t.Fatalf("Refresh() error = %v", err)
+			}
+
+			res, err := cache.SetProject(ctx, &registry.SetProjectRequest{
+				Project: &registry.Project{Path: "team/client", Commit: "abc123", RepositoryURL: "https://example.com/client"},
+				Files: []registry.LocalProjectFile{
+					{Path: "v1/client.proto", Content: []byte("syntax = \"proto3\";\npackage team.client.v1;\n")},
+					{Path: tt.file, Content: []byte("kind: schema\n")},
+				},
+				Author: &git.Author{Name: "Test User", Email: "test@example.com"},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetProject() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, protatoerrors.ErrDisallowedFile) {
+					t.Errorf("SetProject() error = %v, want ErrDisallowedFile", err)
+				}
+				return
+			}
+
+			files, err := cache.ListProjectFiles(ctx, &registry.ListProjectFilesRequest{Project: "team/client", Snapshot: res.Snapshot})
+			if err != nil {
+				t.Fatalf("ListProjectFiles() error = %v", err)
+			}
+			var got []string
+			for _, f := range files.Files {
+				got = append(got, f.Path)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantFiles, ",") {
+				t.Errorf("ListProjectFiles() = %v, want %v", got, tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestRegistryCache_SetProject_RequireApproval(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	cache, err := registry.Open(ctx, filepath.Join(tmpDir, "cache"), registryDir, registry.OpenOptions{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer cache.Close()
+	if err := cache.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	request := func(require bool, approval registry.ApprovalSource) *registry.SetProjectRequest {
+		return &registry.SetProjectRequest{
+			Project: &registry.Project{Path: "team/client", Commit: "abc123", RepositoryURL: "https://example.com/client", RequireApproval: require},
+			Files: []registry.LocalProjectFile{
+				{Path: "v1/client.proto", Content: []byte("syntax = \"proto3\";\npackage team.client.v1;\n")},
+			},
+			Author:   &git.Author{Name: "Test User", Email: "test@example.com"},
+			Approval: approval,
+		}
+	}
+	approve := func(context.Context, registry.ProjectPath) (string, error) { return "reviewer@example.com", nil }
+
+	if _, err := cache.SetProject(ctx, request(true, nil)); !errors.Is(err, protatoerrors.ErrApprovalRequired) {
+		t.Fatalf("SetProject() without approval error = %v, want ErrApprovalRequired", err)
+	}
+
+	res, err := cache.SetProject(ctx, request(true, approve))
+	if err != nil {
+		t.Fatalf("SetProject() with approval error = %v", err)
+	}
+	if _, err := cache.Push(ctx, res.Snapshot); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	if msg := registryGit(t, registryDir, "log", "-1", "--format=%B", string(res.Snapshot)); !strings.HasSuffix(msg, "Approved-by: reviewer@example.com") {
+		t.Errorf("registry commit message = %q, want an Approved-by trailer", msg)
+	}
+
+	lookup, err := cache.LookupProject(ctx, &registry.LookupProjectRequest{Path: "team/client", Snapshot: res.Snapshot})
+	if err != nil {
+		t.Fatalf("LookupProject() error = %v", err)
+	}
+	if !lookup.Project.RequireApproval {
+		t.Error("published project does not record requireApproval")
+	}
+
+	// The published flag applies even when the next push does not ask for it
+	next := request(false, nil)
+	next.Snapshot = res.Snapshot
+	if _, err := cache.SetProject(ctx, next); !errors.Is(err, protatoerrors.ErrApprovalRequired) {
+		t.Errorf("SetProject() on approval-required project error = %v, want ErrApprovalRequired", err)
+	}
+}
+
+func TestRegistryCache_SetProjects(t *testing.T) {
+	author := &git.Author{Name: "Test User", Email: "test@example.com"}
+	request := func(path, url string, snapshot git.Hash) *registry.SetProjectRequest {
+		return &registry.SetProjectRequest{
+			Project:  &registry.Project{Path: registry.ProjectPath(path), Commit: "abc123", RepositoryURL: url},
+			Files:    []registry.LocalProjectFile{{Path: "v1/api.proto", Content: []byte("syntax = \"proto3\";\n")}},
+			Snapshot: snapshot,
+			Author:   author,
+		}
+	}
+
+	tmpDir, registryDir := setupTestRegistry(t)
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	cache, err := registry.Open(ctx, filepath.Join(tmpDir, "cache"), registryDir, registry.OpenOptions{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer cache.Close()
+	if err := cache.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	// Another repository owns team/owned
+	base, err := cache.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	res, err := cache.SetProject(ctx, request("team/owned", "https://example.com/other", base))
+	if err != nil {
+		t.Fatalf("SetProject() error = %v", err)
+	}
+	if _, err := cache.Push(ctx, res.Snapshot); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	base = res.Snapshot
+
+	t.Run("one unclaimable project rejects the batch", func(t *testing.T) {
+		_, err := cache.SetProjects(ctx, []*registry.SetProjectRequest{
+			request("team/a", "https://example.com/mine", base),
+			request("team/owned", "https://example.com/mine", base),
+		})
+		if !errors.Is(err, protatoerrors.ErrOwnershipConflict) {
+			t.Fatalf("SetProjects() error = %v, want ErrOwnershipConflict", err)
+		}
+		if head := registryGit(t, registryDir, "rev-parse", "HEAD"); head != string(base) {
+			t.Errorf("registry HEAD = %s, want it unchanged at %s", head, base)
+		}
+	})
+
+	t.Run("projects are committed together", func(t *testing.T) {
+		res, err := cache.SetProjects(ctx, []*registry.SetProjectRequest{
+			request("team/a", "https://example.com/mine", base),
+			request("team/b", "https://example.com/mine", base),
+		})
+		if err != nil {
+			t.Fatalf("SetProjects() error = %v", err)
+		}
+		if res.FilesChanged != 2 {
+			t.Errorf("SetProjects() FilesChanged = %d, want 2", res.FilesChanged)
+		}
+		if _, err := cache.Push(ctx, res.Snapshot); err != nil {
+			t.Fatalf("Push() error = %v", err)
+		}
+
+		if parent := registryGit(t, registryDir, "rev-parse", string(res.Snapshot)+"^"); parent != string(base) {
+			t.Errorf("new snapshot parent = %s, want %s (a single commit)", parent, base)
+		}
+		if head := registryGit(t, registryDir, "rev-parse", "HEAD"); head != string(res.Snapshot) {
+			t.Errorf("registry HEAD = %s, want %s", head, res.Snapshot)
+		}
+		for _, project := range []string{"team/a", "team/b", "team/owned"} {
+			if _, err := cache.LookupProject(ctx, &registry.LookupProjectRequest{Path: project, Snapshot: res.Snapshot}); err != nil {
+				t.Errorf("LookupProject(%s) error = %v", project, err)
+			}
+		}
+	})
+
+	t.Run("requests must share a base snapshot", func(t *testing.T) {
+		if _, err := cache.SetProjects(ctx, []*registry.SetProjectRequest{
+			request("team/c", "https://example.com/mine", base),
+			request("team/d", "https://example.com/mine", ""),
+		}); err == nil {
+			t.Error("SetProjects() expected error for different base snapshots")
+		}
+	})
+}
+
+func TestRegistryCache_Push_ReappliesOnMovedTip(t *testing.T) {
+	author := &git.Author{Name: "Test User", Email: "test@example.com"}
+	request := func(path, content string) *registry.SetProjectRequest {
+		return &registry.SetProjectRequest{
+			Project: &registry.Project{Path: registry.ProjectPath(path), Commit: "abc123", RepositoryURL: "https://example.com/" + path},
+			Files:   []registry.LocalProjectFile{{Path: "v1/api.proto", Content: []byte(content)}},
+			Author:  author,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		theirs  string // Project pushed by another cache first
+		ours    string // Project pushed on the stale base
+		wantErr bool
+	}{
+		{name: "other project moved the tip", theirs: "team/theirs", ours: "team/ours"},
+		{name: "same project moved the tip", theirs: "team/shared", ours: "team/shared", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, registryDir := setupTestRegistry(t)
+			log := logger.Init()
+			ctx := logger.WithLogger(context.Background(), &log)
+
+			open := func(name string) *registry.Cache {
+				cache, err := registry.Open(ctx, filepath.Join(tmpDir, name), registryDir, registry.OpenOptions{})
+				if err != nil {
+					t.Fatalf("Open() error = %v", err)
+				}
+				t.Cleanup(func() { cache.Close() })
+				if err := cache.Refresh(ctx); err != nil {
+					t.Fatalf("Refresh() error = %v", err)
+				}
+				return cache
+			}
+			mine, other := open("mine"), open("other")
+
+			// Our commit is built before the other push lands
+			res, err := mine.SetProject(ctx, request(tt.ours, "syntax = \"proto3\";\npackage ours;\n"))
+			if err != nil {
+				t.Fatalf("SetProject() error = %v", err)
+			}
+			theirs, err := other.SetProject(ctx, request(tt.theirs, "syntax = \"proto3\";\npackage theirs;\n"))
+			if err != nil {
+				t.Fatalf("SetProject() error = %v", err)
+			}
+			theirsPushed, err := other.Push(ctx, theirs.Snapshot)
+			if err != nil {
+				t.Fatalf("Push() error = %v", err)
+			}
+
+			pushed, err := mine.Push(ctx, res.Snapshot)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Push() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if head := registryGit(t, registryDir, "rev-parse", "HEAD"); head != string(theirsPushed) {
+					t.Errorf("registry HEAD = %s, want the other push %s", head, theirsPushed)
+				}
+				return
+			}
+
+			if pushed == res.Snapshot {
+				t.Error("Push() returned the stale commit, want it re-applied onto the new tip")
+			}
+			if head := registryGit(t, registryDir, "rev-parse", "HEAD"); head != string(pushed) {
+				t.Errorf("registry HEAD = %s, want %s", head, pushed)
+			}
+			if parent := registryGit(t, registryDir, "rev-parse", string(pushed)+"^"); parent != string(theirsPushed) {
+				t.Errorf("pushed commit parent = %s, want %s", parent, theirsPushed)
+			}
+			for _, project := range []string{tt.theirs, tt.ours, "team/service"} {
+				if _, err := mine.LookupProject(ctx, &registry.LookupProjectRequest{Path: project, Snapshot: pushed}); err != nil {
+					t.Errorf("LookupProject(%s) error = %v", project, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistryCache_RenameProject(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	cache, err := registry.Open(ctx, filepath.Join(tmpDir, "cache"), registryDir, registry.OpenOptions{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer cache.Close()
+	if err := cache.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	author := &git.Author{Name: "Test User", Email: "test@example.com"}
+	owner := "https://example.com/payments"
+	res, err := cache.SetProject(ctx, &registry.SetProjectRequest{
+		Project: &registry.Project{Path: "team/payments", Commit: "abc123", RepositoryURL: owner},
+		Files: []registry.LocalProjectFile{
+			{Path: "v1/api.proto", Content: []byte("syntax = \"proto3\";\npackage team.payments.v1;\n")},
+			{Path: "v1/types.proto", Content: []byte("syntax = \"proto3\";\npackage team.payments.v1;\nmessage Money {}\n")},
+		},
+		Author: author,
+	})
+	if err != nil {
+		t.Fatalf("SetProject() error = %v", err)
+	}
+
+	renamed, err := cache.RenameProject(ctx, &registry.RenameProjectRequest{
+		From:          "team/payments",
+		To:            "finance/payments",
+		RepositoryURL: owner,
+		Snapshot:      res.Snapshot,
+		Author:        author,
+	})
+	if err != nil {
+		t.Fatalf("RenameProject() error = %v", err)
+	}
+	if renamed.FilesMoved != 3 {
+		t.Errorf("RenameProject() FilesMoved = %d, want 3", renamed.FilesMoved)
+	}
+	if _, err := cache.Push(ctx, renamed.Snapshot); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	if parent := registryGit(t, registryDir, "rev-parse", string(renamed.Snapshot)+"^"); parent != string(res.Snapshot) {
+		t.Errorf("rename commit parent = %s, want %s (a single commit)", parent, res.Snapshot)
+	}
+
+	lookup, err := cache.LookupProject(ctx, &registry.LookupProjectRequest{Path: "finance/payments", Snapshot: renamed.Snapshot})
+	if err != nil {
+		t.Fatalf("LookupProject() of the new path error = %v", err)
+	}
+	if lookup.Project.RepositoryURL != owner || lookup.Project.Commit != "abc123" {
+		t.Errorf("renamed project = %+v, want the original URL and commit", lookup.Project)
+	}
+	files, err := cache.ListProjectFiles(ctx, &registry.ListProjectFilesRequest{Project: "finance/payments", Snapshot: renamed.Snapshot})
+	if err != nil {
+		t.Fatalf("ListProjectFiles() error = %v", err)
+	}
+	if len(files.Files) != 2 {
+		t.Errorf("ListProjectFiles() = %d files, want 2", len(files.Files))
+	}
+	if _, err := cache.LookupProject(ctx, &registry.LookupProjectRequest{Path: "team/payments", Snapshot: renamed.Snapshot}); !errors.Is(err, protatoerrors.ErrNotFound) {
+		t.Errorf("LookupProject() of the old path error = %v, want ErrNotFound", err)
+	}
+
+	// Another repository cannot move it back
+	if _, err := cache.RenameProject(ctx, &registry.RenameProjectRequest{
+		From:          "finance/payments",
+		To:            "team/payments",
+		RepositoryURL: "https://example.com/intruder",
+		Snapshot:      renamed.Snapshot,
+		Author:        author,
+	}); !errors.Is(err, protatoerrors.ErrOwnershipConflict) {
+		t.Errorf("RenameProject() by another repository error = %v, want ErrOwnershipConflict", err)
+	}
+}
+
+func TestRegistryCache_RenameProject_MovesImports(t *testing.T) {
+	tests := []struct {
+		name        string
+		moveImports registry.ImportRewriter
+		wantErr     bool
+	}{
+		{name: "imports follow the project", moveImports: protoc.MoveImports},
+		{name: "stale imports rejected", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, registryDir := setupTestRegistry(t)
+			workDir := filepath.Join(tmpDir, "work")
+			os.WriteFile(filepath.Join(workDir, "protato.registry.yaml"), []byte("validateOnPush: true\n"), 0644)
+			commitAndPush(t, workDir, "Configure registry")
+
+			log := logger.Init()
+			ctx := logger.WithLogger(context.Background(), &log)
+			cache, err := registry.Open(ctx, filepath.Join(tmpDir, "cache"), registryDir, registry.OpenOptions{})
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer cache.Close()
+			if err := cache.Refresh(ctx); err != nil {
+				t.Fatalf("Refresh() error = %v", err)
+			}
+
+			author := &git.Author{Name: "Test User", Email: "test@example.com"}
+			owner := "https://example.com/payments"
+			res, err := cache.SetProject(ctx, &registry.SetProjectRequest{
+				Project: &registry.Project{Path: "team/payments", Commit: "abc123", RepositoryURL: owner},
+				Files: []registry.LocalProjectFile{
+					{Path: "v1/api.proto", Content: []byte("syntax = \"proto3\";\npackage team.payments.v1;\nimport \"team/payments/v1/types.proto\";\nmessage Charge { Money amount = 1; }\n")},
+					{Path: "v1/types.proto", Content: []byte("syntax = \"proto3\";\npackage team.payments.v1;\nmessage Money {}\n")},
+				},
+				Author:   author,
+				Validate: protoc.ValidateRegistryProject,
+			})
+			if err != nil {
+				t.Fatalf("SetProject() error = %v", err)
+			}
+
+			renamed, err := cache.RenameProject(ctx, &registry.RenameProjectRequest{
+				From:          "team/payments",
+				To:            "finance/payments",
+				RepositoryURL: owner,
+				Snapshot:      res.Snapshot,
+				Author:        author,
+				MoveImports:   tt.moveImports,
+				Validate:      protoc.ValidateRegistryProject,
+			})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "validation failed") {
+					t.Fatalf("RenameProject() error = %v, want validation failure", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenameProject() error = %v", err)
+			}
+
+			api := registryCacheFile(ctx, t, cache, renamed.Snapshot, "finance/payments", "v1/api.proto")
+			if !strings.Contains(api, `import "finance/payments/v1/types.proto";`) {
+				t.Errorf("moved api.proto = %q, want the import of the moved types.proto", api)
+			}
+			if err := protoc.ValidateRegistryProject(ctx, cache, renamed.Snapshot, "finance/payments"); err != nil {
+				t.Errorf("ValidateRegistryProject() of the moved project error = %v", err)
+			}
+		})
+	}
+}
+
+func TestRegistryCache_RenameService(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	cache, err := registry.Open(ctx, filepath.Join(tmpDir, "cache"), registryDir, registry.OpenOptions{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer cache.Close()
+	if err := cache.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	// old-svc/team/service imports old-svc/team/common, which is moved after it
+	files := map[string]map[string]string{
+		"team/service": {"api.proto": "syntax = \"proto3\";\npackage team.service.v1;\nimport \"old-svc/team/common/types.proto\";\nmessage Charge { team.common.v1.Money amount = 1; }\n"},
+		"team/common":  {"types.proto": "syntax = \"proto3\";\npackage team.common.v1;\nmessage Money {}\n"},
+	}
+	author := &git.Author{Name: "Test User", Email: "test@example.com"}
+	owner := "https://example.com/payments"
+	var snapshot git.Hash
+	for _, project := range []string{"team/common", "team/service"} {
+		var projectFiles []registry.LocalProjectFile
+		for name, content := range files[project] {
+			projectFiles = append(projectFiles, registry.LocalProjectFile{Path: name, Content: []byte(content)})
+		}
+		res, err := cache.SetProject(ctx, &registry.SetProjectRequest{
+			Project:  &registry.Project{Path: registry.ProjectPath("old-svc/" + project), Commit: "abc123", RepositoryURL: owner},
+			Files:    projectFiles,
+			Snapshot: snapshot,
+			Author:   author,
+		})
+		if err != nil {
+			t.Fatalf("SetProject(%s) error = %v", project, err)
+		}
+		snapshot = res.Snapshot
+	}
+
+	wsDir, ws := testhelpers.SetupTestWorkspaceWithConfig(t, &local.Config{
+		Service:     "old-svc",
+		Projects:    []string{"team/service", "team/common"},
+		Directories: local.DirectoryConfig{Owned: "proto", Vendor: "vendor-proto"},
+	})
+	for project, projectFiles := range files {
+		testhelpers.CreateTestProject(t, wsDir, "proto/"+project, projectFiles)
+	}
+
+	res, err := ws.RenameService(ctx, "new-svc", &local.RenameServiceOptions{
+		Registry:      cache,
+		RepositoryURL: owner,
+		Snapshot:      snapshot,
+		Author:        author,
+	})
+	if err != nil {
+		t.Fatalf("RenameService() error = %v", err)
+	}
+	if res.ProjectsMoved != 2 {
+		t.Errorf("RenameService() ProjectsMoved = %d, want 2", res.ProjectsMoved)
+	}
+	for _, project := range []registry.ProjectPath{"new-svc/team/service", "new-svc/team/common"} {
+		if err := protoc.ValidateRegistryProject(ctx, cache, res.Snapshot, project); err != nil {
+			t.Errorf("ValidateRegistryProject(%s) of the moved snapshot error = %v", project, err)
+		}
+	}
+	api := registryCacheFile(ctx, t, cache, res.Snapshot, "new-svc/team/service", "api.proto")
+	if strings.Contains(api, "old-svc") {
+		t.Errorf("moved api.proto = %q, still imports old-svc", api)
+	}
+}
+
+// registryCacheFile returns the content of a project file in the cache at snapshot.
+func registryCacheFile(ctx context.Context, t *testing.T, cache *registry.Cache, snapshot git.Hash, project registry.ProjectPath, file string) string {
+	t.Helper()
+	files, err := cache.ListProjectFiles(ctx, &registry.ListProjectFilesRequest{Project: project, Snapshot: snapshot})
+	if err != nil {
+		t.Fatalf("ListProjectFiles() error = %v", err)
+	}
+	for _, f := range files.Files {
+		if f.Path == file {
+			var buf bytes.Buffer
+			if err := cache.ReadProjectFile(ctx, f, &buf); err != nil {
+				t.Fatalf("ReadProjectFile() error = %v", err)
+			}
+			return buf.String()
+		}
+	}
+	t.Fatalf("%s has no file %s", project, file)
+	return ""
+}
+
+func TestRegistryCache_ConfiguredBranch(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+
+	defaultTip := registryGit(t, registryDir, "rev-parse", "HEAD")
+	registryGit(t, registryDir, "branch", "registry", defaultTip)
+	opts := registry.OpenOptions{Branch: "registry"}
+
+	cache, err := registry.Open(ctx, filepath.Join(tmpDir, "cache"), registryDir, opts)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := cache.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	res, err := cache.SetProject(ctx, &registry.SetProjectRequest{
+		Project: &registry.Project{Path: "team/billing", Commit: "abc123"},
+		Files:   []registry.LocalProjectFile{{Path: "v1/billing.proto", Content: []byte("syntax = \"proto3\";\npackage team.billing.v1;\n")}},
+		Author:  &git.Author{Name: "Test User", Email: "test@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("SetProject() error = %v", err)
+	}
+	pushed, err := cache.Push(ctx, res.Snapshot)
+	if err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	cache.Close()
+
+	if got := registryGit(t, registryDir, "rev-parse", "refs/heads/registry"); got != string(pushed) {
+		t.Errorf("registry branch = %s, want pushed commit %s", got, pushed)
+	}
+	if got := registryGit(t, registryDir, "rev-parse", "HEAD"); got != defaultTip {
+		t.Errorf("default branch moved to %s, want it left at %s", got, defaultTip)
+	}
+
+	// A fresh clone follows the configured branch too
+	fresh, err := registry.Open(ctx, filepath.Join(tmpDir, "fresh"), registryDir, opts)
+	if err != nil {
+		t.Fatalf("Open() of a fresh cache error = %v", err)
+	}
+	defer fresh.Close()
+	if err := fresh.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if snapshot, err := fresh.Snapshot(ctx); err != nil || snapshot != pushed {
+		t.Errorf("Snapshot() = %s, %v, want %s", snapshot, err, pushed)
+	}
+}
+
+func TestRegistryCache_SnapshotDiff_MergeBase(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+
+	gitRun := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = workDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v, output: %s", args, err, out)
+		}
+	}
+	addProject := func(project string) {
+		t.Helper()
+		dir := filepath.Join(workDir, "protos", "team", project)
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "protato.root.yaml"), []byte("service: test-service\n"), 0644)
+		gitRun("add", ".")
+		gitRun("commit", "--no-verify", "-m", "Add "+project)
+	}
+
+	// staging and the default branch diverge, each adding a project
+	gitRun("checkout", "-b", "staging")
+	addProject("staged")
+	gitRun("push", "origin", "staging:staging")
+	gitRun("checkout", "-")
+	addProject("released")
+	gitRun("push", "origin", "HEAD")
+
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	cache, err := registry.Open(ctx, filepath.Join(tmpDir, "cache"), registryDir, registry.OpenOptions{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer cache.Close()
+
+	if err := cache.RefreshBranch(ctx, "staging"); err != nil {
+		t.Fatalf("RefreshBranch() error = %v", err)
+	}
+	staging, err := cache.BranchSnapshot(ctx, "staging")
+	if err != nil {
+		t.Fatalf("BranchSnapshot() error = %v", err)
+	}
+	tip, err := cache.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+
+	// Each direction reports only the target branch's side of the divergence
+	tests := []struct {
+		from, to git.Hash
+		want     []registry.ProjectPath
+	}{
+		{from: staging, to: tip, want: []registry.ProjectPath{"team/released"}},
+		{from: tip, to: staging, want: []registry.ProjectPath{"team/staged"}},
+	}
+	for _, tt := range tests {
+		changed, err := cache.SnapshotDiff(ctx, tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("SnapshotDiff() error = %v", err)
+		}
+		if !reflect.DeepEqual(changed, tt.want) {
+			t.Errorf("SnapshotDiff(%s, %s) = %v, want %v", tt.from.Short(), tt.to.Short(), changed, tt.want)
+		}
+	}
+}
+
+func TestRegistryCache_GetProjectHistory_DeletedFile(t *testing.T) {
+	tmpDir, registryDir := setupTestRegistry(t)
+	workDir := filepath.Join(tmpDir, "work")
+	projectDir := filepath.Join(workDir, "protos", "team", "service")
+
+	testhelpers.CreateTestProtoFile(t, projectDir, "v1/legacy.proto", "syntax = \"proto3\";\npackage team.service.v1;\nmessage Legacy {}")
+	commitAndPush(t, workDir, "Add legacy")
+	if err := os.Rename(filepath.Join(projectDir, "v1", "legacy.proto"), filepath.Join(projectDir, "v1", "old.proto")); err != nil {
+		t.Fatal(err)
+	}
+	commitAndPush(t, workDir, "Rename legacy")
+	if err := os.Remove(filepath.Join(projectDir, "v1", "old.proto")); err != nil {
+		t.Fatal(err)
+	}
+	commitAndPush(t, workDir, "Remove old")
+
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	cache, err := registry.Open(ctx, filepath.Join(tmpDir, "cache"), registryDir, registry.OpenOptions{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer cache.Close()
+	if err := cache.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	req := &registry.ProjectHistoryRequest{Project: "team/service", File: "v1/old.proto"}
+	if _, err := cache.GetProjectHistory(ctx, req); !errors.Is(err, protatoerrors.ErrNotFound) {
+		t.Fatalf("GetProjectHistory() of a deleted file error = %v, want ErrNotFound", err)
+	}
+
+	req.IncludeDeleted = true
+	revisions, err := cache.GetProjectHistory(ctx, req)
+	if err != nil {
+		t.Fatalf("GetProjectHistory() error = %v", err)
+	}
+	var got []string
+	for _, rev := range revisions {
+		got = append(got, rev.Message)
+	}
+	if want := []string{"Remove old", "Rename legacy", "Add legacy"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("GetProjectHistory() messages = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,103 @@
+package integration
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rahulagarwal0605/protato/internal/git"
+	"github.com/rahulagarwal0605/protato/internal/logger"
+	"github.com/rahulagarwal0605/protato/internal/registry"
+)
+
+// lsTree lists the files at the tip of a branch in a bare repository.
+func lsTree(t *testing.T, gitDir, branch string) []string {
+	t.Helper()
+	cmd := exec.Command("git", "ls-tree", "-r", "--name-only", branch)
+	cmd.Dir = gitDir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("ls-tree %s: %v", branch, err)
+	}
+	return strings.Fields(string(out))
+}
+
+func TestRegistryInit_LocalPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	registryDir := filepath.Join(tmpDir, "registry.git")
+
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	commit, err := registry.Init(ctx, &registry.InitRequest{
+		Path:   registryDir,
+		Author: &git.Author{Name: "Test User", Email: "test@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if commit == "" {
+		t.Fatal("Init() returned empty commit")
+	}
+
+	files := lsTree(t, registryDir, "main")
+	want := []string{"protato.registry.yaml", "protos/.gitkeep"}
+	if strings.Join(files, ",") != strings.Join(want, ",") {
+		t.Errorf("registry files = %v, want %v", files, want)
+	}
+
+	// The new registry must be usable as a cache source
+	cache, err := registry.Open(ctx, filepath.Join(tmpDir, "cache"), registryDir, registry.OpenOptions{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer cache.Close()
+
+	snapshot, err := cache.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if snapshot != commit {
+		t.Errorf("Snapshot() = %v, want %v", snapshot, commit)
+	}
+
+	projects, err := cache.ListProjects(ctx, &registry.ListProjectsOptions{Snapshot: snapshot})
+	if err != nil {
+		t.Fatalf("ListProjects() error = %v", err)
+	}
+	if len(projects) != 0 {
+		t.Errorf("ListProjects() = %v, want none", projects)
+	}
+}
+
+func TestRegistryInit_Remote(t *testing.T) {
+	tmpDir := t.TempDir()
+	remoteDir := filepath.Join(tmpDir, "remote.git")
+	initCmd := exec.Command("git", "init", "--bare", remoteDir)
+	initCmd.Dir = tmpDir
+	if err := initCmd.Run(); err != nil {
+		t.Fatalf("Failed to init remote: %v", err)
+	}
+
+	log := logger.Init()
+	ctx := logger.WithLogger(context.Background(), &log)
+	commit, err := registry.Init(ctx, &registry.InitRequest{
+		Path:   filepath.Join(tmpDir, "staging.git"),
+		Remote: "file://" + remoteDir,
+		Author: &git.Author{Name: "Test User", Email: "test@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	cmd := exec.Command("git", "rev-parse", "refs/heads/main")
+	cmd.Dir = remoteDir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("rev-parse remote main: %v", err)
+	}
+	if got := git.Hash(strings.TrimSpace(string(out))); got != commit {
+		t.Errorf("remote main = %v, want %v", got, commit)
+	}
+}